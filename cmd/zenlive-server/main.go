@@ -6,14 +6,22 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/aminofox/zenlive/pkg/api"
 	"github.com/aminofox/zenlive/pkg/auth"
 	"github.com/aminofox/zenlive/pkg/config"
+	"github.com/aminofox/zenlive/pkg/health"
 	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/quota"
 	"github.com/aminofox/zenlive/pkg/room"
+	"github.com/aminofox/zenlive/pkg/storage"
+	"github.com/aminofox/zenlive/pkg/storage/formats"
+	"github.com/aminofox/zenlive/pkg/streaming/rtmp"
+	"github.com/aminofox/zenlive/pkg/streaming/webrtc"
+	"github.com/redis/go-redis/v9"
 )
 
 var (
@@ -55,9 +63,9 @@ func main() {
 
 	ctx := context.Background()
 
-	// Initialize API key manager (used by token builder)
+	// Initialize API key manager (used by token builder and signed API requests)
 	apiKeyStore := auth.NewMemoryAPIKeyStore()
-	_ = auth.NewAPIKeyManager(apiKeyStore) // Will be used for API endpoints later
+	apiKeyManager := auth.NewAPIKeyManager(apiKeyStore)
 
 	// Generate default API key in dev mode
 	if cfg.Server.DevMode && cfg.Auth.DefaultAPIKey != "" && cfg.Auth.DefaultSecretKey != "" {
@@ -84,8 +92,37 @@ func main() {
 
 	// Initialize room manager
 	roomMgr := room.NewRoomManager(log)
+	roomMgr.StartEmptyRoomSweeper(time.Minute)
 	log.Info("Room manager initialized")
 
+	// Enforce per-tenant quotas on rooms and participants
+	var quotas *quota.Manager
+	if cfg.Quota.Enabled {
+		quotas = newQuotaManager(cfg)
+		roomMgr.SetQuotaManager(quotas)
+		log.Info("Tenant quotas enabled",
+			logger.Int("max_rooms", cfg.Quota.MaxRooms),
+			logger.Int("max_participants", cfg.Quota.MaxParticipants),
+			logger.Int("max_streams", cfg.Quota.MaxStreams),
+		)
+	}
+
+	// Track open recorders so they can be finalized on shutdown
+	recorders := storage.NewRecorderRegistry(log)
+
+	// Accept RTMP ingest, recording published streams if configured
+	var rtmpServer *rtmp.Server
+	if cfg.Streaming.EnableRTMP {
+		rtmpServer = rtmp.NewServer(fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Streaming.RTMP.Port), log)
+		if cfg.Streaming.RTMP.Record {
+			rtmpServer.Use(rtmp.NewRecordingStage(newFLVRecorder(cfg, log), recorders, log))
+		}
+		if err := rtmpServer.Start(); err != nil {
+			log.Error("Failed to start RTMP server", logger.Err(err))
+			os.Exit(1)
+		}
+	}
+
 	// Initialize API server
 	apiCfg := &api.Config{
 		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
@@ -94,8 +131,29 @@ func main() {
 		CORSOrigins:  []string{"*"},
 		CORSMethods:  []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		CORSHeaders:  []string{"Content-Type", "Authorization"},
+
+		TrustedProxies: cfg.Server.TrustedProxies,
 	}
 	apiServer := api.NewServer(roomMgr, jwtAuth, apiCfg, log)
+	apiServer.SetAPIKeyManager(apiKeyManager)
+	if quotas != nil {
+		apiServer.SetQuotaManager(quotas)
+	}
+
+	// Serve component health from /api/health and /api/ready
+	healthRegistry := health.NewRegistry()
+	healthRegistry.Register("room_manager", roomMgr, true)
+	apiServer.SetHealthRegistry(healthRegistry)
+
+	// Self-test STUN/TURN reachability, then keep checking in the background
+	var iceMonitor *webrtc.ICEMonitor
+	if cfg.Streaming.EnableWebRTC && cfg.Streaming.WebRTC.ICECheckInterval >= 0 {
+		iceMonitor = newICEMonitor(cfg.Streaming.WebRTC, log)
+		iceMonitor.Check(context.Background())
+		iceMonitor.Start()
+		healthRegistry.Register("ice", iceMonitor, false)
+		apiServer.SetICEMonitor(iceMonitor)
+	}
 
 	// Start API server in background
 	go func() {
@@ -110,19 +168,102 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+	healthRegistry.SetReady(true)
 	log.Info("ZenLive server started successfully")
 	log.Info("Press Ctrl+C to shutdown")
 
 	// Block until signal received
 	<-sigChan
 	log.Info("Shutdown signal received, starting graceful shutdown...")
+	healthRegistry.SetReady(false)
+	if iceMonitor != nil {
+		iceMonitor.Stop()
+	}
 
 	// Graceful shutdown with timeout
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Cleanup (room manager doesn't have Close method yet)
-	_ = shutdownCtx // Use the context if needed
+	if err := apiServer.Shutdown(shutdownCtx); err != nil {
+		log.Error("Failed to stop API server", logger.Err(err))
+	}
+
+	// Stop ingest before finalizing, so no recording starts afterwards
+	if rtmpServer != nil {
+		if err := rtmpServer.Stop(); err != nil {
+			log.Error("Failed to stop RTMP server", logger.Err(err))
+		}
+	}
+
+	// Finalize in-flight recordings so their files remain playable
+	if err := recorders.FinalizeAll(shutdownCtx); err != nil {
+		log.Error("Failed to finalize recordings", logger.Err(err))
+	}
+
+	// End all rooms so clients see participants leave and SFU resources are released
+	if err := roomMgr.Close(shutdownCtx); err != nil {
+		log.Error("Failed to close room manager", logger.Err(err))
+	}
 
 	log.Info("ZenLive server stopped")
 }
+
+// newFLVRecorder returns a recorder factory for the RTMP recording stage
+// that writes each stream's FLV segments under the storage base path
+func newFLVRecorder(cfg *config.Config, log logger.Logger) func(streamKey string) (rtmp.TagRecorder, error) {
+	return func(streamKey string) (rtmp.TagRecorder, error) {
+		recordingCfg := storage.DefaultRecordingConfig()
+		recordingCfg.Format = storage.FormatFLV
+		recordingCfg.StreamID = streamKey
+		recordingCfg.OutputPath = filepath.Join(cfg.Storage.BasePath, "recordings")
+
+		return formats.NewFLVRecorder(recordingCfg, log)
+	}
+}
+
+// newQuotaManager creates a quota manager with the configured default
+// limits, counting usage in Redis when it is enabled so every node shares
+// the same counts
+func newQuotaManager(cfg *config.Config) *quota.Manager {
+	var store quota.UsageStore = quota.NewMemoryUsageStore()
+	if cfg.Redis.Enabled {
+		store = quota.NewRedisUsageStore(redis.NewClient(&redis.Options{
+			Addr:       cfg.Redis.Address,
+			Password:   cfg.Redis.Password,
+			DB:         cfg.Redis.DB,
+			PoolSize:   cfg.Redis.PoolSize,
+			MaxRetries: cfg.Redis.MaxRetries,
+		}))
+	}
+
+	return quota.NewManager(store, quota.Limits{
+		MaxRooms:        cfg.Quota.MaxRooms,
+		MaxParticipants: cfg.Quota.MaxParticipants,
+		MaxStreams:      cfg.Quota.MaxStreams,
+	})
+}
+
+// newICEMonitor creates an ICE monitor for the configured STUN and TURN
+// servers that logs an error whenever one becomes unreachable
+func newICEMonitor(cfg config.WebRTCConfig, log logger.Logger) *webrtc.ICEMonitor {
+	iceCfg := webrtc.DefaultConfig()
+	iceCfg.STUNServers = cfg.STUNServers
+	iceCfg.TURNServers = make([]webrtc.TURNServer, 0, len(cfg.TURNServers))
+	for _, turn := range cfg.TURNServers {
+		iceCfg.TURNServers = append(iceCfg.TURNServers, webrtc.TURNServer{
+			URLs:       turn.URLs,
+			Username:   turn.Username,
+			Credential: turn.Credential,
+		})
+	}
+
+	monitor := webrtc.NewICEMonitor(iceCfg, cfg.ICECheckInterval, log)
+	monitor.OnAlert(func(result webrtc.ICEServerResult) {
+		log.Error("ICE server unreachable, clients behind NAT may fail to connect",
+			logger.String("url", result.URL),
+			logger.String("kind", result.Kind),
+			logger.String("error", result.Error),
+		)
+	})
+	return monitor
+}
@@ -0,0 +1,361 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/quota"
+	"github.com/aminofox/zenlive/pkg/room"
+	"github.com/aminofox/zenlive/pkg/streaming/webrtc"
+	"github.com/aminofox/zenlive/pkg/types"
+)
+
+// AdminScope is the scope required by the admin API. JWTs carry it as an
+// admin role or a "scope" claim; API keys carry it in their "scope" metadata.
+const AdminScope = "admin"
+
+// AdminHandler exposes live SFU and room state for operators
+type AdminHandler struct {
+	roomManager *room.RoomManager
+	signaling   *SignalingServer
+	sfu         *webrtc.SFU
+	quota       *quota.Manager
+	logger      logger.Logger
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(roomManager *room.RoomManager, signaling *SignalingServer, log logger.Logger) *AdminHandler {
+	return &AdminHandler{
+		roomManager: roomManager,
+		signaling:   signaling,
+		logger:      log,
+	}
+}
+
+// SetSFU sets the SFU whose streams are inspected and terminated
+func (h *AdminHandler) SetSFU(sfu *webrtc.SFU) {
+	h.sfu = sfu
+}
+
+// SetQuotaManager sets the quota manager whose tenant usage is reported
+func (h *AdminHandler) SetQuotaManager(quotas *quota.Manager) {
+	h.quota = quotas
+}
+
+// AdminStreamResponse describes an active SFU stream
+type AdminStreamResponse struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	PublisherID     string `json:"publisher_id,omitempty"`
+	Publishing      bool   `json:"publishing"`
+	SubscriberCount int    `json:"subscriber_count"`
+	Bitrate         int    `json:"bitrate"` // bps, summed over published tracks
+}
+
+// AdminRoomStats describes the live state of a room
+type AdminRoomStats struct {
+	RoomID            string         `json:"room_id"`
+	Name              string         `json:"name"`
+	CreatedAt         time.Time      `json:"created_at"`
+	Participants      int            `json:"participants"`
+	ParticipantStates map[string]int `json:"participant_states"`
+	Tracks            int            `json:"tracks"`
+	Connections       int            `json:"connections"` // open signaling connections
+	Streams           int            `json:"streams"`     // SFU streams published by participants
+	Bitrate           int            `json:"bitrate"`     // bps, summed over those streams
+}
+
+// RequireAdmin authenticates the request and rejects it unless the caller
+// has the admin scope
+func (m *AuthMiddleware) RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return m.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+		if !hasAdminScope(r) {
+			m.sendError(w, http.StatusForbidden, "admin scope required")
+			return
+		}
+		next(w, r)
+	})
+}
+
+// hasAdminScope reports whether the authenticated caller has the admin scope
+func hasAdminScope(r *http.Request) bool {
+	if claims, ok := GetClaims(r); ok {
+		if claims.Role == types.RoleAdmin {
+			return true
+		}
+		scope, _ := claims.Custom["scope"].(string)
+		return containsScope(scope, AdminScope)
+	}
+
+	if apiKey, ok := GetAPIKey(r); ok {
+		return containsScope(apiKey.Metadata["scope"], AdminScope)
+	}
+
+	return false
+}
+
+// containsScope reports whether a space-separated scope list contains scope
+func containsScope(scopes, scope string) bool {
+	for _, s := range strings.Fields(scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ListStreams handles GET /admin/streams
+func (h *AdminHandler) ListStreams(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	responses := make([]AdminStreamResponse, 0)
+	if h.sfu != nil {
+		for _, stream := range h.sfu.GetStreams() {
+			responses = append(responses, streamToResponse(stream))
+		}
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"streams": responses,
+		"total":   len(responses),
+	})
+}
+
+// TerminateStream handles POST /admin/streams/:streamId/terminate
+func (h *AdminHandler) TerminateStream(w http.ResponseWriter, r *http.Request, streamID string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	if h.sfu == nil {
+		writeError(w, http.StatusNotFound, "stream not found", nil)
+		return
+	}
+
+	if err := h.sfu.DeleteStream(streamID); err != nil {
+		writeErrorFor(w, err, "failed to terminate stream")
+		return
+	}
+
+	h.logger.Warn("Stream terminated via admin API", logger.String("stream_id", streamID))
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "stream terminated",
+	})
+}
+
+// GetPeerStats handles GET /admin/peers/:peerId/stats
+func (h *AdminHandler) GetPeerStats(w http.ResponseWriter, r *http.Request, peerID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	if h.sfu == nil {
+		writeError(w, http.StatusNotFound, "peer not found", nil)
+		return
+	}
+
+	stats, err := h.sfu.GetPeerStats(peerID)
+	if err != nil {
+		writeErrorFor(w, err, "peer not found")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, stats)
+}
+
+// GetRoomStats handles GET /admin/rooms/:roomId/stats
+func (h *AdminHandler) GetRoomStats(w http.ResponseWriter, r *http.Request, roomID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	rm, err := h.roomManager.GetRoom(roomID)
+	if err != nil {
+		writeErrorFor(w, err, "room not found")
+		return
+	}
+
+	stats := AdminRoomStats{
+		RoomID:            rm.ID,
+		Name:              rm.Name,
+		CreatedAt:         rm.CreatedAt,
+		ParticipantStates: make(map[string]int),
+		Connections:       h.signaling.GetConnectionCount(roomID),
+	}
+
+	participantIDs := make(map[string]bool)
+	for _, p := range rm.ListParticipants() {
+		participantIDs[p.ID] = true
+		stats.Participants++
+		stats.ParticipantStates[string(p.GetState())]++
+		stats.Tracks += len(p.GetTracks())
+	}
+
+	if h.sfu != nil {
+		for _, stream := range h.sfu.GetStreams() {
+			response := streamToResponse(stream)
+			if participantIDs[response.PublisherID] {
+				stats.Streams++
+				stats.Bitrate += response.Bitrate
+			}
+		}
+	}
+
+	h.sendJSON(w, http.StatusOK, stats)
+}
+
+// KickParticipant handles POST /admin/rooms/:roomId/kick/:participantId
+func (h *AdminHandler) KickParticipant(w http.ResponseWriter, r *http.Request, roomID, participantID string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	rm, err := h.roomManager.GetRoom(roomID)
+	if err != nil {
+		writeErrorFor(w, err, "room not found")
+		return
+	}
+
+	// Connected participants are disconnected; others are just removed
+	if !h.signaling.KickParticipant(roomID, participantID) {
+		if err := rm.RemoveParticipant(participantID); err != nil {
+			writeErrorFor(w, err, "failed to kick participant")
+			return
+		}
+	}
+
+	h.logger.Warn("Participant kicked via admin API",
+		logger.String("room_id", roomID),
+		logger.String("participant_id", participantID),
+	)
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "participant kicked",
+	})
+}
+
+// GetQuotaUsage handles GET /admin/quotas/:tenant
+func (h *AdminHandler) GetQuotaUsage(w http.ResponseWriter, r *http.Request, tenant string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	if h.quota == nil {
+		writeError(w, http.StatusNotFound, "quotas not configured", nil)
+		return
+	}
+
+	usage, err := h.quota.GetUsage(r.Context(), tenant)
+	if err != nil {
+		writeErrorFor(w, err, "failed to get quota usage")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, usage)
+}
+
+// ServeHTTP routes admin requests by path
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := splitPath(strings.TrimPrefix(r.URL.Path, "/admin/"))
+
+	switch {
+	case len(parts) == 1 && parts[0] == "streams":
+		h.ListStreams(w, r)
+	case len(parts) == 3 && parts[0] == "streams" && parts[2] == "terminate":
+		h.TerminateStream(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "peers" && parts[2] == "stats":
+		h.GetPeerStats(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "rooms" && parts[2] == "stats":
+		h.GetRoomStats(w, r, parts[1])
+	case len(parts) == 4 && parts[0] == "rooms" && parts[2] == "kick":
+		h.KickParticipant(w, r, parts[1], parts[3])
+	case len(parts) == 2 && parts[0] == "quotas":
+		h.GetQuotaUsage(w, r, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, "not found", nil)
+	}
+}
+
+func (h *AdminHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+// streamToResponse summarizes an SFU stream
+func streamToResponse(stream *webrtc.SFUStream) AdminStreamResponse {
+	response := AdminStreamResponse{
+		ID:              stream.ID,
+		Name:            stream.Name,
+		SubscriberCount: stream.GetSubscriberCount(),
+	}
+
+	if publisher := stream.GetPublisher(); publisher != nil {
+		response.PublisherID = publisher.GetID()
+		response.Publishing = publisher.IsPublishing()
+		for _, stats := range publisher.GetStats() {
+			response.Bitrate += stats.Bitrate
+		}
+	}
+
+	return response
+}
+
+// GetConnectionCount returns the number of signaling connections in a room
+func (s *SignalingServer) GetConnectionCount(roomID string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.roomClients[roomID])
+}
+
+// KickParticipant removes a connected participant from a room, announcing
+// the departure as kicked, and closes its connection. It returns false if
+// the participant has no connection on this server.
+func (s *SignalingServer) KickParticipant(roomID, participantID string) bool {
+	s.mu.RLock()
+	var client *WSClient
+	for _, c := range s.roomClients[roomID] {
+		c.mu.RLock()
+		isTarget := c.participantID == participantID
+		c.mu.RUnlock()
+
+		if isTarget {
+			client = c
+			break
+		}
+	}
+	s.mu.RUnlock()
+
+	if client == nil {
+		return false
+	}
+
+	// Detach the client first so unregisterClient doesn't announce a second departure
+	client.mu.Lock()
+	client.roomID = ""
+	client.participantID = ""
+	client.mu.Unlock()
+
+	s.removeFromRoom(client, roomID, participantID, LeaveReasonKicked)
+
+	if client.conn != nil {
+		client.conn.Close()
+	}
+
+	return true
+}
@@ -0,0 +1,1304 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/auth"
+	"github.com/aminofox/zenlive/pkg/cluster"
+	"github.com/aminofox/zenlive/pkg/discovery"
+	zerrors "github.com/aminofox/zenlive/pkg/errors"
+	"github.com/aminofox/zenlive/pkg/health"
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/overlay"
+	"github.com/aminofox/zenlive/pkg/room"
+	"github.com/gorilla/websocket"
+)
+
+func TestCORSCredentialsEchoOrigin(t *testing.T) {
+	cors := NewCORSMiddleware([]string{"https://app.example.com", "https://*.example.org"}, []string{"GET"}, []string{"Authorization"})
+	cors.SetAllowCredentials(true)
+	cors.SetMaxAge(10 * time.Minute)
+
+	handler := cors.Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		origin      string
+		allowOrigin string
+		credentials string
+	}{
+		{"https://app.example.com", "https://app.example.com", "true"},
+		{"https://tenant.example.org", "https://tenant.example.org", "true"},
+		{"https://evil.com", "", ""},
+	}
+
+	// A "*" entry allows any origin, but never with credentials
+	wildcard := NewCORSMiddleware([]string{"*", "https://app.example.com"}, []string{"GET"}, []string{"Authorization"})
+	wildcard.SetAllowCredentials(true)
+	wildcardHandler := wildcard.Handle(func(w http.ResponseWriter, r *http.Request) {})
+	req := httptest.NewRequest(http.MethodGet, "/api/rooms", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	rec := httptest.NewRecorder()
+	wildcardHandler(rec, req)
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" || rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("Expected \"*\" without credentials for an unlisted origin, got %v", rec.Header())
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/rooms", nil)
+		req.Header.Set("Origin", tt.origin)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+			t.Errorf("Origin %s: expected Allow-Origin %q, got %q", tt.origin, tt.allowOrigin, got)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tt.credentials {
+			t.Errorf("Origin %s: expected Allow-Credentials %q, got %q", tt.origin, tt.credentials, got)
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	cors := NewCORSMiddleware([]string{"*"}, []string{"GET", "POST"}, []string{"Content-Type"})
+	cors.SetMaxAge(10 * time.Minute)
+
+	called := false
+	handler := cors.Handle(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/rooms", nil)
+	req.Header.Set("Origin", "https://any.example.com")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if called {
+		t.Error("Preflight should not reach the handler")
+	}
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Expected wildcard Allow-Origin without credentials, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Expected Max-Age 600, got %q", got)
+	}
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	manager := room.NewRoomManager(log)
+	server := NewServer(manager, auth.NewJWTAuthenticator("secret", nil, nil), DefaultConfig(), log)
+
+	registry := health.NewRegistry()
+	registry.Register("room_manager", manager, true)
+	server.SetHealthRegistry(registry)
+
+	probe := func(handler http.HandlerFunc) (int, health.Report) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		var report health.Report
+		json.Unmarshal(rec.Body.Bytes(), &report)
+		return rec.Code, report
+	}
+
+	if code, _ := probe(server.healthCheck); code != http.StatusOK {
+		t.Errorf("Expected live before start, got %d", code)
+	}
+	if code, _ := probe(server.readyCheck); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected not ready before start, got %d", code)
+	}
+
+	registry.SetReady(true)
+	if code, report := probe(server.readyCheck); code != http.StatusOK || len(report.Components) != 1 {
+		t.Errorf("Expected ready with one component, got %d %+v", code, report)
+	}
+
+	manager.Shutdown()
+	if code, report := probe(server.healthCheck); code != http.StatusServiceUnavailable || report.Status != health.StatusUnhealthy {
+		t.Errorf("Expected unhealthy once the room manager is closed, got %d %s", code, report.Status)
+	}
+}
+
+func TestServerShutdownStopsWorkers(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	config := DefaultConfig()
+	config.Addr = "127.0.0.1:0"
+	server := NewServer(room.NewRoomManager(log), auth.NewJWTAuthenticator("secret", nil, nil), config, log)
+
+	started := make(chan error, 1)
+	go func() { started <- server.Start() }()
+
+	// Wait for Start to create the HTTP server
+	deadline := time.Now().Add(time.Second)
+	for {
+		server.mu.Lock()
+		running := server.httpServer != nil
+		server.mu.Unlock()
+		if running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the server to start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Errorf("Expected Start to return nil after Shutdown, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Start to return after Shutdown")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		server.signalingServer.fanout.wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the fanout workers to exit")
+	}
+}
+
+func TestReplayBufferResume(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	server := NewSignalingServer(room.NewRoomManager(log), log)
+	server.SetReplayBufferSize(3)
+
+	for i := 0; i < 5; i++ {
+		server.BroadcastToRoom("room-1", &WSMessage{
+			Type:   MsgRoomEvent,
+			RoomID: "room-1",
+			Data:   mustMarshal(RoomEventData{EventType: "metadata.updated"}),
+		}, "")
+	}
+
+	client := &WSClient{id: "client-1", send: make(chan []byte, 16), server: server}
+
+	if !client.replayEvents("room-1", 3) {
+		t.Error("Expected complete replay from seq 3")
+	}
+	if len(client.send) != 2 {
+		t.Fatalf("Expected 2 replayed events, got %d", len(client.send))
+	}
+
+	for _, want := range []uint64{4, 5} {
+		var msg WSMessage
+		if err := json.Unmarshal(<-client.send, &msg); err != nil {
+			t.Fatalf("Failed to decode replayed event: %v", err)
+		}
+		if msg.Seq != want {
+			t.Errorf("Expected seq %d, got %d", want, msg.Seq)
+		}
+	}
+
+	// Event 2 was evicted, so resuming from 1 cannot be complete
+	if client.replayEvents("room-1", 1) {
+		t.Error("Expected incomplete replay after eviction")
+	}
+}
+
+func TestReplayHoldsLiveBroadcasts(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	server := NewSignalingServer(room.NewRoomManager(log), log)
+	defer server.Close()
+
+	event := &WSMessage{Type: MsgRoomEvent, RoomID: "room-1", Data: mustMarshal(RoomEventData{EventType: "metadata.updated"})}
+	for i := 0; i < 3; i++ {
+		copied := *event
+		server.BroadcastToRoom("room-1", &copied, "")
+	}
+
+	// A resuming client is registered before its replay, so live
+	// broadcasts reach it meanwhile
+	client := &WSClient{id: "client-1", roomID: "room-1", replaying: true, send: make(chan []byte, 16), server: server}
+	server.mu.Lock()
+	server.roomClients["room-1"] = map[string]*WSClient{client.id: client}
+	server.mu.Unlock()
+
+	live := *event
+	server.BroadcastToRoom("room-1", &live, "")
+	server.BroadcastToRoom("room-1", &WSMessage{Type: MsgSendData, RoomID: "room-1"}, "")
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		client.mu.RLock()
+		held := len(client.held)
+		client.mu.RUnlock()
+		if held == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 2 held broadcasts, got %d", held)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(client.send) != 0 {
+		t.Fatal("Expected live broadcasts to be held during replay")
+	}
+
+	if !client.replayEvents("room-1", 1) {
+		t.Error("Expected complete replay from seq 1")
+	}
+
+	// Replayed events first, without the live duplicate, then the rest
+	var received []string
+	for len(client.send) > 0 {
+		var msg WSMessage
+		if err := json.Unmarshal(<-client.send, &msg); err != nil {
+			t.Fatalf("Failed to decode message: %v", err)
+		}
+		received = append(received, fmt.Sprintf("%s:%d", msg.Type, msg.Seq))
+	}
+	want := []string{MsgRoomEvent + ":2", MsgRoomEvent + ":3", MsgRoomEvent + ":4", MsgSendData + ":0"}
+	if strings.Join(received, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, received)
+	}
+}
+
+func TestReplayBufferSkipsNonEvents(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	server := NewSignalingServer(room.NewRoomManager(log), log)
+
+	server.BroadcastToRoom("room-1", &WSMessage{Type: MsgSendData, RoomID: "room-1"}, "")
+
+	if seq := server.getReplayBuffer("room-1").sequence(); seq != 0 {
+		t.Errorf("Expected data messages not to be sequenced, got seq %d", seq)
+	}
+}
+
+func TestHeartbeatTimeout(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	manager := room.NewRoomManager(log)
+	server := NewSignalingServer(manager, log)
+	defer server.Close()
+	server.SetHeartbeatTimeout(50 * time.Millisecond)
+
+	rm, err := manager.CreateRoom(&room.CreateRoomRequest{Name: "heartbeat"}, "host")
+	if err != nil {
+		t.Fatalf("Failed to create room: %v", err)
+	}
+
+	join := func(clientID, participantID string, lastHeartbeat time.Time) *WSClient {
+		if err := rm.AddParticipant(&room.Participant{ID: participantID, UserID: participantID}); err != nil {
+			t.Fatalf("Failed to add participant: %v", err)
+		}
+		client := &WSClient{
+			id:            clientID,
+			roomID:        rm.ID,
+			participantID: participantID,
+			lastHeartbeat: lastHeartbeat,
+			send:          make(chan []byte, 16),
+			server:        server,
+		}
+		server.mu.Lock()
+		server.clients[clientID] = client
+		if server.roomClients[rm.ID] == nil {
+			server.roomClients[rm.ID] = make(map[string]*WSClient)
+		}
+		server.roomClients[rm.ID][clientID] = client
+		server.mu.Unlock()
+		return client
+	}
+
+	join("client-ghost", "ghost", time.Now().Add(-time.Minute))
+	alive := join("client-alive", "alive", time.Now())
+
+	stale := server.GetStaleParticipants()
+	if len(stale) != 1 || stale[0].ParticipantID != "ghost" {
+		t.Fatalf("Expected only ghost to be stale, got %+v", stale)
+	}
+
+	server.expireStaleParticipants()
+
+	if _, err := rm.GetParticipant("ghost"); err == nil {
+		t.Error("Expected ghost participant to be removed")
+	}
+	if len(server.GetStaleParticipants()) != 0 {
+		t.Error("Expected no stale participants after expiry")
+	}
+
+	var msg WSMessage
+	if err := json.Unmarshal(<-alive.send, &msg); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	var event struct {
+		EventType string            `json:"event_type"`
+		Data      map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		t.Fatalf("Failed to decode event data: %v", err)
+	}
+	if event.EventType != "participant.left" || event.Data["reason"] != LeaveReasonTimeout {
+		t.Errorf("Expected participant.left with reason timeout, got %s %v", event.EventType, event.Data)
+	}
+}
+
+func TestPongRefreshesHeartbeat(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	server := NewSignalingServer(room.NewRoomManager(log), log)
+	defer server.Close()
+
+	httpServer := httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+	defer httpServer.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	lastHeartbeat := func() time.Time {
+		server.mu.RLock()
+		defer server.mu.RUnlock()
+		for _, client := range server.clients {
+			client.mu.RLock()
+			defer client.mu.RUnlock()
+			return client.lastHeartbeat
+		}
+		return time.Time{}
+	}
+
+	// Wait for the client to register, then age its heartbeat
+	deadline := time.Now().Add(time.Second)
+	for lastHeartbeat().IsZero() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stale := time.Now().Add(-time.Hour)
+	server.mu.RLock()
+	for _, client := range server.clients {
+		client.mu.Lock()
+		client.lastHeartbeat = stale
+		client.mu.Unlock()
+	}
+	server.mu.RUnlock()
+
+	if err := conn.WriteControl(websocket.PongMessage, nil, time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Failed to send pong: %v", err)
+	}
+
+	deadline = time.Now().Add(time.Second)
+	for !lastHeartbeat().After(stale) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a pong to refresh the client's heartbeat")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSignalingMigration(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	manager := room.NewRoomManager(log)
+	server := NewSignalingServer(manager, log)
+	defer server.Close()
+
+	discovery := cluster.NewInMemoryServiceDiscovery()
+	discovery.Register(ctx, &cluster.ServiceInfo{ID: "sfu-a", Name: "sfu", NodeID: "node-a", Address: "10.0.0.1:7880"})
+	discovery.Register(ctx, &cluster.ServiceInfo{ID: "sfu-b", Name: "sfu", NodeID: "node-b", Address: "10.0.0.2:7880"})
+
+	sessions := cluster.NewInMemorySessionManager(time.Hour)
+	server.SetClusterSessions(sessions, "node-a")
+
+	rm, _ := manager.CreateRoom(&room.CreateRoomRequest{Name: "failover"}, "host")
+	rm.AddParticipant(room.NewParticipant("p1", "user-1", "Alice", room.RoleSpeaker))
+
+	client := &WSClient{id: "client-1", roomID: rm.ID, participantID: "p1", send: make(chan []byte, 16), server: server}
+	server.mu.Lock()
+	server.clients[client.id] = client
+	server.mu.Unlock()
+	server.trackSession(client, rm.ID, "p1", "user-1")
+
+	// Heartbeats keep the session alive
+	session, _ := sessions.GetSession(ctx, client.id)
+	expiresAt := session.ExpiresAt
+	client.mu.Lock()
+	client.sessionRefreshedAt = time.Now().Add(-sessionRefreshInterval)
+	client.mu.Unlock()
+	client.touch()
+	if session, _ = sessions.GetSession(ctx, client.id); !session.ExpiresAt.After(expiresAt) {
+		t.Errorf("Expected a heartbeat to extend the session past %v, got %v", expiresAt, session.ExpiresAt)
+	}
+
+	coordinator := cluster.NewMigrationCoordinator(discovery, sessions, "sfu")
+	coordinator.OnParticipantMigrated(server.NotifyMigration)
+
+	if _, err := coordinator.MigrateNode(ctx, "node-a"); err != nil {
+		t.Fatalf("Failed to migrate node: %v", err)
+	}
+
+	var msg WSMessage
+	if err := json.Unmarshal(<-client.send, &msg); err != nil {
+		t.Fatalf("Failed to decode message: %v", err)
+	}
+	var data MigrateData
+	json.Unmarshal(msg.Data, &data)
+	if msg.Type != MsgMigrate || data.NodeID != "node-b" || data.Address != "10.0.0.2:7880" || !data.ICERestart {
+		t.Errorf("Expected migrate to node-b with ICE restart, got %s %+v", msg.Type, data)
+	}
+
+	if _, err := rm.GetParticipant("p1"); err != nil {
+		t.Errorf("Expected participant to stay in the room, got %v", err)
+	}
+}
+
+func TestSignalingJoinTokenBinding(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	manager := room.NewRoomManager(log)
+	server := NewSignalingServer(manager, log)
+	defer server.Close()
+	server.SetAccessTokenSecret("test-secret")
+
+	rm, _ := manager.CreateRoom(&room.CreateRoomRequest{Name: "secure"}, "host")
+
+	byName, _ := auth.NewAccessTokenBuilder("api-key", "test-secret").
+		SetIdentity("user-1").
+		SetRoomJoin("secure").
+		Build()
+	client := &WSClient{id: "client-1", remoteIP: "10.0.0.1", send: make(chan []byte, 16), server: server}
+	if _, err := server.verifyJoinToken(client, rm, &JoinRoomData{RoomID: rm.ID, Token: byName}); !errors.Is(err, room.ErrUnauthorized) {
+		t.Errorf("Expected a token for the room name to be rejected, got %v", err)
+	}
+
+	token, err := auth.NewAccessTokenBuilder("api-key", "test-secret").
+		SetIdentity("user-1").
+		SetRoomJoin(rm.ID).
+		SetBoundIP("10.0.0.1").
+		SetBoundDeviceID("device-1").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build token: %v", err)
+	}
+
+	if _, err := server.verifyJoinToken(client, rm, &JoinRoomData{RoomID: rm.ID, Token: token, DeviceID: "other"}); !errors.Is(err, auth.ErrBindingMismatch) {
+		t.Errorf("Expected ErrBindingMismatch for wrong device, got %v", err)
+	}
+
+	data := &JoinRoomData{RoomID: rm.ID, Token: token, DeviceID: "device-1"}
+	if _, err := server.verifyJoinToken(client, rm, data); err != nil {
+		t.Errorf("Expected bound client to be accepted, got %v", err)
+	}
+	if data.UserID != "user-1" {
+		t.Errorf("Expected user ID from token, got %s", data.UserID)
+	}
+}
+
+func TestSignalingJoinTokenGrants(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	manager := room.NewRoomManager(log)
+	server := NewSignalingServer(manager, log)
+	defer server.Close()
+	server.SetAccessTokenSecret("test-secret")
+
+	rm, _ := manager.CreateRoom(&room.CreateRoomRequest{Name: "grants"}, "host")
+
+	token, err := auth.NewAccessTokenBuilder("api-key", "test-secret").
+		SetIdentity("viewer-1").
+		SetRoomJoin(rm.ID).
+		SetCanSubscribe(true).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build token: %v", err)
+	}
+
+	client := &WSClient{id: "client-1", send: make(chan []byte, 16), server: server}
+	client.handleJoinRoom(&WSMessage{Type: MsgJoinRoom, Data: mustMarshal(JoinRoomData{RoomID: rm.ID, Token: token})})
+
+	client.mu.RLock()
+	participantID := client.participantID
+	client.mu.RUnlock()
+	participant, err := rm.GetParticipant(participantID)
+	if err != nil {
+		t.Fatalf("Expected the client to join, got %v", err)
+	}
+
+	perms := participant.GetPermissions()
+	if perms.CanPublish || perms.CanPublishData || !perms.CanSubscribe {
+		t.Errorf("Expected subscribe-only permissions from the token, got %+v", perms)
+	}
+	if participant.CanPublish {
+		t.Error("Expected the participant not to be allowed to publish")
+	}
+}
+
+func TestSignalingReleasesTokenOnFailedJoin(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	manager := room.NewRoomManager(log)
+	server := NewSignalingServer(manager, log)
+	defer server.Close()
+	server.SetAccessTokenSecret("test-secret")
+
+	rm, _ := manager.CreateRoom(&room.CreateRoomRequest{Name: "full", MaxParticipants: 1}, "host")
+	rm.AddParticipant(room.NewParticipant("p1", "user-1", "Alice", room.RoleSpeaker))
+
+	token, err := auth.NewAccessTokenBuilder("api-key", "test-secret").
+		SetIdentity("viewer-1").
+		SetRoomJoin(rm.ID).
+		SetSingleUse(true).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build token: %v", err)
+	}
+
+	join := func(id string) *WSClient {
+		client := &WSClient{id: id, send: make(chan []byte, 16), server: server}
+		client.handleJoinRoom(&WSMessage{Type: MsgJoinRoom, Data: mustMarshal(JoinRoomData{RoomID: rm.ID, Token: token})})
+		return client
+	}
+
+	if client := join("client-1"); client.participantID != "" {
+		t.Fatal("Expected the join to fail while the room is full")
+	}
+
+	rm.RemoveParticipant("p1")
+	client := join("client-2")
+	if _, err := rm.GetParticipant(client.participantID); err != nil {
+		t.Fatalf("Expected the token to be usable after the failed join, got %v", err)
+	}
+}
+
+func TestSignalingSendDataAssignsMessageID(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	manager := room.NewRoomManager(log)
+	server := NewSignalingServer(manager, log)
+	defer server.Close()
+
+	rm, _ := manager.CreateRoom(&room.CreateRoomRequest{Name: "receipts", DeliveryReceipts: true}, "host")
+	rm.AddParticipant(room.NewParticipant("p1", "user-1", "Alice", room.RoleSpeaker))
+	rm.AddParticipant(room.NewParticipant("p2", "user-2", "Bob", room.RoleSpeaker))
+	rm.TrackMessage("m1", "p2", nil)
+
+	client := &WSClient{id: "client-1", roomID: rm.ID, participantID: "p1", send: make(chan []byte, 16), server: server}
+	client.handleSendData(&WSMessage{Type: MsgSendData, Data: mustMarshal(DataMessage{ID: "m1", Topic: "chat", Payload: []byte("hi")})})
+
+	var msg WSMessage
+	if err := json.Unmarshal(<-client.send, &msg); err != nil {
+		t.Fatalf("Failed to decode message: %v", err)
+	}
+	var receipt room.DeliveryReceipt
+	json.Unmarshal(msg.Data, &receipt)
+	if msg.Type != MsgDeliveryReceipt || receipt.MessageID == "" || receipt.MessageID == "m1" {
+		t.Fatalf("Expected a receipt for a server-assigned message ID, got %s %+v", msg.Type, receipt)
+	}
+
+	if state, _ := rm.GetDeliveryState("m1"); state.SenderID != "p2" {
+		t.Errorf("Expected m1 to stay p2's message, got sender %s", state.SenderID)
+	}
+}
+
+func TestTrustedProxiesClientIP(t *testing.T) {
+	proxies, err := NewTrustedProxies([]string{"10.0.0.1", "192.168.0.0/16"})
+	if err != nil {
+		t.Fatalf("Failed to parse proxies: %v", err)
+	}
+	if _, err := NewTrustedProxies([]string{"not-an-ip"}); err == nil {
+		t.Error("Expected an invalid proxy to be rejected")
+	}
+
+	request := func(remoteAddr, forwarded, realIP string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remoteAddr
+		if forwarded != "" {
+			r.Header.Set("X-Forwarded-For", forwarded)
+		}
+		if realIP != "" {
+			r.Header.Set("X-Real-IP", realIP)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		proxies *TrustedProxies
+		r       *http.Request
+		want    string
+	}{
+		{"direct client", proxies, request("203.0.113.7:5000", "", ""), "203.0.113.7"},
+		{"spoofed header", proxies, request("203.0.113.7:5000", "1.2.3.4", "5.6.7.8"), "203.0.113.7"},
+		{"no trusted proxies", nil, request("203.0.113.7:5000", "1.2.3.4", ""), "203.0.113.7"},
+		{"trusted proxy", proxies, request("10.0.0.1:5000", "198.51.100.2", ""), "198.51.100.2"},
+		{"proxy chain", proxies, request("10.0.0.1:5000", "1.2.3.4, 198.51.100.2, 192.168.1.1", ""), "198.51.100.2"},
+		{"real ip", proxies, request("192.168.3.4:5000", "", "198.51.100.3"), "198.51.100.3"},
+	}
+	for _, tt := range tests {
+		if got := tt.proxies.ClientIP(tt.r); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestAuthMiddlewareSignedRequest(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	ctx := context.Background()
+
+	keys := auth.NewAPIKeyManager(auth.NewMemoryAPIKeyStore())
+	apiKey, err := keys.GenerateAPIKey(ctx, "backend", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %v", err)
+	}
+
+	mw := NewAuthMiddleware(auth.NewJWTAuthenticator("secret", nil, nil), log)
+	mw.SetAPIKeyManager(keys, time.Minute)
+
+	handler := mw.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := GetAPIKey(r)
+		if !ok || key.AccessKey != apiKey.AccessKey {
+			t.Error("Expected API key in request context")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/api/rooms?x=1", strings.NewReader(`{"name":"room"}`))
+		if err := auth.SignHTTPRequest(req, apiKey); err != nil {
+			t.Fatalf("Failed to sign request: %v", err)
+		}
+		return req
+	}
+
+	rec := httptest.NewRecorder()
+	handler(rec, newRequest())
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected signed request to be accepted, got %d", rec.Code)
+	}
+
+	tampered := newRequest()
+	tampered.Body = http.NoBody
+	rec = httptest.NewRecorder()
+	handler(rec, tampered)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected tampered body to be rejected, got %d", rec.Code)
+	}
+
+	stale := httptest.NewRequest(http.MethodPost, "/api/rooms?x=1", nil)
+	old := time.Now().Add(-time.Hour)
+	stale.Header.Set(auth.HeaderAccessKey, apiKey.AccessKey)
+	stale.Header.Set(auth.HeaderTimestamp, strconv.FormatInt(old.Unix(), 10))
+	stale.Header.Set(auth.HeaderSignature, auth.SignRequest(apiKey.SecretKey, http.MethodPost, "/api/rooms?x=1", nil, old))
+	rec = httptest.NewRecorder()
+	handler(rec, stale)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected stale timestamp to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestGenerateAccessTokenBatch(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	manager := room.NewRoomManager(log)
+	handler := NewTokenHandler(manager, nil, "secret", log)
+
+	rm, _ := manager.CreateRoom(&room.CreateRoomRequest{Name: "webinar"}, "host")
+
+	body := `{"participants":[{"user_id":"u1","username":"one"},{"user_id":"u2","username":"two","ttl":60}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/rooms/"+rm.ID+"/tokens:batch", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.GenerateAccessTokenBatch(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp BatchTokenResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Tokens) != 2 || resp.Tokens[0].UserID != "u1" || resp.Tokens[1].RoomID != rm.ID {
+		t.Errorf("Unexpected batch response: %+v", resp.Tokens)
+	}
+
+	// One invalid participant fails the whole batch
+	body = `{"participants":[{"user_id":"u1","username":"one"},{"user_id":"u1","username":"dup"}]}`
+	req = httptest.NewRequest(http.MethodPost, "/api/rooms/"+rm.ID+"/tokens:batch", strings.NewReader(body))
+	rec = httptest.NewRecorder()
+	handler.GenerateAccessTokenBatch(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for duplicate user, got %d", rec.Code)
+	}
+}
+
+func TestMsgPackCodec(t *testing.T) {
+	original := []byte(`{"type":"send_data","room_id":"room-1","seq":300,"data":{"from":"p1","payload":"AQID","score":-1.5,"big":-70000,"ok":true,"none":null,"list":[1,2,3]}}`)
+
+	packed, err := jsonToMsgPack(original)
+	if err != nil {
+		t.Fatalf("Failed to encode msgpack: %v", err)
+	}
+	if len(packed) >= len(original) {
+		t.Errorf("Expected msgpack (%d bytes) to be smaller than JSON (%d bytes)", len(packed), len(original))
+	}
+
+	var want, got WSMessage
+	json.Unmarshal(original, &want)
+	if err := decodeMessage(websocket.BinaryMessage, packed, &got); err != nil {
+		t.Fatalf("Failed to decode msgpack: %v", err)
+	}
+	if got.Type != want.Type || got.RoomID != want.RoomID || got.Seq != want.Seq {
+		t.Errorf("Round trip mismatch: want %+v, got %+v", want, got)
+	}
+
+	var wantData, gotData interface{}
+	json.Unmarshal(want.Data, &wantData)
+	json.Unmarshal(got.Data, &gotData)
+	wantJSON, _ := json.Marshal(wantData)
+	gotJSON, _ := json.Marshal(gotData)
+	if string(wantJSON) != string(gotJSON) {
+		t.Errorf("Data round trip mismatch:\nwant %s\ngot  %s", wantJSON, gotJSON)
+	}
+
+	if err := decodeMessage(websocket.BinaryMessage, []byte{0x92, 0x01}, &got); err == nil {
+		t.Error("Expected error for truncated msgpack")
+	}
+	notMap, _ := jsonToMsgPack([]byte(`[1,2]`))
+	if err := decodeMessage(websocket.BinaryMessage, notMap, &got); err == nil {
+		t.Error("Expected error for a msgpack message that is not a map")
+	}
+}
+
+func TestFanoutEncodesPerCodec(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	server := NewSignalingServer(room.NewRoomManager(log), log)
+	defer server.Close()
+
+	clients := []*WSClient{
+		{id: "json", codec: CodecJSON, send: make(chan []byte, 1), server: server},
+		{id: "pack-1", codec: CodecMsgPack, send: make(chan []byte, 1), server: server},
+		{id: "pack-2", codec: CodecMsgPack, send: make(chan []byte, 1), server: server},
+	}
+	server.mu.Lock()
+	server.roomClients["room-1"] = make(map[string]*WSClient)
+	for _, client := range clients {
+		server.roomClients["room-1"][client.id] = client
+	}
+	server.mu.Unlock()
+
+	server.BroadcastToRoom("room-1", &WSMessage{Type: MsgPong, RoomID: "room-1"}, "")
+
+	frames := make(map[string][]byte)
+	for _, client := range clients {
+		select {
+		case frames[client.id] = <-client.send:
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for the broadcast to %s", client.id)
+		}
+	}
+
+	var msg WSMessage
+	if err := json.Unmarshal(frames["json"], &msg); err != nil || msg.Type != MsgPong {
+		t.Errorf("Expected a JSON pong, got %s (%v)", frames["json"], err)
+	}
+	if err := decodeMessage(websocket.BinaryMessage, frames["pack-1"], &msg); err != nil || msg.Type != MsgPong {
+		t.Errorf("Expected a msgpack pong, got %x (%v)", frames["pack-1"], err)
+	}
+	if &frames["pack-1"][0] != &frames["pack-2"][0] {
+		t.Error("Expected msgpack clients to share one encoded frame")
+	}
+}
+
+func TestSignalingMsgPackNegotiation(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	server := NewSignalingServer(room.NewRoomManager(log), log)
+	defer server.Close()
+
+	httpServer := httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+	defer httpServer.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{SubprotocolMsgPack}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	if conn.Subprotocol() != SubprotocolMsgPack {
+		t.Fatalf("Expected msgpack subprotocol, got %q", conn.Subprotocol())
+	}
+
+	ping, _ := jsonToMsgPack([]byte(`{"type":"ping"}`))
+	if err := conn.WriteMessage(websocket.BinaryMessage, ping); err != nil {
+		t.Fatalf("Failed to send ping: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	messageType, frame, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read pong: %v", err)
+	}
+	if messageType != websocket.BinaryMessage {
+		t.Fatalf("Expected a binary frame, got type %d", messageType)
+	}
+
+	var msg WSMessage
+	if err := decodeMessage(messageType, frame, &msg); err != nil || msg.Type != MsgPong {
+		t.Errorf("Expected pong, got %x (%v)", frame, err)
+	}
+}
+
+func TestHTTPStatusMapping(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{room.ErrRoomNotFound, http.StatusNotFound},
+		{fmt.Errorf("join: %w", room.ErrRoomFull), http.StatusConflict},
+		{fmt.Errorf("%w: room name is required", room.ErrInvalidRoomRequest), http.StatusBadRequest},
+		{fmt.Errorf("authentication failed: %w", auth.ErrTokenExpired), http.StatusUnauthorized},
+		{room.ErrSubscribeDenied, http.StatusForbidden},
+		{zerrors.NewRoomLimitExceededError(5), http.StatusTooManyRequests},
+		{fmt.Errorf("wrapped: %w", zerrors.NewParticipantNotFoundError("p1")), http.StatusNotFound},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got := HTTPStatus(tt.err); got != tt.want {
+			t.Errorf("HTTPStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+
+	// Malformed tokens are reported as invalid tokens
+	if _, err := auth.ParseAccessToken("not-a-token", "secret"); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for malformed token, got %v", err)
+	}
+}
+
+func TestErrorResponseBody(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	handler := NewRoomHandler(room.NewRoomManager(log), log)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/rooms/missing", nil)
+	rec := httptest.NewRecorder()
+	handler.GetRoom(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404, got %d", rec.Code)
+	}
+
+	var body ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode error body: %v", err)
+	}
+	if body.Code != ErrorCodeNotFound || body.Message == "" {
+		t.Errorf("Unexpected error body: %+v", body)
+	}
+	if body.Details["error"] != room.ErrRoomNotFound.Error() {
+		t.Errorf("Expected underlying error in details, got %v", body.Details)
+	}
+
+	// Server errors do not expose the underlying error
+	rec = httptest.NewRecorder()
+	writeErrorFor(rec, errors.New("database password rejected"), "internal error")
+	body = ErrorResponse{}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusInternalServerError || body.Code != ErrorCodeInternal || body.Details != nil {
+		t.Errorf("Unexpected server error response: %d %+v", rec.Code, body)
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	handler := NewRoomHandler(room.NewRoomManager(log), log)
+
+	body := `{"name":"","max_participants":-1,"empty_timeout":60}`
+	req := httptest.NewRequest(http.MethodPost, "/api/rooms", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.CreateRoom(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d", rec.Code)
+	}
+
+	var resp struct {
+		Code    string `json:"code"`
+		Details struct {
+			Fields []FieldError `json:"fields"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode error body: %v", err)
+	}
+
+	invalid := make(map[string]bool)
+	for _, f := range resp.Details.Fields {
+		invalid[f.Field] = true
+	}
+	if resp.Code != ErrorCodeInvalidRequest || len(invalid) != 3 ||
+		!invalid["name"] || !invalid["created_by"] || !invalid["max_participants"] {
+		t.Errorf("Unexpected validation response: %s", rec.Body.String())
+	}
+
+	batch := &BatchTokenRequest{Participants: []GenerateTokenRequest{
+		{UserID: "u1", Username: "one", TTL: MaxTokenTTL + 1},
+		{UserID: "u1", Username: "again"},
+	}}
+	var verr *ValidationError
+	if err := batch.Validate(); !errors.As(err, &verr) || len(verr.Fields) != 2 ||
+		verr.Fields[0].Field != "participants[0].ttl" || verr.Fields[1].Field != "participants[1].user_id" {
+		t.Errorf("Unexpected batch validation error: %v", err)
+	}
+
+	valid := &AddParticipantRequest{ParticipantID: "p1", UserID: "u1", Username: "one", Role: "attendee"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid request, got %v", err)
+	}
+}
+
+func TestAdminAPI(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	ctx := context.Background()
+
+	manager := room.NewRoomManager(log)
+	rm, _ := manager.CreateRoom(&room.CreateRoomRequest{Name: "ops"}, "host")
+	rm.AddParticipant(room.NewParticipant("p1", "u1", "one", room.RoleSpeaker))
+	rm.AddParticipant(room.NewParticipant("p2", "u2", "two", room.RoleAttendee))
+
+	signaling := NewSignalingServer(manager, log)
+	defer signaling.Close()
+
+	keys := auth.NewAPIKeyManager(auth.NewMemoryAPIKeyStore())
+	adminKey, _ := keys.GenerateAPIKey(ctx, "ops", nil, map[string]string{"scope": AdminScope})
+	plainKey, _ := keys.GenerateAPIKey(ctx, "backend", nil, nil)
+
+	mw := NewAuthMiddleware(auth.NewJWTAuthenticator("secret", nil, nil), log)
+	mw.SetAPIKeyManager(keys, time.Minute)
+	handler := mw.RequireAdmin(NewAdminHandler(manager, signaling, log).ServeHTTP)
+
+	do := func(method, path string, key *auth.APIKey) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if err := auth.SignHTTPRequest(req, key); err != nil {
+			t.Fatalf("Failed to sign request: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodGet, "/admin/streams", plainKey); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 without admin scope, got %d", rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/admin/rooms/"+rm.ID+"/stats", adminKey)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var stats AdminRoomStats
+	json.Unmarshal(rec.Body.Bytes(), &stats)
+	if stats.Participants != 2 || stats.ParticipantStates[string(room.StateJoined)] != 2 {
+		t.Errorf("Unexpected room stats: %+v", stats)
+	}
+
+	if rec := do(http.MethodPost, "/admin/rooms/"+rm.ID+"/kick/p2", adminKey); rec.Code != http.StatusOK {
+		t.Fatalf("Expected kick to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := rm.GetParticipant("p2"); err == nil {
+		t.Error("Expected kicked participant to be removed")
+	}
+
+	if rec := do(http.MethodPost, "/admin/streams/missing/terminate", adminKey); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown stream, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/admin/peers/missing/stats", adminKey); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown peer, got %d", rec.Code)
+	}
+}
+
+func TestBroadcastFanout(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	server := NewSignalingServer(room.NewRoomManager(log), log)
+	defer server.Close()
+
+	clients := make([]*WSClient, 3)
+	server.mu.Lock()
+	server.roomClients["room-1"] = make(map[string]*WSClient)
+	for i := range clients {
+		clients[i] = &WSClient{id: fmt.Sprintf("client-%d", i), send: make(chan []byte, 16), server: server}
+		server.clients[clients[i].id] = clients[i]
+		server.roomClients["room-1"][clients[i].id] = clients[i]
+	}
+	server.mu.Unlock()
+
+	for i := 0; i < 3; i++ {
+		server.BroadcastToRoom("room-1", &WSMessage{Type: MsgSendData, RoomID: "room-1", Data: mustMarshal(i)}, "client-0")
+	}
+
+	// Successive state snapshots within a tick are coalesced to the latest
+	for _, speaker := range []string{"a", "b", "c"} {
+		server.BroadcastStateToRoom("room-1", "active_speaker", &WSMessage{Type: MsgRoomEvent, Data: mustMarshal(speaker)}, "")
+	}
+	time.Sleep(3 * DefaultCoalesceInterval)
+
+	// An identical snapshot is not sent again
+	server.BroadcastStateToRoom("room-1", "active_speaker", &WSMessage{Type: MsgRoomEvent, Data: mustMarshal("c")}, "")
+	time.Sleep(3 * DefaultCoalesceInterval)
+
+	receive := func(client *WSClient) []string {
+		var got []string
+		for len(client.send) > 0 {
+			var msg WSMessage
+			json.Unmarshal(<-client.send, &msg)
+			got = append(got, string(msg.Data))
+		}
+		return got
+	}
+
+	if got := strings.Join(receive(clients[0]), ","); got != `"c"` {
+		t.Errorf("Expected excluded client to get only the coalesced state, got %s", got)
+	}
+	for _, client := range clients[1:] {
+		if got := strings.Join(receive(client), ","); got != `0,1,2,"c"` {
+			t.Errorf("Expected ordered broadcasts and one state for %s, got %s", client.id, got)
+		}
+	}
+}
+
+func BenchmarkBroadcastToRoom(b *testing.B) {
+	for _, n := range []int{1000, 5000} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			log := logger.NewDefaultLogger(logger.ErrorLevel, "text")
+			server := NewSignalingServer(room.NewRoomManager(log), log)
+			defer server.Close()
+
+			server.roomClients["room-1"] = make(map[string]*WSClient)
+			for i := 0; i < n; i++ {
+				client := &WSClient{id: fmt.Sprintf("client-%d", i), send: make(chan []byte, 256), server: server}
+				server.clients[client.id] = client
+				server.roomClients["room-1"][client.id] = client
+				go func() {
+					for range client.send {
+					}
+				}()
+			}
+
+			msg := &WSMessage{Type: MsgSendData, RoomID: "room-1", Data: mustMarshal("payload")}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				server.BroadcastToRoom("room-1", msg, "")
+			}
+		})
+	}
+}
+
+func TestDiscoveryAPI(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+
+	unconfigured := NewDiscoveryHandler(nil, log)
+	rec := httptest.NewRecorder()
+	unconfigured.ListLiveStreams(rec, httptest.NewRequest(http.MethodGet, "/api/discover/live", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without an index, got %d", rec.Code)
+	}
+
+	index := discovery.NewIndex()
+	index.Add(discovery.LiveStream{StreamID: "s1", Title: "Speedrun", Category: "gaming", Tags: []string{"retro"}, ViewerCount: 3})
+	index.Add(discovery.LiveStream{StreamID: "s2", Title: "Piano", Category: "music", ViewerCount: 9})
+	index.ViewerJoined("s1")
+	handler := NewDiscoveryHandler(index, log)
+
+	get := func(handle http.HandlerFunc, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handle(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec = get(handler.ListLiveStreams, "/api/discover/live?category=gaming&tag=retro")
+	var result discovery.Result
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if rec.Code != http.StatusOK || result.TotalCount != 1 || result.Streams[0].StreamID != "s1" {
+		t.Errorf("Expected s1 in gaming, got %d %+v", rec.Code, result)
+	}
+
+	rec = get(handler.ListLiveStreams, "/api/discover/live?sort=loudest&limit=-1")
+	var body ErrorResponse
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusBadRequest || len(body.Details["fields"].([]interface{})) != 2 {
+		t.Errorf("Expected 400 listing both invalid fields, got %d %+v", rec.Code, body)
+	}
+
+	rec = get(handler.GetTrending, "/api/discover/trending?window=5m")
+	var trending struct {
+		Streams []discovery.TrendingStream `json:"streams"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &trending)
+	if rec.Code != http.StatusOK || len(trending.Streams) != 1 || trending.Streams[0].RecentJoins != 1 {
+		t.Errorf("Expected s1 to trend, got %d %+v", rec.Code, trending)
+	}
+
+	if rec := get(handler.GetTrending, "/api/discover/trending?window=forever"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid window, got %d", rec.Code)
+	}
+}
+
+func TestOverlayAPI(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+
+	rec := httptest.NewRecorder()
+	NewOverlayHandler(nil, log).StreamAlerts(rec, httptest.NewRequest(http.MethodGet, "/api/overlay/s1/alerts", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without a manager, got %d", rec.Code)
+	}
+
+	config := overlay.DefaultConfig()
+	config.KeySecret = "secret"
+	config.MaxSubscribers = 1
+	manager := overlay.NewManager(config, log)
+	defer manager.Close()
+	server := httptest.NewServer(http.HandlerFunc(NewOverlayHandler(manager, log).StreamAlerts))
+	defer server.Close()
+
+	for _, query := range []string{"", "?key=wrong"} {
+		resp, err := http.Get(server.URL + "/api/overlay/s1/alerts" + query)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for query %q, got %d", query, resp.StatusCode)
+		}
+	}
+
+	key, _ := manager.Key("s1")
+	resp, err := http.Get(server.URL + "/api/overlay/s1/alerts?key=" + key)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", resp.Header.Get("Content-Type"))
+	}
+
+	extra, err := http.Get(server.URL + "/api/overlay/s1/alerts?key=" + key)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	extra.Body.Close()
+	if extra.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 past the subscriber cap, got %d", extra.StatusCode)
+	}
+
+	manager.Push(&overlay.Alert{StreamID: "s1", Type: overlay.AlertGift, Message: "alice sent rose"})
+
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read the event stream: %v", err)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var alert overlay.Alert
+			json.Unmarshal([]byte(data), &alert)
+			if alert.Type != overlay.AlertGift || alert.Message != "alice sent rose" {
+				t.Errorf("Unexpected alert: %+v", alert)
+			}
+			break
+		}
+	}
+}
+
+func TestSignalingLobbyJoin(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	manager := room.NewRoomManager(log)
+	server := NewSignalingServer(manager, log)
+	defer server.Close()
+
+	rm, _ := manager.CreateRoom(&room.CreateRoomRequest{Name: "lobby", LobbyEnabled: true}, "host")
+
+	next := func(client *WSClient) WSMessage {
+		t.Helper()
+		select {
+		case frame := <-client.send:
+			var msg WSMessage
+			json.Unmarshal(frame, &msg)
+			return msg
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for a message")
+			return WSMessage{}
+		}
+	}
+	join := func(clientID, userID string) (*WSClient, string) {
+		t.Helper()
+		client := &WSClient{id: clientID, send: make(chan []byte, 16), server: server}
+		server.mu.Lock()
+		server.clients[clientID] = client
+		server.mu.Unlock()
+
+		client.handleJoinRoom(&WSMessage{Type: MsgJoinRoom, Data: mustMarshal(JoinRoomData{RoomID: rm.ID, UserID: userID})})
+		msg := next(client)
+		if msg.Type != MsgWaiting {
+			t.Fatalf("Expected %s, got %s %s", MsgWaiting, msg.Type, msg.Data)
+		}
+		var data map[string]string
+		json.Unmarshal(msg.Data, &data)
+		return client, data["participant_id"]
+	}
+	inRoom := func(client *WSClient) bool {
+		server.mu.RLock()
+		defer server.mu.RUnlock()
+		_, ok := server.roomClients[rm.ID][client.id]
+		return ok
+	}
+
+	// A waiting client receives no room traffic until admitted
+	admitted, admittedID := join("client-1", "user-1")
+	if inRoom(admitted) {
+		t.Fatal("Expected the waiting client to be kept out of the room")
+	}
+
+	if err := rm.AdmitParticipant(admittedID); err != nil {
+		t.Fatalf("Failed to admit: %v", err)
+	}
+	if msg := next(admitted); msg.Type != MsgJoinRoom {
+		t.Fatalf("Expected %s after admission, got %s", MsgJoinRoom, msg.Type)
+	}
+	if !inRoom(admitted) {
+		t.Error("Expected the admitted client to be in the room")
+	}
+
+	denied, deniedID := join("client-2", "user-2")
+	rm.DenyParticipant(deniedID)
+	if msg := next(denied); msg.Type != MsgDenied {
+		t.Fatalf("Expected %s, got %s", MsgDenied, msg.Type)
+	}
+	if inRoom(denied) {
+		t.Error("Expected the denied client to stay out of the room")
+	}
+
+	// Knocking on a locked room holds the client in the same lobby
+	locked, _ := manager.CreateRoom(&room.CreateRoomRequest{Name: "locked"}, "host")
+	locked.Lock()
+	knocker := &WSClient{id: "client-3", send: make(chan []byte, 16), server: server}
+	knocker.handleMessage(&WSMessage{Type: MsgKnock, Data: mustMarshal(JoinRoomData{RoomID: locked.ID, UserID: "user-3"})})
+	msg := next(knocker)
+	if msg.Type != MsgWaiting {
+		t.Fatalf("Expected %s after knocking, got %s %s", MsgWaiting, msg.Type, msg.Data)
+	}
+	var knocked map[string]string
+	json.Unmarshal(msg.Data, &knocked)
+	if err := locked.AdmitParticipant(knocked["participant_id"]); err != nil {
+		t.Fatalf("Failed to admit the knocker: %v", err)
+	}
+	if msg := next(knocker); msg.Type != MsgJoinRoom {
+		t.Fatalf("Expected %s after admission, got %s", MsgJoinRoom, msg.Type)
+	}
+}
@@ -0,0 +1,437 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+)
+
+// Codec is a wire encoding for signaling messages
+type Codec string
+
+const (
+	// CodecJSON encodes messages as JSON text frames (default)
+	CodecJSON Codec = "json"
+	// CodecMsgPack encodes messages as MessagePack binary frames
+	CodecMsgPack Codec = "msgpack"
+)
+
+// WebSocket subprotocols used to negotiate the codec on connect
+const (
+	SubprotocolJSON    = "zenlive.json"
+	SubprotocolMsgPack = "zenlive.msgpack"
+)
+
+// errInvalidMsgPack is returned for malformed or unsupported MessagePack input
+var errInvalidMsgPack = errors.New("invalid msgpack message")
+
+// negotiateCodec picks the client's codec from the accepted subprotocol or,
+// failing that, the "codec" query parameter
+func negotiateCodec(conn *websocket.Conn, r *http.Request) Codec {
+	switch conn.Subprotocol() {
+	case SubprotocolMsgPack:
+		return CodecMsgPack
+	case SubprotocolJSON:
+		return CodecJSON
+	}
+
+	if Codec(r.URL.Query().Get("codec")) == CodecMsgPack {
+		return CodecMsgPack
+	}
+
+	return CodecJSON
+}
+
+// frameType returns the WebSocket frame type the codec is sent in
+func (c Codec) frameType() int {
+	if c == CodecMsgPack {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+// encode converts an internally JSON-encoded message to the codec's payload
+func (c Codec) encode(message []byte) ([]byte, error) {
+	if c != CodecMsgPack {
+		return message, nil
+	}
+	return jsonToMsgPack(message)
+}
+
+// decodeMessage decodes a received frame into msg. Binary frames are always
+// MessagePack, so a client may send either encoding. MessagePack envelopes
+// are read directly; only the data payload is re-encoded as JSON for the
+// message handlers.
+func decodeMessage(messageType int, message []byte, msg *WSMessage) error {
+	if messageType != websocket.BinaryMessage {
+		return json.Unmarshal(message, msg)
+	}
+
+	reader := &msgPackReader{data: message}
+	value, err := reader.read(0)
+	if err != nil {
+		return err
+	}
+	if reader.pos != len(message) {
+		return errInvalidMsgPack
+	}
+
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return errInvalidMsgPack
+	}
+
+	var decoded WSMessage
+	for key, value := range fields {
+		switch key {
+		case "type":
+			if decoded.Type, ok = value.(string); !ok {
+				return errInvalidMsgPack
+			}
+		case "room_id":
+			if decoded.RoomID, ok = value.(string); !ok {
+				return errInvalidMsgPack
+			}
+		case "seq":
+			switch n := value.(type) {
+			case uint64:
+				decoded.Seq = n
+			case int64:
+				if n < 0 {
+					return errInvalidMsgPack
+				}
+				decoded.Seq = uint64(n)
+			default:
+				return errInvalidMsgPack
+			}
+		case "data":
+			if value == nil {
+				continue
+			}
+			if decoded.Data, err = json.Marshal(value); err != nil {
+				return err
+			}
+		}
+	}
+
+	*msg = decoded
+	return nil
+}
+
+// jsonToMsgPack transcodes a JSON document to MessagePack. Object keys are
+// written in sorted order so the output is deterministic.
+func jsonToMsgPack(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	writeMsgPack(buf, value)
+	return buf.Bytes(), nil
+}
+
+// writeMsgPack appends value, as decoded by encoding/json with UseNumber, to buf
+func writeMsgPack(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			writeMsgPackInt(buf, i)
+		} else if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			buf.WriteByte(0xcf)
+			binary.Write(buf, binary.BigEndian, u)
+		} else {
+			f, _ := v.Float64()
+			buf.WriteByte(0xcb)
+			binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+		}
+	case string:
+		writeMsgPackString(buf, v)
+	case []interface{}:
+		writeMsgPackHeader(buf, len(v), 0x90, 15, 0xdc, 0xdd)
+		for _, item := range v {
+			writeMsgPack(buf, item)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		writeMsgPackHeader(buf, len(v), 0x80, 15, 0xde, 0xdf)
+		for _, key := range keys {
+			writeMsgPackString(buf, key)
+			writeMsgPack(buf, v[key])
+		}
+	}
+}
+
+// writeMsgPackInt appends an integer in its smallest encoding
+func writeMsgPackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= 127:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(int8(i)))
+	case i >= 0 && i <= math.MaxUint8:
+		buf.Write([]byte{0xcc, byte(i)})
+	case i >= 0 && i <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		binary.Write(buf, binary.BigEndian, uint16(i))
+	case i >= 0 && i <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		binary.Write(buf, binary.BigEndian, uint32(i))
+	case i >= math.MinInt8 && i < 0:
+		buf.Write([]byte{0xd0, byte(int8(i))})
+	case i >= math.MinInt16 && i < 0:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i < 0:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+// writeMsgPackString appends a string
+func writeMsgPackString(buf *bytes.Buffer, s string) {
+	switch n := len(s); {
+	case n <= 31:
+		buf.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{0xd9, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xda)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(0xdb)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+	buf.WriteString(s)
+}
+
+// writeMsgPackHeader appends an array or map header of n elements
+func writeMsgPackHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, code16, code32 byte) {
+	switch {
+	case n <= fixMax:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(code32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+// maxMsgPackDepth bounds nesting so hostile input cannot exhaust the stack
+const maxMsgPackDepth = 64
+
+// msgPackReader decodes MessagePack values from a byte slice
+type msgPackReader struct {
+	data []byte
+	pos  int
+}
+
+// next returns the next n bytes
+func (r *msgPackReader) next(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, errInvalidMsgPack
+	}
+
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+// length reads a big-endian length of size bytes
+func (r *msgPackReader) length(size int) (int, error) {
+	b, err := r.next(size)
+	if err != nil {
+		return 0, err
+	}
+
+	switch size {
+	case 1:
+		return int(b[0]), nil
+	case 2:
+		return int(binary.BigEndian.Uint16(b)), nil
+	default:
+		return int(binary.BigEndian.Uint32(b)), nil
+	}
+}
+
+// read decodes one value
+func (r *msgPackReader) read(depth int) (interface{}, error) {
+	if depth > maxMsgPackDepth {
+		return nil, errInvalidMsgPack
+	}
+
+	b, err := r.next(1)
+	if err != nil {
+		return nil, err
+	}
+	code := b[0]
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xf0 == 0x80:
+		return r.readMap(int(code&0x0f), depth)
+	case code&0xf0 == 0x90:
+		return r.readArray(int(code&0x0f), depth)
+	case code&0xe0 == 0xa0:
+		return r.readString(int(code & 0x1f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := r.length(1 << (code - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return r.next(n)
+	case 0xca:
+		b, err := r.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 0xcb:
+		b, err := r.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		b, err := r.next(1 << (code - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		var u uint64
+		for _, c := range b {
+			u = u<<8 | uint64(c)
+		}
+		return u, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		b, err := r.next(size)
+		if err != nil {
+			return nil, err
+		}
+		var u uint64
+		for _, c := range b {
+			u = u<<8 | uint64(c)
+		}
+		// Sign-extend from the encoded width
+		shift := 64 - 8*size
+		return int64(u<<shift) >> shift, nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.length(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return r.readString(n)
+	case 0xdc, 0xdd:
+		n, err := r.length(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return r.readArray(n, depth)
+	case 0xde, 0xdf:
+		n, err := r.length(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return r.readMap(n, depth)
+	}
+
+	// Extension types are not part of the signaling protocol
+	return nil, errInvalidMsgPack
+}
+
+// readString decodes a string of n bytes
+func (r *msgPackReader) readString(n int) (string, error) {
+	b, err := r.next(n)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// readArray decodes n array elements
+func (r *msgPackReader) readArray(n int, depth int) ([]interface{}, error) {
+	if n > len(r.data)-r.pos {
+		return nil, errInvalidMsgPack
+	}
+
+	items := make([]interface{}, n)
+	for i := range items {
+		item, err := r.read(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+
+	return items, nil
+}
+
+// readMap decodes n map entries. Non-string keys are formatted as strings,
+// since JSON objects only have string keys.
+func (r *msgPackReader) readMap(n int, depth int) (map[string]interface{}, error) {
+	if n > len(r.data)-r.pos {
+		return nil, errInvalidMsgPack
+	}
+
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := r.read(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := r.read(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		if s, ok := key.(string); ok {
+			m[s] = value
+		} else {
+			m[fmt.Sprint(key)] = value
+		}
+	}
+
+	return m, nil
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/discovery"
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// DiscoveryHandler serves the directory of live streams
+type DiscoveryHandler struct {
+	index  *discovery.Index
+	logger logger.Logger
+}
+
+// NewDiscoveryHandler creates a handler for a discovery index (nil = not
+// configured, every request is a 404)
+func NewDiscoveryHandler(index *discovery.Index, log logger.Logger) *DiscoveryHandler {
+	return &DiscoveryHandler{
+		index:  index,
+		logger: log,
+	}
+}
+
+// ListLiveStreams handles GET /api/discover/live. Streams are filtered with
+// ?category=, ?tag= (repeatable, all must match), ?q= and ?min_viewers=,
+// ordered with ?sort=viewers|newest and paged with ?offset= and ?limit=.
+func (h *DiscoveryHandler) ListLiveStreams(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	if h.index == nil {
+		writeError(w, http.StatusNotFound, "discovery not configured", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	verr := &ValidationError{}
+
+	filter := discovery.Filter{
+		Category:   query.Get("category"),
+		Tags:       query["tag"],
+		Search:     query.Get("q"),
+		MinViewers: int64(queryInt(verr, query.Get("min_viewers"), "min_viewers")),
+	}
+	page := discovery.Page{
+		Offset: queryInt(verr, query.Get("offset"), "offset"),
+		Limit:  queryInt(verr, query.Get("limit"), "limit"),
+	}
+	order, err := discovery.ParseSortOrder(query.Get("sort"))
+	if err != nil {
+		verr.add("sort", "must be %q or %q", discovery.SortViewers, discovery.SortNewest)
+	}
+	if err := verr.err(); err != nil {
+		writeErrorFor(w, err, "invalid discovery query")
+		return
+	}
+
+	result, err := h.index.GetLiveStreams(filter, order, page)
+	if err != nil {
+		writeErrorFor(w, err, "failed to list live streams")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, result)
+}
+
+// GetTrending handles GET /api/discover/trending. The window defaults to
+// 15 minutes and is set with ?window= as a duration (e.g. "30m"); ?limit=
+// caps the number of streams returned.
+func (h *DiscoveryHandler) GetTrending(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	if h.index == nil {
+		writeError(w, http.StatusNotFound, "discovery not configured", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	verr := &ValidationError{}
+
+	window := 15 * time.Minute
+	if value := query.Get("window"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 || parsed > discovery.MaxTrendingWindow {
+			verr.add("window", "must be a duration up to %s", discovery.MaxTrendingWindow)
+		}
+		window = parsed
+	}
+	limit := queryInt(verr, query.Get("limit"), "limit")
+	if err := verr.err(); err != nil {
+		writeErrorFor(w, err, "invalid trending query")
+		return
+	}
+
+	trending := h.index.GetTrending(window)
+	if limit > 0 && len(trending) > limit {
+		trending = trending[:limit]
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"streams": trending,
+		"window":  window.String(),
+	})
+}
+
+func (h *DiscoveryHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+// queryInt parses a non-negative integer query parameter, recording an
+// invalid value in verr. An empty value is 0.
+func queryInt(verr *ValidationError, value, field string) int {
+	if value == "" {
+		return 0
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		verr.add(field, "must be a non-negative integer")
+		return 0
+	}
+	return n
+}
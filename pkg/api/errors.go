@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aminofox/zenlive/pkg/auth"
+	zerrors "github.com/aminofox/zenlive/pkg/errors"
+	"github.com/aminofox/zenlive/pkg/overlay"
+	"github.com/aminofox/zenlive/pkg/quota"
+	"github.com/aminofox/zenlive/pkg/room"
+	"github.com/aminofox/zenlive/pkg/streaming/webrtc"
+)
+
+// ErrorResponse is the JSON body of every REST API error
+type ErrorResponse struct {
+	// Code is a stable, machine-readable error code such as "not_found"
+	Code string `json:"code"`
+	// Message is a human-readable description of the error
+	Message string `json:"message"`
+	// Details carries additional context, such as the underlying error
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Error codes reported in ErrorResponse.Code
+const (
+	ErrorCodeInvalidRequest   = "invalid_request"
+	ErrorCodeUnauthenticated  = "unauthenticated"
+	ErrorCodePermissionDenied = "permission_denied"
+	ErrorCodeNotFound         = "not_found"
+	ErrorCodeMethodNotAllowed = "method_not_allowed"
+	ErrorCodeConflict         = "conflict"
+	ErrorCodePayloadTooLarge  = "payload_too_large"
+	ErrorCodeRateLimited      = "rate_limited"
+	ErrorCodeInternal         = "internal"
+)
+
+// HTTPStatus maps an error from the room, auth or errors packages to the
+// HTTP status code it should be reported with. Errors are matched with
+// errors.Is and errors.As, so wrapped errors map like their sentinels.
+// Unrecognized errors map to 500.
+func HTTPStatus(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+
+	case errors.As(err, new(*ValidationError)):
+		return http.StatusBadRequest
+
+	case errors.Is(err, room.ErrRoomNotFound),
+		errors.Is(err, room.ErrParticipantNotFound),
+		errors.Is(err, room.ErrTrackNotFound),
+		errors.Is(err, room.ErrTemplateNotFound),
+		errors.Is(err, webrtc.ErrStreamNotFound),
+		errors.Is(err, webrtc.ErrPeerNotFound),
+		errors.Is(err, auth.ErrAPIKeyNotFound):
+		return http.StatusNotFound
+
+	case errors.Is(err, room.ErrRoomExists),
+		errors.Is(err, room.ErrParticipantExists),
+		errors.Is(err, room.ErrTemplateExists),
+		errors.Is(err, room.ErrRoomFull),
+		errors.Is(err, room.ErrRoomClosed),
+		errors.Is(err, room.ErrRoomNotOpen):
+		return http.StatusConflict
+
+	case errors.Is(err, room.ErrUnauthorized),
+		errors.Is(err, room.ErrSubscribeDenied),
+		errors.Is(err, auth.ErrBindingMismatch),
+		errors.Is(err, auth.ErrDelegationNotAllowed),
+		errors.Is(err, auth.ErrScopeExceedsGrant):
+		return http.StatusForbidden
+
+	case errors.Is(err, auth.ErrInvalidToken),
+		errors.Is(err, auth.ErrTokenExpired),
+		errors.Is(err, auth.ErrTokenNotYetValid),
+		errors.Is(err, auth.ErrTokenAlreadyUsed),
+		errors.Is(err, auth.ErrInvalidAPIKeyFormat):
+		return http.StatusUnauthorized
+
+	case errors.Is(err, room.ErrInvalidRoomRequest),
+		errors.Is(err, room.ErrInvalidMetadata),
+		errors.Is(err, room.ErrUnknownPermission),
+		errors.Is(err, room.ErrInvalidPlaybackState),
+		errors.Is(err, room.ErrInvalidSharedStateKey):
+		return http.StatusBadRequest
+
+	case errors.Is(err, room.ErrMetadataTooLarge):
+		return http.StatusRequestEntityTooLarge
+
+	case errors.Is(err, quota.ErrQuotaExceeded),
+		errors.Is(err, overlay.ErrTooManySubscribers):
+		return http.StatusTooManyRequests
+	}
+
+	switch zerrors.GetErrorCode(err) {
+	case zerrors.ErrCodeNotFound, zerrors.ErrCodeRoomNotFound, zerrors.ErrCodeParticipantNotFound,
+		zerrors.ErrCodeSessionNotFound, zerrors.ErrCodeStreamNotFound, zerrors.ErrCodeFileNotFound:
+		return http.StatusNotFound
+	case zerrors.ErrCodeUnauthorized:
+		return http.StatusForbidden
+	case zerrors.ErrCodeAuthenticationFailed, zerrors.ErrCodeInvalidToken,
+		zerrors.ErrCodeTokenExpired, zerrors.ErrCodeInvalidCredentials:
+		return http.StatusUnauthorized
+	case zerrors.ErrCodeRateLimitExceeded, zerrors.ErrCodeRoomLimitExceeded, zerrors.ErrCodeTrackLimitExceeded:
+		return http.StatusTooManyRequests
+	case zerrors.ErrCodeValidationFailed, zerrors.ErrCodeInvalidInput, zerrors.ErrCodeMissingParameter:
+		return http.StatusBadRequest
+	case zerrors.ErrCodeInvalidState:
+		return http.StatusConflict
+	}
+
+	return http.StatusInternalServerError
+}
+
+// ErrorCode returns the ErrorResponse code for an HTTP status
+func ErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrorCodeInvalidRequest
+	case http.StatusUnauthorized:
+		return ErrorCodeUnauthenticated
+	case http.StatusForbidden:
+		return ErrorCodePermissionDenied
+	case http.StatusNotFound:
+		return ErrorCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return ErrorCodeMethodNotAllowed
+	case http.StatusConflict:
+		return ErrorCodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return ErrorCodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return ErrorCodeRateLimited
+	}
+
+	return ErrorCodeInternal
+}
+
+// writeError writes an ErrorResponse with the given status
+func writeError(w http.ResponseWriter, status int, message string, details map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Code:    ErrorCode(status),
+		Message: message,
+		Details: details,
+	})
+}
+
+// writeErrorFor writes the ErrorResponse for err, with the status chosen by
+// HTTPStatus. The error text is included in the details unless the status
+// is a server error, so internal failures are not exposed to clients.
+// Validation errors list their invalid fields.
+func writeErrorFor(w http.ResponseWriter, err error, message string) {
+	status := HTTPStatus(err)
+
+	var details map[string]interface{}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		details = map[string]interface{}{"fields": verr.Fields}
+	} else if status < http.StatusInternalServerError {
+		details = map[string]interface{}{"error": err.Error()}
+	}
+
+	writeError(w, status, message, details)
+}
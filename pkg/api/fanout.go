@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bytes"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+const (
+	// DefaultFanoutWorkers is the number of broadcast delivery workers
+	DefaultFanoutWorkers = 8
+
+	// DefaultFanoutQueueSize is the number of broadcasts queued per worker
+	DefaultFanoutQueueSize = 1024
+
+	// DefaultCoalesceInterval is how often coalesced state broadcasts are sent
+	DefaultCoalesceInterval = 50 * time.Millisecond
+)
+
+// fanoutJob is a serialized broadcast waiting for delivery
+type fanoutJob struct {
+	message []byte
+	// seq is the room event sequence of the message (0 = not a room event)
+	seq     uint64
+	clients []*WSClient
+	// frames caches the message encoded per codec, so it is encoded once
+	// per codec rather than once per client
+	frames map[Codec][]byte
+}
+
+// frame returns the job's message encoded for a codec
+func (j *fanoutJob) frame(codec Codec) ([]byte, error) {
+	if frame, ok := j.frames[codec]; ok {
+		return frame, nil
+	}
+
+	frame, err := codec.encode(j.message)
+	if err != nil {
+		return nil, err
+	}
+	j.frames[codec] = frame
+	return frame, nil
+}
+
+// coalescedState is the latest state broadcast for one room and key
+type coalescedState struct {
+	roomID    string
+	message   []byte
+	exclude   string
+	delivered []byte
+	pending   bool
+}
+
+// fanout delivers room broadcasts off the caller's goroutine. Broadcasts of
+// a room always go to the same worker, so clients receive them in order.
+// Each worker writes the one serialized message to every client without
+// blocking; a client whose buffer is full is disconnected.
+type fanout struct {
+	server *SignalingServer
+	queues []chan fanoutJob
+
+	// states holds coalesced state broadcasts by room ID + "\x00" + key
+	states   map[string]*coalescedState
+	statesMu sync.Mutex
+
+	// workers tracks the delivery workers and coalescing ticker
+	workers sync.WaitGroup
+}
+
+// newFanout starts the delivery workers and coalescing ticker; they stop
+// when the server's done channel is closed
+func newFanout(s *SignalingServer, workers, queueSize int, interval time.Duration) *fanout {
+	f := &fanout{
+		server: s,
+		queues: make([]chan fanoutJob, workers),
+		states: make(map[string]*coalescedState),
+	}
+
+	f.workers.Add(len(f.queues) + 1)
+	for i := range f.queues {
+		f.queues[i] = make(chan fanoutJob, queueSize)
+		go f.run(f.queues[i])
+	}
+	go f.runCoalescing(interval)
+
+	return f
+}
+
+// wait blocks until the workers and coalescing ticker have stopped
+func (f *fanout) wait() {
+	f.workers.Wait()
+}
+
+// enqueue queues a message for delivery to a snapshot of the room's clients.
+// seq is the message's room event sequence, or 0. It blocks only when the
+// room's worker queue is full.
+func (f *fanout) enqueue(roomID string, message []byte, seq uint64, excludeClientID string) {
+	f.server.mu.RLock()
+	roomClients := f.server.roomClients[roomID]
+	clients := make([]*WSClient, 0, len(roomClients))
+	for clientID, client := range roomClients {
+		if excludeClientID == "" || clientID != excludeClientID {
+			clients = append(clients, client)
+		}
+	}
+	f.server.mu.RUnlock()
+
+	if len(clients) == 0 {
+		return
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(roomID))
+	queue := f.queues[h.Sum32()%uint32(len(f.queues))]
+
+	select {
+	case queue <- fanoutJob{message: message, seq: seq, clients: clients, frames: make(map[Codec][]byte, 2)}:
+	case <-f.server.done:
+	}
+}
+
+// run delivers queued broadcasts
+func (f *fanout) run(queue chan fanoutJob) {
+	defer f.workers.Done()
+
+	for {
+		select {
+		case <-f.server.done:
+			return
+		case job := <-queue:
+			for _, client := range job.clients {
+				frame, err := job.frame(client.codec)
+				if err != nil {
+					f.server.logger.Error("Failed to encode broadcast", logger.Err(err))
+					continue
+				}
+				if !client.deliverBroadcast(frame, job.seq) {
+					go f.server.unregisterClient(client)
+				}
+			}
+		}
+	}
+}
+
+// coalesce records the latest state broadcast for a room and key, to be
+// sent on the next tick
+func (f *fanout) coalesce(roomID, key string, message []byte, excludeClientID string) {
+	f.statesMu.Lock()
+	defer f.statesMu.Unlock()
+
+	stateKey := roomID + "\x00" + key
+	state, exists := f.states[stateKey]
+	if !exists {
+		state = &coalescedState{roomID: roomID}
+		f.states[stateKey] = state
+	}
+
+	state.message = message
+	state.exclude = excludeClientID
+	state.pending = true
+}
+
+// runCoalescing sends the pending state broadcasts every interval
+func (f *fanout) runCoalescing(interval time.Duration) {
+	defer f.workers.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.server.done:
+			return
+		case <-ticker.C:
+			f.flushStates()
+		}
+	}
+}
+
+// flushStates sends each pending state broadcast unless it is identical to
+// the last one sent for its key
+func (f *fanout) flushStates() {
+	type delivery struct {
+		roomID, exclude string
+		message         []byte
+	}
+
+	f.statesMu.Lock()
+	var deliveries []delivery
+	for _, state := range f.states {
+		if !state.pending {
+			continue
+		}
+		state.pending = false
+		if bytes.Equal(state.message, state.delivered) {
+			continue
+		}
+		state.delivered = state.message
+		deliveries = append(deliveries, delivery{state.roomID, state.exclude, state.message})
+	}
+	f.statesMu.Unlock()
+
+	for _, d := range deliveries {
+		f.enqueue(d.roomID, d.message, 0, d.exclude)
+	}
+}
+
+// dropRoom forgets a room's coalesced states
+func (f *fanout) dropRoom(roomID string) {
+	f.statesMu.Lock()
+	defer f.statesMu.Unlock()
+
+	for key, state := range f.states {
+		if state.roomID == roomID {
+			delete(f.states, key)
+		}
+	}
+}
+
+// BroadcastStateToRoom broadcasts a state snapshot in which only the latest
+// value matters (e.g. the active speaker). Snapshots with the same key are
+// coalesced: at most one is sent per tick, and one identical to the last
+// sent is dropped.
+func (s *SignalingServer) BroadcastStateToRoom(roomID, key string, msg *WSMessage, excludeClientID string) {
+	s.fanout.coalesce(roomID, key, mustMarshal(msg), excludeClientID)
+}
+
+// enqueue encodes a JSON message in the client's codec and queues it on
+// the client's send buffer without blocking. It reports false when the
+// buffer is full; messages to a closed client or that fail to encode are
+// dropped.
+func (c *WSClient) enqueue(message []byte) bool {
+	frame, err := c.codec.encode(message)
+	if err != nil {
+		c.server.logger.Error("Failed to encode message", logger.Err(err))
+		return true
+	}
+
+	return c.enqueueFrame(frame)
+}
+
+// enqueueFrame queues a message already encoded in the client's codec, as
+// enqueue does
+func (c *WSClient) enqueueFrame(frame []byte) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.closed {
+		return true
+	}
+
+	select {
+	case c.send <- frame:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// DefaultHeartbeatTimeout is how long a client may stay silent before it is removed
+const DefaultHeartbeatTimeout = 30 * time.Second
+
+// heartbeatCheckInterval is how often stale participants are swept
+const heartbeatCheckInterval = 5 * time.Second
+
+// sessionRefreshInterval is the least time between refreshes of a client's
+// cluster session, well within practical session TTLs
+const sessionRefreshInterval = 10 * time.Second
+
+// Reasons attached to participant.left events
+const (
+	LeaveReasonLeft         = "left"
+	LeaveReasonDisconnected = "disconnected"
+	LeaveReasonTimeout      = "timeout"
+	LeaveReasonKicked       = "kicked"
+)
+
+// StaleParticipant describes a participant whose heartbeat has lapsed
+type StaleParticipant struct {
+	ClientID      string    `json:"client_id"`
+	RoomID        string    `json:"room_id"`
+	ParticipantID string    `json:"participant_id"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+}
+
+// SetHeartbeatTimeout sets how long a client may go without a ping (or any
+// other message) before its participant is removed. A timeout of 0 disables it.
+func (s *SignalingServer) SetHeartbeatTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.heartbeatTimeout = timeout
+}
+
+// GetStaleParticipants returns participants whose heartbeat has lapsed
+func (s *SignalingServer) GetStaleParticipants() []StaleParticipant {
+	s.mu.RLock()
+	timeout := s.heartbeatTimeout
+	clients := make([]*WSClient, 0, len(s.clients))
+	for _, client := range s.clients {
+		clients = append(clients, client)
+	}
+	s.mu.RUnlock()
+
+	if timeout <= 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(-timeout)
+	stale := make([]StaleParticipant, 0)
+
+	for _, client := range clients {
+		client.mu.RLock()
+		if client.roomID != "" && client.lastHeartbeat.Before(deadline) {
+			stale = append(stale, StaleParticipant{
+				ClientID:      client.id,
+				RoomID:        client.roomID,
+				ParticipantID: client.participantID,
+				LastHeartbeat: client.lastHeartbeat,
+			})
+		}
+		client.mu.RUnlock()
+	}
+
+	return stale
+}
+
+// monitorHeartbeats periodically removes participants whose heartbeat has lapsed
+func (s *SignalingServer) monitorHeartbeats() {
+	ticker := time.NewTicker(heartbeatCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.expireStaleParticipants()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// expireStaleParticipants removes stale participants and closes their connections
+func (s *SignalingServer) expireStaleParticipants() {
+	for _, stale := range s.GetStaleParticipants() {
+		s.mu.RLock()
+		client, ok := s.clients[stale.ClientID]
+		s.mu.RUnlock()
+
+		if !ok {
+			continue
+		}
+
+		// Detach the client first so unregisterClient doesn't announce a second departure
+		client.mu.Lock()
+		client.roomID = ""
+		client.participantID = ""
+		client.mu.Unlock()
+
+		s.removeFromRoom(client, stale.RoomID, stale.ParticipantID, LeaveReasonTimeout)
+
+		s.logger.Info("Participant heartbeat timed out",
+			logger.String("room_id", stale.RoomID),
+			logger.String("participant_id", stale.ParticipantID),
+		)
+
+		if client.conn != nil {
+			client.conn.Close()
+		}
+	}
+}
+
+// touch records a heartbeat from the client and keeps the cluster session
+// of a joined client from expiring
+func (c *WSClient) touch() {
+	now := time.Now()
+
+	c.mu.Lock()
+	c.lastHeartbeat = now
+	refresh := c.roomID != "" && now.Sub(c.sessionRefreshedAt) >= sessionRefreshInterval
+	if refresh {
+		c.sessionRefreshedAt = now
+	}
+	c.mu.Unlock()
+
+	if refresh {
+		c.server.refreshSession(c)
+	}
+}
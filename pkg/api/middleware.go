@@ -2,8 +2,13 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -18,11 +23,18 @@ type ContextKey string
 const (
 	// ContextKeyClaims is the key for storing claims in context
 	ContextKeyClaims ContextKey = "claims"
+	// ContextKeyAPIKey is the key for storing the API key of a signed request in context
+	ContextKeyAPIKey ContextKey = "api_key"
 )
 
+// maxSignedBodySize limits the body read when verifying a signed request
+const maxSignedBodySize = 10 << 20
+
 // AuthMiddleware provides JWT authentication middleware
 type AuthMiddleware struct {
 	jwtAuth *auth.JWTAuthenticator
+	apiKeys *auth.APIKeyManager
+	maxSkew time.Duration
 	logger  logger.Logger
 }
 
@@ -34,9 +46,22 @@ func NewAuthMiddleware(jwtAuth *auth.JWTAuthenticator, log logger.Logger) *AuthM
 	}
 }
 
-// Authenticate validates JWT tokens from Authorization header
+// SetAPIKeyManager enables API key request signing as an alternative to JWTs
+// for server-to-server calls
+func (m *AuthMiddleware) SetAPIKeyManager(apiKeys *auth.APIKeyManager, maxSkew time.Duration) {
+	m.apiKeys = apiKeys
+	m.maxSkew = maxSkew
+}
+
+// Authenticate validates JWT tokens from Authorization header, or the
+// signature headers of a signed request when API keys are enabled
 func (m *AuthMiddleware) Authenticate(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if m.apiKeys != nil && r.Header.Get(auth.HeaderAccessKey) != "" {
+			m.authenticateSigned(next, w, r)
+			return
+		}
+
 		// Extract token from Authorization header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -67,12 +92,58 @@ func (m *AuthMiddleware) Authenticate(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// authenticateSigned verifies a request signed with an API secret key
+func (m *AuthMiddleware) authenticateSigned(next http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	unix, err := strconv.ParseInt(r.Header.Get(auth.HeaderTimestamp), 10, 64)
+	if err != nil {
+		m.sendError(w, http.StatusUnauthorized, "invalid request timestamp")
+		return
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, err = io.ReadAll(io.LimitReader(r.Body, maxSignedBodySize+1))
+		if err != nil || len(body) > maxSignedBodySize {
+			m.sendError(w, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	apiKey, err := m.apiKeys.VerifyRequestSignature(
+		r.Context(),
+		r.Header.Get(auth.HeaderAccessKey),
+		r.Header.Get(auth.HeaderSignature),
+		r.Method,
+		r.URL.RequestURI(),
+		body,
+		time.Unix(unix, 0),
+		m.maxSkew,
+	)
+	if err != nil {
+		m.logger.Warn("Request signature verification failed", logger.Err(err))
+		m.sendError(w, http.StatusUnauthorized, "invalid request signature")
+		return
+	}
+
+	// Add API key to request context
+	ctx := context.WithValue(r.Context(), ContextKeyAPIKey, apiKey)
+	next(w, r.WithContext(ctx))
+}
+
 // GetClaims extracts claims from request context
 func GetClaims(r *http.Request) (*auth.TokenClaims, bool) {
 	claims, ok := r.Context().Value(ContextKeyClaims).(*auth.TokenClaims)
 	return claims, ok
 }
 
+// GetAPIKey extracts the API key of a signed request from request context
+func GetAPIKey(r *http.Request) (*auth.APIKey, bool) {
+	apiKey, ok := r.Context().Value(ContextKeyAPIKey).(*auth.APIKey)
+	return apiKey, ok
+}
+
 // RateLimiter provides rate limiting middleware
 type RateLimiter struct {
 	mu      sync.RWMutex
@@ -82,6 +153,9 @@ type RateLimiter struct {
 	// Configuration
 	requestsPerMinute int
 	cleanupInterval   time.Duration
+
+	// proxies may report the client IP in forwarding headers (nil = none)
+	proxies *TrustedProxies
 }
 
 type clientLimiter struct {
@@ -104,11 +178,17 @@ func NewRateLimiter(requestsPerMinute int, log logger.Logger) *RateLimiter {
 	return rl
 }
 
+// SetTrustedProxies lets the listed proxies report the client IP that
+// requests are limited by
+func (rl *RateLimiter) SetTrustedProxies(proxies *TrustedProxies) {
+	rl.proxies = proxies
+}
+
 // Limit applies rate limiting based on client IP
 func (rl *RateLimiter) Limit(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Get client IP
-		clientIP := getClientIP(r)
+		clientIP := rl.proxies.ClientIP(r)
 
 		// Check rate limit
 		if !rl.allow(clientIP) {
@@ -176,51 +256,113 @@ func (rl *RateLimiter) cleanup() {
 	}
 }
 
-// getClientIP extracts client IP from request
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header first
-	forwarded := r.Header.Get("X-Forwarded-For")
-	if forwarded != "" {
-		// Take the first IP in the list
-		ips := strings.Split(forwarded, ",")
-		return strings.TrimSpace(ips[0])
+// TrustedProxies resolves the client IP of a request. X-Forwarded-For and
+// X-Real-IP can be set by any client, so they are only believed when the
+// request comes from one of the listed proxies.
+type TrustedProxies struct {
+	networks []*net.IPNet
+}
+
+// NewTrustedProxies parses the proxies allowed to report client IPs, given
+// as IP addresses or CIDR ranges
+func NewTrustedProxies(proxies []string) (*TrustedProxies, error) {
+	tp := &TrustedProxies{}
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			tp.networks = append(tp.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		tp.networks = append(tp.networks, network)
 	}
+	return tp, nil
+}
 
-	// Check X-Real-IP header
-	realIP := r.Header.Get("X-Real-IP")
-	if realIP != "" {
-		return realIP
+// trusts reports whether ip belongs to a trusted proxy
+func (tp *TrustedProxies) trusts(ip string) bool {
+	if tp == nil {
+		return false
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range tp.networks {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the IP of the client that sent r. The peer address is
+// used unless it is a trusted proxy, in which case X-Forwarded-For is walked
+// from the right past further trusted proxies, then X-Real-IP is consulted.
+// A nil TrustedProxies trusts no proxy.
+func (tp *TrustedProxies) ClientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if !tp.trusts(remote) {
+		return remote
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			if i == 0 || !tp.trusts(hop) {
+				return hop
+			}
+		}
 	}
 
-	// Fall back to RemoteAddr
-	ip := r.RemoteAddr
-	// Remove port if present
-	if idx := strings.LastIndex(ip, ":"); idx != -1 {
-		ip = ip[:idx]
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
 	}
-	return ip
+
+	return remote
 }
 
 // Helper methods
 
 func (m *AuthMiddleware) sendError(w http.ResponseWriter, status int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
+	writeError(w, status, message, nil)
 }
 
 func (rl *RateLimiter) sendError(w http.ResponseWriter, status int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
+	writeError(w, status, message, nil)
 }
 
 // CORS middleware for cross-origin requests
 type CORSMiddleware struct {
-	allowedOrigins []string
-	allowedMethods []string
-	allowedHeaders []string
+	allowedOrigins   []string
+	allowedMethods   []string
+	allowedHeaders   []string
+	allowCredentials bool
+	maxAge           time.Duration
 }
 
-// NewCORSMiddleware creates a new CORS middleware
+// NewCORSMiddleware creates a new CORS middleware.
+// Origins may be exact ("https://app.example.com"), wildcard subdomains
+// ("https://*.example.com"), or "*" to allow any origin.
 func NewCORSMiddleware(origins, methods, headers []string) *CORSMiddleware {
 	return &CORSMiddleware{
 		allowedOrigins: origins,
@@ -229,21 +371,61 @@ func NewCORSMiddleware(origins, methods, headers []string) *CORSMiddleware {
 	}
 }
 
+// SetAllowCredentials enables Access-Control-Allow-Credentials for origins
+// matched by an explicit allowlist entry. Origins matched only by "*" never
+// get credentials, so any website can't make credentialed calls.
+func (cm *CORSMiddleware) SetAllowCredentials(allow bool) {
+	cm.allowCredentials = allow
+}
+
+// SetMaxAge sets how long browsers may cache preflight responses (0 = no header)
+func (cm *CORSMiddleware) SetMaxAge(maxAge time.Duration) {
+	cm.maxAge = maxAge
+}
+
 // Handle applies CORS headers
 func (cm *CORSMiddleware) Handle(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
 		origin := r.Header.Get("Origin")
-		if origin != "" && cm.isOriginAllowed(origin) {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
+
+		// Responses differ per origin, so caches must key on it
+		w.Header().Add("Vary", "Origin")
+
+		// Not a cross-origin request
+		if origin == "" {
+			next(w, r)
+			return
 		}
 
-		w.Header().Set("Access-Control-Allow-Methods", strings.Join(cm.allowedMethods, ", "))
-		w.Header().Set("Access-Control-Allow-Headers", strings.Join(cm.allowedHeaders, ", "))
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		allowed, explicit := cm.matchOrigin(origin)
+		if !allowed {
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next(w, r)
+			return
+		}
+
+		// Explicitly allowed origins are echoed, since browsers reject "*"
+		// combined with credentials; origins allowed only by "*" get "*"
+		// and no credentials
+		if explicit {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if cm.allowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
 
 		// Handle preflight requests
 		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(cm.allowedMethods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(cm.allowedHeaders, ", "))
+			if cm.maxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(cm.maxAge.Seconds())))
+			}
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
@@ -252,11 +434,29 @@ func (cm *CORSMiddleware) Handle(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-func (cm *CORSMiddleware) isOriginAllowed(origin string) bool {
-	for _, allowed := range cm.allowedOrigins {
-		if allowed == "*" || allowed == origin {
-			return true
+// matchOrigin reports whether an origin is allowed, and whether it is
+// allowed by an exact or wildcard subdomain entry rather than only by "*"
+func (cm *CORSMiddleware) matchOrigin(origin string) (allowed, explicit bool) {
+	for _, entry := range cm.allowedOrigins {
+		if entry == "*" {
+			allowed = true
+			continue
+		}
+		if strings.EqualFold(entry, origin) {
+			return true, true
+		}
+
+		// Wildcard subdomain match, e.g. https://*.example.com
+		if idx := strings.Index(entry, "*."); idx != -1 {
+			scheme := entry[:idx]
+			suffix := entry[idx+1:]
+			lower := strings.ToLower(origin)
+			if strings.HasPrefix(lower, strings.ToLower(scheme)) &&
+				strings.HasSuffix(lower, strings.ToLower(suffix)) &&
+				len(lower) > len(scheme)+len(suffix) {
+				return true, true
+			}
 		}
 	}
-	return false
+	return allowed, false
 }
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/cluster"
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// MigrateData tells a client to renegotiate its media against another SFU
+type MigrateData struct {
+	// NodeID is the SFU node taking over
+	NodeID string `json:"node_id"`
+	// Address is the address of the SFU on that node
+	Address string `json:"address"`
+	// ICERestart asks the client to send a new offer with an ICE restart
+	ICERestart bool `json:"ice_restart"`
+}
+
+// SetClusterSessions records each joined client as a session on the SFU
+// node nodeID, so a MigrationCoordinator can move it when the node fails.
+// Session IDs are client IDs.
+func (s *SignalingServer) SetClusterSessions(sessions cluster.SessionManager, nodeID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions = sessions
+	s.nodeID = nodeID
+}
+
+// trackSession records a joined client in the cluster session manager
+func (s *SignalingServer) trackSession(c *WSClient, roomID, participantID, userID string) {
+	s.mu.RLock()
+	sessions := s.sessions
+	nodeID := s.nodeID
+	s.mu.RUnlock()
+
+	if sessions == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.sessionRefreshedAt = time.Now()
+	c.mu.Unlock()
+
+	err := sessions.CreateSession(context.Background(), &cluster.Session{
+		ID:       c.id,
+		UserID:   userID,
+		StreamID: roomID,
+		NodeID:   nodeID,
+		Data:     map[string]interface{}{"participant_id": participantID},
+	})
+	if err != nil {
+		s.logger.Warn("Failed to record cluster session",
+			logger.String("client_id", c.id),
+			logger.Err(err),
+		)
+	}
+}
+
+// refreshSession extends a joined client's cluster session, so it does not
+// expire while the client is connected
+func (s *SignalingServer) refreshSession(c *WSClient) {
+	s.mu.RLock()
+	sessions := s.sessions
+	s.mu.RUnlock()
+
+	if sessions == nil {
+		return
+	}
+
+	if err := sessions.RefreshSession(context.Background(), c.id); err != nil {
+		s.logger.Warn("Failed to refresh cluster session",
+			logger.String("client_id", c.id),
+			logger.Err(err),
+		)
+	}
+}
+
+// untrackSession removes a client's cluster session
+func (s *SignalingServer) untrackSession(c *WSClient) {
+	s.mu.RLock()
+	sessions := s.sessions
+	s.mu.RUnlock()
+
+	if sessions != nil {
+		sessions.DeleteSession(context.Background(), c.id)
+	}
+}
+
+// NotifyMigration tells the client of a migrated session to renegotiate
+// against the new SFU node. The participant stays in its room throughout,
+// so no leave or join is broadcast. Register it with
+// MigrationCoordinator.OnParticipantMigrated; migrations of clients
+// connected to other signaling nodes are ignored.
+func (s *SignalingServer) NotifyMigration(migration cluster.Migration) {
+	s.mu.RLock()
+	client, exists := s.clients[migration.SessionID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return
+	}
+
+	client.mu.RLock()
+	roomID := client.roomID
+	client.mu.RUnlock()
+
+	s.logger.Info("Migrating participant to healthy SFU",
+		logger.String("client_id", client.id),
+		logger.String("room_id", roomID),
+		logger.String("from_node", migration.FromNode),
+		logger.String("to_node", migration.ToNode),
+	)
+
+	client.sendMessage(&WSMessage{
+		Type:   MsgMigrate,
+		RoomID: roomID,
+		Data: mustMarshal(MigrateData{
+			NodeID:     migration.ToNode,
+			Address:    migration.Address,
+			ICERestart: true,
+		}),
+	})
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/overlay"
+)
+
+// overlayKeepAlive is the interval of SSE comments that keep idle overlay
+// connections open through proxies
+const overlayKeepAlive = 15 * time.Second
+
+// OverlayHandler streams overlay alerts to streamers' overlay clients
+type OverlayHandler struct {
+	manager *overlay.Manager
+	logger  logger.Logger
+}
+
+// NewOverlayHandler creates a handler for an overlay manager (nil = not
+// configured, every request is a 404)
+func NewOverlayHandler(manager *overlay.Manager, log logger.Logger) *OverlayHandler {
+	return &OverlayHandler{
+		manager: manager,
+		logger:  log,
+	}
+}
+
+// StreamAlerts handles GET /api/overlay/:streamID/alerts?key=... as a
+// server-sent event stream with one "alert" event per alert, in the order
+// and at the pace the overlay should show them. The key is the stream's
+// overlay key from overlay.Manager.Key.
+func (h *OverlayHandler) StreamAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	if h.manager == nil {
+		writeError(w, http.StatusNotFound, "overlay not configured", nil)
+		return
+	}
+
+	streamID, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/overlay/"), "/alerts")
+	if !ok || streamID == "" || strings.Contains(streamID, "/") {
+		writeError(w, http.StatusNotFound, "not found", nil)
+		return
+	}
+
+	if err := h.manager.CheckKey(streamID, r.URL.Query().Get(overlay.KeyParam)); err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid overlay key", nil)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported", nil)
+		return
+	}
+
+	sub, err := h.manager.Subscribe(streamID)
+	if err != nil {
+		writeErrorFor(w, err, "failed to subscribe to alerts")
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(overlayKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case alert, ok := <-sub.C:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(alert)
+			if err != nil {
+				h.logger.Error("Failed to encode overlay alert",
+					logger.String("alert_id", alert.ID),
+					logger.Err(err),
+				)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: alert\ndata: %s\n\n", alert.ID, data); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
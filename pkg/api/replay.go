@@ -0,0 +1,197 @@
+package api
+
+import "sync"
+
+// DefaultReplayBufferSize is the number of room events retained per room for replay
+const DefaultReplayBufferSize = 256
+
+// replayEntry is a room event message with its sequence number
+type replayEntry struct {
+	seq     uint64
+	message []byte
+}
+
+// replayBuffer is a bounded per-room history of room events
+type replayBuffer struct {
+	capacity int
+	entries  []replayEntry
+	lastSeq  uint64
+	mu       sync.Mutex
+}
+
+// newReplayBuffer creates a replay buffer holding at most capacity events
+func newReplayBuffer(capacity int) *replayBuffer {
+	return &replayBuffer{
+		capacity: capacity,
+		entries:  make([]replayEntry, 0, capacity),
+	}
+}
+
+// append assigns the next sequence number to msg, stores it and returns the
+// encoded message
+func (b *replayBuffer) append(msg *WSMessage) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastSeq++
+	msg.Seq = b.lastSeq
+	message := mustMarshal(msg)
+
+	if b.capacity <= 0 {
+		return message
+	}
+
+	if len(b.entries) >= b.capacity {
+		copy(b.entries, b.entries[1:])
+		b.entries = b.entries[:len(b.entries)-1]
+	}
+	b.entries = append(b.entries, replayEntry{seq: b.lastSeq, message: message})
+
+	return message
+}
+
+// since returns the buffered entries with a sequence greater than seq.
+// complete is false when events after seq have already been evicted.
+func (b *replayBuffer) since(seq uint64) (entries []replayEntry, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete = true
+	if seq < b.lastSeq && (len(b.entries) == 0 || b.entries[0].seq > seq+1) {
+		complete = false
+	}
+
+	for _, entry := range b.entries {
+		if entry.seq > seq {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, complete
+}
+
+// sequence returns the last assigned sequence number
+func (b *replayBuffer) sequence() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.lastSeq
+}
+
+// SetReplayBufferSize sets how many room events are retained per room for
+// clients resuming with resume_from. A size of 0 disables replay.
+func (s *SignalingServer) SetReplayBufferSize(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.replaySize = size
+}
+
+// getReplayBuffer returns the replay buffer for a room, creating it if needed
+func (s *SignalingServer) getReplayBuffer(roomID string) *replayBuffer {
+	s.mu.RLock()
+	buf, ok := s.replay[roomID]
+	s.mu.RUnlock()
+
+	if ok {
+		return buf
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if buf, ok := s.replay[roomID]; ok {
+		return buf
+	}
+
+	buf = newReplayBuffer(s.replaySize)
+	s.replay[roomID] = buf
+
+	return buf
+}
+
+// dropReplayBuffer discards the replay history for a room
+func (s *SignalingServer) dropReplayBuffer(roomID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.replay, roomID)
+}
+
+// heldBroadcast is a room broadcast held back while a client replays
+type heldBroadcast struct {
+	frame []byte
+	seq   uint64 // room event sequence (0 = not a room event)
+}
+
+// replayEvents sends the client all buffered room events after seq, then
+// the broadcasts held back meanwhile, and reports whether the replay
+// covered every missed event
+func (c *WSClient) replayEvents(roomID string, seq uint64) bool {
+	entries, complete := c.server.getReplayBuffer(roomID).since(seq)
+
+	lastSeq := seq
+	for _, entry := range entries {
+		if !c.enqueue(entry.message) {
+			go c.server.unregisterClient(c)
+			return false
+		}
+		lastSeq = entry.seq
+	}
+
+	if !c.endReplay(lastSeq) {
+		go c.server.unregisterClient(c)
+		return false
+	}
+
+	return complete
+}
+
+// deliverBroadcast queues a room broadcast encoded in the client's codec.
+// While the client replays missed events the broadcast is held back, so
+// live events never overtake replayed ones. It reports false when the
+// client cannot keep up.
+func (c *WSClient) deliverBroadcast(frame []byte, seq uint64) bool {
+	c.mu.Lock()
+	if c.replaying {
+		defer c.mu.Unlock()
+
+		if len(c.held) >= cap(c.send) {
+			return false
+		}
+		c.held = append(c.held, heldBroadcast{frame: frame, seq: seq})
+		return true
+	}
+	c.mu.Unlock()
+
+	return c.enqueueFrame(frame)
+}
+
+// endReplay stops holding broadcasts back and queues the held ones, except
+// room events already replayed up to lastSeq. It reports false when the
+// send buffer is full.
+func (c *WSClient) endReplay(lastSeq uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	held := c.held
+	c.held = nil
+	c.replaying = false
+
+	for _, broadcast := range held {
+		if c.closed {
+			return true
+		}
+		if broadcast.seq != 0 && broadcast.seq <= lastSeq {
+			continue
+		}
+
+		select {
+		case c.send <- broadcast.frame:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
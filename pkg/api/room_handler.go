@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/quota"
 	"github.com/aminofox/zenlive/pkg/room"
+	"github.com/aminofox/zenlive/pkg/types"
 )
 
 // RoomHandler handles HTTP requests for room management
@@ -31,6 +33,9 @@ type CreateRoomRequest struct {
 	EmptyTimeout    int                    `json:"empty_timeout,omitempty"` // seconds
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
 	CreatedBy       string                 `json:"created_by"`
+	// Localized holds translations of localized fields (e.g. "title" -> "ja" -> text)
+	Localized       map[string]types.LocalizedText `json:"localized,omitempty"`
+	DefaultLanguage string                         `json:"default_language,omitempty"`
 }
 
 // RoomResponse represents a room in API responses
@@ -42,6 +47,9 @@ type RoomResponse struct {
 	MaxParticipants  int                    `json:"max_participants"`
 	ParticipantCount int                    `json:"participant_count"`
 	Metadata         map[string]interface{} `json:"metadata,omitempty"`
+	// Title and Description are localized to the requested language
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
 }
 
 // ParticipantResponse represents a participant in API responses
@@ -64,13 +72,6 @@ type AddParticipantRequest struct {
 	Metadata      map[string]interface{} `json:"metadata,omitempty"`
 }
 
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-}
-
 // CreateRoom handles POST /api/rooms
 func (h *RoomHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -79,19 +80,8 @@ func (h *RoomHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req CreateRoomRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, http.StatusBadRequest, "invalid request body")
-		return
-	}
-
-	// Validate request
-	if req.Name == "" {
-		h.sendError(w, http.StatusBadRequest, "room name is required")
-		return
-	}
-
-	if req.CreatedBy == "" {
-		h.sendError(w, http.StatusBadRequest, "created_by is required")
+	if err := decodeRequest(r, &req); err != nil {
+		h.sendErrorFor(w, err, "invalid request body")
 		return
 	}
 
@@ -101,13 +91,20 @@ func (h *RoomHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
 		MaxParticipants: req.MaxParticipants,
 		EmptyTimeout:    time.Duration(req.EmptyTimeout) * time.Second,
 		Metadata:        req.Metadata,
+		Localized:       req.Localized,
+		DefaultLanguage: req.DefaultLanguage,
+	}
+
+	// Rooms created with a signed request count against the key's tenant quota
+	if apiKey, ok := GetAPIKey(r); ok {
+		roomReq.Tenant = quota.TenantFromAPIKey(apiKey)
 	}
 
 	// Create room
 	rm, err := h.roomManager.CreateRoom(roomReq, req.CreatedBy)
 	if err != nil {
 		h.logger.Error("Failed to create room", logger.Err(err))
-		h.sendError(w, http.StatusInternalServerError, "failed to create room")
+		h.sendErrorFor(w, err, "failed to create room")
 		return
 	}
 
@@ -127,11 +124,18 @@ func (h *RoomHandler) ListRooms(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rooms := h.roomManager.ListRooms()
+	// Rooms can be searched and localized with ?q= and ?lang=
+	query := r.URL.Query().Get("q")
+	lang := r.URL.Query().Get("lang")
+
+	rooms := h.roomManager.SearchRooms(query, lang)
 
 	responses := make([]RoomResponse, 0, len(rooms))
 	for _, rm := range rooms {
-		responses = append(responses, h.roomToResponse(rm))
+		resp := h.roomToResponse(rm)
+		resp.Title = rm.GetLocalized(types.FieldTitle, lang)
+		resp.Description = rm.GetLocalized(types.FieldDescription, lang)
+		responses = append(responses, resp)
 	}
 
 	h.sendJSON(w, http.StatusOK, map[string]interface{}{
@@ -155,7 +159,7 @@ func (h *RoomHandler) GetRoom(w http.ResponseWriter, r *http.Request) {
 
 	rm, err := h.roomManager.GetRoom(roomID)
 	if err != nil {
-		h.sendError(w, http.StatusNotFound, "room not found")
+		h.sendErrorFor(w, err, "room not found")
 		return
 	}
 
@@ -180,7 +184,7 @@ func (h *RoomHandler) DeleteRoom(w http.ResponseWriter, r *http.Request) {
 			logger.String("room_id", roomID),
 			logger.Err(err),
 		)
-		h.sendError(w, http.StatusInternalServerError, "failed to delete room")
+		h.sendErrorFor(w, err, "failed to delete room")
 		return
 	}
 
@@ -207,7 +211,7 @@ func (h *RoomHandler) ListParticipants(w http.ResponseWriter, r *http.Request) {
 
 	rm, err := h.roomManager.GetRoom(roomID)
 	if err != nil {
-		h.sendError(w, http.StatusNotFound, "room not found")
+		h.sendErrorFor(w, err, "room not found")
 		return
 	}
 
@@ -238,20 +242,14 @@ func (h *RoomHandler) AddParticipant(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req AddParticipantRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, http.StatusBadRequest, "invalid request body")
-		return
-	}
-
-	// Validate request
-	if req.ParticipantID == "" || req.UserID == "" || req.Username == "" {
-		h.sendError(w, http.StatusBadRequest, "participant_id, user_id, and username are required")
+	if err := decodeRequest(r, &req); err != nil {
+		h.sendErrorFor(w, err, "invalid request body")
 		return
 	}
 
 	rm, err := h.roomManager.GetRoom(roomID)
 	if err != nil {
-		h.sendError(w, http.StatusNotFound, "room not found")
+		h.sendErrorFor(w, err, "room not found")
 		return
 	}
 
@@ -274,7 +272,7 @@ func (h *RoomHandler) AddParticipant(w http.ResponseWriter, r *http.Request) {
 			logger.String("participant_id", req.ParticipantID),
 			logger.Err(err),
 		)
-		h.sendError(w, http.StatusInternalServerError, "failed to add participant")
+		h.sendErrorFor(w, err, "failed to add participant")
 		return
 	}
 
@@ -303,7 +301,7 @@ func (h *RoomHandler) RemoveParticipant(w http.ResponseWriter, r *http.Request)
 
 	rm, err := h.roomManager.GetRoom(roomID)
 	if err != nil {
-		h.sendError(w, http.StatusNotFound, "room not found")
+		h.sendErrorFor(w, err, "room not found")
 		return
 	}
 
@@ -313,7 +311,7 @@ func (h *RoomHandler) RemoveParticipant(w http.ResponseWriter, r *http.Request)
 			logger.String("participant_id", participantID),
 			logger.Err(err),
 		)
-		h.sendError(w, http.StatusInternalServerError, "failed to remove participant")
+		h.sendErrorFor(w, err, "failed to remove participant")
 		return
 	}
 
@@ -407,9 +405,9 @@ func (h *RoomHandler) sendJSON(w http.ResponseWriter, status int, data interface
 }
 
 func (h *RoomHandler) sendError(w http.ResponseWriter, status int, message string) {
-	h.sendJSON(w, status, ErrorResponse{
-		Error:   http.StatusText(status),
-		Code:    status,
-		Message: message,
-	})
+	writeError(w, status, message, nil)
+}
+
+func (h *RoomHandler) sendErrorFor(w http.ResponseWriter, err error, message string) {
+	writeErrorFor(w, err, message)
 }
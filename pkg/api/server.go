@@ -2,12 +2,22 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/aminofox/zenlive/pkg/auth"
+	"github.com/aminofox/zenlive/pkg/discovery"
+	"github.com/aminofox/zenlive/pkg/health"
 	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/overlay"
+	"github.com/aminofox/zenlive/pkg/quota"
 	"github.com/aminofox/zenlive/pkg/room"
+	"github.com/aminofox/zenlive/pkg/streaming/webrtc"
 )
 
 // Server represents the REST API server
@@ -15,11 +25,21 @@ type Server struct {
 	roomHandler     *RoomHandler
 	tokenHandler    *TokenHandler
 	signalingServer *SignalingServer
+	adminHandler    *AdminHandler
+	discovery       *DiscoveryHandler
+	overlay         *OverlayHandler
 	authMW          *AuthMiddleware
 	rateLimiter     *RateLimiter
 	corsMW          *CORSMiddleware
+	health          *health.Registry
+	iceMonitor      *webrtc.ICEMonitor
 	logger          logger.Logger
 	addr            string
+
+	// httpServer is the running server, set by Start
+	httpServer *http.Server
+	shutdown   bool
+	mu         sync.Mutex
 }
 
 // Config contains server configuration
@@ -30,6 +50,21 @@ type Config struct {
 	CORSOrigins  []string
 	CORSMethods  []string
 	CORSHeaders  []string
+	// CORSAllowCredentials allows cookies/Authorization on cross-origin requests
+	// from origins listed explicitly in CORSOrigins; "*" never grants credentials.
+	CORSAllowCredentials bool
+	// CORSMaxAge is how long browsers may cache preflight responses
+	CORSMaxAge time.Duration
+	// HeartbeatTimeout is how long a WebSocket client may stay silent before
+	// its participant is removed (0 = disabled)
+	HeartbeatTimeout time.Duration
+	// SignalingTokenSecret enables access token verification on join_room,
+	// including IP/device binding checks (empty = disabled)
+	SignalingTokenSecret string
+	// TrustedProxies lists the reverse proxies (IPs or CIDR ranges) whose
+	// X-Forwarded-For/X-Real-IP headers identify the client. Requests from
+	// anywhere else are identified by their peer address.
+	TrustedProxies []string
 }
 
 // DefaultConfig returns default server configuration
@@ -41,6 +76,9 @@ func DefaultConfig() *Config {
 		CORSOrigins:  []string{"*"},
 		CORSMethods:  []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		CORSHeaders:  []string{"Content-Type", "Authorization"},
+		CORSMaxAge:   10 * time.Minute,
+
+		HeartbeatTimeout: DefaultHeartbeatTimeout,
 	}
 }
 
@@ -59,16 +97,34 @@ func NewServer(
 	roomHandler := NewRoomHandler(roomManager, log)
 	tokenHandler := NewTokenHandler(roomManager, jwtAuth, config.JWTSecret, log)
 	signalingServer := NewSignalingServer(roomManager, log)
+	signalingServer.SetHeartbeatTimeout(config.HeartbeatTimeout)
+	if config.SignalingTokenSecret != "" {
+		signalingServer.SetAccessTokenSecret(config.SignalingTokenSecret)
+	}
+
+	proxies, err := NewTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		// Trust no proxy rather than a partial list
+		log.Error("Ignoring trusted proxies", logger.Err(err))
+		proxies = nil
+	}
+	signalingServer.SetTrustedProxies(proxies)
 
 	// Create middleware
 	authMW := NewAuthMiddleware(jwtAuth, log)
 	rateLimiter := NewRateLimiter(config.RateLimitRPM, log)
+	rateLimiter.SetTrustedProxies(proxies)
 	corsMW := NewCORSMiddleware(config.CORSOrigins, config.CORSMethods, config.CORSHeaders)
+	corsMW.SetAllowCredentials(config.CORSAllowCredentials)
+	corsMW.SetMaxAge(config.CORSMaxAge)
 
 	return &Server{
 		roomHandler:     roomHandler,
 		tokenHandler:    tokenHandler,
 		signalingServer: signalingServer,
+		adminHandler:    NewAdminHandler(roomManager, signalingServer, log),
+		discovery:       NewDiscoveryHandler(nil, log),
+		overlay:         NewOverlayHandler(nil, log),
 		authMW:          authMW,
 		rateLimiter:     rateLimiter,
 		corsMW:          corsMW,
@@ -77,6 +133,46 @@ func NewServer(
 	}
 }
 
+// SetAPIKeyManager lets backend services authenticate protected routes by
+// signing requests with an API key instead of sending a JWT
+func (s *Server) SetAPIKeyManager(apiKeys *auth.APIKeyManager) {
+	s.authMW.SetAPIKeyManager(apiKeys, auth.DefaultSignatureMaxSkew)
+}
+
+// SetSFU lets the admin API inspect and terminate the SFU's streams
+func (s *Server) SetSFU(sfu *webrtc.SFU) {
+	s.adminHandler.SetSFU(sfu)
+}
+
+// SetQuotaManager reports tenant quota usage at /admin/quotas/:tenant.
+// Quotas are enforced by the room manager the manager is set on.
+func (s *Server) SetQuotaManager(quotas *quota.Manager) {
+	s.adminHandler.SetQuotaManager(quotas)
+}
+
+// SetHealthRegistry serves the registry's report from the health and
+// readiness endpoints
+func (s *Server) SetHealthRegistry(registry *health.Registry) {
+	s.health = registry
+}
+
+// SetICEMonitor enables on-demand STUN/TURN connectivity checks
+func (s *Server) SetICEMonitor(monitor *webrtc.ICEMonitor) {
+	s.iceMonitor = monitor
+}
+
+// SetDiscoveryIndex serves the index's live streams at /api/discover/live
+// and /api/discover/trending
+func (s *Server) SetDiscoveryIndex(index *discovery.Index) {
+	s.discovery.index = index
+}
+
+// SetOverlayManager streams the manager's alerts to overlay clients at
+// /api/overlay/:streamID/alerts?key=..., where key is the stream's overlay key
+func (s *Server) SetOverlayManager(manager *overlay.Manager) {
+	s.overlay.manager = manager
+}
+
 // Start starts the API server
 func (s *Server) Start() error {
 	mux := http.NewServeMux()
@@ -84,14 +180,56 @@ func (s *Server) Start() error {
 	// Register routes
 	s.registerRoutes(mux)
 
+	httpServer := &http.Server{Addr: s.addr, Handler: mux}
+	s.mu.Lock()
+	if s.shutdown {
+		s.mu.Unlock()
+		return nil
+	}
+	s.httpServer = httpServer
+	s.mu.Unlock()
+
 	s.logger.Info("Starting API server", logger.String("addr", s.addr))
-	return http.ListenAndServe(s.addr, mux)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting requests and waits, until ctx is done, for
+// in-flight requests to finish, then stops the signaling server's heartbeat
+// monitor and broadcast workers. Start returns nil once Shutdown is called.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.shutdown = true
+	httpServer := s.httpServer
+	s.mu.Unlock()
+
+	var err error
+	if httpServer != nil {
+		err = httpServer.Shutdown(ctx)
+	}
+
+	s.signalingServer.Close()
+
+	return err
 }
 
 // registerRoutes registers all API routes
 func (s *Server) registerRoutes(mux *http.ServeMux) {
 	// Public routes (with rate limiting and CORS only)
 	mux.HandleFunc("/api/health", s.chain(s.healthCheck, s.corsMW.Handle, s.rateLimiter.Limit))
+	mux.HandleFunc("/api/ready", s.chain(s.readyCheck, s.corsMW.Handle, s.rateLimiter.Limit))
+
+	// ICE connectivity check (protected by auth)
+	mux.HandleFunc("/api/ice/check", s.chain(s.authMW.Authenticate(s.iceCheck), s.corsMW.Handle, s.rateLimiter.Limit))
+
+	// Live stream directory (public, for browse pages)
+	mux.HandleFunc("/api/discover/live", s.chain(s.discovery.ListLiveStreams, s.corsMW.Handle, s.rateLimiter.Limit))
+	mux.HandleFunc("/api/discover/trending", s.chain(s.discovery.GetTrending, s.corsMW.Handle, s.rateLimiter.Limit))
+
+	// Overlay alerts (authenticated by overlay key, for OBS browser sources)
+	mux.HandleFunc("/api/overlay/", s.chain(s.overlay.StreamAlerts, s.corsMW.Handle, s.rateLimiter.Limit))
 
 	// WebSocket endpoint
 	mux.HandleFunc("/ws", s.chain(s.signalingServer.HandleWebSocket, s.corsMW.Handle))
@@ -99,8 +237,8 @@ func (s *Server) registerRoutes(mux *http.ServeMux) {
 	// Token generation (protected by auth)
 	mux.HandleFunc("/api/rooms/", s.routeRoomRequests)
 
-	// Admin routes (protected by auth and rate limiting)
-	// In production, you should add role-based access control here
+	// Admin routes (protected by auth with the admin scope)
+	mux.HandleFunc("/admin/", s.chain(s.authMW.RequireAdmin(s.adminHandler.ServeHTTP), s.corsMW.Handle, s.rateLimiter.Limit))
 }
 
 // routeRoomRequests routes room-related requests
@@ -118,7 +256,7 @@ func (s *Server) routeRoomRequests(w http.ResponseWriter, r *http.Request) {
 				// Create room requires authentication
 				s.authMW.Authenticate(s.roomHandler.CreateRoom)(w, r)
 			} else {
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
 			}
 			return
 		}
@@ -127,7 +265,13 @@ func (s *Server) routeRoomRequests(w http.ResponseWriter, r *http.Request) {
 		// Format: /api/rooms/{roomId}/...
 		roomID := s.extractRoomID(path)
 		if roomID == "" {
-			http.Error(w, "Invalid room ID", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, "invalid room ID", nil)
+			return
+		}
+
+		// Check if it's a batch token request
+		if path == "/api/rooms/"+roomID+"/tokens:batch" {
+			s.authMW.Authenticate(s.tokenHandler.GenerateAccessTokenBatch)(w, r)
 			return
 		}
 
@@ -150,7 +294,7 @@ func (s *Server) routeRoomRequests(w http.ResponseWriter, r *http.Request) {
 			} else if r.Method == http.MethodDelete {
 				s.authMW.Authenticate(s.roomHandler.RemoveParticipant)(w, r)
 			} else {
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
 			}
 			return
 		}
@@ -161,7 +305,7 @@ func (s *Server) routeRoomRequests(w http.ResponseWriter, r *http.Request) {
 		} else if r.Method == http.MethodDelete {
 			s.authMW.Authenticate(s.roomHandler.DeleteRoom)(w, r)
 		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
 		}
 	}, s.corsMW.Handle, s.rateLimiter.Limit)
 
@@ -198,11 +342,70 @@ func (s *Server) chain(handler http.HandlerFunc, middleware ...func(http.Handler
 // healthCheck handles health check requests
 func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	if s.health == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"status":"ok"}`)
+		return
+	}
+
+	report := s.health.Check(r.Context())
+	status := http.StatusOK
+	if report.Status == health.StatusUnhealthy {
+		status = http.StatusServiceUnavailable
+	}
+	writeHealthReport(w, status, report)
+}
+
+// readyCheck handles readiness probes, which fail until the SDK has started
+// and while a critical component is failing
+func (s *Server) readyCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	if s.health == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"status":"ok","ready":true}`)
+		return
+	}
+
+	report := s.health.Check(r.Context())
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeHealthReport(w, status, report)
+}
+
+// iceCheck checks reachability of the configured STUN and TURN servers now
+func (s *Server) iceCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed", nil)
 		return
 	}
 
+	if s.iceMonitor == nil {
+		writeError(w, http.StatusNotFound, "ICE checks not configured", nil)
+		return
+	}
+
+	report := s.iceMonitor.Check(r.Context())
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, `{"status":"ok"}`)
+	json.NewEncoder(w).Encode(report)
+}
+
+// writeHealthReport writes a health report as JSON
+func writeHealthReport(w http.ResponseWriter, status int, report *health.Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
 }
@@ -7,6 +7,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/aminofox/zenlive/pkg/auth"
@@ -58,29 +59,15 @@ func (h *TokenHandler) GenerateAccessToken(w http.ResponseWriter, r *http.Reques
 	}
 
 	var req GenerateTokenRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendError(w, http.StatusBadRequest, "invalid request body")
-		return
-	}
-
-	// Validate request
-	if req.RoomID == "" {
-		h.sendError(w, http.StatusBadRequest, "room_id is required")
-		return
-	}
-	if req.UserID == "" {
-		h.sendError(w, http.StatusBadRequest, "user_id is required")
-		return
-	}
-	if req.Username == "" {
-		h.sendError(w, http.StatusBadRequest, "username is required")
+	if err := decodeRequest(r, &req); err != nil {
+		h.sendErrorFor(w, err, "invalid request body")
 		return
 	}
 
 	// Verify room exists
 	_, err := h.roomManager.GetRoom(req.RoomID)
 	if err != nil {
-		h.sendError(w, http.StatusNotFound, "room not found")
+		h.sendErrorFor(w, err, "room not found")
 		return
 	}
 
@@ -134,6 +121,99 @@ func (h *TokenHandler) GenerateAccessToken(w http.ResponseWriter, r *http.Reques
 	})
 }
 
+// MaxTokenBatchSize is the maximum number of tokens generated by one batch request
+const MaxTokenBatchSize = 1000
+
+// BatchTokenRequest represents a request to generate tokens for many participants
+type BatchTokenRequest struct {
+	Participants []GenerateTokenRequest `json:"participants"`
+	TTL          int                    `json:"ttl,omitempty"` // seconds, default 24h, used when a participant has no TTL
+}
+
+// BatchTokenResponse represents a batch token response
+type BatchTokenResponse struct {
+	Tokens []TokenResponse `json:"tokens"`
+}
+
+// GenerateAccessTokenBatch handles POST /api/rooms/:roomId/tokens:batch
+func (h *TokenHandler) GenerateAccessTokenBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// Path format: /api/rooms/ROOM_ID/tokens:batch
+	var roomID string
+	if parts := splitPath(strings.TrimPrefix(r.URL.Path, "/api/rooms/")); len(parts) > 0 {
+		roomID = parts[0]
+	}
+
+	var req BatchTokenRequest
+	if err := decodeRequest(r, &req); err != nil {
+		h.sendErrorFor(w, err, "invalid request body")
+		return
+	}
+
+	// Verify room exists
+	if _, err := h.roomManager.GetRoom(roomID); err != nil {
+		h.sendErrorFor(w, err, "room not found")
+		return
+	}
+
+	now := time.Now()
+	tokens := make([]TokenResponse, 0, len(req.Participants))
+
+	for _, p := range req.Participants {
+		// Default TTL: 24 hours
+		ttl := 24 * time.Hour
+		if p.TTL > 0 {
+			ttl = time.Duration(p.TTL) * time.Second
+		} else if req.TTL > 0 {
+			ttl = time.Duration(req.TTL) * time.Second
+		}
+
+		customClaims := map[string]interface{}{
+			"room_id":     roomID,
+			"permissions": p.Permissions,
+		}
+		if p.Metadata != nil {
+			customClaims["metadata"] = p.Metadata
+		}
+
+		expiresAt := now.Add(ttl)
+		token, err := h.generateSimpleJWT(&auth.TokenClaims{
+			UserID:    p.UserID,
+			Username:  p.Username,
+			IssuedAt:  now,
+			ExpiresAt: expiresAt,
+			Custom:    customClaims,
+		})
+		if err != nil {
+			h.logger.Error("Failed to generate token",
+				logger.String("room_id", roomID),
+				logger.String("user_id", p.UserID),
+				logger.Err(err),
+			)
+			h.sendError(w, http.StatusInternalServerError, "failed to generate token")
+			return
+		}
+
+		tokens = append(tokens, TokenResponse{
+			Token:     token,
+			ExpiresAt: expiresAt,
+			RoomID:    roomID,
+			UserID:    p.UserID,
+		})
+	}
+
+	h.logger.Info("Access tokens generated",
+		logger.String("room_id", roomID),
+		logger.Int("count", len(tokens)),
+	)
+
+	h.sendJSON(w, http.StatusOK, BatchTokenResponse{Tokens: tokens})
+}
+
 // Helper methods
 
 func (h *TokenHandler) sendJSON(w http.ResponseWriter, status int, data interface{}) {
@@ -143,11 +223,11 @@ func (h *TokenHandler) sendJSON(w http.ResponseWriter, status int, data interfac
 }
 
 func (h *TokenHandler) sendError(w http.ResponseWriter, status int, message string) {
-	h.sendJSON(w, status, ErrorResponse{
-		Error:   http.StatusText(status),
-		Code:    status,
-		Message: message,
-	})
+	writeError(w, status, message, nil)
+}
+
+func (h *TokenHandler) sendErrorFor(w http.ResponseWriter, err error, message string) {
+	writeErrorFor(w, err, message)
 }
 
 // generateSimpleJWT generates a simple JWT token for room access
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aminofox/zenlive/pkg/room"
+)
+
+const (
+	// MaxRoomNameLength is the longest room name accepted by the API
+	MaxRoomNameLength = 256
+
+	// MaxRoomParticipants is the largest max_participants accepted by the API
+	MaxRoomParticipants = 10000
+
+	// MaxEmptyTimeout is the longest empty_timeout, in seconds, accepted by the API
+	MaxEmptyTimeout = 7 * 24 * 60 * 60
+
+	// MaxTokenTTL is the longest token ttl, in seconds, accepted by the API
+	MaxTokenTTL = 30 * 24 * 60 * 60
+)
+
+// FieldError describes one invalid field of a request body
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists the invalid fields of a request body. It is reported
+// as a 400 with the fields in the error details.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Field + ": " + f.Message
+	}
+
+	return "invalid request: " + strings.Join(messages, "; ")
+}
+
+// add records an invalid field
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the validation error, or nil if no field was invalid
+func (e *ValidationError) err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// Validator is implemented by request bodies that check their own fields
+type Validator interface {
+	Validate() error
+}
+
+// decodeRequest decodes a JSON request body into v and, if v is a
+// Validator, validates it
+func decodeRequest(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		verr := &ValidationError{}
+		verr.add("body", "invalid JSON: %v", err)
+		return verr
+	}
+
+	if validator, ok := v.(Validator); ok {
+		return validator.Validate()
+	}
+
+	return nil
+}
+
+// Validate checks a create room request
+func (req *CreateRoomRequest) Validate() error {
+	verr := &ValidationError{}
+
+	if req.Name == "" {
+		verr.add("name", "is required")
+	} else if len(req.Name) > MaxRoomNameLength {
+		verr.add("name", "must be at most %d characters", MaxRoomNameLength)
+	}
+	if req.CreatedBy == "" {
+		verr.add("created_by", "is required")
+	}
+	if req.MaxParticipants < 0 || req.MaxParticipants > MaxRoomParticipants {
+		verr.add("max_participants", "must be between 1 and %d, or omitted for no limit", MaxRoomParticipants)
+	}
+	if req.EmptyTimeout < 0 || req.EmptyTimeout > MaxEmptyTimeout {
+		verr.add("empty_timeout", "must be between 0 and %d seconds", MaxEmptyTimeout)
+	}
+
+	return verr.err()
+}
+
+// Validate checks an add participant request
+func (req *AddParticipantRequest) Validate() error {
+	verr := &ValidationError{}
+
+	if req.ParticipantID == "" {
+		verr.add("participant_id", "is required")
+	}
+	if req.UserID == "" {
+		verr.add("user_id", "is required")
+	}
+	if req.Username == "" {
+		verr.add("username", "is required")
+	}
+	switch room.ParticipantRole(req.Role) {
+	case "", room.RoleHost, room.RoleSpeaker, room.RoleAttendee:
+	default:
+		verr.add("role", "must be one of %s, %s or %s", room.RoleHost, room.RoleSpeaker, room.RoleAttendee)
+	}
+
+	return verr.err()
+}
+
+// Validate checks a token request
+func (req *GenerateTokenRequest) Validate() error {
+	verr := &ValidationError{}
+
+	if req.RoomID == "" {
+		verr.add("room_id", "is required")
+	}
+	req.validateIdentity(verr, "")
+
+	return verr.err()
+}
+
+// validateIdentity checks the user and TTL fields, prefixing field names
+func (req *GenerateTokenRequest) validateIdentity(verr *ValidationError, prefix string) {
+	if req.UserID == "" {
+		verr.add(prefix+"user_id", "is required")
+	}
+	if req.Username == "" {
+		verr.add(prefix+"username", "is required")
+	}
+	if req.TTL < 0 || req.TTL > MaxTokenTTL {
+		verr.add(prefix+"ttl", "must be between 0 and %d seconds", MaxTokenTTL)
+	}
+}
+
+// Validate checks a batch token request. The room comes from the URL, so
+// participants need no room_id.
+func (req *BatchTokenRequest) Validate() error {
+	verr := &ValidationError{}
+
+	if len(req.Participants) == 0 {
+		verr.add("participants", "is required")
+	} else if len(req.Participants) > MaxTokenBatchSize {
+		verr.add("participants", "must have at most %d entries", MaxTokenBatchSize)
+	}
+	if req.TTL < 0 || req.TTL > MaxTokenTTL {
+		verr.add("ttl", "must be between 0 and %d seconds", MaxTokenTTL)
+	}
+
+	seen := make(map[string]bool, len(req.Participants))
+	for i := range req.Participants {
+		p := &req.Participants[i]
+		prefix := fmt.Sprintf("participants[%d].", i)
+
+		p.validateIdentity(verr, prefix)
+		if p.UserID != "" && seen[p.UserID] {
+			verr.add(prefix+"user_id", "duplicate user_id %s", p.UserID)
+		}
+		seen[p.UserID] = true
+	}
+
+	return verr.err()
+}
@@ -3,10 +3,15 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/aminofox/zenlive/pkg/auth"
+	"github.com/aminofox/zenlive/pkg/cluster"
+	"github.com/aminofox/zenlive/pkg/idgen"
 	"github.com/aminofox/zenlive/pkg/logger"
 	"github.com/aminofox/zenlive/pkg/room"
 	"github.com/gorilla/websocket"
@@ -14,24 +19,35 @@ import (
 
 // WebSocket message types
 const (
-	MsgJoinRoom         = "join_room"
-	MsgLeaveRoom        = "leave_room"
-	MsgPublishTrack     = "publish_track"
-	MsgUnpublishTrack   = "unpublish_track"
-	MsgSubscribeTrack   = "subscribe_track"
-	MsgUnsubscribeTrack = "unsubscribe_track"
-	MsgUpdateMetadata   = "update_metadata"
-	MsgSendData         = "send_data"
-	MsgRoomEvent        = "room_event"
-	MsgError            = "error"
-	MsgPing             = "ping"
-	MsgPong             = "pong"
+	MsgJoinRoom                  = "join_room"
+	MsgLeaveRoom                 = "leave_room"
+	MsgKnock                     = "knock"
+	MsgWaiting                   = "waiting"
+	MsgDenied                    = "denied"
+	MsgPublishTrack              = "publish_track"
+	MsgUnpublishTrack            = "unpublish_track"
+	MsgSubscribeTrack            = "subscribe_track"
+	MsgUnsubscribeTrack          = "unsubscribe_track"
+	MsgUpdateMetadata            = "update_metadata"
+	MsgUpdateParticipantMetadata = "update_participant_metadata"
+	MsgSendData                  = "send_data"
+	MsgDeliveryReceipt           = "delivery_receipt"
+	MsgTyping                    = "typing"
+	MsgUpdateSharedState         = "update_shared_state"
+	MsgSharedStateSnapshot       = "shared_state_snapshot"
+	MsgClientStats               = "client_stats"
+	MsgMigrate                   = "migrate"
+	MsgRoomEvent                 = "room_event"
+	MsgError                     = "error"
+	MsgPing                      = "ping"
+	MsgPong                      = "pong"
 )
 
 // WSMessage represents a WebSocket message
 type WSMessage struct {
 	Type   string          `json:"type"`
 	RoomID string          `json:"room_id,omitempty"`
+	Seq    uint64          `json:"seq,omitempty"` // Set on room events, monotonic per room
 	Data   json.RawMessage `json:"data,omitempty"`
 }
 
@@ -40,6 +56,11 @@ type JoinRoomData struct {
 	RoomID string `json:"room_id"`
 	Token  string `json:"token"`
 	UserID string `json:"user_id"`
+	// DeviceID identifies the client device for device-bound tokens
+	DeviceID string `json:"device_id,omitempty"`
+	// ResumeFrom is the last room event sequence seen by a reconnecting client.
+	// Buffered events after it are replayed; clients should ignore duplicate sequences.
+	ResumeFrom uint64 `json:"resume_from,omitempty"`
 }
 
 // PublishTrackData represents publish track message data
@@ -64,12 +85,32 @@ type UpdateMetadataData struct {
 
 // DataMessage represents a data channel message
 type DataMessage struct {
+	// ID identifies the message for delivery receipts; assigned by the
+	// server in rooms with delivery receipts, replacing any client value
+	ID      string `json:"id,omitempty"`
 	From    string `json:"from"`
 	To      string `json:"to,omitempty"` // Empty = broadcast
 	Topic   string `json:"topic"`
 	Payload []byte `json:"payload"`
 }
 
+// DeliveryReceiptData represents a recipient's acknowledgement of a data message
+type DeliveryReceiptData struct {
+	MessageID string              `json:"message_id"`
+	Status    room.DeliveryStatus `json:"status"`
+}
+
+// TypingData represents a typing indicator update
+type TypingData struct {
+	Typing bool `json:"typing"`
+}
+
+// UpdateSharedStateData represents writes to a room shared state
+type UpdateSharedStateData struct {
+	Name string               `json:"name"`
+	Ops  []room.SharedStateOp `json:"ops"`
+}
+
 // RoomEventData represents room event data
 type RoomEventData struct {
 	EventType string      `json:"event_type"`
@@ -84,9 +125,22 @@ type WSClient struct {
 	roomID        string
 	participantID string
 	userID        string
-	send          chan []byte
-	server        *SignalingServer
-	mu            sync.RWMutex
+	// waitingRoomID is the room whose lobby holds the client's participant
+	waitingRoomID string
+	remoteIP      string
+	lastHeartbeat time.Time
+	// sessionRefreshedAt is when the client's cluster session was last
+	// created or refreshed
+	sessionRefreshedAt time.Time
+	codec              Codec
+	send               chan []byte // frames already encoded in codec
+	closed             bool        // send is closed
+	server             *SignalingServer
+	mu                 sync.RWMutex
+
+	// replaying holds room broadcasts back while missed events are replayed
+	replaying bool
+	held      []heldBroadcast
 }
 
 // SignalingServer handles WebSocket connections for room signaling
@@ -95,17 +149,40 @@ type SignalingServer struct {
 	upgrader    websocket.Upgrader
 	clients     map[string]*WSClient            // clientID -> client
 	roomClients map[string]map[string]*WSClient // roomID -> clientID -> client
+	waiting     map[string]*WSClient            // participantID -> client held in a lobby
+	replay      map[string]*replayBuffer        // roomID -> recent room events
+	replaySize  int
 	logger      logger.Logger
 	mu          sync.RWMutex
+
+	// tokenSecret enables access token verification on join when set
+	tokenSecret  string
+	tokenTracker *auth.TokenUseTracker
+
+	// proxies may report the client IP tokens are bound to (nil = none)
+	proxies *TrustedProxies
+
+	// sessions records joined clients for SFU failover (nil = disabled)
+	sessions cluster.SessionManager
+	nodeID   string
+
+	// fanout delivers room broadcasts asynchronously
+	fanout *fanout
+
+	// heartbeatTimeout is how long a client may stay silent (0 = disabled)
+	heartbeatTimeout time.Duration
+	done             chan struct{}
+	closeOnce        sync.Once
 }
 
 // NewSignalingServer creates a new signaling server
 func NewSignalingServer(roomManager *room.RoomManager, log logger.Logger) *SignalingServer {
-	return &SignalingServer{
+	s := &SignalingServer{
 		roomManager: roomManager,
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
+			Subprotocols:    []string{SubprotocolJSON, SubprotocolMsgPack},
 			CheckOrigin: func(r *http.Request) bool {
 				// In production, check origin properly
 				return true
@@ -113,8 +190,56 @@ func NewSignalingServer(roomManager *room.RoomManager, log logger.Logger) *Signa
 		},
 		clients:     make(map[string]*WSClient),
 		roomClients: make(map[string]map[string]*WSClient),
+		waiting:     make(map[string]*WSClient),
+		replay:      make(map[string]*replayBuffer),
+		replaySize:  DefaultReplayBufferSize,
 		logger:      log,
+
+		heartbeatTimeout: DefaultHeartbeatTimeout,
+		done:             make(chan struct{}),
 	}
+
+	s.fanout = newFanout(s, DefaultFanoutWorkers, DefaultFanoutQueueSize, DefaultCoalesceInterval)
+
+	// Drop replay history once a room is gone
+	roomManager.OnRoomDeleted(func(event *room.RoomEvent) {
+		s.dropReplayBuffer(event.RoomID)
+		s.fanout.dropRoom(event.RoomID)
+	})
+
+	// Let clients held in a lobby in once the host decides
+	roomManager.OnParticipantAdmitted(s.admitWaiting)
+	roomManager.OnParticipantDenied(s.denyWaiting)
+
+	// Rebroadcast events from other nodes to local clients
+	roomManager.GetEventBus().SubscribeAll(func(event *room.RoomEvent) {
+		if !event.Remote {
+			return
+		}
+		s.BroadcastToRoom(event.RoomID, &WSMessage{
+			Type:   MsgRoomEvent,
+			RoomID: event.RoomID,
+			Data: mustMarshal(RoomEventData{
+				EventType: string(event.Type),
+				Data:      event.Data,
+				Timestamp: event.Timestamp,
+			}),
+		}, "")
+	})
+
+	// Start heartbeat monitor
+	go s.monitorHeartbeats()
+
+	return s
+}
+
+// Close stops the heartbeat monitor and broadcast workers, returning once
+// the broadcast workers have exited. It is safe to call more than once.
+func (s *SignalingServer) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	s.fanout.wait()
 }
 
 // HandleWebSocket handles WebSocket connection requests
@@ -128,10 +253,13 @@ func (s *SignalingServer) HandleWebSocket(w http.ResponseWriter, r *http.Request
 
 	// Create client
 	client := &WSClient{
-		id:     generateClientID(),
-		conn:   conn,
-		send:   make(chan []byte, 256),
-		server: s,
+		id:            generateClientID(),
+		conn:          conn,
+		remoteIP:      s.trustedProxies().ClientIP(r),
+		lastHeartbeat: time.Now(),
+		codec:         negotiateCodec(conn, r),
+		send:          make(chan []byte, 256),
+		server:        s,
 	}
 
 	// Register client
@@ -156,11 +284,13 @@ func (c *WSClient) readPump() {
 	c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		// Answering pings keeps an otherwise idle client alive
+		c.touch()
 		return nil
 	})
 
 	for {
-		_, message, err := c.conn.ReadMessage()
+		messageType, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				c.server.logger.Error("WebSocket error", logger.Err(err))
@@ -170,11 +300,14 @@ func (c *WSClient) readPump() {
 
 		// Parse message
 		var msg WSMessage
-		if err := json.Unmarshal(message, &msg); err != nil {
+		if err := decodeMessage(messageType, message, &msg); err != nil {
 			c.sendError("invalid message format")
 			continue
 		}
 
+		// Any message counts as a heartbeat
+		c.touch()
+
 		// Handle message
 		c.handleMessage(&msg)
 	}
@@ -197,7 +330,7 @@ func (c *WSClient) writePump() {
 				return
 			}
 
-			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			if err := c.conn.WriteMessage(c.codec.frameType(), message); err != nil {
 				return
 			}
 
@@ -217,6 +350,8 @@ func (c *WSClient) handleMessage(msg *WSMessage) {
 		c.handleJoinRoom(msg)
 	case MsgLeaveRoom:
 		c.handleLeaveRoom(msg)
+	case MsgKnock:
+		c.handleKnock(msg)
 	case MsgPublishTrack:
 		c.handlePublishTrack(msg)
 	case MsgUnpublishTrack:
@@ -227,8 +362,18 @@ func (c *WSClient) handleMessage(msg *WSMessage) {
 		c.handleUnsubscribeTrack(msg)
 	case MsgUpdateMetadata:
 		c.handleUpdateMetadata(msg)
+	case MsgUpdateParticipantMetadata:
+		c.handleUpdateParticipantMetadata(msg)
 	case MsgSendData:
 		c.handleSendData(msg)
+	case MsgDeliveryReceipt:
+		c.handleDeliveryReceipt(msg)
+	case MsgTyping:
+		c.handleTyping(msg)
+	case MsgUpdateSharedState:
+		c.handleUpdateSharedState(msg)
+	case MsgClientStats:
+		c.handleClientStats(msg)
 	case MsgPing:
 		c.sendMessage(&WSMessage{Type: MsgPong})
 	default:
@@ -238,6 +383,19 @@ func (c *WSClient) handleMessage(msg *WSMessage) {
 
 // handleJoinRoom handles join room messages
 func (c *WSClient) handleJoinRoom(msg *WSMessage) {
+	c.joinRoom(msg, false)
+}
+
+// handleKnock handles knock messages. They carry join room data; on a
+// locked room the client waits in the lobby until the host admits it, on
+// an unlocked room it joins directly.
+func (c *WSClient) handleKnock(msg *WSMessage) {
+	c.joinRoom(msg, true)
+}
+
+// joinRoom adds the client's participant to a room, or to its lobby when
+// the room holds it for approval
+func (c *WSClient) joinRoom(msg *WSMessage, knock bool) {
 	var data JoinRoomData
 	if err := json.Unmarshal(msg.Data, &data); err != nil {
 		c.sendError("invalid join room data")
@@ -251,6 +409,18 @@ func (c *WSClient) handleJoinRoom(msg *WSMessage) {
 		return
 	}
 
+	// Verify access token
+	claims, err := c.server.verifyJoinToken(c, rm, &data)
+	if err != nil {
+		c.server.logger.Warn("Join token rejected",
+			logger.String("room_id", data.RoomID),
+			logger.String("client_ip", c.remoteIP),
+			logger.Err(err),
+		)
+		c.sendError("unauthorized: " + err.Error())
+		return
+	}
+
 	// Create participant
 	participant := &room.Participant{
 		ID:       generateParticipantID(),
@@ -268,19 +438,51 @@ func (c *WSClient) handleJoinRoom(msg *WSMessage) {
 		Metadata: make(map[string]interface{}),
 	}
 
+	// Apply room-level permission defaults; a verified token's grants
+	// take precedence
+	if claims != nil {
+		applyGrant(participant, claims.Video)
+	} else if rm.DefaultPermissions != nil {
+		participant.Permissions = *rm.DefaultPermissions
+	}
+
 	// Add participant to room
-	if err := rm.AddParticipant(participant); err != nil {
+	add := rm.AddParticipant
+	if knock {
+		add = rm.Knock
+	}
+	if err := add(participant); err != nil {
+		// The token did not get the client in, so it may be presented again
+		c.server.releaseJoinToken(claims)
 		c.sendError("failed to join room: " + err.Error())
 		return
 	}
 
+	// Held in the lobby: the client joins once the host admits it
+	if participant.GetState() == room.StateWaiting {
+		c.holdInLobby(rm, participant, data.UserID)
+		return
+	}
+
+	c.completeJoin(rm, participant, data.UserID, data.ResumeFrom)
+}
+
+// completeJoin registers a client whose participant is in the room,
+// delivers its join state and announces it to the other participants
+func (c *WSClient) completeJoin(rm *room.Room, participant *room.Participant, userID string, resumeFrom uint64) {
+	data := JoinRoomData{RoomID: rm.ID, UserID: userID, ResumeFrom: resumeFrom}
+
 	// Update client state
 	c.mu.Lock()
 	c.roomID = data.RoomID
 	c.participantID = participant.ID
 	c.userID = data.UserID
+	c.waitingRoomID = ""
+	c.replaying = data.ResumeFrom > 0
 	c.mu.Unlock()
 
+	c.server.trackSession(c, data.RoomID, participant.ID, data.UserID)
+
 	// Register client in room
 	c.server.mu.Lock()
 	if c.server.roomClients[data.RoomID] == nil {
@@ -298,9 +500,31 @@ func (c *WSClient) handleJoinRoom(msg *WSMessage) {
 	c.sendMessage(&WSMessage{
 		Type:   MsgJoinRoom,
 		RoomID: data.RoomID,
-		Data:   mustMarshal(map[string]interface{}{"participant_id": participant.ID}),
+		Data: mustMarshal(map[string]interface{}{
+			"participant_id": participant.ID,
+			"seq":            c.server.getReplayBuffer(data.RoomID).sequence(),
+		}),
 	})
 
+	// Deliver shared state to the late joiner
+	if snapshots := rm.SharedStateSnapshots(); len(snapshots) > 0 {
+		c.sendMessage(&WSMessage{
+			Type:   MsgSharedStateSnapshot,
+			RoomID: data.RoomID,
+			Data:   mustMarshal(snapshots),
+		})
+	}
+
+	// Replay events missed while disconnected; broadcasts to the client
+	// are held back until the replay is done
+	if data.ResumeFrom > 0 && !c.replayEvents(data.RoomID, data.ResumeFrom) {
+		c.server.logger.Warn("Replay buffer exhausted, client needs full resync",
+			logger.String("room_id", data.RoomID),
+			logger.String("participant_id", participant.ID),
+		)
+		c.sendError("resume_from is too old, full resync required")
+	}
+
 	// Broadcast to other participants
 	c.server.BroadcastToRoom(data.RoomID, &WSMessage{
 		Type:   MsgRoomEvent,
@@ -313,13 +537,231 @@ func (c *WSClient) handleJoinRoom(msg *WSMessage) {
 	}, c.id)
 }
 
+// holdInLobby records a client whose participant waits in a room's lobby.
+// It receives no room traffic until admitted.
+func (c *WSClient) holdInLobby(rm *room.Room, participant *room.Participant, userID string) {
+	roomID, participantID := rm.ID, participant.ID
+
+	c.mu.Lock()
+	c.waitingRoomID = roomID
+	c.participantID = participantID
+	c.userID = userID
+	c.mu.Unlock()
+
+	c.server.mu.Lock()
+	c.server.waiting[participantID] = c
+	c.server.mu.Unlock()
+
+	c.server.logger.Info("Participant waiting in lobby",
+		logger.String("room_id", roomID),
+		logger.String("participant_id", participantID),
+	)
+
+	c.sendMessage(&WSMessage{
+		Type:   MsgWaiting,
+		RoomID: roomID,
+		Data: mustMarshal(map[string]interface{}{
+			"participant_id": participantID,
+		}),
+	})
+
+	// The host may have decided before the client was registered
+	switch participant.GetState() {
+	case room.StateJoined:
+		if c.server.takeWaiting(participantID) != nil {
+			c.completeJoin(rm, participant, userID, 0)
+		}
+	case room.StateDisconnected:
+		c.server.denyWaiting(&room.RoomEvent{Type: room.EventParticipantDenied, RoomID: roomID, Data: participant})
+	}
+}
+
+// takeWaiting removes and returns the client of a participant held in a
+// lobby, if it is connected here
+func (s *SignalingServer) takeWaiting(participantID string) *WSClient {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	client := s.waiting[participantID]
+	delete(s.waiting, participantID)
+	return client
+}
+
+// admitWaiting completes the join of a client admitted from a lobby
+func (s *SignalingServer) admitWaiting(event *room.RoomEvent) {
+	participant, ok := event.Data.(*room.Participant)
+	if !ok {
+		return
+	}
+
+	client := s.takeWaiting(participant.ID)
+	if client == nil {
+		return
+	}
+
+	rm, err := s.roomManager.GetRoom(event.RoomID)
+	if err != nil {
+		return
+	}
+
+	client.mu.RLock()
+	userID := client.userID
+	client.mu.RUnlock()
+
+	client.completeJoin(rm, participant, userID, 0)
+}
+
+// denyWaiting tells a client held in a lobby that the host turned it away
+func (s *SignalingServer) denyWaiting(event *room.RoomEvent) {
+	participant, ok := event.Data.(*room.Participant)
+	if !ok {
+		return
+	}
+
+	client := s.takeWaiting(participant.ID)
+	if client == nil {
+		return
+	}
+
+	client.mu.Lock()
+	client.waitingRoomID = ""
+	client.participantID = ""
+	client.mu.Unlock()
+
+	client.sendMessage(&WSMessage{
+		Type:   MsgDenied,
+		RoomID: event.RoomID,
+		Data: mustMarshal(map[string]interface{}{
+			"participant_id": participant.ID,
+		}),
+	})
+}
+
+// leaveLobby withdraws a client's participant from the lobby it waits in
+func (s *SignalingServer) leaveLobby(client *WSClient, roomID, participantID string) {
+	s.takeWaiting(participantID)
+
+	if rm, err := s.roomManager.GetRoom(roomID); err == nil {
+		rm.RemoveParticipant(participantID)
+	}
+
+	client.mu.Lock()
+	client.waitingRoomID = ""
+	client.participantID = ""
+	client.mu.Unlock()
+}
+
+// SetAccessTokenSecret enables access token verification for join_room.
+// Tokens must grant the room by ID and match the client's IP/device bindings.
+func (s *SignalingServer) SetAccessTokenSecret(secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokenSecret = secret
+	if s.tokenTracker == nil {
+		s.tokenTracker = auth.NewTokenUseTracker()
+	}
+}
+
+// SetTrustedProxies lets the listed proxies report the client IP that
+// IP-bound access tokens are checked against
+func (s *SignalingServer) SetTrustedProxies(proxies *TrustedProxies) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.proxies = proxies
+}
+
+func (s *SignalingServer) trustedProxies() *TrustedProxies {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.proxies
+}
+
+// verifyJoinToken validates the join token when token verification is
+// enabled and returns its claims (nil when verification is disabled)
+func (s *SignalingServer) verifyJoinToken(c *WSClient, rm *room.Room, data *JoinRoomData) (*auth.AccessTokenClaims, error) {
+	s.mu.RLock()
+	secret := s.tokenSecret
+	tracker := s.tokenTracker
+	s.mu.RUnlock()
+
+	if secret == "" {
+		return nil, nil
+	}
+
+	claims, err := auth.ParseAccessToken(data.Token, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	// Room names are not unique, so tokens are bound to the room ID
+	if claims.Video == nil || !claims.Video.RoomJoin || claims.Video.Room != rm.ID {
+		return nil, fmt.Errorf("%w: token does not grant access to this room", room.ErrUnauthorized)
+	}
+
+	if err := claims.VerifyBinding(c.remoteIP, data.DeviceID); err != nil {
+		return nil, err
+	}
+
+	if err := tracker.Consume(claims); err != nil {
+		return nil, err
+	}
+
+	data.UserID = claims.Identity
+
+	return claims, nil
+}
+
+// releaseJoinToken returns a single-use join token consumed by
+// verifyJoinToken when the join it authorized failed
+func (s *SignalingServer) releaseJoinToken(claims *auth.AccessTokenClaims) {
+	if claims == nil {
+		return
+	}
+
+	s.mu.RLock()
+	tracker := s.tokenTracker
+	s.mu.RUnlock()
+
+	if tracker != nil {
+		tracker.Release(claims)
+	}
+}
+
+// applyGrant gives a participant exactly the permissions a token's video
+// grant allows
+func applyGrant(participant *room.Participant, grant *auth.VideoGrant) {
+	participant.Permissions = room.ParticipantPermissions{
+		CanPublish:        grant.CanPublish,
+		CanSubscribe:      grant.CanSubscribe,
+		CanPublishData:    grant.CanPublishData,
+		CanUpdateMetadata: grant.RoomAdmin,
+		Hidden:            grant.Hidden,
+	}
+	participant.CanPublish = grant.CanPublish
+	participant.CanSubscribe = grant.CanSubscribe
+	participant.CanPublishData = grant.CanPublishData
+	participant.IsAdmin = grant.RoomAdmin
+	participant.IsHidden = grant.Hidden
+	participant.IsRecorder = grant.Recorder
+}
+
 // handleLeaveRoom handles leave room messages
 func (c *WSClient) handleLeaveRoom(msg *WSMessage) {
 	c.mu.RLock()
 	roomID := c.roomID
 	participantID := c.participantID
+	waitingRoomID := c.waitingRoomID
 	c.mu.RUnlock()
 
+	// Give up waiting in the lobby
+	if waitingRoomID != "" {
+		c.server.leaveLobby(c, waitingRoomID, participantID)
+		return
+	}
+
 	if roomID == "" {
 		c.sendError("not in a room")
 		return
@@ -354,11 +796,16 @@ func (c *WSClient) handleLeaveRoom(msg *WSMessage) {
 		RoomID: roomID,
 		Data: mustMarshal(RoomEventData{
 			EventType: "participant.left",
-			Data:      map[string]string{"participant_id": participantID},
+			Data: map[string]string{
+				"participant_id": participantID,
+				"reason":         LeaveReasonLeft,
+			},
 			Timestamp: time.Now(),
 		}),
 	}, c.id)
 
+	c.server.untrackSession(c)
+
 	// Clear client state
 	c.mu.Lock()
 	c.roomID = ""
@@ -482,6 +929,45 @@ func (c *WSClient) handleSubscribeTrack(msg *WSMessage) {
 		return
 	}
 
+	c.mu.RLock()
+	roomID := c.roomID
+	participantID := c.participantID
+	c.mu.RUnlock()
+
+	if roomID == "" {
+		c.sendError("not in a room")
+		return
+	}
+
+	// Get room
+	rm, err := c.server.roomManager.GetRoom(roomID)
+	if err != nil {
+		c.sendError("room not found")
+		return
+	}
+
+	// Enforce permissions and per-publisher subscribe rules
+	if err := rm.AuthorizeSubscribe(participantID, data.ParticipantID, data.TrackID); err != nil {
+		if errors.Is(err, room.ErrSubscribeDenied) {
+			c.server.notifyModerators(rm, &WSMessage{
+				Type:   MsgRoomEvent,
+				RoomID: roomID,
+				Data: mustMarshal(RoomEventData{
+					EventType: string(room.EventSubscribeDenied),
+					Data: room.SubscribeDenial{
+						SubscriberID: participantID,
+						PublisherID:  data.ParticipantID,
+						TrackID:      data.TrackID,
+						Timestamp:    time.Now(),
+					},
+					Timestamp: time.Now(),
+				}),
+			})
+		}
+		c.sendError("failed to subscribe: " + err.Error())
+		return
+	}
+
 	// In a real implementation, this would set up WebRTC subscription
 	// For now, just acknowledge
 	c.sendMessage(&WSMessage{
@@ -545,6 +1031,58 @@ func (c *WSClient) handleUpdateMetadata(msg *WSMessage) {
 	}, "")
 }
 
+// handleUpdateParticipantMetadata handles updates to the sender's own participant metadata
+func (c *WSClient) handleUpdateParticipantMetadata(msg *WSMessage) {
+	var data UpdateMetadataData
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		c.sendError("invalid metadata data")
+		return
+	}
+
+	c.mu.RLock()
+	roomID := c.roomID
+	participantID := c.participantID
+	c.mu.RUnlock()
+
+	if roomID == "" {
+		c.sendError("not in a room")
+		return
+	}
+
+	// Get room
+	rm, err := c.server.roomManager.GetRoom(roomID)
+	if err != nil {
+		c.sendError("room not found")
+		return
+	}
+
+	// Update metadata
+	if err := rm.UpdateParticipantMetadata(participantID, data.Metadata); err != nil {
+		c.sendError("failed to update participant metadata: " + err.Error())
+		return
+	}
+
+	participant, err := rm.GetParticipant(participantID)
+	if err != nil {
+		c.sendError("participant not found")
+		return
+	}
+
+	// Broadcast to all participants
+	c.server.BroadcastToRoom(roomID, &WSMessage{
+		Type:   MsgRoomEvent,
+		RoomID: roomID,
+		Data: mustMarshal(RoomEventData{
+			EventType: string(room.EventParticipantMetadataUpdated),
+			Data: map[string]interface{}{
+				"participant_id": participantID,
+				"metadata":       participant.GetMetadata(),
+			},
+			Timestamp: time.Now(),
+		}),
+	}, "")
+}
+
 // handleSendData handles data channel messages
 func (c *WSClient) handleSendData(msg *WSMessage) {
 	var data DataMessage
@@ -566,6 +1104,31 @@ func (c *WSClient) handleSendData(msg *WSMessage) {
 	// Set sender
 	data.From = participantID
 
+	// Get room
+	rm, err := c.server.roomManager.GetRoom(roomID)
+	if err != nil {
+		c.sendError("room not found")
+		return
+	}
+
+	// Track delivery in rooms with delivery receipts
+	tracked := false
+	if rm.DeliveryReceipts {
+		// Always use a server ID: a client-chosen one could collide with,
+		// and take over, another sender's tracked message
+		data.ID = idgen.WithPrefix(idGenerator, "message")
+
+		var recipients []string
+		if data.To != "" {
+			recipients = []string{data.To}
+		}
+		if err := rm.TrackMessage(data.ID, participantID, recipients); err != nil {
+			c.sendError("failed to send data: " + err.Error())
+			return
+		}
+		tracked = true
+	}
+
 	// Broadcast or send to specific participant
 	if data.To == "" {
 		// Broadcast to all participants
@@ -582,29 +1145,206 @@ func (c *WSClient) handleSendData(msg *WSMessage) {
 			Data:   mustMarshal(data),
 		})
 	}
+
+	// Tell the sender the message ID its receipts will refer to
+	if tracked {
+		c.sendMessage(&WSMessage{
+			Type:   MsgDeliveryReceipt,
+			RoomID: roomID,
+			Data: mustMarshal(room.DeliveryReceipt{
+				MessageID: data.ID,
+				SenderID:  participantID,
+				Status:    room.DeliverySent,
+				Timestamp: time.Now(),
+			}),
+		})
+	}
 }
 
-// BroadcastToRoom broadcasts a message to all clients in a room
-func (s *SignalingServer) BroadcastToRoom(roomID string, msg *WSMessage, excludeClientID string) {
-	s.mu.RLock()
-	clients, ok := s.roomClients[roomID]
-	s.mu.RUnlock()
+// handleDeliveryReceipt handles a recipient's delivery or read
+// acknowledgement and forwards it to the message's sender
+func (c *WSClient) handleDeliveryReceipt(msg *WSMessage) {
+	var data DeliveryReceiptData
+	if err := json.Unmarshal(msg.Data, &data); err != nil || data.MessageID == "" {
+		c.sendError("invalid delivery receipt data")
+		return
+	}
 
-	if !ok {
+	c.mu.RLock()
+	roomID := c.roomID
+	participantID := c.participantID
+	c.mu.RUnlock()
+
+	if roomID == "" {
+		c.sendError("not in a room")
 		return
 	}
 
-	message := mustMarshal(msg)
-	for clientID, client := range clients {
-		if excludeClientID != "" && clientID == excludeClientID {
-			continue
-		}
-		select {
-		case client.send <- message:
-		default:
-			// Client send buffer is full, disconnect
-			go s.unregisterClient(client)
-		}
+	// Get room
+	rm, err := c.server.roomManager.GetRoom(roomID)
+	if err != nil {
+		c.sendError("room not found")
+		return
+	}
+
+	receipt, err := rm.AckDelivery(data.MessageID, participantID, data.Status)
+	if err != nil {
+		c.sendError("failed to acknowledge message: " + err.Error())
+		return
+	}
+
+	// Already acknowledged
+	if receipt == nil {
+		return
+	}
+
+	c.server.SendToParticipant(roomID, receipt.SenderID, &WSMessage{
+		Type:   MsgDeliveryReceipt,
+		RoomID: roomID,
+		Data:   mustMarshal(receipt),
+	})
+}
+
+// handleTyping handles typing indicator updates
+func (c *WSClient) handleTyping(msg *WSMessage) {
+	var data TypingData
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		c.sendError("invalid typing data")
+		return
+	}
+
+	c.mu.RLock()
+	roomID := c.roomID
+	participantID := c.participantID
+	c.mu.RUnlock()
+
+	if roomID == "" {
+		c.sendError("not in a room")
+		return
+	}
+
+	// Get room
+	rm, err := c.server.roomManager.GetRoom(roomID)
+	if err != nil {
+		c.sendError("room not found")
+		return
+	}
+
+	indicator, err := rm.SetTyping(participantID, data.Typing)
+	if err != nil {
+		c.sendError("failed to update typing: " + err.Error())
+		return
+	}
+
+	// Typing state unchanged
+	if indicator == nil {
+		return
+	}
+
+	c.server.BroadcastToRoom(roomID, &WSMessage{
+		Type:   MsgRoomEvent,
+		RoomID: roomID,
+		Data: mustMarshal(RoomEventData{
+			EventType: string(room.EventTypingUpdated),
+			Data:      indicator,
+			Timestamp: indicator.Timestamp,
+		}),
+	}, c.id)
+}
+
+// handleUpdateSharedState applies writes to a room shared state and
+// broadcasts the accepted ones as a delta
+func (c *WSClient) handleUpdateSharedState(msg *WSMessage) {
+	var data UpdateSharedStateData
+	if err := json.Unmarshal(msg.Data, &data); err != nil || data.Name == "" {
+		c.sendError("invalid shared state data")
+		return
+	}
+
+	c.mu.RLock()
+	roomID := c.roomID
+	participantID := c.participantID
+	c.mu.RUnlock()
+
+	if roomID == "" {
+		c.sendError("not in a room")
+		return
+	}
+
+	// Get room
+	rm, err := c.server.roomManager.GetRoom(roomID)
+	if err != nil {
+		c.sendError("room not found")
+		return
+	}
+
+	delta, err := rm.UpdateSharedState(participantID, data.Name, data.Ops)
+	if err != nil {
+		c.sendError("failed to update shared state: " + err.Error())
+		return
+	}
+
+	// Every write lost to a newer one
+	if delta == nil {
+		return
+	}
+
+	// Broadcast to all participants, including the writer so it learns the outcome
+	c.server.BroadcastToRoom(roomID, &WSMessage{
+		Type:   MsgRoomEvent,
+		RoomID: roomID,
+		Data: mustMarshal(RoomEventData{
+			EventType: string(room.EventSharedStateUpdated),
+			Data:      delta,
+			Timestamp: time.Now(),
+		}),
+	}, "")
+}
+
+// handleClientStats handles client-side playback quality reports
+func (c *WSClient) handleClientStats(msg *WSMessage) {
+	var data room.ClientStats
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		c.sendError("invalid client stats data")
+		return
+	}
+
+	c.mu.RLock()
+	roomID := c.roomID
+	participantID := c.participantID
+	c.mu.RUnlock()
+
+	if roomID == "" {
+		c.sendError("not in a room")
+		return
+	}
+
+	if err := c.server.roomManager.ReportClientStats(roomID, participantID, data); err != nil {
+		c.sendError("failed to report client stats: " + err.Error())
+	}
+}
+
+// BroadcastToRoom broadcasts a message to all clients in a room.
+// Room events are sequenced and retained for replay. The message is
+// serialized once and delivered asynchronously, in order per room, so the
+// caller does not wait on large rooms.
+func (s *SignalingServer) BroadcastToRoom(roomID string, msg *WSMessage, excludeClientID string) {
+	var message []byte
+	var seq uint64
+	if msg.Type == MsgRoomEvent {
+		message = s.getReplayBuffer(roomID).append(msg)
+		seq = msg.Seq
+	} else {
+		message = mustMarshal(msg)
+	}
+
+	s.fanout.enqueue(roomID, message, seq, excludeClientID)
+}
+
+// notifyModerators sends a message to each of the room's hosts
+func (s *SignalingServer) notifyModerators(rm *room.Room, msg *WSMessage) {
+	for _, moderator := range rm.Moderators() {
+		s.SendToParticipant(rm.ID, moderator.ID, msg)
 	}
 }
 
@@ -625,9 +1365,7 @@ func (s *SignalingServer) SendToParticipant(roomID, participantID string, msg *W
 		client.mu.RUnlock()
 
 		if isTarget {
-			select {
-			case client.send <- message:
-			default:
+			if !client.enqueue(message) {
 				go s.unregisterClient(client)
 			}
 			break
@@ -640,34 +1378,15 @@ func (s *SignalingServer) unregisterClient(client *WSClient) {
 	client.mu.RLock()
 	roomID := client.roomID
 	participantID := client.participantID
+	waitingRoomID := client.waitingRoomID
 	client.mu.RUnlock()
 
 	// Remove from room if in one
 	if roomID != "" && participantID != "" {
-		if rm, err := s.roomManager.GetRoom(roomID); err == nil {
-			rm.RemoveParticipant(participantID)
-
-			// Notify other participants
-			s.BroadcastToRoom(roomID, &WSMessage{
-				Type:   MsgRoomEvent,
-				RoomID: roomID,
-				Data: mustMarshal(RoomEventData{
-					EventType: "participant.left",
-					Data:      map[string]string{"participant_id": participantID},
-					Timestamp: time.Now(),
-				}),
-			}, client.id)
-		}
-
-		// Remove from room clients
-		s.mu.Lock()
-		if clients, ok := s.roomClients[roomID]; ok {
-			delete(clients, client.id)
-			if len(clients) == 0 {
-				delete(s.roomClients, roomID)
-			}
-		}
-		s.mu.Unlock()
+		s.removeFromRoom(client, roomID, participantID, LeaveReasonDisconnected)
+	}
+	if waitingRoomID != "" {
+		s.leaveLobby(client, waitingRoomID, participantID)
 	}
 
 	// Remove from global clients
@@ -675,17 +1394,52 @@ func (s *SignalingServer) unregisterClient(client *WSClient) {
 	delete(s.clients, client.id)
 	s.mu.Unlock()
 
-	close(client.send)
+	client.mu.Lock()
+	if !client.closed {
+		client.closed = true
+		close(client.send)
+	}
+	client.mu.Unlock()
 
 	s.logger.Info("WebSocket client disconnected", logger.String("client_id", client.id))
 }
 
+// removeFromRoom removes a client's participant from a room and notifies the others
+func (s *SignalingServer) removeFromRoom(client *WSClient, roomID, participantID, reason string) {
+	if rm, err := s.roomManager.GetRoom(roomID); err == nil {
+		rm.RemoveParticipant(participantID)
+
+		// Notify other participants
+		s.BroadcastToRoom(roomID, &WSMessage{
+			Type:   MsgRoomEvent,
+			RoomID: roomID,
+			Data: mustMarshal(RoomEventData{
+				EventType: "participant.left",
+				Data: map[string]string{
+					"participant_id": participantID,
+					"reason":         reason,
+				},
+				Timestamp: time.Now(),
+			}),
+		}, client.id)
+	}
+
+	s.untrackSession(client)
+
+	// Remove from room clients
+	s.mu.Lock()
+	if clients, ok := s.roomClients[roomID]; ok {
+		delete(clients, client.id)
+		if len(clients) == 0 {
+			delete(s.roomClients, roomID)
+		}
+	}
+	s.mu.Unlock()
+}
+
 // sendMessage sends a message to the client
 func (c *WSClient) sendMessage(msg *WSMessage) {
-	data := mustMarshal(msg)
-	select {
-	case c.send <- data:
-	default:
+	if !c.enqueue(mustMarshal(msg)) {
 		// Buffer full, disconnect
 		go c.server.unregisterClient(c)
 	}
@@ -706,19 +1460,18 @@ func mustMarshal(v interface{}) json.RawMessage {
 	return data
 }
 
-func generateClientID() string {
-	return "client_" + time.Now().Format("20060102150405") + "_" + randString(8)
+// idGenerator produces client and participant IDs
+var idGenerator = idgen.NewSwappable(idgen.Default())
+
+// SetIDGenerator replaces the generator used for client and participant IDs
+func SetIDGenerator(gen idgen.Generator) {
+	idGenerator.Set(gen)
 }
 
-func generateParticipantID() string {
-	return "participant_" + time.Now().Format("20060102150405") + "_" + randString(8)
+func generateClientID() string {
+	return idgen.WithPrefix(idGenerator, "client")
 }
 
-func randString(n int) string {
-	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
-	b := make([]byte, n)
-	for i := range b {
-		b[i] = letters[time.Now().UnixNano()%int64(len(letters))]
-	}
-	return string(b)
+func generateParticipantID() string {
+	return idgen.WithPrefix(idGenerator, "participant")
 }
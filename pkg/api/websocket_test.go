@@ -0,0 +1,37 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratedIDsAreUnique(t *testing.T) {
+	const n = 10000
+
+	clientIDs := make(map[string]bool, n)
+	participantIDs := make(map[string]bool, n)
+
+	for i := 0; i < n; i++ {
+		clientID := generateClientID()
+		if clientIDs[clientID] {
+			t.Fatalf("Duplicate client ID after %d generations: %s", i, clientID)
+		}
+		clientIDs[clientID] = true
+
+		participantID := generateParticipantID()
+		if participantIDs[participantID] {
+			t.Fatalf("Duplicate participant ID after %d generations: %s", i, participantID)
+		}
+		participantIDs[participantID] = true
+	}
+}
+
+func TestGeneratedIDPrefixes(t *testing.T) {
+	if id := generateClientID(); !strings.HasPrefix(id, "client_") {
+		t.Errorf("Expected client ID prefix, got %s", id)
+	}
+
+	if id := generateParticipantID(); !strings.HasPrefix(id, "participant_") {
+		t.Errorf("Expected participant ID prefix, got %s", id)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
-	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -252,7 +251,7 @@ func (s *MemoryAPIKeyStore) GetAPIKey(ctx context.Context, accessKey string) (*A
 
 	key, ok := s.keys[accessKey]
 	if !ok {
-		return nil, fmt.Errorf("API key not found")
+		return nil, ErrAPIKeyNotFound
 	}
 	return key, nil
 }
@@ -275,7 +274,7 @@ func (s *MemoryAPIKeyStore) UpdateAPIKey(ctx context.Context, key *APIKey) error
 	defer s.mu.Unlock()
 
 	if _, ok := s.keys[key.AccessKey]; !ok {
-		return fmt.Errorf("API key not found")
+		return ErrAPIKeyNotFound
 	}
 	s.keys[key.AccessKey] = key
 	return nil
@@ -299,7 +298,7 @@ func ParseAPIKeyFromAuth(authHeader string) (accessKey, secretKey string, err er
 	// Split by colon
 	parts := strings.Split(authHeader, ":")
 	if len(parts) != 2 {
-		return "", "", fmt.Errorf("invalid API key format, expected ACCESS_KEY:SECRET_KEY")
+		return "", "", ErrInvalidAPIKeyFormat
 	}
 
 	return parts[0], parts[1], nil
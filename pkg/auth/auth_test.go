@@ -2,9 +2,20 @@ package auth
 
 import (
 	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/aminofox/zenlive/pkg/clock"
 	"github.com/aminofox/zenlive/pkg/types"
 )
 
@@ -299,6 +310,33 @@ func TestSessionManager(t *testing.T) {
 			t.Errorf("Expected 0 sessions, got %d", len(sessions))
 		}
 	})
+
+	t.Run("ConcurrencyLimit", func(t *testing.T) {
+		sm.SetMaxConcurrentSessions(1, EvictReject)
+		if _, err := sm.CreateSession(ctx, "device1", user); err != nil {
+			t.Fatalf("CreateSession failed: %v", err)
+		}
+		if _, err := sm.CreateSession(ctx, "device2", user); err == nil {
+			t.Error("Expected second session to be rejected")
+		}
+
+		var evicted []string
+		sm.OnSessionEvicted(func(session *Session) {
+			evicted = append(evicted, session.SessionID)
+		})
+		sm.SetMaxConcurrentSessions(1, EvictOldest)
+		if _, err := sm.CreateSession(ctx, "device2", user); err != nil {
+			t.Fatalf("CreateSession failed: %v", err)
+		}
+		if len(evicted) != 1 || evicted[0] != "device1" {
+			t.Errorf("Expected device1 to be evicted, got %v", evicted)
+		}
+
+		sessions, _ := sm.GetUserSessions(ctx, user.ID)
+		if len(sessions) != 1 || sessions[0].SessionID != "device2" {
+			t.Errorf("Expected only device2 to remain, got %d sessions", len(sessions))
+		}
+	})
 }
 
 func TestRBACAuthorizer(t *testing.T) {
@@ -472,3 +510,619 @@ func TestAuthRateLimiter(t *testing.T) {
 		}
 	})
 }
+
+func TestTokenUseTracker(t *testing.T) {
+	secret := "test-secret"
+
+	token, err := NewAccessTokenBuilder("api-key", secret).
+		SetIdentity("user1").
+		SetRoomJoin("room1").
+		SetSingleUse(true).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build token: %v", err)
+	}
+
+	claims, err := ParseAccessToken(token, secret)
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+
+	if claims.TokenID == "" {
+		t.Fatal("Single-use token should have a generated token ID")
+	}
+
+	tracker := NewTokenUseTracker()
+
+	if err := tracker.Consume(claims); err != nil {
+		t.Fatalf("First use should succeed: %v", err)
+	}
+
+	if err := tracker.Consume(claims); err != ErrTokenAlreadyUsed {
+		t.Errorf("Expected ErrTokenAlreadyUsed, got %v", err)
+	}
+
+	// A released token can be presented again
+	tracker.Release(claims)
+	if err := tracker.Consume(claims); err != nil {
+		t.Errorf("Released token should be accepted: %v", err)
+	}
+
+	// Reusable tokens are not tracked
+	reusable := &AccessTokenClaims{Identity: "user2", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+	for i := 0; i < 2; i++ {
+		if err := tracker.Consume(reusable); err != nil {
+			t.Errorf("Reusable token should be accepted: %v", err)
+		}
+	}
+
+	// Expired entries are purged
+	expired := &AccessTokenClaims{TokenID: "tok_expired", SingleUse: true, ExpiresAt: time.Now().Add(-time.Second).Unix()}
+	tracker.Consume(expired)
+	tracker.CleanExpired()
+	if tracker.IsUsed("tok_expired") {
+		t.Error("Expired token ID should be purged")
+	}
+
+	if err := tracker.Consume(&AccessTokenClaims{SingleUse: true}); err != ErrTokenIDRequired {
+		t.Errorf("Expected ErrTokenIDRequired, got %v", err)
+	}
+}
+
+func TestTokenBinding(t *testing.T) {
+	secret := "test-secret"
+
+	token, err := NewAccessTokenBuilder("api-key", secret).
+		SetIdentity("user1").
+		SetRoomJoin("room1").
+		SetBoundIP("203.0.113.0/24").
+		SetBoundDeviceID("device-abc").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build token: %v", err)
+	}
+
+	claims, err := ParseAccessToken(token, secret)
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+
+	tests := []struct {
+		ip     string
+		device string
+		want   error
+	}{
+		{"203.0.113.7", "device-abc", nil},
+		{"203.0.113.250", "device-abc", nil},
+		{"198.51.100.1", "device-abc", ErrBindingMismatch},
+		{"203.0.113.7", "device-xyz", ErrBindingMismatch},
+		{"not-an-ip", "device-abc", ErrBindingMismatch},
+	}
+
+	for _, tt := range tests {
+		if err := claims.VerifyBinding(tt.ip, tt.device); err != tt.want {
+			t.Errorf("VerifyBinding(%s, %s) = %v, want %v", tt.ip, tt.device, err, tt.want)
+		}
+	}
+
+	exact := &AccessTokenClaims{BoundIP: "2001:db8::1"}
+	if err := exact.VerifyBinding("2001:db8::1", ""); err != nil {
+		t.Errorf("Expected exact IPv6 match, got %v", err)
+	}
+
+	if _, err := NewAccessTokenBuilder("api-key", secret).SetIdentity("user1").SetBoundIP("bogus").Build(); err != ErrInvalidBoundIP {
+		t.Errorf("Expected ErrInvalidBoundIP, got %v", err)
+	}
+}
+
+func TestDelegationToken(t *testing.T) {
+	secret := "test-secret"
+
+	admin := &AccessTokenClaims{
+		Identity:  "admin1",
+		Name:      "Support Admin",
+		ExpiresAt: time.Now().Add(30 * time.Minute).Unix(),
+		Video: &VideoGrant{
+			RoomJoin:     true,
+			Room:         "room1",
+			RoomAdmin:    true,
+			CanSubscribe: true,
+			Hidden:       true,
+		},
+	}
+
+	token, err := NewDelegationToken("api-key", secret, admin, "user42", &VideoGrant{
+		RoomJoin:     true,
+		Room:         "room1",
+		CanPublish:   true,
+		CanSubscribe: true,
+		RoomAdmin:    true,
+	}, 2*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create delegation token: %v", err)
+	}
+
+	claims, err := ParseAccessToken(token, secret)
+	if err != nil {
+		t.Fatalf("Failed to parse delegation token: %v", err)
+	}
+
+	if claims.Identity != "user42" {
+		t.Errorf("Expected target identity user42, got %s", claims.Identity)
+	}
+	if !claims.IsDelegated() || claims.Actor.Subject != "admin1" {
+		t.Errorf("Expected act claim for admin1, got %+v", claims.Actor)
+	}
+	if claims.Video.CanPublish || claims.Video.RoomAdmin {
+		t.Error("Delegated grant must not exceed the admin's grant or include room admin")
+	}
+	if !claims.Video.CanSubscribe {
+		t.Error("Expected subscribe scope to be delegated")
+	}
+	if claims.ExpiresAt > admin.ExpiresAt {
+		t.Error("Delegated token must not outlive the admin token")
+	}
+
+	if _, err := NewDelegationToken("api-key", secret, admin, "user42", &VideoGrant{RoomJoin: true, Room: "room2"}, time.Hour); err != ErrScopeExceedsGrant {
+		t.Errorf("Expected ErrScopeExceedsGrant, got %v", err)
+	}
+
+	if _, err := NewDelegationToken("api-key", secret, claims, "user43", nil, time.Hour); err != ErrDelegationNotAllowed {
+		t.Errorf("Expected ErrDelegationNotAllowed for non-admin, got %v", err)
+	}
+
+	for _, ttl := range []time.Duration{0, -time.Minute} {
+		if _, err := NewDelegationToken("api-key", secret, admin, "user42", nil, ttl); err != ErrInvalidDelegationTTL {
+			t.Errorf("Expected ErrInvalidDelegationTTL for ttl %v, got %v", ttl, err)
+		}
+	}
+
+	// A short ttl is kept, and an expired admin token can't delegate
+	token, _ = NewDelegationToken("api-key", secret, admin, "user42", nil, time.Minute)
+	if claims, _ := ParseAccessToken(token, secret); claims == nil || claims.ExpiresAt > time.Now().Add(time.Minute).Unix() {
+		t.Errorf("Expected the delegated token to expire within the ttl, got %+v", claims)
+	}
+	admin.ExpiresAt = time.Now().Add(-time.Second).Unix()
+	if _, err := NewDelegationToken("api-key", secret, admin, "user42", nil, time.Hour); err != ErrTokenExpired {
+		t.Errorf("Expected ErrTokenExpired for an expired admin token, got %v", err)
+	}
+}
+
+func TestRBACRoleHierarchy(t *testing.T) {
+	authorizer := NewRBACAuthorizer()
+
+	const (
+		roleChatMod   types.UserRole = "chat_moderator"
+		roleLeadMod   types.UserRole = "lead_moderator"
+		roleUndefined types.UserRole = "undefined"
+	)
+
+	if err := authorizer.DefineRole(roleChatMod, []types.Permission{types.PermissionChatModerate}, types.RoleViewer); err != nil {
+		t.Fatalf("Failed to define role: %v", err)
+	}
+	if err := authorizer.DefineRole(roleLeadMod, []types.Permission{types.PermissionStreamUpdate}, roleChatMod); err != nil {
+		t.Fatalf("Failed to define role: %v", err)
+	}
+
+	lead := &types.User{ID: "lead1", Role: roleLeadMod}
+
+	for _, perm := range []types.Permission{types.PermissionStreamUpdate, types.PermissionChatModerate, types.PermissionStreamView} {
+		if !authorizer.HasPermission(lead, perm) {
+			t.Errorf("Lead moderator should inherit permission %s", perm)
+		}
+	}
+	if authorizer.HasPermission(lead, types.PermissionUserManage) {
+		t.Error("Lead moderator should not have user management permission")
+	}
+
+	if err := authorizer.DefineRole(roleChatMod, nil, roleLeadMod); err == nil {
+		t.Error("Expected error for inheritance cycle")
+	}
+	if err := authorizer.DefineRole("other", nil, roleUndefined); err == nil {
+		t.Error("Expected error for undefined inherited role")
+	}
+
+	// Per-user overrides
+	viewer := &types.User{ID: "viewer1", Role: types.RoleViewer}
+	authorizer.GrantPermission(viewer.ID, types.PermissionStreamCreate)
+	if !authorizer.HasPermission(viewer, types.PermissionStreamCreate) {
+		t.Error("Viewer should have granted permission")
+	}
+
+	authorizer.RevokePermission(viewer.ID, types.PermissionStreamCreate)
+	if authorizer.HasPermission(viewer, types.PermissionStreamCreate) {
+		t.Error("Viewer should lose revoked permission")
+	}
+}
+
+func TestPolicyEngine(t *testing.T) {
+	engine := NewPolicyEngine()
+	ctx := context.Background()
+
+	if err := engine.AddPolicy(Policy{
+		ID:        "owner-manage",
+		Effect:    PolicyAllow,
+		Actions:   []string{"stream:update", "stream:delete"},
+		Condition: `resource.owner == subject.id || subject.role in ["admin"]`,
+	}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	if err := engine.AddPolicy(Policy{
+		ID:        "no-delete-live",
+		Effect:    PolicyDeny,
+		Actions:   []string{"stream:delete"},
+		Condition: `resource.live == true && !(subject.role == "admin")`,
+	}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	if err := engine.AddPolicy(Policy{ID: "bad", Effect: PolicyAllow, Condition: "resource.owner =="}); err == nil {
+		t.Error("Expected error for invalid condition")
+	}
+
+	owner := UserAttributes(&types.User{ID: "user1", Role: types.RoleStreamer})
+	other := UserAttributes(&types.User{ID: "user2", Role: types.RoleViewer})
+	stream := map[string]interface{}{"owner": "user1", "live": true}
+
+	tests := []struct {
+		name    string
+		subject map[string]interface{}
+		action  string
+		allowed bool
+	}{
+		{"owner update", owner, "stream:update", true},
+		{"other update", other, "stream:update", false},
+		{"owner delete live", owner, "stream:delete", false},
+		{"admin delete live", map[string]interface{}{"id": "admin1", "role": "admin"}, "stream:delete", true},
+		{"unmatched action", owner, "stream:view", false},
+	}
+
+	for _, tt := range tests {
+		decision, err := engine.Evaluate(ctx, PolicyRequest{Subject: tt.subject, Resource: stream, Action: tt.action})
+		if err != nil {
+			t.Fatalf("%s: evaluate failed: %v", tt.name, err)
+		}
+		if decision.Allowed != tt.allowed {
+			t.Errorf("%s: expected allowed=%v, got %v (%s)", tt.name, tt.allowed, decision.Allowed, decision.Reason)
+		}
+	}
+
+	if err := engine.AddPolicy(Policy{
+		ID:        "viewers-quota",
+		Effect:    PolicyAllow,
+		Actions:   []string{"*"},
+		Condition: `subject.level >= 3 && env.region != "restricted"`,
+	}); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	req := PolicyRequest{
+		Subject:     map[string]interface{}{"level": 5},
+		Action:      "chat:send",
+		Environment: map[string]interface{}{"region": "eu"},
+	}
+	if err := engine.Authorize(ctx, req); err != nil {
+		t.Errorf("Expected request to be allowed: %v", err)
+	}
+
+	req.Environment["region"] = "restricted"
+	if err := engine.Authorize(ctx, req); err == nil {
+		t.Error("Expected request to be denied")
+	}
+}
+
+func TestPolicyMissingAttributes(t *testing.T) {
+	engine := NewPolicyEngine()
+	ctx := context.Background()
+
+	policies := []Policy{
+		{ID: "owner", Effect: PolicyAllow, Actions: []string{"owner"}, Condition: `resource.owner == subject.id`},
+		{ID: "not-banned", Effect: PolicyAllow, Actions: []string{"not-banned"}, Condition: `subject.status != "banned"`},
+		{ID: "member", Effect: PolicyAllow, Actions: []string{"member"}, Condition: `subject.team in resource.teams`},
+		{ID: "unowned", Effect: PolicyAllow, Actions: []string{"unowned"}, Condition: `resource.owner == null`},
+	}
+	for _, policy := range policies {
+		if err := engine.AddPolicy(policy); err != nil {
+			t.Fatalf("Failed to add policy: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		req     PolicyRequest
+		allowed bool
+	}{
+		{"both sides missing", PolicyRequest{Action: "owner"}, false},
+		{"owner missing", PolicyRequest{Action: "owner", Subject: map[string]interface{}{"id": "user1"}}, false},
+		{"owner matches", PolicyRequest{Action: "owner", Subject: map[string]interface{}{"id": "user1"}, Resource: map[string]interface{}{"owner": "user1"}}, true},
+		{"status missing", PolicyRequest{Action: "not-banned"}, false},
+		{"status set", PolicyRequest{Action: "not-banned", Subject: map[string]interface{}{"status": "active"}}, true},
+		{"team missing", PolicyRequest{Action: "member", Resource: map[string]interface{}{"teams": []interface{}{nil, "red"}}}, false},
+		{"explicit null check", PolicyRequest{Action: "unowned"}, true},
+		{"explicit null check with owner", PolicyRequest{Action: "unowned", Resource: map[string]interface{}{"owner": "user1"}}, false},
+	}
+
+	for _, tt := range tests {
+		decision, err := engine.Evaluate(ctx, tt.req)
+		if err != nil {
+			t.Fatalf("%s: evaluate failed: %v", tt.name, err)
+		}
+		if decision.Allowed != tt.allowed {
+			t.Errorf("%s: expected allowed=%v, got %v (%s)", tt.name, tt.allowed, decision.Allowed, decision.Reason)
+		}
+	}
+}
+
+func TestOIDCAuthenticator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "key1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	defer jwksServer.Close()
+
+	signIDToken := func(claims map[string]interface{}) string {
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "key1", "typ": "JWT"})
+		payload, _ := json.Marshal(claims)
+		message := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		digest := sha256.Sum256([]byte(message))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return message + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+
+	userStore := NewInMemoryUserStore()
+	jwtAuth := NewJWTAuthenticator("test-secret", userStore, NewInMemoryTokenStore())
+
+	oidc, err := NewOIDCAuthenticator(OIDCConfig{
+		Provider: "google",
+		Issuer:   "https://accounts.example.com",
+		ClientID: "zenlive-client",
+		JWKSURL:  jwksServer.URL,
+	}, userStore, jwtAuth)
+	if err != nil {
+		t.Fatalf("Failed to create OIDC authenticator: %v", err)
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":            "https://accounts.example.com",
+		"aud":            []string{"zenlive-client"},
+		"sub":            "12345",
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+
+	token, user, err := oidc.Login(ctx, signIDToken(claims))
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if user.ID != "google|12345" || user.Username != "alice@example.com" || user.Role != types.RoleViewer {
+		t.Errorf("Unexpected user: %+v", user)
+	}
+
+	accessClaims, err := jwtAuth.ValidateToken(ctx, token.AccessToken)
+	if err != nil {
+		t.Fatalf("Issued access token is invalid: %v", err)
+	}
+	if accessClaims.UserID != user.ID {
+		t.Errorf("Expected access token for %s, got %s", user.ID, accessClaims.UserID)
+	}
+
+	// Second login reuses the stored user
+	if _, again, err := oidc.Login(ctx, signIDToken(claims)); err != nil || again.ID != user.ID {
+		t.Errorf("Expected second login to reuse user, got err=%v", err)
+	}
+
+	invalid := []struct {
+		name  string
+		key   string
+		value interface{}
+	}{
+		{"wrong issuer", "iss", "https://evil.example.com"},
+		{"wrong audience", "aud", "other-client"},
+		{"expired", "exp", now.Add(-time.Hour).Unix()},
+	}
+
+	for _, tt := range invalid {
+		bad := make(map[string]interface{}, len(claims))
+		for k, v := range claims {
+			bad[k] = v
+		}
+		bad[tt.key] = tt.value
+
+		if _, err := oidc.VerifyIDToken(ctx, signIDToken(bad)); err == nil {
+			t.Errorf("%s: expected verification to fail", tt.name)
+		}
+	}
+
+	tampered := signIDToken(claims)
+	if _, err := oidc.VerifyIDToken(ctx, tampered[:len(tampered)-4]+"AAAA"); err == nil {
+		t.Error("Expected tampered token to fail verification")
+	}
+}
+
+// unavailableUserStore fails lookups as an unreachable backend would
+type unavailableUserStore struct {
+	*InMemoryUserStore
+	created int
+}
+
+func (s *unavailableUserStore) GetUserByID(ctx context.Context, userID string) (*types.User, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (s *unavailableUserStore) CreateUser(ctx context.Context, user *types.User, password string) error {
+	s.created++
+	return s.InMemoryUserStore.CreateUser(ctx, user, password)
+}
+
+func TestOIDCResolveUserLookupError(t *testing.T) {
+	store := &unavailableUserStore{InMemoryUserStore: NewInMemoryUserStore()}
+	oidc := &OIDCAuthenticator{config: OIDCConfig{Provider: "google"}, userStore: store}
+
+	if _, err := oidc.resolveUser(context.Background(), &OIDCClaims{Subject: "12345"}); err == nil {
+		t.Error("Expected the lookup error to be returned")
+	}
+	if store.created != 0 {
+		t.Errorf("Expected no user to be created, got %d", store.created)
+	}
+}
+
+func TestAccessTokenBuildBatch(t *testing.T) {
+	builder := NewAccessTokenBuilder("api-key", "secret").
+		SetRoomJoin("webinar").
+		SetCanSubscribe(true).
+		SetSingleUse(true)
+
+	tokens, err := builder.BuildBatch([]TokenSpec{
+		{Identity: "viewer-1", Name: "Viewer"},
+		{Identity: "host-1", Name: "Host", Grant: &VideoGrant{RoomJoin: true, Room: "webinar", CanPublish: true}},
+	})
+	if err != nil {
+		t.Fatalf("BuildBatch failed: %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("Expected 2 tokens, got %d", len(tokens))
+	}
+
+	viewer, err := ParseAccessToken(tokens[0], "secret")
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	host, err := ParseAccessToken(tokens[1], "secret")
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+
+	if viewer.Identity != "viewer-1" || !viewer.Video.CanSubscribe || viewer.Video.CanPublish {
+		t.Errorf("Unexpected viewer claims: %+v", viewer.Video)
+	}
+	if host.Identity != "host-1" || !host.Video.CanPublish {
+		t.Errorf("Unexpected host claims: %+v", host.Video)
+	}
+	if viewer.TokenID == "" || viewer.TokenID == host.TokenID {
+		t.Error("Expected each token to have a unique ID")
+	}
+
+	if _, err := builder.BuildBatch([]TokenSpec{{Identity: "ok"}, {}}); !errors.Is(err, ErrIdentityRequired) {
+		t.Errorf("Expected ErrIdentityRequired, got %v", err)
+	}
+}
+
+func TestStreamKeyManager(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	manager, err := NewStreamKeyManager("ingest-secret")
+	if err != nil {
+		t.Fatalf("NewStreamKeyManager failed: %v", err)
+	}
+	manager.SetClock(fake)
+
+	if _, err := NewStreamKeyManager(""); err != ErrStreamKeySecret {
+		t.Errorf("Expected ErrStreamKeySecret for an empty secret, got %v", err)
+	}
+
+	key, err := manager.DeriveStreamKey("user1", "stream1")
+	if err != nil {
+		t.Fatalf("DeriveStreamKey failed: %v", err)
+	}
+
+	info, err := manager.VerifyStreamKey(key)
+	if err != nil {
+		t.Fatalf("VerifyStreamKey failed: %v", err)
+	}
+	if info.UserID != "user1" || info.StreamID != "stream1" {
+		t.Errorf("Unexpected stream key info: %+v", info)
+	}
+
+	if _, err := ParseStreamKey(key, "other-secret"); err != ErrInvalidStreamKey {
+		t.Errorf("Expected ErrInvalidStreamKey for wrong secret, got %v", err)
+	}
+	if _, err := manager.VerifyStreamKey(key + "x"); err != ErrInvalidStreamKey {
+		t.Errorf("Expected ErrInvalidStreamKey for tampered key, got %v", err)
+	}
+
+	// Revoke a single key
+	other, _ := manager.DeriveStreamKey("user1", "stream1")
+	if err := manager.RevokeStreamKey(key); err != nil {
+		t.Fatalf("RevokeStreamKey failed: %v", err)
+	}
+	if _, err := manager.VerifyStreamKey(key); err != ErrStreamKeyRevoked {
+		t.Errorf("Expected ErrStreamKeyRevoked, got %v", err)
+	}
+	if _, err := manager.VerifyStreamKey(other); err != nil {
+		t.Errorf("Expected other key to remain valid, got %v", err)
+	}
+
+	// Revoke all keys for the stream
+	manager.RevokeStreamKeys("stream1")
+	if err := manager.OnPublish()(other, nil); err != ErrStreamKeyRevoked {
+		t.Errorf("Expected ErrStreamKeyRevoked from publish callback, got %v", err)
+	}
+
+	// Keys issued in the second of revocation are revoked with it
+	fake.Advance(500 * time.Millisecond)
+	sameSecond, _ := manager.DeriveStreamKey("user1", "stream1")
+	if _, err := manager.VerifyStreamKey(sameSecond); err != ErrStreamKeyRevoked {
+		t.Errorf("Expected key derived in the revocation second to be revoked, got %v", err)
+	}
+
+	fake.Advance(time.Second)
+	fresh, _ := manager.DeriveStreamKey("user1", "stream1")
+	if _, err := manager.VerifyStreamKey(fresh); err != nil {
+		t.Errorf("Expected key derived after revocation to be valid, got %v", err)
+	}
+}
+
+func TestSessionManagerClock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sm := NewSessionManager()
+	sm.SetClock(fake)
+	sm.SetIdleTimeout(10 * time.Minute)
+	ctx := context.Background()
+
+	user := &types.User{ID: "user1", Username: "alice"}
+	if _, err := sm.CreateSession(ctx, "s1", user); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	fake.Advance(9 * time.Minute)
+	if _, err := sm.GetSession(ctx, "s1"); err != nil {
+		t.Fatalf("Expected session to be valid before idle timeout: %v", err)
+	}
+
+	fake.Advance(11 * time.Minute)
+	if _, err := sm.GetSession(ctx, "s1"); err == nil {
+		t.Error("Expected session to expire after idle timeout")
+	}
+
+	limiter := NewTokenBucketLimiter(1, 1, time.Minute)
+	limiter.SetClock(fake)
+	if ok, _ := limiter.Allow(ctx, "k"); !ok {
+		t.Fatal("Expected first request to be allowed")
+	}
+	if ok, _ := limiter.Allow(ctx, "k"); ok {
+		t.Error("Expected second request to be limited")
+	}
+	fake.Advance(time.Minute)
+	if ok, _ := limiter.Allow(ctx, "k"); !ok {
+		t.Error("Expected request to be allowed after refill period")
+	}
+}
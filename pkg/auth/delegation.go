@@ -0,0 +1,97 @@
+package auth
+
+import "time"
+
+// ActorClaim identifies the party actually presenting a delegated token (RFC 8693 "act")
+type ActorClaim struct {
+	// Subject is the real identity of the actor
+	Subject string `json:"sub"`
+
+	// Name is the actor's display name
+	Name string `json:"name,omitempty"`
+}
+
+// Delegation errors
+var (
+	ErrDelegationNotAllowed = &AuthError{Message: "token is not allowed to delegate"}
+	ErrScopeExceedsGrant    = &AuthError{Message: "delegated scope exceeds the delegating grant"}
+	ErrInvalidDelegationTTL = &AuthError{Message: "delegation ttl must be positive"}
+)
+
+// NewDelegationToken mints a token that acts as targetIdentity on behalf of
+// the admin described by admin. The delegated grant is the intersection of
+// scopes and the admin's grant, never includes room admin, and expires after
+// ttl, capped at the admin token's remaining lifetime. An expired admin
+// token can't delegate. The admin is recorded in the act claim.
+func NewDelegationToken(apiKey, apiSecret string, admin *AccessTokenClaims, targetIdentity string, scopes *VideoGrant, ttl time.Duration) (string, error) {
+	if admin == nil || admin.Video == nil || !admin.Video.RoomAdmin {
+		return "", ErrDelegationNotAllowed
+	}
+
+	// Delegated tokens cannot be delegated again
+	if admin.Actor != nil {
+		return "", ErrDelegationNotAllowed
+	}
+
+	if targetIdentity == "" {
+		return "", ErrIdentityRequired
+	}
+
+	if ttl <= 0 {
+		return "", ErrInvalidDelegationTTL
+	}
+
+	now := time.Now()
+	if admin.ExpiresAt > 0 && admin.ExpiresAt <= now.Unix() {
+		return "", ErrTokenExpired
+	}
+
+	if scopes == nil {
+		scopes = &VideoGrant{RoomJoin: true, Room: admin.Video.Room, CanSubscribe: true}
+	}
+
+	if admin.Video.Room != "" && scopes.Room != admin.Video.Room {
+		return "", ErrScopeExceedsGrant
+	}
+
+	// A delegated token never outlives the admin's
+	expiresAt := now.Add(ttl).Unix()
+	if admin.ExpiresAt > 0 && admin.ExpiresAt < expiresAt {
+		expiresAt = admin.ExpiresAt
+	}
+
+	claims := &AccessTokenClaims{
+		Identity:  targetIdentity,
+		Video:     restrictGrant(scopes, admin.Video),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt,
+		Issuer:    apiKey,
+		Actor: &ActorClaim{
+			Subject: admin.Identity,
+			Name:    admin.Name,
+		},
+	}
+
+	return generateJWT(claims, apiSecret)
+}
+
+// IsDelegated returns whether the token was minted through delegation
+func (c *AccessTokenClaims) IsDelegated() bool {
+	return c.Actor != nil
+}
+
+// restrictGrant returns the permissions present in both requested and allowed
+func restrictGrant(requested, allowed *VideoGrant) *VideoGrant {
+	return &VideoGrant{
+		RoomJoin:       requested.RoomJoin && allowed.RoomJoin,
+		Room:           requested.Room,
+		RoomCreate:     requested.RoomCreate && allowed.RoomCreate,
+		RoomList:       requested.RoomList && allowed.RoomList,
+		RoomAdmin:      false,
+		CanPublish:     requested.CanPublish && allowed.CanPublish,
+		CanSubscribe:   requested.CanSubscribe && allowed.CanSubscribe,
+		CanPublishData: requested.CanPublishData && allowed.CanPublishData,
+		Hidden:         requested.Hidden && allowed.Hidden,
+		Recorder:       requested.Recorder && allowed.Recorder,
+	}
+}
@@ -61,6 +61,11 @@ func (j *JWTAuthenticator) Authenticate(ctx context.Context, credentials *types.
 		return nil, errors.NewAuthenticationError("invalid credentials")
 	}
 
+	return j.issueTokens(ctx, user)
+}
+
+// issueTokens generates and stores an access and refresh token pair for user
+func (j *JWTAuthenticator) issueTokens(ctx context.Context, user *types.User) (*types.AuthToken, error) {
 	// Generate access and refresh tokens
 	now := time.Now()
 
@@ -218,25 +223,25 @@ func (j *JWTAuthenticator) parseToken(token string) (*TokenClaims, error) {
 	// Split token into parts
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
-		return nil, fmt.Errorf("invalid token format")
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
 	}
 
 	// Verify signature
 	message := parts[0] + "." + parts[1]
 	expectedSignature := j.sign(message)
 	if parts[2] != expectedSignature {
-		return nil, fmt.Errorf("invalid token signature")
+		return nil, fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
 	}
 
 	// Decode payload
 	payloadJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode payload: %w", err)
+		return nil, fmt.Errorf("%w: failed to decode payload: %w", ErrInvalidToken, err)
 	}
 
 	var payload map[string]interface{}
 	if err := json.Unmarshal(payloadJSON, &payload); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+		return nil, fmt.Errorf("%w: failed to unmarshal payload: %w", ErrInvalidToken, err)
 	}
 
 	// Extract claims
@@ -315,25 +320,25 @@ func parseJWT(token, secret string) (*AccessTokenClaims, error) {
 	// Split token into parts
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
-		return nil, fmt.Errorf("invalid token format")
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
 	}
 
 	// Verify signature
 	message := parts[0] + "." + parts[1]
 	expectedSignature := signWithSecret(message, secret)
 	if parts[2] != expectedSignature {
-		return nil, fmt.Errorf("invalid token signature")
+		return nil, fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
 	}
 
 	// Decode payload
 	payloadJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode payload: %w", err)
+		return nil, fmt.Errorf("%w: failed to decode payload: %w", ErrInvalidToken, err)
 	}
 
 	var claims AccessTokenClaims
 	if err := json.Unmarshal(payloadJSON, &claims); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+		return nil, fmt.Errorf("%w: failed to unmarshal payload: %w", ErrInvalidToken, err)
 	}
 
 	return &claims, nil
@@ -0,0 +1,458 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/errors"
+	"github.com/aminofox/zenlive/pkg/types"
+)
+
+// DefaultJWKSCacheTTL is how long fetched signing keys are cached
+const DefaultJWKSCacheTTL = time.Hour
+
+// oidcClockSkew is the tolerance applied to exp, nbf and iat checks
+const oidcClockSkew = time.Minute
+
+// OIDCConfig configures an OpenID Connect identity provider
+type OIDCConfig struct {
+	// Provider is a short name for the provider (e.g. "google"), used to namespace user IDs
+	Provider string
+
+	// Issuer is the expected iss claim (e.g. "https://accounts.google.com")
+	Issuer string
+
+	// ClientID is the expected aud claim
+	ClientID string
+
+	// JWKSURL is the provider's JSON Web Key Set endpoint
+	JWKSURL string
+
+	// DefaultRole is assigned to users created on first login (default: viewer)
+	DefaultRole types.UserRole
+
+	// JWKSCacheTTL is how long keys are cached (default: 1 hour)
+	JWKSCacheTTL time.Duration
+
+	// HTTPClient fetches the JWKS (default: client with a 10 second timeout)
+	HTTPClient *http.Client
+}
+
+// OIDCClaims are the validated claims of an OIDC ID token
+type OIDCClaims struct {
+	Issuer            string          `json:"iss"`
+	Subject           string          `json:"sub"`
+	Audience          oidcAudience    `json:"aud"`
+	ExpiresAt         int64           `json:"exp"`
+	IssuedAt          int64           `json:"iat"`
+	NotBefore         int64           `json:"nbf,omitempty"`
+	Email             string          `json:"email,omitempty"`
+	EmailVerified     bool            `json:"email_verified,omitempty"`
+	Name              string          `json:"name,omitempty"`
+	PreferredUsername string          `json:"preferred_username,omitempty"`
+	Raw               json.RawMessage `json:"-"`
+}
+
+// oidcAudience accepts both the string and array forms of the aud claim
+type oidcAudience []string
+
+// UnmarshalJSON decodes a string or array audience
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = oidcAudience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+
+	return nil
+}
+
+// contains reports whether the audience includes clientID
+func (a oidcAudience) contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// OIDCAuthenticator signs users in with ID tokens from an OpenID Connect
+// provider and issues the same token pairs as JWTAuthenticator
+type OIDCAuthenticator struct {
+	config    OIDCConfig
+	userStore UserStore
+	tokens    *JWTAuthenticator
+
+	keys      map[string]crypto.PublicKey // kid -> key
+	fetchedAt time.Time
+	mu        sync.RWMutex
+	fetchMu   sync.Mutex
+}
+
+// NewOIDCAuthenticator creates an OIDC authenticator. Users are looked up and
+// created in userStore and tokens are issued by tokens.
+func NewOIDCAuthenticator(config OIDCConfig, userStore UserStore, tokens *JWTAuthenticator) (*OIDCAuthenticator, error) {
+	if config.Issuer == "" || config.ClientID == "" || config.JWKSURL == "" {
+		return nil, errors.NewValidationError("OIDC issuer, client ID and JWKS URL are required")
+	}
+
+	if config.Provider == "" {
+		config.Provider = "oidc"
+	}
+	if config.DefaultRole == "" {
+		config.DefaultRole = types.RoleViewer
+	}
+	if config.JWKSCacheTTL <= 0 {
+		config.JWKSCacheTTL = DefaultJWKSCacheTTL
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &OIDCAuthenticator{
+		config:    config,
+		userStore: userStore,
+		tokens:    tokens,
+		keys:      make(map[string]crypto.PublicKey),
+	}, nil
+}
+
+// Login validates an ID token, creates the user on first login and returns a token pair
+func (o *OIDCAuthenticator) Login(ctx context.Context, idToken string) (*types.AuthToken, *types.User, error) {
+	claims, err := o.VerifyIDToken(ctx, idToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := o.resolveUser(ctx, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !user.IsActive {
+		return nil, nil, errors.NewAuthenticationError("user account is disabled")
+	}
+
+	token, err := o.tokens.issueTokens(ctx, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return token, user, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience and lifetime of an ID token
+func (o *OIDCAuthenticator) VerifyIDToken(ctx context.Context, idToken string) (*OIDCClaims, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.NewInvalidTokenError()
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.NewInvalidTokenError()
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, errors.NewInvalidTokenError()
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.NewInvalidTokenError()
+	}
+
+	key, err := o.getKey(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInvalidToken, "invalid ID token signature", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.NewInvalidTokenError()
+	}
+
+	var claims OIDCClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.NewInvalidTokenError()
+	}
+	claims.Raw = payload
+
+	if claims.Issuer != o.config.Issuer {
+		return nil, errors.NewAuthenticationError("unexpected ID token issuer")
+	}
+	if !claims.Audience.contains(o.config.ClientID) {
+		return nil, errors.NewAuthenticationError("ID token not issued for this client")
+	}
+	if claims.Subject == "" {
+		return nil, errors.NewAuthenticationError("ID token has no subject")
+	}
+
+	now := time.Now()
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(oidcClockSkew)) {
+		return nil, errors.NewTokenExpiredError()
+	}
+	if claims.NotBefore != 0 && now.Add(oidcClockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, errors.NewAuthenticationError("ID token not yet valid")
+	}
+
+	return &claims, nil
+}
+
+// resolveUser returns the local user for the claims, creating it on first
+// login. A lookup failure other than not found is returned, as the user may
+// exist.
+func (o *OIDCAuthenticator) resolveUser(ctx context.Context, claims *OIDCClaims) (*types.User, error) {
+	userID := o.config.Provider + "|" + claims.Subject
+
+	user, err := o.userStore.GetUserByID(ctx, userID)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.IsErrorCode(err, errors.ErrCodeNotFound) {
+		return nil, errors.Wrap(errors.ErrCodeAuthenticationFailed, "failed to look up user", err)
+	}
+
+	username := claims.PreferredUsername
+	if username == "" && claims.EmailVerified {
+		username = claims.Email
+	}
+	if username == "" {
+		username = userID
+	}
+
+	now := time.Now()
+	user = &types.User{
+		ID:        userID,
+		Username:  username,
+		Email:     claims.Email,
+		Role:      o.config.DefaultRole,
+		CreatedAt: now,
+		UpdatedAt: now,
+		IsActive:  true,
+		Metadata: map[string]interface{}{
+			"oidc_provider": o.config.Provider,
+			"oidc_subject":  claims.Subject,
+		},
+	}
+	if claims.Name != "" {
+		user.Metadata["name"] = claims.Name
+	}
+
+	// Federated users cannot sign in with a password
+	password, err := generateRandomKey("oidc", 32)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.userStore.CreateUser(ctx, user, password); err != nil {
+		return nil, errors.Wrap(errors.ErrCodeAuthenticationFailed, "failed to create user", err)
+	}
+
+	return user, nil
+}
+
+// getKey returns the signing key for kid, refreshing the JWKS when the cache
+// is stale or the key is unknown
+func (o *OIDCAuthenticator) getKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	o.mu.RLock()
+	key, ok := o.lookupKey(kid)
+	fresh := time.Since(o.fetchedAt) < o.config.JWKSCacheTTL
+	o.mu.RUnlock()
+
+	if ok && fresh {
+		return key, nil
+	}
+
+	if err := o.refreshKeys(ctx); err != nil {
+		// Fall back to a stale key rather than failing while the provider is unreachable
+		if ok {
+			return key, nil
+		}
+		return nil, errors.Wrap(errors.ErrCodeAuthenticationFailed, "failed to fetch JWKS", err)
+	}
+
+	o.mu.RLock()
+	key, ok = o.lookupKey(kid)
+	o.mu.RUnlock()
+
+	if !ok {
+		return nil, errors.NewAuthenticationError("unknown ID token signing key")
+	}
+
+	return key, nil
+}
+
+// lookupKey finds a key by ID, or the only key when kid is empty. Caller must hold o.mu.
+func (o *OIDCAuthenticator) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(o.keys) == 1 {
+		for _, key := range o.keys {
+			return key, true
+		}
+	}
+
+	key, ok := o.keys[kid]
+	return key, ok
+}
+
+// refreshKeys fetches the JWKS from the provider
+func (o *OIDCAuthenticator) refreshKeys(ctx context.Context) error {
+	o.fetchMu.Lock()
+	defer o.fetchMu.Unlock()
+
+	// Another caller may have refreshed while we waited
+	o.mu.RLock()
+	recent := time.Since(o.fetchedAt) < time.Second
+	o.mu.RUnlock()
+	if recent {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.config.JWKSURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := o.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected JWKS status %d", resp.StatusCode)
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip keys of unsupported types
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	o.mu.Lock()
+	o.keys = keys
+	o.fetchedAt = time.Now()
+	o.mu.Unlock()
+
+	return nil
+}
+
+// jsonWebKey is an RSA or EC public key from a JWKS document
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// publicKey converts the JWK to a crypto public key
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+// verifyJWTSignature verifies an RS256 or ES256 signature
+func verifyJWTSignature(alg string, key crypto.PublicKey, message string, signature []byte) error {
+	digest := sha256.Sum256([]byte(message))
+
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("key does not match algorithm %s", alg)
+		}
+		return rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature)
+
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return fmt.Errorf("key does not match algorithm %s", alg)
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return fmt.Errorf("signature verification failed")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported algorithm %q", alg)
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aminofox/zenlive/pkg/types"
+)
+
+// PolicyEffect is the outcome a matching policy produces
+type PolicyEffect string
+
+const (
+	// PolicyAllow grants the request when the policy matches
+	PolicyAllow PolicyEffect = "allow"
+	// PolicyDeny rejects the request when the policy matches
+	PolicyDeny PolicyEffect = "deny"
+)
+
+// Policy is an attribute-based access rule.
+//
+// Condition is an expression over the request attributes, for example
+//
+//	resource.owner == subject.id && subject.role in ["streamer", "admin"]
+//
+// Supported operators are ==, !=, <, <=, >, >=, in, &&, || and !, with
+// parentheses for grouping. Operands are attribute paths rooted at subject,
+// resource, action or env, or string, number, boolean, null and list literals.
+// An empty condition always matches.
+//
+// A missing attribute never satisfies a comparison: resource.owner ==
+// subject.id and resource.owner != "bob" are both false when either side
+// is absent, so an allow condition cannot match on missing data. Test for
+// absence explicitly with resource.owner == null.
+type Policy struct {
+	// ID identifies the policy in decisions
+	ID string
+	// Effect is applied when the policy matches
+	Effect PolicyEffect
+	// Actions the policy applies to ("*" matches any action)
+	Actions []string
+	// Condition is the attribute expression that must hold
+	Condition string
+
+	expr expression
+}
+
+// PolicyRequest describes an access request to evaluate
+type PolicyRequest struct {
+	// Subject holds attributes of the caller (e.g. id, role)
+	Subject map[string]interface{}
+	// Resource holds attributes of the target (e.g. owner, type)
+	Resource map[string]interface{}
+	// Action is the operation being performed (e.g. "stream:delete")
+	Action string
+	// Environment holds contextual attributes (e.g. ip, time)
+	Environment map[string]interface{}
+}
+
+// PolicyDecision is the result of evaluating a request
+type PolicyDecision struct {
+	// Allowed is true when the request is permitted
+	Allowed bool
+	// PolicyID is the policy that decided the outcome (empty if none matched)
+	PolicyID string
+	// Reason describes the decision
+	Reason string
+}
+
+// PolicyEngine evaluates attribute-based policies. Deny policies take
+// precedence over allow policies and requests matching no policy are denied.
+type PolicyEngine struct {
+	policies []*Policy
+	mu       sync.RWMutex
+}
+
+// NewPolicyEngine creates a new policy engine
+func NewPolicyEngine() *PolicyEngine {
+	return &PolicyEngine{
+		policies: make([]*Policy, 0),
+	}
+}
+
+// AddPolicy compiles and registers a policy
+func (e *PolicyEngine) AddPolicy(policy Policy) error {
+	if policy.ID == "" {
+		return fmt.Errorf("policy ID is required")
+	}
+
+	if policy.Effect != PolicyAllow && policy.Effect != PolicyDeny {
+		return fmt.Errorf("policy %s: invalid effect %q", policy.ID, policy.Effect)
+	}
+
+	if policy.Condition != "" {
+		expr, err := parseExpression(policy.Condition)
+		if err != nil {
+			return fmt.Errorf("policy %s: %w", policy.ID, err)
+		}
+		policy.expr = expr
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, existing := range e.policies {
+		if existing.ID == policy.ID {
+			e.policies[i] = &policy
+			return nil
+		}
+	}
+
+	e.policies = append(e.policies, &policy)
+
+	return nil
+}
+
+// RemovePolicy removes a policy by ID
+func (e *PolicyEngine) RemovePolicy(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, policy := range e.policies {
+		if policy.ID == id {
+			e.policies = append(e.policies[:i], e.policies[i+1:]...)
+			return
+		}
+	}
+}
+
+// Evaluate decides whether the request is allowed
+func (e *PolicyEngine) Evaluate(ctx context.Context, req PolicyRequest) (*PolicyDecision, error) {
+	e.mu.RLock()
+	policies := make([]*Policy, len(e.policies))
+	copy(policies, e.policies)
+	e.mu.RUnlock()
+
+	var allowedBy string
+
+	for _, policy := range policies {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if !policy.appliesTo(req.Action) {
+			continue
+		}
+
+		matched := true
+		if policy.expr != nil {
+			value, err := policy.expr.eval(&req)
+			if err != nil {
+				return nil, fmt.Errorf("policy %s: %w", policy.ID, err)
+			}
+			matched = truthy(value)
+		}
+
+		if !matched {
+			continue
+		}
+
+		if policy.Effect == PolicyDeny {
+			return &PolicyDecision{Allowed: false, PolicyID: policy.ID, Reason: "denied by policy"}, nil
+		}
+
+		if allowedBy == "" {
+			allowedBy = policy.ID
+		}
+	}
+
+	if allowedBy != "" {
+		return &PolicyDecision{Allowed: true, PolicyID: allowedBy, Reason: "allowed by policy"}, nil
+	}
+
+	return &PolicyDecision{Allowed: false, Reason: "no matching policy"}, nil
+}
+
+// Authorize evaluates the request and returns an error unless it is allowed
+func (e *PolicyEngine) Authorize(ctx context.Context, req PolicyRequest) error {
+	decision, err := e.Evaluate(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if !decision.Allowed {
+		return &AuthError{Message: "access denied: " + decision.Reason}
+	}
+
+	return nil
+}
+
+// UserAttributes returns the subject attributes of a user for policy evaluation
+func UserAttributes(user *types.User) map[string]interface{} {
+	if user == nil {
+		return map[string]interface{}{}
+	}
+
+	return map[string]interface{}{
+		"id":       user.ID,
+		"username": user.Username,
+		"email":    user.Email,
+		"role":     string(user.Role),
+	}
+}
+
+// appliesTo reports whether the policy covers action
+func (p *Policy) appliesTo(action string) bool {
+	if len(p.Actions) == 0 {
+		return true
+	}
+
+	for _, a := range p.Actions {
+		if a == "*" || a == action {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,540 @@
+package auth
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// expression is a compiled policy condition
+type expression interface {
+	eval(req *PolicyRequest) (interface{}, error)
+}
+
+// literalExpr is a constant value
+type literalExpr struct {
+	value interface{}
+}
+
+func (e *literalExpr) eval(req *PolicyRequest) (interface{}, error) {
+	return e.value, nil
+}
+
+// listExpr is a list literal
+type listExpr struct {
+	items []expression
+}
+
+func (e *listExpr) eval(req *PolicyRequest) (interface{}, error) {
+	values := make([]interface{}, 0, len(e.items))
+	for _, item := range e.items {
+		value, err := item.eval(req)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// pathExpr is an attribute reference such as resource.owner
+type pathExpr struct {
+	path []string
+}
+
+func (e *pathExpr) eval(req *PolicyRequest) (interface{}, error) {
+	var current interface{}
+
+	switch e.path[0] {
+	case "subject":
+		current = req.Subject
+	case "resource":
+		current = req.Resource
+	case "env":
+		current = req.Environment
+	case "action":
+		if len(e.path) > 1 {
+			return nil, nil
+		}
+		return req.Action, nil
+	default:
+		return nil, fmt.Errorf("unknown attribute root %q", e.path[0])
+	}
+
+	// Missing attributes evaluate to nil rather than failing; compareExpr
+	// treats them as satisfying no comparison
+	for _, key := range e.path[1:] {
+		attrs, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		current = attrs[key]
+	}
+
+	return current, nil
+}
+
+// notExpr negates its operand
+type notExpr struct {
+	operand expression
+}
+
+func (e *notExpr) eval(req *PolicyRequest) (interface{}, error) {
+	value, err := e.operand.eval(req)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(value), nil
+}
+
+// logicalExpr is a short-circuiting && or ||
+type logicalExpr struct {
+	op          string
+	left, right expression
+}
+
+func (e *logicalExpr) eval(req *PolicyRequest) (interface{}, error) {
+	left, err := e.left.eval(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.op == "&&" && !truthy(left) {
+		return false, nil
+	}
+	if e.op == "||" && truthy(left) {
+		return true, nil
+	}
+
+	right, err := e.right.eval(req)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(right), nil
+}
+
+// compareExpr is a binary comparison
+type compareExpr struct {
+	op          string
+	left, right expression
+}
+
+func (e *compareExpr) eval(req *PolicyRequest) (interface{}, error) {
+	left, err := e.left.eval(req)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := e.right.eval(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// A missing attribute satisfies no comparison, not even != or a
+	// comparison with another missing attribute, so conditions fail closed.
+	// Only an explicit test against null matches it.
+	if missing(e.left, left) || missing(e.right, right) {
+		if !isNullLiteral(e.left) && !isNullLiteral(e.right) {
+			return false, nil
+		}
+	}
+
+	switch e.op {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	case "in":
+		return containsValue(right, left), nil
+	}
+
+	// Ordering comparisons on numbers or strings
+	if l, ok := toNumber(left); ok {
+		r, ok := toNumber(right)
+		if !ok {
+			return false, nil
+		}
+		return compareOrdered(e.op, l, r), nil
+	}
+
+	if l, ok := left.(string); ok {
+		r, ok := right.(string)
+		if !ok {
+			return false, nil
+		}
+		return compareOrdered(e.op, l, r), nil
+	}
+
+	return false, nil
+}
+
+// missing reports whether expr is an attribute path that evaluated to no value
+func missing(expr expression, value interface{}) bool {
+	_, isPath := expr.(*pathExpr)
+	return isPath && value == nil
+}
+
+// isNullLiteral reports whether expr is the null literal
+func isNullLiteral(expr expression) bool {
+	lit, ok := expr.(*literalExpr)
+	return ok && lit.value == nil
+}
+
+// compareOrdered applies an ordering operator
+func compareOrdered[T float64 | string](op string, l, r T) bool {
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+	return false
+}
+
+// truthy converts a value to a boolean
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+
+	if n, ok := toNumber(value); ok {
+		return n != 0
+	}
+
+	return true
+}
+
+// toNumber converts numeric values to float64
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// valuesEqual compares two values, treating all numeric types alike. nil
+// only equals nil, which policies reach through explicit null checks.
+func valuesEqual(a, b interface{}) bool {
+	if an, ok := toNumber(a); ok {
+		bn, ok := toNumber(b)
+		return ok && an == bn
+	}
+
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || a == nil {
+		return a == nil && b == nil
+	}
+
+	if !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+
+	return a == b
+}
+
+// containsValue reports whether list contains value
+func containsValue(list, value interface{}) bool {
+	if list == nil {
+		return false
+	}
+
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return false
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		if valuesEqual(v.Index(i).Interface(), value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// exprToken is a lexical token of a policy condition
+type exprToken struct {
+	kind  string // "ident", "string", "number", "op", "eof"
+	value string
+}
+
+// tokenizeExpression splits a condition into tokens
+func tokenizeExpression(input string) ([]exprToken, error) {
+	tokens := make([]exprToken, 0)
+
+	for i := 0; i < len(input); {
+		c := input[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(input[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			tokens = append(tokens, exprToken{kind: "string", value: input[i+1 : i+1+end]})
+			i += end + 2
+
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(input) && input[i+1] >= '0' && input[i+1] <= '9':
+			start := i
+			i++
+			for i < len(input) && (input[i] >= '0' && input[i] <= '9' || input[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: "number", value: input[start:i]})
+
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			start := i
+			for i < len(input) && (input[i] == '_' || input[i] == '.' ||
+				input[i] >= 'a' && input[i] <= 'z' || input[i] >= 'A' && input[i] <= 'Z' ||
+				input[i] >= '0' && input[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: "ident", value: input[start:i]})
+
+		default:
+			if i+1 < len(input) {
+				switch two := input[i : i+2]; two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					tokens = append(tokens, exprToken{kind: "op", value: two})
+					i += 2
+					continue
+				}
+			}
+
+			switch c {
+			case '<', '>', '!', '(', ')', '[', ']', ',':
+				tokens = append(tokens, exprToken{kind: "op", value: string(c)})
+				i++
+			default:
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+		}
+	}
+
+	return append(tokens, exprToken{kind: "eof"}), nil
+}
+
+// exprParser is a recursive descent parser for policy conditions
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+// parseExpression compiles a policy condition
+func parseExpression(input string) (expression, error) {
+	tokens, err := tokenizeExpression(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != "eof" {
+		return nil, fmt.Errorf("unexpected token %q", tok.value)
+	}
+
+	return expr, nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != "eof" {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is the operator op
+func (p *exprParser) accept(op string) bool {
+	if tok := p.peek(); tok.kind == "op" && tok.value == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{op: "||", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (expression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{op: "&&", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (expression, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{operand: operand}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (expression, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	switch {
+	case tok.kind == "op" && (tok.value == "==" || tok.value == "!=" ||
+		tok.value == "<" || tok.value == "<=" || tok.value == ">" || tok.value == ">="):
+	case tok.kind == "ident" && tok.value == "in":
+	default:
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return &compareExpr{op: tok.value, left: left, right: right}, nil
+}
+
+func (p *exprParser) parseOperand() (expression, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case "string":
+		return &literalExpr{value: tok.value}, nil
+
+	case "number":
+		n, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.value)
+		}
+		return &literalExpr{value: n}, nil
+
+	case "ident":
+		switch tok.value {
+		case "true":
+			return &literalExpr{value: true}, nil
+		case "false":
+			return &literalExpr{value: false}, nil
+		case "null":
+			return &literalExpr{value: nil}, nil
+		}
+
+		path := strings.Split(tok.value, ".")
+		for _, segment := range path {
+			if segment == "" {
+				return nil, fmt.Errorf("invalid attribute path %q", tok.value)
+			}
+		}
+
+		switch path[0] {
+		case "subject", "resource", "action", "env":
+		default:
+			return nil, fmt.Errorf("unknown attribute root %q", path[0])
+		}
+
+		return &pathExpr{path: path}, nil
+
+	case "op":
+		switch tok.value {
+		case "(":
+			expr, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.accept(")") {
+				return nil, fmt.Errorf("expected ')'")
+			}
+			return expr, nil
+
+		case "[":
+			list := &listExpr{}
+			if p.accept("]") {
+				return list, nil
+			}
+			for {
+				item, err := p.parseOperand()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+
+				if p.accept("]") {
+					return list, nil
+				}
+				if !p.accept(",") {
+					return nil, fmt.Errorf("expected ',' or ']' in list")
+				}
+			}
+		}
+	}
+
+	if tok.kind == "eof" {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	return nil, fmt.Errorf("unexpected token %q", tok.value)
+}
@@ -5,6 +5,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aminofox/zenlive/pkg/clock"
 	"github.com/aminofox/zenlive/pkg/errors"
 )
 
@@ -33,6 +34,7 @@ type TokenBucketLimiter struct {
 	capacity     int
 	refillRate   int
 	refillPeriod time.Duration
+	clock        clock.Clock
 }
 
 // NewTokenBucketLimiter creates a new token bucket rate limiter
@@ -45,9 +47,17 @@ func NewTokenBucketLimiter(capacity int, refillRate int, refillPeriod time.Durat
 		capacity:     capacity,
 		refillRate:   refillRate,
 		refillPeriod: refillPeriod,
+		clock:        clock.Real(),
 	}
 }
 
+// SetClock sets the clock used for token refills
+func (rl *TokenBucketLimiter) SetClock(c clock.Clock) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.clock = clock.OrReal(c)
+}
+
 // Allow checks if an action is allowed for a key
 func (rl *TokenBucketLimiter) Allow(ctx context.Context, key string) (bool, error) {
 	rl.mu.Lock()
@@ -58,7 +68,7 @@ func (rl *TokenBucketLimiter) Allow(ctx context.Context, key string) (bool, erro
 	if !exists {
 		b = &bucket{
 			tokens:       rl.capacity,
-			lastRefill:   time.Now(),
+			lastRefill:   rl.clock.Now(),
 			capacity:     rl.capacity,
 			refillRate:   rl.refillRate,
 			refillPeriod: rl.refillPeriod,
@@ -67,7 +77,7 @@ func (rl *TokenBucketLimiter) Allow(ctx context.Context, key string) (bool, erro
 	}
 
 	// Refill tokens based on time elapsed
-	now := time.Now()
+	now := rl.clock.Now()
 	elapsed := now.Sub(b.lastRefill)
 	if elapsed >= b.refillPeriod {
 		periods := int(elapsed / b.refillPeriod)
@@ -99,7 +109,7 @@ func (rl *TokenBucketLimiter) CleanupOldBuckets(maxAge time.Duration) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	now := time.Now()
+	now := rl.clock.Now()
 	for key, b := range rl.buckets {
 		if now.Sub(b.lastRefill) > maxAge {
 			delete(rl.buckets, key)
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"context"
+	"fmt"
+	"sync"
 
 	"github.com/aminofox/zenlive/pkg/errors"
 	"github.com/aminofox/zenlive/pkg/types"
@@ -24,12 +26,198 @@ type Authorizer interface {
 
 // RBACAuthorizer implements role-based access control authorization
 type RBACAuthorizer struct {
-	// Custom permission overrides can be added here in the future
+	// roles stores custom role definitions, which take precedence over built-in roles
+	roles map[types.UserRole]*roleDefinition
+	// userPermissions stores per-user permission grants by user ID
+	userPermissions map[string]map[types.Permission]bool
+	// mu protects concurrent access
+	mu sync.RWMutex
+}
+
+// roleDefinition describes a custom role
+type roleDefinition struct {
+	permissions []types.Permission
+	inherits    []types.UserRole
+}
+
+// builtinRoles are the roles with permissions defined in the types package
+var builtinRoles = []types.UserRole{
+	types.RoleAdmin,
+	types.RoleModerator,
+	types.RoleStreamer,
+	types.RoleViewer,
 }
 
 // NewRBACAuthorizer creates a new RBAC authorizer
 func NewRBACAuthorizer() *RBACAuthorizer {
-	return &RBACAuthorizer{}
+	return &RBACAuthorizer{
+		roles:           make(map[types.UserRole]*roleDefinition),
+		userPermissions: make(map[string]map[types.Permission]bool),
+	}
+}
+
+// DefineRole creates or replaces a role with its own permissions plus the
+// permissions of every inherited role. Inherited roles must already exist
+// and the hierarchy must not contain cycles.
+func (a *RBACAuthorizer) DefineRole(name types.UserRole, permissions []types.Permission, inherits ...types.UserRole) error {
+	if name == "" {
+		return errors.NewValidationError("role name is required")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, parent := range inherits {
+		if parent == name {
+			return errors.NewValidationError(fmt.Sprintf("role %s cannot inherit itself", name))
+		}
+		if !a.roleExists(parent) {
+			return errors.NewValidationError(fmt.Sprintf("inherited role %s is not defined", parent))
+		}
+		if a.inheritsFrom(parent, name, make(map[types.UserRole]bool)) {
+			return errors.NewValidationError(fmt.Sprintf("role %s would create an inheritance cycle", name))
+		}
+	}
+
+	a.roles[name] = &roleDefinition{
+		permissions: append([]types.Permission(nil), permissions...),
+		inherits:    append([]types.UserRole(nil), inherits...),
+	}
+
+	return nil
+}
+
+// GrantPermission grants a permission to a single user regardless of role
+func (a *RBACAuthorizer) GrantPermission(userID string, permission types.Permission) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.userPermissions[userID] == nil {
+		a.userPermissions[userID] = make(map[types.Permission]bool)
+	}
+	a.userPermissions[userID][permission] = true
+}
+
+// RevokePermission removes a per-user permission grant
+func (a *RBACAuthorizer) RevokePermission(userID string, permission types.Permission) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.userPermissions[userID], permission)
+	if len(a.userPermissions[userID]) == 0 {
+		delete(a.userPermissions, userID)
+	}
+}
+
+// GetRolePermissions returns the effective permissions of a role, including inherited ones
+func (a *RBACAuthorizer) GetRolePermissions(role types.UserRole) []types.Permission {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	collected := make(map[types.Permission]bool)
+	a.collectPermissions(role, collected, make(map[types.UserRole]bool))
+
+	permissions := make([]types.Permission, 0, len(collected))
+	for p := range collected {
+		permissions = append(permissions, p)
+	}
+
+	return permissions
+}
+
+// roleExists reports whether role is custom or built-in. Caller must hold a.mu.
+func (a *RBACAuthorizer) roleExists(role types.UserRole) bool {
+	if _, ok := a.roles[role]; ok {
+		return true
+	}
+
+	for _, builtin := range builtinRoles {
+		if builtin == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inheritsFrom reports whether role inherits target. Caller must hold a.mu.
+func (a *RBACAuthorizer) inheritsFrom(role, target types.UserRole, visited map[types.UserRole]bool) bool {
+	if role == target {
+		return true
+	}
+	if visited[role] {
+		return false
+	}
+	visited[role] = true
+
+	def, ok := a.roles[role]
+	if !ok {
+		return false
+	}
+
+	for _, parent := range def.inherits {
+		if a.inheritsFrom(parent, target, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collectPermissions adds the permissions of role and its ancestors. Caller must hold a.mu.
+func (a *RBACAuthorizer) collectPermissions(role types.UserRole, collected map[types.Permission]bool, visited map[types.UserRole]bool) {
+	if visited[role] {
+		return
+	}
+	visited[role] = true
+
+	def, ok := a.roles[role]
+	if !ok {
+		for _, p := range types.GetRolePermissions(role) {
+			collected[p] = true
+		}
+		return
+	}
+
+	for _, p := range def.permissions {
+		collected[p] = true
+	}
+
+	for _, parent := range def.inherits {
+		a.collectPermissions(parent, collected, visited)
+	}
+}
+
+// hasRolePermission reports whether role or its ancestors grant permission. Caller must hold a.mu.
+func (a *RBACAuthorizer) hasRolePermission(role types.UserRole, permission types.Permission, visited map[types.UserRole]bool) bool {
+	if visited[role] {
+		return false
+	}
+	visited[role] = true
+
+	def, ok := a.roles[role]
+	if !ok {
+		for _, p := range types.GetRolePermissions(role) {
+			if p == permission {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, p := range def.permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	for _, parent := range def.inherits {
+		if a.hasRolePermission(parent, permission, visited) {
+			return true
+		}
+	}
+
+	return false
 }
 
 // Authorize checks if a user has permission to perform an action on a resource
@@ -55,26 +243,16 @@ func (a *RBACAuthorizer) HasPermission(user *types.User, permission types.Permis
 		return false
 	}
 
-	// Get permissions for the user's role
-	rolePermissions := types.GetRolePermissions(user.Role)
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 
-	// Check if the permission is in the role's permissions
-	for _, p := range rolePermissions {
-		if p == permission {
-			return true
-		}
+	// Check per-user grants first
+	if a.userPermissions[user.ID][permission] {
+		return true
 	}
 
-	// TODO: Custom user permissions can be added in future phases
-	// if user.Permissions != nil {
-	// 	for _, p := range user.Permissions {
-	// 		if p == permission {
-	// 			return true
-	// 		}
-	// 	}
-	// }
-
-	return false
+	// Resolve through the role hierarchy
+	return a.hasRolePermission(user.Role, permission, make(map[types.UserRole]bool))
 }
 
 // HasRole checks if a user has a specific role
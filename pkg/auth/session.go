@@ -5,6 +5,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aminofox/zenlive/pkg/clock"
 	"github.com/aminofox/zenlive/pkg/errors"
 	"github.com/aminofox/zenlive/pkg/types"
 )
@@ -35,12 +36,22 @@ type Session struct {
 
 // IsExpired checks if the session is expired
 func (s *Session) IsExpired() bool {
-	return time.Now().After(s.ExpiresAt)
+	return s.expiredAt(time.Now())
 }
 
 // IsIdle checks if the session has been idle for longer than the given duration
 func (s *Session) IsIdle(idleTimeout time.Duration) bool {
-	return time.Since(s.LastAccessedAt) > idleTimeout
+	return s.idleAt(time.Now(), idleTimeout)
+}
+
+// expiredAt reports whether the session is expired at now
+func (s *Session) expiredAt(now time.Time) bool {
+	return now.After(s.ExpiresAt)
+}
+
+// idleAt reports whether the session is idle at now
+func (s *Session) idleAt(now time.Time, idleTimeout time.Duration) bool {
+	return now.Sub(s.LastAccessedAt) > idleTimeout
 }
 
 // SessionManager manages user sessions
@@ -50,6 +61,10 @@ type SessionManager struct {
 	mu            sync.RWMutex
 	sessionExpiry time.Duration
 	idleTimeout   time.Duration
+	maxSessions   int
+	evictStrategy EvictStrategy
+	onEvicted     func(session *Session)
+	clock         clock.Clock
 }
 
 // NewSessionManager creates a new session manager
@@ -59,9 +74,17 @@ func NewSessionManager() *SessionManager {
 		userSessions:  make(map[string][]string),
 		sessionExpiry: 24 * time.Hour,   // Default 24 hours
 		idleTimeout:   30 * time.Minute, // Default 30 minutes
+		clock:         clock.Real(),
 	}
 }
 
+// SetClock sets the clock used for session expiry and idle checks
+func (sm *SessionManager) SetClock(c clock.Clock) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.clock = clock.OrReal(c)
+}
+
 // SetSessionExpiry sets the session expiry duration
 func (sm *SessionManager) SetSessionExpiry(duration time.Duration) {
 	sm.sessionExpiry = duration
@@ -75,9 +98,14 @@ func (sm *SessionManager) SetIdleTimeout(duration time.Duration) {
 // CreateSession creates a new session for a user
 func (sm *SessionManager) CreateSession(ctx context.Context, sessionID string, user *types.User) (*Session, error) {
 	sm.mu.Lock()
-	defer sm.mu.Unlock()
 
-	now := time.Now()
+	evicted, err := sm.enforceSessionLimit(user.ID)
+	if err != nil {
+		sm.mu.Unlock()
+		return nil, err
+	}
+
+	now := sm.clock.Now()
 	session := &Session{
 		SessionID:      sessionID,
 		UserID:         user.ID,
@@ -90,6 +118,14 @@ func (sm *SessionManager) CreateSession(ctx context.Context, sessionID string, u
 
 	sm.sessions[sessionID] = session
 	sm.userSessions[user.ID] = append(sm.userSessions[user.ID], sessionID)
+	onEvicted := sm.onEvicted
+	sm.mu.Unlock()
+
+	if onEvicted != nil {
+		for _, s := range evicted {
+			onEvicted(s)
+		}
+	}
 
 	return session, nil
 }
@@ -98,6 +134,7 @@ func (sm *SessionManager) CreateSession(ctx context.Context, sessionID string, u
 func (sm *SessionManager) GetSession(ctx context.Context, sessionID string) (*Session, error) {
 	sm.mu.RLock()
 	session, exists := sm.sessions[sessionID]
+	now := sm.clock.Now()
 	sm.mu.RUnlock()
 
 	if !exists {
@@ -105,20 +142,20 @@ func (sm *SessionManager) GetSession(ctx context.Context, sessionID string) (*Se
 	}
 
 	// Check if expired
-	if session.IsExpired() {
+	if session.expiredAt(now) {
 		sm.DeleteSession(ctx, sessionID)
 		return nil, errors.NewAuthenticationError("session expired")
 	}
 
 	// Check if idle
-	if session.IsIdle(sm.idleTimeout) {
+	if session.idleAt(now, sm.idleTimeout) {
 		sm.DeleteSession(ctx, sessionID)
 		return nil, errors.NewAuthenticationError("session expired due to inactivity")
 	}
 
 	// Update last accessed time
 	sm.mu.Lock()
-	session.LastAccessedAt = time.Now()
+	session.LastAccessedAt = now
 	sm.mu.Unlock()
 
 	return session, nil
@@ -134,10 +171,11 @@ func (sm *SessionManager) GetUserSessions(ctx context.Context, userID string) ([
 		return []*Session{}, nil
 	}
 
+	now := sm.clock.Now()
 	sessions := make([]*Session, 0, len(sessionIDs))
 	for _, sessionID := range sessionIDs {
 		if session, exists := sm.sessions[sessionID]; exists {
-			if !session.IsExpired() && !session.IsIdle(sm.idleTimeout) {
+			if !session.expiredAt(now) && !session.idleAt(now, sm.idleTimeout) {
 				sessions = append(sessions, session)
 			}
 		}
@@ -151,9 +189,20 @@ func (sm *SessionManager) DeleteSession(ctx context.Context, sessionID string) e
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
+	if _, exists := sm.sessions[sessionID]; !exists {
+		return errors.NewNotFoundError("session not found")
+	}
+
+	sm.removeSession(sessionID)
+
+	return nil
+}
+
+// removeSession removes a session from both indexes. Caller must hold sm.mu.
+func (sm *SessionManager) removeSession(sessionID string) {
 	session, exists := sm.sessions[sessionID]
 	if !exists {
-		return errors.NewNotFoundError("session not found")
+		return
 	}
 
 	// Remove from sessions map
@@ -172,8 +221,6 @@ func (sm *SessionManager) DeleteSession(ctx context.Context, sessionID string) e
 	if len(sm.userSessions[session.UserID]) == 0 {
 		delete(sm.userSessions, session.UserID)
 	}
-
-	return nil
 }
 
 // DeleteUserSessions deletes all sessions for a user
@@ -199,7 +246,7 @@ func (sm *SessionManager) CleanExpiredSessions(ctx context.Context) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	now := time.Now()
+	now := sm.clock.Now()
 	for sessionID, session := range sm.sessions {
 		if now.After(session.ExpiresAt) || now.Sub(session.LastAccessedAt) > sm.idleTimeout {
 			// Remove session
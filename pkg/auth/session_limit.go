@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"github.com/aminofox/zenlive/pkg/errors"
+)
+
+// EvictStrategy decides what happens when a user exceeds the session limit
+type EvictStrategy int
+
+const (
+	// EvictReject rejects new sessions once the limit is reached
+	EvictReject EvictStrategy = iota
+	// EvictOldest removes the user's oldest sessions to make room
+	EvictOldest
+)
+
+// SetMaxConcurrentSessions limits the number of active sessions per user
+// (0 = unlimited). When a new session would exceed the limit, strategy
+// decides whether it is rejected or the oldest sessions are evicted.
+func (sm *SessionManager) SetMaxConcurrentSessions(n int, strategy EvictStrategy) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.maxSessions = n
+	sm.evictStrategy = strategy
+}
+
+// OnSessionEvicted registers a callback for sessions evicted by the concurrency limit
+func (sm *SessionManager) OnSessionEvicted(callback func(session *Session)) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.onEvicted = callback
+}
+
+// enforceSessionLimit makes room for one more session for userID and returns
+// the evicted sessions. Caller must hold sm.mu.
+func (sm *SessionManager) enforceSessionLimit(userID string) ([]*Session, error) {
+	if sm.maxSessions <= 0 {
+		return nil, nil
+	}
+
+	// Expired and idle sessions do not count towards the limit
+	for _, sessionID := range append([]string(nil), sm.userSessions[userID]...) {
+		if session := sm.sessions[sessionID]; session.IsExpired() || session.IsIdle(sm.idleTimeout) {
+			sm.removeSession(sessionID)
+		}
+	}
+
+	active := sm.userSessions[userID]
+	if len(active) < sm.maxSessions {
+		return nil, nil
+	}
+
+	if sm.evictStrategy == EvictReject {
+		return nil, errors.NewUnauthorizedError("maximum concurrent sessions reached")
+	}
+
+	// Sessions are stored in creation order, so the oldest come first
+	excess := len(active) - sm.maxSessions + 1
+	evicted := make([]*Session, 0, excess)
+	for _, sessionID := range append([]string(nil), active[:excess]...) {
+		evicted = append(evicted, sm.sessions[sessionID])
+		sm.removeSession(sessionID)
+	}
+
+	return evicted, nil
+}
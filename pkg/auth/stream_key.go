@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/clock"
+)
+
+// streamKeyPrefix marks keys produced by DeriveStreamKey
+const streamKeyPrefix = "sk_"
+
+// Stream key errors
+var (
+	ErrInvalidStreamKey = &AuthError{Message: "invalid stream key"}
+	ErrStreamKeyRevoked = &AuthError{Message: "stream key has been revoked"}
+	ErrStreamKeySecret  = &AuthError{Message: "stream key secret is required"}
+)
+
+// StreamKeyInfo is the identity encoded in a stream key
+type StreamKeyInfo struct {
+	// KeyID uniquely identifies the key for revocation
+	KeyID string `json:"k"`
+	// UserID is the user allowed to publish
+	UserID string `json:"u"`
+	// StreamID is the stream the key publishes to
+	StreamID string `json:"s"`
+	// IssuedAt is when the key was derived (Unix seconds, as a JWT iat)
+	IssuedAt int64 `json:"i"`
+}
+
+// DeriveStreamKey produces a signed ingest key for a user's stream. The key
+// can be verified with the same secret without a lookup table.
+func DeriveStreamKey(userID, streamID, secret string) (string, error) {
+	return deriveStreamKey(userID, streamID, secret, time.Now())
+}
+
+// deriveStreamKey produces a stream key issued at now
+func deriveStreamKey(userID, streamID, secret string, now time.Time) (string, error) {
+	if secret == "" {
+		return "", ErrStreamKeySecret
+	}
+	if userID == "" || streamID == "" {
+		return "", ErrIdentityRequired
+	}
+
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(StreamKeyInfo{
+		KeyID:    base64.RawURLEncoding.EncodeToString(nonce),
+		UserID:   userID,
+		StreamID: streamID,
+		IssuedAt: now.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return streamKeyPrefix + encoded + "." + signStreamKey(encoded, secret), nil
+}
+
+// ParseStreamKey verifies the signature of a stream key and returns its
+// identity. It does not check revocation; use StreamKeyManager for that.
+func ParseStreamKey(key, secret string) (*StreamKeyInfo, error) {
+	if secret == "" {
+		return nil, ErrStreamKeySecret
+	}
+
+	body, ok := strings.CutPrefix(key, streamKeyPrefix)
+	if !ok {
+		return nil, ErrInvalidStreamKey
+	}
+
+	encoded, signature, ok := strings.Cut(body, ".")
+	if !ok {
+		return nil, ErrInvalidStreamKey
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(signStreamKey(encoded, secret))) {
+		return nil, ErrInvalidStreamKey
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidStreamKey
+	}
+
+	var info StreamKeyInfo
+	if err := json.Unmarshal(payload, &info); err != nil {
+		return nil, ErrInvalidStreamKey
+	}
+
+	return &info, nil
+}
+
+// signStreamKey returns a truncated HMAC-SHA256 of the encoded payload
+func signStreamKey(encoded, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(streamKeyPrefix + encoded))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:16])
+}
+
+// StreamKeyManager derives and verifies stream keys with revocation support
+type StreamKeyManager struct {
+	secret        string
+	revokedKeys   map[string]bool  // key ID -> revoked
+	revokedBefore map[string]int64 // stream ID -> keys issued at or before (Unix seconds) are revoked
+	clock         clock.Clock
+	mu            sync.RWMutex
+}
+
+// NewStreamKeyManager creates a stream key manager using secret for signing.
+// The secret must not be empty.
+func NewStreamKeyManager(secret string) (*StreamKeyManager, error) {
+	if secret == "" {
+		return nil, ErrStreamKeySecret
+	}
+
+	return &StreamKeyManager{
+		secret:        secret,
+		revokedKeys:   make(map[string]bool),
+		revokedBefore: make(map[string]int64),
+		clock:         clock.Real(),
+	}, nil
+}
+
+// SetClock sets the clock used for key issue and revocation times
+func (m *StreamKeyManager) SetClock(c clock.Clock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clock = clock.OrReal(c)
+}
+
+// now returns the current time from the manager's clock
+func (m *StreamKeyManager) now() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.clock.Now()
+}
+
+// DeriveStreamKey produces a new ingest key for a user's stream
+func (m *StreamKeyManager) DeriveStreamKey(userID, streamID string) (string, error) {
+	return deriveStreamKey(userID, streamID, m.secret, m.now())
+}
+
+// VerifyStreamKey validates a key and returns the associated user and stream
+func (m *StreamKeyManager) VerifyStreamKey(key string) (*StreamKeyInfo, error) {
+	info, err := ParseStreamKey(key, m.secret)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.revokedKeys[info.KeyID] {
+		return nil, ErrStreamKeyRevoked
+	}
+
+	if revokedAt, ok := m.revokedBefore[info.StreamID]; ok && revokedAt >= info.IssuedAt {
+		return nil, ErrStreamKeyRevoked
+	}
+
+	return info, nil
+}
+
+// RevokeStreamKey revokes a single key
+func (m *StreamKeyManager) RevokeStreamKey(key string) error {
+	info, err := ParseStreamKey(key, m.secret)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revokedKeys[info.KeyID] = true
+
+	return nil
+}
+
+// RevokeStreamKeys revokes every key issued so far for a stream. Issue times
+// have one-second granularity, so keys derived in the same second are
+// revoked too; keys derived in a later second remain valid.
+func (m *StreamKeyManager) RevokeStreamKeys(streamID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revokedBefore[streamID] = m.clock.Now().Unix()
+}
+
+// OnPublish returns a publish callback for rtmp.Server.SetOnPublish that
+// rejects invalid or revoked stream keys
+func (m *StreamKeyManager) OnPublish() func(streamKey string, metadata map[string]interface{}) error {
+	return func(streamKey string, metadata map[string]interface{}) error {
+		_, err := m.VerifyStreamKey(streamKey)
+		return err
+	}
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"net"
+	"strings"
+)
+
+// VerifyBinding checks the presenting client against the token's IP and
+// device bindings. Tokens without bindings accept any client.
+func (c *AccessTokenClaims) VerifyBinding(clientIP, deviceID string) error {
+	if c.BoundIP != "" && !matchBoundIP(c.BoundIP, clientIP) {
+		return ErrBindingMismatch
+	}
+
+	if c.DeviceID != "" && c.DeviceID != deviceID {
+		return ErrBindingMismatch
+	}
+
+	return nil
+}
+
+// matchBoundIP reports whether ip equals bound or falls within the bound CIDR
+func matchBoundIP(bound, ip string) bool {
+	clientIP := net.ParseIP(ip)
+	if clientIP == nil {
+		return false
+	}
+
+	if strings.Contains(bound, "/") {
+		_, network, err := net.ParseCIDR(bound)
+		if err != nil {
+			return false
+		}
+		return network.Contains(clientIP)
+	}
+
+	boundIP := net.ParseIP(bound)
+	return boundIP != nil && boundIP.Equal(clientIP)
+}
+
+// isValidIPOrCIDR reports whether s is an IP address or CIDR range
+func isValidIPOrCIDR(s string) bool {
+	if strings.Contains(s, "/") {
+		_, _, err := net.ParseCIDR(s)
+		return err == nil
+	}
+
+	return net.ParseIP(s) != nil
+}
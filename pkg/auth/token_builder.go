@@ -2,6 +2,7 @@ package auth
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -41,15 +42,20 @@ type VideoGrant struct {
 // AccessTokenClaims represents the complete claims for a room access token
 type AccessTokenClaims struct {
 	// Standard JWT claims
-	Identity  string      `json:"sub"`                // User identity/ID
-	Name      string      `json:"name"`               // Display name
-	Email     string      `json:"email"`              // Email (optional)
-	Metadata  string      `json:"metadata,omitempty"` // Custom metadata as JSON string
-	Video     *VideoGrant `json:"video,omitempty"`    // Video permissions
-	IssuedAt  int64       `json:"iat"`                // Issued at (Unix timestamp)
-	ExpiresAt int64       `json:"exp"`                // Expires at (Unix timestamp)
-	NotBefore int64       `json:"nbf,omitempty"`      // Not valid before (Unix timestamp)
-	Issuer    string      `json:"iss,omitempty"`      // Issuer (access key)
+	Identity  string      `json:"sub"`                  // User identity/ID
+	Name      string      `json:"name"`                 // Display name
+	Email     string      `json:"email"`                // Email (optional)
+	Metadata  string      `json:"metadata,omitempty"`   // Custom metadata as JSON string
+	Video     *VideoGrant `json:"video,omitempty"`      // Video permissions
+	IssuedAt  int64       `json:"iat"`                  // Issued at (Unix timestamp)
+	ExpiresAt int64       `json:"exp"`                  // Expires at (Unix timestamp)
+	NotBefore int64       `json:"nbf,omitempty"`        // Not valid before (Unix timestamp)
+	Issuer    string      `json:"iss,omitempty"`        // Issuer (access key)
+	TokenID   string      `json:"jti,omitempty"`        // Unique token ID
+	SingleUse bool        `json:"single_use,omitempty"` // Token may only be presented once
+	BoundIP   string      `json:"bound_ip,omitempty"`   // Client IP or CIDR the token is bound to
+	DeviceID  string      `json:"device_id,omitempty"`  // Client device ID the token is bound to
+	Actor     *ActorClaim `json:"act,omitempty"`        // Real identity acting through a delegated token
 }
 
 // AccessTokenBuilder helps build access tokens for room joining
@@ -63,6 +69,10 @@ type AccessTokenBuilder struct {
 	grants    *VideoGrant
 	ttl       time.Duration
 	notBefore *time.Time
+	tokenID   string
+	singleUse bool
+	boundIP   string
+	deviceID  string
 }
 
 // NewAccessTokenBuilder creates a new access token builder
@@ -111,6 +121,30 @@ func (b *AccessTokenBuilder) SetNotBefore(notBefore time.Time) *AccessTokenBuild
 	return b
 }
 
+// SetTokenID sets the token ID (jti claim)
+func (b *AccessTokenBuilder) SetTokenID(tokenID string) *AccessTokenBuilder {
+	b.tokenID = tokenID
+	return b
+}
+
+// SetSingleUse marks the token as single-use. A token ID is generated if none is set.
+func (b *AccessTokenBuilder) SetSingleUse(singleUse bool) *AccessTokenBuilder {
+	b.singleUse = singleUse
+	return b
+}
+
+// SetBoundIP binds the token to a client IP address or CIDR range (e.g. "203.0.113.0/24")
+func (b *AccessTokenBuilder) SetBoundIP(ip string) *AccessTokenBuilder {
+	b.boundIP = ip
+	return b
+}
+
+// SetBoundDeviceID binds the token to a client device ID
+func (b *AccessTokenBuilder) SetBoundDeviceID(deviceID string) *AccessTokenBuilder {
+	b.deviceID = deviceID
+	return b
+}
+
 // AddGrant adds a video grant for room access
 func (b *AccessTokenBuilder) AddGrant(grant *VideoGrant) *AccessTokenBuilder {
 	b.grants = grant
@@ -178,6 +212,10 @@ func (b *AccessTokenBuilder) Build() (string, error) {
 		return "", ErrIdentityRequired
 	}
 
+	if b.boundIP != "" && !isValidIPOrCIDR(b.boundIP) {
+		return "", ErrInvalidBoundIP
+	}
+
 	now := time.Now()
 	claims := &AccessTokenClaims{
 		Identity:  b.identity,
@@ -187,6 +225,18 @@ func (b *AccessTokenBuilder) Build() (string, error) {
 		IssuedAt:  now.Unix(),
 		ExpiresAt: now.Add(b.ttl).Unix(),
 		Issuer:    b.apiKey,
+		TokenID:   b.tokenID,
+		SingleUse: b.singleUse,
+		BoundIP:   b.boundIP,
+		DeviceID:  b.deviceID,
+	}
+
+	if claims.SingleUse && claims.TokenID == "" {
+		tokenID, err := generateRandomKey("tok", 16)
+		if err != nil {
+			return "", err
+		}
+		claims.TokenID = tokenID
 	}
 
 	if b.notBefore != nil {
@@ -210,6 +260,59 @@ func (b *AccessTokenBuilder) Build() (string, error) {
 	return token, nil
 }
 
+// TokenSpec describes one participant in a batch of access tokens
+type TokenSpec struct {
+	// Identity is the user identity (required)
+	Identity string
+	// Name is the display name
+	Name string
+	// Email is the user's email
+	Email string
+	// Metadata overrides the builder's metadata when set
+	Metadata map[string]interface{}
+	// Grant overrides the builder's grants when set
+	Grant *VideoGrant
+}
+
+// BuildBatch generates one access token per spec, using the builder's
+// settings for everything a spec does not override. All specs are validated
+// before any token is generated.
+func (b *AccessTokenBuilder) BuildBatch(specs []TokenSpec) ([]string, error) {
+	for i, spec := range specs {
+		if spec.Identity == "" {
+			return nil, fmt.Errorf("spec %d: %w", i, ErrIdentityRequired)
+		}
+	}
+
+	tokens := make([]string, 0, len(specs))
+	for i, spec := range specs {
+		builder := *b
+		builder.identity = spec.Identity
+		builder.name = spec.Name
+		builder.email = spec.Email
+		// Each token gets its own ID
+		builder.tokenID = ""
+
+		if spec.Metadata != nil {
+			builder.metadata = spec.Metadata
+		}
+
+		grants := *b.grants
+		if spec.Grant != nil {
+			grants = *spec.Grant
+		}
+		builder.grants = &grants
+
+		token, err := builder.Build()
+		if err != nil {
+			return nil, fmt.Errorf("spec %d: %w", i, err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
 // ParseAccessToken parses and validates an access token
 func ParseAccessToken(token, apiSecret string) (*AccessTokenClaims, error) {
 	claims, err := parseJWT(token, apiSecret)
@@ -236,6 +339,13 @@ var (
 	ErrTokenExpired     = &AuthError{Message: "token is expired"}
 	ErrTokenNotYetValid = &AuthError{Message: "token is not yet valid"}
 	ErrInvalidToken     = &AuthError{Message: "invalid token"}
+	ErrTokenAlreadyUsed = &AuthError{Message: "token has already been used"}
+	ErrTokenIDRequired  = &AuthError{Message: "single-use token requires a token ID"}
+	ErrInvalidBoundIP   = &AuthError{Message: "bound IP must be an IP address or CIDR"}
+	ErrBindingMismatch  = &AuthError{Message: "token is bound to a different client"}
+
+	ErrAPIKeyNotFound      = &AuthError{Message: "API key not found"}
+	ErrInvalidAPIKeyFormat = &AuthError{Message: "invalid API key format, expected ACCESS_KEY:SECRET_KEY"}
 )
 
 // AuthError represents an authentication error
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenTrackerSweepInterval is how often expired token IDs are purged
+const tokenTrackerSweepInterval = time.Minute
+
+// TokenUseTracker records presented single-use token IDs until they expire
+// so a replayed token is rejected
+type TokenUseTracker struct {
+	used      map[string]time.Time // token ID -> expiry
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+// NewTokenUseTracker creates a new token use tracker
+func NewTokenUseTracker() *TokenUseTracker {
+	return &TokenUseTracker{
+		used:      make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// Consume marks the token as used. Single-use tokens presented a second time
+// before they expire return ErrTokenAlreadyUsed. Other tokens are accepted.
+func (t *TokenUseTracker) Consume(claims *AccessTokenClaims) error {
+	if !claims.SingleUse {
+		return nil
+	}
+
+	if claims.TokenID == "" {
+		return ErrTokenIDRequired
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(t.lastSweep) > tokenTrackerSweepInterval {
+		t.sweep(now)
+	}
+
+	if expiresAt, used := t.used[claims.TokenID]; used && now.Before(expiresAt) {
+		return ErrTokenAlreadyUsed
+	}
+
+	t.used[claims.TokenID] = time.Unix(claims.ExpiresAt, 0)
+
+	return nil
+}
+
+// Release forgets a consumed token so it can be presented again, for when
+// the action it authorized did not happen
+func (t *TokenUseTracker) Release(claims *AccessTokenClaims) {
+	if !claims.SingleUse || claims.TokenID == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.used, claims.TokenID)
+}
+
+// IsUsed returns whether a token ID has been consumed and not yet expired
+func (t *TokenUseTracker) IsUsed(tokenID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	expiresAt, used := t.used[tokenID]
+	return used && time.Now().Before(expiresAt)
+}
+
+// CleanExpired removes token IDs whose tokens have expired
+func (t *TokenUseTracker) CleanExpired() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sweep(time.Now())
+}
+
+// sweep removes expired entries. Caller must hold t.mu.
+func (t *TokenUseTracker) sweep(now time.Time) {
+	for tokenID, expiresAt := range t.used {
+		if !now.Before(expiresAt) {
+			delete(t.used, tokenID)
+		}
+	}
+	t.lastSweep = now
+}
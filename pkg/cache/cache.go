@@ -353,25 +353,63 @@ func (c *InMemoryCache) cleanup() {
 
 // MultiLevelCache implements a multi-level cache
 type MultiLevelCache struct {
-	caches []Cache
-	mu     sync.RWMutex
+	caches     []Cache
+	promote    bool          // Copy values found in lower levels to higher levels on Get
+	promoteTTL time.Duration // TTL for promoted values (0 = level default)
+	mu         sync.RWMutex
 }
 
 // NewMultiLevelCache creates a new multi-level cache
 func NewMultiLevelCache(caches ...Cache) *MultiLevelCache {
 	return &MultiLevelCache{
-		caches: caches,
+		caches:  caches,
+		promote: true,
 	}
 }
 
+// SetPromotion controls whether Get copies values found in lower levels to
+// higher levels, and the TTL they are stored with (0 = level default)
+func (mc *MultiLevelCache) SetPromotion(enabled bool, ttl time.Duration) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.promote = enabled
+	mc.promoteTTL = ttl
+}
+
+// Promote copies a value from the highest level holding it to all levels above
+func (mc *MultiLevelCache) Promote(ctx context.Context, key string) error {
+	mc.mu.RLock()
+	ttl := mc.promoteTTL
+	mc.mu.RUnlock()
+
+	for i, cache := range mc.caches {
+		value, err := cache.Get(ctx, key)
+		if err == nil {
+			for j := 0; j < i; j++ {
+				mc.caches[j].Set(ctx, key, value, ttl)
+			}
+			return nil
+		}
+	}
+
+	return ErrCacheMiss
+}
+
 // Get retrieves a value from the cache hierarchy
 func (mc *MultiLevelCache) Get(ctx context.Context, key string) (interface{}, error) {
+	mc.mu.RLock()
+	promote, ttl := mc.promote, mc.promoteTTL
+	mc.mu.RUnlock()
+
 	for i, cache := range mc.caches {
 		value, err := cache.Get(ctx, key)
 		if err == nil {
 			// Promote to higher levels
-			for j := 0; j < i; j++ {
-				mc.caches[j].Set(ctx, key, value, 0)
+			if promote {
+				for j := 0; j < i; j++ {
+					mc.caches[j].Set(ctx, key, value, ttl)
+				}
 			}
 			return value, nil
 		}
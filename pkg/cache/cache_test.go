@@ -270,3 +270,54 @@ func TestCacheClear(t *testing.T) {
 		t.Errorf("Expected 0 keys after clear, got %d", len(keys))
 	}
 }
+
+func TestCacheWarmAndPreload(t *testing.T) {
+	l1 := NewInMemoryCache(10, 1*time.Minute, EvictionPolicyLRU)
+	l2 := NewInMemoryCache(100, 5*time.Minute, EvictionPolicyLRU)
+	ctx := context.Background()
+
+	if err := Warm(ctx, l2, map[string]interface{}{"stream:1": "meta1", "stream:2": "meta2"}, time.Minute); err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+
+	preloader := NewPreloader(l2, time.Minute)
+	preloader.AddSource("popular", func(ctx context.Context) (map[string]interface{}, error) {
+		return map[string]interface{}{"stream:3": "meta3"}, nil
+	})
+	preloader.AddSource("broken", func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, ErrCacheMiss
+	})
+
+	loaded, err := preloader.Load(ctx)
+	if err == nil {
+		t.Error("Expected error from broken source")
+	}
+	if loaded != 1 {
+		t.Errorf("Expected 1 entry loaded, got %d", loaded)
+	}
+
+	keys, _ := l2.Keys(ctx)
+	if len(keys) != 3 {
+		t.Errorf("Expected 3 warmed keys, got %d", len(keys))
+	}
+
+	// Promotion disabled: lower-level hits stay in L2
+	mlc := NewMultiLevelCache(l1, l2)
+	mlc.SetPromotion(false, 0)
+	if _, err := mlc.Get(ctx, "stream:1"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if exists, _ := l1.Exists(ctx, "stream:1"); exists {
+		t.Error("Expected no promotion to L1 when disabled")
+	}
+
+	if err := mlc.Promote(ctx, "stream:1"); err != nil {
+		t.Fatalf("Promote failed: %v", err)
+	}
+	if exists, _ := l1.Exists(ctx, "stream:1"); !exists {
+		t.Error("Expected explicit Promote to copy key to L1")
+	}
+	if err := mlc.Promote(ctx, "missing"); err != ErrCacheMiss {
+		t.Errorf("Expected ErrCacheMiss, got %v", err)
+	}
+}
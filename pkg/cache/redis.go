@@ -393,6 +393,11 @@ func (rc *RedisCache) SMembers(ctx context.Context, key string) ([]interface{},
 	return result, nil
 }
 
+// CheckHealth pings Redis
+func (rc *RedisCache) CheckHealth(ctx context.Context) error {
+	return rc.client.Ping(ctx).Err()
+}
+
 // getKey returns the full key with prefix
 func (rc *RedisCache) getKey(key string) string {
 	return rc.keyPrefix + key
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Warm stores entries in the cache so the first requests after startup hit.
+// It stops early if ctx is cancelled and returns the first error encountered.
+func Warm(ctx context.Context, c Cache, entries map[string]interface{}, ttl time.Duration) error {
+	var firstErr error
+
+	for key, value := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := c.Set(ctx, key, value, ttl); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("warm %s: %w", key, err)
+		}
+	}
+
+	return firstErr
+}
+
+// LoaderFunc returns entries to preload into a cache
+type LoaderFunc func(ctx context.Context) (map[string]interface{}, error)
+
+// Preloader bulk-loads cache entries from registered sources
+type Preloader struct {
+	cache   Cache
+	ttl     time.Duration
+	sources map[string]LoaderFunc
+	mu      sync.RWMutex
+}
+
+// NewPreloader creates a preloader that warms c with entries stored for ttl
+func NewPreloader(c Cache, ttl time.Duration) *Preloader {
+	return &Preloader{
+		cache:   c,
+		ttl:     ttl,
+		sources: make(map[string]LoaderFunc),
+	}
+}
+
+// AddSource registers a named source of entries
+func (p *Preloader) AddSource(name string, loader LoaderFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sources[name] = loader
+}
+
+// RemoveSource unregisters a source
+func (p *Preloader) RemoveSource(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.sources, name)
+}
+
+// Load runs all sources concurrently and warms the cache with their entries.
+// It returns the number of entries loaded and an error naming any failed sources.
+func (p *Preloader) Load(ctx context.Context) (int, error) {
+	p.mu.RLock()
+	sources := make(map[string]LoaderFunc, len(p.sources))
+	for name, loader := range p.sources {
+		sources[name] = loader
+	}
+	p.mu.RUnlock()
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		loaded int
+		failed []string
+	)
+
+	for name, loader := range sources {
+		wg.Add(1)
+		go func(name string, loader LoaderFunc) {
+			defer wg.Done()
+
+			entries, err := loader(ctx)
+			if err == nil {
+				err = Warm(ctx, p.cache, entries, p.ttl)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+				return
+			}
+			loaded += len(entries)
+		}(name, loader)
+	}
+
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return loaded, fmt.Errorf("preload failed for %d source(s): %v", len(failed), failed)
+	}
+
+	return loaded, nil
+}
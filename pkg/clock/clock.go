@@ -0,0 +1,80 @@
+// Package clock abstracts the current time so TTL and window logic can be
+// tested and simulated by advancing a fake clock instead of sleeping
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock reports the current time
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// Since returns the time elapsed since t
+	Since(t time.Time) time.Duration
+}
+
+// realClock is the wall clock
+type realClock struct{}
+
+// Now returns time.Now()
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Since returns time.Since(t)
+func (realClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+// Real returns the wall clock
+func Real() Clock {
+	return realClock{}
+}
+
+// OrReal returns c, or the wall clock if c is nil
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return realClock{}
+	}
+	return c
+}
+
+// Fake is a clock that only moves when told to
+type Fake struct {
+	now time.Time
+	mu  sync.RWMutex
+}
+
+// NewFake creates a fake clock set to now
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the fake clock's time
+func (f *Fake) Now() time.Time {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.now
+}
+
+// Since returns the fake time elapsed since t
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// Advance moves the fake clock forward by d
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// Set moves the fake clock to t
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = t
+}
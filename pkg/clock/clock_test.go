@@ -0,0 +1,29 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := NewFake(start)
+
+	if !fake.Now().Equal(start) {
+		t.Errorf("Expected %v, got %v", start, fake.Now())
+	}
+
+	fake.Advance(90 * time.Second)
+	if since := fake.Since(start); since != 90*time.Second {
+		t.Errorf("Expected 90s elapsed, got %v", since)
+	}
+
+	fake.Set(start)
+	if !fake.Now().Equal(start) {
+		t.Errorf("Expected clock reset to %v, got %v", start, fake.Now())
+	}
+
+	if OrReal(nil) == nil || OrReal(fake) != Clock(fake) {
+		t.Error("Expected OrReal to default only a nil clock")
+	}
+}
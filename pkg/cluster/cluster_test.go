@@ -282,3 +282,60 @@ func TestServiceSelector(t *testing.T) {
 		t.Log("Round-robin may select same service occasionally, not an error")
 	}
 }
+
+func TestMigrationCoordinator(t *testing.T) {
+	ctx := context.Background()
+	discovery := NewInMemoryServiceDiscovery()
+	sessions := NewInMemorySessionManager(time.Hour)
+
+	for _, node := range []string{"node1", "node2", "node3"} {
+		discovery.Register(ctx, &ServiceInfo{ID: "sfu-" + node, Name: "sfu", NodeID: node, Address: node + ":7880"})
+	}
+
+	for _, id := range []string{"s1", "s2", "s3"} {
+		sessions.CreateSession(ctx, &Session{ID: id, UserID: "user-" + id, StreamID: "room-1", NodeID: "node1"})
+	}
+	sessions.CreateSession(ctx, &Session{ID: "s4", UserID: "user-s4", StreamID: "room-1", NodeID: "node2"})
+
+	coordinator := NewMigrationCoordinator(discovery, sessions, "sfu")
+
+	migrated := make(chan Migration, 10)
+	coordinator.OnParticipantMigrated(func(m Migration) {
+		migrated <- m
+	})
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := coordinator.Start(watchCtx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	discovery.UpdateServiceStatus(ctx, "sfu-node1", ServiceStatusUnhealthy)
+
+	for i := 0; i < 3; i++ {
+		select {
+		case m := <-migrated:
+			if m.FromNode != "node1" || m.ToNode == "node1" || m.Address != m.ToNode+":7880" {
+				t.Errorf("Unexpected migration: %+v", m)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected 3 migrations, got %d", i)
+		}
+	}
+
+	remaining, _ := sessions.GetNodeSessions(ctx, "node1")
+	if len(remaining) != 0 {
+		t.Errorf("Expected no sessions left on node1, got %d", len(remaining))
+	}
+
+	session, _ := sessions.GetSession(ctx, "s1")
+	if session.NodeID == "node1" || session.Data["migrated_from"] != "node1" || session.StreamID != "room-1" {
+		t.Errorf("Expected s1 moved with its room, got %+v", session)
+	}
+
+	// With no other healthy node there is nowhere to go
+	discovery.UpdateServiceStatus(ctx, "sfu-node3", ServiceStatusUnhealthy)
+	if _, err := coordinator.MigrateNode(ctx, "node2"); err != ErrNoHealthyNode {
+		t.Errorf("Expected ErrNoHealthyNode, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNoHealthyNode is returned when no healthy node can take over sessions
+var ErrNoHealthyNode = errors.New("no healthy node available for migration")
+
+// Migration describes a session moved off a failed node
+type Migration struct {
+	SessionID string    // Migrated session
+	UserID    string    // Session user
+	StreamID  string    // Session stream or room
+	FromNode  string    // Failed node
+	ToNode    string    // Node taking over the session
+	Address   string    // Address of the service on ToNode
+	Time      time.Time // When the session was migrated
+}
+
+// MigrationCoordinator moves sessions off failed nodes. It watches service
+// discovery for services of one name (e.g. "sfu") becoming unhealthy or
+// deregistered, reassigns the sessions of their node to healthy nodes in
+// the session manager and reports each move, so the signaling layer can
+// tell the affected clients to renegotiate against the new node.
+type MigrationCoordinator struct {
+	discovery   ServiceDiscovery
+	sessions    SessionManager
+	serviceName string
+	selector    *ServiceSelector
+	onMigrated  []func(Migration)
+	mu          sync.RWMutex
+}
+
+// NewMigrationCoordinator creates a coordinator for services named serviceName
+func NewMigrationCoordinator(discovery ServiceDiscovery, sessions SessionManager, serviceName string) *MigrationCoordinator {
+	return &MigrationCoordinator{
+		discovery:   discovery,
+		sessions:    sessions,
+		serviceName: serviceName,
+		selector:    NewServiceSelector(discovery, RoundRobin),
+	}
+}
+
+// OnParticipantMigrated registers a callback for each migrated session
+func (mc *MigrationCoordinator) OnParticipantMigrated(callback func(Migration)) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.onMigrated = append(mc.onMigrated, callback)
+}
+
+// Start watches for failed nodes until ctx is done
+func (mc *MigrationCoordinator) Start(ctx context.Context) error {
+	events, err := mc.discovery.Watch(ctx)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-events:
+				if event.Service == nil || event.Service.Name != mc.serviceName {
+					continue
+				}
+				failed := event.Type == ServiceEventDeregistered ||
+					(event.Type == ServiceEventHealthChanged && event.Service.Status == ServiceStatusUnhealthy)
+				if failed {
+					mc.MigrateNode(ctx, event.Service.NodeID)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// MigrateNode reassigns every session of a failed node to healthy nodes.
+// Sessions are spread round-robin; the session data is kept, so room state
+// survives the move. It returns the migrations made.
+func (mc *MigrationCoordinator) MigrateNode(ctx context.Context, nodeID string) ([]Migration, error) {
+	sessions, err := mc.sessions.GetNodeSessions(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(sessions))
+	for _, session := range sessions {
+		target, err := mc.selectTarget(ctx, nodeID, session.ID)
+		if err != nil {
+			return migrations, err
+		}
+
+		// Update a copy so the stored session still shows the old node
+		moved := *session
+		moved.Data = make(map[string]interface{}, len(session.Data)+1)
+		for key, value := range session.Data {
+			moved.Data[key] = value
+		}
+		moved.Data["migrated_from"] = nodeID
+		moved.NodeID = target.NodeID
+
+		if err := mc.sessions.UpdateSession(ctx, &moved); err != nil {
+			continue
+		}
+
+		migration := Migration{
+			SessionID: session.ID,
+			UserID:    session.UserID,
+			StreamID:  session.StreamID,
+			FromNode:  nodeID,
+			ToNode:    target.NodeID,
+			Address:   target.Address,
+			Time:      time.Now(),
+		}
+		migrations = append(migrations, migration)
+
+		mc.mu.RLock()
+		callbacks := mc.onMigrated
+		mc.mu.RUnlock()
+
+		for _, callback := range callbacks {
+			callback(migration)
+		}
+	}
+
+	return migrations, nil
+}
+
+// selectTarget picks a healthy service on another node for a session
+func (mc *MigrationCoordinator) selectTarget(ctx context.Context, failedNode, sessionID string) (*ServiceInfo, error) {
+	// The failed node may still be listed as healthy for a moment
+	services, err := mc.discovery.GetServicesByName(ctx, mc.serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	for range services {
+		service, err := mc.selector.SelectService(ctx, mc.serviceName, sessionID)
+		if err != nil {
+			return nil, ErrNoHealthyNode
+		}
+		if service.NodeID != failedNode {
+			return service, nil
+		}
+	}
+
+	return nil, ErrNoHealthyNode
+}
@@ -145,7 +145,7 @@ func (rsm *RedisSessionManager) GetSession(ctx context.Context, sessionID string
 // UpdateSession updates an existing session
 func (rsm *RedisSessionManager) UpdateSession(ctx context.Context, session *Session) error {
 	// Check if session exists
-	_, err := rsm.GetSession(ctx, session.ID)
+	existing, err := rsm.GetSession(ctx, session.ID)
 	if err != nil {
 		return err
 	}
@@ -160,7 +160,23 @@ func (rsm *RedisSessionManager) UpdateSession(ctx context.Context, session *Sess
 	key := rsm.getSessionKey(session.ID)
 
 	// Update session data with TTL refresh
-	return rsm.client.Set(ctx, key, data, rsm.sessionTTL).Err()
+	if err := rsm.client.Set(ctx, key, data, rsm.sessionTTL).Err(); err != nil {
+		return err
+	}
+
+	// Move the session between node indexes when it changes node
+	if existing.NodeID != session.NodeID {
+		if existing.NodeID != "" {
+			rsm.client.SRem(ctx, rsm.getNodeKey(existing.NodeID), session.ID)
+		}
+		if session.NodeID != "" {
+			nodeKey := rsm.getNodeKey(session.NodeID)
+			rsm.client.SAdd(ctx, nodeKey, session.ID)
+			rsm.client.Expire(ctx, nodeKey, rsm.sessionTTL)
+		}
+	}
+
+	return nil
 }
 
 // DeleteSession deletes a session from Redis
@@ -424,10 +440,21 @@ func (ism *InMemorySessionManager) UpdateSession(ctx context.Context, session *S
 	ism.mu.Lock()
 	defer ism.mu.Unlock()
 
-	if _, exists := ism.sessions[session.ID]; !exists {
+	existing, exists := ism.sessions[session.ID]
+	if !exists {
 		return errors.New("session not found")
 	}
 
+	// Move the session between node indexes when it changes node
+	if existing.NodeID != session.NodeID {
+		if existing.NodeID != "" {
+			ism.removeFromIndex(ism.nodeIndex, existing.NodeID, session.ID)
+		}
+		if session.NodeID != "" {
+			ism.nodeIndex[session.NodeID] = append(ism.nodeIndex[session.NodeID], session.ID)
+		}
+	}
+
 	session.LastSeen = time.Now()
 	ism.sessions[session.ID] = session
 
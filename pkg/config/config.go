@@ -34,6 +34,9 @@ type Config struct {
 	// Redis configuration (optional - required when Cluster.Enabled = true)
 	Redis RedisConfig `json:"redis"`
 
+	// Quota configuration (optional - for multi-tenant deployments)
+	Quota QuotaConfig `json:"quota"`
+
 	// Logging configuration
 	Logging LoggingConfig `json:"logging"`
 }
@@ -60,6 +63,10 @@ type ServerConfig struct {
 
 	// DevMode enables development mode
 	DevMode bool `json:"dev_mode" yaml:"dev_mode"`
+
+	// TrustedProxies lists the reverse proxies (IPs or CIDR ranges) allowed
+	// to report client IPs in X-Forwarded-For/X-Real-IP
+	TrustedProxies []string `json:"trusted_proxies" yaml:"trusted_proxies"`
 }
 
 // AuthConfig holds authentication-related configuration
@@ -147,6 +154,9 @@ type RTMPConfig struct {
 
 	// EnableSSL enables RTMPS
 	EnableSSL bool `json:"enable_ssl"`
+
+	// Record records every published stream as FLV under Storage.BasePath
+	Record bool `json:"record"`
 }
 
 // HLSConfig holds HLS-specific configuration
@@ -168,6 +178,10 @@ type WebRTCConfig struct {
 
 	// TURNServers is the list of TURN server configurations
 	TURNServers []TURNServer `json:"turn_servers"`
+
+	// ICECheckInterval is how often STUN/TURN reachability is checked in the
+	// background (0 = default, negative = disabled)
+	ICECheckInterval time.Duration `json:"ice_check_interval"`
 }
 
 // TURNServer represents a TURN server configuration
@@ -260,6 +274,23 @@ type RedisConfig struct {
 	SessionTTL time.Duration `json:"session_ttl" yaml:"session_ttl"`
 }
 
+// QuotaConfig holds per-tenant quota configuration. Tenants are derived from
+// the API key of signed requests. Usage is counted in Redis when
+// Redis.Enabled = true, so limits hold across the cluster.
+type QuotaConfig struct {
+	// Enabled enables quota enforcement
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// MaxRooms is the maximum number of open rooms per tenant (0 = unlimited)
+	MaxRooms int `json:"max_rooms" yaml:"max_rooms"`
+
+	// MaxParticipants is the maximum number of participants per tenant across all rooms (0 = unlimited)
+	MaxParticipants int `json:"max_participants" yaml:"max_participants"`
+
+	// MaxStreams is the maximum number of streams per tenant (0 = unlimited)
+	MaxStreams int `json:"max_streams" yaml:"max_streams"`
+}
+
 // LoggingConfig holds logging-related configuration
 type LoggingConfig struct {
 	// Level is the logging level (debug, info, warn, error)
@@ -0,0 +1,403 @@
+// Package discovery indexes the streams that are live now, with their
+// categories, tags and viewer counts, so clients can browse them and find
+// trending streams
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/clock"
+)
+
+// Stream metadata entries read into the index
+const (
+	// CategoryMetadataKey is the stream metadata entry naming its category
+	CategoryMetadataKey = "category"
+	// TagsMetadataKey is the stream metadata entry listing its tags, comma separated
+	TagsMetadataKey = "tags"
+)
+
+const (
+	// DefaultPageLimit is the page size used when a page has no limit
+	DefaultPageLimit = 20
+	// MaxPageLimit is the largest page size returned
+	MaxPageLimit = 100
+	// MaxTrendingWindow is the longest window trending is ranked over;
+	// older viewer joins are forgotten
+	MaxTrendingWindow = time.Hour
+)
+
+// SortOrder orders the streams of a listing
+type SortOrder string
+
+// Sort orders of a listing
+const (
+	// SortViewers lists the most watched streams first
+	SortViewers SortOrder = "viewers"
+	// SortNewest lists the most recently started streams first
+	SortNewest SortOrder = "newest"
+)
+
+// ErrInvalidSort is returned for an unknown sort order
+var ErrInvalidSort = errors.New("invalid sort order")
+
+// ParseSortOrder parses a sort order, defaulting to SortViewers
+func ParseSortOrder(value string) (SortOrder, error) {
+	switch SortOrder(value) {
+	case "", SortViewers:
+		return SortViewers, nil
+	case SortNewest:
+		return SortNewest, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrInvalidSort, value)
+}
+
+// LiveStream is a live stream in the index
+type LiveStream struct {
+	// StreamID identifies the stream
+	StreamID string `json:"stream_id"`
+	// UserID is the streamer
+	UserID string `json:"user_id"`
+	// Title is the stream title
+	Title string `json:"title"`
+	// Category is the stream category (lowercase)
+	Category string `json:"category,omitempty"`
+	// Tags are the stream tags (lowercase)
+	Tags []string `json:"tags,omitempty"`
+	// ViewerCount is the number of current viewers
+	ViewerCount int64 `json:"viewer_count"`
+	// PeakViewers is the most viewers the stream had at once
+	PeakViewers int64 `json:"peak_viewers"`
+	// StartedAt is when the stream went live
+	StartedAt time.Time `json:"started_at"`
+}
+
+// hasTag reports whether the stream is tagged with tag
+func (s *LiveStream) hasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter selects the streams of a listing. Empty fields match every stream.
+type Filter struct {
+	// Category matches streams of a category, ignoring case
+	Category string
+	// Tags matches streams tagged with all of the tags, ignoring case
+	Tags []string
+	// Search matches streams whose title contains the term, ignoring case
+	Search string
+	// MinViewers matches streams with at least this many viewers
+	MinViewers int64
+}
+
+// matches reports whether a stream passes the filter
+func (f *Filter) matches(stream *LiveStream) bool {
+	if f.Category != "" && stream.Category != normalize(f.Category) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !stream.hasTag(normalize(tag)) {
+			return false
+		}
+	}
+	if f.Search != "" && !strings.Contains(strings.ToLower(stream.Title), strings.ToLower(f.Search)) {
+		return false
+	}
+	return stream.ViewerCount >= f.MinViewers
+}
+
+// Page selects a page of a listing
+type Page struct {
+	// Offset is the number of streams skipped
+	Offset int
+	// Limit is the page size (0 = DefaultPageLimit, capped at MaxPageLimit)
+	Limit int
+}
+
+// Result is a page of a listing
+type Result struct {
+	Streams    []LiveStream `json:"streams"`
+	TotalCount int          `json:"total_count"`
+	Offset     int          `json:"offset"`
+	Limit      int          `json:"limit"`
+}
+
+// TrendingStream is a live stream ranked by its recent viewer joins
+type TrendingStream struct {
+	LiveStream
+	// RecentJoins is the number of viewers that joined within the window
+	RecentJoins int `json:"recent_joins"`
+}
+
+// entry is an indexed stream with its recent viewer joins
+type entry struct {
+	stream LiveStream
+	joins  []time.Time
+}
+
+// Index tracks the streams that are live now
+type Index struct {
+	streams map[string]*entry
+	clock   clock.Clock
+	mu      sync.RWMutex
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{
+		streams: make(map[string]*entry),
+		clock:   clock.Real(),
+	}
+}
+
+// SetClock sets the clock viewer joins are timed with (nil = real time)
+func (idx *Index) SetClock(c clock.Clock) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.clock = clock.OrReal(c)
+}
+
+// Add indexes a live stream. Adding a stream that is already indexed
+// updates its details but keeps its viewer counts and start time.
+func (idx *Index) Add(stream LiveStream) {
+	stream.Category = normalize(stream.Category)
+	stream.Tags = normalizeTags(stream.Tags)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if e, exists := idx.streams[stream.StreamID]; exists {
+		e.stream.UserID = stream.UserID
+		e.stream.Title = stream.Title
+		e.stream.Category = stream.Category
+		e.stream.Tags = stream.Tags
+		return
+	}
+
+	if stream.StartedAt.IsZero() {
+		stream.StartedAt = idx.clock.Now()
+	}
+	if stream.PeakViewers < stream.ViewerCount {
+		stream.PeakViewers = stream.ViewerCount
+	}
+	idx.streams[stream.StreamID] = &entry{stream: stream}
+}
+
+// Remove drops a stream that is no longer live
+func (idx *Index) Remove(streamID string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	delete(idx.streams, streamID)
+}
+
+// Get returns an indexed stream
+func (idx *Index) Get(streamID string) (LiveStream, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	e, exists := idx.streams[streamID]
+	if !exists {
+		return LiveStream{}, false
+	}
+	return e.stream.copy(), true
+}
+
+// Count returns the number of live streams
+func (idx *Index) Count() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.streams)
+}
+
+// ViewerJoined counts a viewer joining a stream, for its viewer count and
+// trending rank. Joins to streams that are not indexed are ignored.
+func (idx *Index) ViewerJoined(streamID string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	e, exists := idx.streams[streamID]
+	if !exists {
+		return
+	}
+
+	now := idx.clock.Now()
+	e.joins = append(pruneJoins(e.joins, now.Add(-MaxTrendingWindow)), now)
+	e.stream.ViewerCount++
+	if e.stream.ViewerCount > e.stream.PeakViewers {
+		e.stream.PeakViewers = e.stream.ViewerCount
+	}
+}
+
+// ViewerLeft counts a viewer leaving a stream
+func (idx *Index) ViewerLeft(streamID string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if e, exists := idx.streams[streamID]; exists && e.stream.ViewerCount > 0 {
+		e.stream.ViewerCount--
+	}
+}
+
+// SetViewerCount sets a stream's viewer count, e.g. from an analytics
+// sample, without counting joins
+func (idx *Index) SetViewerCount(streamID string, count int64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	e, exists := idx.streams[streamID]
+	if !exists {
+		return
+	}
+
+	e.stream.ViewerCount = count
+	if count > e.stream.PeakViewers {
+		e.stream.PeakViewers = count
+	}
+}
+
+// GetLiveStreams returns a page of the live streams passing filter, in the
+// given order
+func (idx *Index) GetLiveStreams(filter Filter, order SortOrder, page Page) (*Result, error) {
+	if _, err := ParseSortOrder(string(order)); err != nil {
+		return nil, err
+	}
+
+	idx.mu.RLock()
+	streams := make([]LiveStream, 0, len(idx.streams))
+	for _, e := range idx.streams {
+		if filter.matches(&e.stream) {
+			streams = append(streams, e.stream.copy())
+		}
+	}
+	idx.mu.RUnlock()
+
+	sort.Slice(streams, func(i, j int) bool {
+		a, b := &streams[i], &streams[j]
+		if order == SortNewest || a.ViewerCount == b.ViewerCount {
+			if !a.StartedAt.Equal(b.StartedAt) {
+				return a.StartedAt.After(b.StartedAt)
+			}
+			return a.StreamID < b.StreamID
+		}
+		return a.ViewerCount > b.ViewerCount
+	})
+
+	limit := page.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+	offset := page.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	result := &Result{
+		Streams:    []LiveStream{},
+		TotalCount: len(streams),
+		Offset:     offset,
+		Limit:      limit,
+	}
+	if offset < len(streams) {
+		end := offset + limit
+		if end > len(streams) {
+			end = len(streams)
+		}
+		result.Streams = streams[offset:end]
+	}
+
+	return result, nil
+}
+
+// GetTrending returns the live streams viewers joined within the window,
+// most joins first. The window is capped at MaxTrendingWindow.
+func (idx *Index) GetTrending(window time.Duration) []TrendingStream {
+	if window <= 0 || window > MaxTrendingWindow {
+		window = MaxTrendingWindow
+	}
+
+	idx.mu.RLock()
+	since := idx.clock.Now().Add(-window)
+	trending := make([]TrendingStream, 0)
+	for _, e := range idx.streams {
+		if joins := len(pruneJoins(e.joins, since)); joins > 0 {
+			trending = append(trending, TrendingStream{LiveStream: e.stream.copy(), RecentJoins: joins})
+		}
+	}
+	idx.mu.RUnlock()
+
+	sort.Slice(trending, func(i, j int) bool {
+		a, b := &trending[i], &trending[j]
+		if a.RecentJoins != b.RecentJoins {
+			return a.RecentJoins > b.RecentJoins
+		}
+		if a.ViewerCount != b.ViewerCount {
+			return a.ViewerCount > b.ViewerCount
+		}
+		return a.StreamID < b.StreamID
+	})
+
+	return trending
+}
+
+// copy returns a copy of the stream that shares no slices with the index
+func (s *LiveStream) copy() LiveStream {
+	c := *s
+	c.Tags = append([]string(nil), s.Tags...)
+	return c
+}
+
+// pruneJoins drops the join times before since. Joins are in order, so the
+// remaining ones are a suffix.
+func pruneJoins(joins []time.Time, since time.Time) []time.Time {
+	i := sort.Search(len(joins), func(i int) bool {
+		return !joins[i].Before(since)
+	})
+	return joins[i:]
+}
+
+// normalize lowercases and trims a category or tag
+func normalize(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// normalizeTags normalizes tags, dropping empty and duplicate ones
+func normalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = normalize(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// ParseTags splits a comma separated tag list
+func ParseTags(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return normalizeTags(strings.Split(value, ","))
+}
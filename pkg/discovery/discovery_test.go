@@ -0,0 +1,163 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/clock"
+	"github.com/aminofox/zenlive/pkg/sdk"
+)
+
+// TestGetLiveStreams tests filtering, sorting and paging the live index
+func TestGetLiveStreams(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	idx := NewIndex()
+	idx.SetClock(fake)
+
+	idx.Add(LiveStream{StreamID: "a", Title: "Ranked FPS grind", Category: "Gaming", Tags: []string{"FPS", "ranked", "fps"}, ViewerCount: 10})
+	fake.Advance(time.Minute)
+	idx.Add(LiveStream{StreamID: "b", Title: "Casual FPS", Category: "gaming", Tags: []string{"fps"}, ViewerCount: 50})
+	fake.Advance(time.Minute)
+	idx.Add(LiveStream{StreamID: "c", Title: "Lo-fi beats", Category: "music", ViewerCount: 5})
+
+	result, err := idx.GetLiveStreams(Filter{}, SortViewers, Page{})
+	if err != nil {
+		t.Fatalf("Failed to list live streams: %v", err)
+	}
+	if result.TotalCount != 3 || result.Limit != DefaultPageLimit {
+		t.Fatalf("Expected 3 streams with the default limit, got %d and %d", result.TotalCount, result.Limit)
+	}
+	if ids := streamIDs(result.Streams); ids != "bac" {
+		t.Errorf("Expected most viewers first, got %s", ids)
+	}
+
+	result, _ = idx.GetLiveStreams(Filter{}, SortNewest, Page{})
+	if ids := streamIDs(result.Streams); ids != "cba" {
+		t.Errorf("Expected newest first, got %s", ids)
+	}
+
+	result, _ = idx.GetLiveStreams(Filter{Category: "GAMING", Tags: []string{"Ranked"}}, SortViewers, Page{})
+	if ids := streamIDs(result.Streams); ids != "a" {
+		t.Errorf("Expected the ranked gaming stream, got %s", ids)
+	}
+	if stream, _ := idx.Get("a"); len(stream.Tags) != 2 {
+		t.Errorf("Expected tags to be normalized and deduplicated, got %v", stream.Tags)
+	}
+
+	result, _ = idx.GetLiveStreams(Filter{Search: "fps", MinViewers: 20}, SortViewers, Page{})
+	if ids := streamIDs(result.Streams); ids != "b" {
+		t.Errorf("Expected the search and viewer filters to match b, got %s", ids)
+	}
+
+	result, _ = idx.GetLiveStreams(Filter{}, SortViewers, Page{Offset: 1, Limit: 1})
+	if ids := streamIDs(result.Streams); ids != "a" || result.TotalCount != 3 {
+		t.Errorf("Expected the second page to hold a of 3, got %s of %d", ids, result.TotalCount)
+	}
+
+	if _, err := idx.GetLiveStreams(Filter{}, "loudest", Page{}); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("Expected ErrInvalidSort, got %v", err)
+	}
+
+	idx.Remove("b")
+	if idx.Count() != 2 {
+		t.Errorf("Expected 2 streams after removal, got %d", idx.Count())
+	}
+}
+
+// TestGetTrending tests ranking streams by viewer joins within a window
+func TestGetTrending(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	idx := NewIndex()
+	idx.SetClock(fake)
+
+	idx.Add(LiveStream{StreamID: "old", ViewerCount: 100})
+	idx.Add(LiveStream{StreamID: "new"})
+
+	for i := 0; i < 5; i++ {
+		idx.ViewerJoined("old")
+	}
+	fake.Advance(20 * time.Minute)
+	for i := 0; i < 3; i++ {
+		idx.ViewerJoined("new")
+	}
+	idx.ViewerLeft("new")
+	idx.ViewerJoined("missing")
+
+	trending := idx.GetTrending(10 * time.Minute)
+	if len(trending) != 1 || trending[0].StreamID != "new" || trending[0].RecentJoins != 3 {
+		t.Fatalf("Expected only new to trend over 10 minutes, got %+v", trending)
+	}
+	if trending[0].ViewerCount != 2 || trending[0].PeakViewers != 3 {
+		t.Errorf("Expected 2 viewers with a peak of 3, got %d and %d", trending[0].ViewerCount, trending[0].PeakViewers)
+	}
+
+	trending = idx.GetTrending(30 * time.Minute)
+	if len(trending) != 2 || trending[0].StreamID != "old" {
+		t.Errorf("Expected old to lead over 30 minutes, got %+v", trending)
+	}
+
+	// Joins older than the maximum window are forgotten
+	fake.Advance(MaxTrendingWindow + time.Second)
+	if trending := idx.GetTrending(MaxTrendingWindow); len(trending) != 0 {
+		t.Errorf("Expected no trending streams after the window, got %+v", trending)
+	}
+}
+
+// TestAttach tests updating the index from stream lifecycle events
+func TestAttach(t *testing.T) {
+	bus := sdk.NewEventBus(nil)
+	manager := sdk.NewStreamManager(nil)
+	idx := NewIndex()
+	subs := idx.Attach(bus, manager)
+	defer bus.UnsubscribeAll(subs)
+
+	stream, err := manager.CreateStream(context.Background(), &sdk.CreateStreamRequest{
+		UserID:   "streamer",
+		Title:    "Speedrun",
+		Metadata: map[string]string{CategoryMetadataKey: "Gaming", TagsMetadataKey: "speedrun, retro"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create stream: %v", err)
+	}
+
+	bus.Publish(&sdk.StreamEvent{Type: sdk.EventStreamStart, StreamID: stream.ID, UserID: "streamer"})
+	waitFor(t, func() bool { return idx.Count() == 1 })
+
+	live, _ := idx.Get(stream.ID)
+	if live.Title != "Speedrun" || live.Category != "gaming" || len(live.Tags) != 2 {
+		t.Errorf("Expected stream details from the manager, got %+v", live)
+	}
+
+	bus.Publish(&sdk.StreamEvent{Type: sdk.EventViewerJoin, StreamID: stream.ID})
+	waitFor(t, func() bool {
+		live, _ := idx.Get(stream.ID)
+		return live.ViewerCount == 1
+	})
+
+	bus.Publish(&sdk.StreamEvent{Type: sdk.EventStreamEnd, StreamID: stream.ID})
+	waitFor(t, func() bool { return idx.Count() == 0 })
+}
+
+// streamIDs concatenates the IDs of streams, in order
+func streamIDs(streams []LiveStream) string {
+	ids := ""
+	for _, stream := range streams {
+		ids += stream.StreamID
+	}
+	return ids
+}
+
+// waitFor polls until cond holds, failing the test after a second
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the index to update")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
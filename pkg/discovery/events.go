@@ -0,0 +1,69 @@
+package discovery
+
+import (
+	"context"
+
+	"github.com/aminofox/zenlive/pkg/sdk"
+)
+
+// FromStream converts an SDK stream into an index entry, reading its
+// category and tags from its metadata
+func FromStream(stream *sdk.Stream) LiveStream {
+	live := LiveStream{
+		StreamID:    stream.ID,
+		UserID:      stream.UserID,
+		Title:       stream.Title,
+		Category:    stream.Metadata[CategoryMetadataKey],
+		Tags:        ParseTags(stream.Metadata[TagsMetadataKey]),
+		ViewerCount: stream.GetViewerCount(),
+	}
+	if stream.StartedAt != nil {
+		live.StartedAt = *stream.StartedAt
+	}
+	return live
+}
+
+// Attach keeps the index up to date from stream lifecycle and viewer
+// events on bus. Streams are indexed when they start or resume and dropped
+// when they pause, end, fail or are deleted; their details are looked up in
+// streams (nil = only the event's stream and user IDs are known).
+func (idx *Index) Attach(bus *sdk.EventBus, streams *sdk.StreamManager) []*sdk.EventSubscription {
+	add := func(event *sdk.StreamEvent) {
+		live := LiveStream{StreamID: event.StreamID, UserID: event.UserID}
+		if streams != nil {
+			if stream, err := streams.GetStream(context.Background(), event.StreamID); err == nil {
+				live = FromStream(stream)
+			}
+		}
+		idx.Add(live)
+	}
+
+	update := func(event *sdk.StreamEvent) {
+		if _, exists := idx.Get(event.StreamID); !exists || streams == nil {
+			return
+		}
+		if stream, err := streams.GetStream(context.Background(), event.StreamID); err == nil {
+			idx.Add(FromStream(stream))
+		}
+	}
+
+	remove := func(event *sdk.StreamEvent) {
+		idx.Remove(event.StreamID)
+	}
+
+	return []*sdk.EventSubscription{
+		bus.Subscribe(sdk.EventStreamStart, add),
+		bus.Subscribe(sdk.EventStreamResume, add),
+		bus.Subscribe(sdk.EventStreamUpdate, update),
+		bus.Subscribe(sdk.EventStreamPause, remove),
+		bus.Subscribe(sdk.EventStreamEnd, remove),
+		bus.Subscribe(sdk.EventStreamError, remove),
+		bus.Subscribe(sdk.EventStreamDelete, remove),
+		bus.Subscribe(sdk.EventViewerJoin, func(event *sdk.StreamEvent) {
+			idx.ViewerJoined(event.StreamID)
+		}),
+		bus.Subscribe(sdk.EventViewerLeave, func(event *sdk.StreamEvent) {
+			idx.ViewerLeft(event.StreamID)
+		}),
+	}
+}
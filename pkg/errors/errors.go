@@ -1,6 +1,7 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
 )
 
@@ -111,26 +112,21 @@ func Wrap(code ErrorCode, message string, cause error) *Error {
 	}
 }
 
-// IsErrorCode checks if the error has the given error code
+// IsErrorCode checks if the error, or any error it wraps, has the given error code
 func IsErrorCode(err error, code ErrorCode) bool {
-	if err == nil {
-		return false
-	}
-
-	if e, ok := err.(*Error); ok {
+	var e *Error
+	if stderrors.As(err, &e) {
 		return e.Code == code
 	}
 
 	return false
 }
 
-// GetErrorCode returns the error code from an error, or ErrCodeUnknown if not found
+// GetErrorCode returns the error code from an error or the errors it wraps,
+// or ErrCodeUnknown if not found
 func GetErrorCode(err error) ErrorCode {
-	if err == nil {
-		return ErrCodeUnknown
-	}
-
-	if e, ok := err.(*Error); ok {
+	var e *Error
+	if stderrors.As(err, &e) {
 		return e.Code
 	}
 
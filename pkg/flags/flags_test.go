@@ -0,0 +1,51 @@
+package flags
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFlagEvaluate(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryFlagStore()
+	f := New(store)
+
+	if f.Enabled(ctx, "missing", Context{UserID: "u1"}) {
+		t.Error("Expected missing flag to be off")
+	}
+
+	store.Set(ctx, &Flag{Name: "beta", Enabled: true, Users: []string{"u1"}, Streams: []string{"s1"}})
+	if !f.Enabled(ctx, "beta", Context{UserID: "u1"}) || !f.Enabled(ctx, "beta", Context{StreamID: "s1"}) {
+		t.Error("Expected flag on for targeted user and stream")
+	}
+	if f.Enabled(ctx, "beta", Context{UserID: "u2", StreamID: "s2"}) {
+		t.Error("Expected flag off for untargeted context")
+	}
+
+	// Kill switch overrides targeting
+	store.Set(ctx, &Flag{Name: "beta", Enabled: false, Users: []string{"u1"}, Rollout: 100})
+	if f.Enabled(ctx, "beta", Context{UserID: "u1"}) {
+		t.Error("Expected disabled flag to be off")
+	}
+
+	// Percentage rollout is deterministic and roughly proportional
+	store.Set(ctx, &Flag{Name: "rollout", Enabled: true, Rollout: 30})
+	on := 0
+	for i := 0; i < 1000; i++ {
+		user := Context{UserID: "user-" + string(rune('a'+i%26)) + string(rune('a'+i/26))}
+		enabled := f.Enabled(ctx, "rollout", user)
+		if enabled != f.Enabled(ctx, "rollout", user) {
+			t.Fatal("Expected rollout to be deterministic")
+		}
+		if enabled {
+			on++
+		}
+	}
+	if on < 200 || on > 400 {
+		t.Errorf("Expected about 30%% of users enabled, got %d/1000", on)
+	}
+
+	if err := store.Set(ctx, &Flag{Name: "bad", Rollout: 101}); err != ErrInvalidFlag {
+		t.Errorf("Expected ErrInvalidFlag, got %v", err)
+	}
+}
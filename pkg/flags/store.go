@@ -0,0 +1,177 @@
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MemoryFlagStore is an in-memory implementation of FlagStore
+type MemoryFlagStore struct {
+	flags map[string]*Flag
+	mu    sync.RWMutex
+}
+
+// NewMemoryFlagStore creates an empty in-memory flag store
+func NewMemoryFlagStore() *MemoryFlagStore {
+	return &MemoryFlagStore{
+		flags: make(map[string]*Flag),
+	}
+}
+
+// Get returns a copy of a flag
+func (s *MemoryFlagStore) Get(ctx context.Context, name string) (*Flag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	flag, exists := s.flags[name]
+	if !exists {
+		return nil, ErrFlagNotFound
+	}
+
+	return cloneFlag(flag), nil
+}
+
+// Set stores a copy of a flag
+func (s *MemoryFlagStore) Set(ctx context.Context, flag *Flag) error {
+	if err := flag.Validate(); err != nil {
+		return err
+	}
+
+	stored := cloneFlag(flag)
+	stored.UpdatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.flags[flag.Name] = stored
+	return nil
+}
+
+// Delete removes a flag
+func (s *MemoryFlagStore) Delete(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.flags[name]; !exists {
+		return ErrFlagNotFound
+	}
+
+	delete(s.flags, name)
+	return nil
+}
+
+// List returns copies of all flags ordered by name
+func (s *MemoryFlagStore) List(ctx context.Context) ([]*Flag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	flags := make([]*Flag, 0, len(s.flags))
+	for _, flag := range s.flags {
+		flags = append(flags, cloneFlag(flag))
+	}
+
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Name < flags[j].Name
+	})
+
+	return flags, nil
+}
+
+// cloneFlag copies a flag and its target lists
+func cloneFlag(flag *Flag) *Flag {
+	clone := *flag
+	clone.Users = append([]string(nil), flag.Users...)
+	clone.Streams = append([]string(nil), flag.Streams...)
+	return &clone
+}
+
+// RedisFlagStore implements FlagStore using a Redis hash, so flag changes
+// take effect on every node at once
+type RedisFlagStore struct {
+	client *redis.Client
+	key    string
+}
+
+// NewRedisFlagStore creates a Redis-based flag store
+func NewRedisFlagStore(client *redis.Client) *RedisFlagStore {
+	return &RedisFlagStore{
+		client: client,
+		key:    "flags",
+	}
+}
+
+// Get returns a flag from Redis
+func (s *RedisFlagStore) Get(ctx context.Context, name string) (*Flag, error) {
+	data, err := s.client.HGet(ctx, s.key, name).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrFlagNotFound
+		}
+		return nil, err
+	}
+
+	var flag Flag
+	if err := json.Unmarshal(data, &flag); err != nil {
+		return nil, err
+	}
+
+	return &flag, nil
+}
+
+// Set stores a flag in Redis
+func (s *RedisFlagStore) Set(ctx context.Context, flag *Flag) error {
+	if err := flag.Validate(); err != nil {
+		return err
+	}
+
+	stored := cloneFlag(flag)
+	stored.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	return s.client.HSet(ctx, s.key, flag.Name, data).Err()
+}
+
+// Delete removes a flag from Redis
+func (s *RedisFlagStore) Delete(ctx context.Context, name string) error {
+	removed, err := s.client.HDel(ctx, s.key, name).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrFlagNotFound
+	}
+
+	return nil
+}
+
+// List returns all flags in Redis ordered by name
+func (s *RedisFlagStore) List(ctx context.Context) ([]*Flag, error) {
+	values, err := s.client.HGetAll(ctx, s.key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	flags := make([]*Flag, 0, len(values))
+	for _, data := range values {
+		var flag Flag
+		if err := json.Unmarshal([]byte(data), &flag); err != nil {
+			return nil, err
+		}
+		flags = append(flags, &flag)
+	}
+
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Name < flags[j].Name
+	})
+
+	return flags, nil
+}
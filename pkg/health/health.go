@@ -0,0 +1,198 @@
+// Package health aggregates the health of SDK components into one report
+// that backs liveness and readiness probes
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultCheckTimeout bounds each component check
+const DefaultCheckTimeout = 5 * time.Second
+
+// Status is the health of a component or of the whole SDK
+type Status string
+
+// Health statuses
+const (
+	// StatusHealthy means every check passed
+	StatusHealthy Status = "healthy"
+	// StatusDegraded means a non-critical check failed
+	StatusDegraded Status = "degraded"
+	// StatusUnhealthy means a critical check failed
+	StatusUnhealthy Status = "unhealthy"
+)
+
+// Checker is implemented by components that can report their health
+type Checker interface {
+	// CheckHealth returns nil if the component is healthy
+	CheckHealth(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to a Checker
+type CheckerFunc func(ctx context.Context) error
+
+// CheckHealth calls f(ctx)
+func (f CheckerFunc) CheckHealth(ctx context.Context) error {
+	return f(ctx)
+}
+
+// ComponentReport is the result of one component check
+type ComponentReport struct {
+	Name      string `json:"name"`
+	Status    Status `json:"status"`
+	Critical  bool   `json:"critical"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// Report is the aggregated health of all registered components
+type Report struct {
+	// Status is unhealthy if any critical component failed and degraded if
+	// any other component failed
+	Status Status `json:"status"`
+	// Ready is true once the SDK has started and no critical component failed
+	Ready      bool              `json:"ready"`
+	Components []ComponentReport `json:"components"`
+	CheckedAt  time.Time         `json:"checked_at"`
+}
+
+// component is a registered checker
+type component struct {
+	checker  Checker
+	critical bool
+}
+
+// Registry holds the components whose health makes up the SDK's health
+type Registry struct {
+	components map[string]component
+	ready      bool
+	timeout    time.Duration
+	mu         sync.RWMutex
+}
+
+// NewRegistry creates an empty registry that is not ready
+func NewRegistry() *Registry {
+	return &Registry{
+		components: make(map[string]component),
+		timeout:    DefaultCheckTimeout,
+	}
+}
+
+// Register adds or replaces a component. A failing critical component makes
+// the report unhealthy and not ready; any other failing component only
+// degrades it.
+func (r *Registry) Register(name string, checker Checker, critical bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.components[name] = component{checker: checker, critical: critical}
+}
+
+// Unregister removes a component
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.components, name)
+}
+
+// SetReady marks whether the SDK has started and may receive traffic
+func (r *Registry) SetReady(ready bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ready = ready
+}
+
+// SetTimeout sets the time limit of each component check
+func (r *Registry) SetTimeout(timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.timeout = timeout
+}
+
+// Check runs all component checks concurrently and aggregates the results.
+// Components are reported in name order.
+func (r *Registry) Check(ctx context.Context) *Report {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.components))
+	for name := range r.components {
+		names = append(names, name)
+	}
+	components := make(map[string]component, len(r.components))
+	for name, c := range r.components {
+		components[name] = c
+	}
+	ready := r.ready
+	timeout := r.timeout
+	r.mu.RUnlock()
+
+	sort.Strings(names)
+
+	results := make([]ComponentReport, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string, c component) {
+			defer wg.Done()
+			results[i] = check(ctx, name, c, timeout)
+		}(i, name, components[name])
+	}
+	wg.Wait()
+
+	report := &Report{
+		Status:     StatusHealthy,
+		Components: results,
+		CheckedAt:  time.Now(),
+	}
+	for _, result := range results {
+		switch {
+		case result.Status == StatusHealthy:
+		case result.Critical:
+			report.Status = StatusUnhealthy
+		case report.Status == StatusHealthy:
+			report.Status = StatusDegraded
+		}
+	}
+	report.Ready = ready && report.Status != StatusUnhealthy
+
+	return report
+}
+
+// check runs one component check within the timeout
+func check(ctx context.Context, name string, c component, timeout time.Duration) ComponentReport {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.checker.CheckHealth(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := ComponentReport{
+		Name:      name,
+		Status:    StatusHealthy,
+		Critical:  c.critical,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = StatusUnhealthy
+		result.Error = err.Error()
+	}
+	return result
+}
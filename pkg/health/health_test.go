@@ -0,0 +1,45 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistryCheck(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("sfu", CheckerFunc(func(ctx context.Context) error { return nil }), true)
+
+	report := registry.Check(context.Background())
+	if report.Status != StatusHealthy || report.Ready {
+		t.Errorf("Expected healthy but not ready before start, got %s ready=%v", report.Status, report.Ready)
+	}
+
+	registry.SetReady(true)
+	registry.Register("cache", CheckerFunc(func(ctx context.Context) error { return errors.New("connection refused") }), false)
+
+	report = registry.Check(context.Background())
+	if report.Status != StatusDegraded || !report.Ready {
+		t.Errorf("Expected degraded and ready with a failing optional component, got %s ready=%v", report.Status, report.Ready)
+	}
+	if report.Components[0].Name != "cache" || report.Components[0].Error != "connection refused" {
+		t.Errorf("Expected cache failure reported first, got %+v", report.Components[0])
+	}
+
+	registry.SetTimeout(10 * time.Millisecond)
+	registry.Register("storage", CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}), true)
+
+	report = registry.Check(context.Background())
+	if report.Status != StatusUnhealthy || report.Ready {
+		t.Errorf("Expected a hung critical component to make the SDK unhealthy, got %s ready=%v", report.Status, report.Ready)
+	}
+
+	registry.Unregister("storage")
+	if report = registry.Check(context.Background()); report.Status != StatusDegraded {
+		t.Errorf("Expected degraded after unregistering storage, got %s", report.Status)
+	}
+}
@@ -0,0 +1,240 @@
+// Package idgen provides sortable, collision-resistant ID generation.
+// It offers ULID and KSUID generators behind a common Generator interface so
+// packages can inject their own source (for example in tests).
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	mathrand "math/rand"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/clock"
+)
+
+// Generator produces unique string identifiers
+type Generator interface {
+	// NewID returns a new unique identifier
+	NewID() string
+}
+
+// GeneratorFunc adapts a function to the Generator interface
+type GeneratorFunc func() string
+
+// NewID calls f()
+func (f GeneratorFunc) NewID() string {
+	return f()
+}
+
+// crockford is the Crockford base32 alphabet used by ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator generates lexicographically sortable ULIDs.
+// IDs generated within the same millisecond are monotonically increasing.
+type ULIDGenerator struct {
+	entropy io.Reader
+	clock   clock.Clock
+
+	lastMS   uint64
+	lastRand [10]byte
+
+	mu sync.Mutex
+}
+
+// NewULIDGenerator creates a ULID generator backed by crypto/rand
+func NewULIDGenerator() *ULIDGenerator {
+	return &ULIDGenerator{entropy: rand.Reader, clock: clock.Real()}
+}
+
+// NewULIDGeneratorWithEntropy creates a ULID generator with a custom entropy source
+func NewULIDGeneratorWithEntropy(entropy io.Reader) *ULIDGenerator {
+	return &ULIDGenerator{entropy: entropy, clock: clock.Real()}
+}
+
+// NewULIDGeneratorWithClock creates a ULID generator with a custom entropy
+// source and clock (nil = real time), so tests can pin the timestamp
+// component of IDs
+func NewULIDGeneratorWithClock(entropy io.Reader, c clock.Clock) *ULIDGenerator {
+	return &ULIDGenerator{entropy: entropy, clock: clock.OrReal(c)}
+}
+
+// NewSeededULIDGenerator creates a ULID generator whose output depends only
+// on seed and clock, so tests can reproduce IDs. It must not be used in
+// production, as its IDs are predictable.
+func NewSeededULIDGenerator(seed int64, c clock.Clock) *ULIDGenerator {
+	return NewULIDGeneratorWithClock(mathrand.New(mathrand.NewSource(seed)), c)
+}
+
+// NewID returns a new 26-character ULID
+func (g *ULIDGenerator) NewID() string {
+	return g.newIDAt(g.clock.Now())
+}
+
+// newIDAt returns a ULID for the given time
+func (g *ULIDGenerator) newIDAt(t time.Time) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(t.UnixMilli())
+
+	if ms <= g.lastMS {
+		// Same (or earlier) millisecond: increment randomness to stay monotonic
+		ms = g.lastMS
+		incrementBytes(g.lastRand[:])
+	} else {
+		if _, err := io.ReadFull(g.entropy, g.lastRand[:]); err != nil {
+			incrementBytes(g.lastRand[:])
+		}
+		g.lastMS = ms
+	}
+
+	var raw [16]byte
+	raw[0] = byte(ms >> 40)
+	raw[1] = byte(ms >> 32)
+	raw[2] = byte(ms >> 24)
+	raw[3] = byte(ms >> 16)
+	raw[4] = byte(ms >> 8)
+	raw[5] = byte(ms)
+	copy(raw[6:], g.lastRand[:])
+
+	return encodeULID(raw)
+}
+
+// incrementBytes adds one to a big-endian byte slice
+func incrementBytes(b []byte) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return
+		}
+	}
+}
+
+// encodeULID encodes 128 bits as 26 Crockford base32 characters
+func encodeULID(raw [16]byte) string {
+	hi := binary.BigEndian.Uint64(raw[:8])
+	lo := binary.BigEndian.Uint64(raw[8:])
+
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = (lo >> 5) | (hi << 59)
+		hi >>= 5
+	}
+
+	return string(out)
+}
+
+// base62 is the KSUID alphabet
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ksuidEpoch is the KSUID epoch (2014-05-13T16:53:20Z)
+const ksuidEpoch = 1400000000
+
+// KSUIDGenerator generates K-sortable unique IDs with second precision
+type KSUIDGenerator struct {
+	entropy io.Reader
+}
+
+// NewKSUIDGenerator creates a KSUID generator backed by crypto/rand
+func NewKSUIDGenerator() *KSUIDGenerator {
+	return &KSUIDGenerator{entropy: rand.Reader}
+}
+
+// NewID returns a new 27-character KSUID
+func (g *KSUIDGenerator) NewID() string {
+	var raw [20]byte
+	binary.BigEndian.PutUint32(raw[:4], uint32(time.Now().Unix()-ksuidEpoch))
+	io.ReadFull(g.entropy, raw[4:])
+
+	n := new(big.Int).SetBytes(raw[:])
+	base := big.NewInt(62)
+	mod := new(big.Int)
+
+	out := make([]byte, 27)
+	for i := 26; i >= 0; i-- {
+		n.DivMod(n, base, mod)
+		out[i] = base62[mod.Int64()]
+	}
+
+	return string(out)
+}
+
+// SequenceGenerator returns zero-padded increasing integers ("000000000001",
+// "000000000002", ...), which makes IDs easy to assert on in tests
+type SequenceGenerator struct {
+	next uint64
+	mu   sync.Mutex
+}
+
+// NewSequenceGenerator creates a sequence generator starting at 1
+func NewSequenceGenerator() *SequenceGenerator {
+	return &SequenceGenerator{next: 1}
+}
+
+// NewID returns the next number in the sequence
+func (g *SequenceGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := fmt.Sprintf("%012d", g.next)
+	g.next++
+	return id
+}
+
+// Swappable holds a Generator that can be replaced at runtime
+type Swappable struct {
+	gen Generator
+	mu  sync.RWMutex
+}
+
+// NewSwappable creates a swappable wrapper around gen
+func NewSwappable(gen Generator) *Swappable {
+	return &Swappable{gen: gen}
+}
+
+// Set replaces the underlying generator
+func (s *Swappable) Set(gen Generator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gen = gen
+}
+
+// Get returns the underlying generator
+func (s *Swappable) Get() Generator {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.gen
+}
+
+// NewID returns an ID from the current generator
+func (s *Swappable) NewID() string {
+	return s.Get().NewID()
+}
+
+// defaultGenerator is the process-wide generator
+var defaultGenerator = NewSwappable(NewULIDGenerator())
+
+// Default returns the process-wide generator
+func Default() Generator {
+	return defaultGenerator
+}
+
+// SetDefault replaces the process-wide generator
+func SetDefault(gen Generator) {
+	defaultGenerator.Set(gen)
+}
+
+// New returns a new ID from the process-wide generator
+func New() string {
+	return defaultGenerator.NewID()
+}
+
+// WithPrefix returns a new ID from gen joined to prefix with an underscore
+func WithPrefix(gen Generator, prefix string) string {
+	return prefix + "_" + gen.NewID()
+}
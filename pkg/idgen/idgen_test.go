@@ -0,0 +1,106 @@
+package idgen
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/clock"
+)
+
+func TestULIDGenerator(t *testing.T) {
+	gen := NewULIDGenerator()
+
+	ids := make([]string, 10000)
+	seen := make(map[string]bool, len(ids))
+	for i := range ids {
+		id := gen.NewID()
+		if len(id) != 26 {
+			t.Fatalf("Expected ULID length 26, got %d (%s)", len(id), id)
+		}
+		if seen[id] {
+			t.Fatalf("Duplicate ULID generated: %s", id)
+		}
+		seen[id] = true
+		ids[i] = id
+	}
+
+	if !sort.StringsAreSorted(ids) {
+		t.Error("Expected ULIDs to be monotonically increasing")
+	}
+}
+
+func TestULIDSameMillisecond(t *testing.T) {
+	gen := NewULIDGenerator()
+	now := time.Now()
+
+	first := gen.newIDAt(now)
+	second := gen.newIDAt(now)
+
+	if first >= second {
+		t.Errorf("Expected %s < %s within the same millisecond", first, second)
+	}
+
+	if first[:10] != second[:10] {
+		t.Error("Expected identical timestamp component within the same millisecond")
+	}
+}
+
+func TestKSUIDGenerator(t *testing.T) {
+	gen := NewKSUIDGenerator()
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := gen.NewID()
+		if len(id) != 27 {
+			t.Fatalf("Expected KSUID length 27, got %d (%s)", len(id), id)
+		}
+		if seen[id] {
+			t.Fatalf("Duplicate KSUID generated: %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestSwappable(t *testing.T) {
+	s := NewSwappable(NewULIDGenerator())
+
+	s.Set(GeneratorFunc(func() string { return "fixed" }))
+
+	if id := s.NewID(); id != "fixed" {
+		t.Errorf("Expected 'fixed', got %s", id)
+	}
+
+	if id := WithPrefix(s, "client"); id != "client_fixed" {
+		t.Errorf("Expected 'client_fixed', got %s", id)
+	}
+}
+
+func TestSeededULIDGenerator(t *testing.T) {
+	fixed := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	a := NewSeededULIDGenerator(42, fixed)
+	b := NewSeededULIDGenerator(42, fixed)
+
+	for i := 0; i < 100; i++ {
+		idA, idB := a.NewID(), b.NewID()
+		if idA != idB {
+			t.Fatalf("Expected identical IDs for the same seed, got %s and %s", idA, idB)
+		}
+	}
+
+	if other := NewSeededULIDGenerator(7, fixed).NewID(); other == NewSeededULIDGenerator(42, fixed).NewID() {
+		t.Error("Expected different seeds to produce different IDs")
+	}
+}
+
+func TestSequenceGenerator(t *testing.T) {
+	gen := NewSequenceGenerator()
+
+	if id := WithPrefix(gen, "source"); id != "source_000000000001" {
+		t.Errorf("Expected source_000000000001, got %s", id)
+	}
+	if id := gen.NewID(); id != "000000000002" {
+		t.Errorf("Expected 000000000002, got %s", id)
+	}
+}
@@ -0,0 +1,411 @@
+// Package notifications tells viewers when streamers they follow go live,
+// through the SDK event bus (and so webhooks) and a pluggable push provider
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/clock"
+	"github.com/aminofox/zenlive/pkg/idgen"
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/queue"
+	"github.com/aminofox/zenlive/pkg/sdk"
+)
+
+var (
+	// ErrSelfFollow is returned when a user tries to follow themselves
+	ErrSelfFollow = errors.New("cannot follow yourself")
+	// ErrInvalidUser is returned for an empty user or streamer ID
+	ErrInvalidUser = errors.New("user ID is required")
+)
+
+// Preferences are a user's notification settings. The zero value notifies
+// on every channel for every followed streamer.
+type Preferences struct {
+	// Disabled turns off go-live notifications for the user
+	Disabled bool `json:"disabled"`
+	// DisablePush stops push delivery, keeping event and webhook delivery
+	DisablePush bool `json:"disable_push"`
+	// Muted lists followed streamers the user is not notified about
+	Muted []string `json:"muted,omitempty"`
+}
+
+// isMuted reports whether the user muted a streamer
+func (p *Preferences) isMuted(streamerID string) bool {
+	for _, muted := range p.Muted {
+		if muted == streamerID {
+			return true
+		}
+	}
+	return false
+}
+
+// Notification tells a user that a streamer they follow went live
+type Notification struct {
+	// ID uniquely identifies the notification
+	ID string `json:"id"`
+	// UserID is the notified follower
+	UserID string `json:"user_id"`
+	// StreamerID is the streamer who went live
+	StreamerID string `json:"streamer_id"`
+	// StreamID is the live stream
+	StreamID string `json:"stream_id"`
+	// Title is the stream title
+	Title string `json:"title,omitempty"`
+	// CreatedAt is when the notification was created
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PushProvider delivers notifications to users' devices, e.g. through APNs
+// or FCM
+type PushProvider interface {
+	// Push delivers a notification. Failed pushes are retried.
+	Push(ctx context.Context, notification *Notification) error
+}
+
+// Config configures a Notifier
+type Config struct {
+	// DedupWindow is how long after notifying a user about a streamer
+	// further go-live notifications for that streamer are suppressed, so a
+	// reconnecting stream doesn't notify again
+	DedupWindow time.Duration
+	// PushWorkers is the number of concurrent push deliveries
+	PushWorkers int
+	// PushMaxAttempts is how many times a push is tried
+	PushMaxAttempts int
+	// PushRetryDelay is the delay before retrying a failed push, growing
+	// with each attempt
+	PushRetryDelay time.Duration
+	// QueueCapacity caps the pending push deliveries
+	QueueCapacity int
+}
+
+// DefaultConfig returns the default notifier configuration
+func DefaultConfig() Config {
+	return Config{
+		DedupWindow:     10 * time.Minute,
+		PushWorkers:     4,
+		PushMaxAttempts: 3,
+		PushRetryDelay:  2 * time.Second,
+		QueueCapacity:   10000,
+	}
+}
+
+// Notifier manages follows and notifies followers when streamers go live
+type Notifier struct {
+	store  Store
+	config Config
+	logger logger.Logger
+	clock  clock.Clock
+
+	bus  *sdk.EventBus
+	push PushProvider
+
+	// pushQueue holds pending push deliveries
+	pushQueue *queue.Queue[*Notification]
+	wg        sync.WaitGroup
+
+	// notified maps user and streamer to when the user was last notified
+	notified  map[string]time.Time
+	lastPrune time.Time
+	mu        sync.Mutex
+}
+
+// NewNotifier creates a notifier over a follow store and starts its push
+// workers
+func NewNotifier(store Store, config Config, log logger.Logger) *Notifier {
+	if log == nil {
+		log = logger.NewDefaultLogger(logger.InfoLevel, "text")
+	}
+	if config.PushWorkers <= 0 {
+		config.PushWorkers = 1
+	}
+
+	n := &Notifier{
+		store:  store,
+		config: config,
+		logger: log,
+		clock:  clock.Real(),
+		pushQueue: queue.New[*Notification](queue.Options{
+			Capacity:    config.QueueCapacity,
+			MaxAttempts: config.PushMaxAttempts,
+		}),
+		notified: make(map[string]time.Time),
+	}
+
+	n.pushQueue.OnDeadLetter(func(msg *queue.Message[*Notification]) {
+		n.logger.Error("Push notification failed after max attempts",
+			logger.String("notification_id", msg.Payload.ID),
+			logger.String("user_id", msg.Payload.UserID),
+		)
+	})
+
+	for i := 0; i < config.PushWorkers; i++ {
+		n.wg.Add(1)
+		go n.pushWorker()
+	}
+
+	return n
+}
+
+// SetEventBus publishes an EventLiveNotification per notification and an
+// EventFollow per follow on bus, where webhooks and other subscribers
+// deliver them
+func (n *Notifier) SetEventBus(bus *sdk.EventBus) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.bus = bus
+}
+
+// SetPushProvider delivers notifications through a push provider
+func (n *Notifier) SetPushProvider(provider PushProvider) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.push = provider
+}
+
+// SetClock sets the clock used for deduplication (nil = real time)
+func (n *Notifier) SetClock(c clock.Clock) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.clock = clock.OrReal(c)
+}
+
+// Follow makes userID follow streamerID
+func (n *Notifier) Follow(ctx context.Context, userID, streamerID string) error {
+	if userID == "" || streamerID == "" {
+		return ErrInvalidUser
+	}
+	if userID == streamerID {
+		return ErrSelfFollow
+	}
+
+	// Only new follows are announced
+	following, err := n.store.IsFollowing(ctx, userID, streamerID)
+	if err != nil {
+		return err
+	}
+	if err := n.store.Follow(ctx, userID, streamerID); err != nil {
+		return err
+	}
+	if following {
+		return nil
+	}
+
+	n.mu.Lock()
+	bus, now := n.bus, n.clock.Now()
+	n.mu.Unlock()
+
+	if bus != nil {
+		bus.Publish(&sdk.StreamEvent{
+			Type:      sdk.EventFollow,
+			UserID:    userID,
+			Timestamp: now,
+			Data: map[string]interface{}{
+				"streamer_id": streamerID,
+			},
+		})
+	}
+
+	return nil
+}
+
+// Unfollow makes userID stop following streamerID
+func (n *Notifier) Unfollow(ctx context.Context, userID, streamerID string) error {
+	return n.store.Unfollow(ctx, userID, streamerID)
+}
+
+// IsFollowing reports whether userID follows streamerID
+func (n *Notifier) IsFollowing(ctx context.Context, userID, streamerID string) (bool, error) {
+	return n.store.IsFollowing(ctx, userID, streamerID)
+}
+
+// GetFollowers returns the users following a streamer
+func (n *Notifier) GetFollowers(ctx context.Context, streamerID string) ([]string, error) {
+	return n.store.Followers(ctx, streamerID)
+}
+
+// GetFollowing returns the streamers a user follows
+func (n *Notifier) GetFollowing(ctx context.Context, userID string) ([]string, error) {
+	return n.store.Following(ctx, userID)
+}
+
+// SetPreferences stores a user's notification preferences
+func (n *Notifier) SetPreferences(ctx context.Context, userID string, prefs Preferences) error {
+	if userID == "" {
+		return ErrInvalidUser
+	}
+	return n.store.SetPreferences(ctx, userID, prefs)
+}
+
+// GetPreferences returns a user's notification preferences
+func (n *Notifier) GetPreferences(ctx context.Context, userID string) (Preferences, error) {
+	return n.store.GetPreferences(ctx, userID)
+}
+
+// NotifyLive notifies the followers of a streamer that stream went live.
+// Followers who disabled notifications or muted the streamer are skipped,
+// as are followers already notified about the streamer within the dedup
+// window. It returns the notifications sent.
+func (n *Notifier) NotifyLive(ctx context.Context, streamerID, streamID, title string) ([]*Notification, error) {
+	followers, err := n.store.Followers(ctx, streamerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get followers: %w", err)
+	}
+
+	n.mu.Lock()
+	bus, push := n.bus, n.push
+	now := n.clock.Now()
+	n.mu.Unlock()
+
+	sent := make([]*Notification, 0, len(followers))
+	for _, userID := range followers {
+		prefs, err := n.store.GetPreferences(ctx, userID)
+		if err != nil {
+			n.logger.Warn("Failed to get notification preferences",
+				logger.String("user_id", userID),
+				logger.Err(err),
+			)
+			continue
+		}
+		if prefs.Disabled || prefs.isMuted(streamerID) || !n.claim(userID, streamerID, now) {
+			continue
+		}
+
+		notification := &Notification{
+			ID:         idgen.WithPrefix(idgen.Default(), "notification"),
+			UserID:     userID,
+			StreamerID: streamerID,
+			StreamID:   streamID,
+			Title:      title,
+			CreatedAt:  now,
+		}
+		sent = append(sent, notification)
+
+		if bus != nil {
+			bus.Publish(&sdk.StreamEvent{
+				Type:      sdk.EventLiveNotification,
+				StreamID:  streamID,
+				UserID:    userID,
+				Timestamp: now,
+				Data: map[string]interface{}{
+					"notification_id": notification.ID,
+					"streamer_id":     streamerID,
+					"title":           title,
+				},
+			})
+		}
+
+		if push != nil && !prefs.DisablePush {
+			if _, err := n.pushQueue.Enqueue(notification); err != nil {
+				n.logger.Warn("Failed to queue push notification",
+					logger.String("user_id", userID),
+					logger.Err(err),
+				)
+			}
+		}
+	}
+
+	if len(sent) > 0 {
+		n.logger.Info("Notified followers of live stream",
+			logger.String("streamer_id", streamerID),
+			logger.String("stream_id", streamID),
+			logger.Int("notified", len(sent)),
+		)
+	}
+
+	return sent, nil
+}
+
+// Attach notifies followers whenever a stream starts on bus, and publishes
+// the notifications on it. Stream titles are looked up in streams (nil =
+// notifications have no title).
+func (n *Notifier) Attach(bus *sdk.EventBus, streams *sdk.StreamManager) *sdk.EventSubscription {
+	n.SetEventBus(bus)
+
+	return bus.Subscribe(sdk.EventStreamStart, func(event *sdk.StreamEvent) {
+		var title string
+		if streams != nil {
+			if stream, err := streams.GetStream(context.Background(), event.StreamID); err == nil {
+				title = stream.Title
+			}
+		}
+
+		if _, err := n.NotifyLive(context.Background(), event.UserID, event.StreamID, title); err != nil {
+			n.logger.Error("Failed to notify followers",
+				logger.String("streamer_id", event.UserID),
+				logger.Err(err),
+			)
+		}
+	})
+}
+
+// PendingPushes returns the number of queued and in-flight push deliveries
+func (n *Notifier) PendingPushes() int {
+	return n.pushQueue.Len()
+}
+
+// Close stops the push workers. Pending pushes are dropped.
+func (n *Notifier) Close() {
+	n.pushQueue.Close()
+	n.wg.Wait()
+}
+
+// claim records that a user is notified about a streamer, unless they
+// already were within the dedup window
+func (n *Notifier) claim(userID, streamerID string, now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	window := n.config.DedupWindow
+	if now.Sub(n.lastPrune) >= window {
+		for key, at := range n.notified {
+			if now.Sub(at) >= window {
+				delete(n.notified, key)
+			}
+		}
+		n.lastPrune = now
+	}
+
+	key := userID + "\x00" + streamerID
+	if at, exists := n.notified[key]; exists && now.Sub(at) < window {
+		return false
+	}
+	n.notified[key] = now
+	return true
+}
+
+// pushWorker delivers queued push notifications until the queue is closed
+func (n *Notifier) pushWorker() {
+	defer n.wg.Done()
+
+	for {
+		msg, err := n.pushQueue.Dequeue(context.Background())
+		if err != nil {
+			return
+		}
+
+		n.mu.Lock()
+		push := n.push
+		n.mu.Unlock()
+
+		if err := push.Push(context.Background(), msg.Payload); err != nil {
+			n.logger.Warn("Push notification failed",
+				logger.String("notification_id", msg.Payload.ID),
+				logger.Int("attempt", msg.Attempts),
+				logger.Err(err),
+			)
+			n.pushQueue.Nack(msg.ID, n.config.PushRetryDelay*time.Duration(msg.Attempts))
+			continue
+		}
+
+		n.pushQueue.Ack(msg.ID)
+	}
+}
@@ -0,0 +1,178 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/clock"
+	"github.com/aminofox/zenlive/pkg/sdk"
+)
+
+// recordingPush is a PushProvider that records pushes, failing the first
+// failures attempts
+type recordingPush struct {
+	failures int
+	pushed   []*Notification
+	attempts int
+	mu       sync.Mutex
+}
+
+func (p *recordingPush) Push(ctx context.Context, notification *Notification) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.attempts++
+	if p.attempts <= p.failures {
+		return errors.New("provider unavailable")
+	}
+	p.pushed = append(p.pushed, notification)
+	return nil
+}
+
+func (p *recordingPush) count() (pushed, attempts int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.pushed), p.attempts
+}
+
+// TestFollow tests following and unfollowing streamers
+func TestFollow(t *testing.T) {
+	ctx := context.Background()
+	n := NewNotifier(NewMemoryStore(), DefaultConfig(), nil)
+	defer n.Close()
+
+	if err := n.Follow(ctx, "alice", "alice"); !errors.Is(err, ErrSelfFollow) {
+		t.Errorf("Expected ErrSelfFollow, got %v", err)
+	}
+	if err := n.Follow(ctx, "", "streamer"); !errors.Is(err, ErrInvalidUser) {
+		t.Errorf("Expected ErrInvalidUser, got %v", err)
+	}
+
+	n.Follow(ctx, "bob", "streamer")
+	n.Follow(ctx, "alice", "streamer")
+	n.Follow(ctx, "alice", "other")
+
+	followers, _ := n.GetFollowers(ctx, "streamer")
+	if len(followers) != 2 || followers[0] != "alice" {
+		t.Errorf("Expected alice and bob to follow, got %v", followers)
+	}
+	following, _ := n.GetFollowing(ctx, "alice")
+	if len(following) != 2 {
+		t.Errorf("Expected alice to follow 2 streamers, got %v", following)
+	}
+
+	n.Unfollow(ctx, "alice", "streamer")
+	if ok, _ := n.IsFollowing(ctx, "alice", "streamer"); ok {
+		t.Error("Expected alice to no longer follow streamer")
+	}
+
+	// Only new follows are published
+	bus := sdk.NewEventBus(nil)
+	followed := make(chan *sdk.StreamEvent, 2)
+	bus.Subscribe(sdk.EventFollow, func(event *sdk.StreamEvent) {
+		followed <- event
+	})
+	n.SetEventBus(bus)
+
+	n.Follow(ctx, "bob", "streamer")
+	n.Follow(ctx, "carol", "streamer")
+	select {
+	case event := <-followed:
+		if event.UserID != "carol" || event.Data["streamer_id"] != "streamer" {
+			t.Errorf("Unexpected follow event: %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the follow event")
+	}
+	select {
+	case event := <-followed:
+		t.Errorf("Expected no event for an existing follow, got %+v", event)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// TestNotifyLive tests notifying followers with preferences and deduplication
+func TestNotifyLive(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	config := DefaultConfig()
+	config.PushRetryDelay = time.Millisecond
+
+	n := NewNotifier(NewMemoryStore(), config, nil)
+	defer n.Close()
+	n.SetClock(fake)
+	push := &recordingPush{failures: 1}
+	n.SetPushProvider(push)
+
+	for _, user := range []string{"alice", "bob", "carol", "dave"} {
+		n.Follow(ctx, user, "streamer")
+	}
+	n.SetPreferences(ctx, "bob", Preferences{Disabled: true})
+	n.SetPreferences(ctx, "carol", Preferences{Muted: []string{"streamer"}})
+	n.SetPreferences(ctx, "dave", Preferences{DisablePush: true})
+
+	sent, err := n.NotifyLive(ctx, "streamer", "stream-1", "Going live")
+	if err != nil {
+		t.Fatalf("Failed to notify: %v", err)
+	}
+	if len(sent) != 2 || sent[0].UserID != "alice" || sent[1].UserID != "dave" {
+		t.Fatalf("Expected alice and dave to be notified, got %+v", sent)
+	}
+
+	// Only alice gets a push, delivered on the retry
+	deadline := time.Now().Add(time.Second)
+	for {
+		if pushed, attempts := push.count(); pushed == 1 && attempts == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			pushed, attempts := push.count()
+			t.Fatalf("Expected 1 push after 2 attempts, got %d after %d", pushed, attempts)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// A reconnect within the dedup window notifies nobody
+	fake.Advance(time.Minute)
+	if sent, _ := n.NotifyLive(ctx, "streamer", "stream-2", "Back again"); len(sent) != 0 {
+		t.Errorf("Expected no notifications within the dedup window, got %d", len(sent))
+	}
+
+	fake.Advance(config.DedupWindow)
+	if sent, _ := n.NotifyLive(ctx, "streamer", "stream-3", "Later"); len(sent) != 2 {
+		t.Errorf("Expected 2 notifications after the dedup window, got %d", len(sent))
+	}
+}
+
+// TestAttach tests notifying followers from stream start events
+func TestAttach(t *testing.T) {
+	ctx := context.Background()
+	bus := sdk.NewEventBus(nil)
+	manager := sdk.NewStreamManager(nil)
+
+	n := NewNotifier(NewMemoryStore(), DefaultConfig(), nil)
+	defer n.Close()
+	n.Follow(ctx, "alice", "streamer")
+
+	received := make(chan *sdk.StreamEvent, 1)
+	bus.Subscribe(sdk.EventLiveNotification, func(event *sdk.StreamEvent) {
+		received <- event
+	})
+	n.Attach(bus, manager)
+
+	stream, _ := manager.CreateStream(ctx, &sdk.CreateStreamRequest{UserID: "streamer", Title: "Speedrun"})
+	bus.Publish(&sdk.StreamEvent{Type: sdk.EventStreamStart, StreamID: stream.ID, UserID: "streamer"})
+
+	select {
+	case event := <-received:
+		if event.UserID != "alice" || event.StreamID != stream.ID || event.Data["title"] != "Speedrun" {
+			t.Errorf("Unexpected notification event: %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the notification event")
+	}
+}
@@ -0,0 +1,238 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store persists follows and notification preferences
+type Store interface {
+	// Follow records that userID follows streamerID
+	Follow(ctx context.Context, userID, streamerID string) error
+
+	// Unfollow removes a follow, if any
+	Unfollow(ctx context.Context, userID, streamerID string) error
+
+	// IsFollowing reports whether userID follows streamerID
+	IsFollowing(ctx context.Context, userID, streamerID string) (bool, error)
+
+	// Followers returns the users following a streamer
+	Followers(ctx context.Context, streamerID string) ([]string, error)
+
+	// Following returns the streamers a user follows
+	Following(ctx context.Context, userID string) ([]string, error)
+
+	// SetPreferences stores a user's preferences
+	SetPreferences(ctx context.Context, userID string, prefs Preferences) error
+
+	// GetPreferences returns a user's preferences, or the zero value if unset
+	GetPreferences(ctx context.Context, userID string) (Preferences, error)
+}
+
+// MemoryStore is an in-memory implementation of Store for a single node
+type MemoryStore struct {
+	followers   map[string]map[string]bool
+	following   map[string]map[string]bool
+	preferences map[string]Preferences
+	mu          sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		followers:   make(map[string]map[string]bool),
+		following:   make(map[string]map[string]bool),
+		preferences: make(map[string]Preferences),
+	}
+}
+
+// Follow records a follow
+func (s *MemoryStore) Follow(ctx context.Context, userID, streamerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	addMember(s.followers, streamerID, userID)
+	addMember(s.following, userID, streamerID)
+	return nil
+}
+
+// Unfollow removes a follow
+func (s *MemoryStore) Unfollow(ctx context.Context, userID, streamerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removeMember(s.followers, streamerID, userID)
+	removeMember(s.following, userID, streamerID)
+	return nil
+}
+
+// IsFollowing reports whether userID follows streamerID
+func (s *MemoryStore) IsFollowing(ctx context.Context, userID, streamerID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.following[userID][streamerID], nil
+}
+
+// Followers returns the users following a streamer, sorted
+func (s *MemoryStore) Followers(ctx context.Context, streamerID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedMembers(s.followers[streamerID]), nil
+}
+
+// Following returns the streamers a user follows, sorted
+func (s *MemoryStore) Following(ctx context.Context, userID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedMembers(s.following[userID]), nil
+}
+
+// SetPreferences stores a user's preferences
+func (s *MemoryStore) SetPreferences(ctx context.Context, userID string, prefs Preferences) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefs.Muted = append([]string(nil), prefs.Muted...)
+	s.preferences[userID] = prefs
+	return nil
+}
+
+// GetPreferences returns a user's preferences
+func (s *MemoryStore) GetPreferences(ctx context.Context, userID string) (Preferences, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prefs := s.preferences[userID]
+	prefs.Muted = append([]string(nil), prefs.Muted...)
+	return prefs, nil
+}
+
+// addMember adds member to the set at key
+func addMember(sets map[string]map[string]bool, key, member string) {
+	set, exists := sets[key]
+	if !exists {
+		set = make(map[string]bool)
+		sets[key] = set
+	}
+	set[member] = true
+}
+
+// removeMember removes member from the set at key, dropping empty sets
+func removeMember(sets map[string]map[string]bool, key, member string) {
+	delete(sets[key], member)
+	if len(sets[key]) == 0 {
+		delete(sets, key)
+	}
+}
+
+// sortedMembers returns the members of a set, sorted
+func sortedMembers(set map[string]bool) []string {
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// RedisStore implements Store using Redis sets of followers and followed
+// streamers, so follows are shared by every node
+type RedisStore struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// NewRedisStore creates a Redis-based store
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{
+		client:    client,
+		keyPrefix: "notifications:",
+	}
+}
+
+// Follow records a follow in both directions atomically
+func (s *RedisStore) Follow(ctx context.Context, userID, streamerID string) error {
+	pipe := s.client.TxPipeline()
+	pipe.SAdd(ctx, s.followersKey(streamerID), userID)
+	pipe.SAdd(ctx, s.followingKey(userID), streamerID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Unfollow removes a follow in both directions atomically
+func (s *RedisStore) Unfollow(ctx context.Context, userID, streamerID string) error {
+	pipe := s.client.TxPipeline()
+	pipe.SRem(ctx, s.followersKey(streamerID), userID)
+	pipe.SRem(ctx, s.followingKey(userID), streamerID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// IsFollowing reports whether userID follows streamerID
+func (s *RedisStore) IsFollowing(ctx context.Context, userID, streamerID string) (bool, error) {
+	return s.client.SIsMember(ctx, s.followingKey(userID), streamerID).Result()
+}
+
+// Followers returns the users following a streamer, sorted
+func (s *RedisStore) Followers(ctx context.Context, streamerID string) ([]string, error) {
+	return s.members(ctx, s.followersKey(streamerID))
+}
+
+// Following returns the streamers a user follows, sorted
+func (s *RedisStore) Following(ctx context.Context, userID string) ([]string, error) {
+	return s.members(ctx, s.followingKey(userID))
+}
+
+// SetPreferences stores a user's preferences as JSON
+func (s *RedisStore) SetPreferences(ctx context.Context, userID string, prefs Preferences) error {
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(ctx, s.keyPrefix+"prefs:"+userID, data, 0).Err()
+}
+
+// GetPreferences returns a user's preferences
+func (s *RedisStore) GetPreferences(ctx context.Context, userID string) (Preferences, error) {
+	var prefs Preferences
+
+	data, err := s.client.Get(ctx, s.keyPrefix+"prefs:"+userID).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return prefs, nil
+		}
+		return prefs, err
+	}
+
+	err = json.Unmarshal(data, &prefs)
+	return prefs, err
+}
+
+// members returns the members of a set, sorted
+func (s *RedisStore) members(ctx context.Context, key string) ([]string, error) {
+	members, err := s.client.SMembers(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(members)
+	return members, nil
+}
+
+// followersKey returns the key of a streamer's followers
+func (s *RedisStore) followersKey(streamerID string) string {
+	return s.keyPrefix + "followers:" + streamerID
+}
+
+// followingKey returns the key of the streamers a user follows
+func (s *RedisStore) followingKey(userID string) string {
+	return s.keyPrefix + "following:" + userID
+}
@@ -0,0 +1,84 @@
+package overlay
+
+import (
+	"fmt"
+
+	"github.com/aminofox/zenlive/pkg/sdk"
+)
+
+// Normalize converts a gift, reaction, follow or poll event into an alert.
+// It returns false for other events and events without a stream.
+func Normalize(event *sdk.StreamEvent) (*Alert, bool) {
+	if event == nil || event.StreamID == "" {
+		return nil, false
+	}
+
+	alert := &Alert{
+		StreamID:  event.StreamID,
+		UserID:    event.UserID,
+		Data:      event.Data,
+		CreatedAt: event.Timestamp,
+	}
+	name := stringData(event.Data, "username", event.UserID)
+
+	switch event.Type {
+	case sdk.EventGiftCombo:
+		alert.Type = AlertGift
+		combo := numberData(event.Data, "combo")
+		alert.Amount = numberData(event.Data, "value")
+		if alert.Amount == 0 {
+			alert.Amount = combo
+		}
+		gift := stringData(event.Data, "gift", "gift")
+		if combo > 1 {
+			alert.Message = fmt.Sprintf("%s sent %s x%d", name, gift, int(combo))
+		} else {
+			alert.Message = fmt.Sprintf("%s sent %s", name, gift)
+		}
+
+	case sdk.EventReactionBurst:
+		alert.Type = AlertReaction
+		alert.Amount = numberData(event.Data, "count")
+		alert.Message = fmt.Sprintf("%d %s reactions", int(alert.Amount), stringData(event.Data, "emoji", "new"))
+
+	case sdk.EventFollow:
+		alert.Type = AlertFollow
+		alert.Message = fmt.Sprintf("%s followed", name)
+
+	case sdk.EventPollEnded:
+		alert.Type = AlertPoll
+		question := stringData(event.Data, "question", "Poll")
+		if winner := stringData(event.Data, "winner", ""); winner != "" {
+			alert.Message = fmt.Sprintf("%s: %s wins", question, winner)
+		} else {
+			alert.Message = fmt.Sprintf("%s: poll closed", question)
+		}
+
+	default:
+		return nil, false
+	}
+
+	return alert, true
+}
+
+// stringData reads a string event data field, or fallback when unset
+func stringData(data map[string]interface{}, key, fallback string) string {
+	if v, ok := data[key].(string); ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+// numberData reads a numeric event data field, which may have been decoded
+// from JSON as a float64
+func numberData(data map[string]interface{}, key string) float64 {
+	switch v := data[key].(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package overlay
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+)
+
+// KeyParam is the query parameter overlay clients pass their key in.
+// Browser sources cannot set headers, so the key is part of the overlay URL.
+const KeyParam = "key"
+
+var (
+	// ErrKeysDisabled is returned when no key secret is configured
+	ErrKeysDisabled = errors.New("overlay keys are not configured")
+	// ErrInvalidKey is returned for a missing or wrong overlay key
+	ErrInvalidKey = errors.New("invalid overlay key")
+)
+
+// Key returns the overlay key of a stream, for the streamer to put in the
+// overlay URL. Keys are derived from Config.KeySecret, so changing the
+// secret revokes every key; RevokeKey revokes a single stream's key.
+func (m *Manager) Key(streamID string) (string, error) {
+	if m.config.KeySecret == "" {
+		return "", ErrKeysDisabled
+	}
+	if streamID == "" {
+		return "", ErrInvalidKey
+	}
+
+	m.mu.Lock()
+	generation := m.keyGenerations[streamID]
+	m.mu.Unlock()
+
+	return m.deriveKey(streamID, generation), nil
+}
+
+// RevokeKey replaces a stream's overlay key, e.g. after its overlay URL
+// leaked, disconnects the stream's overlay clients and returns the new key.
+// Revocations are kept in memory and do not survive a restart; change
+// Config.KeySecret to revoke keys for good.
+func (m *Manager) RevokeKey(streamID string) (string, error) {
+	if m.config.KeySecret == "" {
+		return "", ErrKeysDisabled
+	}
+	if streamID == "" {
+		return "", ErrInvalidKey
+	}
+
+	m.mu.Lock()
+	m.keyGenerations[streamID]++
+	generation := m.keyGenerations[streamID]
+
+	// Every connected client was admitted with the revoked key
+	if stream, exists := m.streams[streamID]; exists {
+		for sub := range stream.subscribers {
+			delete(stream.subscribers, sub)
+			sub.once.Do(func() { close(sub.ch) })
+		}
+		m.dropIfIdle(streamID, stream)
+	}
+	m.mu.Unlock()
+
+	return m.deriveKey(streamID, generation), nil
+}
+
+// deriveKey returns a stream's overlay key for a key generation. The first
+// generation's key is the HMAC of the stream ID alone.
+func (m *Manager) deriveKey(streamID string, generation uint64) string {
+	h := hmac.New(sha256.New, []byte(m.config.KeySecret))
+	h.Write([]byte(streamID))
+	if generation > 0 {
+		h.Write(binary.BigEndian.AppendUint64([]byte{0}, generation))
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// CheckKey verifies the overlay key of a stream
+func (m *Manager) CheckKey(streamID, key string) error {
+	expected, err := m.Key(streamID)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(key), []byte(expected)) {
+		return ErrInvalidKey
+	}
+	return nil
+}
@@ -0,0 +1,397 @@
+// Package overlay turns audience activity into alerts for streamers'
+// overlays, such as OBS browser sources. Gift, reaction, follow and poll
+// events are normalized into alert payloads and queued per stream, so
+// alerts are shown one at a time and bursts of one kind are throttled.
+package overlay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/idgen"
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/sdk"
+)
+
+var (
+	// ErrInvalidAlert is returned for an alert without a stream or type
+	ErrInvalidAlert = errors.New("invalid alert")
+	// ErrAlertCooldown is returned when an alert of the same type was shown
+	// too recently on the stream
+	ErrAlertCooldown = errors.New("alert type is cooling down")
+	// ErrQueueFull is returned when a stream has too many alerts waiting
+	ErrQueueFull = errors.New("alert queue is full")
+	// ErrClosed is returned after the manager is closed
+	ErrClosed = errors.New("overlay manager is closed")
+	// ErrTooManySubscribers is returned when a stream has as many overlay
+	// clients as Config.MaxSubscribers allows
+	ErrTooManySubscribers = errors.New("too many overlay subscribers")
+)
+
+// AlertType is the kind of activity an alert shows
+type AlertType string
+
+const (
+	// AlertGift shows a gift or gift combo
+	AlertGift AlertType = "gift"
+	// AlertReaction shows a burst of reactions
+	AlertReaction AlertType = "reaction"
+	// AlertFollow shows a new follower
+	AlertFollow AlertType = "follow"
+	// AlertPoll shows the result of a poll
+	AlertPoll AlertType = "poll"
+)
+
+// Alert is an overlay-ready alert payload
+type Alert struct {
+	// ID uniquely identifies the alert
+	ID string `json:"id"`
+	// StreamID is the stream whose overlay shows the alert
+	StreamID string `json:"stream_id"`
+	// Type is the kind of alert
+	Type AlertType `json:"type"`
+	// UserID is the viewer behind the alert, if any
+	UserID string `json:"user_id,omitempty"`
+	// Message is the text to show
+	Message string `json:"message"`
+	// Amount is the gift value, reaction count or similar (0 = none)
+	Amount float64 `json:"amount,omitempty"`
+	// Data carries the source event data for custom overlays
+	Data map[string]interface{} `json:"data,omitempty"`
+	// Duration is how long the overlay shows the alert
+	Duration time.Duration `json:"duration"`
+	// CreatedAt is when the activity happened
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Config configures alert queueing
+type Config struct {
+	// DisplayDuration is how long each alert is shown before the next one
+	// of the stream is delivered
+	DisplayDuration time.Duration
+	// Cooldowns are the minimum times between queued alerts of a type on a
+	// stream; alerts within the cooldown are dropped
+	Cooldowns map[AlertType]time.Duration
+	// MaxQueue caps the alerts waiting per stream
+	MaxQueue int
+	// SubscriberBuffer is the alerts buffered per subscriber; a subscriber
+	// that falls further behind misses alerts
+	SubscriberBuffer int
+	// MaxSubscribers caps the overlay clients per stream (0 = unlimited)
+	MaxSubscribers int
+	// KeySecret signs the per-stream overlay keys that overlay clients must
+	// present (empty = no keys can be issued or verified)
+	KeySecret string
+}
+
+// DefaultConfig returns the default overlay configuration. Gifts are never
+// throttled; reactions and follows are.
+func DefaultConfig() Config {
+	return Config{
+		DisplayDuration: 5 * time.Second,
+		Cooldowns: map[AlertType]time.Duration{
+			AlertReaction: 30 * time.Second,
+			AlertFollow:   10 * time.Second,
+		},
+		MaxQueue:         50,
+		SubscriberBuffer: 16,
+		MaxSubscribers:   5,
+	}
+}
+
+// Subscription receives the alerts of one stream
+type Subscription struct {
+	// C delivers alerts as they are shown. It is closed when the
+	// subscription or the manager is closed.
+	C <-chan *Alert
+
+	ch       chan *Alert
+	streamID string
+	manager  *Manager
+	once     sync.Once
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.manager.unsubscribe(s)
+}
+
+// streamAlerts is the alert state of one stream
+type streamAlerts struct {
+	queue       []*Alert
+	lastByType  map[AlertType]time.Time
+	subscribers map[*Subscription]struct{}
+	dispatching bool
+}
+
+// Manager queues alerts per stream and delivers them to the stream's
+// overlay subscribers
+type Manager struct {
+	config Config
+	logger logger.Logger
+
+	streams map[string]*streamAlerts
+	// keyGenerations counts the revocations of each stream's overlay key
+	keyGenerations map[string]uint64
+	closed         bool
+	done           chan struct{}
+	wg             sync.WaitGroup
+	mu             sync.Mutex
+}
+
+// NewManager creates an overlay manager
+func NewManager(config Config, log logger.Logger) *Manager {
+	if log == nil {
+		log = logger.NewDefaultLogger(logger.InfoLevel, "text")
+	}
+	if config.SubscriberBuffer <= 0 {
+		config.SubscriberBuffer = 1
+	}
+
+	return &Manager{
+		config:         config,
+		logger:         log,
+		streams:        make(map[string]*streamAlerts),
+		keyGenerations: make(map[string]uint64),
+		done:           make(chan struct{}),
+	}
+}
+
+// Subscribe returns a subscription to the alerts of a stream, for the
+// streamer's overlay client
+func (m *Manager) Subscribe(streamID string) (*Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+
+	stream := m.stream(streamID)
+	if m.config.MaxSubscribers > 0 && len(stream.subscribers) >= m.config.MaxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+
+	ch := make(chan *Alert, m.config.SubscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, streamID: streamID, manager: m}
+	stream.subscribers[sub] = struct{}{}
+
+	return sub, nil
+}
+
+// unsubscribe removes a subscription and closes its channel
+func (m *Manager) unsubscribe(sub *Subscription) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stream, exists := m.streams[sub.streamID]; exists {
+		delete(stream.subscribers, sub)
+		m.dropIfIdle(sub.streamID, stream)
+	}
+	sub.once.Do(func() { close(sub.ch) })
+}
+
+// Push queues an alert for its stream's overlay. The alert is dropped with
+// ErrAlertCooldown when its type is cooling down on the stream.
+func (m *Manager) Push(alert *Alert) error {
+	if alert == nil || alert.StreamID == "" || alert.Type == "" {
+		return ErrInvalidAlert
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+
+	if alert.ID == "" {
+		alert.ID = idgen.WithPrefix(idgen.Default(), "alert")
+	}
+	if alert.CreatedAt.IsZero() {
+		alert.CreatedAt = time.Now()
+	}
+	if alert.Duration <= 0 {
+		alert.Duration = m.config.DisplayDuration
+	}
+
+	stream := m.stream(alert.StreamID)
+
+	if cooldown := m.config.Cooldowns[alert.Type]; cooldown > 0 {
+		if last, exists := stream.lastByType[alert.Type]; exists && alert.CreatedAt.Sub(last) < cooldown {
+			return fmt.Errorf("%w: %s", ErrAlertCooldown, alert.Type)
+		}
+	}
+	if m.config.MaxQueue > 0 && len(stream.queue) >= m.config.MaxQueue {
+		return ErrQueueFull
+	}
+
+	stream.lastByType[alert.Type] = alert.CreatedAt
+	stream.queue = append(stream.queue, alert)
+
+	if !stream.dispatching {
+		stream.dispatching = true
+		m.wg.Add(1)
+		go m.dispatch(alert.StreamID)
+	}
+
+	return nil
+}
+
+// PushEvent normalizes an event and queues the resulting alert
+func (m *Manager) PushEvent(event *sdk.StreamEvent) error {
+	alert, ok := Normalize(event)
+	if !ok {
+		return ErrInvalidAlert
+	}
+	return m.Push(alert)
+}
+
+// Attach queues alerts for the gift, reaction, follow and poll events on
+// bus. Follows are shown on the followed streamer's live streams, looked up
+// in streams (nil = follows are not shown).
+func (m *Manager) Attach(bus *sdk.EventBus, streams *sdk.StreamManager) []*sdk.EventSubscription {
+	push := func(event *sdk.StreamEvent) {
+		if err := m.PushEvent(event); err != nil && !errors.Is(err, ErrAlertCooldown) {
+			m.logger.Warn("Failed to queue overlay alert",
+				logger.String("stream_id", event.StreamID),
+				logger.String("event_type", string(event.Type)),
+				logger.Err(err),
+			)
+		}
+	}
+
+	return []*sdk.EventSubscription{
+		bus.Subscribe(sdk.EventGiftCombo, push),
+		bus.Subscribe(sdk.EventReactionBurst, push),
+		bus.Subscribe(sdk.EventPollEnded, push),
+		bus.Subscribe(sdk.EventFollow, func(event *sdk.StreamEvent) {
+			if streams == nil {
+				return
+			}
+
+			streamerID, _ := event.Data["streamer_id"].(string)
+			live, err := streams.GetStreamsByUser(context.Background(), streamerID)
+			if err != nil {
+				return
+			}
+
+			for _, stream := range live {
+				if stream.State != sdk.StateLive {
+					continue
+				}
+				followed := *event
+				followed.StreamID = stream.ID
+				push(&followed)
+			}
+		}),
+	}
+}
+
+// Pending returns the number of alerts waiting to be shown on a stream
+func (m *Manager) Pending(streamID string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stream, exists := m.streams[streamID]; exists {
+		return len(stream.queue)
+	}
+	return 0
+}
+
+// Close stops delivering alerts and closes every subscription. Queued
+// alerts are dropped.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	close(m.done)
+	m.mu.Unlock()
+
+	m.wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, stream := range m.streams {
+		for sub := range stream.subscribers {
+			sub.once.Do(func() { close(sub.ch) })
+		}
+	}
+	m.streams = make(map[string]*streamAlerts)
+}
+
+// stream returns the state of a stream, creating it if needed. The caller
+// must hold m.mu.
+func (m *Manager) stream(streamID string) *streamAlerts {
+	stream, exists := m.streams[streamID]
+	if !exists {
+		stream = &streamAlerts{
+			lastByType:  make(map[AlertType]time.Time),
+			subscribers: make(map[*Subscription]struct{}),
+		}
+		m.streams[streamID] = stream
+	}
+	return stream
+}
+
+// dropIfIdle forgets a stream with no subscribers, queued alerts or
+// cooldowns still running. The caller must hold m.mu.
+func (m *Manager) dropIfIdle(streamID string, stream *streamAlerts) {
+	if len(stream.subscribers) > 0 || len(stream.queue) > 0 || stream.dispatching {
+		return
+	}
+
+	now := time.Now()
+	for alertType, last := range stream.lastByType {
+		if now.Sub(last) < m.config.Cooldowns[alertType] {
+			return
+		}
+	}
+	delete(m.streams, streamID)
+}
+
+// dispatch delivers a stream's queued alerts one at a time, waiting for
+// each to be shown before delivering the next
+func (m *Manager) dispatch(streamID string) {
+	defer m.wg.Done()
+
+	for {
+		m.mu.Lock()
+		stream := m.streams[streamID]
+		if len(stream.queue) == 0 {
+			stream.dispatching = false
+			m.dropIfIdle(streamID, stream)
+			m.mu.Unlock()
+			return
+		}
+
+		alert := stream.queue[0]
+		stream.queue = stream.queue[1:]
+
+		// Deliver without blocking the queue on a slow overlay client
+		for sub := range stream.subscribers {
+			select {
+			case sub.ch <- alert:
+			default:
+				m.logger.Warn("Overlay subscriber is falling behind, alert dropped",
+					logger.String("stream_id", streamID),
+					logger.String("alert_id", alert.ID),
+				)
+			}
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-time.After(alert.Duration):
+		case <-m.done:
+			return
+		}
+	}
+}
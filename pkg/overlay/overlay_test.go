@@ -0,0 +1,251 @@
+package overlay
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/sdk"
+)
+
+// testConfig shows alerts briefly so tests run fast
+func testConfig() Config {
+	config := DefaultConfig()
+	config.DisplayDuration = 20 * time.Millisecond
+	return config
+}
+
+// receive waits for the next alert of a subscription
+func receive(t *testing.T, sub *Subscription) *Alert {
+	t.Helper()
+
+	select {
+	case alert := <-sub.C:
+		return alert
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for an alert")
+		return nil
+	}
+}
+
+// TestNormalize tests converting events into alerts
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   *sdk.StreamEvent
+		want    AlertType
+		message string
+		amount  float64
+	}{
+		{
+			"gift combo",
+			&sdk.StreamEvent{Type: sdk.EventGiftCombo, StreamID: "s1", UserID: "u1", Data: map[string]interface{}{"username": "alice", "gift": "rose", "combo": 5}},
+			AlertGift, "alice sent rose x5", 5,
+		},
+		{
+			"gift value",
+			&sdk.StreamEvent{Type: sdk.EventGiftCombo, StreamID: "s1", UserID: "u1", Data: map[string]interface{}{"combo": 1.0, "value": 250.0}},
+			AlertGift, "u1 sent gift", 250,
+		},
+		{
+			"reaction burst",
+			&sdk.StreamEvent{Type: sdk.EventReactionBurst, StreamID: "s1", Data: map[string]interface{}{"count": 40, "emoji": "🔥"}},
+			AlertReaction, "40 🔥 reactions", 40,
+		},
+		{
+			"follow",
+			&sdk.StreamEvent{Type: sdk.EventFollow, StreamID: "s1", UserID: "bob"},
+			AlertFollow, "bob followed", 0,
+		},
+		{
+			"poll",
+			&sdk.StreamEvent{Type: sdk.EventPollEnded, StreamID: "s1", Data: map[string]interface{}{"question": "Next game?", "winner": "Tetris"}},
+			AlertPoll, "Next game?: Tetris wins", 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert, ok := Normalize(tt.event)
+			if !ok {
+				t.Fatal("Expected the event to normalize")
+			}
+			if alert.Type != tt.want || alert.Message != tt.message || alert.Amount != tt.amount {
+				t.Errorf("Expected %s %q %v, got %s %q %v", tt.want, tt.message, tt.amount, alert.Type, alert.Message, alert.Amount)
+			}
+		})
+	}
+
+	if _, ok := Normalize(&sdk.StreamEvent{Type: sdk.EventStreamStart, StreamID: "s1"}); ok {
+		t.Error("Expected stream start not to normalize")
+	}
+	if _, ok := Normalize(&sdk.StreamEvent{Type: sdk.EventGiftCombo}); ok {
+		t.Error("Expected an event without a stream not to normalize")
+	}
+}
+
+// TestManagerQueuesAlerts tests that alerts are delivered one at a time,
+// in order, with cooldowns applied
+func TestManagerQueuesAlerts(t *testing.T) {
+	m := NewManager(testConfig(), nil)
+	defer m.Close()
+
+	sub, err := m.Subscribe("s1")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	other, _ := m.Subscribe("s2")
+
+	start := time.Now()
+	m.Push(&Alert{StreamID: "s1", Type: AlertGift, Message: "first"})
+	m.Push(&Alert{StreamID: "s1", Type: AlertGift, Message: "second"})
+	if err := m.Push(&Alert{StreamID: "s1", Type: AlertFollow, Message: "follow"}); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if err := m.Push(&Alert{StreamID: "s1", Type: AlertFollow, Message: "again"}); !errors.Is(err, ErrAlertCooldown) {
+		t.Errorf("Expected ErrAlertCooldown, got %v", err)
+	}
+	if err := m.Push(&Alert{Type: AlertGift}); !errors.Is(err, ErrInvalidAlert) {
+		t.Errorf("Expected ErrInvalidAlert, got %v", err)
+	}
+
+	for _, want := range []string{"first", "second", "follow"} {
+		if alert := receive(t, sub); alert.Message != want || alert.ID == "" {
+			t.Errorf("Expected %q, got %+v", want, alert)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected alerts to be spaced by the display duration, all arrived in %v", elapsed)
+	}
+
+	select {
+	case alert := <-other.C:
+		t.Errorf("Expected no alerts on another stream, got %+v", alert)
+	default:
+	}
+
+	sub.Close()
+	if _, ok := <-sub.C; ok {
+		t.Error("Expected a closed subscription channel")
+	}
+}
+
+// TestManagerAccess tests overlay keys and the subscriber cap
+func TestManagerAccess(t *testing.T) {
+	if _, err := NewManager(testConfig(), nil).Key("s1"); !errors.Is(err, ErrKeysDisabled) {
+		t.Errorf("Expected ErrKeysDisabled without a secret, got %v", err)
+	}
+
+	config := testConfig()
+	config.KeySecret = "secret"
+	config.MaxSubscribers = 2
+	m := NewManager(config, nil)
+	defer m.Close()
+
+	key, err := m.Key("s1")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if err := m.CheckKey("s1", key); err != nil {
+		t.Errorf("Expected the stream's key to verify, got %v", err)
+	}
+	if err := m.CheckKey("s2", key); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey for another stream, got %v", err)
+	}
+	if err := m.CheckKey("s1", ""); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey without a key, got %v", err)
+	}
+
+	first, _ := m.Subscribe("s1")
+	m.Subscribe("s1")
+	if _, err := m.Subscribe("s1"); !errors.Is(err, ErrTooManySubscribers) {
+		t.Errorf("Expected ErrTooManySubscribers, got %v", err)
+	}
+	first.Close()
+	connected, err := m.Subscribe("s1")
+	if err != nil {
+		t.Errorf("Expected a subscription after one closed, got %v", err)
+	}
+
+	// Revoking one stream's key leaves the others valid
+	other, _ := m.Key("s2")
+	revoked, err := m.RevokeKey("s1")
+	if err != nil {
+		t.Fatalf("Failed to revoke key: %v", err)
+	}
+	if err := m.CheckKey("s1", key); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey for the revoked key, got %v", err)
+	}
+	if err := m.CheckKey("s1", revoked); err != nil {
+		t.Errorf("Expected the new key to verify, got %v", err)
+	}
+	if current, _ := m.Key("s1"); current != revoked {
+		t.Error("Expected Key to return the new key")
+	}
+	if err := m.CheckKey("s2", other); err != nil {
+		t.Errorf("Expected another stream's key to stay valid, got %v", err)
+	}
+	if _, ok := <-connected.C; ok {
+		t.Error("Expected clients admitted with the revoked key to be disconnected")
+	}
+}
+
+// TestManagerAttach tests queueing alerts from the event bus
+func TestManagerAttach(t *testing.T) {
+	ctx := context.Background()
+	bus := sdk.NewEventBus(nil)
+	streams := sdk.NewStreamManager(nil)
+
+	m := NewManager(testConfig(), nil)
+	defer m.Close()
+
+	stream, _ := streams.CreateStream(ctx, &sdk.CreateStreamRequest{UserID: "streamer", Title: "Speedrun"})
+	sdk.NewStreamController(streams, nil, nil).StartStream(ctx, stream.ID)
+	m.Attach(bus, streams)
+
+	sub, _ := m.Subscribe(stream.ID)
+	bus.Publish(&sdk.StreamEvent{Type: sdk.EventFollow, UserID: "alice", Data: map[string]interface{}{"streamer_id": "streamer"}})
+
+	if alert := receive(t, sub); alert.Type != AlertFollow || alert.UserID != "alice" {
+		t.Errorf("Expected a follow alert from alice, got %+v", alert)
+	}
+}
+
+// TestAudienceEvents tests the application-published reaction, gift and
+// poll events reaching both the overlay and the highlight detector
+func TestAudienceEvents(t *testing.T) {
+	bus := sdk.NewEventBus(nil)
+
+	m := NewManager(testConfig(), nil)
+	defer m.Close()
+	m.Attach(bus, nil)
+
+	detector := sdk.NewHighlightDetector(sdk.DefaultHighlightConfig(), nil, nil, bus, nil)
+	detector.Attach()
+
+	sub, _ := m.Subscribe("s1")
+	bus.Publish(&sdk.StreamEvent{Type: sdk.EventReactionBurst, StreamID: "s1", Data: map[string]interface{}{"count": 60, "emoji": "fire"}})
+	if alert := receive(t, sub); alert.Type != AlertReaction || alert.Amount != 60 {
+		t.Errorf("Expected a reaction alert for 60 reactions, got %+v", alert)
+	}
+
+	bus.Publish(&sdk.StreamEvent{Type: sdk.EventGiftCombo, StreamID: "s1", UserID: "alice", Data: map[string]interface{}{"combo": 5, "gift": "rose"}})
+	if alert := receive(t, sub); alert.Type != AlertGift || alert.Message != "alice sent rose x5" {
+		t.Errorf("Expected a gift alert from alice, got %+v", alert)
+	}
+
+	bus.Publish(&sdk.StreamEvent{Type: sdk.EventPollEnded, StreamID: "s1", Data: map[string]interface{}{"question": "Next map?", "winner": "Dust"}})
+	if alert := receive(t, sub); alert.Type != AlertPoll || alert.Message != "Next map?: Dust wins" {
+		t.Errorf("Expected a poll alert, got %+v", alert)
+	}
+
+	// 60 reactions and a 5-gift combo score 110, crossing the threshold
+	deadline := time.Now().Add(time.Second)
+	for len(detector.Pending("s1")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if pending := detector.Pending("s1"); len(pending) != 1 || pending[0].Score != 110 {
+		t.Errorf("Expected one highlight scoring 110, got %+v", pending)
+	}
+}
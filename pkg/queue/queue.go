@@ -0,0 +1,350 @@
+// Package queue provides a small in-process message queue with delayed
+// requeue, bounded attempts and an optional persistence hook.
+package queue
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/idgen"
+)
+
+var (
+	// ErrQueueFull is returned when the queue is at capacity
+	ErrQueueFull = errors.New("queue is full")
+	// ErrQueueClosed is returned once the queue has been closed
+	ErrQueueClosed = errors.New("queue is closed")
+	// ErrMessageNotFound is returned when acking an unknown or already settled message
+	ErrMessageNotFound = errors.New("message not in flight")
+)
+
+// Message is a queued payload and its delivery state
+type Message[T any] struct {
+	// ID uniquely identifies the message
+	ID string
+	// Payload is the queued value
+	Payload T
+	// Attempts is the number of times the message has been dequeued
+	Attempts int
+	// EnqueuedAt is when the message was first enqueued
+	EnqueuedAt time.Time
+	// AvailableAt is when the message may next be dequeued
+	AvailableAt time.Time
+}
+
+// Store persists queued messages so they survive a restart
+type Store[T any] interface {
+	// Save stores a new or updated message
+	Save(msg *Message[T]) error
+	// Remove deletes a settled message
+	Remove(id string) error
+	// Load returns all stored messages
+	Load() ([]*Message[T], error)
+}
+
+// Options configures a queue
+type Options struct {
+	// Capacity is the maximum number of pending and in-flight messages (0 = unlimited)
+	Capacity int
+	// MaxAttempts is how many times a message may be dequeued before Nack
+	// dead-letters it (0 = unlimited)
+	MaxAttempts int
+}
+
+// Queue is an in-memory queue of T. Dequeued messages stay in flight until
+// they are acknowledged with Ack or returned with Nack.
+type Queue[T any] struct {
+	opts         Options
+	pending      messageHeap[T]
+	inFlight     map[string]*Message[T]
+	store        Store[T]
+	onDeadLetter func(msg *Message[T])
+	wake         chan struct{}
+	closed       bool
+	seq          uint64
+	mu           sync.Mutex
+}
+
+// New creates a queue
+func New[T any](opts Options) *Queue[T] {
+	return &Queue[T]{
+		opts:     opts,
+		inFlight: make(map[string]*Message[T]),
+		wake:     make(chan struct{}),
+	}
+}
+
+// SetStore attaches a persistence hook and loads previously stored messages.
+// Messages that were in flight when the process stopped are redelivered.
+func (q *Queue[T]) SetStore(store Store[T]) error {
+	msgs, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.store = store
+	for _, msg := range msgs {
+		q.push(msg)
+	}
+	q.notify()
+
+	return nil
+}
+
+// OnDeadLetter registers a callback for messages dropped after MaxAttempts
+func (q *Queue[T]) OnDeadLetter(callback func(msg *Message[T])) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.onDeadLetter = callback
+}
+
+// Enqueue adds a payload that is available immediately
+func (q *Queue[T]) Enqueue(payload T) (string, error) {
+	return q.EnqueueDelayed(payload, 0)
+}
+
+// EnqueueDelayed adds a payload that becomes available after delay
+func (q *Queue[T]) EnqueueDelayed(payload T, delay time.Duration) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return "", ErrQueueClosed
+	}
+
+	if q.opts.Capacity > 0 && q.lenLocked() >= q.opts.Capacity {
+		return "", ErrQueueFull
+	}
+
+	now := time.Now()
+	msg := &Message[T]{
+		ID:          idgen.WithPrefix(idgen.Default(), "msg"),
+		Payload:     payload,
+		EnqueuedAt:  now,
+		AvailableAt: now.Add(delay),
+	}
+
+	if q.store != nil {
+		if err := q.store.Save(msg); err != nil {
+			return "", err
+		}
+	}
+
+	q.push(msg)
+	q.notify()
+
+	return msg.ID, nil
+}
+
+// Dequeue waits for the next available message and marks it in flight.
+// It returns ErrQueueClosed once the queue is closed.
+func (q *Queue[T]) Dequeue(ctx context.Context) (*Message[T], error) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return nil, ErrQueueClosed
+		}
+
+		var wait time.Duration = -1
+		if len(q.pending) > 0 {
+			next := q.pending[0]
+			if wait = time.Until(next.AvailableAt); wait <= 0 {
+				msg := q.take()
+				q.mu.Unlock()
+				return msg, nil
+			}
+		}
+		wake := q.wake
+		q.mu.Unlock()
+
+		// Wait for the next message to become available or the queue to change
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-wake:
+		case <-timeout:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// TryDequeue returns the next available message without waiting
+func (q *Queue[T]) TryDequeue() (*Message[T], bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || len(q.pending) == 0 || time.Now().Before(q.pending[0].AvailableAt) {
+		return nil, false
+	}
+
+	return q.take(), true
+}
+
+// Ack settles an in-flight message
+func (q *Queue[T]) Ack(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.inFlight[id]; !ok {
+		return ErrMessageNotFound
+	}
+
+	delete(q.inFlight, id)
+
+	if q.store != nil {
+		return q.store.Remove(id)
+	}
+
+	return nil
+}
+
+// Nack returns an in-flight message to the queue after delay. Messages that
+// have reached MaxAttempts are dead-lettered instead.
+func (q *Queue[T]) Nack(id string, delay time.Duration) error {
+	q.mu.Lock()
+
+	msg, ok := q.inFlight[id]
+	if !ok {
+		q.mu.Unlock()
+		return ErrMessageNotFound
+	}
+
+	delete(q.inFlight, id)
+
+	if q.opts.MaxAttempts > 0 && msg.Attempts >= q.opts.MaxAttempts {
+		var err error
+		if q.store != nil {
+			err = q.store.Remove(id)
+		}
+		callback := q.onDeadLetter
+		q.mu.Unlock()
+
+		if callback != nil {
+			callback(msg)
+		}
+		return err
+	}
+
+	defer q.mu.Unlock()
+
+	msg.AvailableAt = time.Now().Add(delay)
+
+	if q.store != nil {
+		if err := q.store.Save(msg); err != nil {
+			return err
+		}
+	}
+
+	if !q.closed {
+		q.push(msg)
+		q.notify()
+	}
+
+	return nil
+}
+
+// Len returns the number of pending and in-flight messages
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.lenLocked()
+}
+
+// InFlight returns the number of dequeued messages awaiting Ack or Nack
+func (q *Queue[T]) InFlight() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.inFlight)
+}
+
+// Close stops the queue and wakes waiting consumers. Stored messages are kept.
+func (q *Queue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+
+	q.closed = true
+	q.notify()
+}
+
+// lenLocked returns the queue size. Caller must hold q.mu.
+func (q *Queue[T]) lenLocked() int {
+	return len(q.pending) + len(q.inFlight)
+}
+
+// push adds a message to the pending heap. Caller must hold q.mu.
+func (q *Queue[T]) push(msg *Message[T]) {
+	q.seq++
+	heap.Push(&q.pending, &heapItem[T]{Message: msg, seq: q.seq})
+}
+
+// take pops the next message and marks it in flight. Caller must hold q.mu.
+func (q *Queue[T]) take() *Message[T] {
+	msg := heap.Pop(&q.pending).(*heapItem[T]).Message
+	msg.Attempts++
+	q.inFlight[msg.ID] = msg
+	return msg
+}
+
+// notify wakes all waiting consumers. Caller must hold q.mu.
+func (q *Queue[T]) notify() {
+	close(q.wake)
+	q.wake = make(chan struct{})
+}
+
+// heapItem orders messages by availability, then insertion order
+type heapItem[T any] struct {
+	*Message[T]
+	seq uint64
+}
+
+// messageHeap is a min-heap of pending messages
+type messageHeap[T any] []*heapItem[T]
+
+func (h messageHeap[T]) Len() int { return len(h) }
+
+func (h messageHeap[T]) Less(i, j int) bool {
+	if h[i].AvailableAt.Equal(h[j].AvailableAt) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].AvailableAt.Before(h[j].AvailableAt)
+}
+
+func (h messageHeap[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *messageHeap[T]) Push(x interface{}) { *h = append(*h, x.(*heapItem[T])) }
+
+func (h *messageHeap[T]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,232 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore is an in-memory Store used by tests
+type memoryStore struct {
+	msgs map[string]Message[string]
+	mu   sync.Mutex
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{msgs: make(map[string]Message[string])}
+}
+
+func (s *memoryStore) Save(msg *Message[string]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.msgs[msg.ID] = *msg
+	return nil
+}
+
+func (s *memoryStore) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.msgs, id)
+	return nil
+}
+
+func (s *memoryStore) Load() ([]*Message[string], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msgs := make([]*Message[string], 0, len(s.msgs))
+	for _, msg := range s.msgs {
+		m := msg
+		msgs = append(msgs, &m)
+	}
+	return msgs, nil
+}
+
+func TestQueueOrderAndAck(t *testing.T) {
+	q := New[string](Options{})
+	ctx := context.Background()
+
+	for _, payload := range []string{"a", "b", "c"} {
+		if _, err := q.Enqueue(payload); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+
+	for _, want := range []string{"a", "b", "c"} {
+		msg, err := q.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue failed: %v", err)
+		}
+		if msg.Payload != want {
+			t.Errorf("Expected %s, got %s", want, msg.Payload)
+		}
+		if msg.Attempts != 1 {
+			t.Errorf("Expected 1 attempt, got %d", msg.Attempts)
+		}
+		if err := q.Ack(msg.ID); err != nil {
+			t.Errorf("Ack failed: %v", err)
+		}
+	}
+
+	if q.Len() != 0 {
+		t.Errorf("Expected empty queue, got %d", q.Len())
+	}
+
+	if err := q.Ack("unknown"); err != ErrMessageNotFound {
+		t.Errorf("Expected ErrMessageNotFound, got %v", err)
+	}
+}
+
+func TestQueueCapacity(t *testing.T) {
+	q := New[string](Options{Capacity: 1})
+
+	if _, err := q.Enqueue("a"); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if _, err := q.Enqueue("b"); err != ErrQueueFull {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	// In-flight messages still count towards capacity
+	msg, _ := q.TryDequeue()
+	if _, err := q.Enqueue("b"); err != ErrQueueFull {
+		t.Errorf("Expected ErrQueueFull while in flight, got %v", err)
+	}
+
+	q.Ack(msg.ID)
+	if _, err := q.Enqueue("b"); err != nil {
+		t.Errorf("Enqueue after Ack failed: %v", err)
+	}
+}
+
+func TestQueueNackDelay(t *testing.T) {
+	q := New[string](Options{})
+
+	q.Enqueue("a")
+	msg, ok := q.TryDequeue()
+	if !ok {
+		t.Fatal("Expected a message")
+	}
+
+	if err := q.Nack(msg.ID, 50*time.Millisecond); err != nil {
+		t.Fatalf("Nack failed: %v", err)
+	}
+
+	if _, ok := q.TryDequeue(); ok {
+		t.Error("Message should not be available before its delay")
+	}
+
+	start := time.Now()
+	msg, err := q.Dequeue(context.Background())
+	if err != nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	if time.Since(start) < 30*time.Millisecond {
+		t.Error("Dequeue returned before the retry delay")
+	}
+	if msg.Attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", msg.Attempts)
+	}
+}
+
+func TestQueueMaxAttempts(t *testing.T) {
+	q := New[string](Options{MaxAttempts: 2})
+
+	var dead *Message[string]
+	q.OnDeadLetter(func(msg *Message[string]) {
+		dead = msg
+	})
+
+	q.Enqueue("a")
+
+	msg, _ := q.TryDequeue()
+	q.Nack(msg.ID, 0)
+
+	msg, ok := q.TryDequeue()
+	if !ok {
+		t.Fatal("Expected message to be requeued")
+	}
+	q.Nack(msg.ID, 0)
+
+	if _, ok := q.TryDequeue(); ok {
+		t.Error("Message should be dead-lettered after MaxAttempts")
+	}
+	if dead == nil || dead.Payload != "a" || dead.Attempts != 2 {
+		t.Errorf("Expected dead letter for 'a' after 2 attempts, got %+v", dead)
+	}
+	if q.Len() != 0 {
+		t.Errorf("Expected empty queue, got %d", q.Len())
+	}
+}
+
+func TestQueueCloseUnblocksDequeue(t *testing.T) {
+	q := New[string](Options{})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := q.Dequeue(context.Background())
+		done <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	q.Close()
+
+	select {
+	case err := <-done:
+		if err != ErrQueueClosed {
+			t.Errorf("Expected ErrQueueClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Dequeue did not return after Close")
+	}
+
+	if _, err := q.Enqueue("a"); err != ErrQueueClosed {
+		t.Errorf("Expected ErrQueueClosed, got %v", err)
+	}
+}
+
+func TestQueueDequeueContext(t *testing.T) {
+	q := New[string](Options{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := q.Dequeue(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestQueueStore(t *testing.T) {
+	store := newMemoryStore()
+
+	q := New[string](Options{})
+	if err := q.SetStore(store); err != nil {
+		t.Fatalf("SetStore failed: %v", err)
+	}
+
+	q.Enqueue("a")
+	q.Enqueue("b")
+
+	msg, _ := q.TryDequeue()
+	q.Ack(msg.ID)
+
+	// Leave "b" in flight to simulate a crash mid-delivery
+	q.TryDequeue()
+	q.Close()
+
+	restored := New[string](Options{})
+	if err := restored.SetStore(store); err != nil {
+		t.Fatalf("SetStore failed: %v", err)
+	}
+
+	msg, ok := restored.TryDequeue()
+	if !ok {
+		t.Fatal("Expected stored message to be redelivered")
+	}
+	if msg.Payload != "b" {
+		t.Errorf("Expected b, got %s", msg.Payload)
+	}
+	if restored.Len() != 1 {
+		t.Errorf("Expected 1 message, got %d", restored.Len())
+	}
+}
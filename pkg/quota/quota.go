@@ -0,0 +1,192 @@
+// Package quota caps the concurrent rooms, participants and streams of each
+// tenant, so one customer of a shared deployment can't exhaust its capacity
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aminofox/zenlive/pkg/auth"
+)
+
+// Resource is a kind of capacity counted against a tenant's quota
+type Resource string
+
+// Resources limited by a quota
+const (
+	// ResourceRooms counts open rooms
+	ResourceRooms Resource = "rooms"
+	// ResourceParticipants counts participants in rooms, including those waiting in a lobby
+	ResourceParticipants Resource = "participants"
+	// ResourceStreams counts created streams
+	ResourceStreams Resource = "streams"
+)
+
+// TenantMetadataKey is the API key metadata entry naming the key's tenant
+const TenantMetadataKey = "tenant"
+
+var (
+	// ErrQuotaExceeded is returned when acquiring a resource would exceed the tenant's limit
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrUnknownResource is returned for a resource that is not limited by quotas
+	ErrUnknownResource = errors.New("unknown quota resource")
+)
+
+// Limits caps the concurrent usage of a tenant. A zero limit is unlimited.
+type Limits struct {
+	// MaxRooms is the maximum number of open rooms
+	MaxRooms int `json:"max_rooms"`
+	// MaxParticipants is the maximum number of participants across all rooms
+	MaxParticipants int `json:"max_participants"`
+	// MaxStreams is the maximum number of streams
+	MaxStreams int `json:"max_streams"`
+}
+
+// Limit returns the limit for a resource
+func (l Limits) Limit(resource Resource) int {
+	switch resource {
+	case ResourceRooms:
+		return l.MaxRooms
+	case ResourceParticipants:
+		return l.MaxParticipants
+	case ResourceStreams:
+		return l.MaxStreams
+	}
+	return 0
+}
+
+// Usage is the current usage of a tenant
+type Usage struct {
+	// Tenant identifies the tenant
+	Tenant string `json:"tenant"`
+	// Rooms is the number of open rooms
+	Rooms int `json:"rooms"`
+	// Participants is the number of participants across all rooms
+	Participants int `json:"participants"`
+	// Streams is the number of streams
+	Streams int `json:"streams"`
+	// Limits are the limits the usage is checked against
+	Limits Limits `json:"limits"`
+}
+
+// UsageStore counts the usage of every tenant. Implementations must check
+// and increment atomically, so concurrent acquires on different nodes can't
+// overshoot a limit.
+type UsageStore interface {
+	// Acquire increments a tenant's usage of resource, or returns
+	// ErrQuotaExceeded if it already reached limit (0 = unlimited)
+	Acquire(ctx context.Context, tenant string, resource Resource, limit int) error
+
+	// Release decrements a tenant's usage of resource by count, stopping at zero
+	Release(ctx context.Context, tenant string, resource Resource, count int) error
+
+	// Get returns a tenant's usage
+	Get(ctx context.Context, tenant string) (*Usage, error)
+}
+
+// Manager enforces per-tenant limits on a usage store
+type Manager struct {
+	store    UsageStore
+	defaults Limits
+	limits   map[string]Limits
+	mu       sync.RWMutex
+}
+
+// NewManager creates a quota manager applying defaults to tenants without
+// their own limits
+func NewManager(store UsageStore, defaults Limits) *Manager {
+	return &Manager{
+		store:    store,
+		defaults: defaults,
+		limits:   make(map[string]Limits),
+	}
+}
+
+// SetLimits overrides the default limits for a tenant. Lowering a limit
+// below current usage rejects new acquires until usage drops.
+func (m *Manager) SetLimits(tenant string, limits Limits) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.limits[tenant] = limits
+}
+
+// Limits returns the limits applied to a tenant
+func (m *Manager) Limits(tenant string) Limits {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if limits, exists := m.limits[tenant]; exists {
+		return limits
+	}
+	return m.defaults
+}
+
+// Acquire takes one unit of resource for a tenant. It returns an error
+// wrapping ErrQuotaExceeded when the tenant is at its limit. Requests
+// without a tenant are not limited.
+func (m *Manager) Acquire(ctx context.Context, tenant string, resource Resource) error {
+	if tenant == "" {
+		return nil
+	}
+	if err := validateResource(resource); err != nil {
+		return err
+	}
+
+	limit := m.Limits(tenant).Limit(resource)
+	if err := m.store.Acquire(ctx, tenant, resource, limit); err != nil {
+		if errors.Is(err, ErrQuotaExceeded) {
+			return fmt.Errorf("%w: tenant %s reached its limit of %d %s", ErrQuotaExceeded, tenant, limit, resource)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Release returns count units of resource taken by Acquire
+func (m *Manager) Release(ctx context.Context, tenant string, resource Resource, count int) error {
+	if tenant == "" || count <= 0 {
+		return nil
+	}
+	if err := validateResource(resource); err != nil {
+		return err
+	}
+
+	return m.store.Release(ctx, tenant, resource, count)
+}
+
+// GetUsage returns a tenant's live usage and limits
+func (m *Manager) GetUsage(ctx context.Context, tenant string) (*Usage, error) {
+	usage, err := m.store.Get(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	usage.Tenant = tenant
+	usage.Limits = m.Limits(tenant)
+	return usage, nil
+}
+
+// TenantFromAPIKey returns the tenant an API key belongs to: its "tenant"
+// metadata if set, otherwise the access key itself
+func TenantFromAPIKey(key *auth.APIKey) string {
+	if key == nil {
+		return ""
+	}
+	if tenant := key.Metadata[TenantMetadataKey]; tenant != "" {
+		return tenant
+	}
+	return key.AccessKey
+}
+
+// validateResource checks that a resource is limited by quotas
+func validateResource(resource Resource) error {
+	switch resource {
+	case ResourceRooms, ResourceParticipants, ResourceStreams:
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownResource, resource)
+}
@@ -0,0 +1,81 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aminofox/zenlive/pkg/auth"
+)
+
+func TestManagerAcquireRelease(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(NewMemoryUsageStore(), Limits{MaxRooms: 2})
+
+	for i := 0; i < 2; i++ {
+		if err := m.Acquire(ctx, "acme", ResourceRooms); err != nil {
+			t.Fatalf("Expected room %d within quota, got %v", i, err)
+		}
+	}
+	if err := m.Acquire(ctx, "acme", ResourceRooms); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+	}
+
+	// Tenants are counted separately, and unlimited resources never fail
+	if err := m.Acquire(ctx, "globex", ResourceRooms); err != nil {
+		t.Errorf("Expected another tenant within quota, got %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := m.Acquire(ctx, "acme", ResourceStreams); err != nil {
+			t.Fatalf("Expected unlimited streams, got %v", err)
+		}
+	}
+
+	m.Release(ctx, "acme", ResourceRooms, 1)
+	if err := m.Acquire(ctx, "acme", ResourceRooms); err != nil {
+		t.Errorf("Expected released room to be reusable, got %v", err)
+	}
+
+	usage, err := m.GetUsage(ctx, "acme")
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if usage.Tenant != "acme" || usage.Rooms != 2 || usage.Streams != 10 || usage.Limits.MaxRooms != 2 {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+
+	// Releasing more than acquired stops at zero
+	m.Release(ctx, "acme", ResourceStreams, 20)
+	if usage, _ := m.GetUsage(ctx, "acme"); usage.Streams != 0 {
+		t.Errorf("Expected streams usage to stop at zero, got %d", usage.Streams)
+	}
+
+	// Per-tenant limits override the defaults
+	m.SetLimits("acme", Limits{MaxRooms: 3})
+	if err := m.Acquire(ctx, "acme", ResourceRooms); err != nil {
+		t.Errorf("Expected raised limit to allow another room, got %v", err)
+	}
+
+	// Requests without a tenant are not limited
+	if err := m.Acquire(ctx, "", ResourceRooms); err != nil {
+		t.Errorf("Expected no limit without a tenant, got %v", err)
+	}
+	if err := m.Acquire(ctx, "acme", Resource("bogus")); !errors.Is(err, ErrUnknownResource) {
+		t.Errorf("Expected ErrUnknownResource, got %v", err)
+	}
+}
+
+func TestTenantFromAPIKey(t *testing.T) {
+	if tenant := TenantFromAPIKey(&auth.APIKey{AccessKey: "API_1"}); tenant != "API_1" {
+		t.Errorf("Expected access key as tenant, got %q", tenant)
+	}
+
+	key := &auth.APIKey{AccessKey: "API_2", Metadata: map[string]string{TenantMetadataKey: "acme"}}
+	if tenant := TenantFromAPIKey(key); tenant != "acme" {
+		t.Errorf("Expected metadata tenant, got %q", tenant)
+	}
+
+	if tenant := TenantFromAPIKey(nil); tenant != "" {
+		t.Errorf("Expected no tenant for a nil key, got %q", tenant)
+	}
+}
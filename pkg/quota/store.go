@@ -0,0 +1,158 @@
+package quota
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MemoryUsageStore is an in-memory implementation of UsageStore for a
+// single node
+type MemoryUsageStore struct {
+	usage map[string]map[Resource]int
+	mu    sync.Mutex
+}
+
+// NewMemoryUsageStore creates an empty in-memory usage store
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{
+		usage: make(map[string]map[Resource]int),
+	}
+}
+
+// Acquire increments a tenant's usage unless it reached limit
+func (s *MemoryUsageStore) Acquire(ctx context.Context, tenant string, resource Resource, limit int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts, exists := s.usage[tenant]
+	if !exists {
+		counts = make(map[Resource]int)
+		s.usage[tenant] = counts
+	}
+
+	if limit > 0 && counts[resource] >= limit {
+		return ErrQuotaExceeded
+	}
+
+	counts[resource]++
+	return nil
+}
+
+// Release decrements a tenant's usage, stopping at zero
+func (s *MemoryUsageStore) Release(ctx context.Context, tenant string, resource Resource, count int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts, exists := s.usage[tenant]
+	if !exists {
+		return nil
+	}
+
+	counts[resource] -= count
+	if counts[resource] <= 0 {
+		delete(counts, resource)
+	}
+	if len(counts) == 0 {
+		delete(s.usage, tenant)
+	}
+
+	return nil
+}
+
+// Get returns a tenant's usage
+func (s *MemoryUsageStore) Get(ctx context.Context, tenant string) (*Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := s.usage[tenant]
+	return &Usage{
+		Rooms:        counts[ResourceRooms],
+		Participants: counts[ResourceParticipants],
+		Streams:      counts[ResourceStreams],
+	}, nil
+}
+
+// acquireScript increments a usage counter unless it reached the limit
+// (ARGV[2], 0 = unlimited), returning -1 when it did
+var acquireScript = redis.NewScript(`
+local current = tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0')
+local limit = tonumber(ARGV[2])
+if limit > 0 and current >= limit then
+	return -1
+end
+return redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+`)
+
+// releaseScript decrements a usage counter by ARGV[2], stopping at zero
+var releaseScript = redis.NewScript(`
+local remaining = redis.call('HINCRBY', KEYS[1], ARGV[1], -tonumber(ARGV[2]))
+if remaining <= 0 then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+	return 0
+end
+return remaining
+`)
+
+// RedisUsageStore implements UsageStore using a Redis hash per tenant, so
+// limits hold across every node in the cluster
+type RedisUsageStore struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// NewRedisUsageStore creates a Redis-based usage store
+func NewRedisUsageStore(client *redis.Client) *RedisUsageStore {
+	return &RedisUsageStore{
+		client:    client,
+		keyPrefix: "quota:",
+	}
+}
+
+// Acquire atomically increments a tenant's usage unless it reached limit
+func (s *RedisUsageStore) Acquire(ctx context.Context, tenant string, resource Resource, limit int) error {
+	count, err := acquireScript.Run(ctx, s.client, []string{s.getKey(tenant)}, string(resource), limit).Int64()
+	if err != nil {
+		return err
+	}
+	if count < 0 {
+		return ErrQuotaExceeded
+	}
+
+	return nil
+}
+
+// Release atomically decrements a tenant's usage, stopping at zero
+func (s *RedisUsageStore) Release(ctx context.Context, tenant string, resource Resource, count int) error {
+	return releaseScript.Run(ctx, s.client, []string{s.getKey(tenant)}, string(resource), count).Err()
+}
+
+// Get returns a tenant's usage across the cluster
+func (s *RedisUsageStore) Get(ctx context.Context, tenant string) (*Usage, error) {
+	values, err := s.client.HMGet(ctx, s.getKey(tenant),
+		string(ResourceRooms), string(ResourceParticipants), string(ResourceStreams),
+	).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]int, len(values))
+	for i, value := range values {
+		if text, ok := value.(string); ok {
+			counts[i], _ = strconv.Atoi(text)
+		}
+	}
+
+	return &Usage{
+		Rooms:        counts[0],
+		Participants: counts[1],
+		Streams:      counts[2],
+	}, nil
+}
+
+// getKey returns the Redis key holding a tenant's usage
+func (s *RedisUsageStore) getKey(tenant string) string {
+	return s.keyPrefix + tenant
+}
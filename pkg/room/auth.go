@@ -2,11 +2,13 @@ package room
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/aminofox/zenlive/pkg/auth"
 	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/security"
 )
 
 // JoinRoomRequest represents a request to join a room with token authentication
@@ -17,6 +19,12 @@ type JoinRoomRequest struct {
 	// AccessToken is the JWT token for authentication
 	AccessToken string `json:"access_token"`
 
+	// ClientIP is the presenting connection's IP, checked against IP-bound tokens
+	ClientIP string `json:"-"`
+
+	// DeviceID identifies the client device, checked against device-bound tokens
+	DeviceID string `json:"device_id,omitempty"`
+
 	// Additional connection metadata
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
@@ -41,22 +49,37 @@ func NewRoomAuthenticator(apiKeyManager *auth.APIKeyManager, log logger.Logger)
 
 // AuthenticateJoinRequest authenticates a room join request with token
 func (ra *RoomAuthenticator) AuthenticateJoinRequest(ctx context.Context, req *JoinRoomRequest, apiSecret string) (*Participant, error) {
+	participant, _, err := ra.authenticate(req, apiSecret)
+	return participant, err
+}
+
+// authenticate validates the join request and returns the participant with its token claims
+func (ra *RoomAuthenticator) authenticate(req *JoinRoomRequest, apiSecret string) (*Participant, *auth.AccessTokenClaims, error) {
 	// Parse and validate the access token
 	claims, err := auth.ParseAccessToken(req.AccessToken, apiSecret)
 	if err != nil {
 		ra.logger.Error("Failed to parse access token",
 			logger.Field{Key: "error", Value: err.Error()},
 		)
-		return nil, fmt.Errorf("invalid access token: %w", err)
+		return nil, nil, fmt.Errorf("invalid access token: %w", err)
 	}
 
 	// Verify the token is for the correct room
 	if claims.Video == nil || !claims.Video.RoomJoin {
-		return nil, fmt.Errorf("token does not grant room access")
+		return nil, nil, fmt.Errorf("%w: token does not grant room access", ErrUnauthorized)
 	}
 
 	if claims.Video.Room != req.RoomName {
-		return nil, fmt.Errorf("token is for room '%s', not '%s'", claims.Video.Room, req.RoomName)
+		return nil, nil, fmt.Errorf("%w: token is for room '%s', not '%s'", ErrUnauthorized, claims.Video.Room, req.RoomName)
+	}
+
+	// Verify the token is presented by the client it was issued to
+	if err := claims.VerifyBinding(req.ClientIP, req.DeviceID); err != nil {
+		ra.logger.Warn("Access token binding mismatch",
+			logger.Field{Key: "user_id", Value: claims.Identity},
+			logger.Field{Key: "client_ip", Value: req.ClientIP},
+		)
+		return nil, nil, err
 	}
 
 	// Create participant from token claims
@@ -76,6 +99,11 @@ func (ra *RoomAuthenticator) AuthenticateJoinRequest(ctx context.Context, req *J
 		IsRecorder:     claims.Video.Recorder,
 	}
 
+	// Record the real identity behind a delegated token
+	if claims.Actor != nil {
+		participant.ActorID = claims.Actor.Subject
+	}
+
 	// Add email if present
 	if claims.Email != "" {
 		if participant.Metadata == nil {
@@ -101,9 +129,12 @@ func (ra *RoomAuthenticator) AuthenticateJoinRequest(ctx context.Context, req *J
 		logger.Field{Key: "is_admin", Value: participant.IsAdmin},
 	)
 
-	return participant, nil
+	return participant, claims, nil
 }
 
+// ErrUnknownPermission is returned when validating a permission name that does not exist
+var ErrUnknownPermission = errors.New("unknown permission")
+
 // ValidateRoomPermission checks if a participant has a specific permission
 func (ra *RoomAuthenticator) ValidateRoomPermission(participant *Participant, permission string) error {
 	switch permission {
@@ -124,7 +155,7 @@ func (ra *RoomAuthenticator) ValidateRoomPermission(participant *Participant, pe
 			return ErrUnauthorized
 		}
 	default:
-		return fmt.Errorf("unknown permission: %s", permission)
+		return fmt.Errorf("%w: %s", ErrUnknownPermission, permission)
 	}
 
 	return nil
@@ -135,6 +166,8 @@ type AuthenticatedRoomManager struct {
 	*RoomManager
 	authenticator *RoomAuthenticator
 	apiSecret     string
+	tokenTracker  *auth.TokenUseTracker
+	auditLogger   *security.AuditLogger
 }
 
 // NewAuthenticatedRoomManager creates a room manager with authentication
@@ -147,17 +180,38 @@ func NewAuthenticatedRoomManager(
 		RoomManager:   NewRoomManager(log),
 		authenticator: authenticator,
 		apiSecret:     apiSecret,
+		tokenTracker:  auth.NewTokenUseTracker(),
 	}
 }
 
+// SetAuditLogger records token joins, including delegated identities, in the audit log
+func (arm *AuthenticatedRoomManager) SetAuditLogger(auditLogger *security.AuditLogger) {
+	arm.auditLogger = auditLogger
+}
+
+// SetTokenUseTracker replaces the tracker used to reject replayed single-use tokens
+func (arm *AuthenticatedRoomManager) SetTokenUseTracker(tracker *auth.TokenUseTracker) {
+	arm.tokenTracker = tracker
+}
+
 // JoinRoomWithToken allows a user to join a room using an access token
 func (arm *AuthenticatedRoomManager) JoinRoomWithToken(ctx context.Context, req *JoinRoomRequest) (*Participant, *Room, error) {
 	// Authenticate the request
-	participant, err := arm.authenticator.AuthenticateJoinRequest(ctx, req, arm.apiSecret)
+	participant, claims, err := arm.authenticator.authenticate(req, arm.apiSecret)
 	if err != nil {
 		return nil, nil, fmt.Errorf("authentication failed: %w", err)
 	}
 
+	// Reject replayed single-use tokens. The token is released again if the
+	// join fails, so it can be retried.
+	if err := arm.tokenTracker.Consume(claims); err != nil {
+		arm.logger.Warn("Rejected reused access token",
+			logger.Field{Key: "participant_id", Value: participant.ID},
+			logger.Field{Key: "token_id", Value: claims.TokenID},
+		)
+		return nil, nil, fmt.Errorf("authentication failed: %w", err)
+	}
+
 	// Get or create the room
 	room, err := arm.GetRoomByName(req.RoomName)
 	if err != nil {
@@ -170,6 +224,7 @@ func (arm *AuthenticatedRoomManager) JoinRoomWithToken(ctx context.Context, req
 		}
 		room, err = arm.CreateRoom(createReq, participant.ID)
 		if err != nil {
+			arm.tokenTracker.Release(claims)
 			return nil, nil, fmt.Errorf("failed to create room: %w", err)
 		}
 		arm.logger.Info("Room created automatically",
@@ -180,6 +235,7 @@ func (arm *AuthenticatedRoomManager) JoinRoomWithToken(ctx context.Context, req
 
 	// Add participant to the room
 	if err := room.AddParticipant(participant); err != nil {
+		arm.tokenTracker.Release(claims)
 		return nil, nil, fmt.Errorf("failed to join room: %w", err)
 	}
 
@@ -187,8 +243,28 @@ func (arm *AuthenticatedRoomManager) JoinRoomWithToken(ctx context.Context, req
 		logger.Field{Key: "room_name", Value: req.RoomName},
 		logger.Field{Key: "participant_id", Value: participant.ID},
 		logger.Field{Key: "username", Value: participant.Username},
+		logger.Field{Key: "actor_id", Value: participant.ActorID},
 	)
 
+	if arm.auditLogger != nil {
+		metadata := map[string]interface{}{"room_name": req.RoomName}
+		if participant.ActorID != "" {
+			metadata["actor_id"] = participant.ActorID
+		}
+
+		arm.auditLogger.Log(&security.AuditEvent{
+			Type:       security.AuditEventAccess,
+			Severity:   security.AuditSeverityInfo,
+			UserID:     participant.ID,
+			IP:         req.ClientIP,
+			Action:     "room.join",
+			Resource:   "room",
+			ResourceID: room.ID,
+			Status:     "success",
+			Metadata:   metadata,
+		})
+	}
+
 	return participant, room, nil
 }
 
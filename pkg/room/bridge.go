@@ -0,0 +1,231 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrBridgeSubscribed is returned when subscribing an event bridge twice
+var ErrBridgeSubscribed = errors.New("event bridge already subscribed")
+
+// EventBridge propagates room events between nodes of a cluster
+type EventBridge interface {
+	// Publish sends a local event to other nodes. It must not block.
+	Publish(event *RoomEvent)
+	// Subscribe delivers events published by other nodes to handler
+	Subscribe(handler EventCallback) error
+	// Close stops the bridge
+	Close() error
+}
+
+// SetBridge forwards events published on the bus to bridge. Remote events
+// are never forwarded again.
+func (eb *EventBus) SetBridge(bridge EventBridge) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	eb.bridge = bridge
+}
+
+// SetEventBridge connects the room manager to other nodes. Local events are
+// published through bridge and remote events are delivered to local
+// subscribers with Remote set.
+func (rm *RoomManager) SetEventBridge(bridge EventBridge) error {
+	if err := bridge.Subscribe(func(event *RoomEvent) {
+		event.Remote = true
+		rm.eventBus.Publish(event)
+	}); err != nil {
+		return err
+	}
+
+	rm.eventBus.SetBridge(bridge)
+
+	rm.mu.Lock()
+	rm.bridge = bridge
+	rm.mu.Unlock()
+
+	return nil
+}
+
+// DefaultEventChannel is the Redis channel used for room events
+const DefaultEventChannel = "zenlive:room-events"
+
+// redisBridgeQueueSize is the number of events buffered for publishing
+const redisBridgeQueueSize = 1024
+
+// redisEnvelope wraps a room event with its origin node
+type redisEnvelope struct {
+	NodeID string     `json:"node_id"`
+	Event  *RoomEvent `json:"event"`
+}
+
+// redisMessage is an encoded event waiting to be published
+type redisMessage struct {
+	eventType RoomEventType
+	payload   []byte
+}
+
+// RedisEventBridge implements EventBridge using Redis pub/sub.
+// Event data arrives on other nodes decoded as generic JSON values.
+type RedisEventBridge struct {
+	client  *redis.Client
+	channel string
+	nodeID  string
+	logger  logger.Logger
+
+	queue  chan redisMessage
+	pubsub *redis.PubSub
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+	mu        sync.Mutex
+}
+
+// NewRedisEventBridge creates a Redis event bridge. nodeID must be unique per
+// node so a node ignores its own events.
+func NewRedisEventBridge(client *redis.Client, channel, nodeID string, log logger.Logger) *RedisEventBridge {
+	if channel == "" {
+		channel = DefaultEventChannel
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b := &RedisEventBridge{
+		client:  client,
+		channel: channel,
+		nodeID:  nodeID,
+		logger:  log,
+		queue:   make(chan redisMessage, redisBridgeQueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	// Publish events in order from a single goroutine
+	go b.publishLoop()
+
+	return b
+}
+
+// Publish encodes a local event and queues it for publishing. Events are
+// encoded here, while the publisher still holds the room lock, because the
+// room may change its participants as soon as the lock is released.
+func (b *RedisEventBridge) Publish(event *RoomEvent) {
+	encoded := *event
+	encoded.Data = snapshotEventData(event.Data)
+
+	payload, err := json.Marshal(redisEnvelope{NodeID: b.nodeID, Event: &encoded})
+	if err != nil {
+		b.logger.Error("Failed to encode room event",
+			logger.Field{Key: "event_type", Value: string(event.Type)},
+			logger.Field{Key: "error", Value: err.Error()},
+		)
+		return
+	}
+
+	select {
+	case <-b.ctx.Done():
+	case b.queue <- redisMessage{eventType: event.Type, payload: payload}:
+	default:
+		b.logger.Warn("Event bridge queue full, dropping event",
+			logger.Field{Key: "room_id", Value: event.RoomID},
+			logger.Field{Key: "event_type", Value: string(event.Type)},
+		)
+	}
+}
+
+// snapshotEventData copies participants in event data under their own
+// locks so they can be encoded safely
+func snapshotEventData(data interface{}) interface{} {
+	switch v := data.(type) {
+	case *Participant:
+		return v.Snapshot()
+	case []*Participant:
+		snapshots := make([]*Participant, len(v))
+		for i, p := range v {
+			snapshots[i] = p.Snapshot()
+		}
+		return snapshots
+	}
+
+	return data
+}
+
+// publishLoop sends queued events to Redis
+func (b *RedisEventBridge) publishLoop() {
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case msg := <-b.queue:
+			if err := b.client.Publish(b.ctx, b.channel, msg.payload).Err(); err != nil && b.ctx.Err() == nil {
+				b.logger.Error("Failed to publish room event",
+					logger.Field{Key: "event_type", Value: string(msg.eventType)},
+					logger.Field{Key: "error", Value: err.Error()},
+				)
+			}
+		}
+	}
+}
+
+// Subscribe starts delivering events from other nodes to handler
+func (b *RedisEventBridge) Subscribe(handler EventCallback) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.pubsub != nil {
+		return ErrBridgeSubscribed
+	}
+
+	pubsub := b.client.Subscribe(b.ctx, b.channel)
+	if _, err := pubsub.Receive(b.ctx); err != nil {
+		pubsub.Close()
+		return err
+	}
+
+	b.pubsub = pubsub
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			var envelope redisEnvelope
+			if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil || envelope.Event == nil {
+				b.logger.Warn("Ignoring malformed room event",
+					logger.Field{Key: "channel", Value: msg.Channel},
+				)
+				continue
+			}
+
+			// Skip our own events
+			if envelope.NodeID == b.nodeID {
+				continue
+			}
+
+			handler(envelope.Event)
+		}
+	}()
+
+	return nil
+}
+
+// Close stops publishing and unsubscribes from Redis
+func (b *RedisEventBridge) Close() error {
+	var err error
+
+	b.closeOnce.Do(func() {
+		b.cancel()
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if b.pubsub != nil {
+			err = b.pubsub.Close()
+		}
+	})
+
+	return err
+}
@@ -0,0 +1,177 @@
+package room
+
+import "github.com/aminofox/zenlive/pkg/logger"
+
+// AddParticipants adds several participants under a single lock and publishes
+// one batched event. Each participant goes through the same checks as
+// AddParticipant and either all are accepted or none are. Participants the
+// room's lobby holds for approval wait there instead of joining.
+func (r *Room) AddParticipants(participants []*Participant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Validate the whole batch before changing anything
+	seen := make(map[string]bool, len(participants))
+	for i, p := range participants {
+		if err := r.checkJoin(p, i); err != nil {
+			return err
+		}
+		if seen[p.ID] {
+			return ErrParticipantExists
+		}
+		seen[p.ID] = true
+
+		if r.isLocked && !bypassesLobby(p) {
+			return ErrRoomLocked
+		}
+	}
+
+	if len(participants) == 0 {
+		return nil
+	}
+
+	// Take the tenant quota for the whole batch, giving it back if it doesn't fit
+	for i := range participants {
+		if err := r.acquireParticipantQuota(); err != nil {
+			r.releaseParticipantQuota(i)
+			return err
+		}
+	}
+
+	joined := make([]*Participant, 0, len(participants))
+	for _, p := range participants {
+		if r.LobbyEnabled && !bypassesLobby(p) {
+			r.addToLobby(p)
+			continue
+		}
+		r.seat(p)
+		joined = append(joined, p)
+	}
+
+	if len(joined) == 0 {
+		return nil
+	}
+
+	r.logger.Info("Participants joined room",
+		logger.Field{Key: "room_id", Value: r.ID},
+		logger.Field{Key: "count", Value: len(joined)},
+	)
+
+	// Publish event
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventParticipantsJoined, r.ID, joined))
+	}
+
+	return nil
+}
+
+// RemoveParticipants removes several participants under a single lock and
+// publishes one batched event. Unknown IDs are skipped; the removed
+// participants are returned.
+func (r *Room) RemoveParticipants(participantIDs []string) []*Participant {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := make([]*Participant, 0, len(participantIDs))
+	for _, id := range participantIDs {
+		if participant, exists := r.unseat(id); exists {
+			removed = append(removed, participant)
+		}
+	}
+
+	if len(removed) == 0 {
+		return removed
+	}
+
+	r.logger.Info("Participants left room",
+		logger.Field{Key: "room_id", Value: r.ID},
+		logger.Field{Key: "count", Value: len(removed)},
+	)
+
+	// Publish event
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventParticipantsLeft, r.ID, removed))
+	}
+
+	r.markIfEmpty()
+
+	return removed
+}
+
+// MuteAll revokes publish permission from every participant except those in
+// exceptIDs. Each participant is updated as by UpdateParticipantPermissions,
+// then one batched event is published. It returns the muted participants.
+func (r *Room) MuteAll(exceptIDs []string) []*Participant {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	except := make(map[string]bool, len(exceptIDs))
+	for _, id := range exceptIDs {
+		except[id] = true
+	}
+
+	muted := make([]*Participant, 0, len(r.participants))
+	for id, participant := range r.participants {
+		if except[id] {
+			continue
+		}
+
+		perms := participant.GetPermissions()
+		if !perms.CanPublish {
+			continue
+		}
+
+		perms.CanPublish = false
+		r.updatePermissions(participant, perms)
+		muted = append(muted, participant)
+	}
+
+	if len(muted) == 0 {
+		return muted
+	}
+
+	r.logger.Info("Participants muted",
+		logger.Field{Key: "room_id", Value: r.ID},
+		logger.Field{Key: "count", Value: len(muted)},
+	)
+
+	// Publish event
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventParticipantsUpdated, r.ID, muted))
+	}
+
+	return muted
+}
+
+// UpdatePermissionsBulk sets the same permissions on several participants under
+// a single lock. Each participant is updated as by UpdateParticipantPermissions,
+// then one batched event is published. If any participant is missing nothing
+// is changed and ErrParticipantNotFound is returned.
+func (r *Room) UpdatePermissionsBulk(participantIDs []string, perms ParticipantPermissions) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	updated := make([]*Participant, 0, len(participantIDs))
+	for _, id := range participantIDs {
+		participant, exists := r.participants[id]
+		if !exists {
+			return ErrParticipantNotFound
+		}
+		updated = append(updated, participant)
+	}
+
+	if len(updated) == 0 {
+		return nil
+	}
+
+	for _, participant := range updated {
+		r.updatePermissions(participant, perms)
+	}
+
+	// Publish event
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventParticipantsUpdated, r.ID, updated))
+	}
+
+	return nil
+}
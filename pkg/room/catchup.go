@@ -0,0 +1,140 @@
+package room
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/streaming/hls"
+)
+
+const (
+	// DefaultCatchupLiveThreshold is how close to live a catch-up viewer must
+	// be before switching from HLS to WebRTC
+	DefaultCatchupLiveThreshold = 10 * time.Second
+
+	// DefaultCatchupRate is the suggested playback rate while catching up
+	DefaultCatchupRate = 1.25
+)
+
+var (
+	// ErrCatchupUnavailable is returned when a stream has no recorded segments to catch up on
+	ErrCatchupUnavailable = errors.New("catch-up is not available for stream")
+)
+
+// catchupSource is the HLS DVR recording of a WebRTC stream
+type catchupSource struct {
+	dvr            *hls.DVRWindow
+	targetDuration int
+}
+
+// CatchupPlaylist lets a late WebRTC viewer play a stream from an earlier
+// point over HLS, then switch to the live WebRTC stream once caught up
+type CatchupPlaylist struct {
+	// StreamID is the stream being caught up on
+	StreamID string `json:"stream_id"`
+	// Playlist is the HLS event playlist of the recorded segments
+	Playlist *hls.MediaPlaylist `json:"-"`
+	// StartOffset is where playback starts, from the first available segment
+	StartOffset time.Duration `json:"start_offset"`
+	// Duration is the recorded duration up to the live edge
+	Duration time.Duration `json:"duration"`
+	// LiveEdge is the wall-clock time of the newest recorded segment
+	LiveEdge time.Time `json:"live_edge"`
+	// LiveThreshold is the lag at which the viewer should switch to WebRTC
+	LiveThreshold time.Duration `json:"live_threshold"`
+	// CatchupRate is the suggested playback rate until the switch
+	CatchupRate float64 `json:"catchup_rate"`
+}
+
+// Render returns the M3U8 content of the catch-up playlist
+func (c *CatchupPlaylist) Render() string {
+	return c.Playlist.Render()
+}
+
+// LagAt returns how far behind live a viewer at position is, measured from
+// the start of the playlist
+func (c *CatchupPlaylist) LagAt(position time.Duration) time.Duration {
+	lag := c.Duration - position
+	if lag < 0 {
+		return 0
+	}
+
+	return lag
+}
+
+// ShouldSwitchToLive reports whether a viewer at position is close enough to
+// the live edge to leave HLS and subscribe to the WebRTC stream
+func (c *CatchupPlaylist) ShouldSwitchToLive(position time.Duration) bool {
+	return c.LagAt(position) <= c.LiveThreshold
+}
+
+// EnableCatchup registers the HLS DVR recording of a WebRTC stream so late
+// viewers can start from an earlier point
+func (rm *RoomManager) EnableCatchup(streamID string, dvr *hls.DVRWindow, targetDuration int) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if targetDuration <= 0 {
+		targetDuration = hls.DefaultSegmentDuration
+	}
+
+	rm.catchups[streamID] = &catchupSource{
+		dvr:            dvr,
+		targetDuration: targetDuration,
+	}
+
+	rm.logger.Info("Catch-up enabled",
+		logger.Field{Key: "stream_id", Value: streamID},
+	)
+}
+
+// DisableCatchup removes a stream's catch-up recording
+func (rm *RoomManager) DisableCatchup(streamID string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	delete(rm.catchups, streamID)
+}
+
+// GetCatchupPlaylist returns a playlist that starts from the beginning of the
+// stream's recorded window
+func (rm *RoomManager) GetCatchupPlaylist(streamID string) (*CatchupPlaylist, error) {
+	return rm.GetCatchupPlaylistAt(streamID, 0)
+}
+
+// GetCatchupPlaylistAt returns a playlist that starts at offset into the
+// stream's recorded window. Offsets past the live edge start at the newest
+// segment.
+func (rm *RoomManager) GetCatchupPlaylistAt(streamID string, offset time.Duration) (*CatchupPlaylist, error) {
+	rm.mu.RLock()
+	source, exists := rm.catchups[streamID]
+	rm.mu.RUnlock()
+
+	if !exists || source.dvr.GetSegmentCount() == 0 {
+		return nil, ErrCatchupUnavailable
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	playlist := source.dvr.CreatePlaylistFromWindow(source.targetDuration)
+	duration := time.Duration(playlist.GetTotalDuration() * float64(time.Second))
+	if offset > duration {
+		offset = duration
+	}
+
+	playlist.StartOffset = offset.Seconds()
+	playlist.HasStartOffset = true
+
+	return &CatchupPlaylist{
+		StreamID:      streamID,
+		Playlist:      playlist,
+		StartOffset:   offset,
+		Duration:      duration,
+		LiveEdge:      source.dvr.GetNewestSegmentTime(),
+		LiveThreshold: DefaultCatchupLiveThreshold,
+		CatchupRate:   DefaultCatchupRate,
+	}, nil
+}
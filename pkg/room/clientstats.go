@@ -0,0 +1,139 @@
+package room
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// ErrInvalidClientStats is returned for client stats reports with missing or negative values
+var ErrInvalidClientStats = errors.New("invalid client stats")
+
+// ClientStats is a subscriber's report of the playback quality it perceives
+// for one stream, as measured by the client's player
+type ClientStats struct {
+	// ParticipantID is the reporting participant, set by the room
+	ParticipantID string `json:"participant_id"`
+	// StreamID is the stream being played
+	StreamID string `json:"stream_id"`
+	// DecodedFPS is the rate of frames decoded
+	DecodedFPS float64 `json:"decoded_fps"`
+	// FreezeCount is the number of video freezes since the previous report
+	FreezeCount int `json:"freeze_count"`
+	// BufferMs is the playout buffer in milliseconds
+	BufferMs int `json:"buffer_ms"`
+	// EstimatedBitrate is the client's estimate of its available bandwidth in bps
+	EstimatedBitrate int `json:"estimated_bitrate"`
+	// Timestamp is when the report was received
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// StreamQoE aggregates the latest client stats of every viewer of a stream
+type StreamQoE struct {
+	// StreamID is the stream
+	StreamID string `json:"stream_id"`
+	// Viewers is the number of participants reporting on the stream
+	Viewers int `json:"viewers"`
+	// AvgDecodedFPS is the mean decoded frame rate
+	AvgDecodedFPS float64 `json:"avg_decoded_fps"`
+	// TotalFreezes is the sum of freezes in the latest reports
+	TotalFreezes int `json:"total_freezes"`
+	// AvgBufferMs is the mean playout buffer in milliseconds
+	AvgBufferMs int `json:"avg_buffer_ms"`
+	// AvgEstimatedBitrate is the mean estimated bandwidth in bps
+	AvgEstimatedBitrate int `json:"avg_estimated_bitrate"`
+}
+
+// ReportClientStats records a participant's client-side playback stats and
+// publishes them as a client.stats event, for analytics and adaptation
+func (r *Room) ReportClientStats(participantID string, stats ClientStats) error {
+	if stats.StreamID == "" || stats.DecodedFPS < 0 || stats.FreezeCount < 0 || stats.BufferMs < 0 || stats.EstimatedBitrate < 0 {
+		return ErrInvalidClientStats
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.participants[participantID]; !exists {
+		if _, waiting := r.lobby[participantID]; waiting {
+			return ErrParticipantWaiting
+		}
+		return ErrParticipantNotFound
+	}
+
+	stats.ParticipantID = participantID
+	stats.Timestamp = time.Now()
+
+	if r.clientStats[stats.StreamID] == nil {
+		r.clientStats[stats.StreamID] = make(map[string]ClientStats)
+	}
+	r.clientStats[stats.StreamID][participantID] = stats
+
+	r.logger.Debug("Client stats reported",
+		logger.Field{Key: "room_id", Value: r.ID},
+		logger.Field{Key: "participant_id", Value: participantID},
+		logger.Field{Key: "stream_id", Value: stats.StreamID},
+		logger.Field{Key: "decoded_fps", Value: stats.DecodedFPS},
+		logger.Field{Key: "freeze_count", Value: stats.FreezeCount},
+	)
+
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventClientStatsReported, r.ID, stats))
+	}
+
+	return nil
+}
+
+// GetStreamQoE aggregates the latest client stats reported for a stream
+func (r *Room) GetStreamQoE(streamID string) StreamQoE {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	qoe := StreamQoE{StreamID: streamID}
+
+	reports := r.clientStats[streamID]
+	if len(reports) == 0 {
+		return qoe
+	}
+
+	var buffer, bitrate int
+	for _, stats := range reports {
+		qoe.AvgDecodedFPS += stats.DecodedFPS
+		qoe.TotalFreezes += stats.FreezeCount
+		buffer += stats.BufferMs
+		bitrate += stats.EstimatedBitrate
+	}
+
+	qoe.Viewers = len(reports)
+	qoe.AvgDecodedFPS /= float64(qoe.Viewers)
+	qoe.AvgBufferMs = buffer / qoe.Viewers
+	qoe.AvgEstimatedBitrate = bitrate / qoe.Viewers
+
+	return qoe
+}
+
+// dropClientStats forgets a participant's reports. Caller must hold r.mu.
+func (r *Room) dropClientStats(participantID string) {
+	for streamID, reports := range r.clientStats {
+		delete(reports, participantID)
+		if len(reports) == 0 {
+			delete(r.clientStats, streamID)
+		}
+	}
+}
+
+// ReportClientStats records client-side playback stats for a participant of a room
+func (rm *RoomManager) ReportClientStats(roomID, participantID string, stats ClientStats) error {
+	room, err := rm.GetRoom(roomID)
+	if err != nil {
+		return err
+	}
+
+	return room.ReportClientStats(participantID, stats)
+}
+
+// OnClientStats registers a callback for client stats reports
+func (rm *RoomManager) OnClientStats(callback EventCallback) {
+	rm.eventBus.Subscribe(EventClientStatsReported, callback)
+}
@@ -0,0 +1,309 @@
+package room
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrDeliveryReceiptsDisabled is returned when tracking messages in a room without delivery receipts
+	ErrDeliveryReceiptsDisabled = errors.New("delivery receipts are not enabled for room")
+	// ErrMessageNotFound is returned for a message that is not tracked, or not addressed to the participant
+	ErrMessageNotFound = errors.New("message not found")
+	// ErrInvalidDeliveryStatus is returned when acknowledging with a status other than delivered or read
+	ErrInvalidDeliveryStatus = errors.New("invalid delivery status")
+	// ErrMessageExists is returned when tracking a message ID that is already tracked
+	ErrMessageExists = errors.New("message already tracked")
+)
+
+const (
+	// DefaultMaxTrackedMessages caps the messages whose delivery state a room keeps
+	DefaultMaxTrackedMessages = 1000
+	// DefaultTypingTimeout is how long a typing indicator lasts without a refresh
+	DefaultTypingTimeout = 5 * time.Second
+)
+
+// DeliveryStatus is how far a data message has got with a recipient
+type DeliveryStatus string
+
+const (
+	// DeliverySent means the message was sent but not yet acknowledged
+	DeliverySent DeliveryStatus = "sent"
+	// DeliveryDelivered means the recipient's connection received the message
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryRead means the recipient read the message
+	DeliveryRead DeliveryStatus = "read"
+)
+
+// rank orders statuses so an acknowledgement never moves a message backwards
+func (s DeliveryStatus) rank() int {
+	switch s {
+	case DeliveryDelivered:
+		return 1
+	case DeliveryRead:
+		return 2
+	}
+	return 0
+}
+
+// RecipientDelivery is a data message's delivery state for one recipient
+type RecipientDelivery struct {
+	// ParticipantID is the recipient
+	ParticipantID string `json:"participant_id"`
+	// Status is the latest acknowledged status
+	Status DeliveryStatus `json:"status"`
+	// DeliveredAt is when delivery was acknowledged
+	DeliveredAt time.Time `json:"delivered_at,omitzero"`
+	// ReadAt is when reading was acknowledged
+	ReadAt time.Time `json:"read_at,omitzero"`
+}
+
+// DeliveryState is the delivery state of a data message
+type DeliveryState struct {
+	// MessageID is the message
+	MessageID string `json:"message_id"`
+	// SenderID is the sending participant
+	SenderID string `json:"sender_id"`
+	// SentAt is when the message was tracked
+	SentAt time.Time `json:"sent_at"`
+	// Recipients are the recipients sorted by participant ID
+	Recipients []RecipientDelivery `json:"recipients"`
+}
+
+// DeliveryReceipt acknowledges a data message for its sender. It is the
+// data of message.delivery events.
+type DeliveryReceipt struct {
+	// MessageID is the acknowledged message
+	MessageID string `json:"message_id"`
+	// SenderID is the participant the receipt is for
+	SenderID string `json:"sender_id"`
+	// ParticipantID is the acknowledging recipient
+	ParticipantID string `json:"participant_id"`
+	// Status is the acknowledged status
+	Status DeliveryStatus `json:"status"`
+	// Timestamp is when the acknowledgement was received
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// TypingIndicator reports a participant starting or stopping typing. It is
+// the data of typing.updated events.
+type TypingIndicator struct {
+	// ParticipantID is the typing participant
+	ParticipantID string `json:"participant_id"`
+	// Typing is false when the participant stopped typing
+	Typing bool `json:"typing"`
+	// Timestamp is when the indicator was received
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// messageDelivery is the tracked state of one data message
+type messageDelivery struct {
+	senderID   string
+	sentAt     time.Time
+	recipients map[string]*RecipientDelivery
+}
+
+// TrackMessage starts tracking delivery of a data message to recipientIDs
+// (empty = every other participant). Message IDs must be unique: tracking an
+// ID again returns ErrMessageExists rather than replacing another sender's
+// delivery state. Only the most recent DefaultMaxTrackedMessages messages
+// are kept.
+func (r *Room) TrackMessage(messageID, senderID string, recipientIDs []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.DeliveryReceipts {
+		return ErrDeliveryReceiptsDisabled
+	}
+
+	if err := r.requireParticipantLocked(senderID); err != nil {
+		return err
+	}
+
+	if _, exists := r.deliveries[messageID]; exists {
+		return ErrMessageExists
+	}
+
+	if len(recipientIDs) == 0 {
+		for id := range r.participants {
+			if id != senderID {
+				recipientIDs = append(recipientIDs, id)
+			}
+		}
+	}
+
+	delivery := &messageDelivery{
+		senderID:   senderID,
+		sentAt:     time.Now(),
+		recipients: make(map[string]*RecipientDelivery, len(recipientIDs)),
+	}
+	for _, id := range recipientIDs {
+		delivery.recipients[id] = &RecipientDelivery{ParticipantID: id, Status: DeliverySent}
+	}
+
+	if r.deliveries == nil {
+		r.deliveries = make(map[string]*messageDelivery)
+	}
+	r.deliveries[messageID] = delivery
+	r.deliveryOrder = append(r.deliveryOrder, messageID)
+
+	for len(r.deliveryOrder) > DefaultMaxTrackedMessages {
+		delete(r.deliveries, r.deliveryOrder[0])
+		r.deliveryOrder = r.deliveryOrder[1:]
+	}
+
+	return nil
+}
+
+// AckDelivery records that a recipient received or read a message and
+// publishes a message.delivery event for the sender. It returns nil when
+// the message had already reached the status.
+func (r *Room) AckDelivery(messageID, participantID string, status DeliveryStatus) (*DeliveryReceipt, error) {
+	if status != DeliveryDelivered && status != DeliveryRead {
+		return nil, ErrInvalidDeliveryStatus
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.DeliveryReceipts {
+		return nil, ErrDeliveryReceiptsDisabled
+	}
+
+	delivery, exists := r.deliveries[messageID]
+	if !exists {
+		return nil, ErrMessageNotFound
+	}
+	recipient, exists := delivery.recipients[participantID]
+	if !exists {
+		return nil, ErrMessageNotFound
+	}
+
+	if status.rank() <= recipient.Status.rank() {
+		return nil, nil
+	}
+
+	// Reading implies delivery
+	now := time.Now()
+	if recipient.DeliveredAt.IsZero() {
+		recipient.DeliveredAt = now
+	}
+	if status == DeliveryRead {
+		recipient.ReadAt = now
+	}
+	recipient.Status = status
+
+	receipt := &DeliveryReceipt{
+		MessageID:     messageID,
+		SenderID:      delivery.senderID,
+		ParticipantID: participantID,
+		Status:        status,
+		Timestamp:     now,
+	}
+
+	if r.eventBus != nil {
+		published := *receipt
+		r.eventBus.Publish(createEvent(EventMessageDelivery, r.ID, &published))
+	}
+
+	return receipt, nil
+}
+
+// GetDeliveryState returns the delivery state of a tracked message
+func (r *Room) GetDeliveryState(messageID string) (*DeliveryState, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	delivery, exists := r.deliveries[messageID]
+	if !exists {
+		return nil, ErrMessageNotFound
+	}
+
+	state := &DeliveryState{
+		MessageID:  messageID,
+		SenderID:   delivery.senderID,
+		SentAt:     delivery.sentAt,
+		Recipients: make([]RecipientDelivery, 0, len(delivery.recipients)),
+	}
+	for _, recipient := range delivery.recipients {
+		state.Recipients = append(state.Recipients, *recipient)
+	}
+	sort.Slice(state.Recipients, func(i, j int) bool {
+		return state.Recipients[i].ParticipantID < state.Recipients[j].ParticipantID
+	})
+
+	return state, nil
+}
+
+// SetTyping records that a participant started or stopped typing and
+// publishes a typing.updated event when that changes. Typing lapses after
+// DefaultTypingTimeout without a refresh. It returns nil when nothing
+// changed.
+func (r *Room) SetTyping(participantID string, typing bool) (*TypingIndicator, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.DeliveryReceipts {
+		return nil, ErrDeliveryReceiptsDisabled
+	}
+
+	if err := r.requireParticipantLocked(participantID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	last, exists := r.typing[participantID]
+	wasTyping := exists && now.Sub(last) < DefaultTypingTimeout
+
+	if typing {
+		if r.typing == nil {
+			r.typing = make(map[string]time.Time)
+		}
+		r.typing[participantID] = now
+	} else {
+		delete(r.typing, participantID)
+	}
+
+	if typing == wasTyping {
+		return nil, nil
+	}
+
+	indicator := &TypingIndicator{ParticipantID: participantID, Typing: typing, Timestamp: now}
+
+	if r.eventBus != nil {
+		published := *indicator
+		r.eventBus.Publish(createEvent(EventTypingUpdated, r.ID, &published))
+	}
+
+	return indicator, nil
+}
+
+// Typing returns the participants currently typing, sorted
+func (r *Room) Typing() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	typing := make([]string, 0, len(r.typing))
+	for id, last := range r.typing {
+		if _, exists := r.participants[id]; exists && now.Sub(last) < DefaultTypingTimeout {
+			typing = append(typing, id)
+		}
+	}
+	sort.Strings(typing)
+
+	return typing
+}
+
+// requireParticipantLocked returns an error unless participantID is in the
+// room. The caller must hold r.mu.
+func (r *Room) requireParticipantLocked(participantID string) error {
+	if _, exists := r.participants[participantID]; !exists {
+		if _, waiting := r.lobby[participantID]; waiting {
+			return ErrParticipantWaiting
+		}
+		return ErrParticipantNotFound
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package room
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// ErrE2EENotEnabled is returned when exchanging keys in a room without E2EE
+var ErrE2EENotEnabled = errors.New("end-to-end encryption is not enabled for room")
+
+// E2EERatchetReason is the membership change behind a key ratchet
+type E2EERatchetReason string
+
+const (
+	// E2EERatchetJoin ratchets keys forward so a joiner cannot decrypt earlier media
+	E2EERatchetJoin E2EERatchetReason = "join"
+	// E2EERatchetLeave replaces keys so a leaver cannot decrypt later media
+	E2EERatchetLeave E2EERatchetReason = "leave"
+)
+
+// E2EEKey is a participant's media encryption key, as shared with the other
+// participants. The key material is opaque to the server: clients wrap it
+// for each recipient before sharing it, so the SFU never holds a usable key.
+type E2EEKey struct {
+	// ParticipantID is the participant encrypting with this key
+	ParticipantID string `json:"participant_id"`
+	// KeyIndex increments with each key the participant sets
+	KeyIndex int `json:"key_index"`
+	// Epoch is the membership epoch the key was set in
+	Epoch int `json:"epoch"`
+	// Key is the wrapped key material
+	Key []byte `json:"key"`
+	// UpdatedAt is when the key was set
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// E2EERatchet tells participants to rotate their keys after a membership change
+type E2EERatchet struct {
+	// Epoch is the new membership epoch
+	Epoch int `json:"epoch"`
+	// Reason is the membership change
+	Reason E2EERatchetReason `json:"reason"`
+	// ParticipantID is the participant who joined or left
+	ParticipantID string `json:"participant_id"`
+}
+
+// E2EEStatus is a participant's end-to-end encryption status
+type E2EEStatus struct {
+	// Enabled is true when the room uses E2EE
+	Enabled bool `json:"enabled"`
+	// Encrypted is true when the participant has a key for the current epoch
+	Encrypted bool `json:"encrypted"`
+	// KeyIndex is the participant's current key index (-1 = no key)
+	KeyIndex int `json:"key_index"`
+	// Epoch is the room's current membership epoch
+	Epoch int `json:"epoch"`
+}
+
+// SetE2EEKey shares a participant's new media key with the room and
+// publishes it as an e2ee.keyUpdated event for delivery over signaling
+func (r *Room) SetE2EEKey(participantID string, key []byte) (*E2EEKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.E2EE {
+		return nil, ErrE2EENotEnabled
+	}
+
+	if _, exists := r.participants[participantID]; !exists {
+		if _, waiting := r.lobby[participantID]; waiting {
+			return nil, ErrParticipantWaiting
+		}
+		return nil, ErrParticipantNotFound
+	}
+
+	index := 0
+	if previous, exists := r.e2eeKeys[participantID]; exists {
+		index = previous.KeyIndex + 1
+	}
+
+	entry := &E2EEKey{
+		ParticipantID: participantID,
+		KeyIndex:      index,
+		Epoch:         r.e2eeEpoch,
+		Key:           append([]byte(nil), key...),
+		UpdatedAt:     time.Now(),
+	}
+	r.e2eeKeys[participantID] = entry
+
+	if r.eventBus != nil {
+		shared := *entry
+		r.eventBus.Publish(createEvent(EventE2EEKeyUpdated, r.ID, &shared))
+	}
+
+	result := *entry
+	return &result, nil
+}
+
+// GetE2EEKeys returns the current key of every participant, for a joiner to
+// decrypt the media already flowing
+func (r *Room) GetE2EEKeys() []*E2EEKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]*E2EEKey, 0, len(r.e2eeKeys))
+	for _, key := range r.e2eeKeys {
+		k := *key
+		keys = append(keys, &k)
+	}
+
+	return keys
+}
+
+// GetE2EEStatus returns a participant's end-to-end encryption status
+func (r *Room) GetE2EEStatus(participantID string) (E2EEStatus, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, exists := r.participants[participantID]; !exists {
+		return E2EEStatus{}, ErrParticipantNotFound
+	}
+
+	status := E2EEStatus{Enabled: r.E2EE, KeyIndex: -1, Epoch: r.e2eeEpoch}
+	if key, exists := r.e2eeKeys[participantID]; exists {
+		status.KeyIndex = key.KeyIndex
+		status.Encrypted = key.Epoch == r.e2eeEpoch
+	}
+
+	return status, nil
+}
+
+// ratchetE2EE starts a new membership epoch after a participant joins or
+// leaves, dropping the leaver's key. Caller must hold r.mu.
+func (r *Room) ratchetE2EE(reason E2EERatchetReason, participantID string) {
+	if !r.E2EE {
+		return
+	}
+
+	r.e2eeEpoch++
+	if reason == E2EERatchetLeave {
+		delete(r.e2eeKeys, participantID)
+	}
+
+	r.logger.Info("E2EE keys ratcheted",
+		logger.Field{Key: "room_id", Value: r.ID},
+		logger.Field{Key: "epoch", Value: r.e2eeEpoch},
+		logger.Field{Key: "reason", Value: string(reason)},
+	)
+
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventE2EEKeyRatcheted, r.ID, E2EERatchet{
+			Epoch:         r.e2eeEpoch,
+			Reason:        reason,
+			ParticipantID: participantID,
+		}))
+	}
+}
@@ -1,6 +1,7 @@
 package room
 
 import (
+	"context"
 	"sync"
 	"time"
 )
@@ -12,6 +13,13 @@ type EventCallback func(event *RoomEvent)
 type EventBus struct {
 	// subscribers stores callbacks by event type
 	subscribers map[RoomEventType][]EventCallback
+	// bridge forwards local events to other nodes
+	bridge EventBridge
+	// pending counts callbacks still running from Publish; idle is closed
+	// whenever it drops to zero. Both are guarded by pendingMu.
+	pending   int
+	idle      chan struct{}
+	pendingMu sync.Mutex
 	// mu protects concurrent access
 	mu sync.RWMutex
 }
@@ -46,6 +54,21 @@ func (eb *EventBus) SubscribeAll(callback EventCallback) {
 		EventTrackPublished,
 		EventTrackUnpublished,
 		EventMetadataUpdated,
+		EventParticipantsJoined,
+		EventParticipantsLeft,
+		EventParticipantsUpdated,
+		EventRoomScheduled,
+		EventRoomOpened,
+		EventRoomEnded,
+		EventParticipantMetadataUpdated,
+		EventPlaybackSynced,
+		EventPlaybackDrift,
+		EventSharedStateUpdated,
+		EventSubscribeRuleUpdated,
+		EventSubscribeDenied,
+		EventParticipantAdmitted,
+		EventMessageDelivery,
+		EventTypingUpdated,
 	}
 
 	for _, eventType := range eventTypes {
@@ -58,11 +81,59 @@ func (eb *EventBus) Publish(event *RoomEvent) {
 	eb.mu.RLock()
 	callbacks := make([]EventCallback, len(eb.subscribers[event.Type]))
 	copy(callbacks, eb.subscribers[event.Type])
+	bridge := eb.bridge
 	eb.mu.RUnlock()
 
+	// Forward local events to other nodes
+	if bridge != nil && !event.Remote {
+		bridge.Publish(event)
+	}
+
 	// Call callbacks asynchronously to avoid blocking
+	eb.addPending(len(callbacks))
 	for _, callback := range callbacks {
-		go callback(event)
+		go func(callback EventCallback) {
+			defer eb.addPending(-1)
+			callback(event)
+		}(callback)
+	}
+}
+
+// addPending adjusts the count of running callbacks, waking Flush callers
+// when it reaches zero
+func (eb *EventBus) addPending(delta int) {
+	if delta == 0 {
+		return
+	}
+
+	eb.pendingMu.Lock()
+	defer eb.pendingMu.Unlock()
+
+	if eb.pending == 0 {
+		eb.idle = make(chan struct{})
+	}
+	eb.pending += delta
+	if eb.pending == 0 {
+		close(eb.idle)
+	}
+}
+
+// Flush waits until the callbacks of all published events have returned or
+// the context is done
+func (eb *EventBus) Flush(ctx context.Context) error {
+	eb.pendingMu.Lock()
+	if eb.pending == 0 {
+		eb.pendingMu.Unlock()
+		return nil
+	}
+	idle := eb.idle
+	eb.pendingMu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
@@ -71,8 +142,14 @@ func (eb *EventBus) PublishSync(event *RoomEvent) {
 	eb.mu.RLock()
 	callbacks := make([]EventCallback, len(eb.subscribers[event.Type]))
 	copy(callbacks, eb.subscribers[event.Type])
+	bridge := eb.bridge
 	eb.mu.RUnlock()
 
+	// Forward local events to other nodes
+	if bridge != nil && !event.Remote {
+		bridge.Publish(event)
+	}
+
 	for _, callback := range callbacks {
 		callback(event)
 	}
@@ -0,0 +1,228 @@
+package room
+
+import (
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// bypassesLobby reports whether a participant joins a lobby-enabled room
+// directly. Hosts and admins must be in the room to admit anyone.
+func bypassesLobby(p *Participant) bool {
+	return p.Role == RoleHost || p.IsAdmin
+}
+
+// addToLobby holds a participant for host approval. Caller must hold r.mu.
+func (r *Room) addToLobby(p *Participant) {
+	r.lobby[p.ID] = p
+	p.UpdateState(StateWaiting)
+
+	r.logger.Info("Participant waiting in lobby",
+		logger.Field{Key: "room_id", Value: r.ID},
+		logger.Field{Key: "participant_id", Value: p.ID},
+		logger.Field{Key: "username", Value: p.Username},
+	)
+
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventParticipantWaiting, r.ID, p))
+	}
+}
+
+// leaveLobby removes a waiting participant who gave up before being
+// admitted. Caller must hold r.mu.
+func (r *Room) leaveLobby(participantID string) error {
+	participant, waiting := r.lobby[participantID]
+	if !waiting {
+		return ErrParticipantNotFound
+	}
+
+	delete(r.lobby, participantID)
+	participant.UpdateState(StateDisconnected)
+	r.releaseParticipantQuota(1)
+
+	r.logger.Info("Participant left lobby",
+		logger.Field{Key: "room_id", Value: r.ID},
+		logger.Field{Key: "participant_id", Value: participantID},
+	)
+
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventParticipantLeft, r.ID, participant))
+	}
+
+	return nil
+}
+
+// AdmitParticipant moves a participant from the lobby into the room
+func (r *Room) AdmitParticipant(participantID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.isClosed {
+		return ErrRoomClosed
+	}
+
+	participant, waiting := r.lobby[participantID]
+	if !waiting {
+		return ErrParticipantNotFound
+	}
+
+	if r.MaxParticipants > 0 && len(r.participants) >= r.MaxParticipants {
+		return ErrRoomFull
+	}
+
+	delete(r.lobby, participantID)
+	r.admit(participant)
+
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventParticipantAdmitted, r.ID, participant))
+	}
+
+	return nil
+}
+
+// DenyParticipant turns a participant in the lobby away
+func (r *Room) DenyParticipant(participantID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	participant, waiting := r.lobby[participantID]
+	if !waiting {
+		return ErrParticipantNotFound
+	}
+
+	delete(r.lobby, participantID)
+	participant.UpdateState(StateDisconnected)
+	r.releaseParticipantQuota(1)
+
+	r.logger.Info("Participant denied entry",
+		logger.Field{Key: "room_id", Value: r.ID},
+		logger.Field{Key: "participant_id", Value: participantID},
+	)
+
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventParticipantDenied, r.ID, participant))
+	}
+
+	return nil
+}
+
+// ListWaitingParticipants returns the participants waiting in the lobby
+func (r *Room) ListWaitingParticipants() []*Participant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	participants := make([]*Participant, 0, len(r.lobby))
+	for _, p := range r.lobby {
+		participants = append(participants, p)
+	}
+
+	return participants
+}
+
+// Lock stops the room accepting new joins. Hosts and admins still join, and
+// others can only enter by knocking and being admitted.
+func (r *Room) Lock() {
+	r.setLocked(true, EventRoomLocked)
+}
+
+// Unlock lets the room accept new joins again. Participants who knocked
+// stay in the lobby until the host admits or denies them.
+func (r *Room) Unlock() {
+	r.setLocked(false, EventRoomUnlocked)
+}
+
+// setLocked updates the lock and publishes eventType if it changed
+func (r *Room) setLocked(locked bool, eventType RoomEventType) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.isLocked == locked {
+		return
+	}
+	r.isLocked = locked
+
+	r.logger.Info("Room lock changed",
+		logger.Field{Key: "room_id", Value: r.ID},
+		logger.Field{Key: "locked", Value: locked},
+	)
+
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(eventType, r.ID, r.ID))
+	}
+}
+
+// IsLocked returns whether the room is locked
+func (r *Room) IsLocked() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.isLocked
+}
+
+// Knock asks the host of a locked room to let a participant in. The
+// participant waits in the lobby, as if the lobby were enabled, until the
+// host admits or denies them. Knocking on an unlocked room joins it.
+func (r *Room) Knock(p *Participant) error {
+	r.mu.Lock()
+	if !r.isLocked {
+		r.mu.Unlock()
+		return r.AddParticipant(p)
+	}
+	defer r.mu.Unlock()
+
+	if err := r.checkJoin(p, 0); err != nil {
+		return err
+	}
+
+	// Waiting participants count against the quota like joined ones, and
+	// release it when they leave the lobby
+	if err := r.acquireParticipantQuota(); err != nil {
+		return err
+	}
+
+	r.addToLobby(p)
+
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventParticipantKnocked, r.ID, p))
+	}
+
+	return nil
+}
+
+// AdmitParticipant admits a participant waiting in a room's lobby
+func (rm *RoomManager) AdmitParticipant(roomID, participantID string) error {
+	room, err := rm.GetRoom(roomID)
+	if err != nil {
+		return err
+	}
+
+	return room.AdmitParticipant(participantID)
+}
+
+// DenyParticipant turns away a participant waiting in a room's lobby
+func (rm *RoomManager) DenyParticipant(roomID, participantID string) error {
+	room, err := rm.GetRoom(roomID)
+	if err != nil {
+		return err
+	}
+
+	return room.DenyParticipant(participantID)
+}
+
+// OnParticipantWaiting registers a callback for participants entering a lobby
+func (rm *RoomManager) OnParticipantWaiting(callback EventCallback) {
+	rm.eventBus.Subscribe(EventParticipantWaiting, callback)
+}
+
+// OnParticipantAdmitted registers a callback for participants admitted from a lobby
+func (rm *RoomManager) OnParticipantAdmitted(callback EventCallback) {
+	rm.eventBus.Subscribe(EventParticipantAdmitted, callback)
+}
+
+// OnParticipantDenied registers a callback for participants turned away from a lobby
+func (rm *RoomManager) OnParticipantDenied(callback EventCallback) {
+	rm.eventBus.Subscribe(EventParticipantDenied, callback)
+}
+
+// OnParticipantKnocked registers a callback for knocks on locked rooms
+func (rm *RoomManager) OnParticipantKnocked(callback EventCallback) {
+	rm.eventBus.Subscribe(EventParticipantKnocked, callback)
+}
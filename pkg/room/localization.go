@@ -0,0 +1,81 @@
+package room
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aminofox/zenlive/pkg/types"
+)
+
+// GetLocalized returns a localized field of the room in lang, falling back
+// to the room's default language and then to the room name for the title
+func (r *Room) GetLocalized(field, lang string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.localizedLocked(field, lang)
+}
+
+// localizedLocked is GetLocalized. Caller must hold r.mu.
+func (r *Room) localizedLocked(field, lang string) string {
+	if text, ok := r.Localized[field].Get(lang, r.DefaultLanguage); ok {
+		return text
+	}
+	if field == types.FieldTitle {
+		return r.Name
+	}
+	return ""
+}
+
+// UpdateLocalized merges translations into the room's localized fields
+func (r *Room) UpdateLocalized(localized map[string]types.LocalizedText) error {
+	if err := types.ValidateLocalized(localized, ""); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for field, texts := range localized {
+		if r.Localized[field] == nil {
+			r.Localized[field] = make(types.LocalizedText, len(texts))
+		}
+		for lang, text := range texts {
+			r.Localized[field][lang] = text
+		}
+	}
+
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventMetadataUpdated, r.ID, map[string]interface{}{"localized": localized}))
+	}
+
+	return nil
+}
+
+// validateLocalization checks the localized fields of a create request
+func validateLocalization(req *CreateRoomRequest) error {
+	if err := types.ValidateLocalized(req.Localized, req.DefaultLanguage); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRoomRequest, err)
+	}
+	return nil
+}
+
+// SearchRooms returns the rooms whose title or description in lang contains
+// query, case-insensitively. An empty query matches every room.
+func (rm *RoomManager) SearchRooms(query, lang string) []*Room {
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	var matches []*Room
+	for _, room := range rm.ListRooms() {
+		room.mu.RLock()
+		title := room.localizedLocked(types.FieldTitle, lang)
+		description := room.localizedLocked(types.FieldDescription, lang)
+		room.mu.RUnlock()
+
+		if query == "" || strings.Contains(strings.ToLower(title), query) || strings.Contains(strings.ToLower(description), query) {
+			matches = append(matches, room)
+		}
+	}
+
+	return matches
+}
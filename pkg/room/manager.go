@@ -1,10 +1,14 @@
 package room
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/quota"
 )
 
 var (
@@ -12,49 +16,105 @@ var (
 	ErrRoomNotFound = errors.New("room not found")
 	// ErrRoomExists is returned when trying to create a duplicate room
 	ErrRoomExists = errors.New("room already exists")
+	// ErrInvalidRoomRequest is returned when a create or schedule request is incomplete or inconsistent
+	ErrInvalidRoomRequest = errors.New("invalid room request")
+	// ErrRoomManagerClosed is returned when creating rooms after Close
+	ErrRoomManagerClosed = errors.New("room manager closed")
 )
 
 // RoomManager manages all rooms in the system
 type RoomManager struct {
 	// rooms stores all rooms by room ID
 	rooms map[string]*Room
+	// templates stores reusable room settings by template name
+	templates map[string]*CreateRoomRequest
 	// mu protects concurrent access
 	mu sync.RWMutex
 	// eventBus for publishing room events
 	eventBus *EventBus
+	// bridge propagates events to other nodes
+	bridge EventBridge
+	// catchups stores HLS DVR recordings of WebRTC streams by stream ID
+	catchups map[string]*catchupSource
+	// quota limits the rooms and participants of each tenant (nil = unlimited)
+	quota *quota.Manager
 	// logger for room manager events
 	logger logger.Logger
+	// closed is set once Close has started
+	closed bool
+	// done stops the empty-room sweeper
+	done chan struct{}
 }
 
 // NewRoomManager creates a new room manager
 func NewRoomManager(log logger.Logger) *RoomManager {
 	return &RoomManager{
-		rooms:    make(map[string]*Room),
-		eventBus: NewEventBus(),
-		logger:   log,
+		rooms:     make(map[string]*Room),
+		templates: make(map[string]*CreateRoomRequest),
+		catchups:  make(map[string]*catchupSource),
+		eventBus:  NewEventBus(),
+		logger:    log,
+		done:      make(chan struct{}),
 	}
 }
 
-// CreateRoom creates a new room
+// SetQuotaManager enforces tenant quotas on rooms created afterwards and
+// on their participants
+func (rm *RoomManager) SetQuotaManager(quotas *quota.Manager) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.quota = quotas
+}
+
+// CreateRoom creates a new room. Rooms with a tenant count against its
+// quota; an error wrapping quota.ErrQuotaExceeded is returned when the
+// tenant has no rooms left.
 func (rm *RoomManager) CreateRoom(req *CreateRoomRequest, createdBy string) (*Room, error) {
+	return rm.createRoom(req, createdBy, nil)
+}
+
+// createRoom creates and registers a room. prepare, if set, finishes
+// setting up the room before it becomes visible to other callers.
+func (rm *RoomManager) createRoom(req *CreateRoomRequest, createdBy string, prepare func(*Room)) (*Room, error) {
 	if req == nil {
-		return nil, errors.New("create room request is required")
+		return nil, fmt.Errorf("%w: create room request is required", ErrInvalidRoomRequest)
 	}
 
 	if req.Name == "" {
-		return nil, errors.New("room name is required")
+		return nil, fmt.Errorf("%w: room name is required", ErrInvalidRoomRequest)
+	}
+
+	if err := validateLocalization(req); err != nil {
+		return nil, err
 	}
 
 	room := NewRoom(req, createdBy, rm.logger, rm.eventBus)
+	if prepare != nil {
+		prepare(room)
+	}
 
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
+	if rm.closed {
+		room.Close()
+		return nil, ErrRoomManagerClosed
+	}
+
 	// Check if room ID already exists (extremely unlikely with UUID)
 	if _, exists := rm.rooms[room.ID]; exists {
 		return nil, ErrRoomExists
 	}
 
+	if rm.quota != nil {
+		if err := rm.quota.Acquire(context.Background(), room.Tenant, quota.ResourceRooms); err != nil {
+			room.Close()
+			return nil, err
+		}
+		room.quota = rm.quota
+	}
+
 	rm.rooms[room.ID] = room
 
 	rm.logger.Info("Room created",
@@ -83,6 +143,7 @@ func (rm *RoomManager) DeleteRoom(roomID string) error {
 
 	// Close the room
 	room.Close()
+	rm.releaseRoomQuota(room)
 
 	rm.logger.Info("Room deleted",
 		logger.Field{Key: "room_id", Value: roomID},
@@ -177,13 +238,16 @@ func (rm *RoomManager) OnTrackUnpublished(callback EventCallback) {
 	rm.eventBus.Subscribe(EventTrackUnpublished, callback)
 }
 
-// CleanupEmptyRooms removes all empty rooms with expired timeouts
+// CleanupEmptyRooms removes rooms that have been empty for at least their
+// EmptyTimeout
 func (rm *RoomManager) CleanupEmptyRooms() {
+	now := time.Now()
+
 	rm.mu.RLock()
 	roomsToDelete := make([]string, 0)
 
 	for roomID, room := range rm.rooms {
-		if room.IsEmpty() && room.EmptyTimeout > 0 {
+		if room.emptyExpired(now) {
 			roomsToDelete = append(roomsToDelete, roomID)
 		}
 	}
@@ -195,23 +259,103 @@ func (rm *RoomManager) CleanupEmptyRooms() {
 	}
 }
 
-// Shutdown closes all rooms and cleans up resources
-func (rm *RoomManager) Shutdown() {
+// StartEmptyRoomSweeper runs CleanupEmptyRooms every interval until the
+// manager is closed
+func (rm *RoomManager) StartEmptyRoomSweeper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-rm.done:
+				return
+			case <-ticker.C:
+				rm.CleanupEmptyRooms()
+			}
+		}
+	}()
+}
+
+// Close stops the empty-room sweeper, ends all rooms (publishing
+// participant-left and room-deleted events and releasing their linked
+// RoomSFUs), then waits for pending event callbacks until ctx is done before
+// dropping subscribers and disconnecting from other nodes. It is safe to
+// call more than once; later calls return nil.
+func (rm *RoomManager) Close(ctx context.Context) error {
 	rm.mu.Lock()
-	defer rm.mu.Unlock()
+	if rm.closed {
+		rm.mu.Unlock()
+		return nil
+	}
+	rm.closed = true
+	close(rm.done)
+
+	rooms := rm.rooms
+	rm.rooms = make(map[string]*Room)
+	rm.mu.Unlock()
 
 	rm.logger.Info("Shutting down room manager",
-		logger.Field{Key: "room_count", Value: len(rm.rooms)},
+		logger.Field{Key: "room_count", Value: len(rooms)},
 	)
 
-	// Close all rooms
-	for _, room := range rm.rooms {
-		room.Close()
+	for roomID, room := range rooms {
+		room.End()
+		rm.releaseRoomQuota(room)
+		rm.eventBus.Publish(createEvent(EventRoomDeleted, roomID, room))
 	}
 
-	// Clear rooms map
-	rm.rooms = make(map[string]*Room)
+	err := rm.eventBus.Flush(ctx)
+	if err != nil {
+		rm.logger.Warn("Room events still pending at shutdown",
+			logger.Field{Key: "error", Value: err.Error()},
+		)
+	}
 
 	// Clear event bus
 	rm.eventBus.Clear()
+	rm.eventBus.SetBridge(nil)
+
+	// Disconnect from other nodes
+	rm.mu.Lock()
+	bridge := rm.bridge
+	rm.bridge = nil
+	rm.mu.Unlock()
+
+	if bridge != nil {
+		bridge.Close()
+	}
+
+	return err
+}
+
+// releaseRoomQuota returns the quota of a removed room to its tenant
+func (rm *RoomManager) releaseRoomQuota(room *Room) {
+	if room.quota == nil {
+		return
+	}
+	if err := room.quota.Release(context.Background(), room.Tenant, quota.ResourceRooms, 1); err != nil {
+		rm.logger.Warn("Failed to release room quota",
+			logger.Field{Key: "room_id", Value: room.ID},
+			logger.Field{Key: "tenant", Value: room.Tenant},
+			logger.Field{Key: "error", Value: err.Error()},
+		)
+	}
+}
+
+// CheckHealth reports ErrRoomManagerClosed once the manager is closed
+func (rm *RoomManager) CheckHealth(ctx context.Context) error {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	if rm.closed {
+		return ErrRoomManagerClosed
+	}
+	return nil
+}
+
+// Shutdown closes all rooms and cleans up resources. It is Close without a
+// deadline.
+func (rm *RoomManager) Shutdown() {
+	rm.Close(context.Background())
 }
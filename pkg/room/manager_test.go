@@ -1,10 +1,22 @@
 package room
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/aminofox/zenlive/pkg/auth"
 	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/quota"
+	"github.com/aminofox/zenlive/pkg/security"
+	"github.com/aminofox/zenlive/pkg/streaming/hls"
+	"github.com/aminofox/zenlive/pkg/types"
 )
 
 func TestNewRoomManager(t *testing.T) {
@@ -198,6 +210,41 @@ func TestRoomManagerShutdown(t *testing.T) {
 	}
 }
 
+func TestRoomManagerClose(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	rm := NewRoomManager(log)
+	rm.StartEmptyRoomSweeper(time.Hour)
+
+	room, _ := rm.CreateRoom(&CreateRoomRequest{Name: "Test Room"}, "user-123")
+	room.AddParticipant(NewParticipant("p1", "user-1", "Alice", RoleSpeaker))
+	room.AddParticipant(NewParticipant("p2", "user-2", "Bob", RoleSpeaker))
+
+	left := make(chan string, 2)
+	rm.OnParticipantLeft(func(event *RoomEvent) {
+		time.Sleep(10 * time.Millisecond)
+		left <- event.Data.(*Participant).ID
+	})
+
+	if err := rm.Close(context.Background()); err != nil {
+		t.Fatalf("Failed to close room manager: %v", err)
+	}
+
+	// Close waits for pending callbacks, so both events are delivered
+	if len(left) != 2 {
+		t.Errorf("Expected 2 participant-left events, got %d", len(left))
+	}
+	if !room.IsClosed() || rm.GetRoomCount() != 0 {
+		t.Error("Expected all rooms to be closed and removed")
+	}
+
+	if err := rm.Close(context.Background()); err != nil {
+		t.Errorf("Expected second close to succeed, got %v", err)
+	}
+	if _, err := rm.CreateRoom(&CreateRoomRequest{Name: "Late Room"}, "user-123"); !errors.Is(err, ErrRoomManagerClosed) {
+		t.Errorf("Expected ErrRoomManagerClosed, got %v", err)
+	}
+}
+
 func TestRoomManagerCleanupEmptyRooms(t *testing.T) {
 	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
 	rm := NewRoomManager(log)
@@ -225,3 +272,724 @@ func TestRoomManagerCleanupEmptyRooms(t *testing.T) {
 		t.Error("Empty room with timeout should have been cleaned up")
 	}
 }
+
+func TestRoomManagerCleanupKeepsRecentlyEmptyRooms(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	rm := NewRoomManager(log)
+
+	room, _ := rm.CreateRoom(&CreateRoomRequest{Name: "Test Room", EmptyTimeout: time.Hour}, "user-123")
+	p1 := NewParticipant("p1", "user-1", "Alice", RoleHost)
+	room.AddParticipant(p1)
+	room.RemoveParticipant("p1")
+
+	// The room just became empty, so its timeout has not elapsed
+	rm.CleanupEmptyRooms()
+	if _, err := rm.GetRoom(room.ID); err != nil {
+		t.Fatalf("Expected recently emptied room to be kept, got %v", err)
+	}
+
+	if !room.emptyExpired(time.Now().Add(time.Hour)) {
+		t.Error("Expected the room to expire once empty for its timeout")
+	}
+
+	// Rejoining resets the empty time
+	room.AddParticipant(NewParticipant("p2", "user-2", "Bob", RoleHost))
+	if room.emptyExpired(time.Now().Add(2 * time.Hour)) {
+		t.Error("Expected an occupied room never to expire")
+	}
+}
+
+func TestEventBusFlushConcurrentPublish(t *testing.T) {
+	eventBus := NewEventBus()
+	var handled atomic.Int64
+	eventBus.Subscribe(EventParticipantJoined, func(event *RoomEvent) {
+		handled.Add(1)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				eventBus.Publish(createEvent(EventParticipantJoined, "room-1", nil))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				eventBus.Flush(context.Background())
+			}
+		}()
+	}
+	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := eventBus.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if got := handled.Load(); got != 400 {
+		t.Errorf("Expected 400 handled events after flush, got %d", got)
+	}
+}
+
+func TestRoomManagerTemplates(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	rm := NewRoomManager(log)
+
+	attendee := DefaultPermissions(RoleAttendee)
+	err := rm.CreateRoomTemplate("weekly-webinar", CreateRoomRequest{
+		Name:               "Weekly Webinar",
+		MaxParticipants:    500,
+		EmptyTimeout:       10 * time.Minute,
+		Metadata:           map[string]interface{}{"series": "webinar"},
+		DefaultPermissions: &attendee,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	if err := rm.CreateRoomTemplate("weekly-webinar", CreateRoomRequest{}); err != ErrTemplateExists {
+		t.Errorf("Expected ErrTemplateExists, got %v", err)
+	}
+
+	room, err := rm.CreateRoomFromTemplate("weekly-webinar", &CreateRoomRequest{
+		Name:     "Webinar #42",
+		Metadata: map[string]interface{}{"episode": 42},
+	}, "user-123")
+	if err != nil {
+		t.Fatalf("Failed to create room from template: %v", err)
+	}
+
+	if room.Name != "Webinar #42" {
+		t.Errorf("Expected overridden name, got '%s'", room.Name)
+	}
+	if room.MaxParticipants != 500 {
+		t.Errorf("Expected max participants 500, got %d", room.MaxParticipants)
+	}
+	if room.Metadata["series"] != "webinar" || room.Metadata["episode"] != 42 {
+		t.Errorf("Expected merged metadata, got %v", room.Metadata)
+	}
+	if room.DefaultPermissions == nil || room.DefaultPermissions.CanPublish {
+		t.Error("Expected attendee default permissions from template")
+	}
+
+	// Rooms must not share the template's metadata map
+	template, _ := rm.GetRoomTemplate("weekly-webinar")
+	if _, ok := template.Metadata["episode"]; ok {
+		t.Error("Template metadata should not be modified by overrides")
+	}
+
+	if _, err := rm.CreateRoomFromTemplate("missing", nil, "user-123"); err != ErrTemplateNotFound {
+		t.Errorf("Expected ErrTemplateNotFound, got %v", err)
+	}
+}
+
+func TestRoomManagerCloneRoom(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	rm := NewRoomManager(log)
+
+	source, _ := rm.CreateRoom(&CreateRoomRequest{
+		Name:            "Daily Standup",
+		MaxParticipants: 12,
+		Metadata:        map[string]interface{}{"team": "core"},
+	}, "user-123")
+	source.AddParticipant(NewParticipant("p1", "user-1", "Alice", RoleHost))
+
+	clone, err := rm.CloneRoom(source.ID)
+	if err != nil {
+		t.Fatalf("Failed to clone room: %v", err)
+	}
+
+	if clone.ID == source.ID {
+		t.Error("Clone should have a new ID")
+	}
+	if clone.MaxParticipants != 12 || clone.Metadata["team"] != "core" {
+		t.Error("Clone should copy room settings")
+	}
+	if !clone.IsEmpty() {
+		t.Error("Clone should not copy participants")
+	}
+
+	clone.UpdateMetadata(map[string]interface{}{"team": "infra"})
+	if source.Metadata["team"] != "core" {
+		t.Error("Clone should not share metadata with the source room")
+	}
+
+	if _, err := rm.CloneRoom("non-existent-room"); err != ErrRoomNotFound {
+		t.Errorf("Expected ErrRoomNotFound, got %v", err)
+	}
+}
+
+func TestRoomManagerScheduleRoom(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	rm := NewRoomManager(log)
+
+	opened := make(chan struct{}, 1)
+	ended := make(chan struct{}, 1)
+	rm.OnRoomOpened(func(event *RoomEvent) { opened <- struct{}{} })
+	rm.OnRoomEnded(func(event *RoomEvent) { ended <- struct{}{} })
+
+	// Bridges see events synchronously, so this observes the room as it
+	// becomes visible
+	var scheduledWhenCreated bool
+	observer := &memoryBridge{handler: func(event *RoomEvent) {
+		if event.Type == EventRoomCreated {
+			scheduledWhenCreated = event.Data.(*Room).IsScheduled()
+		}
+	}}
+	rm.SetEventBridge(&memoryBridge{peers: []*memoryBridge{observer}})
+
+	now := time.Now()
+	room, err := rm.ScheduleRoom(&CreateRoomRequest{Name: "Town Hall"}, "user-123",
+		now.Add(50*time.Millisecond), now.Add(150*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to schedule room: %v", err)
+	}
+
+	if !scheduledWhenCreated {
+		t.Error("Room should be scheduled before it becomes visible")
+	}
+
+	if !room.IsScheduled() {
+		t.Error("Room should be scheduled before its start time")
+	}
+
+	if err := room.AddParticipant(NewParticipant("p1", "user-1", "Alice", RoleHost)); err != ErrRoomNotOpen {
+		t.Errorf("Expected ErrRoomNotOpen, got %v", err)
+	}
+
+	select {
+	case <-opened:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for room opened event")
+	}
+
+	if err := room.AddParticipant(NewParticipant("p1", "user-1", "Alice", RoleHost)); err != nil {
+		t.Errorf("Expected join after start time to succeed, got %v", err)
+	}
+
+	select {
+	case <-ended:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for room ended event")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := rm.GetRoom(room.ID); err != ErrRoomNotFound {
+		t.Errorf("Expected room to be deleted at end time, got %v", err)
+	}
+
+	if _, err := rm.ScheduleRoom(&CreateRoomRequest{Name: "Invalid"}, "user-123", now, now); err == nil {
+		t.Error("Expected error when end time is not after start time")
+	}
+}
+
+// memoryBridge links event bridges in-process for tests
+type memoryBridge struct {
+	peers   []*memoryBridge
+	handler EventCallback
+}
+
+func (b *memoryBridge) Publish(event *RoomEvent) {
+	for _, peer := range b.peers {
+		if peer.handler != nil {
+			copied := *event
+			peer.handler(&copied)
+		}
+	}
+}
+
+func (b *memoryBridge) Subscribe(handler EventCallback) error {
+	b.handler = handler
+	return nil
+}
+
+func (b *memoryBridge) Close() error {
+	return nil
+}
+
+func TestRedisEventBridgeEncodesOnPublish(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	bridge := &RedisEventBridge{
+		nodeID: "node-a",
+		logger: log,
+		queue:  make(chan redisMessage, 1),
+		ctx:    context.Background(),
+	}
+
+	p := NewParticipant("p1", "user-1", "Alice", RoleSpeaker)
+	p.UpdateMetadata(map[string]interface{}{"status": "away"})
+	bridge.Publish(createEvent(EventParticipantJoined, "room-1", p))
+
+	// Changes after Publish returns must not reach the queued event
+	p.UpdateMetadata(map[string]interface{}{"status": "busy"})
+
+	msg := <-bridge.queue
+	var envelope struct {
+		Event struct {
+			Data Participant `json:"data"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(msg.payload, &envelope); err != nil {
+		t.Fatalf("Failed to decode queued event: %v", err)
+	}
+	if status := envelope.Event.Data.Metadata["status"]; status != "away" {
+		t.Errorf("Expected the participant as published, got status %v", status)
+	}
+}
+
+func TestRoomManagerEventBridge(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	nodeA := NewRoomManager(log)
+	nodeB := NewRoomManager(log)
+
+	bridgeA := &memoryBridge{}
+	bridgeB := &memoryBridge{}
+	bridgeA.peers = []*memoryBridge{bridgeB}
+	bridgeB.peers = []*memoryBridge{bridgeA}
+
+	if err := nodeA.SetEventBridge(bridgeA); err != nil {
+		t.Fatalf("Failed to set bridge: %v", err)
+	}
+	if err := nodeB.SetEventBridge(bridgeB); err != nil {
+		t.Fatalf("Failed to set bridge: %v", err)
+	}
+
+	localA := make(chan *RoomEvent, 4)
+	remoteB := make(chan *RoomEvent, 4)
+	nodeA.OnRoomCreated(func(event *RoomEvent) { localA <- event })
+	nodeB.OnRoomCreated(func(event *RoomEvent) { remoteB <- event })
+
+	room, _ := nodeA.CreateRoom(&CreateRoomRequest{Name: "Cluster Room"}, "user-123")
+
+	select {
+	case event := <-remoteB:
+		if !event.Remote {
+			t.Error("Expected event on node B to be marked remote")
+		}
+		if event.RoomID != room.ID {
+			t.Errorf("Expected room ID %s, got %s", room.ID, event.RoomID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for bridged event")
+	}
+
+	select {
+	case event := <-localA:
+		if event.Remote {
+			t.Error("Expected event on node A to be local")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for local event")
+	}
+
+	// Remote events must not bounce back to the origin node
+	time.Sleep(50 * time.Millisecond)
+	if len(localA) != 0 {
+		t.Error("Remote event was forwarded back to its origin")
+	}
+}
+
+func TestJoinRoomWithSingleUseToken(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	secret := "test-secret"
+	arm := NewAuthenticatedRoomManager(NewRoomAuthenticator(nil, log), secret, log)
+
+	token, err := auth.NewAccessTokenBuilder("api-key", secret).
+		SetIdentity("user-1").
+		SetRoomJoin("sensitive-room").
+		SetSingleUse(true).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build token: %v", err)
+	}
+
+	// A join that fails does not use up the token
+	full, _ := arm.CreateRoom(&CreateRoomRequest{Name: "sensitive-room", MaxParticipants: 1}, "host")
+	full.AddParticipant(NewParticipant("host", "host", "Host", RoleHost))
+
+	req := &JoinRoomRequest{RoomName: "sensitive-room", AccessToken: token}
+	if _, _, err := arm.JoinRoomWithToken(context.Background(), req); !errors.Is(err, ErrRoomFull) {
+		t.Fatalf("Expected ErrRoomFull, got %v", err)
+	}
+
+	full.RemoveParticipant("host")
+	if _, _, err := arm.JoinRoomWithToken(context.Background(), req); err != nil {
+		t.Fatalf("Join after a failed attempt should succeed: %v", err)
+	}
+
+	_, _, err = arm.JoinRoomWithToken(context.Background(), req)
+	if !errors.Is(err, auth.ErrTokenAlreadyUsed) {
+		t.Errorf("Expected ErrTokenAlreadyUsed on replay, got %v", err)
+	}
+}
+
+func TestJoinRoomWithBoundToken(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	secret := "test-secret"
+	arm := NewAuthenticatedRoomManager(NewRoomAuthenticator(nil, log), secret, log)
+
+	token, err := auth.NewAccessTokenBuilder("api-key", secret).
+		SetIdentity("user-1").
+		SetRoomJoin("secure-room").
+		SetBoundIP("10.0.0.0/8").
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build token: %v", err)
+	}
+
+	stolen := &JoinRoomRequest{RoomName: "secure-room", AccessToken: token, ClientIP: "192.0.2.10"}
+	if _, _, err := arm.JoinRoomWithToken(context.Background(), stolen); !errors.Is(err, auth.ErrBindingMismatch) {
+		t.Errorf("Expected ErrBindingMismatch, got %v", err)
+	}
+
+	req := &JoinRoomRequest{RoomName: "secure-room", AccessToken: token, ClientIP: "10.1.2.3"}
+	if _, _, err := arm.JoinRoomWithToken(context.Background(), req); err != nil {
+		t.Errorf("Join from bound network should succeed: %v", err)
+	}
+}
+
+func TestJoinRoomWithDelegationToken(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	secret := "test-secret"
+	arm := NewAuthenticatedRoomManager(NewRoomAuthenticator(nil, log), secret, log)
+
+	auditLogger := security.NewAuditLogger(100, nil)
+	arm.SetAuditLogger(auditLogger)
+
+	admin := &auth.AccessTokenClaims{
+		Identity:  "admin-1",
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		Video:     &auth.VideoGrant{RoomJoin: true, Room: "support-room", RoomAdmin: true, CanSubscribe: true},
+	}
+
+	token, err := auth.NewDelegationToken("api-key", secret, admin, "user-1", nil, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create delegation token: %v", err)
+	}
+
+	participant, _, err := arm.JoinRoomWithToken(context.Background(), &JoinRoomRequest{RoomName: "support-room", AccessToken: token})
+	if err != nil {
+		t.Fatalf("Failed to join with delegation token: %v", err)
+	}
+
+	if participant.ID != "user-1" || participant.ActorID != "admin-1" {
+		t.Errorf("Expected user-1 acting via admin-1, got %s via %s", participant.ID, participant.ActorID)
+	}
+
+	events := auditLogger.GetRecent(1)
+	if len(events) != 1 || events[0].Metadata["actor_id"] != "admin-1" {
+		t.Errorf("Expected audit event recording the actor, got %+v", events)
+	}
+}
+
+func TestCatchupPlaylist(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	manager := NewRoomManager(log)
+
+	if _, err := manager.GetCatchupPlaylist("stream-1"); err != ErrCatchupUnavailable {
+		t.Errorf("Expected ErrCatchupUnavailable, got %v", err)
+	}
+
+	dvr := hls.NewDVRWindow(0)
+	start := time.Now().Add(-time.Minute)
+	for i := 0; i < 10; i++ {
+		dvr.AddSegment(&hls.Segment{
+			Index:     uint64(i),
+			Duration:  6.0,
+			Filename:  fmt.Sprintf("segment%d.ts", i),
+			CreatedAt: start.Add(time.Duration(i*6) * time.Second),
+		})
+	}
+	manager.EnableCatchup("stream-1", dvr, 6)
+
+	catchup, err := manager.GetCatchupPlaylist("stream-1")
+	if err != nil {
+		t.Fatalf("Failed to get catch-up playlist: %v", err)
+	}
+
+	if catchup.Duration != time.Minute {
+		t.Errorf("Expected duration 1m, got %v", catchup.Duration)
+	}
+
+	rendered := catchup.Render()
+	if !strings.Contains(rendered, "#EXT-X-START:TIME-OFFSET=0.000") || !strings.Contains(rendered, "segment0.ts") {
+		t.Errorf("Expected playlist starting at the first segment, got:\n%s", rendered)
+	}
+
+	if catchup.ShouldSwitchToLive(30 * time.Second) {
+		t.Error("Expected viewer 30s behind live to stay on HLS")
+	}
+	if !catchup.ShouldSwitchToLive(55 * time.Second) {
+		t.Error("Expected viewer 5s behind live to switch to WebRTC")
+	}
+
+	catchup, _ = manager.GetCatchupPlaylistAt("stream-1", 2*time.Minute)
+	if catchup.StartOffset != time.Minute {
+		t.Errorf("Expected offset clamped to the live edge, got %v", catchup.StartOffset)
+	}
+
+	manager.DisableCatchup("stream-1")
+	if _, err := manager.GetCatchupPlaylist("stream-1"); err != ErrCatchupUnavailable {
+		t.Errorf("Expected ErrCatchupUnavailable after disable, got %v", err)
+	}
+}
+
+func TestRoomManagerLobby(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	manager := NewRoomManager(log)
+	defer manager.Shutdown()
+
+	waiting := make(chan string, 2)
+	manager.OnParticipantWaiting(func(event *RoomEvent) {
+		waiting <- event.Data.(*Participant).ID
+	})
+
+	room, err := manager.CreateRoom(&CreateRoomRequest{Name: "Lobby Room", LobbyEnabled: true}, "host-user")
+	if err != nil {
+		t.Fatalf("Failed to create room: %v", err)
+	}
+
+	host := NewParticipant("host", "host-user", "Host", RoleHost)
+	if err := room.AddParticipant(host); err != nil {
+		t.Fatalf("Failed to add host: %v", err)
+	}
+	if host.GetState() != StateJoined {
+		t.Errorf("Expected host to bypass the lobby, got state %s", host.GetState())
+	}
+
+	guest := NewParticipant("guest", "guest-user", "Guest", RoleSpeaker)
+	if err := room.AddParticipant(guest); err != nil {
+		t.Fatalf("Failed to add guest: %v", err)
+	}
+	if guest.GetState() != StateWaiting {
+		t.Errorf("Expected guest to wait, got state %s", guest.GetState())
+	}
+
+	select {
+	case id := <-waiting:
+		if id != "guest" {
+			t.Errorf("Expected waiting event for guest, got %s", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected participant.waiting event")
+	}
+
+	if room.GetParticipantCount() != 1 || len(room.ListWaitingParticipants()) != 1 {
+		t.Errorf("Expected 1 participant and 1 waiting, got %d and %d", room.GetParticipantCount(), len(room.ListWaitingParticipants()))
+	}
+	if err := room.PublishTrack("guest", &MediaTrack{ID: "t1", Kind: "video"}); err != ErrParticipantWaiting {
+		t.Errorf("Expected ErrParticipantWaiting on publish, got %v", err)
+	}
+	if err := room.AuthorizeSubscribe("guest", "host", "t1"); err != ErrParticipantWaiting {
+		t.Errorf("Expected ErrParticipantWaiting on subscribe, got %v", err)
+	}
+
+	if err := manager.AdmitParticipant(room.ID, "guest"); err != nil {
+		t.Fatalf("Failed to admit guest: %v", err)
+	}
+	if guest.GetState() != StateJoined || room.GetParticipantCount() != 2 {
+		t.Errorf("Expected guest admitted, got state %s", guest.GetState())
+	}
+	if err := room.PublishTrack("guest", &MediaTrack{ID: "t1", Kind: "video"}); err != nil {
+		t.Errorf("Expected admitted guest to publish, got %v", err)
+	}
+
+	intruder := NewParticipant("intruder", "intruder-user", "Intruder", RoleAttendee)
+	room.AddParticipant(intruder)
+	if err := manager.DenyParticipant(room.ID, "intruder"); err != nil {
+		t.Fatalf("Failed to deny participant: %v", err)
+	}
+	if intruder.GetState() != StateDisconnected || len(room.ListWaitingParticipants()) != 0 {
+		t.Errorf("Expected intruder removed from lobby, got state %s", intruder.GetState())
+	}
+	if err := manager.AdmitParticipant(room.ID, "intruder"); err != ErrParticipantNotFound {
+		t.Errorf("Expected ErrParticipantNotFound for denied participant, got %v", err)
+	}
+}
+
+func TestRoomLockAndKnock(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	manager := NewRoomManager(log)
+	defer manager.Shutdown()
+
+	knocks := make(chan string, 1)
+	manager.OnParticipantKnocked(func(event *RoomEvent) {
+		knocks <- event.Data.(*Participant).ID
+	})
+
+	room, err := manager.CreateRoom(&CreateRoomRequest{Name: "Locked Room"}, "host-user")
+	if err != nil {
+		t.Fatalf("Failed to create room: %v", err)
+	}
+	room.AddParticipant(NewParticipant("host", "host-user", "Host", RoleHost))
+
+	room.Lock()
+	if !room.IsLocked() {
+		t.Fatal("Expected room to be locked")
+	}
+
+	if err := room.AddParticipant(NewParticipant("late", "late-user", "Late", RoleSpeaker)); err != ErrRoomLocked {
+		t.Errorf("Expected ErrRoomLocked, got %v", err)
+	}
+	if err := room.AddParticipant(NewParticipant("cohost", "cohost-user", "Co-host", RoleHost)); err != nil {
+		t.Errorf("Expected host to join a locked room, got %v", err)
+	}
+
+	late := NewParticipant("late", "late-user", "Late", RoleSpeaker)
+	if err := room.Knock(late); err != nil {
+		t.Fatalf("Failed to knock: %v", err)
+	}
+	select {
+	case id := <-knocks:
+		if id != "late" {
+			t.Errorf("Expected knock from late, got %s", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected participant.knocked event")
+	}
+	if late.GetState() != StateWaiting {
+		t.Errorf("Expected knocking participant to wait, got state %s", late.GetState())
+	}
+
+	if err := manager.AdmitParticipant(room.ID, "late"); err != nil {
+		t.Fatalf("Failed to admit knocking participant: %v", err)
+	}
+	if room.GetParticipantCount() != 3 {
+		t.Errorf("Expected 3 participants, got %d", room.GetParticipantCount())
+	}
+
+	room.Unlock()
+	if err := room.AddParticipant(NewParticipant("guest", "guest-user", "Guest", RoleAttendee)); err != nil {
+		t.Errorf("Expected join after unlock, got %v", err)
+	}
+
+	// Knocking applies the same admission checks as joining
+	full, _ := manager.CreateRoom(&CreateRoomRequest{Name: "Full Room", MaxParticipants: 1}, "host-user")
+	full.AddParticipant(NewParticipant("full-host", "host-user", "Host", RoleHost))
+	full.Lock()
+	if err := full.Knock(NewParticipant("extra", "extra-user", "Extra", RoleSpeaker)); err != ErrRoomFull {
+		t.Errorf("Expected ErrRoomFull for a knock on a full room, got %v", err)
+	}
+
+	scheduled, _ := manager.ScheduleRoom(&CreateRoomRequest{Name: "Later"}, "host-user", time.Now().Add(time.Hour), time.Time{})
+	scheduled.Lock()
+	if err := scheduled.Knock(NewParticipant("early", "early-user", "Early", RoleSpeaker)); err != ErrRoomNotOpen {
+		t.Errorf("Expected ErrRoomNotOpen for a knock before the start, got %v", err)
+	}
+}
+
+func TestRoomLocalization(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	manager := NewRoomManager(log)
+	defer manager.Shutdown()
+
+	_, err := manager.CreateRoom(&CreateRoomRequest{
+		Name:      "Bad",
+		Localized: map[string]types.LocalizedText{"title": {"not a tag": "x"}},
+	}, "user-1")
+	if !errors.Is(err, ErrInvalidRoomRequest) {
+		t.Errorf("Expected ErrInvalidRoomRequest for a bad language tag, got %v", err)
+	}
+
+	room, err := manager.CreateRoom(&CreateRoomRequest{
+		Name:            "Concert",
+		DefaultLanguage: "en",
+		Localized: map[string]types.LocalizedText{
+			"title":       {"en": "Live Concert", "ja": "ライブコンサート"},
+			"description": {"en": "An evening of jazz"},
+		},
+	}, "user-1")
+	if err != nil {
+		t.Fatalf("Failed to create room: %v", err)
+	}
+	manager.CreateRoom(&CreateRoomRequest{Name: "Standup"}, "user-1")
+
+	if title := room.GetLocalized("title", "ja"); title != "ライブコンサート" {
+		t.Errorf("Expected Japanese title, got %q", title)
+	}
+	if title := room.GetLocalized("title", "en-GB"); title != "Live Concert" {
+		t.Errorf("Expected base language fallback, got %q", title)
+	}
+	if description := room.GetLocalized("description", "ja"); description != "An evening of jazz" {
+		t.Errorf("Expected default language fallback, got %q", description)
+	}
+
+	if err := room.UpdateLocalized(map[string]types.LocalizedText{"title": {"fr": ""}}); !errors.Is(err, types.ErrInvalidLocalization) {
+		t.Errorf("Expected ErrInvalidLocalization for an empty text, got %v", err)
+	}
+	room.UpdateLocalized(map[string]types.LocalizedText{"title": {"fr": "Concert en direct"}})
+
+	if matches := manager.SearchRooms("direct", "fr"); len(matches) != 1 || matches[0].ID != room.ID {
+		t.Errorf("Expected French search to match the concert, got %d rooms", len(matches))
+	}
+	if matches := manager.SearchRooms("direct", "en"); len(matches) != 0 {
+		t.Errorf("Expected English search not to match French text, got %d rooms", len(matches))
+	}
+	if matches := manager.SearchRooms("", "en"); len(matches) != 2 {
+		t.Errorf("Expected empty query to match all rooms, got %d", len(matches))
+	}
+}
+
+func TestRoomManagerQuota(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	manager := NewRoomManager(log)
+	quotas := quota.NewManager(quota.NewMemoryUsageStore(), quota.Limits{MaxRooms: 1, MaxParticipants: 2})
+	manager.SetQuotaManager(quotas)
+	ctx := context.Background()
+
+	room, err := manager.CreateRoom(&CreateRoomRequest{Name: "Room A", Tenant: "acme"}, "user-1")
+	if err != nil {
+		t.Fatalf("Failed to create room: %v", err)
+	}
+	if _, err := manager.CreateRoom(&CreateRoomRequest{Name: "Room B", Tenant: "acme"}, "user-1"); !errors.Is(err, quota.ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded for a second room, got %v", err)
+	}
+	if _, err := manager.CreateRoom(&CreateRoomRequest{Name: "Room C"}, "user-1"); err != nil {
+		t.Errorf("Expected rooms without a tenant to be unlimited, got %v", err)
+	}
+
+	room.AddParticipant(NewParticipant("p1", "u1", "Alice", RoleSpeaker))
+	room.AddParticipant(NewParticipant("p2", "u2", "Bob", RoleSpeaker))
+	if err := room.AddParticipant(NewParticipant("p3", "u3", "Carol", RoleSpeaker)); !errors.Is(err, quota.ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded for a third participant, got %v", err)
+	}
+	if err := room.AddParticipants([]*Participant{NewParticipant("p3", "u3", "Carol", RoleSpeaker)}); !errors.Is(err, quota.ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded for a batch join, got %v", err)
+	}
+
+	room.RemoveParticipant("p1")
+	if err := room.AddParticipant(NewParticipant("p3", "u3", "Carol", RoleSpeaker)); err != nil {
+		t.Errorf("Expected a left participant's quota to be reusable, got %v", err)
+	}
+
+	usage, _ := quotas.GetUsage(ctx, "acme")
+	if usage.Rooms != 1 || usage.Participants != 2 {
+		t.Errorf("Expected 1 room and 2 participants in use, got %+v", usage)
+	}
+
+	// Knocking on a locked room takes quota too, and denial returns it
+	room.Lock()
+	if err := room.Knock(NewParticipant("p4", "u4", "Dave", RoleSpeaker)); !errors.Is(err, quota.ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded for a knock, got %v", err)
+	}
+	room.RemoveParticipant("p2")
+	if err := room.Knock(NewParticipant("p4", "u4", "Dave", RoleSpeaker)); err != nil {
+		t.Fatalf("Failed to knock: %v", err)
+	}
+	room.DenyParticipant("p4")
+	usage, _ = quotas.GetUsage(ctx, "acme")
+	if usage.Participants != 1 {
+		t.Errorf("Expected 1 participant in use after the denial, got %+v", usage)
+	}
+
+	// Deleting the room returns its quota and its participants'
+	manager.DeleteRoom(room.ID)
+	usage, _ = quotas.GetUsage(ctx, "acme")
+	if usage.Rooms != 0 || usage.Participants != 0 {
+		t.Errorf("Expected no usage after deleting the room, got %+v", usage)
+	}
+}
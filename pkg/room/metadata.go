@@ -0,0 +1,92 @@
+package room
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// DefaultMaxParticipantMetadataSize is the default limit for encoded participant metadata in bytes
+const DefaultMaxParticipantMetadataSize = 4096
+
+var (
+	// ErrMetadataTooLarge is returned when participant metadata exceeds the size limit
+	ErrMetadataTooLarge = errors.New("participant metadata exceeds size limit")
+	// ErrInvalidMetadata is returned when participant metadata fails validation
+	ErrInvalidMetadata = errors.New("invalid participant metadata")
+)
+
+// MetadataValidator checks the merged participant metadata before it is applied
+type MetadataValidator func(metadata map[string]interface{}) error
+
+// SetMetadataValidator sets an optional validator for participant metadata
+func (r *Room) SetMetadataValidator(validator MetadataValidator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metadataValidator = validator
+}
+
+// SetMaxParticipantMetadataSize sets the encoded size limit for participant metadata (0 = unlimited)
+func (r *Room) SetMaxParticipantMetadataSize(size int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.maxMetadataSize = size
+}
+
+// UpdateParticipantMetadata merges metadata into a participant's metadata.
+// Keys set to nil are removed. The merged result is validated before it is
+// applied and a participant.metadataUpdated event is published.
+func (r *Room) UpdateParticipantMetadata(participantID string, metadata map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	participant, exists := r.participants[participantID]
+	if !exists {
+		return ErrParticipantNotFound
+	}
+
+	merged := participant.GetMetadata()
+	for key, value := range metadata {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = value
+	}
+
+	encoded, err := json.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+
+	if r.maxMetadataSize > 0 && len(encoded) > r.maxMetadataSize {
+		return ErrMetadataTooLarge
+	}
+
+	if r.metadataValidator != nil {
+		if err := r.metadataValidator(merged); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+		}
+	}
+
+	participant.setMetadata(merged)
+
+	r.logger.Info("Participant metadata updated",
+		logger.Field{Key: "room_id", Value: r.ID},
+		logger.Field{Key: "participant_id", Value: participantID},
+	)
+
+	// Publish event with its own copy, as the participant keeps merged
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventParticipantMetadataUpdated, r.ID, map[string]interface{}{
+			"participant_id": participantID,
+			"metadata":       participant.GetMetadata(),
+		}))
+	}
+
+	return nil
+}
@@ -23,6 +23,8 @@ type Participant struct {
 	State ParticipantState `json:"state"`
 	// Metadata contains custom participant data
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// ActorID is the real identity acting as this participant through a delegated token
+	ActorID string `json:"actor_id,omitempty"`
 
 	// Token-based permissions (from access token)
 	CanPublish     bool `json:"can_publish"`
@@ -113,6 +115,13 @@ func (p *Participant) UpdateMetadata(metadata map[string]interface{}) {
 	}
 }
 
+// setMetadata replaces participant metadata
+func (p *Participant) setMetadata(metadata map[string]interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Metadata = metadata
+}
+
 // GetMetadata returns a copy of participant metadata
 func (p *Participant) GetMetadata() map[string]interface{} {
 	p.mu.RLock()
@@ -124,6 +133,42 @@ func (p *Participant) GetMetadata() map[string]interface{} {
 	return metadata
 }
 
+// Snapshot returns a copy of the participant taken under its lock, with its
+// own metadata and tracks. The copy is detached from the room.
+func (p *Participant) Snapshot() *Participant {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	snapshot := &Participant{
+		ID:             p.ID,
+		UserID:         p.UserID,
+		Username:       p.Username,
+		JoinedAt:       p.JoinedAt,
+		Role:           p.Role,
+		Permissions:    p.Permissions,
+		State:          p.State,
+		Metadata:       make(map[string]interface{}, len(p.Metadata)),
+		ActorID:        p.ActorID,
+		CanPublish:     p.CanPublish,
+		CanSubscribe:   p.CanSubscribe,
+		CanPublishData: p.CanPublishData,
+		IsAdmin:        p.IsAdmin,
+		IsHidden:       p.IsHidden,
+		IsRecorder:     p.IsRecorder,
+		tracks:         make(map[string]*MediaTrack, len(p.tracks)),
+	}
+
+	for key, value := range p.Metadata {
+		snapshot.Metadata[key] = value
+	}
+	for id, track := range p.tracks {
+		t := *track
+		snapshot.tracks[id] = &t
+	}
+
+	return snapshot
+}
+
 // GetState returns the participant's current state
 func (p *Participant) GetState() ParticipantState {
 	p.mu.RLock()
@@ -0,0 +1,205 @@
+package room
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// DefaultPlaybackDriftThreshold is how far a participant may drift from the
+// shared playback position before a correction is published
+const DefaultPlaybackDriftThreshold = 250 * time.Millisecond
+
+var (
+	// ErrInvalidPlaybackState is returned for a negative position or rate
+	ErrInvalidPlaybackState = errors.New("invalid playback state")
+	// ErrNoPlayback is returned when drift is reported before playback is synced
+	ErrNoPlayback = errors.New("room has no synchronized playback")
+)
+
+// PlaybackState is the shared playback position of a watch-party room
+type PlaybackState struct {
+	// PositionMS is the media position in milliseconds at ServerTime
+	PositionMS int64 `json:"position_ms"`
+	// Playing is false while playback is paused
+	Playing bool `json:"playing"`
+	// Rate is the playback speed (0 = 1.0)
+	Rate float64 `json:"rate"`
+	// ServerTime is when the server applied this state; clients extrapolate
+	// the current position from it using their server clock offset
+	ServerTime time.Time `json:"server_time"`
+	// UpdatedBy is the participant who changed the state (empty = server)
+	UpdatedBy string `json:"updated_by,omitempty"`
+}
+
+// PositionAt returns the media position in milliseconds at the given time
+func (s PlaybackState) PositionAt(t time.Time) int64 {
+	if !s.Playing || t.Before(s.ServerTime) {
+		return s.PositionMS
+	}
+
+	return s.PositionMS + int64(float64(t.Sub(s.ServerTime).Milliseconds())*s.Rate)
+}
+
+// PlaybackCorrection tells a drifting participant where playback should be
+type PlaybackCorrection struct {
+	// ParticipantID is the participant that drifted
+	ParticipantID string `json:"participant_id"`
+	// DriftMS is the reported position minus the expected one (positive = ahead)
+	DriftMS int64 `json:"drift_ms"`
+	// State is the shared playback state, with the position at ServerTime
+	State PlaybackState `json:"state"`
+}
+
+// SyncPlayback applies a server-side playback state to a room and broadcasts
+// it to the room's participants
+func (rm *RoomManager) SyncPlayback(roomID string, state PlaybackState) error {
+	room, err := rm.GetRoom(roomID)
+	if err != nil {
+		return err
+	}
+
+	_, err = room.SyncPlayback(state)
+	return err
+}
+
+// OnPlaybackSynced registers a callback for playback sync events
+func (rm *RoomManager) OnPlaybackSynced(callback EventCallback) {
+	rm.eventBus.Subscribe(EventPlaybackSynced, callback)
+}
+
+// OnPlaybackDrift registers a callback for playback drift corrections
+func (rm *RoomManager) OnPlaybackDrift(callback EventCallback) {
+	rm.eventBus.Subscribe(EventPlaybackDrift, callback)
+}
+
+// SetPlaybackDriftThreshold sets the drift tolerated before a correction is published
+func (r *Room) SetPlaybackDriftThreshold(threshold time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.playbackDriftThreshold = threshold
+}
+
+// SyncPlayback stamps the state with the server time, stores it as the
+// room's shared playback and publishes a playback.synced event
+func (r *Room) SyncPlayback(state PlaybackState) (PlaybackState, error) {
+	if state.PositionMS < 0 || state.Rate < 0 {
+		return PlaybackState{}, ErrInvalidPlaybackState
+	}
+
+	if state.Rate == 0 {
+		state.Rate = 1
+	}
+	state.ServerTime = time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.isClosed {
+		return PlaybackState{}, ErrRoomClosed
+	}
+
+	r.playback = &state
+
+	r.logger.Debug("Playback synced",
+		logger.Field{Key: "room_id", Value: r.ID},
+		logger.Field{Key: "position_ms", Value: state.PositionMS},
+		logger.Field{Key: "playing", Value: state.Playing},
+		logger.Field{Key: "rate", Value: state.Rate},
+	)
+
+	// Publish event
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventPlaybackSynced, r.ID, state))
+	}
+
+	return state, nil
+}
+
+// HostSyncPlayback applies a playback change made by a participant. Only the
+// room's hosts control playback; viewers follow the broadcast state.
+func (r *Room) HostSyncPlayback(participantID string, state PlaybackState) (PlaybackState, error) {
+	participant, err := r.GetParticipant(participantID)
+	if err != nil {
+		return PlaybackState{}, err
+	}
+
+	if participant.Role != RoleHost {
+		return PlaybackState{}, ErrUnauthorized
+	}
+
+	state.UpdatedBy = participantID
+	return r.SyncPlayback(state)
+}
+
+// GetPlayback returns the shared playback state with its position advanced to
+// now, or false if playback has not been synced
+func (r *Room) GetPlayback() (PlaybackState, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.playback == nil {
+		return PlaybackState{}, false
+	}
+
+	now := time.Now()
+	state := *r.playback
+	state.PositionMS = state.PositionAt(now)
+	state.ServerTime = now
+
+	return state, true
+}
+
+// ReportPlaybackPosition compares a participant's position, as of the time
+// the server received it, with the shared playback. When the drift exceeds
+// the room's threshold a playback.drift event is published and the
+// correction returned; otherwise the result is nil.
+func (r *Room) ReportPlaybackPosition(participantID string, positionMS int64) (*PlaybackCorrection, error) {
+	r.mu.RLock()
+	_, exists := r.participants[participantID]
+	playback := r.playback
+	threshold := r.playbackDriftThreshold
+	r.mu.RUnlock()
+
+	if !exists {
+		return nil, ErrParticipantNotFound
+	}
+
+	if playback == nil {
+		return nil, ErrNoPlayback
+	}
+
+	now := time.Now()
+	expected := playback.PositionAt(now)
+	drift := positionMS - expected
+
+	if math.Abs(float64(drift)) <= float64(threshold.Milliseconds()) {
+		return nil, nil
+	}
+
+	state := *playback
+	state.PositionMS = expected
+	state.ServerTime = now
+
+	correction := &PlaybackCorrection{
+		ParticipantID: participantID,
+		DriftMS:       drift,
+		State:         state,
+	}
+
+	r.logger.Debug("Playback drift detected",
+		logger.Field{Key: "room_id", Value: r.ID},
+		logger.Field{Key: "participant_id", Value: participantID},
+		logger.Field{Key: "drift_ms", Value: drift},
+	)
+
+	// Publish event
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventPlaybackDrift, r.ID, correction))
+	}
+
+	return correction, nil
+}
@@ -1,11 +1,14 @@
 package room
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"time"
 
 	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/quota"
+	"github.com/aminofox/zenlive/pkg/types"
 	"github.com/google/uuid"
 )
 
@@ -18,6 +21,18 @@ var (
 	ErrParticipantExists = errors.New("participant already exists in room")
 	// ErrUnauthorized is returned when participant lacks permission
 	ErrUnauthorized = errors.New("participant lacks required permissions")
+	// ErrRoomNotOpen is returned when joining a scheduled room before it opens
+	ErrRoomNotOpen = errors.New("room is not open yet")
+	// ErrRoomClosed is returned when modifying a room that has been closed
+	ErrRoomClosed = errors.New("room is closed")
+	// ErrTrackNotFound is returned when a participant has no track with the given ID
+	ErrTrackNotFound = errors.New("track not found")
+	// ErrParticipantWaiting is returned when a participant in the lobby acts in the room
+	ErrParticipantWaiting = errors.New("participant is waiting in the lobby")
+	// ErrRoomLocked is returned when joining a locked room without host admission
+	ErrRoomLocked = errors.New("room is locked")
+	// ErrInvalidParticipant is returned when joining with a nil participant
+	ErrInvalidParticipant = errors.New("invalid participant")
 )
 
 // Room represents a video conferencing room
@@ -36,19 +51,74 @@ type Room struct {
 	EmptyTimeout time.Duration `json:"empty_timeout"`
 	// Metadata contains custom room data
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// DefaultPermissions are granted to participants joining without explicit permissions
+	DefaultPermissions *ParticipantPermissions `json:"default_permissions,omitempty"`
+	// LobbyEnabled holds joining participants in a lobby until the host admits them
+	LobbyEnabled bool `json:"lobby_enabled"`
+	// E2EE enables end-to-end media encryption key coordination
+	E2EE bool `json:"e2ee"`
+	// DeliveryReceipts enables delivery and read receipts and typing indicators for data messages
+	DeliveryReceipts bool `json:"delivery_receipts"`
+	// Localized holds translations of localized fields (e.g. "title" -> "ja" -> text)
+	Localized map[string]types.LocalizedText `json:"localized,omitempty"`
+	// DefaultLanguage is the language used when a translation is missing
+	DefaultLanguage string `json:"default_language,omitempty"`
+	// StartAt is when a scheduled room opens for joins (zero = opened on creation)
+	StartAt time.Time `json:"start_at,omitzero"`
+	// EndAt is when a scheduled room closes automatically (zero = never)
+	EndAt time.Time `json:"end_at,omitzero"`
+	// Tenant is the tenant whose quota the room and its participants count against
+	Tenant string `json:"tenant,omitempty"`
 
 	// participants stores participants by participant ID
 	participants map[string]*Participant
+	// lobby stores participants waiting for admission by participant ID
+	lobby map[string]*Participant
+	// e2eeKeys stores each participant's current media key by participant ID
+	e2eeKeys map[string]*E2EEKey
+	// e2eeEpoch counts membership changes of an E2EE room
+	e2eeEpoch int
+	// clientStats stores the latest client stats (streamID -> participantID -> stats)
+	clientStats map[string]map[string]ClientStats
+	// isLocked rejects new joins that are not admitted by the host
+	isLocked bool
+	// sfu is the RoomSFU linked to this room, if any
+	sfu *RoomSFU
 	// logger for room events
 	logger logger.Logger
 	// eventBus for publishing events
 	eventBus *EventBus
 	// mu protects concurrent access
 	mu sync.RWMutex
-	// emptyTimer tracks when room became empty
-	emptyTimer *time.Timer
+	// emptySince is when the room last became empty (zero while occupied)
+	emptySince time.Time
 	// isClosed indicates if the room is closed
 	isClosed bool
+	// isScheduled indicates the room is waiting for StartAt
+	isScheduled bool
+	// startTimer and endTimer drive the scheduled lifecycle
+	startTimer *time.Timer
+	endTimer   *time.Timer
+	// metadataValidator optionally validates participant metadata updates
+	metadataValidator MetadataValidator
+	// maxMetadataSize limits encoded participant metadata (0 = unlimited)
+	maxMetadataSize int
+	// playback is the shared watch-party playback state (nil = not synced)
+	playback *PlaybackState
+	// playbackDriftThreshold is the drift tolerated before a correction
+	playbackDriftThreshold time.Duration
+	// sharedStates stores collaborative state maps by name
+	sharedStates map[string]*SharedState
+	// deliveries stores the delivery state of tracked data messages by message ID
+	deliveries map[string]*messageDelivery
+	// deliveryOrder lists tracked message IDs oldest first, for eviction
+	deliveryOrder []string
+	// typing stores when each typing participant last reported typing
+	typing map[string]time.Time
+	// subscribeRules stores explicit rules (subscriberID -> publisherID -> allow)
+	subscribeRules map[string]map[string]bool
+	// quota limits the tenant's participants (nil = unlimited)
+	quota *quota.Manager
 }
 
 // NewRoom creates a new room
@@ -56,23 +126,44 @@ func NewRoom(req *CreateRoomRequest, createdBy string, log logger.Logger, eventB
 	roomID := uuid.New().String()
 
 	room := &Room{
-		ID:              roomID,
-		Name:            req.Name,
-		CreatedAt:       time.Now(),
-		CreatedBy:       createdBy,
-		MaxParticipants: req.MaxParticipants,
-		EmptyTimeout:    req.EmptyTimeout,
-		Metadata:        req.Metadata,
-		participants:    make(map[string]*Participant),
-		logger:          log,
-		eventBus:        eventBus,
-		isClosed:        false,
+		ID:                     roomID,
+		Name:                   req.Name,
+		CreatedAt:              time.Now(),
+		CreatedBy:              createdBy,
+		MaxParticipants:        req.MaxParticipants,
+		EmptyTimeout:           req.EmptyTimeout,
+		Metadata:               req.Metadata,
+		DefaultPermissions:     req.DefaultPermissions,
+		LobbyEnabled:           req.LobbyEnabled,
+		E2EE:                   req.E2EE,
+		DeliveryReceipts:       req.DeliveryReceipts,
+		Localized:              make(map[string]types.LocalizedText, len(req.Localized)),
+		DefaultLanguage:        req.DefaultLanguage,
+		Tenant:                 req.Tenant,
+		participants:           make(map[string]*Participant),
+		lobby:                  make(map[string]*Participant),
+		e2eeKeys:               make(map[string]*E2EEKey),
+		clientStats:            make(map[string]map[string]ClientStats),
+		logger:                 log,
+		eventBus:               eventBus,
+		isClosed:               false,
+		maxMetadataSize:        DefaultMaxParticipantMetadataSize,
+		playbackDriftThreshold: DefaultPlaybackDriftThreshold,
 	}
 
+	room.emptySince = room.CreatedAt
+
 	if room.Metadata == nil {
 		room.Metadata = make(map[string]interface{})
 	}
 
+	for field, texts := range req.Localized {
+		room.Localized[field] = make(types.LocalizedText, len(texts))
+		for lang, text := range texts {
+			room.Localized[field][lang] = text
+		}
+	}
+
 	return room
 }
 
@@ -81,29 +172,88 @@ func (r *Room) AddParticipant(p *Participant) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if err := r.checkJoin(p, 0); err != nil {
+		return err
+	}
+
+	if r.isLocked && !bypassesLobby(p) {
+		return ErrRoomLocked
+	}
+
+	if err := r.acquireParticipantQuota(); err != nil {
+		return err
+	}
+
+	// Hold the participant for host approval
+	if r.LobbyEnabled && !bypassesLobby(p) {
+		r.addToLobby(p)
+		return nil
+	}
+
+	r.admit(p)
+
+	return nil
+}
+
+// checkJoin applies the admission checks every way of joining shares, other
+// than the room lock. pending counts participants of the same batch already
+// accepted. Caller must hold r.mu.
+func (r *Room) checkJoin(p *Participant, pending int) error {
+	if p == nil {
+		return ErrInvalidParticipant
+	}
+
 	if r.isClosed {
-		return errors.New("room is closed")
+		return ErrRoomClosed
+	}
+
+	if r.isScheduled {
+		return ErrRoomNotOpen
 	}
 
 	// Check if participant already exists
 	if _, exists := r.participants[p.ID]; exists {
 		return ErrParticipantExists
 	}
+	if _, waiting := r.lobby[p.ID]; waiting {
+		return ErrParticipantExists
+	}
 
 	// Check if room is full
-	if r.MaxParticipants > 0 && len(r.participants) >= r.MaxParticipants {
+	if r.MaxParticipants > 0 && len(r.participants)+pending >= r.MaxParticipants {
 		return ErrRoomFull
 	}
 
-	// Stop empty timer if it's running
-	if r.emptyTimer != nil {
-		r.emptyTimer.Stop()
-		r.emptyTimer = nil
+	return nil
+}
+
+// acquireParticipantQuota counts a joining participant against the room's
+// tenant quota. Caller must hold r.mu.
+func (r *Room) acquireParticipantQuota() error {
+	if r.quota == nil {
+		return nil
 	}
+	return r.quota.Acquire(context.Background(), r.Tenant, quota.ResourceParticipants)
+}
 
-	// Add participant
-	r.participants[p.ID] = p
-	p.UpdateState(StateJoined)
+// releaseParticipantQuota returns the quota of count departed participants.
+// Caller must hold r.mu.
+func (r *Room) releaseParticipantQuota(count int) {
+	if r.quota == nil {
+		return
+	}
+	if err := r.quota.Release(context.Background(), r.Tenant, quota.ResourceParticipants, count); err != nil {
+		r.logger.Warn("Failed to release participant quota",
+			logger.Field{Key: "room_id", Value: r.ID},
+			logger.Field{Key: "tenant", Value: r.Tenant},
+			logger.Field{Key: "error", Value: err.Error()},
+		)
+	}
+}
+
+// admit adds a participant to the room. Caller must hold r.mu.
+func (r *Room) admit(p *Participant) {
+	r.seat(p)
 
 	r.logger.Info("Participant joined room",
 		logger.Field{Key: "room_id", Value: r.ID},
@@ -115,8 +265,16 @@ func (r *Room) AddParticipant(p *Participant) error {
 	if r.eventBus != nil {
 		r.eventBus.Publish(createEvent(EventParticipantJoined, r.ID, p))
 	}
+}
 
-	return nil
+// seat adds a participant to the room without announcing it. Caller must
+// hold r.mu.
+func (r *Room) seat(p *Participant) {
+	r.emptySince = time.Time{}
+
+	r.participants[p.ID] = p
+	p.UpdateState(StateJoined)
+	r.ratchetE2EE(E2EERatchetJoin, p.ID)
 }
 
 // RemoveParticipant removes a participant from the room
@@ -124,15 +282,11 @@ func (r *Room) RemoveParticipant(participantID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	participant, exists := r.participants[participantID]
+	participant, exists := r.unseat(participantID)
 	if !exists {
-		return ErrParticipantNotFound
+		return r.leaveLobby(participantID)
 	}
 
-	// Remove participant
-	delete(r.participants, participantID)
-	participant.UpdateState(StateDisconnected)
-
 	r.logger.Info("Participant left room",
 		logger.Field{Key: "room_id", Value: r.ID},
 		logger.Field{Key: "participant_id", Value: participantID},
@@ -143,28 +297,48 @@ func (r *Room) RemoveParticipant(participantID string) error {
 		r.eventBus.Publish(createEvent(EventParticipantLeft, r.ID, participant))
 	}
 
-	// Start empty timer if room is now empty and has timeout configured
-	if len(r.participants) == 0 && r.EmptyTimeout > 0 && !r.isClosed {
-		r.startEmptyTimer()
-	}
+	r.markIfEmpty()
 
 	return nil
 }
 
-// GetParticipant returns a participant by ID
+// unseat removes a participant from the room without announcing it,
+// reporting false when it is not in the room. Caller must hold r.mu.
+func (r *Room) unseat(participantID string) (*Participant, bool) {
+	participant, exists := r.participants[participantID]
+	if !exists {
+		return nil, false
+	}
+
+	delete(r.participants, participantID)
+	participant.UpdateState(StateDisconnected)
+	r.releaseParticipantQuota(1)
+	r.ratchetE2EE(E2EERatchetLeave, participantID)
+	r.dropClientStats(participantID)
+
+	return participant, true
+}
+
+// GetParticipant returns a participant by ID. The participant is live; read
+// its fields through its getters or Snapshot. Participants waiting in the
+// lobby are not in the room yet and get ErrParticipantWaiting.
 func (r *Room) GetParticipant(participantID string) (*Participant, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	participant, exists := r.participants[participantID]
 	if !exists {
+		if _, waiting := r.lobby[participantID]; waiting {
+			return nil, ErrParticipantWaiting
+		}
 		return nil, ErrParticipantNotFound
 	}
 
 	return participant, nil
 }
 
-// ListParticipants returns all participants in the room
+// ListParticipants returns all participants in the room. The participants
+// are live; use SnapshotParticipants for copies.
 func (r *Room) ListParticipants() []*Participant {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -177,6 +351,19 @@ func (r *Room) ListParticipants() []*Participant {
 	return participants
 }
 
+// SnapshotParticipants returns copies of all participants in the room
+func (r *Room) SnapshotParticipants() []*Participant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	participants := make([]*Participant, 0, len(r.participants))
+	for _, p := range r.participants {
+		participants = append(participants, p.Snapshot())
+	}
+
+	return participants
+}
+
 // GetParticipantCount returns the number of participants in the room
 func (r *Room) GetParticipantCount() int {
 	r.mu.RLock()
@@ -195,19 +382,24 @@ func (r *Room) UpdateParticipantPermissions(participantID string, perms Particip
 		return ErrParticipantNotFound
 	}
 
+	r.updatePermissions(participant, perms)
+
+	return nil
+}
+
+// updatePermissions sets a participant's permissions and announces the change
+func (r *Room) updatePermissions(participant *Participant, perms ParticipantPermissions) {
 	participant.UpdatePermissions(perms)
 
 	r.logger.Info("Participant permissions updated",
 		logger.Field{Key: "room_id", Value: r.ID},
-		logger.Field{Key: "participant_id", Value: participantID},
+		logger.Field{Key: "participant_id", Value: participant.ID},
 	)
 
 	// Publish event
 	if r.eventBus != nil {
 		r.eventBus.Publish(createEvent(EventParticipantUpdated, r.ID, participant))
 	}
-
-	return nil
 }
 
 // UpdateMetadata updates room metadata
@@ -231,12 +423,9 @@ func (r *Room) UpdateMetadata(metadata map[string]interface{}) {
 
 // PublishTrack publishes a media track for a participant
 func (r *Room) PublishTrack(participantID string, track *MediaTrack) error {
-	r.mu.RLock()
-	participant, exists := r.participants[participantID]
-	r.mu.RUnlock()
-
-	if !exists {
-		return ErrParticipantNotFound
+	participant, err := r.GetParticipant(participantID)
+	if err != nil {
+		return err
 	}
 
 	// Check permissions
@@ -273,7 +462,7 @@ func (r *Room) UnpublishTrack(participantID, trackID string) error {
 
 	track, trackExists := participant.GetTrack(trackID)
 	if !trackExists {
-		return errors.New("track not found")
+		return ErrTrackNotFound
 	}
 
 	participant.RemoveTrack(trackID)
@@ -303,20 +492,44 @@ func (r *Room) Close() {
 
 	r.isClosed = true
 
-	// Stop empty timer if running
-	if r.emptyTimer != nil {
-		r.emptyTimer.Stop()
-		r.emptyTimer = nil
-	}
+	// Stop schedule timers
+	r.stopScheduleTimers()
 
 	// Clear all participants
+	r.releaseParticipantQuota(len(r.participants) + len(r.lobby))
 	r.participants = make(map[string]*Participant)
+	r.lobby = make(map[string]*Participant)
+	r.e2eeKeys = make(map[string]*E2EEKey)
+	r.clientStats = make(map[string]map[string]ClientStats)
 
 	r.logger.Info("Room closed",
 		logger.Field{Key: "room_id", Value: r.ID},
 	)
 }
 
+// End removes every participant, publishing a participant-left event for
+// each, closes the room and releases its linked RoomSFU
+func (r *Room) End() {
+	r.mu.Lock()
+	participants := make([]*Participant, 0, len(r.participants))
+	for _, participant := range r.participants {
+		participants = append(participants, participant)
+	}
+	sfu := r.sfu
+	r.sfu = nil
+	r.mu.Unlock()
+
+	for _, participant := range participants {
+		r.RemoveParticipant(participant.ID)
+	}
+
+	r.Close()
+
+	if sfu != nil {
+		sfu.Close()
+	}
+}
+
 // IsClosed returns whether the room is closed
 func (r *Room) IsClosed() bool {
 	r.mu.RLock()
@@ -333,21 +546,23 @@ func (r *Room) IsEmpty() bool {
 	return len(r.participants) == 0
 }
 
-// startEmptyTimer starts the empty room timer
-func (r *Room) startEmptyTimer() {
-	if r.emptyTimer != nil {
-		r.emptyTimer.Stop()
+// markIfEmpty records when the room became empty. Caller must hold r.mu.
+func (r *Room) markIfEmpty() {
+	if len(r.participants) == 0 && r.emptySince.IsZero() {
+		r.emptySince = time.Now()
 	}
+}
 
-	r.logger.Info("Starting empty room timer",
-		logger.Field{Key: "room_id", Value: r.ID},
-		logger.Field{Key: "timeout", Value: r.EmptyTimeout.String()},
-	)
+// emptyExpired reports whether the room has been empty for at least its
+// EmptyTimeout at now. Rooms without a timeout and scheduled rooms that
+// have not opened never expire.
+func (r *Room) emptyExpired(now time.Time) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	r.emptyTimer = time.AfterFunc(r.EmptyTimeout, func() {
-		r.logger.Info("Room empty timeout triggered",
-			logger.Field{Key: "room_id", Value: r.ID},
-		)
-		// Note: Room will be deleted by RoomManager
-	})
+	if r.EmptyTimeout <= 0 || r.isScheduled || len(r.participants) > 0 || r.emptySince.IsZero() {
+		return false
+	}
+
+	return now.Sub(r.emptySince) >= r.EmptyTimeout
 }
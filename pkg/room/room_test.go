@@ -1,10 +1,15 @@
 package room
 
 import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/streaming/webrtc"
 )
 
 func TestNewRoom(t *testing.T) {
@@ -345,3 +350,723 @@ func TestRoomClose(t *testing.T) {
 		t.Error("Should not be able to add participant to closed room")
 	}
 }
+
+func TestRoomBulkParticipants(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	eventBus := NewEventBus()
+
+	var batches int
+	var mu sync.Mutex
+	eventBus.Subscribe(EventParticipantsJoined, func(event *RoomEvent) {
+		mu.Lock()
+		batches++
+		mu.Unlock()
+	})
+
+	req := &CreateRoomRequest{Name: "Webinar", MaxParticipants: 3}
+	room := NewRoom(req, "user-123", log, eventBus)
+
+	host := NewParticipant("host", "user-0", "Host", RoleHost)
+	p1 := NewParticipant("p1", "user-1", "Alice", RoleSpeaker)
+	p2 := NewParticipant("p2", "user-2", "Bob", RoleSpeaker)
+
+	if err := room.AddParticipants([]*Participant{host, p1, p2}); err != nil {
+		t.Fatalf("Failed to add participants: %v", err)
+	}
+
+	if room.GetParticipantCount() != 3 {
+		t.Errorf("Expected 3 participants, got %d", room.GetParticipantCount())
+	}
+
+	// Batch exceeding capacity is rejected as a whole
+	p3 := NewParticipant("p3", "user-3", "Carol", RoleAttendee)
+	if err := room.AddParticipants([]*Participant{p3}); err != ErrRoomFull {
+		t.Errorf("Expected ErrRoomFull, got %v", err)
+	}
+
+	muted := room.MuteAll([]string{"host"})
+	if len(muted) != 2 {
+		t.Errorf("Expected 2 muted participants, got %d", len(muted))
+	}
+	if !host.GetPermissions().CanPublish {
+		t.Error("Expected host to keep publish permission")
+	}
+	if p1.GetPermissions().CanPublish {
+		t.Error("Expected p1 to be muted")
+	}
+
+	perms := ParticipantPermissions{CanPublish: true, CanSubscribe: true}
+	if err := room.UpdatePermissionsBulk([]string{"p1", "p999"}, perms); err != ErrParticipantNotFound {
+		t.Errorf("Expected ErrParticipantNotFound, got %v", err)
+	}
+	if p1.GetPermissions().CanPublish {
+		t.Error("Failed bulk update should not change permissions")
+	}
+	if err := room.UpdatePermissionsBulk([]string{"p1", "p2"}, perms); err != nil {
+		t.Fatalf("Failed to update permissions: %v", err)
+	}
+	if !p2.GetPermissions().CanPublish {
+		t.Error("Expected p2 to be able to publish")
+	}
+
+	removed := room.RemoveParticipants([]string{"p1", "p2", "p999"})
+	if len(removed) != 2 {
+		t.Errorf("Expected 2 removed participants, got %d", len(removed))
+	}
+	if room.GetParticipantCount() != 1 {
+		t.Errorf("Expected 1 participant, got %d", room.GetParticipantCount())
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if batches != 1 {
+		t.Errorf("Expected 1 batched join event, got %d", batches)
+	}
+}
+
+func TestRoomBulkParticipantsAdmission(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	room := NewRoom(&CreateRoomRequest{Name: "Lobby", LobbyEnabled: true}, "user-123", log, NewEventBus())
+
+	host := NewParticipant("host", "user-0", "Host", RoleHost)
+	guest := NewParticipant("guest", "user-1", "Alice", RoleAttendee)
+	if err := room.AddParticipants([]*Participant{host, guest}); err != nil {
+		t.Fatalf("Failed to add participants: %v", err)
+	}
+	if room.GetParticipantCount() != 1 {
+		t.Errorf("Expected only the host to join, got %d participants", room.GetParticipantCount())
+	}
+	if guest.GetState() != StateWaiting {
+		t.Errorf("Expected the guest to wait in the lobby, got %s", guest.GetState())
+	}
+
+	// A participant waiting in the lobby cannot be added again
+	if err := room.AddParticipants([]*Participant{guest}); err != ErrParticipantExists {
+		t.Errorf("Expected ErrParticipantExists for a waiting participant, got %v", err)
+	}
+
+	if err := room.AddParticipants([]*Participant{NewParticipant("p2", "user-2", "Bob", RoleAttendee), nil}); err != ErrInvalidParticipant {
+		t.Errorf("Expected ErrInvalidParticipant for a nil entry, got %v", err)
+	}
+
+	locked := NewRoom(&CreateRoomRequest{Name: "Locked"}, "user-123", log, NewEventBus())
+	locked.Lock()
+	batch := []*Participant{NewParticipant("host", "user-0", "Host", RoleHost), NewParticipant("p1", "user-1", "Alice", RoleAttendee)}
+	if err := locked.AddParticipants(batch); err != ErrRoomLocked {
+		t.Errorf("Expected ErrRoomLocked, got %v", err)
+	}
+	if locked.GetParticipantCount() != 0 {
+		t.Errorf("Expected a rejected batch to add nobody, got %d participants", locked.GetParticipantCount())
+	}
+
+	// Each joiner ratchets E2EE keys
+	encrypted := NewRoom(&CreateRoomRequest{Name: "E2EE", E2EE: true}, "user-123", log, NewEventBus())
+	if err := encrypted.AddParticipants([]*Participant{NewParticipant("p1", "user-1", "Alice", RoleSpeaker), NewParticipant("p2", "user-2", "Bob", RoleSpeaker)}); err != nil {
+		t.Fatalf("Failed to add participants: %v", err)
+	}
+	if status, _ := encrypted.GetE2EEStatus("p1"); status.Epoch != 2 {
+		t.Errorf("Expected epoch 2 after two joins, got %d", status.Epoch)
+	}
+}
+
+func TestRoomBulkRemoveParticipants(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	room := NewRoom(&CreateRoomRequest{Name: "E2EE", E2EE: true}, "user-123", log, NewEventBus())
+
+	for _, id := range []string{"p1", "p2", "p3"} {
+		room.AddParticipant(NewParticipant(id, "user-"+id, id, RoleSpeaker))
+	}
+	room.ReportClientStats("p1", ClientStats{StreamID: "stream-1", DecodedFPS: 30})
+	room.ReportClientStats("p3", ClientStats{StreamID: "stream-1", DecodedFPS: 30})
+
+	if removed := room.RemoveParticipants([]string{"p1", "p2"}); len(removed) != 2 {
+		t.Fatalf("Expected 2 removed participants, got %d", len(removed))
+	}
+
+	// Each leaver ratchets E2EE keys, like RemoveParticipant
+	if status, _ := room.GetE2EEStatus("p3"); status.Epoch != 5 {
+		t.Errorf("Expected epoch 5 after three joins and two leaves, got %d", status.Epoch)
+	}
+	if qoe := room.GetStreamQoE("stream-1"); qoe.Viewers != 1 {
+		t.Errorf("Expected removed participants' client stats to be dropped, got %d viewers", qoe.Viewers)
+	}
+}
+
+func TestRoomBulkPermissionEvents(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	eventBus := NewEventBus()
+
+	var mu sync.Mutex
+	updated := make(map[string]int)
+	eventBus.Subscribe(EventParticipantUpdated, func(event *RoomEvent) {
+		mu.Lock()
+		updated[event.Data.(*Participant).ID]++
+		mu.Unlock()
+	})
+
+	room := NewRoom(&CreateRoomRequest{Name: "Webinar"}, "user-123", log, eventBus)
+	for _, id := range []string{"p1", "p2", "p3"} {
+		room.AddParticipant(NewParticipant(id, "user-"+id, id, RoleSpeaker))
+	}
+
+	if err := room.UpdatePermissionsBulk([]string{"p1", "p2"}, DefaultPermissions(RoleAttendee)); err != nil {
+		t.Fatalf("Failed to update permissions: %v", err)
+	}
+	room.MuteAll([]string{"p1", "p2"})
+	eventBus.Flush(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, id := range []string{"p1", "p2", "p3"} {
+		if updated[id] != 1 {
+			t.Errorf("Expected one participant update event for %s, got %d", id, updated[id])
+		}
+	}
+}
+
+func TestRoomUpdateParticipantMetadata(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	eventBus := NewEventBus()
+
+	events := make(chan *RoomEvent, 2)
+	eventBus.Subscribe(EventParticipantMetadataUpdated, func(event *RoomEvent) {
+		events <- event
+	})
+
+	room := NewRoom(&CreateRoomRequest{Name: "Test Room"}, "user-123", log, eventBus)
+	room.AddParticipant(NewParticipant("p1", "user-1", "Alice", RoleSpeaker))
+
+	err := room.UpdateParticipantMetadata("p1", map[string]interface{}{"avatar": "a.png", "muted": true})
+	if err != nil {
+		t.Fatalf("Failed to update participant metadata: %v", err)
+	}
+
+	select {
+	case event := <-events:
+		if event.RoomID != room.ID {
+			t.Errorf("Expected event for room %s, got %s", room.ID, event.RoomID)
+		}
+
+		// The event keeps the metadata as published
+		published := event.Data.(map[string]interface{})["metadata"].(map[string]interface{})
+		participant, _ := room.GetParticipant("p1")
+		participant.UpdateMetadata(map[string]interface{}{"avatar": "b.png"})
+		if published["avatar"] != "a.png" {
+			t.Errorf("Expected published metadata to be a copy, got %v", published)
+		}
+		participant.UpdateMetadata(map[string]interface{}{"avatar": "a.png"})
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for metadata event")
+	}
+
+	// Merge and delete keys set to nil
+	err = room.UpdateParticipantMetadata("p1", map[string]interface{}{"muted": nil, "hand_raised": true})
+	if err != nil {
+		t.Fatalf("Failed to update participant metadata: %v", err)
+	}
+
+	participant, _ := room.GetParticipant("p1")
+	metadata := participant.GetMetadata()
+	if metadata["avatar"] != "a.png" || metadata["hand_raised"] != true {
+		t.Errorf("Expected merged metadata, got %v", metadata)
+	}
+	if _, ok := metadata["muted"]; ok {
+		t.Error("Expected nil value to remove the key")
+	}
+
+	select {
+	case event := <-events:
+		if event.RoomID != room.ID {
+			t.Errorf("Expected event for room %s, got %s", room.ID, event.RoomID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for metadata event")
+	}
+
+	room.SetMaxParticipantMetadataSize(32)
+	err = room.UpdateParticipantMetadata("p1", map[string]interface{}{"bio": strings.Repeat("x", 64)})
+	if err != ErrMetadataTooLarge {
+		t.Errorf("Expected ErrMetadataTooLarge, got %v", err)
+	}
+
+	room.SetMaxParticipantMetadataSize(0)
+	room.SetMetadataValidator(func(metadata map[string]interface{}) error {
+		if _, ok := metadata["avatar"].(string); !ok {
+			return errors.New("avatar must be a string")
+		}
+		return nil
+	})
+	err = room.UpdateParticipantMetadata("p1", map[string]interface{}{"avatar": 42})
+	if !errors.Is(err, ErrInvalidMetadata) {
+		t.Errorf("Expected ErrInvalidMetadata, got %v", err)
+	}
+	if participant.GetMetadata()["avatar"] != "a.png" {
+		t.Error("Rejected update should not change metadata")
+	}
+
+	if err := room.UpdateParticipantMetadata("p999", nil); err != ErrParticipantNotFound {
+		t.Errorf("Expected ErrParticipantNotFound, got %v", err)
+	}
+}
+
+func TestRoomSyncPlayback(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	eventBus := NewEventBus()
+
+	synced := make(chan *RoomEvent, 1)
+	eventBus.Subscribe(EventPlaybackSynced, func(event *RoomEvent) {
+		synced <- event
+	})
+	drifted := make(chan *RoomEvent, 1)
+	eventBus.Subscribe(EventPlaybackDrift, func(event *RoomEvent) {
+		drifted <- event
+	})
+
+	room := NewRoom(&CreateRoomRequest{Name: "Watch Party"}, "user-123", log, eventBus)
+	room.AddParticipant(NewParticipant("host", "user-1", "Alice", RoleHost))
+	room.AddParticipant(NewParticipant("viewer", "user-2", "Bob", RoleAttendee))
+
+	if _, err := room.ReportPlaybackPosition("viewer", 0); err != ErrNoPlayback {
+		t.Errorf("Expected ErrNoPlayback, got %v", err)
+	}
+
+	if _, err := room.HostSyncPlayback("viewer", PlaybackState{Playing: true}); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized for viewer, got %v", err)
+	}
+
+	if _, err := room.HostSyncPlayback("host", PlaybackState{PositionMS: -1}); err != ErrInvalidPlaybackState {
+		t.Errorf("Expected ErrInvalidPlaybackState, got %v", err)
+	}
+
+	state, err := room.HostSyncPlayback("host", PlaybackState{PositionMS: 10000, Playing: true})
+	if err != nil {
+		t.Fatalf("Failed to sync playback: %v", err)
+	}
+	if state.Rate != 1 || state.UpdatedBy != "host" || state.ServerTime.IsZero() {
+		t.Errorf("Unexpected synced state: %+v", state)
+	}
+
+	select {
+	case event := <-synced:
+		if event.Data.(PlaybackState).PositionMS != 10000 {
+			t.Errorf("Expected broadcast position 10000, got %+v", event.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for playback event")
+	}
+
+	// The position advances with server time while playing
+	if got := state.PositionAt(state.ServerTime.Add(2 * time.Second)); got != 12000 {
+		t.Errorf("Expected extrapolated position 12000, got %d", got)
+	}
+
+	current, ok := room.GetPlayback()
+	if !ok || current.PositionMS < 10000 {
+		t.Errorf("Expected current playback at or after 10000, got %+v", current)
+	}
+
+	correction, err := room.ReportPlaybackPosition("viewer", current.PositionMS)
+	if err != nil || correction != nil {
+		t.Errorf("Expected no correction for an in-sync viewer, got %+v, %v", correction, err)
+	}
+
+	correction, err = room.ReportPlaybackPosition("viewer", current.PositionMS+5000)
+	if err != nil || correction == nil {
+		t.Fatalf("Expected a correction for a drifting viewer, got %v", err)
+	}
+	if correction.DriftMS < 4000 || correction.ParticipantID != "viewer" {
+		t.Errorf("Unexpected correction: %+v", correction)
+	}
+
+	select {
+	case <-drifted:
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for drift event")
+	}
+
+	// Paused playback does not advance
+	paused, _ := room.SyncPlayback(PlaybackState{PositionMS: 3000, Playing: false})
+	if got := paused.PositionAt(paused.ServerTime.Add(time.Minute)); got != 3000 {
+		t.Errorf("Expected paused position 3000, got %d", got)
+	}
+}
+
+func TestRoomSharedState(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	eventBus := NewEventBus()
+
+	deltas := make(chan *RoomEvent, 4)
+	eventBus.Subscribe(EventSharedStateUpdated, func(event *RoomEvent) {
+		deltas <- event
+	})
+
+	room := NewRoom(&CreateRoomRequest{Name: "Whiteboard"}, "user-123", log, eventBus)
+	room.AddParticipant(NewParticipant("alice", "user-1", "Alice", RoleSpeaker))
+	room.AddParticipant(NewParticipant("bob", "user-2", "Bob", RoleSpeaker))
+	room.AddParticipant(NewParticipant("carol", "user-3", "Carol", RoleAttendee))
+
+	if _, err := room.UpdateSharedState("carol", "board", []SharedStateOp{{Key: "x", Value: 1}}); err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized without data permission, got %v", err)
+	}
+
+	if _, err := room.UpdateSharedState("alice", "board", []SharedStateOp{{Key: ""}}); err != ErrInvalidSharedStateKey {
+		t.Errorf("Expected ErrInvalidSharedStateKey, got %v", err)
+	}
+
+	delta, err := room.UpdateSharedState("alice", "board", []SharedStateOp{
+		{Key: "shape-1", Value: "circle", Clock: 5},
+		{Key: "shape-2", Value: "square", Clock: 5},
+	})
+	if err != nil || delta == nil || len(delta.Entries) != 2 || delta.Clock != 5 {
+		t.Fatalf("Unexpected delta %+v, %v", delta, err)
+	}
+
+	select {
+	case event := <-deltas:
+		if event.Data.(*SharedStateDelta).Name != "board" {
+			t.Errorf("Expected delta for board, got %+v", event.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for shared state event")
+	}
+
+	// Concurrent writes at the same clock resolve by participant ID
+	delta, _ = room.UpdateSharedState("bob", "board", []SharedStateOp{{Key: "shape-1", Value: "triangle", Clock: 5}})
+	if delta == nil {
+		t.Fatal("Expected bob's write to win the tie")
+	}
+
+	// Stale writes are dropped
+	delta, _ = room.UpdateSharedState("alice", "board", []SharedStateOp{{Key: "shape-1", Value: "hexagon", Clock: 4}})
+	if delta != nil {
+		t.Errorf("Expected stale write to be dropped, got %+v", delta)
+	}
+
+	board := room.SharedState("board")
+	if value, _ := board.Get("shape-1"); value != "triangle" {
+		t.Errorf("Expected shape-1 to be triangle, got %v", value)
+	}
+
+	// A delete leaves a tombstone that older writes cannot overwrite
+	if err := board.Delete("shape-2"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	room.UpdateSharedState("alice", "board", []SharedStateOp{{Key: "shape-2", Value: "square", Clock: 5}})
+	if _, ok := board.Get("shape-2"); ok {
+		t.Error("Expected deleted key to stay deleted")
+	}
+
+	// Late joiners receive a snapshot of live entries
+	snapshots := room.SharedStateSnapshots()
+	if len(snapshots) != 1 || snapshots[0].Name != "board" {
+		t.Fatalf("Expected one snapshot for board, got %+v", snapshots)
+	}
+	if len(snapshots[0].Entries) != 1 || snapshots[0].Entries[0].Key != "shape-1" {
+		t.Errorf("Expected only shape-1 in snapshot, got %+v", snapshots[0].Entries)
+	}
+	if snapshots[0].Clock != board.Clock() || board.Clock() != 6 {
+		t.Errorf("Expected snapshot clock 6, got %d", snapshots[0].Clock)
+	}
+}
+
+func TestRoomSubscribeRules(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	eventBus := NewEventBus()
+
+	denied := make(chan *RoomEvent, 1)
+	eventBus.Subscribe(EventSubscribeDenied, func(event *RoomEvent) {
+		denied <- event
+	})
+
+	room := NewRoom(&CreateRoomRequest{Name: "Exam"}, "user-123", log, eventBus)
+	room.AddParticipant(NewParticipant("proctor", "user-1", "Proctor", RoleHost))
+	room.AddParticipant(NewParticipant("alice", "user-2", "Alice", RoleSpeaker))
+	room.AddParticipant(NewParticipant("bob", "user-3", "Bob", RoleSpeaker))
+
+	if err := room.AuthorizeSubscribe("alice", "bob", "track-1"); err != nil {
+		t.Errorf("Expected subscription allowed by default, got %v", err)
+	}
+
+	room.SetSubscribeRule("alice", "bob", false)
+	if err := room.AuthorizeSubscribe("alice", "bob", "track-1"); err != ErrSubscribeDenied {
+		t.Errorf("Expected ErrSubscribeDenied, got %v", err)
+	}
+	if !room.CanSubscribeTo("bob", "alice") {
+		t.Error("Expected rules to be asymmetric")
+	}
+
+	select {
+	case event := <-denied:
+		denial := event.Data.(SubscribeDenial)
+		if denial.SubscriberID != "alice" || denial.PublisherID != "bob" || denial.TrackID != "track-1" {
+			t.Errorf("Unexpected denial: %+v", denial)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for denied event")
+	}
+
+	// Default deny with a specific allow forms an allow list
+	room.SetSubscribeRule("bob", SubscribeRuleAll, false)
+	room.SetSubscribeRule("bob", "proctor", true)
+	if room.CanSubscribeTo("bob", "alice") {
+		t.Error("Expected bob to be denied alice by default rule")
+	}
+	if !room.CanSubscribeTo("bob", "proctor") {
+		t.Error("Expected specific allow to override default deny")
+	}
+	if len(room.GetSubscribeRules("bob")) != 2 {
+		t.Errorf("Expected 2 rules for bob, got %d", len(room.GetSubscribeRules("bob")))
+	}
+
+	room.ClearSubscribeRule("alice", "bob")
+	if !room.CanSubscribeTo("alice", "bob") {
+		t.Error("Expected cleared rule to restore default")
+	}
+
+	if moderators := room.Moderators(); len(moderators) != 1 || moderators[0].ID != "proctor" {
+		t.Errorf("Expected proctor as only moderator, got %v", moderators)
+	}
+
+	if err := room.AuthorizeSubscribe("alice", "nobody", ""); err != ErrParticipantNotFound {
+		t.Errorf("Expected ErrParticipantNotFound, got %v", err)
+	}
+}
+
+func TestParticipantSnapshot(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	room := NewRoom(&CreateRoomRequest{Name: "Snapshot Room"}, "user-123", log, NewEventBus())
+
+	participant := NewParticipant("p1", "u1", "Alice", RoleSpeaker)
+	participant.AddTrack(&MediaTrack{ID: "t1", Kind: "audio"})
+	participant.UpdateMetadata(map[string]interface{}{"team": "red"})
+	if err := room.AddParticipant(participant); err != nil {
+		t.Fatalf("Failed to add participant: %v", err)
+	}
+
+	snapshots := room.SnapshotParticipants()
+	if len(snapshots) != 1 {
+		t.Fatalf("Expected 1 snapshot, got %d", len(snapshots))
+	}
+	snapshot := snapshots[0]
+
+	participant.UpdateMetadata(map[string]interface{}{"team": "blue"})
+	participant.UpdateState(StateDisconnected)
+	participant.AddTrack(&MediaTrack{ID: "t2", Kind: "video"})
+
+	if snapshot.Metadata["team"] != "red" || snapshot.State == StateDisconnected {
+		t.Errorf("Expected snapshot to be detached, got %+v", snapshot)
+	}
+	if len(snapshot.GetTracks()) != 1 {
+		t.Errorf("Expected 1 track in snapshot, got %d", len(snapshot.GetTracks()))
+	}
+}
+
+func TestRoomE2EEKeys(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	eventBus := NewEventBus()
+
+	var mu sync.Mutex
+	var ratchets []E2EERatchet
+	eventBus.Subscribe(EventE2EEKeyRatcheted, func(event *RoomEvent) {
+		mu.Lock()
+		ratchets = append(ratchets, event.Data.(E2EERatchet))
+		mu.Unlock()
+	})
+
+	plain := NewRoom(&CreateRoomRequest{Name: "Plain"}, "user-1", log, eventBus)
+	plain.AddParticipant(NewParticipant("p1", "user-1", "Alice", RoleHost))
+	if _, err := plain.SetE2EEKey("p1", []byte("key")); err != ErrE2EENotEnabled {
+		t.Errorf("Expected ErrE2EENotEnabled, got %v", err)
+	}
+
+	room := NewRoom(&CreateRoomRequest{Name: "Secure", E2EE: true}, "user-1", log, eventBus)
+	room.AddParticipant(NewParticipant("p1", "user-1", "Alice", RoleHost))
+
+	key, err := room.SetE2EEKey("p1", []byte("wrapped-key-0"))
+	if err != nil {
+		t.Fatalf("Failed to set key: %v", err)
+	}
+	if key.KeyIndex != 0 || key.Epoch != 1 {
+		t.Errorf("Expected key 0 in epoch 1, got key %d in epoch %d", key.KeyIndex, key.Epoch)
+	}
+
+	status, _ := room.GetE2EEStatus("p1")
+	if !status.Enabled || !status.Encrypted {
+		t.Errorf("Expected p1 encrypted, got %+v", status)
+	}
+
+	room.AddParticipant(NewParticipant("p2", "user-2", "Bob", RoleSpeaker))
+	status, _ = room.GetE2EEStatus("p1")
+	if status.Encrypted || status.Epoch != 2 {
+		t.Errorf("Expected p1 stale after a join, got %+v", status)
+	}
+
+	key, _ = room.SetE2EEKey("p1", []byte("wrapped-key-1"))
+	if key.KeyIndex != 1 {
+		t.Errorf("Expected key index 1, got %d", key.KeyIndex)
+	}
+	room.SetE2EEKey("p2", []byte("wrapped-key-0"))
+	if len(room.GetE2EEKeys()) != 2 {
+		t.Errorf("Expected 2 keys, got %d", len(room.GetE2EEKeys()))
+	}
+
+	room.RemoveParticipant("p2")
+	if len(room.GetE2EEKeys()) != 1 {
+		t.Error("Expected leaver's key dropped")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ratchets) != 3 {
+		t.Fatalf("Expected 3 ratchets, got %+v", ratchets)
+	}
+	for _, ratchet := range ratchets {
+		if ratchet.Epoch == 3 && (ratchet.Reason != E2EERatchetLeave || ratchet.ParticipantID != "p2") {
+			t.Errorf("Expected epoch 3 ratchet for p2 leaving, got %+v", ratchet)
+		}
+	}
+}
+
+func TestRoomClientStats(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	room := NewRoom(&CreateRoomRequest{Name: "Stats"}, "user-1", log, NewEventBus())
+	room.AddParticipant(NewParticipant("p1", "user-1", "Alice", RoleSpeaker))
+	room.AddParticipant(NewParticipant("p2", "user-2", "Bob", RoleSpeaker))
+
+	if err := room.ReportClientStats("p1", ClientStats{DecodedFPS: 30}); err != ErrInvalidClientStats {
+		t.Errorf("Expected ErrInvalidClientStats without a stream, got %v", err)
+	}
+	if err := room.ReportClientStats("p9", ClientStats{StreamID: "s1"}); err != ErrParticipantNotFound {
+		t.Errorf("Expected ErrParticipantNotFound, got %v", err)
+	}
+
+	room.ReportClientStats("p1", ClientStats{StreamID: "s1", DecodedFPS: 30, FreezeCount: 0, BufferMs: 200, EstimatedBitrate: 2000000})
+	room.ReportClientStats("p2", ClientStats{StreamID: "s1", DecodedFPS: 20, FreezeCount: 3, BufferMs: 100, EstimatedBitrate: 1000000})
+
+	qoe := room.GetStreamQoE("s1")
+	if qoe.Viewers != 2 || qoe.AvgDecodedFPS != 25 || qoe.TotalFreezes != 3 || qoe.AvgBufferMs != 150 || qoe.AvgEstimatedBitrate != 1500000 {
+		t.Errorf("Unexpected QoE: %+v", qoe)
+	}
+
+	room.RemoveParticipant("p2")
+	if qoe := room.GetStreamQoE("s1"); qoe.Viewers != 1 {
+		t.Errorf("Expected leaver's stats dropped, got %d viewers", qoe.Viewers)
+	}
+}
+
+func TestRoomDeliveryReceipts(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	eventBus := NewEventBus()
+
+	plain := NewRoom(&CreateRoomRequest{Name: "Public"}, "user-1", log, eventBus)
+	plain.AddParticipant(NewParticipant("p1", "user-1", "Alice", RoleHost))
+	if err := plain.TrackMessage("m1", "p1", nil); err != ErrDeliveryReceiptsDisabled {
+		t.Errorf("Expected ErrDeliveryReceiptsDisabled, got %v", err)
+	}
+
+	room := NewRoom(&CreateRoomRequest{Name: "Coaching", DeliveryReceipts: true}, "user-1", log, eventBus)
+	room.AddParticipant(NewParticipant("p1", "user-1", "Alice", RoleHost))
+	room.AddParticipant(NewParticipant("p2", "user-2", "Bob", RoleSpeaker))
+	room.AddParticipant(NewParticipant("p3", "user-3", "Carol", RoleSpeaker))
+
+	if err := room.TrackMessage("m1", "p1", nil); err != nil {
+		t.Fatalf("Failed to track message: %v", err)
+	}
+	if err := room.TrackMessage("m2", "p1", []string{"p2"}); err != nil {
+		t.Fatalf("Failed to track direct message: %v", err)
+	}
+
+	// Another sender cannot take over a tracked message
+	if err := room.TrackMessage("m1", "p3", nil); err != ErrMessageExists {
+		t.Errorf("Expected ErrMessageExists, got %v", err)
+	}
+	if state, _ := room.GetDeliveryState("m1"); state.SenderID != "p1" {
+		t.Errorf("Expected m1 to stay p1's message, got sender %s", state.SenderID)
+	}
+
+	state, _ := room.GetDeliveryState("m1")
+	if len(state.Recipients) != 2 || state.Recipients[0].ParticipantID != "p2" || state.Recipients[0].Status != DeliverySent {
+		t.Fatalf("Expected p2 and p3 to be sent m1, got %+v", state.Recipients)
+	}
+
+	// Reading implies delivery, and receipts never go backwards
+	receipt, err := room.AckDelivery("m1", "p2", DeliveryRead)
+	if err != nil || receipt == nil || receipt.SenderID != "p1" {
+		t.Fatalf("Expected a read receipt for p1, got %+v (%v)", receipt, err)
+	}
+	if receipt, _ := room.AckDelivery("m1", "p2", DeliveryDelivered); receipt != nil {
+		t.Errorf("Expected no receipt for an older status, got %+v", receipt)
+	}
+	state, _ = room.GetDeliveryState("m1")
+	if r := state.Recipients[0]; r.Status != DeliveryRead || r.DeliveredAt.IsZero() || r.ReadAt.IsZero() {
+		t.Errorf("Expected p2 to have read m1, got %+v", r)
+	}
+
+	if _, err := room.AckDelivery("m2", "p3", DeliveryDelivered); err != ErrMessageNotFound {
+		t.Errorf("Expected ErrMessageNotFound for a non-recipient, got %v", err)
+	}
+	if _, err := room.AckDelivery("m2", "p2", DeliverySent); err != ErrInvalidDeliveryStatus {
+		t.Errorf("Expected ErrInvalidDeliveryStatus, got %v", err)
+	}
+
+	// Typing indicators only report changes
+	if indicator, _ := room.SetTyping("p2", true); indicator == nil || !indicator.Typing {
+		t.Errorf("Expected p2 to start typing, got %+v", indicator)
+	}
+	if indicator, _ := room.SetTyping("p2", true); indicator != nil {
+		t.Errorf("Expected no change while typing, got %+v", indicator)
+	}
+	if typing := room.Typing(); len(typing) != 1 || typing[0] != "p2" {
+		t.Errorf("Expected p2 typing, got %v", typing)
+	}
+	room.SetTyping("p2", false)
+	if typing := room.Typing(); len(typing) != 0 {
+		t.Errorf("Expected nobody typing, got %v", typing)
+	}
+}
+
+func TestRoomSFUMarksE2EEStreams(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	sfu := webrtc.NewSFU(webrtc.DefaultSFUConfig(), log)
+	defer sfu.Close()
+
+	for _, streamID := range []string{"secure-stream", "plain-stream"} {
+		if err := sfu.CreateStream(streamID, streamID); err != nil {
+			t.Fatalf("Failed to create stream: %v", err)
+		}
+	}
+	if _, err := sfu.AddPublisher(context.Background(), "secure-stream", "alice"); err != nil {
+		t.Fatalf("Failed to add publisher: %v", err)
+	}
+	if _, err := sfu.AddPublisher(context.Background(), "plain-stream", "bob"); err != nil {
+		t.Fatalf("Failed to add publisher: %v", err)
+	}
+
+	secure := NewRoom(&CreateRoomRequest{Name: "Secure", E2EE: true}, "user-1", log, NewEventBus())
+	alice := NewParticipant("alice", "user-1", "Alice", RoleHost)
+	alice.CanPublish = true
+	secure.AddParticipant(alice)
+	if _, err := NewRoomSFU(secure, sfu, log).PublishTrack("alice", "video-1", "video", "camera"); err != nil {
+		t.Fatalf("Failed to publish track: %v", err)
+	}
+
+	plain := NewRoom(&CreateRoomRequest{Name: "Plain"}, "user-2", log, NewEventBus())
+	bob := NewParticipant("bob", "user-2", "Bob", RoleHost)
+	bob.CanPublish = true
+	plain.AddParticipant(bob)
+	if _, err := NewRoomSFU(plain, sfu, log).PublishTrack("bob", "video-2", "video", "camera"); err != nil {
+		t.Fatalf("Failed to publish track: %v", err)
+	}
+
+	stream, _ := sfu.GetStream("secure-stream")
+	if !stream.IsEncrypted() {
+		t.Error("Expected the stream published in an E2EE room to be encrypted")
+	}
+	stream, _ = sfu.GetStream("plain-stream")
+	if stream.IsEncrypted() {
+		t.Error("Expected the stream published in a plain room not to be encrypted")
+	}
+}
@@ -0,0 +1,135 @@
+package room
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// ScheduleRoom creates a room that rejects joins until startAt and is deleted
+// automatically at endAt. A zero endAt keeps the room open until deleted.
+// A startAt in the past opens the room immediately.
+func (rm *RoomManager) ScheduleRoom(req *CreateRoomRequest, createdBy string, startAt, endAt time.Time) (*Room, error) {
+	if startAt.IsZero() {
+		return nil, fmt.Errorf("%w: start time is required", ErrInvalidRoomRequest)
+	}
+
+	if !endAt.IsZero() && !endAt.After(startAt) {
+		return nil, fmt.Errorf("%w: end time must be after start time", ErrInvalidRoomRequest)
+	}
+
+	// The room rejects joins from the moment it becomes visible
+	room, err := rm.createRoom(req, createdBy, func(room *Room) {
+		room.StartAt = startAt
+		room.EndAt = endAt
+		room.isScheduled = time.Now().Before(startAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Timers start once the room is registered, so the end timer finds it
+	room.mu.Lock()
+	if !room.isClosed {
+		if room.isScheduled {
+			room.startTimer = time.AfterFunc(time.Until(startAt), room.open)
+		}
+
+		if !endAt.IsZero() {
+			room.endTimer = time.AfterFunc(time.Until(endAt), func() {
+				rm.endScheduledRoom(room.ID)
+			})
+		}
+	}
+	scheduled := room.isScheduled
+	room.mu.Unlock()
+
+	rm.logger.Info("Room scheduled",
+		logger.Field{Key: "room_id", Value: room.ID},
+		logger.Field{Key: "start_at", Value: startAt.String()},
+		logger.Field{Key: "end_at", Value: endAt.String()},
+	)
+
+	// Publish event
+	if scheduled {
+		rm.eventBus.Publish(createEvent(EventRoomScheduled, room.ID, room))
+	} else {
+		rm.eventBus.Publish(createEvent(EventRoomOpened, room.ID, room))
+	}
+
+	return room, nil
+}
+
+// endScheduledRoom publishes the end event and deletes the room
+func (rm *RoomManager) endScheduledRoom(roomID string) {
+	if _, err := rm.GetRoom(roomID); err != nil {
+		return
+	}
+
+	rm.logger.Info("Scheduled room ended",
+		logger.Field{Key: "room_id", Value: roomID},
+	)
+
+	rm.eventBus.Publish(createEvent(EventRoomEnded, roomID, nil))
+
+	rm.DeleteRoom(roomID)
+}
+
+// OnRoomOpened registers a callback for scheduled room opened events
+func (rm *RoomManager) OnRoomOpened(callback EventCallback) {
+	rm.eventBus.Subscribe(EventRoomOpened, callback)
+}
+
+// OnRoomEnded registers a callback for scheduled room ended events
+func (rm *RoomManager) OnRoomEnded(callback EventCallback) {
+	rm.eventBus.Subscribe(EventRoomEnded, callback)
+}
+
+// open allows joins on a scheduled room
+func (r *Room) open() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.isClosed || !r.isScheduled {
+		return
+	}
+
+	r.isScheduled = false
+	r.startTimer = nil
+
+	// The empty timeout counts from opening, not from creation
+	if len(r.participants) == 0 {
+		r.emptySince = time.Now()
+	}
+
+	r.logger.Info("Scheduled room opened",
+		logger.Field{Key: "room_id", Value: r.ID},
+	)
+
+	// Publish event
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventRoomOpened, r.ID, r))
+	}
+}
+
+// IsScheduled returns whether the room is still waiting for its start time
+func (r *Room) IsScheduled() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.isScheduled
+}
+
+// stopScheduleTimers stops pending schedule transitions. Caller must hold r.mu.
+func (r *Room) stopScheduleTimers() {
+	if r.startTimer != nil {
+		r.startTimer.Stop()
+		r.startTimer = nil
+	}
+
+	if r.endTimer != nil {
+		r.endTimer.Stop()
+		r.endTimer = nil
+	}
+}
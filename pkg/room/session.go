@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aminofox/zenlive/pkg/clock"
 	"github.com/aminofox/zenlive/pkg/errors"
 	"github.com/aminofox/zenlive/pkg/logger"
 	"github.com/aminofox/zenlive/pkg/streaming/webrtc"
@@ -69,6 +70,9 @@ type SessionManager struct {
 
 	// sessions maps user ID to their session
 	sessions map[string]*UserSession
+
+	// clock supplies activity timestamps
+	clock clock.Clock
 }
 
 // SessionManagerConfig contains configuration for the session manager
@@ -107,9 +111,17 @@ func NewSessionManager(config SessionManagerConfig, log logger.Logger) *SessionM
 		config:   config,
 		logger:   log,
 		sessions: make(map[string]*UserSession),
+		clock:    clock.Real(),
 	}
 }
 
+// SetClock sets the clock used for session activity and timeouts
+func (sm *SessionManager) SetClock(c clock.Clock) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.clock = clock.OrReal(c)
+}
+
 // CreateSession creates a new user session
 func (sm *SessionManager) CreateSession(userID string) *UserSession {
 	sm.mu.Lock()
@@ -118,8 +130,8 @@ func (sm *SessionManager) CreateSession(userID string) *UserSession {
 	session := &UserSession{
 		UserID:       userID,
 		ActiveRooms:  make(map[string]*RoomParticipation),
-		CreatedAt:    time.Now(),
-		LastActivity: time.Now(),
+		CreatedAt:    sm.clock.Now(),
+		LastActivity: sm.clock.Now(),
 	}
 
 	sm.sessions[userID] = session
@@ -154,8 +166,8 @@ func (sm *SessionManager) JoinRoom(userID, roomID, participantID string) error {
 		session = &UserSession{
 			UserID:       userID,
 			ActiveRooms:  make(map[string]*RoomParticipation),
-			CreatedAt:    time.Now(),
-			LastActivity: time.Now(),
+			CreatedAt:    sm.clock.Now(),
+			LastActivity: sm.clock.Now(),
 		}
 		sm.sessions[userID] = session
 	}
@@ -170,11 +182,11 @@ func (sm *SessionManager) JoinRoom(userID, roomID, participantID string) error {
 		RoomID:        roomID,
 		ParticipantID: participantID,
 		Subscribers:   make(map[string]*webrtc.Subscriber),
-		JoinedAt:      time.Now(),
+		JoinedAt:      sm.clock.Now(),
 	}
 
 	session.ActiveRooms[roomID] = participation
-	session.LastActivity = time.Now()
+	session.LastActivity = sm.clock.Now()
 
 	// Reallocate bandwidth across all rooms
 	sm.reallocateBandwidth(session)
@@ -213,7 +225,7 @@ func (sm *SessionManager) LeaveRoom(userID, roomID string) error {
 	}
 
 	delete(session.ActiveRooms, roomID)
-	session.LastActivity = time.Now()
+	session.LastActivity = sm.clock.Now()
 
 	// Reallocate bandwidth
 	sm.reallocateBandwidth(session)
@@ -243,7 +255,7 @@ func (sm *SessionManager) AddTrack(userID, roomID, trackID string) error {
 	}
 
 	session.TrackCount++
-	session.LastActivity = time.Now()
+	session.LastActivity = sm.clock.Now()
 
 	sm.logger.Debug("Added track to session",
 		logger.String("user_id", userID),
@@ -268,7 +280,7 @@ func (sm *SessionManager) RemoveTrack(userID, trackID string) error {
 	if session.TrackCount > 0 {
 		session.TrackCount--
 	}
-	session.LastActivity = time.Now()
+	session.LastActivity = sm.clock.Now()
 
 	sm.logger.Debug("Removed track from session",
 		logger.String("user_id", userID),
@@ -342,7 +354,7 @@ func (sm *SessionManager) CleanupInactiveSessions() int {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	now := time.Now()
+	now := sm.clock.Now()
 	cleaned := 0
 
 	for userID, session := range sm.sessions {
@@ -53,6 +53,27 @@ func NewRoomSFU(room *Room, sfu *webrtc.SFU, log logger.Logger) *RoomSFU {
 		cancel:      cancel,
 	}
 
+	// Link to the room so its resources are released when the room ends
+	room.mu.Lock()
+	room.sfu = rs
+	room.mu.Unlock()
+
+	// Drop subscriptions that a new rule denies
+	if room.eventBus != nil {
+		room.eventBus.Subscribe(EventSubscribeRuleUpdated, func(event *RoomEvent) {
+			if rule, ok := event.Data.(SubscribeRule); ok && event.RoomID == room.ID && !rule.Allow {
+				rs.enforceSubscribeRule(rule.SubscriberID)
+			}
+		})
+
+		// Adapt forwarded layers to the bandwidth clients report
+		room.eventBus.Subscribe(EventClientStatsReported, func(event *RoomEvent) {
+			if stats, ok := event.Data.(ClientStats); ok && event.RoomID == room.ID && stats.EstimatedBitrate > 0 {
+				rs.sfu.BitrateAllocator().Allocate(stats.ParticipantID, stats.EstimatedBitrate)
+			}
+		})
+	}
+
 	// Start cleanup goroutine
 	go rs.startCleanup()
 
@@ -99,12 +120,38 @@ func (rs *RoomSFU) PublishTrack(participantID, trackID, kind, label string) (str
 		logger.String("kind", kind),
 	)
 
+	rs.markEncrypted(participantID)
+
 	// Auto-subscribe other participants to this track (without holding lock)
 	rs.autoSubscribeToNewTrack(participantID, trackID)
 
 	return trackID, nil
 }
 
+// markEncrypted flags the SFU streams a participant publishes in an E2EE
+// room as encrypted, so the SFU forwards their media untouched instead of
+// transcoding payloads it cannot decode
+func (rs *RoomSFU) markEncrypted(participantID string) {
+	if !rs.room.E2EE {
+		return
+	}
+
+	for _, stream := range rs.sfu.GetStreams() {
+		publisher := stream.GetPublisher()
+		if publisher == nil || publisher.GetID() != participantID || stream.IsEncrypted() {
+			continue
+		}
+
+		if err := rs.sfu.SetStreamEncrypted(stream.ID, true); err != nil {
+			rs.logger.Warn("Failed to mark stream as encrypted",
+				logger.String("room_id", rs.room.ID),
+				logger.String("stream_id", stream.ID),
+				logger.Err(err),
+			)
+		}
+	}
+}
+
 // UnpublishTrack unpublishes a media track
 func (rs *RoomSFU) UnpublishTrack(participantID, trackID string) error {
 	rs.mu.Lock()
@@ -167,6 +214,11 @@ func (rs *RoomSFU) autoSubscribeToNewTrack(publisherID, trackID string) {
 			continue
 		}
 
+		// Respect per-publisher subscribe rules
+		if !rs.room.CanSubscribeTo(participantID, publisherID) {
+			continue
+		}
+
 		rs.logger.Debug("Auto-subscribing participant to track",
 			logger.String("subscriber_id", participant.ID),
 			logger.String("publisher_id", publisherID),
@@ -194,6 +246,11 @@ func (rs *RoomSFU) autoSubscribeToExistingTracks(participantID string) {
 			continue
 		}
 
+		// Respect per-publisher subscribe rules
+		if !rs.room.CanSubscribeTo(participantID, track.ParticipantID) {
+			continue
+		}
+
 		rs.logger.Debug("Auto-subscribing new participant to existing track",
 			logger.String("subscriber_id", participantID),
 			logger.String("publisher_id", track.ParticipantID),
@@ -202,6 +259,29 @@ func (rs *RoomSFU) autoSubscribeToExistingTracks(participantID string) {
 	}
 }
 
+// enforceSubscribeRule stops a participant's subscriptions that the room's
+// subscribe rules no longer allow
+func (rs *RoomSFU) enforceSubscribeRule(subscriberID string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	subs := rs.subscribers[subscriberID]
+	for publisherID, sub := range subs {
+		if rs.room.CanSubscribeTo(subscriberID, publisherID) {
+			continue
+		}
+
+		sub.Stop()
+		delete(subs, publisherID)
+
+		rs.logger.Info("Subscription removed by subscribe rule",
+			logger.String("room_id", rs.room.ID),
+			logger.String("subscriber_id", subscriberID),
+			logger.String("publisher_id", publisherID),
+		)
+	}
+}
+
 // OnParticipantJoined should be called when a participant joins the room
 func (rs *RoomSFU) OnParticipantJoined(participantID string) {
 	rs.logger.Info("Participant joined, setting up WebRTC",
@@ -0,0 +1,292 @@
+package room
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidSharedStateKey is returned for an empty shared state key
+	ErrInvalidSharedStateKey = errors.New("invalid shared state key")
+)
+
+// SharedEntry is one key of a shared state map
+type SharedEntry struct {
+	// Key is the entry key
+	Key string `json:"key"`
+	// Value is the entry value (nil when Deleted)
+	Value interface{} `json:"value,omitempty"`
+	// Clock is the Lamport timestamp of the write that produced the entry
+	Clock uint64 `json:"clock"`
+	// UpdatedBy is the participant that wrote the entry (empty = server)
+	UpdatedBy string `json:"updated_by,omitempty"`
+	// UpdatedAt is when the server accepted the write
+	UpdatedAt time.Time `json:"updated_at"`
+	// Deleted marks a tombstone left by a delete
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// SharedStateOp is a single write to a shared state map
+type SharedStateOp struct {
+	// Key is the entry to write
+	Key string `json:"key"`
+	// Value is the new value, ignored when Delete is set
+	Value interface{} `json:"value,omitempty"`
+	// Delete removes the entry
+	Delete bool `json:"delete,omitempty"`
+	// Clock is the writer's Lamport timestamp; 0 lets the server assign the
+	// next one so the write always wins
+	Clock uint64 `json:"clock,omitempty"`
+}
+
+// SharedStateDelta carries the entries changed by one Apply call. It is the
+// data of shared_state.updated events.
+type SharedStateDelta struct {
+	// Name is the shared state name
+	Name string `json:"name"`
+	// Clock is the state's clock after the change
+	Clock uint64 `json:"clock"`
+	// Entries are the accepted writes, tombstones included
+	Entries []SharedEntry `json:"entries"`
+}
+
+// SharedStateSnapshot is the full content of a shared state, delivered to
+// late joiners
+type SharedStateSnapshot struct {
+	// Name is the shared state name
+	Name string `json:"name"`
+	// Clock is the state's current clock
+	Clock uint64 `json:"clock"`
+	// Entries are the live entries sorted by key
+	Entries []SharedEntry `json:"entries"`
+}
+
+// SharedState is a last-write-wins map synchronized across a room's
+// participants. Writes are ordered by Lamport clock with the writer's
+// participant ID as tie-breaker, so concurrent writers converge on the same
+// value and stale writes are discarded. Deletes leave tombstones so an older
+// write cannot resurrect a deleted key.
+type SharedState struct {
+	// Name identifies the state within its room
+	Name string
+
+	// roomID is the owning room
+	roomID string
+	// clock is the highest Lamport timestamp seen
+	clock uint64
+	// entries stores entries and tombstones by key
+	entries map[string]*SharedEntry
+	// eventBus for publishing deltas
+	eventBus *EventBus
+	// mu protects concurrent access
+	mu sync.RWMutex
+}
+
+// newSharedState creates an empty shared state
+func newSharedState(roomID, name string, eventBus *EventBus) *SharedState {
+	return &SharedState{
+		Name:     name,
+		roomID:   roomID,
+		entries:  make(map[string]*SharedEntry),
+		eventBus: eventBus,
+	}
+}
+
+// SharedState returns a room's shared state by name
+func (rm *RoomManager) SharedState(roomID, name string) (*SharedState, error) {
+	room, err := rm.GetRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	return room.SharedState(name), nil
+}
+
+// OnSharedStateUpdated registers a callback for shared state deltas
+func (rm *RoomManager) OnSharedStateUpdated(callback EventCallback) {
+	rm.eventBus.Subscribe(EventSharedStateUpdated, callback)
+}
+
+// SharedState returns the named shared state, creating it on first use
+func (r *Room) SharedState(name string) *SharedState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.sharedStates == nil {
+		r.sharedStates = make(map[string]*SharedState)
+	}
+
+	state, exists := r.sharedStates[name]
+	if !exists {
+		state = newSharedState(r.ID, name, r.eventBus)
+		r.sharedStates[name] = state
+	}
+
+	return state
+}
+
+// SharedStateSnapshots returns snapshots of all the room's shared states,
+// sorted by name, for delivery to a joining participant
+func (r *Room) SharedStateSnapshots() []SharedStateSnapshot {
+	r.mu.RLock()
+	states := make([]*SharedState, 0, len(r.sharedStates))
+	for _, state := range r.sharedStates {
+		states = append(states, state)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+
+	snapshots := make([]SharedStateSnapshot, 0, len(states))
+	for _, state := range states {
+		snapshots = append(snapshots, state.Snapshot())
+	}
+
+	return snapshots
+}
+
+// UpdateSharedState applies a participant's writes to a shared state. The
+// participant must be allowed to publish data.
+func (r *Room) UpdateSharedState(participantID, name string, ops []SharedStateOp) (*SharedStateDelta, error) {
+	participant, err := r.GetParticipant(participantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !participant.GetPermissions().CanPublishData {
+		return nil, ErrUnauthorized
+	}
+
+	return r.SharedState(name).Apply(participantID, ops)
+}
+
+// Apply merges writes into the state and publishes the accepted ones as a
+// shared_state.updated delta. Writes that lose to an existing entry are
+// dropped; the returned delta is nil when nothing changed.
+func (s *SharedState) Apply(writer string, ops []SharedStateOp) (*SharedStateDelta, error) {
+	for _, op := range ops {
+		if op.Key == "" {
+			return nil, ErrInvalidSharedStateKey
+		}
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	accepted := make([]SharedEntry, 0, len(ops))
+
+	for _, op := range ops {
+		clock := op.Clock
+		if clock == 0 {
+			clock = s.clock + 1
+		}
+		if clock > s.clock {
+			s.clock = clock
+		}
+
+		if existing, exists := s.entries[op.Key]; exists && !wins(clock, writer, existing) {
+			continue
+		}
+
+		entry := &SharedEntry{
+			Key:       op.Key,
+			Clock:     clock,
+			UpdatedBy: writer,
+			UpdatedAt: now,
+			Deleted:   op.Delete,
+		}
+		if !op.Delete {
+			entry.Value = op.Value
+		}
+
+		s.entries[op.Key] = entry
+		accepted = append(accepted, *entry)
+	}
+
+	if len(accepted) == 0 {
+		s.mu.Unlock()
+		return nil, nil
+	}
+
+	delta := &SharedStateDelta{
+		Name:    s.Name,
+		Clock:   s.clock,
+		Entries: accepted,
+	}
+	s.mu.Unlock()
+
+	// Publish event
+	if s.eventBus != nil {
+		s.eventBus.Publish(createEvent(EventSharedStateUpdated, s.roomID, delta))
+	}
+
+	return delta, nil
+}
+
+// Set writes a single key with the next server clock
+func (s *SharedState) Set(key string, value interface{}) error {
+	_, err := s.Apply("", []SharedStateOp{{Key: key, Value: value}})
+	return err
+}
+
+// Delete removes a single key with the next server clock
+func (s *SharedState) Delete(key string) error {
+	_, err := s.Apply("", []SharedStateOp{{Key: key, Delete: true}})
+	return err
+}
+
+// Get returns the value of a live entry
+func (s *SharedState) Get(key string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, exists := s.entries[key]
+	if !exists || entry.Deleted {
+		return nil, false
+	}
+
+	return entry.Value, true
+}
+
+// Clock returns the highest Lamport timestamp the state has seen
+func (s *SharedState) Clock() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.clock
+}
+
+// Snapshot returns the live entries of the state
+func (s *SharedState) Snapshot() SharedStateSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]SharedEntry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		if !entry.Deleted {
+			entries = append(entries, *entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	return SharedStateSnapshot{
+		Name:    s.Name,
+		Clock:   s.clock,
+		Entries: entries,
+	}
+}
+
+// wins reports whether a write at clock by writer supersedes existing
+func wins(clock uint64, writer string, existing *SharedEntry) bool {
+	if clock != existing.Clock {
+		return clock > existing.Clock
+	}
+
+	return writer > existing.UpdatedBy
+}
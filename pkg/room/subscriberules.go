@@ -0,0 +1,194 @@
+package room
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// SubscribeRuleAll as a publisher ID sets a subscriber's default rule. A
+// default deny combined with specific allows forms an allow list.
+const SubscribeRuleAll = "*"
+
+var (
+	// ErrSubscribeDenied is returned when a subscribe rule blocks a subscription
+	ErrSubscribeDenied = errors.New("subscription denied by room rule")
+)
+
+// SubscribeRule is an explicit allow or deny for one subscriber and publisher
+type SubscribeRule struct {
+	// SubscriberID is the participant the rule restricts
+	SubscriberID string `json:"subscriber_id"`
+	// PublisherID is the participant whose tracks are covered (SubscribeRuleAll = everyone)
+	PublisherID string `json:"publisher_id"`
+	// Allow permits the subscription when true and denies it when false
+	Allow bool `json:"allow"`
+}
+
+// SubscribeDenial describes a subscription attempt blocked by a rule. It is
+// the data of subscription.denied events.
+type SubscribeDenial struct {
+	// SubscriberID is the participant that tried to subscribe
+	SubscriberID string `json:"subscriber_id"`
+	// PublisherID is the participant whose track was requested
+	PublisherID string `json:"publisher_id"`
+	// TrackID is the requested track (empty for participant-level checks)
+	TrackID string `json:"track_id,omitempty"`
+	// Timestamp is when the attempt was denied
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SetSubscribeRule allows or denies subscriberID subscribing to publisherID's
+// tracks. Rules for a specific publisher take precedence over the
+// subscriber's SubscribeRuleAll rule; with no rule, subscriptions are allowed.
+func (r *Room) SetSubscribeRule(subscriberID, publisherID string, allow bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.subscribeRules == nil {
+		r.subscribeRules = make(map[string]map[string]bool)
+	}
+	if r.subscribeRules[subscriberID] == nil {
+		r.subscribeRules[subscriberID] = make(map[string]bool)
+	}
+	r.subscribeRules[subscriberID][publisherID] = allow
+
+	r.logger.Info("Subscribe rule updated",
+		logger.Field{Key: "room_id", Value: r.ID},
+		logger.Field{Key: "subscriber_id", Value: subscriberID},
+		logger.Field{Key: "publisher_id", Value: publisherID},
+		logger.Field{Key: "allow", Value: allow},
+	)
+
+	// Publish event
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventSubscribeRuleUpdated, r.ID, SubscribeRule{
+			SubscriberID: subscriberID,
+			PublisherID:  publisherID,
+			Allow:        allow,
+		}))
+	}
+}
+
+// ClearSubscribeRule removes an explicit rule, restoring the default
+func (r *Room) ClearSubscribeRule(subscriberID, publisherID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rules, exists := r.subscribeRules[subscriberID]
+	if !exists {
+		return
+	}
+
+	delete(rules, publisherID)
+	if len(rules) == 0 {
+		delete(r.subscribeRules, subscriberID)
+	}
+}
+
+// GetSubscribeRules returns the explicit rules for a subscriber
+func (r *Room) GetSubscribeRules(subscriberID string) []SubscribeRule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rules := make([]SubscribeRule, 0, len(r.subscribeRules[subscriberID]))
+	for publisherID, allow := range r.subscribeRules[subscriberID] {
+		rules = append(rules, SubscribeRule{
+			SubscriberID: subscriberID,
+			PublisherID:  publisherID,
+			Allow:        allow,
+		})
+	}
+
+	return rules
+}
+
+// CanSubscribeTo reports whether the rules let subscriberID receive
+// publisherID's tracks. It does not check participant permissions.
+func (r *Room) CanSubscribeTo(subscriberID, publisherID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.subscribeAllowed(subscriberID, publisherID)
+}
+
+// subscribeAllowed evaluates the subscribe rules. Caller must hold r.mu.
+func (r *Room) subscribeAllowed(subscriberID, publisherID string) bool {
+	rules, exists := r.subscribeRules[subscriberID]
+	if !exists {
+		return true
+	}
+
+	if allow, exists := rules[publisherID]; exists {
+		return allow
+	}
+
+	if allow, exists := rules[SubscribeRuleAll]; exists {
+		return allow
+	}
+
+	return true
+}
+
+// AuthorizeSubscribe checks that a participant may subscribe to a track of
+// another participant. Attempts blocked by a subscribe rule are published as
+// subscription.denied events for moderators.
+func (r *Room) AuthorizeSubscribe(subscriberID, publisherID, trackID string) error {
+	r.mu.RLock()
+	subscriber, exists := r.participants[subscriberID]
+	_, publisherExists := r.participants[publisherID]
+	_, waiting := r.lobby[subscriberID]
+	allowed := r.subscribeAllowed(subscriberID, publisherID)
+	r.mu.RUnlock()
+
+	if waiting {
+		return ErrParticipantWaiting
+	}
+
+	if !exists || !publisherExists {
+		return ErrParticipantNotFound
+	}
+
+	if !subscriber.GetPermissions().CanSubscribe {
+		return ErrUnauthorized
+	}
+
+	if allowed {
+		return nil
+	}
+
+	r.logger.Warn("Subscription denied by rule",
+		logger.Field{Key: "room_id", Value: r.ID},
+		logger.Field{Key: "subscriber_id", Value: subscriberID},
+		logger.Field{Key: "publisher_id", Value: publisherID},
+		logger.Field{Key: "track_id", Value: trackID},
+	)
+
+	// Publish event
+	if r.eventBus != nil {
+		r.eventBus.Publish(createEvent(EventSubscribeDenied, r.ID, SubscribeDenial{
+			SubscriberID: subscriberID,
+			PublisherID:  publisherID,
+			TrackID:      trackID,
+			Timestamp:    time.Now(),
+		}))
+	}
+
+	return ErrSubscribeDenied
+}
+
+// Moderators returns the room's hosts, who are notified of denied subscriptions
+func (r *Room) Moderators() []*Participant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var moderators []*Participant
+	for _, p := range r.participants {
+		if p.Role == RoleHost {
+			moderators = append(moderators, p)
+		}
+	}
+
+	return moderators
+}
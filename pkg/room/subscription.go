@@ -1,8 +1,11 @@
 package room
 
 import (
+	"context"
 	"sync"
 	"time"
+
+	"github.com/aminofox/zenlive/pkg/flags"
 )
 
 // QualityLevel represents the quality level for adaptive bitrate
@@ -185,6 +188,10 @@ type SubscriptionManager struct {
 	// simulcastConfig is the simulcast configuration for the room
 	simulcastConfig SimulcastConfig
 
+	// flags gates simulcast per room (nil = config only)
+	flags  *flags.Flags
+	roomID string
+
 	// mu protects concurrent access
 	mu sync.RWMutex
 }
@@ -197,6 +204,16 @@ func NewSubscriptionManager(config SimulcastConfig) *SubscriptionManager {
 	}
 }
 
+// SetFlags gates simulcast layer selection behind the simulcast feature flag,
+// evaluated for the given room
+func (sm *SubscriptionManager) SetFlags(f *flags.Flags, roomID string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.flags = f
+	sm.roomID = roomID
+}
+
 // Subscribe creates a new subscription
 func (sm *SubscriptionManager) Subscribe(subscriberID, publisherID, trackID string, quality QualityLevel) (*Subscription, error) {
 	sm.mu.Lock()
@@ -299,6 +316,10 @@ func (sm *SubscriptionManager) SelectLayer(availableBandwidthBps int) QualityLev
 		return QualityHigh
 	}
 
+	if sm.flags != nil && !sm.flags.Enabled(context.Background(), flags.FlagSimulcast, flags.Context{StreamID: sm.roomID}) {
+		return QualityHigh
+	}
+
 	// Select highest layer that fits within bandwidth
 	selectedQuality := QualityLow
 
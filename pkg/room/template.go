@@ -0,0 +1,157 @@
+package room
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/types"
+)
+
+var (
+	// ErrTemplateNotFound is returned when a room template doesn't exist
+	ErrTemplateNotFound = errors.New("room template not found")
+	// ErrTemplateExists is returned when trying to create a duplicate room template
+	ErrTemplateExists = errors.New("room template already exists")
+)
+
+// CreateRoomTemplate stores reusable room settings under name
+func (rm *RoomManager) CreateRoomTemplate(name string, req CreateRoomRequest) error {
+	if name == "" {
+		return fmt.Errorf("%w: template name is required", ErrInvalidRoomRequest)
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if _, exists := rm.templates[name]; exists {
+		return ErrTemplateExists
+	}
+
+	rm.templates[name] = cloneCreateRoomRequest(&req)
+
+	rm.logger.Info("Room template created",
+		logger.Field{Key: "template", Value: name},
+	)
+
+	return nil
+}
+
+// GetRoomTemplate returns a copy of the settings stored under name
+func (rm *RoomManager) GetRoomTemplate(name string) (*CreateRoomRequest, error) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	template, exists := rm.templates[name]
+	if !exists {
+		return nil, ErrTemplateNotFound
+	}
+
+	return cloneCreateRoomRequest(template), nil
+}
+
+// DeleteRoomTemplate removes a room template
+func (rm *RoomManager) DeleteRoomTemplate(name string) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if _, exists := rm.templates[name]; !exists {
+		return ErrTemplateNotFound
+	}
+
+	delete(rm.templates, name)
+
+	return nil
+}
+
+// CreateRoomFromTemplate creates a room from a template. Non-zero fields in
+// overrides replace the template values; override metadata is merged key by key.
+func (rm *RoomManager) CreateRoomFromTemplate(templateName string, overrides *CreateRoomRequest, createdBy string) (*Room, error) {
+	req, err := rm.GetRoomTemplate(templateName)
+	if err != nil {
+		return nil, err
+	}
+
+	if overrides != nil {
+		if overrides.Name != "" {
+			req.Name = overrides.Name
+		}
+		if overrides.MaxParticipants != 0 {
+			req.MaxParticipants = overrides.MaxParticipants
+		}
+		if overrides.EmptyTimeout != 0 {
+			req.EmptyTimeout = overrides.EmptyTimeout
+		}
+		if overrides.Tenant != "" {
+			req.Tenant = overrides.Tenant
+		}
+		if overrides.DefaultPermissions != nil {
+			perms := *overrides.DefaultPermissions
+			req.DefaultPermissions = &perms
+		}
+		for key, value := range overrides.Metadata {
+			if req.Metadata == nil {
+				req.Metadata = make(map[string]interface{})
+			}
+			req.Metadata[key] = value
+		}
+	}
+
+	return rm.CreateRoom(req, createdBy)
+}
+
+// CloneRoom creates a new, empty room with the same settings as an existing one
+func (rm *RoomManager) CloneRoom(roomID string) (*Room, error) {
+	source, err := rm.GetRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	source.mu.RLock()
+	req := cloneCreateRoomRequest(&CreateRoomRequest{
+		Name:               source.Name,
+		MaxParticipants:    source.MaxParticipants,
+		EmptyTimeout:       source.EmptyTimeout,
+		Metadata:           source.Metadata,
+		DefaultPermissions: source.DefaultPermissions,
+		LobbyEnabled:       source.LobbyEnabled,
+		E2EE:               source.E2EE,
+		DeliveryReceipts:   source.DeliveryReceipts,
+		Localized:          source.Localized,
+		DefaultLanguage:    source.DefaultLanguage,
+		Tenant:             source.Tenant,
+	})
+	createdBy := source.CreatedBy
+	source.mu.RUnlock()
+
+	return rm.CreateRoom(req, createdBy)
+}
+
+// cloneCreateRoomRequest copies a request so templates aren't shared with rooms
+func cloneCreateRoomRequest(req *CreateRoomRequest) *CreateRoomRequest {
+	clone := *req
+
+	if req.Metadata != nil {
+		clone.Metadata = make(map[string]interface{}, len(req.Metadata))
+		for key, value := range req.Metadata {
+			clone.Metadata[key] = value
+		}
+	}
+
+	if req.DefaultPermissions != nil {
+		perms := *req.DefaultPermissions
+		clone.DefaultPermissions = &perms
+	}
+
+	if req.Localized != nil {
+		clone.Localized = make(map[string]types.LocalizedText, len(req.Localized))
+		for field, texts := range req.Localized {
+			clone.Localized[field] = make(types.LocalizedText, len(texts))
+			for lang, text := range texts {
+				clone.Localized[field][lang] = text
+			}
+		}
+	}
+
+	return &clone
+}
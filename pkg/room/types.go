@@ -1,6 +1,10 @@
 package room
 
-import "time"
+import (
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/types"
+)
 
 // ParticipantRole defines the role of a participant in a room
 type ParticipantRole string
@@ -20,6 +24,8 @@ type ParticipantState string
 const (
 	// StateJoining indicates participant is joining the room
 	StateJoining ParticipantState = "joining"
+	// StateWaiting indicates participant is in the lobby awaiting host approval
+	StateWaiting ParticipantState = "waiting"
 	// StateJoined indicates participant has successfully joined
 	StateJoined ParticipantState = "joined"
 	// StateReconnecting indicates participant is reconnecting
@@ -94,6 +100,52 @@ const (
 	EventTrackUnpublished RoomEventType = "track.unpublished"
 	// EventMetadataUpdated fires when room metadata is updated
 	EventMetadataUpdated RoomEventType = "metadata.updated"
+	// EventRoomScheduled fires when a room is scheduled for a later start
+	EventRoomScheduled RoomEventType = "room.scheduled"
+	// EventRoomOpened fires when a scheduled room opens for joins
+	EventRoomOpened RoomEventType = "room.opened"
+	// EventRoomEnded fires when a scheduled room reaches its end time
+	EventRoomEnded RoomEventType = "room.ended"
+	// EventParticipantMetadataUpdated fires when a participant's metadata changes
+	EventParticipantMetadataUpdated RoomEventType = "participant.metadataUpdated"
+	// EventParticipantsJoined fires once when a batch of participants joins
+	EventParticipantsJoined RoomEventType = "participants.joined"
+	// EventParticipantsLeft fires once when a batch of participants leaves
+	EventParticipantsLeft RoomEventType = "participants.left"
+	// EventParticipantsUpdated fires once when a batch of participants changes
+	EventParticipantsUpdated RoomEventType = "participants.updated"
+	// EventPlaybackSynced fires when a room's shared playback state changes
+	EventPlaybackSynced RoomEventType = "playback.synced"
+	// EventPlaybackDrift fires when a participant drifts from the shared playback
+	EventPlaybackDrift RoomEventType = "playback.drift"
+	// EventSharedStateUpdated fires when writes to a shared state are accepted
+	EventSharedStateUpdated RoomEventType = "shared_state.updated"
+	// EventSubscribeRuleUpdated fires when a subscribe allow/deny rule changes
+	EventSubscribeRuleUpdated RoomEventType = "subscription.ruleUpdated"
+	// EventSubscribeDenied fires when a subscribe rule blocks a subscription attempt
+	EventSubscribeDenied RoomEventType = "subscription.denied"
+	// EventParticipantWaiting fires when a participant enters a room's lobby
+	EventParticipantWaiting RoomEventType = "participant.waiting"
+	// EventParticipantAdmitted fires when the host admits a waiting participant
+	EventParticipantAdmitted RoomEventType = "participant.admitted"
+	// EventParticipantDenied fires when the host denies a waiting participant
+	EventParticipantDenied RoomEventType = "participant.denied"
+	// EventParticipantKnocked fires when a participant knocks on a locked room
+	EventParticipantKnocked RoomEventType = "participant.knocked"
+	// EventRoomLocked fires when a room stops accepting new joins
+	EventRoomLocked RoomEventType = "room.locked"
+	// EventRoomUnlocked fires when a locked room accepts joins again
+	EventRoomUnlocked RoomEventType = "room.unlocked"
+	// EventE2EEKeyUpdated fires when a participant shares a new media encryption key
+	EventE2EEKeyUpdated RoomEventType = "e2ee.keyUpdated"
+	// EventE2EEKeyRatcheted fires when membership changes and participants must rotate keys
+	EventE2EEKeyRatcheted RoomEventType = "e2ee.keyRatcheted"
+	// EventClientStatsReported fires when a client reports its playback quality
+	EventClientStatsReported RoomEventType = "client.stats"
+	// EventMessageDelivery fires when a recipient acknowledges delivery or reading of a data message
+	EventMessageDelivery RoomEventType = "message.delivery"
+	// EventTypingUpdated fires when a participant starts or stops typing
+	EventTypingUpdated RoomEventType = "typing.updated"
 )
 
 // RoomEvent represents an event that occurred in a room
@@ -106,6 +158,8 @@ type RoomEvent struct {
 	Timestamp time.Time `json:"timestamp"`
 	// Data contains event-specific data
 	Data interface{} `json:"data,omitempty"`
+	// Remote is true for events received from another node through an EventBridge
+	Remote bool `json:"-"`
 }
 
 // MediaTrack represents a media track (placeholder for future WebRTC integration)
@@ -130,4 +184,18 @@ type CreateRoomRequest struct {
 	EmptyTimeout time.Duration `json:"empty_timeout,omitempty"`
 	// Metadata contains custom room data
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// DefaultPermissions are granted to participants joining without explicit permissions
+	DefaultPermissions *ParticipantPermissions `json:"default_permissions,omitempty"`
+	// LobbyEnabled holds joining participants in a lobby until the host admits them
+	LobbyEnabled bool `json:"lobby_enabled,omitempty"`
+	// E2EE enables end-to-end media encryption key coordination
+	E2EE bool `json:"e2ee,omitempty"`
+	// DeliveryReceipts enables delivery and read receipts and typing indicators for data messages
+	DeliveryReceipts bool `json:"delivery_receipts,omitempty"`
+	// Localized holds translations of localized fields (e.g. "title" -> "ja" -> text)
+	Localized map[string]types.LocalizedText `json:"localized,omitempty"`
+	// DefaultLanguage is the language used when a translation is missing
+	DefaultLanguage string `json:"default_language,omitempty"`
+	// Tenant is the tenant whose quota the room and its participants count against
+	Tenant string `json:"tenant,omitempty"`
 }
@@ -0,0 +1,169 @@
+package sdk
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// DefaultSubscriberQueueSize is the default number of events buffered per subscriber
+const DefaultSubscriberQueueSize = 256
+
+// ErrSubscriberQueueFull is returned by TryPublish when a subscriber using
+// OverflowError has no room for the event
+var ErrSubscriberQueueFull = errors.New("subscriber queue full")
+
+// OverflowPolicy decides what happens when a subscriber's queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest discards the oldest queued event to make room
+	OverflowDropOldest OverflowPolicy = iota
+	// OverflowBlock waits until the subscriber has room
+	OverflowBlock
+	// OverflowError discards the new event and reports ErrSubscriberQueueFull
+	OverflowError
+)
+
+// subscriberQueue delivers events to one subscription from its own goroutine
+// so a slow handler only delays its own events
+type subscriberQueue struct {
+	events   chan *StreamEvent
+	done     chan struct{}
+	policy   OverflowPolicy
+	dropped  int64
+	stopOnce sync.Once
+}
+
+// newSubscriberQueue creates a queue and starts delivering to handler
+func newSubscriberQueue(size int, policy OverflowPolicy, handler EventHandler, log logger.Logger) *subscriberQueue {
+	if size <= 0 {
+		size = DefaultSubscriberQueueSize
+	}
+
+	q := &subscriberQueue{
+		events: make(chan *StreamEvent, size),
+		done:   make(chan struct{}),
+		policy: policy,
+	}
+
+	go q.run(handler, log)
+
+	return q
+}
+
+// run delivers queued events in order until the queue is stopped
+func (q *subscriberQueue) run(handler EventHandler, log logger.Logger) {
+	for {
+		select {
+		case <-q.done:
+			return
+		case event := <-q.events:
+			deliverEvent(handler, event, log)
+		}
+	}
+}
+
+// deliverEvent calls handler, recovering from panics
+func deliverEvent(handler EventHandler, event *StreamEvent, log logger.Logger) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("Event handler panic",
+				logger.Field{Key: "type", Value: event.Type},
+				logger.Field{Key: "error", Value: r},
+			)
+		}
+	}()
+
+	handler(event)
+}
+
+// push queues an event according to the overflow policy
+func (q *subscriberQueue) push(event *StreamEvent) error {
+	select {
+	case <-q.done:
+		return nil
+	case q.events <- event:
+		return nil
+	default:
+	}
+
+	switch q.policy {
+	case OverflowBlock:
+		select {
+		case <-q.done:
+		case q.events <- event:
+		}
+		return nil
+
+	case OverflowError:
+		atomic.AddInt64(&q.dropped, 1)
+		return ErrSubscriberQueueFull
+	}
+
+	// Drop the oldest event until the new one fits
+	for {
+		select {
+		case <-q.done:
+			return nil
+		case q.events <- event:
+			return nil
+		default:
+		}
+
+		select {
+		case <-q.events:
+			atomic.AddInt64(&q.dropped, 1)
+		default:
+		}
+	}
+}
+
+// stop ends delivery; queued events are discarded
+func (q *subscriberQueue) stop() {
+	q.stopOnce.Do(func() {
+		close(q.done)
+	})
+}
+
+// QueueDepth returns the number of events waiting to be delivered to the subscriber
+func (s *EventSubscription) QueueDepth() int {
+	if s.queue == nil {
+		return 0
+	}
+	return len(s.queue.events)
+}
+
+// Dropped returns the number of events discarded because the subscriber's queue was full
+func (s *EventSubscription) Dropped() int64 {
+	if s.queue == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&s.queue.dropped)
+}
+
+// SetQueueOptions sets the queue size and overflow policy for subsequent subscriptions
+func (eb *EventBus) SetQueueOptions(size int, policy OverflowPolicy) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	eb.queueSize = size
+	eb.overflow = policy
+}
+
+// QueueDepths returns the queue depth of every subscription by subscription ID
+func (eb *EventBus) QueueDepths() map[string]int {
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+
+	depths := make(map[string]int)
+	for _, subs := range eb.subscriptions {
+		for _, sub := range subs {
+			depths[sub.ID] = sub.QueueDepth()
+		}
+	}
+
+	return depths
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/storage"
 )
 
 // EventType represents the type of stream event
@@ -40,6 +41,50 @@ const (
 
 	// EventViewerLeave is emitted when a viewer leaves
 	EventViewerLeave EventType = "viewer.leave"
+
+	// EventRecordingStarted is emitted when a recording starts
+	EventRecordingStarted EventType = EventType(storage.RecordingEventStarted)
+
+	// EventRecordingSegment is emitted when a recording segment is finalized
+	EventRecordingSegment EventType = EventType(storage.RecordingEventSegment)
+
+	// EventRecordingCompleted is emitted when a recording stops
+	EventRecordingCompleted EventType = EventType(storage.RecordingEventCompleted)
+
+	// EventRecordingUploaded is emitted when a stopped recording is fully uploaded
+	EventRecordingUploaded EventType = EventType(storage.RecordingEventUploaded)
+
+	// EventRecordingFailed is emitted when a recording fails
+	EventRecordingFailed EventType = EventType(storage.RecordingEventFailed)
+
+	// EventLiveNotification is emitted for each follower notified that a
+	// streamer went live
+	EventLiveNotification EventType = "notification.live"
+
+	// EventReactionBurst, EventGiftCombo and EventPollEnded describe
+	// audience features that ZenLive leaves to the application: nothing in
+	// the SDK emits them. Applications publish them on the EventBus from
+	// their own reaction, gift and poll handling, and HighlightDetector and
+	// overlay.Manager consume them.
+
+	// EventReactionBurst is emitted when viewers send a burst of reactions,
+	// with the number of reactions in the "count" data field
+	EventReactionBurst EventType = "reaction.burst"
+
+	// EventGiftCombo is emitted for a gift combo, with the number of gifts in
+	// the "combo" data field and optionally their total value in "value"
+	EventGiftCombo EventType = "gift.combo"
+
+	// EventHighlightCreated is emitted when a highlight clip is created
+	EventHighlightCreated EventType = "highlight.created"
+
+	// EventFollow is emitted when a user follows a streamer, with the
+	// follower as UserID and the streamer in the "streamer_id" data field
+	EventFollow EventType = "follow.created"
+
+	// EventPollEnded is emitted when a poll on a stream closes, with the
+	// question in the "question" data field and the winning option in "winner"
+	EventPollEnded EventType = "poll.ended"
 )
 
 // StreamEvent represents an event that occurred on a stream
@@ -71,13 +116,18 @@ type EventSubscription struct {
 	ID      string
 	Type    EventType
 	Handler EventHandler
+
+	queue *subscriberQueue
 }
 
-// EventBus manages event subscriptions and publishing
+// EventBus manages event subscriptions and publishing. Each subscription has
+// its own bounded queue so a slow handler cannot stall other subscribers.
 type EventBus struct {
 	subscriptions map[EventType][]*EventSubscription
 	mu            sync.RWMutex
 	logger        logger.Logger
+	queueSize     int
+	overflow      OverflowPolicy
 }
 
 // NewEventBus creates a new event bus
@@ -89,11 +139,23 @@ func NewEventBus(log logger.Logger) *EventBus {
 	return &EventBus{
 		subscriptions: make(map[EventType][]*EventSubscription),
 		logger:        log,
+		queueSize:     DefaultSubscriberQueueSize,
+		overflow:      OverflowDropOldest,
 	}
 }
 
-// Subscribe subscribes to events of a specific type
+// Subscribe subscribes to events of a specific type using the bus queue options
 func (eb *EventBus) Subscribe(eventType EventType, handler EventHandler) *EventSubscription {
+	eb.mu.RLock()
+	size, policy := eb.queueSize, eb.overflow
+	eb.mu.RUnlock()
+
+	return eb.SubscribeWithQueue(eventType, handler, size, policy)
+}
+
+// SubscribeWithQueue subscribes to events of a specific type with its own
+// queue size and overflow policy
+func (eb *EventBus) SubscribeWithQueue(eventType EventType, handler EventHandler, queueSize int, policy OverflowPolicy) *EventSubscription {
 	eb.mu.Lock()
 	defer eb.mu.Unlock()
 
@@ -101,6 +163,7 @@ func (eb *EventBus) Subscribe(eventType EventType, handler EventHandler) *EventS
 		ID:      generateSubscriptionID(),
 		Type:    eventType,
 		Handler: handler,
+		queue:   newSubscriberQueue(queueSize, policy, handler, eb.logger),
 	}
 
 	eb.subscriptions[eventType] = append(eb.subscriptions[eventType], subscription)
@@ -126,6 +189,17 @@ func (eb *EventBus) SubscribeAll(handler EventHandler) []*EventSubscription {
 		EventStreamDelete,
 		EventViewerJoin,
 		EventViewerLeave,
+		EventRecordingStarted,
+		EventRecordingSegment,
+		EventRecordingCompleted,
+		EventRecordingUploaded,
+		EventRecordingFailed,
+		EventLiveNotification,
+		EventReactionBurst,
+		EventGiftCombo,
+		EventHighlightCreated,
+		EventFollow,
+		EventPollEnded,
 	}
 
 	subscriptions := make([]*EventSubscription, 0, len(eventTypes))
@@ -155,6 +229,7 @@ func (eb *EventBus) Unsubscribe(subscription *EventSubscription) {
 	for i, sub := range subs {
 		if sub.ID == subscription.ID {
 			eb.subscriptions[subscription.Type] = append(subs[:i], subs[i+1:]...)
+			sub.queue.stop()
 			eb.logger.Debug("Event subscription removed",
 				logger.Field{Key: "type", Value: subscription.Type},
 				logger.Field{Key: "subscription_id", Value: subscription.ID},
@@ -171,47 +246,104 @@ func (eb *EventBus) UnsubscribeAll(subscriptions []*EventSubscription) {
 	}
 }
 
-// Publish publishes an event to all subscribers
+// Publish publishes an event to all subscribers. Queue overflows are logged.
 func (eb *EventBus) Publish(event *StreamEvent) {
+	if err := eb.TryPublish(event); err != nil {
+		eb.logger.Warn("Event dropped for slow subscriber",
+			logger.Field{Key: "type", Value: event.Type},
+			logger.Field{Key: "stream_id", Value: event.StreamID},
+		)
+	}
+}
+
+// TryPublish queues an event for all subscribers and returns
+// ErrSubscriberQueueFull if a subscriber using OverflowError had no room.
+// Subscribers using OverflowBlock make TryPublish wait.
+func (eb *EventBus) TryPublish(event *StreamEvent) error {
 	if event == nil {
-		return
+		return nil
 	}
 
 	eb.mu.RLock()
 	subs, exists := eb.subscriptions[event.Type]
 	if !exists || len(subs) == 0 {
 		eb.mu.RUnlock()
-		return
+		return nil
 	}
 
-	// Create a copy of subscriptions to avoid holding lock during handler execution
-	handlers := make([]EventHandler, len(subs))
+	// Create a copy of subscriptions to avoid holding lock while queueing
+	queues := make([]*subscriberQueue, len(subs))
 	for i, sub := range subs {
-		handlers[i] = sub.Handler
+		queues[i] = sub.queue
 	}
 	eb.mu.RUnlock()
 
-	// Execute handlers asynchronously
-	for _, handler := range handlers {
-		go func(h EventHandler, e *StreamEvent) {
-			defer func() {
-				if r := recover(); r != nil {
-					eb.logger.Error("Event handler panic",
-						logger.Field{Key: "type", Value: e.Type},
-						logger.Field{Key: "error", Value: r},
-					)
-				}
-			}()
-
-			h(e)
-		}(handler, event)
+	var err error
+	for _, queue := range queues {
+		if pushErr := queue.push(event); pushErr != nil {
+			err = pushErr
+		}
 	}
 
 	eb.logger.Debug("Event published",
 		logger.Field{Key: "type", Value: event.Type},
 		logger.Field{Key: "stream_id", Value: event.StreamID},
-		logger.Field{Key: "subscribers", Value: len(handlers)},
+		logger.Field{Key: "subscribers", Value: len(queues)},
 	)
+
+	return err
+}
+
+// PublishRecordingEvent publishes a recording lifecycle event, so webhooks
+// can tell the backend when a recording is ready for processing. Set it as
+// the recorder's event callback with BaseRecorder.SetOnEvent.
+func (eb *EventBus) PublishRecordingEvent(event storage.RecordingEvent) {
+	recording := event.Recording
+	data := map[string]interface{}{
+		"recording_id":  recording.ID,
+		"format":        recording.Format,
+		"size":          recording.Size,
+		"segment_count": recording.SegmentCount,
+	}
+	if !recording.EndTime.IsZero() {
+		data["duration"] = recording.Duration.Seconds()
+	}
+	if event.Segment != nil {
+		data["segment"] = recordingSegmentData(*event.Segment)
+	}
+	if event.Segments != nil {
+		segments := make([]map[string]interface{}, 0, len(event.Segments))
+		for _, segment := range event.Segments {
+			segments = append(segments, recordingSegmentData(segment))
+		}
+		data["segments"] = segments
+	}
+
+	streamEvent := &StreamEvent{
+		Type:      EventType(event.Type),
+		StreamID:  recording.StreamID,
+		Timestamp: event.Timestamp,
+		Data:      data,
+	}
+	if event.Error != nil {
+		streamEvent.Error = event.Error.Error()
+	}
+
+	eb.Publish(streamEvent)
+}
+
+// recordingSegmentData describes a recording segment in event data
+func recordingSegmentData(segment storage.SegmentInfo) map[string]interface{} {
+	data := map[string]interface{}{
+		"index":    segment.Index,
+		"path":     segment.Path,
+		"size":     segment.Size,
+		"duration": segment.Duration.Seconds(),
+	}
+	if segment.Uploaded {
+		data["remote_path"] = segment.RemotePath
+	}
+	return data
 }
 
 // GetSubscriberCount returns the number of subscribers for an event type
@@ -240,6 +372,11 @@ func (eb *EventBus) Clear() {
 	eb.mu.Lock()
 	defer eb.mu.Unlock()
 
+	for _, subs := range eb.subscriptions {
+		for _, sub := range subs {
+			sub.queue.stop()
+		}
+	}
 	eb.subscriptions = make(map[EventType][]*EventSubscription)
 
 	eb.logger.Info("All event subscriptions cleared")
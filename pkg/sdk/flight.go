@@ -0,0 +1,66 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrFlightPanic is returned to every caller of a shared call that panicked
+var ErrFlightPanic = errors.New("shared call panicked")
+
+// flightCall is an in-progress or completed call shared by concurrent callers
+type flightCall struct {
+	wg     sync.WaitGroup
+	result interface{}
+	err    error
+}
+
+// flightGroup deduplicates concurrent calls with the same key so only one
+// computation runs and every caller receives its result
+type flightGroup struct {
+	calls map[string]*flightCall
+	mu    sync.Mutex
+}
+
+// do runs fn once for all concurrent callers of key. shared reports whether
+// the result was produced for another caller.
+func (g *flightGroup) do(key string, fn func() (interface{}, error)) (result interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.result, call.err, true
+	}
+
+	call := &flightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	g.call(key, call, fn)
+
+	return call.result, call.err, false
+}
+
+// call runs fn for a shared call and releases its waiters. A panic in fn is
+// recovered and reported to every caller as an error, so waiters are never
+// left blocked and later calls of key run afresh.
+func (g *flightGroup) call(key string, call *flightCall, fn func() (interface{}, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.result, call.err = nil, fmt.Errorf("%w: %v", ErrFlightPanic, r)
+		}
+		call.wg.Done()
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+	}()
+
+	call.result, call.err = fn()
+}
@@ -0,0 +1,312 @@
+package sdk
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/clock"
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/storage"
+)
+
+// highlightPendingTTL is how long a highlight waits for its recording to be
+// uploaded before it is dropped
+const highlightPendingTTL = 24 * time.Hour
+
+// HighlightConfig configures highlight detection. Reaction bursts and gift
+// combos add to a stream's activity score; a highlight is created when the
+// score within Window reaches Threshold.
+type HighlightConfig struct {
+	// Window is the sliding window activity is scored over
+	Window time.Duration
+	// Threshold is the score that creates a highlight
+	Threshold float64
+	// ReactionWeight is the score of one reaction in a burst
+	ReactionWeight float64
+	// GiftWeight is the score of one unit of gift value in a combo
+	GiftWeight float64
+	// Cooldown is the minimum time between highlights of a stream
+	Cooldown time.Duration
+	// PreRoll and PostRoll are how much of the recording before and after
+	// the moment of the highlight is clipped
+	PreRoll  time.Duration
+	PostRoll time.Duration
+}
+
+// DefaultHighlightConfig returns the default highlight configuration
+func DefaultHighlightConfig() HighlightConfig {
+	return HighlightConfig{
+		Window:         10 * time.Second,
+		Threshold:      100,
+		ReactionWeight: 1,
+		GiftWeight:     10,
+		Cooldown:       time.Minute,
+		PreRoll:        20 * time.Second,
+		PostRoll:       10 * time.Second,
+	}
+}
+
+// Highlight is a detected moment of a stream
+type Highlight struct {
+	StreamID string
+	// Time is when the activity crossed the threshold
+	Time time.Time
+	// Score is the activity score within the window at that time
+	Score float64
+	// Clip is the extracted clip, once the recording is uploaded
+	Clip *storage.Clip
+}
+
+// highlightActivity is one scored event
+type highlightActivity struct {
+	at    time.Time
+	score float64
+}
+
+// highlightStream is the detection state of one stream
+type highlightStream struct {
+	activity      []highlightActivity
+	lastHighlight time.Time
+	pending       []*Highlight
+}
+
+// HighlightDetector creates highlight clips from bursts of audience
+// activity. Highlights are detected live and clipped with
+// storage.ExtractClip once the stream's recording is uploaded, since only
+// then are its segments and seek index in storage.
+type HighlightDetector struct {
+	config        HighlightConfig
+	metadataStore storage.MetadataStore
+	store         storage.Storage
+	bus           *EventBus
+	logger        logger.Logger
+	clock         clock.Clock
+
+	streams map[string]*highlightStream
+	mu      sync.Mutex
+}
+
+// NewHighlightDetector creates a detector that clips highlights from the
+// recordings in store and publishes EventHighlightCreated on bus
+func NewHighlightDetector(config HighlightConfig, metadataStore storage.MetadataStore, store storage.Storage, bus *EventBus, log logger.Logger) *HighlightDetector {
+	if log == nil {
+		log = logger.NewDefaultLogger(logger.InfoLevel, "text")
+	}
+
+	return &HighlightDetector{
+		config:        config,
+		metadataStore: metadataStore,
+		store:         store,
+		bus:           bus,
+		logger:        log,
+		clock:         clock.Real(),
+		streams:       make(map[string]*highlightStream),
+	}
+}
+
+// SetClock sets the clock activity is timed with (nil = real time)
+func (d *HighlightDetector) SetClock(c clock.Clock) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.clock = clock.OrReal(c)
+}
+
+// Attach detects highlights from the reaction burst and gift combo events
+// on the bus, and clips them when recording uploaded events arrive
+func (d *HighlightDetector) Attach() []*EventSubscription {
+	return []*EventSubscription{
+		d.bus.Subscribe(EventReactionBurst, func(event *StreamEvent) {
+			d.HandleReactionBurst(event.StreamID, int(eventNumber(event.Data, "count")))
+		}),
+		d.bus.Subscribe(EventGiftCombo, func(event *StreamEvent) {
+			value := eventNumber(event.Data, "value")
+			if value == 0 {
+				value = eventNumber(event.Data, "combo")
+			}
+			d.HandleGiftCombo(event.StreamID, value)
+		}),
+		d.bus.Subscribe(EventRecordingUploaded, func(event *StreamEvent) {
+			recordingID, _ := event.Data["recording_id"].(string)
+			if _, err := d.ClipPending(context.Background(), event.StreamID, recordingID); err != nil {
+				d.logger.Warn("Failed to clip highlights",
+					logger.String("recording_id", recordingID),
+					logger.Err(err),
+				)
+			}
+		}),
+	}
+}
+
+// HandleReactionBurst scores a burst of count reactions. It returns the
+// highlight it created, or nil.
+func (d *HighlightDetector) HandleReactionBurst(streamID string, count int) *Highlight {
+	return d.record(streamID, float64(count)*d.config.ReactionWeight)
+}
+
+// HandleGiftCombo scores a gift combo of the given total value. It returns
+// the highlight it created, or nil.
+func (d *HighlightDetector) HandleGiftCombo(streamID string, value float64) *Highlight {
+	return d.record(streamID, value*d.config.GiftWeight)
+}
+
+// Pending returns the highlights of a stream waiting for their recording
+func (d *HighlightDetector) Pending(streamID string) []Highlight {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stream, exists := d.streams[streamID]
+	if !exists {
+		return nil
+	}
+
+	pending := make([]Highlight, 0, len(stream.pending))
+	for _, h := range stream.pending {
+		pending = append(pending, *h)
+	}
+	return pending
+}
+
+// record adds activity to a stream and creates a highlight if the score
+// within the window reaches the threshold outside the cooldown
+func (d *HighlightDetector) record(streamID string, score float64) *Highlight {
+	if streamID == "" || score <= 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.clock.Now()
+	stream, exists := d.streams[streamID]
+	if !exists {
+		stream = &highlightStream{}
+		d.streams[streamID] = stream
+	}
+
+	// Drop activity that left the window and highlights that were never clipped
+	since := now.Add(-d.config.Window)
+	kept := stream.activity[:0]
+	for _, activity := range stream.activity {
+		if activity.at.After(since) {
+			kept = append(kept, activity)
+		}
+	}
+	stream.activity = append(kept, highlightActivity{at: now, score: score})
+
+	for len(stream.pending) > 0 && now.Sub(stream.pending[0].Time) > highlightPendingTTL {
+		stream.pending = stream.pending[1:]
+	}
+
+	var total float64
+	for _, activity := range stream.activity {
+		total += activity.score
+	}
+
+	if total < d.config.Threshold {
+		return nil
+	}
+	if !stream.lastHighlight.IsZero() && now.Sub(stream.lastHighlight) < d.config.Cooldown {
+		return nil
+	}
+
+	highlight := &Highlight{StreamID: streamID, Time: now, Score: total}
+	stream.pending = append(stream.pending, highlight)
+	stream.lastHighlight = now
+	stream.activity = nil
+
+	d.logger.Info("Highlight detected",
+		logger.String("stream_id", streamID),
+		logger.Field{Key: "score", Value: total},
+	)
+
+	return highlight
+}
+
+// ClipPending clips the pending highlights of a stream that fall within an
+// uploaded recording, publishing EventHighlightCreated for each. Highlights
+// that fail to clip are logged and dropped. It returns the clipped
+// highlights.
+func (d *HighlightDetector) ClipPending(ctx context.Context, streamID, recordingID string) ([]Highlight, error) {
+	metadata, err := d.metadataStore.Get(ctx, recordingID)
+	if err != nil {
+		return nil, err
+	}
+
+	end := metadata.EndTime
+	if end.IsZero() && metadata.Duration > 0 {
+		end = metadata.StartTime.Add(metadata.Duration)
+	}
+
+	// Take the highlights recorded by this recording
+	d.mu.Lock()
+	var recorded []*Highlight
+	if stream, exists := d.streams[streamID]; exists {
+		remaining := stream.pending[:0]
+		for _, h := range stream.pending {
+			if !h.Time.Before(metadata.StartTime) && (end.IsZero() || !h.Time.After(end)) {
+				recorded = append(recorded, h)
+			} else {
+				remaining = append(remaining, h)
+			}
+		}
+		stream.pending = remaining
+	}
+	d.mu.Unlock()
+
+	clipped := make([]Highlight, 0, len(recorded))
+	for _, h := range recorded {
+		offset := h.Time.Sub(metadata.StartTime)
+		start := offset - d.config.PreRoll
+		if start < 0 {
+			start = 0
+		}
+
+		clip, err := storage.ExtractClip(ctx, d.metadataStore, d.store, recordingID, start, offset+d.config.PostRoll)
+		if err != nil {
+			d.logger.Warn("Failed to extract highlight clip",
+				logger.String("stream_id", streamID),
+				logger.String("recording_id", recordingID),
+				logger.Err(err),
+			)
+			continue
+		}
+		h.Clip = clip
+		clipped = append(clipped, *h)
+
+		if d.bus != nil {
+			d.bus.Publish(&StreamEvent{
+				Type:      EventHighlightCreated,
+				StreamID:  streamID,
+				Timestamp: clip.CreatedAt,
+				Data: map[string]interface{}{
+					"clip_id":      clip.ID,
+					"recording_id": recordingID,
+					"key":          clip.Key,
+					"start":        clip.Start.Seconds(),
+					"end":          clip.End.Seconds(),
+					"size":         clip.Size,
+					"score":        h.Score,
+					"detected_at":  h.Time,
+				},
+			})
+		}
+	}
+
+	return clipped, nil
+}
+
+// eventNumber reads a numeric event data field, which may have been decoded
+// from JSON as a float64
+func eventNumber(data map[string]interface{}, key string) float64 {
+	switch v := data[key].(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
@@ -376,21 +376,26 @@ func (sm *StreamManager) GetRecentStreams(ctx context.Context, limit int) ([]*St
 	return result.Streams, nil
 }
 
-// GetPopularStreams returns streams with most viewers
+// GetPopularStreams returns streams with most viewers. Concurrent calls with
+// the same limit share a single query.
 func (sm *StreamManager) GetPopularStreams(ctx context.Context, limit int) ([]*Stream, error) {
-	query := NewStreamQueryBuilder().
-		WithState(StateLive).
-		SortBy("viewer_count").
-		SortOrder("desc").
-		Limit(limit).
-		Build()
-
-	result, err := sm.QueryStreams(ctx, query)
+	result, err, _ := sm.flight.do(fmt.Sprintf("popular:%d", limit), func() (interface{}, error) {
+		query := NewStreamQueryBuilder().
+			WithState(StateLive).
+			SortBy("viewer_count").
+			SortOrder("desc").
+			Limit(limit).
+			Build()
+
+		return sm.QueryStreams(ctx, query)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return result.Streams, nil
+	// Give each caller its own slice
+	streams := result.(*StreamQueryResult).Streams
+	return append([]*Stream(nil), streams...), nil
 }
 
 // SearchStreams searches for streams by title or description
@@ -2,10 +2,17 @@ package sdk
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/aminofox/zenlive/pkg/clock"
 	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/storage"
 )
 
 // Test State Machine
@@ -482,6 +489,42 @@ func TestEventBus(t *testing.T) {
 	}
 }
 
+func TestPublishRecordingEvent(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	bus := NewEventBus(log)
+
+	received := make(chan *StreamEvent, 1)
+	bus.Subscribe(EventRecordingUploaded, func(event *StreamEvent) {
+		received <- event
+	})
+
+	bus.PublishRecordingEvent(storage.RecordingEvent{
+		Type: storage.RecordingEventUploaded,
+		Recording: storage.RecordingInfo{
+			ID:           "rec-1",
+			StreamID:     "stream-123",
+			SegmentCount: 1,
+		},
+		Segments: []storage.SegmentInfo{
+			{Index: 0, Path: "/tmp/seg0.mp4", RemotePath: "recordings/stream-123/seg0.mp4", Uploaded: true},
+		},
+		Timestamp: time.Now(),
+	})
+
+	select {
+	case event := <-received:
+		if event.StreamID != "stream-123" || event.Data["recording_id"] != "rec-1" {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+		segments, _ := event.Data["segments"].([]map[string]interface{})
+		if len(segments) != 1 || segments[0]["remote_path"] != "recordings/stream-123/seg0.mp4" {
+			t.Errorf("Expected the uploaded segment in the event data, got %v", event.Data["segments"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected recording event to be received")
+	}
+}
+
 func TestEventCallbacks(t *testing.T) {
 	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
 	bus := NewEventBus(log)
@@ -564,3 +607,235 @@ func TestWebhookManager(t *testing.T) {
 		t.Error("expected error when getting removed webhook")
 	}
 }
+
+func TestFlightGroupCoalesces(t *testing.T) {
+	var group flightGroup
+	var calls int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _, _ = group.do("key", func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return "value", nil
+			})
+		}(i)
+	}
+
+	// Let the callers pile up behind the first one
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("expected 1 computation, got %d", n)
+	}
+	for i, result := range results {
+		if result != "value" {
+			t.Errorf("caller %d: expected shared result, got %v", i, result)
+		}
+	}
+}
+
+func TestFlightGroupPanic(t *testing.T) {
+	var group flightGroup
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	go group.do("key", func() (interface{}, error) {
+		close(started)
+		<-release
+		panic("query failed")
+	})
+	<-started
+
+	waiter := make(chan error, 1)
+	go func() {
+		_, err, _ := group.do("key", func() (interface{}, error) {
+			return "unexpected", nil
+		})
+		waiter <- err
+	}()
+
+	// Let the waiter join the panicking call
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-waiter:
+		if !errors.Is(err, ErrFlightPanic) {
+			t.Errorf("expected ErrFlightPanic, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter blocked after the shared call panicked")
+	}
+
+	// The failed call is forgotten, so the key can be computed again
+	result, err, _ := group.do("key", func() (interface{}, error) {
+		return "value", nil
+	})
+	if err != nil || result != "value" {
+		t.Errorf("expected a fresh call, got %v, %v", result, err)
+	}
+}
+
+func BenchmarkGetPopularStreamsThunderingHerd(b *testing.B) {
+	manager := NewStreamManager(logger.NewDefaultLogger(logger.ErrorLevel, "text"))
+	ctx := context.Background()
+
+	for i := 0; i < 1000; i++ {
+		stream, _ := manager.CreateStream(ctx, &CreateStreamRequest{
+			UserID:   fmt.Sprintf("user-%d", i),
+			Title:    fmt.Sprintf("Stream %d", i),
+			Protocol: ProtocolRTMP,
+		})
+		stream.State = StateLive
+		stream.ViewerCount = int64(i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := manager.GetPopularStreams(ctx, 20); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestEventBusSlowSubscriberIsolation(t *testing.T) {
+	bus := NewEventBus(logger.NewDefaultLogger(logger.ErrorLevel, "text"))
+	defer bus.Clear()
+
+	block := make(chan struct{})
+	started := make(chan struct{}, 1)
+	slow := bus.SubscribeWithQueue(EventViewerJoin, func(event *StreamEvent) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-block
+	}, 2, OverflowError)
+
+	var fast int32
+	bus.Subscribe(EventViewerJoin, func(event *StreamEvent) {
+		atomic.AddInt32(&fast, 1)
+	})
+
+	// Wait until the slow handler is busy with the first event
+	bus.TryPublish(&StreamEvent{Type: EventViewerJoin, StreamID: "stream-123"})
+	<-started
+
+	var overflowed bool
+	for i := 1; i < 10; i++ {
+		if err := bus.TryPublish(&StreamEvent{Type: EventViewerJoin, StreamID: "stream-123"}); err == ErrSubscriberQueueFull {
+			overflowed = true
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if n := atomic.LoadInt32(&fast); n != 10 {
+		t.Errorf("expected fast subscriber to receive 10 events, got %d", n)
+	}
+	if !overflowed {
+		t.Error("expected slow subscriber queue to overflow")
+	}
+	if slow.QueueDepth() != 2 {
+		t.Errorf("expected slow queue depth 2, got %d", slow.QueueDepth())
+	}
+	if slow.Dropped() == 0 {
+		t.Error("expected dropped events for slow subscriber")
+	}
+	if depth := bus.QueueDepths()[slow.ID]; depth != 2 {
+		t.Errorf("expected reported depth 2, got %d", depth)
+	}
+
+	close(block)
+}
+
+func TestHighlightDetector(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	ctx := context.Background()
+	start := time.Unix(1700000000, 0)
+	fake := clock.NewFake(start)
+
+	metadataStore := storage.NewInMemoryMetadataStore(log)
+	defer metadataStore.Close()
+
+	storageConfig := storage.DefaultStorageConfig()
+	storageConfig.BasePath = t.TempDir()
+	store, err := storage.NewLocalStorage(storageConfig, log)
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	defer store.Close()
+
+	bus := NewEventBus(log)
+	detector := NewHighlightDetector(DefaultHighlightConfig(), metadataStore, store, bus, log)
+	detector.SetClock(fake)
+	detector.Attach()
+
+	created := make(chan *StreamEvent, 1)
+	bus.Subscribe(EventHighlightCreated, func(event *StreamEvent) {
+		created <- event
+	})
+
+	// Reactions alone stay below the threshold; a gift combo crosses it
+	fake.Advance(30 * time.Second)
+	if h := detector.HandleReactionBurst("stream-1", 60); h != nil {
+		t.Errorf("Expected no highlight below the threshold, got %+v", h)
+	}
+	h := detector.HandleGiftCombo("stream-1", 5)
+	if h == nil || h.Score != 110 {
+		t.Fatalf("Expected a highlight with score 110, got %+v", h)
+	}
+
+	// Further activity within the cooldown creates no highlight
+	fake.Advance(time.Second)
+	if h := detector.HandleReactionBurst("stream-1", 500); h != nil {
+		t.Errorf("Expected no highlight within the cooldown, got %+v", h)
+	}
+	if pending := detector.Pending("stream-1"); len(pending) != 1 {
+		t.Fatalf("Expected 1 pending highlight, got %d", len(pending))
+	}
+
+	// The highlight is clipped once the recording is uploaded
+	store.Upload(ctx, "seg0.ts", strings.NewReader("k0aak1bb"), 8, "video/mp2t")
+	metadataStore.Save(ctx, &storage.RecordingMetadata{
+		RecordingID: "rec-1",
+		StreamID:    "stream-1",
+		StartTime:   start,
+		Duration:    time.Minute,
+		SeekIndex: []storage.SeekPoint{
+			{Timestamp: 0, SegmentKey: "seg0.ts", Offset: 0},
+			{Timestamp: 45 * time.Second, SegmentKey: "seg0.ts", Offset: 4},
+		},
+	})
+	bus.Publish(&StreamEvent{
+		Type:     EventRecordingUploaded,
+		StreamID: "stream-1",
+		Data:     map[string]interface{}{"recording_id": "rec-1"},
+	})
+
+	select {
+	case event := <-created:
+		if event.StreamID != "stream-1" || event.Data["recording_id"] != "rec-1" || event.Data["score"] != 110.0 {
+			t.Errorf("Unexpected highlight event: %+v", event)
+		}
+		if event.Data["start"] != 0.0 || event.Data["end"] != 45.0 {
+			t.Errorf("Expected clip from 0s to 45s, got %v to %v", event.Data["start"], event.Data["end"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the highlight event")
+	}
+
+	if pending := detector.Pending("stream-1"); len(pending) != 0 {
+		t.Errorf("Expected no pending highlights after clipping, got %d", len(pending))
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/quota"
 	"github.com/google/uuid"
 )
 
@@ -35,6 +36,9 @@ type Stream struct {
 	// User ID who owns this stream
 	UserID string `json:"user_id"`
 
+	// Tenant whose quota this stream counts against
+	Tenant string `json:"tenant,omitempty"`
+
 	// Stream title
 	Title string `json:"title"`
 
@@ -127,6 +131,7 @@ func DefaultStreamConfig() *StreamConfig {
 // CreateStreamRequest represents a request to create a stream
 type CreateStreamRequest struct {
 	UserID      string            `json:"user_id"`
+	Tenant      string            `json:"tenant,omitempty"`
 	Title       string            `json:"title"`
 	Description string            `json:"description,omitempty"`
 	Protocol    StreamProtocol    `json:"protocol"`
@@ -147,6 +152,8 @@ type StreamManager struct {
 	streams map[string]*Stream
 	mu      sync.RWMutex
 	logger  logger.Logger
+	flight  flightGroup    // Coalesces concurrent identical queries
+	quota   *quota.Manager // Limits streams per tenant (nil = unlimited)
 }
 
 // NewStreamManager creates a new stream manager
@@ -161,7 +168,17 @@ func NewStreamManager(log logger.Logger) *StreamManager {
 	}
 }
 
-// CreateStream creates a new stream
+// SetQuotaManager enforces tenant stream quotas on CreateStream
+func (sm *StreamManager) SetQuotaManager(quotas *quota.Manager) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.quota = quotas
+}
+
+// CreateStream creates a new stream. Streams with a tenant count against
+// its quota until deleted; an error wrapping quota.ErrQuotaExceeded is
+// returned when the tenant has no streams left.
 func (sm *StreamManager) CreateStream(ctx context.Context, req *CreateStreamRequest) (*Stream, error) {
 	if req == nil {
 		return nil, fmt.Errorf("create stream request is required")
@@ -189,6 +206,16 @@ func (sm *StreamManager) CreateStream(ctx context.Context, req *CreateStreamRequ
 		req.Config = DefaultStreamConfig()
 	}
 
+	sm.mu.RLock()
+	quotas := sm.quota
+	sm.mu.RUnlock()
+
+	if quotas != nil {
+		if err := quotas.Acquire(ctx, req.Tenant, quota.ResourceStreams); err != nil {
+			return nil, err
+		}
+	}
+
 	// Generate unique IDs
 	streamID := uuid.New().String()
 	streamKey := generateStreamKey()
@@ -199,6 +226,7 @@ func (sm *StreamManager) CreateStream(ctx context.Context, req *CreateStreamRequ
 		ID:           streamID,
 		StreamKey:    streamKey,
 		UserID:       req.UserID,
+		Tenant:       req.Tenant,
 		Title:        req.Title,
 		Description:  req.Description,
 		Protocol:     req.Protocol,
@@ -312,9 +340,21 @@ func (sm *StreamManager) DeleteStream(ctx context.Context, streamID string) erro
 	}
 
 	sm.mu.Lock()
+	_, exists := sm.streams[streamID]
 	delete(sm.streams, streamID)
+	quotas := sm.quota
 	sm.mu.Unlock()
 
+	if exists && quotas != nil {
+		if err := quotas.Release(ctx, stream.Tenant, quota.ResourceStreams, 1); err != nil {
+			sm.logger.Warn("Failed to release stream quota",
+				logger.Field{Key: "stream_id", Value: streamID},
+				logger.Field{Key: "tenant", Value: stream.Tenant},
+				logger.Field{Key: "error", Value: err.Error()},
+			)
+		}
+	}
+
 	sm.logger.Info("Stream deleted",
 		logger.Field{Key: "stream_id", Value: streamID},
 	)
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/queue"
 )
 
 // WebhookConfig represents webhook configuration
@@ -87,10 +88,9 @@ type WebhookManager struct {
 	logger     logger.Logger
 	mu         sync.RWMutex
 
-	// Delivery queue
-	deliveryQueue chan *WebhookDelivery
+	// Delivery queue; failed deliveries are requeued with a delay
+	deliveryQueue *queue.Queue[*WebhookDelivery]
 	workers       int
-	stopChan      chan struct{}
 	wg            sync.WaitGroup
 }
 
@@ -109,9 +109,8 @@ func NewWebhookManager(eventBus *EventBus, workers int, log logger.Logger) *Webh
 		eventBus:      eventBus,
 		httpClient:    &http.Client{},
 		logger:        log,
-		deliveryQueue: make(chan *WebhookDelivery, 1000),
+		deliveryQueue: queue.New[*WebhookDelivery](queue.Options{Capacity: 1000}),
 		workers:       workers,
-		stopChan:      make(chan struct{}),
 	}
 
 	// Start workers
@@ -268,17 +267,17 @@ func (wm *WebhookManager) createEventHandler(webhookID string) EventHandler {
 		}
 
 		// Queue for delivery
-		select {
-		case wm.deliveryQueue <- delivery:
-			wm.logger.Debug("Webhook queued",
-				logger.Field{Key: "webhook_id", Value: webhookID},
-				logger.Field{Key: "event_type", Value: event.Type},
-			)
-		default:
+		if _, err := wm.deliveryQueue.Enqueue(delivery); err != nil {
 			wm.logger.Error("Webhook queue full, dropping delivery",
 				logger.Field{Key: "webhook_id", Value: webhookID},
 			)
+			return
 		}
+
+		wm.logger.Debug("Webhook queued",
+			logger.Field{Key: "webhook_id", Value: webhookID},
+			logger.Field{Key: "event_type", Value: event.Type},
+		)
 	}
 }
 
@@ -299,60 +298,65 @@ func (wm *WebhookManager) deliveryWorker(id int) {
 	)
 
 	for {
-		select {
-		case <-wm.stopChan:
+		msg, err := wm.deliveryQueue.Dequeue(context.Background())
+		if err != nil {
 			wm.logger.Debug("Webhook worker stopped",
 				logger.Field{Key: "worker_id", Value: id},
 			)
 			return
-
-		case delivery := <-wm.deliveryQueue:
-			wm.deliverWebhook(delivery)
 		}
+
+		wm.deliverWebhook(msg)
 	}
 }
 
-// deliverWebhook delivers a webhook with retry logic
-func (wm *WebhookManager) deliverWebhook(delivery *WebhookDelivery) {
-	for attempt := 1; attempt <= delivery.Config.MaxRetries; attempt++ {
-		delivery.mu.Lock()
-		delivery.Attempts = attempt
-		delivery.Payload.Attempt = attempt
-		delivery.mu.Unlock()
-
-		err := wm.sendWebhook(delivery)
+// deliverWebhook makes one delivery attempt and requeues the delivery with a
+// growing delay on failure until MaxRetries attempts have been made
+func (wm *WebhookManager) deliverWebhook(msg *queue.Message[*WebhookDelivery]) {
+	delivery := msg.Payload
+	attempt := msg.Attempts
 
-		if err == nil {
-			// Success
-			now := time.Now()
-			delivery.mu.Lock()
-			delivery.DeliveredAt = &now
-			delivery.mu.Unlock()
+	delivery.mu.Lock()
+	delivery.Attempts = attempt
+	delivery.Payload.Attempt = attempt
+	delivery.mu.Unlock()
 
-			wm.logger.Info("Webhook delivered",
-				logger.Field{Key: "delivery_id", Value: delivery.ID},
-				logger.Field{Key: "attempt", Value: attempt},
-			)
-			return
-		}
+	err := wm.sendWebhook(delivery)
 
-		// Log error
+	if err == nil {
+		// Success
+		now := time.Now()
 		delivery.mu.Lock()
-		delivery.LastError = err
+		delivery.DeliveredAt = &now
 		delivery.mu.Unlock()
 
-		wm.logger.Warn("Webhook delivery failed",
+		wm.logger.Info("Webhook delivered",
 			logger.Field{Key: "delivery_id", Value: delivery.ID},
 			logger.Field{Key: "attempt", Value: attempt},
-			logger.Field{Key: "error", Value: err},
 		)
+		wm.deliveryQueue.Ack(msg.ID)
+		return
+	}
 
-		// Retry if not last attempt
-		if attempt < delivery.Config.MaxRetries {
-			time.Sleep(delivery.Config.RetryDelay * time.Duration(attempt))
-		}
+	// Log error
+	delivery.mu.Lock()
+	delivery.LastError = err
+	delivery.mu.Unlock()
+
+	wm.logger.Warn("Webhook delivery failed",
+		logger.Field{Key: "delivery_id", Value: delivery.ID},
+		logger.Field{Key: "attempt", Value: attempt},
+		logger.Field{Key: "error", Value: err},
+	)
+
+	// Retry if not last attempt
+	if attempt < delivery.Config.MaxRetries {
+		wm.deliveryQueue.Nack(msg.ID, delivery.Config.RetryDelay*time.Duration(attempt))
+		return
 	}
 
+	wm.deliveryQueue.Ack(msg.ID)
+
 	wm.logger.Error("Webhook delivery failed after max retries",
 		logger.Field{Key: "delivery_id", Value: delivery.ID},
 		logger.Field{Key: "max_retries", Value: delivery.Config.MaxRetries},
@@ -404,7 +408,7 @@ func (wm *WebhookManager) sendWebhook(delivery *WebhookDelivery) error {
 
 // Stop stops the webhook manager and all workers
 func (wm *WebhookManager) Stop() {
-	close(wm.stopChan)
+	wm.deliveryQueue.Close()
 	wm.wg.Wait()
 	wm.logger.Info("Webhook manager stopped")
 }
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/aminofox/zenlive/pkg/clock"
 )
 
 // StreamKey represents a stream key with metadata
@@ -45,6 +47,7 @@ type KeyRotationManager struct {
 	onRotate     func(oldKey, newKey *StreamKey)
 	onExpire     func(key *StreamKey)
 	stopRotation chan struct{}
+	clock        clock.Clock
 }
 
 // RotationStats provides statistics about key rotations
@@ -87,6 +90,7 @@ func NewKeyRotationManager(policy *KeyRotationPolicy) *KeyRotationManager {
 		keyHistory:   make(map[string][]*StreamKey),
 		policy:       policy,
 		stopRotation: make(chan struct{}),
+		clock:        clock.Real(),
 	}
 
 	if policy.AutoRotate {
@@ -111,11 +115,11 @@ func (krm *KeyRotationManager) GenerateKey(streamID, userID string) (*StreamKey,
 		StreamID:      streamID,
 		Key:           keyString,
 		UserID:        userID,
-		CreatedAt:     time.Now(),
-		ExpiresAt:     time.Now().Add(krm.policy.MaxKeyAge),
+		CreatedAt:     krm.clock.Now(),
+		ExpiresAt:     krm.clock.Now().Add(krm.policy.MaxKeyAge),
 		IsActive:      true,
 		RotationCount: 0,
-		LastRotation:  time.Now(),
+		LastRotation:  krm.clock.Now(),
 	}
 
 	krm.mu.Lock()
@@ -152,7 +156,7 @@ func (krm *KeyRotationManager) RotateKey(streamID string) (*StreamKey, error) {
 	// Deactivate old key but keep it in grace period
 	krm.mu.Lock()
 	oldKey.IsActive = false
-	oldKey.ExpiresAt = time.Now().Add(krm.policy.GracePeriod)
+	oldKey.ExpiresAt = krm.clock.Now().Add(krm.policy.GracePeriod)
 	krm.mu.Unlock()
 
 	if krm.onRotate != nil {
@@ -212,7 +216,7 @@ func (krm *KeyRotationManager) RevokeKey(streamID string) error {
 	}
 
 	key.IsActive = false
-	key.ExpiresAt = time.Now()
+	key.ExpiresAt = krm.clock.Now()
 
 	if krm.onExpire != nil {
 		krm.onExpire(key)
@@ -236,7 +240,7 @@ func (krm *KeyRotationManager) GetRotationStats() *RotationStats {
 	var nextRotation time.Time
 
 	for _, key := range krm.keys {
-		if key.IsActive && time.Now().Before(key.ExpiresAt) {
+		if key.IsActive && krm.clock.Now().Before(key.ExpiresAt) {
 			stats.ActiveKeys++
 
 			// Calculate next rotation time
@@ -260,6 +264,13 @@ func (krm *KeyRotationManager) GetRotationStats() *RotationStats {
 	return stats
 }
 
+// SetClock sets the clock used for key ages, rotation and grace periods
+func (krm *KeyRotationManager) SetClock(c clock.Clock) {
+	krm.mu.Lock()
+	defer krm.mu.Unlock()
+	krm.clock = clock.OrReal(c)
+}
+
 // SetRotationCallback sets the callback for key rotation events
 func (krm *KeyRotationManager) SetRotationCallback(callback func(oldKey, newKey *StreamKey)) {
 	krm.mu.Lock()
@@ -280,7 +291,7 @@ func (krm *KeyRotationManager) checkKeyValidity(key *StreamKey) error {
 		return ErrKeyInactive
 	}
 
-	if time.Now().After(key.ExpiresAt) {
+	if krm.clock.Now().After(key.ExpiresAt) {
 		return ErrKeyExpired
 	}
 
@@ -331,7 +342,7 @@ func (krm *KeyRotationManager) checkAndRotateKeys() {
 		}
 
 		// Check if rotation is needed
-		if time.Since(key.LastRotation) >= krm.policy.RotateEvery {
+		if krm.clock.Since(key.LastRotation) >= krm.policy.RotateEvery {
 			keysToRotate = append(keysToRotate, streamID)
 		}
 	}
@@ -354,7 +365,7 @@ func (krm *KeyRotationManager) cleanupExpiredKeys() {
 	krm.mu.Lock()
 	defer krm.mu.Unlock()
 
-	now := time.Now()
+	now := krm.clock.Now()
 
 	for streamID, history := range krm.keyHistory {
 		filtered := make([]*StreamKey, 0, len(history))
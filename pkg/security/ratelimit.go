@@ -4,6 +4,8 @@ import (
 	"errors"
 	"sync"
 	"time"
+
+	"github.com/aminofox/zenlive/pkg/clock"
 )
 
 // RateLimitLevel represents different levels of rate limiting
@@ -40,6 +42,7 @@ type RateLimiter struct {
 	onExceeded      func(key string, level RateLimitLevel)
 	cleanupInterval time.Duration
 	stopCleanup     chan struct{}
+	clock           clock.Clock
 }
 
 // bucket represents a token bucket for rate limiting
@@ -82,6 +85,7 @@ func NewRateLimiter(config *RateLimitConfig) *RateLimiter {
 		buckets:         make(map[string]*bucket),
 		cleanupInterval: 10 * time.Minute,
 		stopCleanup:     make(chan struct{}),
+		clock:           clock.Real(),
 	}
 
 	// Start cleanup goroutine
@@ -97,7 +101,7 @@ func (rl *RateLimiter) Allow(key string) bool {
 	if !exists {
 		b = &bucket{
 			tokens:     rl.config.Burst,
-			lastRefill: time.Now(),
+			lastRefill: rl.clock.Now(),
 		}
 		rl.buckets[key] = b
 	}
@@ -107,7 +111,7 @@ func (rl *RateLimiter) Allow(key string) bool {
 	defer b.mu.Unlock()
 
 	// Refill tokens based on elapsed time
-	now := time.Now()
+	now := rl.clock.Now()
 	elapsed := now.Sub(b.lastRefill)
 	tokensToAdd := int(elapsed / rl.config.Window * time.Duration(rl.config.Requests))
 
@@ -143,7 +147,7 @@ func (rl *RateLimiter) GetStatus(key string) *RateLimitResult {
 			Allowed:   true,
 			Limit:     rl.config.Requests,
 			Remaining: rl.config.Burst,
-			ResetTime: time.Now().Add(rl.config.Window),
+			ResetTime: rl.clock.Now().Add(rl.config.Window),
 		}
 	}
 
@@ -155,7 +159,7 @@ func (rl *RateLimiter) GetStatus(key string) *RateLimitResult {
 	resetTime := lastRefill.Add(rl.config.Window)
 	retryAfter := time.Duration(0)
 	if tokens == 0 {
-		retryAfter = time.Until(resetTime)
+		retryAfter = resetTime.Sub(rl.clock.Now())
 		if retryAfter < 0 {
 			retryAfter = 0
 		}
@@ -177,6 +181,13 @@ func (rl *RateLimiter) Reset(key string) {
 	delete(rl.buckets, key)
 }
 
+// SetClock sets the clock used for token refills and bucket expiry
+func (rl *RateLimiter) SetClock(c clock.Clock) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.clock = clock.OrReal(c)
+}
+
 // SetCallback sets the callback for when rate limit is exceeded
 func (rl *RateLimiter) SetCallback(callback func(key string, level RateLimitLevel)) {
 	rl.mu.Lock()
@@ -193,7 +204,7 @@ func (rl *RateLimiter) cleanup() {
 		select {
 		case <-ticker.C:
 			rl.mu.Lock()
-			now := time.Now()
+			now := rl.clock.Now()
 			for key, b := range rl.buckets {
 				b.mu.Lock()
 				if now.Sub(b.lastRefill) > rl.config.Window*2 {
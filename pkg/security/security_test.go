@@ -4,6 +4,8 @@ import (
 	"os"
 	"testing"
 	"time"
+
+	"github.com/aminofox/zenlive/pkg/clock"
 )
 
 // TestCertificateManager tests certificate management
@@ -619,3 +621,31 @@ func TestAuditLogger_GenerateComplianceReport(t *testing.T) {
 		t.Error("Expected violations to be reported")
 	}
 }
+
+// TestKeyRotationManager_Clock tests key expiry and rotation with a fake clock
+func TestKeyRotationManager_Clock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	policy := DefaultRotationPolicy()
+	policy.AutoRotate = false
+
+	krm := NewKeyRotationManager(policy)
+	krm.SetClock(fake)
+
+	key, err := krm.GenerateKey("stream1", "user1")
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	fake.Advance(policy.RotateEvery)
+	krm.checkAndRotateKeys()
+
+	current, _ := krm.GetKey("stream1")
+	if current.Key == key.Key || current.RotationCount != 1 {
+		t.Fatalf("Expected key to be rotated after RotateEvery, got rotation count %d", current.RotationCount)
+	}
+
+	fake.Advance(policy.MaxKeyAge + time.Second)
+	if err := krm.ValidateKey("stream1", current.Key); err != ErrKeyExpired {
+		t.Errorf("Expected ErrKeyExpired after MaxKeyAge, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package security
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
@@ -197,6 +198,20 @@ func (cm *CertificateManager) ExpiresIn() time.Duration {
 	return time.Until(cm.expiryTime)
 }
 
+// CheckHealth reports an error if no certificate is loaded or it has expired
+func (cm *CertificateManager) CheckHealth(ctx context.Context) error {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if cm.cert == nil {
+		return errors.New("no certificate loaded")
+	}
+	if !time.Now().Before(cm.expiryTime) {
+		return fmt.Errorf("certificate expired (expired on: %v)", cm.expiryTime)
+	}
+	return nil
+}
+
 // NeedsRenewal checks if the certificate needs renewal
 func (cm *CertificateManager) NeedsRenewal() bool {
 	cm.mu.RLock()
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Clip is a section of a recording copied to its own object
+type Clip struct {
+	ID          string
+	RecordingID string
+	StreamID    string
+	// Key is the storage key of the clip
+	Key string
+	// Start and End are the clip bounds in the recording, widened to
+	// keyframes so the clip starts decodable
+	Start time.Duration
+	End   time.Duration
+	Size  int64
+	// CreatedAt is when the clip was extracted
+	CreatedAt time.Time
+}
+
+// clipRemotePath returns the storage key a clip is uploaded to
+func clipRemotePath(streamID, clipID, ext string) string {
+	return fmt.Sprintf("recordings/%s/clips/%s%s", streamID, clipID, ext)
+}
+
+// ExtractClip copies the section of an uploaded recording between start and
+// end into a new object. The clip starts at the last keyframe at or before
+// start and stops at the first keyframe at or after end, or at the end of
+// the recording. Segments are located through the recording's seek index
+// and their tags copied without remuxing, after a single copy of the
+// container header that starts each segment, such as FLV's.
+func ExtractClip(ctx context.Context, metadataStore MetadataStore, store Storage, recordingID string, start, end time.Duration) (*Clip, error) {
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("%w: %v to %v", ErrInvalidClip, start, end)
+	}
+
+	metadata, err := metadataStore.Get(ctx, recordingID)
+	if err != nil {
+		return nil, err
+	}
+
+	index := metadata.SeekIndex
+	if len(index) == 0 {
+		return nil, ErrNoSeekIndex
+	}
+
+	first := sort.Search(len(index), func(i int) bool {
+		return index[i].Timestamp > start
+	})
+	if first > 0 {
+		first--
+	}
+	last := sort.Search(len(index), func(i int) bool {
+		return index[i].Timestamp >= end
+	})
+
+	from := index[first]
+	clipEnd := metadata.Duration
+	var to *SeekPoint
+	if last < len(index) {
+		to = &index[last]
+		clipEnd = to.Timestamp
+	}
+
+	// Copy each segment between the keyframes in order, skipping the
+	// header each one starts with
+	var data bytes.Buffer
+	data.Write(metadata.SegmentHeader)
+	headerSize := int64(len(metadata.SegmentHeader))
+	for i := first; i < len(index) && i <= last; i++ {
+		key := index[i].SegmentKey
+		if i > first && key == index[i-1].SegmentKey {
+			continue
+		}
+
+		offset, length := headerSize, int64(0)
+		if key == from.SegmentKey && from.Offset > offset {
+			offset = from.Offset
+		}
+		if to != nil && key == to.SegmentKey {
+			if length = to.Offset - offset; length <= 0 {
+				break
+			}
+		}
+
+		if err := copyRange(ctx, store, &data, key, offset, length); err != nil {
+			return nil, err
+		}
+	}
+
+	clip := &Clip{
+		ID:          uuid.New().String(),
+		RecordingID: recordingID,
+		StreamID:    metadata.StreamID,
+		Start:       from.Timestamp,
+		End:         clipEnd,
+		Size:        int64(data.Len()),
+		CreatedAt:   time.Now(),
+	}
+
+	ext := filepath.Ext(from.SegmentKey)
+	clip.Key = clipRemotePath(metadata.StreamID, clip.ID, ext)
+
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	if err := store.Upload(ctx, clip.Key, &data, clip.Size, contentType); err != nil {
+		return nil, fmt.Errorf("failed to upload clip: %w", err)
+	}
+
+	return clip, nil
+}
+
+// copyRange appends a byte range of an object to w (length <= 0 = to the end)
+func copyRange(ctx context.Context, store Storage, w io.Writer, key string, offset, length int64) error {
+	objectRange, err := store.DownloadRange(ctx, key, offset, length)
+	if err != nil {
+		return fmt.Errorf("failed to read segment %s: %w", key, err)
+	}
+	defer objectRange.Close()
+
+	_, err = io.Copy(w, objectRange)
+	return err
+}
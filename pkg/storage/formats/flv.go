@@ -2,11 +2,27 @@ package formats
 
 import (
 	"context"
+	"encoding/binary"
+	"time"
 
 	"github.com/aminofox/zenlive/pkg/logger"
 	"github.com/aminofox/zenlive/pkg/storage"
 )
 
+// FLV tag types
+const (
+	FLVTagAudio  uint8 = 8
+	FLVTagVideo  uint8 = 9
+	FLVTagScript uint8 = 18
+)
+
+// flvHeader is the FLV file header announcing audio and video, followed by
+// the zero size of the tag before the first
+var flvHeader = []byte{'F', 'L', 'V', 0x01, 0x05, 0x00, 0x00, 0x00, 0x09, 0x00, 0x00, 0x00, 0x00}
+
+// flvTagHeaderSize is the size of an FLV tag header
+const flvTagHeaderSize = 11
+
 // FLVRecorder implements recording to FLV format
 type FLVRecorder struct {
 	*storage.BaseRecorder
@@ -23,6 +39,9 @@ func NewFLVRecorder(config storage.RecordingConfig, log logger.Logger) (*FLVReco
 		log = logger.NewDefaultLogger(logger.InfoLevel, "text")
 	}
 
+	// Every segment is a playable FLV file of its own
+	config.SegmentHeader = flvHeader
+
 	base := storage.NewBaseRecorder(config, log)
 
 	return &FLVRecorder{
@@ -40,6 +59,33 @@ func (r *FLVRecorder) Start(ctx context.Context) error {
 	return r.BaseRecorder.Start(ctx)
 }
 
+// WriteTag records an audio, video or script tag with its body as carried
+// by RTMP. Video keyframes are added to the seek index and start a new
+// segment when one is due.
+func (r *FLVRecorder) WriteTag(tagType uint8, timestamp uint32, payload []byte) error {
+	tag := make([]byte, flvTagHeaderSize+len(payload)+4)
+	tag[0] = tagType
+	putUint24(tag[1:4], uint32(len(payload)))
+	putUint24(tag[4:7], timestamp&0xFFFFFF)
+	tag[7] = byte(timestamp >> 24)
+	copy(tag[flvTagHeaderSize:], payload)
+	binary.BigEndian.PutUint32(tag[flvTagHeaderSize+len(payload):], uint32(flvTagHeaderSize+len(payload)))
+
+	// The high nibble of a video tag body is its frame type; 1 is a keyframe
+	if tagType == FLVTagVideo && len(payload) > 0 && payload[0]>>4 == 1 {
+		return r.WriteKeyframe(tag, time.Duration(timestamp)*time.Millisecond)
+	}
+
+	return r.WriteData(tag)
+}
+
+// putUint24 writes v to b as a 24-bit big-endian integer
+func putUint24(b []byte, v uint32) {
+	b[0] = byte(v >> 16)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v)
+}
+
 // Stop ends FLV recording
 func (r *FLVRecorder) Stop(ctx context.Context) error {
 	r.logger.Info("Stopping FLV recording",
@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// readAndHash reads all of data and returns it with its hex SHA-256 hash
+func readAndHash(data io.Reader) ([]byte, string, error) {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read data: %w", err)
+	}
+
+	return content, hashBytes(content), nil
+}
+
+// hashBytes returns the hex SHA-256 hash of content
+func hashBytes(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
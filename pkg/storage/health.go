@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+)
+
+// healthProbePrefix is the key prefix of objects written by CheckWritable
+const healthProbePrefix = ".health/"
+
+// CheckWritable verifies a storage backend accepts writes by uploading and
+// deleting a small probe object
+func CheckWritable(ctx context.Context, s Storage) error {
+	if s == nil {
+		return ErrStorageNotConfigured
+	}
+
+	key := fmt.Sprintf("%sprobe-%d", healthProbePrefix, time.Now().UnixNano())
+	data := []byte("ok")
+
+	if err := s.Upload(ctx, key, bytes.NewReader(data), int64(len(data)), "text/plain"); err != nil {
+		return fmt.Errorf("storage not writable: %w", err)
+	}
+
+	return s.Delete(ctx, key)
+}
@@ -1,9 +1,13 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
@@ -13,10 +17,14 @@ import (
 	"github.com/aminofox/zenlive/pkg/logger"
 )
 
+// metaFileSuffix names the sidecar file holding an object's metadata
+const metaFileSuffix = ".meta"
+
 // LocalStorage implements local filesystem storage
 type LocalStorage struct {
-	config StorageConfig
-	logger logger.Logger
+	config   StorageConfig
+	throttle *UploadThrottle
+	logger   logger.Logger
 }
 
 // NewLocalStorage creates a new local storage backend
@@ -35,14 +43,46 @@ func NewLocalStorage(config StorageConfig, log logger.Logger) (*LocalStorage, er
 	}
 
 	return &LocalStorage{
-		config: config,
-		logger: log,
+		config:   config,
+		throttle: NewUploadThrottle(config.MaxUploadBytesPerSec),
+		logger:   log,
 	}, nil
 }
 
 // Upload uploads data to local filesystem
 func (s *LocalStorage) Upload(ctx context.Context, key string, data io.Reader, size int64, contentType string) error {
-	filePath := s.getFilePath(key)
+	return s.upload(ctx, key, data, size, contentType, "")
+}
+
+// UploadIfAbsent uploads data unless the stored file already has the same content hash
+func (s *LocalStorage) UploadIfAbsent(ctx context.Context, key string, data io.Reader, size int64, contentType string) (bool, error) {
+	content, contentHash, err := readAndHash(data)
+	if err != nil {
+		return false, err
+	}
+
+	if existing, err := s.Stat(ctx, key); err == nil && existing.Hash == contentHash {
+		s.logger.Debug("Skipping upload of identical file",
+			logger.Field{Key: "key", Value: key},
+			logger.Field{Key: "hash", Value: contentHash},
+		)
+		return false, nil
+	}
+
+	if err := s.upload(ctx, key, bytes.NewReader(content), size, contentType, contentHash); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// upload writes data to the file for key. A known content hash is stored
+// as-is; otherwise one is computed when ContentHash is enabled.
+func (s *LocalStorage) upload(ctx context.Context, key string, data io.Reader, size int64, contentType string, contentHash string) error {
+	filePath, err := s.getFilePath(key)
+	if err != nil {
+		return err
+	}
 
 	// Create directory if needed
 	dir := filepath.Dir(filePath)
@@ -68,8 +108,15 @@ func (s *LocalStorage) Upload(ctx context.Context, key string, data io.Reader, s
 			continue
 		}
 
-		// Copy data
-		written, err := io.Copy(file, data)
+		// Copy data, hashing it on the way through if requested
+		var hasher hash.Hash
+		var reader io.Reader = newThrottledReader(ctx, data, s.throttle)
+		if contentHash == "" && s.config.ContentHash {
+			hasher = sha256.New()
+			reader = io.TeeReader(reader, hasher)
+		}
+
+		written, err := io.Copy(file, reader)
 		file.Close()
 
 		if err != nil {
@@ -91,6 +138,12 @@ func (s *LocalStorage) Upload(ctx context.Context, key string, data io.Reader, s
 			"uploaded-at":  time.Now().Format(time.RFC3339),
 		}
 
+		if hasher != nil {
+			metadata[MetadataContentHash] = hex.EncodeToString(hasher.Sum(nil))
+		} else if contentHash != "" {
+			metadata[MetadataContentHash] = contentHash
+		}
+
 		if err := s.saveMetadataFile(filePath, metadata); err != nil {
 			s.logger.Warn("Failed to save metadata",
 				logger.Field{Key: "error", Value: err},
@@ -110,7 +163,10 @@ func (s *LocalStorage) Upload(ctx context.Context, key string, data io.Reader, s
 
 // Download downloads data from local filesystem
 func (s *LocalStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
-	filePath := s.getFilePath(key)
+	filePath, err := s.getFilePath(key)
+	if err != nil {
+		return nil, err
+	}
 
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -123,9 +179,52 @@ func (s *LocalStorage) Download(ctx context.Context, key string) (io.ReadCloser,
 	return file, nil
 }
 
+// DownloadRange downloads a byte range from local filesystem
+func (s *LocalStorage) DownloadRange(ctx context.Context, key string, offset, length int64) (*ObjectRange, error) {
+	filePath, err := s.getFilePath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	start, n, err := resolveRange(offset, length, stat.Size())
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek file: %w", err)
+	}
+
+	return &ObjectRange{
+		ReadCloser: &limitedReadCloser{Reader: io.LimitReader(file, n), Closer: file},
+		Offset:     start,
+		Length:     n,
+		Size:       stat.Size(),
+	}, nil
+}
+
 // Delete removes a file from local filesystem
 func (s *LocalStorage) Delete(ctx context.Context, key string) error {
-	filePath := s.getFilePath(key)
+	filePath, err := s.getFilePath(key)
+	if err != nil {
+		return err
+	}
 
 	// Delete main file
 	if err := os.Remove(filePath); err != nil {
@@ -136,7 +235,7 @@ func (s *LocalStorage) Delete(ctx context.Context, key string) error {
 	}
 
 	// Delete metadata file
-	metaPath := filePath + ".meta"
+	metaPath := filePath + metaFileSuffix
 	os.Remove(metaPath) // Ignore error
 
 	s.logger.Info("File deleted",
@@ -148,9 +247,12 @@ func (s *LocalStorage) Delete(ctx context.Context, key string) error {
 
 // Exists checks if a file exists
 func (s *LocalStorage) Exists(ctx context.Context, key string) (bool, error) {
-	filePath := s.getFilePath(key)
+	filePath, err := s.getFilePath(key)
+	if err != nil {
+		return false, err
+	}
 
-	_, err := os.Stat(filePath)
+	_, err = os.Stat(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return false, nil
@@ -163,19 +265,22 @@ func (s *LocalStorage) Exists(ctx context.Context, key string) (bool, error) {
 
 // List lists files with the given prefix
 func (s *LocalStorage) List(ctx context.Context, prefix string, maxKeys int) ([]StorageObject, error) {
-	searchPath := s.getFilePath(prefix)
+	searchPath, err := s.getFilePath(prefix)
+	if err != nil {
+		return nil, err
+	}
 	baseDir := s.config.BasePath
 
 	objects := make([]StorageObject, 0)
 	count := 0
 
-	err := filepath.Walk(baseDir, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(baseDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
 		// Skip directories and metadata files
-		if info.IsDir() || strings.HasSuffix(path, ".meta") {
+		if info.IsDir() || strings.HasSuffix(path, metaFileSuffix) {
 			return nil
 		}
 
@@ -217,9 +322,47 @@ func (s *LocalStorage) List(ctx context.Context, prefix string, maxKeys int) ([]
 	return objects, nil
 }
 
+// UploadThroughput returns the recent upload rate in bytes per second
+func (s *LocalStorage) UploadThroughput() float64 {
+	return s.throttle.Throughput()
+}
+
+// Stat returns size, hash, content type and modification time of a file
+func (s *LocalStorage) Stat(ctx context.Context, key string) (*StorageObject, error) {
+	filePath, err := s.getFilePath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+
+	metadata, err := s.loadMetadataFile(filePath)
+	if err != nil {
+		metadata = make(map[string]string)
+	}
+
+	return &StorageObject{
+		Key:          key,
+		Size:         stat.Size(),
+		LastModified: stat.ModTime(),
+		ContentType:  metadata["content-type"],
+		Hash:         metadata[MetadataContentHash],
+		Metadata:     metadata,
+	}, nil
+}
+
 // GetMetadata retrieves metadata for a file
 func (s *LocalStorage) GetMetadata(ctx context.Context, key string) (map[string]string, error) {
-	filePath := s.getFilePath(key)
+	filePath, err := s.getFilePath(key)
+	if err != nil {
+		return nil, err
+	}
 
 	// Check if file exists
 	if _, err := os.Stat(filePath); err != nil {
@@ -239,7 +382,10 @@ func (s *LocalStorage) GetMetadata(ctx context.Context, key string) (map[string]
 
 // SetMetadata sets metadata for a file
 func (s *LocalStorage) SetMetadata(ctx context.Context, key string, metadata map[string]string) error {
-	filePath := s.getFilePath(key)
+	filePath, err := s.getFilePath(key)
+	if err != nil {
+		return err
+	}
 
 	// Check if file exists
 	if _, err := os.Stat(filePath); err != nil {
@@ -254,8 +400,14 @@ func (s *LocalStorage) SetMetadata(ctx context.Context, key string, metadata map
 
 // Copy copies a file to a new location
 func (s *LocalStorage) Copy(ctx context.Context, srcKey, dstKey string) error {
-	srcPath := s.getFilePath(srcKey)
-	dstPath := s.getFilePath(dstKey)
+	srcPath, err := s.getFilePath(srcKey)
+	if err != nil {
+		return err
+	}
+	dstPath, err := s.getFilePath(dstKey)
+	if err != nil {
+		return err
+	}
 
 	// Open source file
 	src, err := os.Open(srcPath)
@@ -300,7 +452,10 @@ func (s *LocalStorage) Copy(ctx context.Context, srcKey, dstKey string) error {
 
 // GetURL returns a file:// URL for the file
 func (s *LocalStorage) GetURL(ctx context.Context, key string, expires time.Duration) (string, error) {
-	filePath := s.getFilePath(key)
+	filePath, err := s.getFilePath(key)
+	if err != nil {
+		return "", err
+	}
 
 	// Check if file exists
 	if _, err := os.Stat(filePath); err != nil {
@@ -325,18 +480,27 @@ func (s *LocalStorage) Close() error {
 	return nil
 }
 
-// getFilePath returns the full file path for a key
-func (s *LocalStorage) getFilePath(key string) string {
-	// Sanitize key to prevent directory traversal
-	key = filepath.Clean(key)
-	key = strings.TrimPrefix(key, "/")
+// getFilePath returns the full file path for a key. Keys that would resolve
+// outside BasePath or name a metadata sidecar file return ErrInvalidObjectKey.
+func (s *LocalStorage) getFilePath(key string) (string, error) {
+	if !servableKey(filepath.ToSlash(key)) {
+		return "", ErrInvalidObjectKey
+	}
+
+	filePath := filepath.Join(s.config.BasePath, filepath.Clean("/"+key))
+
+	// Defense in depth: the cleaned path must stay inside BasePath
+	rel, err := filepath.Rel(s.config.BasePath, filePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidObjectKey
+	}
 
-	return filepath.Join(s.config.BasePath, key)
+	return filePath, nil
 }
 
 // saveMetadataFile saves metadata to a .meta file
 func (s *LocalStorage) saveMetadataFile(filePath string, metadata map[string]string) error {
-	metaPath := filePath + ".meta"
+	metaPath := filePath + metaFileSuffix
 
 	data, err := json.Marshal(metadata)
 	if err != nil {
@@ -348,7 +512,7 @@ func (s *LocalStorage) saveMetadataFile(filePath string, metadata map[string]str
 
 // loadMetadataFile loads metadata from a .meta file
 func (s *LocalStorage) loadMetadataFile(filePath string) (map[string]string, error) {
-	metaPath := filePath + ".meta"
+	metaPath := filePath + metaFileSuffix
 
 	data, err := os.ReadFile(metaPath)
 	if err != nil {
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// MarkerType represents the kind of a recording marker
+type MarkerType string
+
+const (
+	// MarkerChapter marks the start of a chapter
+	MarkerChapter MarkerType = "chapter"
+	// MarkerHighlight marks a highlight such as a reaction burst
+	MarkerHighlight MarkerType = "highlight"
+	// MarkerPoll marks a poll being started
+	MarkerPoll MarkerType = "poll"
+	// MarkerGift marks a gift combo
+	MarkerGift MarkerType = "gift"
+)
+
+// RecordingMarker is a labelled point in a recording
+type RecordingMarker struct {
+	ID        string
+	Type      MarkerType
+	Label     string
+	Offset    time.Duration
+	CreatedAt time.Time
+}
+
+// MarkerEvent is an interactive event that may become a recording marker
+type MarkerEvent struct {
+	Type  string
+	Label string
+	Time  time.Time
+}
+
+// DefaultMarkerEventTypes maps interactive event types to the markers they create
+var DefaultMarkerEventTypes = map[string]MarkerType{
+	"poll.started":   MarkerPoll,
+	"gift.combo":     MarkerGift,
+	"reaction.burst": MarkerHighlight,
+}
+
+// MarkerBridge adds markers to recording metadata, either directly or from
+// interactive events mapped to marker types
+type MarkerBridge struct {
+	store      MetadataStore
+	eventTypes map[string]MarkerType
+	logger     logger.Logger
+	mu         sync.Mutex
+}
+
+// NewMarkerBridge creates a marker bridge using the default event mappings
+func NewMarkerBridge(store MetadataStore, log logger.Logger) *MarkerBridge {
+	if log == nil {
+		log = logger.NewDefaultLogger(logger.InfoLevel, "text")
+	}
+
+	eventTypes := make(map[string]MarkerType, len(DefaultMarkerEventTypes))
+	for eventType, markerType := range DefaultMarkerEventTypes {
+		eventTypes[eventType] = markerType
+	}
+
+	return &MarkerBridge{
+		store:      store,
+		eventTypes: eventTypes,
+		logger:     log,
+	}
+}
+
+// MapEvent makes events of eventType create markers of markerType. An empty
+// markerType stops the event type from creating markers.
+func (b *MarkerBridge) MapEvent(eventType string, markerType MarkerType) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if markerType == "" {
+		delete(b.eventTypes, eventType)
+		return
+	}
+	b.eventTypes[eventType] = markerType
+}
+
+// AddMarker records a marker at the given offset into a recording
+func (b *MarkerBridge) AddMarker(ctx context.Context, recordingID string, markerType MarkerType, label string, at time.Duration) (*RecordingMarker, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.addMarker(ctx, recordingID, markerType, label, at)
+}
+
+// HandleEvent creates a marker for a mapped interactive event, placed at the
+// event time relative to the recording start. Unmapped events are ignored
+// and return a nil marker.
+func (b *MarkerBridge) HandleEvent(ctx context.Context, recordingID string, event MarkerEvent) (*RecordingMarker, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	markerType, ok := b.eventTypes[event.Type]
+	if !ok {
+		return nil, nil
+	}
+
+	metadata, err := b.store.Get(ctx, recordingID)
+	if err != nil {
+		return nil, err
+	}
+
+	label := event.Label
+	if label == "" {
+		label = event.Type
+	}
+
+	return b.addMarker(ctx, recordingID, markerType, label, event.Time.Sub(metadata.StartTime))
+}
+
+// Markers returns a recording's markers ordered by offset
+func (b *MarkerBridge) Markers(ctx context.Context, recordingID string) ([]RecordingMarker, error) {
+	metadata, err := b.store.Get(ctx, recordingID)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]RecordingMarker(nil), metadata.Markers...), nil
+}
+
+// addMarker inserts a marker in offset order. Caller must hold b.mu.
+func (b *MarkerBridge) addMarker(ctx context.Context, recordingID string, markerType MarkerType, label string, at time.Duration) (*RecordingMarker, error) {
+	if markerType == "" || at < 0 {
+		return nil, ErrInvalidMarker
+	}
+
+	metadata, err := b.store.Get(ctx, recordingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata.Duration > 0 && at > metadata.Duration {
+		return nil, ErrInvalidMarker
+	}
+
+	marker := RecordingMarker{
+		ID:        uuid.New().String(),
+		Type:      markerType,
+		Label:     label,
+		Offset:    at,
+		CreatedAt: time.Now(),
+	}
+
+	// Copy so the stored metadata's slice is never shared
+	markers := make([]RecordingMarker, 0, len(metadata.Markers)+1)
+	markers = append(markers, metadata.Markers...)
+	markers = append(markers, marker)
+	sort.SliceStable(markers, func(i, j int) bool {
+		return markers[i].Offset < markers[j].Offset
+	})
+	metadata.Markers = markers
+
+	if err := b.store.Update(ctx, metadata); err != nil {
+		return nil, err
+	}
+
+	b.logger.Debug("Recording marker added",
+		logger.Field{Key: "recording_id", Value: recordingID},
+		logger.Field{Key: "type", Value: string(markerType)},
+		logger.Field{Key: "offset", Value: at.String()},
+	)
+
+	return &marker, nil
+}
+
+// WriteChaptersVTT writes markers as a WebVTT chapters track, which players
+// use for chapter navigation alongside the recording. Each chapter runs
+// until the next marker, the last one until duration.
+func WriteChaptersVTT(w io.Writer, markers []RecordingMarker, duration time.Duration) error {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n")
+
+	for i, marker := range markers {
+		end := duration
+		if i+1 < len(markers) {
+			end = markers[i+1].Offset
+		}
+		if end <= marker.Offset {
+			end = marker.Offset + time.Second
+		}
+
+		fmt.Fprintf(&sb, "\n%d\n%s --> %s\n%s\n", i+1, vttTimestamp(marker.Offset), vttTimestamp(end), marker.Label)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// vttTimestamp formats an offset as a WebVTT timestamp
+func vttTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// FrameGrabber returns the latest decoded video frame of a live stream and
+// the time it was received from ingest
+type FrameGrabber func(ctx context.Context, streamID string) (image.Image, time.Time, error)
+
+// LivePreviewConfig contains configuration for live stream previews
+type LivePreviewConfig struct {
+	// Interval is how often a frame is grabbed
+	Interval time.Duration
+	// StallTimeout skips grabs when the latest frame is older than this
+	StallTimeout time.Duration
+	// Size is the preview size
+	Size ThumbnailSize
+	// Format is the image format ("jpeg" or "png")
+	Format string
+	// Storage receives the preview objects
+	Storage Storage
+}
+
+// DefaultLivePreviewConfig returns a default live preview configuration
+func DefaultLivePreviewConfig() LivePreviewConfig {
+	return LivePreviewConfig{
+		Interval:     30 * time.Second,
+		StallTimeout: 10 * time.Second,
+		Size:         ThumbnailSize{Name: "preview", Width: 320, Height: 180, Quality: 80},
+		Format:       "jpeg",
+	}
+}
+
+// LivePreviewer keeps a preview image of each live stream up to date for
+// browse pages. Each stream has a single preview object, overwritten on
+// every grab, so its URL stays stable; OnThumbnailUpdated is the hook to
+// purge that URL from a CDN.
+type LivePreviewer struct {
+	config    LivePreviewConfig
+	grabber   FrameGrabber
+	logger    logger.Logger
+	streams   map[string]*previewStream
+	onUpdated func(streamID, key string)
+	mu        sync.RWMutex
+}
+
+// previewStream tracks the grab loop of one stream
+type previewStream struct {
+	cancel    context.CancelFunc
+	lastFrame time.Time
+}
+
+// NewLivePreviewer creates a previewer grabbing frames with grabber
+func NewLivePreviewer(config LivePreviewConfig, grabber FrameGrabber, log logger.Logger) *LivePreviewer {
+	if log == nil {
+		log = logger.NewDefaultLogger(logger.InfoLevel, "text")
+	}
+
+	return &LivePreviewer{
+		config:  config,
+		grabber: grabber,
+		logger:  log,
+		streams: make(map[string]*previewStream),
+	}
+}
+
+// OnThumbnailUpdated sets the callback for updated previews
+func (p *LivePreviewer) OnThumbnailUpdated(callback func(streamID, key string)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onUpdated = callback
+}
+
+// PreviewKey returns the storage key of a stream's preview
+func (p *LivePreviewer) PreviewKey(streamID string) string {
+	return fmt.Sprintf("previews/%s.%s", streamID, p.config.Format)
+}
+
+// Start begins grabbing previews of a stream every interval
+func (p *LivePreviewer) Start(streamID string) error {
+	if p.config.Storage == nil {
+		return ErrStorageNotConfigured
+	}
+	if p.config.Interval <= 0 {
+		return fmt.Errorf("invalid preview interval: %v", p.config.Interval)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, exists := p.streams[streamID]; exists {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &previewStream{cancel: cancel}
+	p.streams[streamID] = stream
+
+	go p.run(ctx, streamID, stream)
+
+	return nil
+}
+
+// Stop stops grabbing previews of a stream
+func (p *LivePreviewer) Stop(streamID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if stream, exists := p.streams[streamID]; exists {
+		stream.cancel()
+		delete(p.streams, streamID)
+	}
+}
+
+// run grabs a preview immediately and then every interval
+func (p *LivePreviewer) run(ctx context.Context, streamID string, stream *previewStream) {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Capture(ctx, streamID); err != nil && ctx.Err() == nil {
+			p.logger.Debug("Skipped live preview",
+				logger.Field{Key: "stream_id", Value: streamID},
+				logger.Field{Key: "error", Value: err.Error()},
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Capture grabs a frame of a stream and uploads it as the stream's preview.
+// Frames older than the stall timeout, or not newer than the last preview,
+// are skipped so a stalled stream keeps its last good preview.
+func (p *LivePreviewer) Capture(ctx context.Context, streamID string) error {
+	frame, receivedAt, err := p.grabber(ctx, streamID)
+	if err != nil {
+		return fmt.Errorf("failed to grab frame: %w", err)
+	}
+
+	if p.config.StallTimeout > 0 && time.Since(receivedAt) > p.config.StallTimeout {
+		return fmt.Errorf("stream stalled since %v", receivedAt)
+	}
+
+	p.mu.RLock()
+	stream := p.streams[streamID]
+	stale := stream != nil && !receivedAt.After(stream.lastFrame)
+	p.mu.RUnlock()
+
+	if stale {
+		return fmt.Errorf("no new frame since %v", receivedAt)
+	}
+
+	var buf bytes.Buffer
+	resized := resizeImage(frame, p.config.Size.Width, p.config.Size.Height)
+
+	contentType := "image/jpeg"
+	switch p.config.Format {
+	case "jpeg", "jpg":
+		err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: p.config.Size.Quality})
+	case "png":
+		contentType = "image/png"
+		err = png.Encode(&buf, resized)
+	default:
+		err = fmt.Errorf("unsupported format: %s", p.config.Format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode preview: %w", err)
+	}
+
+	key := p.PreviewKey(streamID)
+	if err := p.config.Storage.Upload(ctx, key, &buf, int64(buf.Len()), contentType); err != nil {
+		return fmt.Errorf("%w: %v", ErrUploadFailed, err)
+	}
+
+	p.mu.Lock()
+	if stream != nil {
+		stream.lastFrame = receivedAt
+	}
+	callback := p.onUpdated
+	p.mu.Unlock()
+
+	if callback != nil {
+		callback(streamID, key)
+	}
+
+	return nil
+}
+
+// Close stops every stream's grab loop
+func (p *LivePreviewer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for streamID, stream := range p.streams {
+		stream.cancel()
+		delete(p.streams, streamID)
+	}
+
+	return nil
+}
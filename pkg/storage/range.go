@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// limitedReadCloser reads from a limited reader and closes the underlying file
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// resolveRange converts a DownloadRange offset and length into an absolute
+// start and byte count for an object of the given size
+func resolveRange(offset, length, size int64) (int64, int64, error) {
+	start := offset
+	if offset < 0 {
+		if size == 0 {
+			return 0, 0, ErrInvalidRange
+		}
+		start = size + offset
+		if start < 0 {
+			start = 0
+		}
+	} else if offset >= size && !(offset == 0 && length <= 0) {
+		return 0, 0, ErrInvalidRange
+	}
+
+	n := size - start
+	if offset >= 0 && length > 0 && length < n {
+		n = length
+	}
+
+	return start, n, nil
+}
+
+// parseRangeHeader parses a single "bytes=" range into a DownloadRange offset
+// and length. Multiple or malformed ranges are reported as not ok so the
+// whole object is served, as permitted by RFC 9110.
+func parseRangeHeader(header string) (offset, length int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	// Suffix range: the last N bytes
+	if first == "" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		return -n, 0, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false
+	}
+
+	if last == "" {
+		return start, 0, true
+	}
+
+	end, err := strconv.ParseInt(last, 10, 64)
+	if err != nil || end < start {
+		return 0, 0, false
+	}
+
+	return start, end - start + 1, true
+}
+
+// RangeHandler serves stored objects over HTTP with support for Range
+// requests. The object key is the request path without its leading slash,
+// so the handler is usually mounted with http.StripPrefix. With a metadata
+// store set, "?recording=<id>&t=<ms>" serves the recording's segment from
+// the keyframe nearest t instead, for instant scrubbing.
+type RangeHandler struct {
+	storage  Storage
+	metadata MetadataStore
+	logger   logger.Logger
+}
+
+// NewRangeHandler creates a new range handler
+func NewRangeHandler(storage Storage, log logger.Logger) *RangeHandler {
+	if log == nil {
+		log = logger.NewDefaultLogger(logger.InfoLevel, "text")
+	}
+
+	return &RangeHandler{
+		storage: storage,
+		logger:  log,
+	}
+}
+
+// SetMetadataStore enables seeking by recording and time using the seek
+// indexes in store
+func (h *RangeHandler) SetMetadataStore(store MetadataStore) {
+	h.metadata = store
+}
+
+// resolveSeek maps a "recording" and "t" query to the segment key and offset
+// of the nearest keyframe, writing an error response if it fails
+func (h *RangeHandler) resolveSeek(w http.ResponseWriter, r *http.Request, recordingID string) (*SeekPoint, bool) {
+	timeMS, err := strconv.ParseInt(r.URL.Query().Get("t"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid seek time", http.StatusBadRequest)
+		return nil, false
+	}
+
+	point, err := Seek(r.Context(), h.metadata, recordingID, timeMS)
+	switch {
+	case err == nil:
+		return point, true
+	case errors.Is(err, ErrObjectNotFound), errors.Is(err, ErrNoSeekIndex):
+		http.NotFound(w, r)
+	case errors.Is(err, ErrInvalidSeek):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		h.logger.Error("Failed to seek recording",
+			logger.Field{Key: "recording_id", Value: recordingID},
+			logger.Field{Key: "error", Value: err},
+		)
+		http.Error(w, "seek failed", http.StatusInternalServerError)
+	}
+	return nil, false
+}
+
+// servableKey reports whether a requested key may be served. Keys must not
+// climb out of the storage root or name a metadata sidecar file.
+func servableKey(key string) bool {
+	if strings.HasSuffix(key, metaFileSuffix) {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// ServeHTTP serves the requested object or byte range
+func (h *RangeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	if !servableKey(key) {
+		http.Error(w, ErrInvalidObjectKey.Error(), http.StatusBadRequest)
+		return
+	}
+	offset, length, partial := parseRangeHeader(r.Header.Get("Range"))
+
+	if recordingID := r.URL.Query().Get("recording"); recordingID != "" && h.metadata != nil {
+		point, ok := h.resolveSeek(w, r, recordingID)
+		if !ok {
+			return
+		}
+		key, offset, length, partial = point.SegmentKey, point.Offset, 0, true
+		w.Header().Set("X-Seek-Timestamp", strconv.FormatInt(point.Timestamp.Milliseconds(), 10))
+	}
+
+	if key == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	objectRange, err := h.storage.DownloadRange(r.Context(), key, offset, length)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrObjectNotFound):
+		http.NotFound(w, r)
+		return
+	case errors.Is(err, ErrInvalidObjectKey):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, ErrInvalidRange) && partial:
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return
+	case errors.Is(err, ErrInvalidRange):
+		// Full download of an empty object
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(http.StatusOK)
+		return
+	default:
+		h.logger.Error("Failed to download object",
+			logger.Field{Key: "key", Value: key},
+			logger.Field{Key: "error", Value: err},
+		)
+		http.Error(w, "download failed", http.StatusInternalServerError)
+		return
+	}
+	defer objectRange.Close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(objectRange.Length, 10))
+
+	status := http.StatusOK
+	if partial {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d",
+			objectRange.Offset, objectRange.Offset+objectRange.Length-1, objectRange.Size))
+		status = http.StatusPartialContent
+	}
+	w.WriteHeader(status)
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if _, err := io.Copy(w, objectRange); err != nil {
+		h.logger.Warn("Failed to write object response",
+			logger.Field{Key: "key", Value: key},
+			logger.Field{Key: "error", Value: err},
+		)
+	}
+}
@@ -20,6 +20,21 @@ type BaseRecorder struct {
 	segments       []SegmentInfo
 	currentFile    *os.File
 	currentSegment *SegmentInfo
+	buffer         *SpillBuffer
+
+	// segmentBytes counts the bytes written to the current segment
+	segmentBytes int64
+	// seekIndex lists the keyframes written, in timestamp order
+	seekIndex []SeekPoint
+
+	// drainSignal wakes the drain loop after data is buffered
+	drainSignal chan struct{}
+	// drainStop stops the drain loop of the running recording
+	drainStop chan struct{}
+	// fileMu serializes writes to currentFile so the drain loop can write
+	// without holding mu. It is taken after mu; currentFile is only
+	// replaced with both held.
+	fileMu sync.Mutex
 
 	mu     sync.RWMutex
 	logger logger.Logger
@@ -28,6 +43,7 @@ type BaseRecorder struct {
 	onSegmentComplete func(segment SegmentInfo)
 	onThumbnail       func(thumbnail ThumbnailInfo)
 	onError           func(error)
+	onEvent           func(event RecordingEvent)
 }
 
 // NewBaseRecorder creates a new base recorder
@@ -40,6 +56,9 @@ func NewBaseRecorder(config RecordingConfig, log logger.Logger) *BaseRecorder {
 		config:   config,
 		logger:   log,
 		segments: make([]SegmentInfo, 0),
+		buffer:   NewSpillBuffer(config.MaxMemoryBuffer, config.SpillDir),
+		// Buffered so a write never waits for the drain loop
+		drainSignal: make(chan struct{}, 1),
 		info: RecordingInfo{
 			ID:       uuid.New().String(),
 			StreamID: config.StreamID,
@@ -73,9 +92,13 @@ func (r *BaseRecorder) Start(ctx context.Context) error {
 	if err := r.createNewSegment(); err != nil {
 		r.info.State = StateError
 		r.info.Error = err
+		r.emit(RecordingEventFailed, nil, err)
 		return err
 	}
 
+	r.startDrain()
+	r.emit(RecordingEventStarted, nil, nil)
+
 	return nil
 }
 
@@ -88,10 +111,13 @@ func (r *BaseRecorder) Stop(ctx context.Context) error {
 		return ErrRecordingNotStarted
 	}
 
+	r.stopDrain()
+
 	// Finalize current segment
-	if err := r.finalizeCurrentSegment(); err != nil {
+	finalizeErr := r.finalizeCurrentSegment()
+	if finalizeErr != nil {
 		r.logger.Error("Failed to finalize segment",
-			logger.Field{Key: "error", Value: err},
+			logger.Field{Key: "error", Value: finalizeErr},
 		)
 	}
 
@@ -99,6 +125,12 @@ func (r *BaseRecorder) Stop(ctx context.Context) error {
 	r.info.EndTime = time.Now()
 	r.info.Duration = r.info.EndTime.Sub(r.info.StartTime)
 
+	if finalizeErr != nil {
+		r.emit(RecordingEventFailed, nil, finalizeErr)
+	} else {
+		r.emit(RecordingEventCompleted, nil, nil)
+	}
+
 	r.logger.Info("Recording stopped",
 		logger.Field{Key: "recording_id", Value: r.info.ID},
 		logger.Field{Key: "duration", Value: r.info.Duration},
@@ -111,6 +143,12 @@ func (r *BaseRecorder) Stop(ctx context.Context) error {
 		go r.uploadPendingSegments(context.Background())
 	}
 
+	if r.config.MetadataStore != nil && len(r.seekIndex) > 0 {
+		index := make([]SeekPoint, len(r.seekIndex))
+		copy(index, r.seekIndex)
+		r.saveSeekIndex(ctx, r.config.MetadataStore, r.info.ID, index)
+	}
+
 	return nil
 }
 
@@ -150,6 +188,180 @@ func (r *BaseRecorder) Resume(ctx context.Context) error {
 	return nil
 }
 
+// WriteData buffers media data for the current segment. The buffer drains
+// into the segment file in the background; data is held in memory up to
+// MaxMemoryBuffer while the file falls behind and spills to disk beyond that.
+func (r *BaseRecorder) WriteData(data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.info.State == StatePaused {
+		return ErrRecordingPaused
+	}
+
+	if r.info.State != StateRecording || r.currentFile == nil {
+		return ErrRecordingNotStarted
+	}
+
+	return r.writeData(data)
+}
+
+// WriteKeyframe buffers media data that starts with a keyframe, adding it to
+// the recording's seek index at its media timestamp. A segment due to
+// rotate rotates first, so the keyframe starts the new segment.
+func (r *BaseRecorder) WriteKeyframe(data []byte, timestamp time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.info.State == StatePaused {
+		return ErrRecordingPaused
+	}
+
+	if r.info.State != StateRecording || r.currentFile == nil {
+		return ErrRecordingNotStarted
+	}
+
+	if r.shouldRotateSegment() {
+		if err := r.createNewSegment(); err != nil {
+			r.emit(RecordingEventFailed, nil, err)
+			return err
+		}
+	}
+
+	r.seekIndex = append(r.seekIndex, SeekPoint{
+		Timestamp:  timestamp,
+		Segment:    r.currentSegment.Index,
+		SegmentKey: segmentRemotePath(r.info.StreamID, r.currentSegment.Path),
+		Offset:     r.segmentBytes,
+	})
+
+	return r.writeData(data)
+}
+
+// writeData buffers data and rotates the segment when it is full. Caller
+// must hold r.mu.
+func (r *BaseRecorder) writeData(data []byte) error {
+	if _, err := r.buffer.Write(data); err != nil {
+		return fmt.Errorf("failed to buffer recording data: %w", err)
+	}
+	r.segmentBytes += int64(len(data))
+
+	if r.shouldRotateSegment() {
+		if err := r.createNewSegment(); err != nil {
+			r.emit(RecordingEventFailed, nil, err)
+			return err
+		}
+	}
+
+	select {
+	case r.drainSignal <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// startDrain starts the loop that drains the buffer into the segment file
+// as data arrives. Caller must hold r.mu.
+func (r *BaseRecorder) startDrain() {
+	r.drainStop = make(chan struct{})
+	go r.drainLoop(r.drainStop)
+}
+
+// stopDrain stops the drain loop, leaving any remaining data for the next
+// flush. Caller must hold r.mu.
+func (r *BaseRecorder) stopDrain() {
+	if r.drainStop != nil {
+		close(r.drainStop)
+		r.drainStop = nil
+	}
+}
+
+// drainLoop drains the buffer each time data is written until stop is closed
+func (r *BaseRecorder) drainLoop(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-r.drainSignal:
+		}
+
+		r.fileMu.Lock()
+		err := r.drainBuffer()
+		r.fileMu.Unlock()
+
+		if err != nil {
+			r.logger.Error("Failed to drain recording buffer",
+				logger.Field{Key: "recording_id", Value: r.GetInfo().ID},
+				logger.Field{Key: "error", Value: err},
+			)
+		}
+	}
+}
+
+// GetSeekIndex returns the keyframes written so far
+func (r *BaseRecorder) GetSeekIndex() []SeekPoint {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	index := make([]SeekPoint, len(r.seekIndex))
+	copy(index, r.seekIndex)
+	return index
+}
+
+// saveSeekIndex stores the seek index in the recording's metadata
+func (r *BaseRecorder) saveSeekIndex(ctx context.Context, store MetadataStore, recordingID string, index []SeekPoint) {
+	metadata, err := store.Get(ctx, recordingID)
+	if err == nil {
+		metadata.SeekIndex = index
+		metadata.SegmentHeader = r.config.SegmentHeader
+		err = store.Update(ctx, metadata)
+	}
+
+	if err != nil {
+		r.logger.Error("Failed to save seek index",
+			logger.Field{Key: "recording_id", Value: recordingID},
+			logger.Field{Key: "error", Value: err},
+		)
+	}
+}
+
+// Flush writes all buffered data to the current segment file
+func (r *BaseRecorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.flushBuffer()
+}
+
+// GetBufferedBytes returns the number of buffered bytes not yet written to the segment file
+func (r *BaseRecorder) GetBufferedBytes() int64 {
+	return r.buffer.Len()
+}
+
+// flushBuffer drains the buffer into the current segment file. Caller must
+// hold r.mu.
+func (r *BaseRecorder) flushBuffer() error {
+	r.fileMu.Lock()
+	defer r.fileMu.Unlock()
+
+	return r.drainBuffer()
+}
+
+// drainBuffer drains the buffer into the current segment file. Caller must
+// hold r.fileMu.
+func (r *BaseRecorder) drainBuffer() error {
+	if r.currentFile == nil {
+		return nil
+	}
+
+	if _, err := r.buffer.WriteTo(r.currentFile); err != nil {
+		return fmt.Errorf("failed to flush recording buffer: %w", err)
+	}
+
+	return nil
+}
+
 // GetInfo returns current recording information
 func (r *BaseRecorder) GetInfo() RecordingInfo {
 	r.mu.RLock()
@@ -178,13 +390,26 @@ func (r *BaseRecorder) Close() error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	r.stopDrain()
+
 	if r.currentFile != nil {
+		if err := r.flushBuffer(); err != nil {
+			r.logger.Error("Failed to flush recording buffer",
+				logger.Field{Key: "error", Value: err},
+			)
+		}
 		if err := r.currentFile.Close(); err != nil {
 			r.logger.Error("Failed to close current file",
 				logger.Field{Key: "error", Value: err},
 			)
 		}
-		r.currentFile = nil
+		r.setCurrentFile(nil)
+	}
+
+	if err := r.buffer.Close(); err != nil {
+		r.logger.Error("Failed to release recording buffer",
+			logger.Field{Key: "error", Value: err},
+		)
 	}
 
 	r.logger.Info("Recorder closed",
@@ -215,6 +440,40 @@ func (r *BaseRecorder) SetOnError(callback func(error)) {
 	r.onError = callback
 }
 
+// SetOnEvent sets the callback for recording lifecycle events. It is called
+// synchronously, in event order, with the recorder locked, so it must not
+// block or call back into the recorder; sdk.EventBus.PublishRecordingEvent
+// only queues the event.
+func (r *BaseRecorder) SetOnEvent(callback func(RecordingEvent)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onEvent = callback
+}
+
+// emit reports a lifecycle event to the event callback. Caller must hold r.mu.
+func (r *BaseRecorder) emit(eventType RecordingEventType, segment *SegmentInfo, err error) {
+	if r.onEvent == nil {
+		return
+	}
+
+	event := RecordingEvent{
+		Type:      eventType,
+		Recording: r.info,
+		Error:     err,
+		Timestamp: time.Now(),
+	}
+	if segment != nil {
+		finalized := *segment
+		event.Segment = &finalized
+	}
+	if eventType == RecordingEventCompleted || eventType == RecordingEventUploaded {
+		event.Segments = make([]SegmentInfo, len(r.segments))
+		copy(event.Segments, r.segments)
+	}
+
+	r.onEvent(event)
+}
+
 // createNewSegment creates a new recording segment
 func (r *BaseRecorder) createNewSegment() error {
 	// Close current segment if exists
@@ -243,7 +502,15 @@ func (r *BaseRecorder) createNewSegment() error {
 		return fmt.Errorf("failed to create segment file: %w", err)
 	}
 
-	r.currentFile = file
+	r.setCurrentFile(file)
+	r.segmentBytes = 0
+
+	if len(r.config.SegmentHeader) > 0 {
+		if _, err := r.buffer.Write(r.config.SegmentHeader); err != nil {
+			return fmt.Errorf("failed to buffer segment header: %w", err)
+		}
+		r.segmentBytes = int64(len(r.config.SegmentHeader))
+	}
 	r.currentSegment = &SegmentInfo{
 		Index:     segmentIndex,
 		Path:      segmentPath,
@@ -260,12 +527,25 @@ func (r *BaseRecorder) createNewSegment() error {
 	return nil
 }
 
+// setCurrentFile replaces the current segment file. Caller must hold r.mu.
+func (r *BaseRecorder) setCurrentFile(file *os.File) {
+	r.fileMu.Lock()
+	defer r.fileMu.Unlock()
+
+	r.currentFile = file
+}
+
 // finalizeCurrentSegment finalizes the current segment
 func (r *BaseRecorder) finalizeCurrentSegment() error {
 	if r.currentFile == nil || r.currentSegment == nil {
 		return nil
 	}
 
+	// Flush buffered data before closing
+	if err := r.flushBuffer(); err != nil {
+		return err
+	}
+
 	// Sync and close file
 	if err := r.currentFile.Sync(); err != nil {
 		return fmt.Errorf("failed to sync segment file: %w", err)
@@ -275,7 +555,7 @@ func (r *BaseRecorder) finalizeCurrentSegment() error {
 		return fmt.Errorf("failed to close segment file: %w", err)
 	}
 
-	r.currentFile = nil
+	r.setCurrentFile(nil)
 
 	// Get file size
 	stat, err := os.Stat(r.currentSegment.Path)
@@ -299,6 +579,8 @@ func (r *BaseRecorder) finalizeCurrentSegment() error {
 		logger.Field{Key: "duration", Value: r.currentSegment.Duration},
 	)
 
+	r.emit(RecordingEventSegment, r.currentSegment, nil)
+
 	// Call segment complete callback
 	if r.onSegmentComplete != nil {
 		segment := *r.currentSegment
@@ -330,10 +612,10 @@ func (r *BaseRecorder) shouldRotateSegment() bool {
 		}
 	}
 
-	// Check size limit
+	// Check size limit, including data still buffered
 	if r.config.MaxSegmentSize > 0 && r.currentFile != nil {
 		stat, err := r.currentFile.Stat()
-		if err == nil && stat.Size() >= r.config.MaxSegmentSize {
+		if err == nil && stat.Size()+r.buffer.Len() >= r.config.MaxSegmentSize {
 			return true
 		}
 	}
@@ -341,10 +623,10 @@ func (r *BaseRecorder) shouldRotateSegment() bool {
 	return false
 }
 
-// uploadSegment uploads a segment to storage
-func (r *BaseRecorder) uploadSegment(ctx context.Context, segment *SegmentInfo) {
+// uploadSegment uploads a segment to storage and marks it uploaded
+func (r *BaseRecorder) uploadSegment(ctx context.Context, segment *SegmentInfo) error {
 	if segment.Uploaded {
-		return
+		return nil
 	}
 
 	file, err := os.Open(segment.Path)
@@ -353,13 +635,12 @@ func (r *BaseRecorder) uploadSegment(ctx context.Context, segment *SegmentInfo)
 			logger.Field{Key: "error", Value: err},
 			logger.Field{Key: "path", Value: segment.Path},
 		)
-		return
+		return err
 	}
 	defer file.Close()
 
 	// Generate remote path
-	remotePath := fmt.Sprintf("recordings/%s/segments/%s",
-		r.info.StreamID, filepath.Base(segment.Path))
+	remotePath := segmentRemotePath(r.info.StreamID, segment.Path)
 
 	// Upload to storage
 	err = r.config.Storage.Upload(ctx, remotePath, file, segment.Size, "video/mp4")
@@ -368,29 +649,53 @@ func (r *BaseRecorder) uploadSegment(ctx context.Context, segment *SegmentInfo)
 			logger.Field{Key: "error", Value: err},
 			logger.Field{Key: "path", Value: remotePath},
 		)
-		return
+		return err
 	}
 
 	segment.RemotePath = remotePath
 	segment.Uploaded = true
 
+	r.mu.Lock()
+	for i := range r.segments {
+		if r.segments[i].Index == segment.Index {
+			r.segments[i].RemotePath = remotePath
+			r.segments[i].Uploaded = true
+		}
+	}
+	r.mu.Unlock()
+
 	r.logger.Info("Segment uploaded",
 		logger.Field{Key: "recording_id", Value: r.info.ID},
 		logger.Field{Key: "segment_index", Value: segment.Index},
 		logger.Field{Key: "remote_path", Value: remotePath},
 	)
+
+	return nil
 }
 
-// uploadPendingSegments uploads all pending segments
+// uploadPendingSegments uploads all pending segments, then emits an
+// uploaded event, or a failed event with the first upload error
 func (r *BaseRecorder) uploadPendingSegments(ctx context.Context) {
 	r.mu.RLock()
 	segments := make([]SegmentInfo, len(r.segments))
 	copy(segments, r.segments)
 	r.mu.RUnlock()
 
+	var uploadErr error
 	for i := range segments {
 		if !segments[i].Uploaded {
-			r.uploadSegment(ctx, &segments[i])
+			if err := r.uploadSegment(ctx, &segments[i]); err != nil && uploadErr == nil {
+				uploadErr = err
+			}
 		}
 	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if uploadErr != nil {
+		r.emit(RecordingEventFailed, nil, fmt.Errorf("failed to upload recording: %w", uploadErr))
+		return
+	}
+	r.emit(RecordingEventUploaded, nil, nil)
 }
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// RecordingFinalizer is implemented by recorders that must write a container
+// trailer (such as an MP4 moov atom) after their last segment is finalized
+type RecordingFinalizer interface {
+	Finalize(ctx context.Context) error
+}
+
+// RecorderRegistry tracks open recorders so they can be finalized on shutdown
+type RecorderRegistry struct {
+	recorders map[string]Recorder
+	mu        sync.RWMutex
+	logger    logger.Logger
+}
+
+// NewRecorderRegistry creates a new recorder registry
+func NewRecorderRegistry(log logger.Logger) *RecorderRegistry {
+	if log == nil {
+		log = logger.NewDefaultLogger(logger.InfoLevel, "text")
+	}
+
+	return &RecorderRegistry{
+		recorders: make(map[string]Recorder),
+		logger:    log,
+	}
+}
+
+// Register tracks a recorder under its recording ID
+func (reg *RecorderRegistry) Register(recorder Recorder) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	reg.recorders[recorder.GetInfo().ID] = recorder
+}
+
+// Unregister stops tracking a recorder
+func (reg *RecorderRegistry) Unregister(recordingID string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	delete(reg.recorders, recordingID)
+}
+
+// Get returns a tracked recorder by recording ID
+func (reg *RecorderRegistry) Get(recordingID string) (Recorder, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	recorder, ok := reg.recorders[recordingID]
+	return recorder, ok
+}
+
+// Len returns the number of tracked recorders
+func (reg *RecorderRegistry) Len() int {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	return len(reg.recorders)
+}
+
+// FinalizeAll stops every tracked recorder, flushing buffered data and
+// writing trailers, then closes and unregisters it. Recorders are finalized
+// concurrently; if ctx expires first, FinalizeAll returns without waiting
+// for the remaining recorders.
+func (reg *RecorderRegistry) FinalizeAll(ctx context.Context) error {
+	reg.mu.Lock()
+	recorders := make([]Recorder, 0, len(reg.recorders))
+	for _, recorder := range reg.recorders {
+		recorders = append(recorders, recorder)
+	}
+	reg.recorders = make(map[string]Recorder)
+	reg.mu.Unlock()
+
+	if len(recorders) == 0 {
+		return nil
+	}
+
+	reg.logger.Info("Finalizing active recordings",
+		logger.Field{Key: "count", Value: len(recorders)},
+	)
+
+	errs := make([]error, len(recorders))
+	var wg sync.WaitGroup
+
+	for i, recorder := range recorders {
+		wg.Add(1)
+		go func(i int, recorder Recorder) {
+			defer wg.Done()
+			errs[i] = reg.finalize(ctx, recorder)
+		}(i, recorder)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return errors.Join(errs...)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// finalize stops and closes a single recorder
+func (reg *RecorderRegistry) finalize(ctx context.Context, recorder Recorder) error {
+	info := recorder.GetInfo()
+
+	var result error
+	if info.State == StateRecording || info.State == StatePaused {
+		if err := recorder.Stop(ctx); err != nil && !errors.Is(err, ErrRecordingNotStarted) {
+			result = fmt.Errorf("recording %s: stop: %w", info.ID, err)
+		}
+
+		if finalizer, ok := recorder.(RecordingFinalizer); ok && result == nil {
+			if err := finalizer.Finalize(ctx); err != nil {
+				result = fmt.Errorf("recording %s: finalize: %w", info.ID, err)
+			}
+		}
+	}
+
+	if err := recorder.Close(); err != nil {
+		result = errors.Join(result, fmt.Errorf("recording %s: close: %w", info.ID, err))
+	}
+
+	if result != nil {
+		reg.logger.Error("Failed to finalize recording",
+			logger.Field{Key: "recording_id", Value: info.ID},
+			logger.Field{Key: "error", Value: result},
+		)
+		return result
+	}
+
+	reg.logger.Info("Recording finalized",
+		logger.Field{Key: "recording_id", Value: info.ID},
+		logger.Field{Key: "segments", Value: len(recorder.GetSegments())},
+	)
+
+	return nil
+}
@@ -21,9 +21,10 @@ import (
 
 // S3Storage implements AWS S3 storage backend
 type S3Storage struct {
-	client *s3.Client
-	config StorageConfig
-	logger logger.Logger
+	client   *s3.Client
+	config   StorageConfig
+	throttle *UploadThrottle
+	logger   logger.Logger
 }
 
 // NewS3Storage creates a new S3 storage backend
@@ -78,9 +79,10 @@ func NewS3Storage(cfg StorageConfig, log logger.Logger) (*S3Storage, error) {
 	client := s3.NewFromConfig(awsConfig, s3Options...)
 
 	return &S3Storage{
-		client: client,
-		config: cfg,
-		logger: log,
+		client:   client,
+		config:   cfg,
+		throttle: NewUploadThrottle(cfg.MaxUploadBytesPerSec),
+		logger:   log,
 	}, nil
 }
 
@@ -92,6 +94,43 @@ func (s *S3Storage) Upload(ctx context.Context, key string, data io.Reader, size
 		return fmt.Errorf("failed to read data: %w", err)
 	}
 
+	var contentHash string
+	if s.config.ContentHash {
+		contentHash = hashBytes(buf.Bytes())
+	}
+
+	return s.upload(ctx, key, buf.Bytes(), size, contentType, contentHash)
+}
+
+// UploadIfAbsent uploads data unless the stored object already has the same content hash
+func (s *S3Storage) UploadIfAbsent(ctx context.Context, key string, data io.Reader, size int64, contentType string) (bool, error) {
+	content, contentHash, err := readAndHash(data)
+	if err != nil {
+		return false, err
+	}
+
+	if existing, err := s.Stat(ctx, key); err == nil && existing.Hash == contentHash {
+		s.logger.Debug("Skipping upload of identical S3 object",
+			logger.Field{Key: "key", Value: key},
+			logger.Field{Key: "hash", Value: contentHash},
+		)
+		return false, nil
+	}
+
+	if err := s.upload(ctx, key, content, size, contentType, contentHash); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// upload puts content to S3 with retries, recording contentHash in the object metadata if set
+func (s *S3Storage) upload(ctx context.Context, key string, content []byte, size int64, contentType string, contentHash string) error {
+	var metadata map[string]string
+	if contentHash != "" {
+		metadata = map[string]string{MetadataContentHash: contentHash}
+	}
+
 	// Retry logic
 	var lastErr error
 	for attempt := 0; attempt <= s.config.MaxRetries; attempt++ {
@@ -105,10 +144,12 @@ func (s *S3Storage) Upload(ctx context.Context, key string, data io.Reader, size
 
 		// Create upload input
 		input := &s3.PutObjectInput{
-			Bucket:      aws.String(s.config.Bucket),
-			Key:         aws.String(s.normalizeKey(key)),
-			Body:        bytes.NewReader(buf.Bytes()),
-			ContentType: aws.String(contentType),
+			Bucket:        aws.String(s.config.Bucket),
+			Key:           aws.String(s.normalizeKey(key)),
+			Body:          s.throttledBody(ctx, content),
+			ContentLength: aws.Int64(int64(len(content))),
+			ContentType:   aws.String(contentType),
+			Metadata:      metadata,
 		}
 
 		// Upload to S3
@@ -148,6 +189,50 @@ func (s *S3Storage) Download(ctx context.Context, key string) (io.ReadCloser, er
 	return result.Body, nil
 }
 
+// DownloadRange downloads a byte range from S3
+func (s *S3Storage) DownloadRange(ctx context.Context, key string, offset, length int64) (*ObjectRange, error) {
+	var rangeHeader string
+	switch {
+	case offset < 0:
+		rangeHeader = fmt.Sprintf("bytes=%d", offset)
+	case length > 0:
+		rangeHeader = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	default:
+		rangeHeader = fmt.Sprintf("bytes=%d-", offset)
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(s.normalizeKey(key)),
+		Range:  aws.String(rangeHeader),
+	}
+
+	result, err := s.client.GetObject(ctx, input)
+	if err != nil {
+		if s.isNotFoundError(err) {
+			return nil, ErrObjectNotFound
+		}
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidRange" {
+			return nil, ErrInvalidRange
+		}
+		return nil, fmt.Errorf("failed to download range from S3: %w", err)
+	}
+
+	objectRange := &ObjectRange{
+		ReadCloser: result.Body,
+		Length:     aws.ToInt64(result.ContentLength),
+	}
+
+	// Content-Range has the form "bytes start-end/size"
+	var end int64
+	if _, err := fmt.Sscanf(aws.ToString(result.ContentRange), "bytes %d-%d/%d", &objectRange.Offset, &end, &objectRange.Size); err != nil {
+		objectRange.Size = objectRange.Offset + objectRange.Length
+	}
+
+	return objectRange, nil
+}
+
 // Delete removes an object from S3
 func (s *S3Storage) Delete(ctx context.Context, key string) error {
 	input := &s3.DeleteObjectInput{
@@ -228,6 +313,50 @@ func (s *S3Storage) List(ctx context.Context, prefix string, maxKeys int) ([]Sto
 	return objects, nil
 }
 
+// UploadThroughput returns the recent upload rate in bytes per second
+func (s *S3Storage) UploadThroughput() float64 {
+	return s.throttle.Throughput()
+}
+
+// throttledBody wraps content in a seekable reader paced by the upload throttle
+func (s *S3Storage) throttledBody(ctx context.Context, content []byte) io.ReadSeeker {
+	body := bytes.NewReader(content)
+	return &throttledReadSeeker{
+		throttledReader: newThrottledReader(ctx, body, s.throttle),
+		seeker:          body,
+	}
+}
+
+// Stat returns size, hash, content type and modification time of an S3 object
+func (s *S3Storage) Stat(ctx context.Context, key string) (*StorageObject, error) {
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(s.normalizeKey(key)),
+	}
+
+	result, err := s.client.HeadObject(ctx, input)
+	if err != nil {
+		if s.isNotFoundError(err) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+
+	metadata := make(map[string]string)
+	for k, v := range result.Metadata {
+		metadata[k] = v
+	}
+
+	return &StorageObject{
+		Key:          key,
+		Size:         aws.ToInt64(result.ContentLength),
+		LastModified: aws.ToTime(result.LastModified),
+		ContentType:  aws.ToString(result.ContentType),
+		Hash:         metadata[MetadataContentHash],
+		Metadata:     metadata,
+	}, nil
+}
+
 // GetMetadata retrieves metadata for an S3 object
 func (s *S3Storage) GetMetadata(ctx context.Context, key string) (map[string]string, error) {
 	input := &s3.HeadObjectInput{
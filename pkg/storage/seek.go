@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// SeekPoint is a keyframe in a recording: the media timestamp it is
+// presented at and where it starts in its segment, so players can start
+// decoding there without scanning the segment
+type SeekPoint struct {
+	Timestamp  time.Duration
+	Segment    int
+	SegmentKey string // storage key of the uploaded segment
+	Offset     int64  // byte offset of the keyframe within the segment
+}
+
+// segmentRemotePath returns the storage key a segment is uploaded to
+func segmentRemotePath(streamID, path string) string {
+	return fmt.Sprintf("recordings/%s/segments/%s", streamID, filepath.Base(path))
+}
+
+// Seek returns the keyframe a player should start from to play a recording
+// at timeMS: the last keyframe at or before it, or the first keyframe if
+// timeMS precedes it
+func Seek(ctx context.Context, store MetadataStore, recordingID string, timeMS int64) (*SeekPoint, error) {
+	if timeMS < 0 {
+		return nil, ErrInvalidSeek
+	}
+
+	metadata, err := store.Get(ctx, recordingID)
+	if err != nil {
+		return nil, err
+	}
+
+	index := metadata.SeekIndex
+	if len(index) == 0 {
+		return nil, ErrNoSeekIndex
+	}
+
+	at := time.Duration(timeMS) * time.Millisecond
+	i := sort.Search(len(index), func(i int) bool {
+		return index[i].Timestamp > at
+	})
+	if i > 0 {
+		i--
+	}
+
+	point := index[i]
+	return &point, nil
+}
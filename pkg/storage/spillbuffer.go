@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// SpillBuffer buffers recording data in memory and spills it to a temporary
+// file once the memory watermark is exceeded. Draining the buffer removes the
+// spill file so subsequent writes are held in memory again.
+type SpillBuffer struct {
+	maxMemory int64
+	tempDir   string
+
+	mem     bytes.Buffer
+	spill   *os.File
+	spilled int64
+	// draining counts the bytes detached by a running WriteTo
+	draining int64
+
+	mu sync.Mutex
+}
+
+// NewSpillBuffer creates a spill buffer. A maxMemory of 0 or less never spills.
+// An empty tempDir uses the system temporary directory.
+func NewSpillBuffer(maxMemory int64, tempDir string) *SpillBuffer {
+	return &SpillBuffer{
+		maxMemory: maxMemory,
+		tempDir:   tempDir,
+	}
+}
+
+// Write appends data to the buffer, spilling to disk if the watermark is exceeded
+func (b *SpillBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.spill != nil {
+		n, err := b.spill.Write(p)
+		b.spilled += int64(n)
+		return n, err
+	}
+
+	if b.maxMemory > 0 && int64(b.mem.Len()+len(p)) > b.maxMemory {
+		if err := b.startSpill(); err != nil {
+			return 0, err
+		}
+
+		n, err := b.spill.Write(p)
+		b.spilled += int64(n)
+		return n, err
+	}
+
+	return b.mem.Write(p)
+}
+
+// startSpill moves the in-memory data to a new temporary file
+func (b *SpillBuffer) startSpill() error {
+	file, err := os.CreateTemp(b.tempDir, "zenlive-spill-*")
+	if err != nil {
+		return fmt.Errorf("failed to create spill file: %w", err)
+	}
+
+	n, err := b.mem.WriteTo(file)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to spill buffer: %w", err)
+	}
+
+	b.spill = file
+	b.spilled = n
+
+	return nil
+}
+
+// WriteTo drains all buffered data into w in write order and releases the
+// spill file. The data is detached from the buffer before it is copied, so
+// writes made while WriteTo runs are buffered rather than blocked; calls to
+// WriteTo must not overlap.
+func (b *SpillBuffer) WriteTo(w io.Writer) (int64, error) {
+	b.mu.Lock()
+	spill := b.spill
+	mem := b.mem
+	b.spill = nil
+	b.mem = bytes.Buffer{}
+	b.draining = int64(mem.Len()) + b.spilled
+	b.spilled = 0
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		b.draining = 0
+		b.mu.Unlock()
+	}()
+
+	var total int64
+
+	if spill != nil {
+		_, err := spill.Seek(0, io.SeekStart)
+		if err == nil {
+			var n int64
+			n, err = io.Copy(w, spill)
+			total += n
+		}
+		releaseErr := releaseSpillFile(spill)
+		if err != nil {
+			return total, fmt.Errorf("failed to drain spill file: %w", err)
+		}
+		if releaseErr != nil {
+			return total, releaseErr
+		}
+	}
+
+	n, err := mem.WriteTo(w)
+	total += n
+
+	return total, err
+}
+
+// releaseSpill closes and removes the spill file
+func (b *SpillBuffer) releaseSpill() error {
+	if b.spill == nil {
+		return nil
+	}
+
+	err := releaseSpillFile(b.spill)
+	b.spill = nil
+	b.spilled = 0
+
+	return err
+}
+
+// releaseSpillFile closes and removes a spill file
+func releaseSpillFile(file *os.File) error {
+	name := file.Name()
+	closeErr := file.Close()
+	removeErr := os.Remove(name)
+
+	if closeErr != nil {
+		return closeErr
+	}
+
+	return removeErr
+}
+
+// Len returns the total number of buffered bytes, including bytes being
+// drained by WriteTo
+func (b *SpillBuffer) Len() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return int64(b.mem.Len()) + b.spilled + b.draining
+}
+
+// MemoryLen returns the number of bytes held in memory
+func (b *SpillBuffer) MemoryLen() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return int64(b.mem.Len())
+}
+
+// IsSpilled returns whether buffered data currently lives on disk
+func (b *SpillBuffer) IsSpilled() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.spill != nil
+}
+
+// Close discards buffered data and removes any spill file
+func (b *SpillBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.mem.Reset()
+
+	return b.releaseSpill()
+}
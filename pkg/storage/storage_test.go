@@ -1,6 +1,16 @@
 package storage
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -193,6 +203,213 @@ func TestBaseRecorder(t *testing.T) {
 	recorder.Close()
 }
 
+func TestSpillBuffer(t *testing.T) {
+	buffer := NewSpillBuffer(8, t.TempDir())
+	defer buffer.Close()
+
+	buffer.Write([]byte("abcd"))
+	if buffer.IsSpilled() {
+		t.Error("Expected buffer to stay in memory below watermark")
+	}
+
+	buffer.Write([]byte("efghij"))
+	if !buffer.IsSpilled() {
+		t.Error("Expected buffer to spill above watermark")
+	}
+
+	if buffer.MemoryLen() != 0 {
+		t.Errorf("Expected no bytes in memory after spill, got %d", buffer.MemoryLen())
+	}
+
+	if buffer.Len() != 10 {
+		t.Errorf("Expected 10 buffered bytes, got %d", buffer.Len())
+	}
+
+	var out bytes.Buffer
+	if _, err := buffer.WriteTo(&out); err != nil {
+		t.Fatalf("Failed to drain buffer: %v", err)
+	}
+
+	if out.String() != "abcdefghij" {
+		t.Errorf("Expected drained data 'abcdefghij', got %q", out.String())
+	}
+
+	if buffer.IsSpilled() || buffer.Len() != 0 {
+		t.Error("Expected buffer to return to memory after drain")
+	}
+
+	buffer.Write([]byte("xy"))
+	if buffer.IsSpilled() {
+		t.Error("Expected writes after drain to be held in memory")
+	}
+}
+
+func TestBaseRecorderWriteData(t *testing.T) {
+	config := DefaultRecordingConfig()
+	config.StreamID = "test-stream"
+	config.OutputPath = t.TempDir()
+	config.MaxMemoryBuffer = 4
+	config.SpillDir = t.TempDir()
+
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	recorder := NewBaseRecorder(config, log)
+	defer recorder.Close()
+
+	if err := recorder.WriteData([]byte("data")); err != ErrRecordingNotStarted {
+		t.Errorf("Expected ErrRecordingNotStarted, got %v", err)
+	}
+
+	ctx := context.Background()
+	if err := recorder.Start(ctx); err != nil {
+		t.Fatalf("Failed to start recording: %v", err)
+	}
+
+	if err := recorder.WriteData([]byte("segment-data")); err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
+
+	// The buffer drains into the segment file without waiting for a flush
+	deadline := time.Now().Add(2 * time.Second)
+	for recorder.GetBufferedBytes() != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if recorder.GetBufferedBytes() != 0 {
+		t.Errorf("Expected buffer to drain in the background, got %d bytes", recorder.GetBufferedBytes())
+	}
+	files, _ := filepath.Glob(filepath.Join(config.OutputPath, "*"))
+	if len(files) != 1 {
+		t.Fatalf("Expected one segment file, got %v", files)
+	}
+	if data, _ := os.ReadFile(files[0]); string(data) != "segment-data" {
+		t.Errorf("Expected drained data in the segment file, got %q", data)
+	}
+
+	if err := recorder.WriteData([]byte("-more")); err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
+
+	if err := recorder.Stop(ctx); err != nil {
+		t.Fatalf("Failed to stop recording: %v", err)
+	}
+
+	segments := recorder.GetSegments()
+	if len(segments) != 1 {
+		t.Fatalf("Expected 1 segment, got %d", len(segments))
+	}
+
+	if segments[0].Size != 17 {
+		t.Errorf("Expected segment size 17, got %d", segments[0].Size)
+	}
+
+	if recorder.GetBufferedBytes() != 0 {
+		t.Errorf("Expected empty buffer after stop, got %d", recorder.GetBufferedBytes())
+	}
+}
+
+func TestBaseRecorderEvents(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	storageConfig := DefaultStorageConfig()
+	storageConfig.BasePath = t.TempDir()
+	store, err := NewLocalStorage(storageConfig, log)
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	defer store.Close()
+
+	config := DefaultRecordingConfig()
+	config.StreamID = "test-stream"
+	config.OutputPath = t.TempDir()
+	config.Storage = store
+	config.AutoUpload = true
+
+	recorder := NewBaseRecorder(config, log)
+	defer recorder.Close()
+
+	events := make(chan RecordingEvent, 10)
+	recorder.SetOnEvent(func(event RecordingEvent) {
+		events <- event
+	})
+
+	ctx := context.Background()
+	recorder.Start(ctx)
+	recorder.WriteData([]byte("segment-data"))
+	recorder.Stop(ctx)
+
+	expected := []RecordingEventType{
+		RecordingEventStarted,
+		RecordingEventSegment,
+		RecordingEventCompleted,
+		RecordingEventUploaded,
+	}
+	for _, eventType := range expected {
+		select {
+		case event := <-events:
+			if event.Type != eventType {
+				t.Fatalf("Expected %s event, got %s (error: %v)", eventType, event.Type, event.Error)
+			}
+			if event.Recording.StreamID != "test-stream" {
+				t.Errorf("Expected event for test-stream, got %q", event.Recording.StreamID)
+			}
+			if eventType == RecordingEventSegment && (event.Segment == nil || event.Segment.Size != 12) {
+				t.Errorf("Expected the finalized 12-byte segment, got %+v", event.Segment)
+			}
+			if eventType == RecordingEventUploaded && (len(event.Segments) != 1 || event.Segments[0].RemotePath == "") {
+				t.Errorf("Expected one uploaded segment, got %+v", event.Segments)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for %s event", eventType)
+		}
+	}
+}
+
+func TestRecorderRegistryFinalizeAll(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	registry := NewRecorderRegistry(log)
+	ctx := context.Background()
+
+	recorders := make([]*BaseRecorder, 0, 2)
+	for _, streamID := range []string{"stream-a", "stream-b"} {
+		config := DefaultRecordingConfig()
+		config.StreamID = streamID
+		config.OutputPath = t.TempDir()
+		config.SpillDir = t.TempDir()
+
+		recorder := NewBaseRecorder(config, log)
+		if err := recorder.Start(ctx); err != nil {
+			t.Fatalf("Failed to start recording: %v", err)
+		}
+		if err := recorder.WriteData([]byte("buffered")); err != nil {
+			t.Fatalf("Failed to write data: %v", err)
+		}
+
+		registry.Register(recorder)
+		recorders = append(recorders, recorder)
+	}
+
+	if registry.Len() != 2 {
+		t.Fatalf("Expected 2 tracked recorders, got %d", registry.Len())
+	}
+
+	if err := registry.FinalizeAll(ctx); err != nil {
+		t.Fatalf("FinalizeAll failed: %v", err)
+	}
+
+	if registry.Len() != 0 {
+		t.Errorf("Expected registry to be empty, got %d", registry.Len())
+	}
+
+	for _, recorder := range recorders {
+		if state := recorder.GetInfo().State; state != StateStopped {
+			t.Errorf("Expected state stopped, got %s", state)
+		}
+
+		segments := recorder.GetSegments()
+		if len(segments) != 1 || segments[0].Size != 8 {
+			t.Errorf("Expected one flushed segment of 8 bytes, got %+v", segments)
+		}
+	}
+}
+
 func TestInMemoryMetadataStore(t *testing.T) {
 	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
 	store := NewInMemoryMetadataStore(log)
@@ -224,6 +441,253 @@ func TestLocalStorage(t *testing.T) {
 	storage.Close()
 }
 
+func TestLocalStorageDownloadRange(t *testing.T) {
+	config := DefaultStorageConfig()
+	config.BasePath = t.TempDir()
+
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	store, err := NewLocalStorage(config, log)
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	content := "0123456789"
+	if err := store.Upload(ctx, "video.mp4", strings.NewReader(content), int64(len(content)), "video/mp4"); err != nil {
+		t.Fatalf("Failed to upload: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		offset, length int64
+		want           string
+	}{
+		{"middle", 2, 3, "234"},
+		{"to end", 7, 0, "789"},
+		{"clamped", 8, 10, "89"},
+		{"suffix", -4, 0, "6789"},
+		{"whole", 0, 0, content},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objectRange, err := store.DownloadRange(ctx, "video.mp4", tt.offset, tt.length)
+			if err != nil {
+				t.Fatalf("DownloadRange failed: %v", err)
+			}
+			defer objectRange.Close()
+
+			data, _ := io.ReadAll(objectRange)
+			if string(data) != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, data)
+			}
+			if objectRange.Length != int64(len(tt.want)) || objectRange.Size != int64(len(content)) {
+				t.Errorf("Unexpected range %+v", objectRange)
+			}
+		})
+	}
+
+	if _, err := store.DownloadRange(ctx, "video.mp4", 10, 0); err != ErrInvalidRange {
+		t.Errorf("Expected ErrInvalidRange, got %v", err)
+	}
+
+	if _, err := store.DownloadRange(ctx, "missing.mp4", 0, 0); err != ErrObjectNotFound {
+		t.Errorf("Expected ErrObjectNotFound, got %v", err)
+	}
+
+	// Serve over HTTP
+	server := httptest.NewServer(http.StripPrefix("/recordings/", NewRangeHandler(store, log)))
+	defer server.Close()
+
+	requests := []struct {
+		rangeHeader  string
+		status       int
+		contentRange string
+		body         string
+	}{
+		{"", http.StatusOK, "", content},
+		{"bytes=3-5", http.StatusPartialContent, "bytes 3-5/10", "345"},
+		{"bytes=8-", http.StatusPartialContent, "bytes 8-9/10", "89"},
+		{"bytes=-3", http.StatusPartialContent, "bytes 7-9/10", "789"},
+		{"bytes=20-", http.StatusRequestedRangeNotSatisfiable, "", ""},
+		{"bytes=0-1,4-5", http.StatusOK, "", content},
+	}
+
+	for _, tt := range requests {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/recordings/video.mp4", nil)
+		if tt.rangeHeader != "" {
+			req.Header.Set("Range", tt.rangeHeader)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("Range %q: expected status %d, got %d", tt.rangeHeader, tt.status, resp.StatusCode)
+			continue
+		}
+		if got := resp.Header.Get("Content-Range"); got != tt.contentRange {
+			t.Errorf("Range %q: expected Content-Range %q, got %q", tt.rangeHeader, tt.contentRange, got)
+		}
+		if tt.status != http.StatusRequestedRangeNotSatisfiable && string(body) != tt.body {
+			t.Errorf("Range %q: expected body %q, got %q", tt.rangeHeader, tt.body, body)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/recordings/missing.mp4")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for missing object, got %d", resp.StatusCode)
+	}
+}
+
+func TestLocalStorageRejectsUnsafeKeys(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	config := DefaultStorageConfig()
+	config.BasePath = filepath.Join(root, "store")
+
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	store, err := NewLocalStorage(config, log)
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	if err := store.Upload(ctx, "video.mp4", strings.NewReader("data"), 4, "video/mp4"); err != nil {
+		t.Fatalf("Failed to upload: %v", err)
+	}
+
+	for _, key := range []string{"../secret.txt", "clips/../../secret.txt", "video.mp4.meta"} {
+		if _, err := store.Download(ctx, key); !errors.Is(err, ErrInvalidObjectKey) {
+			t.Errorf("Download %q: expected ErrInvalidObjectKey, got %v", key, err)
+		}
+		if err := store.Upload(ctx, key, strings.NewReader("x"), 1, "text/plain"); !errors.Is(err, ErrInvalidObjectKey) {
+			t.Errorf("Upload %q: expected ErrInvalidObjectKey, got %v", key, err)
+		}
+	}
+
+	handler := NewRangeHandler(store, log)
+	for _, path := range []string{"/../secret.txt", "/video.mp4.meta"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.URL.Path = path
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestLocalStorageStatAndUploadIfAbsent(t *testing.T) {
+	config := DefaultStorageConfig()
+	config.BasePath = t.TempDir()
+	config.ContentHash = true
+
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	store, err := NewLocalStorage(config, log)
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	content := "segment-data"
+	if err := store.Upload(ctx, "seg.ts", strings.NewReader(content), int64(len(content)), "video/mp2t"); err != nil {
+		t.Fatalf("Failed to upload: %v", err)
+	}
+
+	info, err := store.Stat(ctx, "seg.ts")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Size != int64(len(content)) || info.ContentType != "video/mp2t" {
+		t.Errorf("Unexpected stat result %+v", info)
+	}
+	if info.Hash != hashBytes([]byte(content)) {
+		t.Errorf("Expected content hash %s, got %s", hashBytes([]byte(content)), info.Hash)
+	}
+
+	// Identical content is skipped
+	uploaded, err := store.UploadIfAbsent(ctx, "seg.ts", strings.NewReader(content), int64(len(content)), "video/mp2t")
+	if err != nil {
+		t.Fatalf("UploadIfAbsent failed: %v", err)
+	}
+	if uploaded {
+		t.Error("Expected identical upload to be skipped")
+	}
+
+	// Changed content is uploaded
+	uploaded, err = store.UploadIfAbsent(ctx, "seg.ts", strings.NewReader("other-data"), 10, "video/mp2t")
+	if err != nil {
+		t.Fatalf("UploadIfAbsent failed: %v", err)
+	}
+	if !uploaded {
+		t.Error("Expected changed content to be uploaded")
+	}
+
+	info, _ = store.Stat(ctx, "seg.ts")
+	if info.Hash != hashBytes([]byte("other-data")) {
+		t.Errorf("Expected hash of new content, got %s", info.Hash)
+	}
+
+	if _, err := store.Stat(ctx, "missing.ts"); err != ErrObjectNotFound {
+		t.Errorf("Expected ErrObjectNotFound, got %v", err)
+	}
+}
+
+func TestUploadThrottle(t *testing.T) {
+	config := DefaultStorageConfig()
+	config.BasePath = t.TempDir()
+	config.MaxUploadBytesPerSec = 100 * 1024
+
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	store, err := NewLocalStorage(config, log)
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	defer store.Close()
+
+	// The first second's worth of bytes is covered by the burst; the rest is paced
+	content := bytes.Repeat([]byte("x"), 150*1024)
+
+	start := time.Now()
+	if err := store.Upload(context.Background(), "seg.ts", bytes.NewReader(content), int64(len(content)), "video/mp2t"); err != nil {
+		t.Fatalf("Failed to upload: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if elapsed < 400*time.Millisecond {
+		t.Errorf("Expected throttled upload to take about 500ms, took %v", elapsed)
+	}
+
+	// Throughput is reported once a measurement window has elapsed
+	time.Sleep(throughputWindow - elapsed + 10*time.Millisecond)
+	if throughput := store.UploadThroughput(); throughput <= 0 || throughput > 2*float64(config.MaxUploadBytesPerSec) {
+		t.Errorf("Unexpected upload throughput %.0f B/s", throughput)
+	}
+
+	// Waits honour context cancellation
+	throttle := NewUploadThrottle(1024)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := throttle.WaitN(ctx, 10*1024); err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+}
+
 func TestThumbnailGenerator(t *testing.T) {
 	config := DefaultThumbnailConfig()
 
@@ -241,3 +705,322 @@ func TestThumbnailGenerator(t *testing.T) {
 	// Clean up
 	generator.Close()
 }
+
+func TestMarkerBridge(t *testing.T) {
+	ctx := context.Background()
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	store := NewInMemoryMetadataStore(log)
+
+	start := time.Now()
+	store.Save(ctx, &RecordingMetadata{RecordingID: "rec-1", StartTime: start, Duration: 10 * time.Minute})
+
+	bridge := NewMarkerBridge(store, log)
+
+	if _, err := bridge.AddMarker(ctx, "rec-1", MarkerChapter, "Intro", 0); err != nil {
+		t.Fatalf("Failed to add marker: %v", err)
+	}
+	if _, err := bridge.AddMarker(ctx, "rec-1", MarkerChapter, "Q&A", 8*time.Minute); err != nil {
+		t.Fatalf("Failed to add marker: %v", err)
+	}
+
+	if _, err := bridge.AddMarker(ctx, "rec-1", MarkerChapter, "Late", 11*time.Minute); err != ErrInvalidMarker {
+		t.Errorf("Expected ErrInvalidMarker past the end, got %v", err)
+	}
+	if _, err := bridge.AddMarker(ctx, "missing", MarkerChapter, "x", 0); err != ErrObjectNotFound {
+		t.Errorf("Expected ErrObjectNotFound, got %v", err)
+	}
+
+	// Mapped interactive events become markers at their offset
+	marker, err := bridge.HandleEvent(ctx, "rec-1", MarkerEvent{Type: "poll.started", Label: "Favourite song?", Time: start.Add(3 * time.Minute)})
+	if err != nil || marker == nil {
+		t.Fatalf("Expected a poll marker, got %v", err)
+	}
+	if marker.Type != MarkerPoll || marker.Offset != 3*time.Minute {
+		t.Errorf("Unexpected marker: %+v", marker)
+	}
+
+	if marker, _ := bridge.HandleEvent(ctx, "rec-1", MarkerEvent{Type: "chat.message", Time: start}); marker != nil {
+		t.Errorf("Expected unmapped event to be ignored, got %+v", marker)
+	}
+
+	markers, _ := bridge.Markers(ctx, "rec-1")
+	if len(markers) != 3 || markers[1].Label != "Favourite song?" {
+		t.Fatalf("Expected 3 markers ordered by offset, got %+v", markers)
+	}
+
+	var vtt bytes.Buffer
+	if err := WriteChaptersVTT(&vtt, markers, 10*time.Minute); err != nil {
+		t.Fatalf("Failed to write chapters: %v", err)
+	}
+	if !strings.Contains(vtt.String(), "00:03:00.000 --> 00:08:00.000\nFavourite song?") {
+		t.Errorf("Unexpected chapters track:\n%s", vtt.String())
+	}
+}
+
+func TestRecordingSeekIndex(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	ctx := context.Background()
+
+	metadataStore := NewInMemoryMetadataStore(log)
+	defer metadataStore.Close()
+
+	config := DefaultRecordingConfig()
+	config.StreamID = "test-stream"
+	config.OutputPath = t.TempDir()
+	config.MetadataStore = metadataStore
+
+	recorder := NewBaseRecorder(config, log)
+	defer recorder.Close()
+
+	recordingID := recorder.GetInfo().ID
+	if err := metadataStore.Save(ctx, &RecordingMetadata{RecordingID: recordingID, StreamID: "test-stream"}); err != nil {
+		t.Fatalf("Failed to save metadata: %v", err)
+	}
+
+	if err := recorder.Start(ctx); err != nil {
+		t.Fatalf("Failed to start recording: %v", err)
+	}
+	recorder.WriteData([]byte("hdr"))
+	recorder.WriteKeyframe([]byte("key1"), 0)
+	recorder.WriteData([]byte("p"))
+	recorder.WriteKeyframe([]byte("key2"), 2*time.Second)
+	recorder.WriteData([]byte("pp"))
+	if err := recorder.Stop(ctx); err != nil {
+		t.Fatalf("Failed to stop recording: %v", err)
+	}
+
+	metadata, _ := metadataStore.Get(ctx, recordingID)
+	if len(metadata.SeekIndex) != 2 {
+		t.Fatalf("Expected 2 seek points in metadata, got %d", len(metadata.SeekIndex))
+	}
+	if metadata.SeekIndex[0].Offset != 3 || metadata.SeekIndex[1].Offset != 8 {
+		t.Errorf("Expected offsets 3 and 8, got %d and %d", metadata.SeekIndex[0].Offset, metadata.SeekIndex[1].Offset)
+	}
+
+	point, err := Seek(ctx, metadataStore, recordingID, 1999)
+	if err != nil || point.Timestamp != 0 {
+		t.Errorf("Expected first keyframe at 1999ms, got %+v (%v)", point, err)
+	}
+	point, _ = Seek(ctx, metadataStore, recordingID, 2500)
+	if point.Timestamp != 2*time.Second {
+		t.Errorf("Expected second keyframe at 2500ms, got %v", point.Timestamp)
+	}
+	if _, err := Seek(ctx, metadataStore, recordingID, -1); err != ErrInvalidSeek {
+		t.Errorf("Expected ErrInvalidSeek, got %v", err)
+	}
+
+	// Serve the segment from the keyframe through the range handler
+	storageConfig := DefaultStorageConfig()
+	storageConfig.BasePath = t.TempDir()
+	store, err := NewLocalStorage(storageConfig, log)
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	defer store.Close()
+
+	segment := "hdrkey1pkey2pp"
+	store.Upload(ctx, point.SegmentKey, strings.NewReader(segment), int64(len(segment)), "video/mp4")
+
+	handler := NewRangeHandler(store, log)
+	handler.SetMetadataStore(metadataStore)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/?recording=" + recordingID + "&t=2500")
+	if err != nil {
+		t.Fatalf("Failed to seek: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "key2pp" {
+		t.Errorf("Expected 206 with key2pp, got %d %q", resp.StatusCode, body)
+	}
+	if resp.Header.Get("X-Seek-Timestamp") != "2000" {
+		t.Errorf("Expected X-Seek-Timestamp 2000, got %q", resp.Header.Get("X-Seek-Timestamp"))
+	}
+}
+
+func TestExtractClip(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	ctx := context.Background()
+
+	metadataStore := NewInMemoryMetadataStore(log)
+	defer metadataStore.Close()
+
+	storageConfig := DefaultStorageConfig()
+	storageConfig.BasePath = t.TempDir()
+	store, err := NewLocalStorage(storageConfig, log)
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	defer store.Close()
+
+	// Two segments with two keyframes each, two seconds apart
+	store.Upload(ctx, "seg0.ts", strings.NewReader("k0aak1bb"), 8, "video/mp2t")
+	store.Upload(ctx, "seg1.ts", strings.NewReader("k2cck3dd"), 8, "video/mp2t")
+	metadataStore.Save(ctx, &RecordingMetadata{
+		RecordingID: "rec-1",
+		StreamID:    "test-stream",
+		Duration:    8 * time.Second,
+		SeekIndex: []SeekPoint{
+			{Timestamp: 0, SegmentKey: "seg0.ts", Offset: 0},
+			{Timestamp: 2 * time.Second, SegmentKey: "seg0.ts", Offset: 4},
+			{Timestamp: 4 * time.Second, Segment: 1, SegmentKey: "seg1.ts", Offset: 0},
+			{Timestamp: 6 * time.Second, Segment: 1, SegmentKey: "seg1.ts", Offset: 4},
+		},
+	})
+
+	tests := []struct {
+		name       string
+		start, end time.Duration
+		want       string
+		wantStart  time.Duration
+		wantEnd    time.Duration
+	}{
+		{"within segment", 500 * time.Millisecond, time.Second, "k0aa", 0, 2 * time.Second},
+		{"across segments", 2500 * time.Millisecond, 4500 * time.Millisecond, "k1bbk2cc", 2 * time.Second, 6 * time.Second},
+		{"to end", 5 * time.Second, 20 * time.Second, "k2cck3dd", 4 * time.Second, 8 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clip, err := ExtractClip(ctx, metadataStore, store, "rec-1", tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("ExtractClip failed: %v", err)
+			}
+			if clip.Start != tt.wantStart || clip.End != tt.wantEnd {
+				t.Errorf("Expected bounds %v-%v, got %v-%v", tt.wantStart, tt.wantEnd, clip.Start, clip.End)
+			}
+			if !strings.HasPrefix(clip.Key, "recordings/test-stream/clips/") || !strings.HasSuffix(clip.Key, ".ts") {
+				t.Errorf("Unexpected clip key %q", clip.Key)
+			}
+
+			reader, err := store.Download(ctx, clip.Key)
+			if err != nil {
+				t.Fatalf("Failed to download clip: %v", err)
+			}
+			defer reader.Close()
+
+			data, _ := io.ReadAll(reader)
+			if string(data) != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, data)
+			}
+		})
+	}
+
+	if _, err := ExtractClip(ctx, metadataStore, store, "rec-1", 2*time.Second, time.Second); !errors.Is(err, ErrInvalidClip) {
+		t.Errorf("Expected ErrInvalidClip, got %v", err)
+	}
+
+	// Segments that start with a container header give the clip one header
+	store.Upload(ctx, "seg0.flv", strings.NewReader("HDk0aak1bb"), 10, "video/x-flv")
+	store.Upload(ctx, "seg1.flv", strings.NewReader("HDk2cck3dd"), 10, "video/x-flv")
+	metadataStore.Save(ctx, &RecordingMetadata{
+		RecordingID: "rec-2",
+		StreamID:    "test-stream",
+		Duration:    8 * time.Second,
+		SeekIndex: []SeekPoint{
+			{Timestamp: 0, SegmentKey: "seg0.flv", Offset: 2},
+			{Timestamp: 2 * time.Second, SegmentKey: "seg0.flv", Offset: 6},
+			{Timestamp: 4 * time.Second, Segment: 1, SegmentKey: "seg1.flv", Offset: 2},
+			{Timestamp: 6 * time.Second, Segment: 1, SegmentKey: "seg1.flv", Offset: 6},
+		},
+		SegmentHeader: []byte("HD"),
+	})
+
+	clip, err := ExtractClip(ctx, metadataStore, store, "rec-2", 2500*time.Millisecond, 4500*time.Millisecond)
+	if err != nil {
+		t.Fatalf("ExtractClip failed: %v", err)
+	}
+	reader, err := store.Download(ctx, clip.Key)
+	if err != nil {
+		t.Fatalf("Failed to download clip: %v", err)
+	}
+	defer reader.Close()
+
+	if data, _ := io.ReadAll(reader); string(data) != "HDk1bbk2cc" {
+		t.Errorf("Expected %q, got %q", "HDk1bbk2cc", data)
+	}
+}
+
+func TestLivePreviewer(t *testing.T) {
+	config := DefaultStorageConfig()
+	config.BasePath = t.TempDir()
+
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	store, err := NewLocalStorage(config, log)
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	defer store.Close()
+
+	receivedAt := time.Now()
+	grabber := func(ctx context.Context, streamID string) (image.Image, time.Time, error) {
+		return image.NewRGBA(image.Rect(0, 0, 64, 36)), receivedAt, nil
+	}
+
+	previewConfig := DefaultLivePreviewConfig()
+	previewConfig.Storage = store
+	previewConfig.Interval = time.Hour
+
+	previewer := NewLivePreviewer(previewConfig, grabber, log)
+	defer previewer.Close()
+
+	updated := make(chan string, 4)
+	previewer.OnThumbnailUpdated(func(streamID, key string) {
+		updated <- key
+	})
+
+	if err := previewer.Start("stream-1"); err != nil {
+		t.Fatalf("Failed to start previews: %v", err)
+	}
+
+	select {
+	case key := <-updated:
+		if key != "previews/stream-1.jpeg" {
+			t.Errorf("Unexpected preview key %s", key)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected preview to be captured on start")
+	}
+
+	if exists, _ := store.Exists(context.Background(), "previews/stream-1.jpeg"); !exists {
+		t.Error("Expected preview object in storage")
+	}
+
+	// The same frame again means the stream is not advancing
+	if err := previewer.Capture(context.Background(), "stream-1"); err == nil {
+		t.Error("Expected capture of a repeated frame to be skipped")
+	}
+
+	// A frame older than the stall timeout is not used
+	receivedAt = time.Now().Add(-time.Minute)
+	if err := previewer.Capture(context.Background(), "stream-2"); err == nil {
+		t.Error("Expected capture of a stalled stream to be skipped")
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	config := DefaultStorageConfig()
+	config.BasePath = t.TempDir()
+
+	storage, err := NewLocalStorage(config, logger.NewDefaultLogger(logger.InfoLevel, "text"))
+	if err != nil {
+		t.Fatalf("Failed to create local storage: %v", err)
+	}
+	defer storage.Close()
+
+	if err := CheckWritable(context.Background(), storage); err != nil {
+		t.Errorf("Expected local storage to be writable, got %v", err)
+	}
+
+	objects, _ := storage.List(context.Background(), healthProbePrefix, 0)
+	if len(objects) != 0 {
+		t.Errorf("Expected probe object to be deleted, found %d", len(objects))
+	}
+
+	if err := CheckWritable(context.Background(), nil); err != ErrStorageNotConfigured {
+		t.Errorf("Expected ErrStorageNotConfigured, got %v", err)
+	}
+}
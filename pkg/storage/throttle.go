@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// throughputWindow is the minimum interval over which upload throughput is measured
+const throughputWindow = time.Second
+
+// throttleChunkSize bounds each throttled read so waits stay short and smooth
+const throttleChunkSize = 32 * 1024
+
+// UploadThrottle is a token-bucket limiter for upload bandwidth that also
+// tracks the observed upload throughput. A zero rate disables limiting.
+type UploadThrottle struct {
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	windowStart time.Time
+	windowBytes int64
+	throughput  float64
+
+	mu sync.Mutex
+}
+
+// NewUploadThrottle creates a throttle allowing bytesPerSec bytes per second
+func NewUploadThrottle(bytesPerSec int64) *UploadThrottle {
+	now := time.Now()
+	return &UploadThrottle{
+		rate:        float64(bytesPerSec),
+		burst:       float64(bytesPerSec),
+		tokens:      float64(bytesPerSec),
+		last:        now,
+		windowStart: now,
+	}
+}
+
+// WaitN records n uploaded bytes and blocks until the rate limit allows them
+func (t *UploadThrottle) WaitN(ctx context.Context, n int) error {
+	t.mu.Lock()
+	now := time.Now()
+	t.roll(now)
+	t.windowBytes += int64(n)
+
+	if t.rate <= 0 {
+		t.mu.Unlock()
+		return nil
+	}
+
+	// Refill, then take the tokens, going into debt if necessary
+	t.tokens += now.Sub(t.last).Seconds() * t.rate
+	if t.tokens > t.burst {
+		t.tokens = t.burst
+	}
+	t.last = now
+	t.tokens -= float64(n)
+
+	var wait time.Duration
+	if t.tokens < 0 {
+		wait = time.Duration(-t.tokens / t.rate * float64(time.Second))
+	}
+	t.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Throughput returns the recently observed upload rate in bytes per second
+func (t *UploadThrottle) Throughput() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.roll(time.Now())
+	return t.throughput
+}
+
+// roll closes the current measurement window once it is long enough.
+// Caller must hold t.mu.
+func (t *UploadThrottle) roll(now time.Time) {
+	elapsed := now.Sub(t.windowStart)
+	if elapsed < throughputWindow {
+		return
+	}
+
+	t.throughput = float64(t.windowBytes) / elapsed.Seconds()
+	t.windowBytes = 0
+	t.windowStart = now
+}
+
+// throttledReader paces reads from an upload source through an UploadThrottle
+type throttledReader struct {
+	ctx      context.Context
+	reader   io.Reader
+	throttle *UploadThrottle
+}
+
+// newThrottledReader wraps reader so that reads are paced by throttle
+func newThrottledReader(ctx context.Context, reader io.Reader, throttle *UploadThrottle) *throttledReader {
+	return &throttledReader{ctx: ctx, reader: reader, throttle: throttle}
+}
+
+// Read reads at most one chunk and waits for the throttle before returning
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > throttleChunkSize {
+		p = p[:throttleChunkSize]
+	}
+
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if werr := r.throttle.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+
+	return n, err
+}
+
+// throttledReadSeeker is a throttledReader over a seekable body, as required
+// by S3 uploads
+type throttledReadSeeker struct {
+	*throttledReader
+	seeker io.Seeker
+}
+
+// Seek repositions the underlying body
+func (r *throttledReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	return r.seeker.Seek(offset, whence)
+}
@@ -149,6 +149,11 @@ func (g *ThumbnailGenerator) generateSingleThumbnail(ctx context.Context, record
 
 // resizeImage resizes an image to the specified dimensions
 func (g *ThumbnailGenerator) resizeImage(src image.Image, width, height int) image.Image {
+	return resizeImage(src, width, height)
+}
+
+// resizeImage resizes an image to the specified dimensions
+func resizeImage(src image.Image, width, height int) image.Image {
 	bounds := src.Bounds()
 	dst := image.NewRGBA(image.Rect(0, 0, width, height))
 
@@ -35,6 +35,11 @@ var (
 	ErrInvalidObjectKey        = errors.New("invalid object key")
 	ErrUploadFailed            = errors.New("upload failed")
 	ErrDownloadFailed          = errors.New("download failed")
+	ErrInvalidRange            = errors.New("requested range not satisfiable")
+	ErrInvalidMarker           = errors.New("invalid recording marker")
+	ErrNoSeekIndex             = errors.New("recording has no seek index")
+	ErrInvalidSeek             = errors.New("invalid seek time")
+	ErrInvalidClip             = errors.New("invalid clip bounds")
 )
 
 // RecordingFormat represents the format of the recording
@@ -53,6 +58,14 @@ type RecordingConfig struct {
 	Storage         Storage
 	AutoUpload      bool
 	Metadata        map[string]string
+	// MaxMemoryBuffer is the number of bytes buffered in memory before spilling to a temp file (0 = never spill)
+	MaxMemoryBuffer int64
+	// SpillDir is the directory for spill files (empty = system temp dir)
+	SpillDir string
+	// MetadataStore receives the recording's seek index when it stops (nil = not stored)
+	MetadataStore MetadataStore
+	// SegmentHeader starts every segment file, such as a container header (nil = none)
+	SegmentHeader []byte
 }
 
 // DefaultRecordingConfig returns a default recording configuration
@@ -63,6 +76,7 @@ func DefaultRecordingConfig() RecordingConfig {
 		MaxSegmentSize:  500 * 1024 * 1024,
 		AutoUpload:      false,
 		Metadata:        make(map[string]string),
+		MaxMemoryBuffer: 16 * 1024 * 1024,
 	}
 }
 
@@ -94,6 +108,34 @@ type SegmentInfo struct {
 	Uploaded   bool
 }
 
+// RecordingEventType identifies a recording lifecycle event
+type RecordingEventType string
+
+const (
+	// RecordingEventStarted is emitted when a recording starts
+	RecordingEventStarted RecordingEventType = "recording.started"
+	// RecordingEventSegment is emitted when a segment is finalized
+	RecordingEventSegment RecordingEventType = "recording.segment"
+	// RecordingEventCompleted is emitted when a recording stops with all segments finalized
+	RecordingEventCompleted RecordingEventType = "recording.completed"
+	// RecordingEventUploaded is emitted when every segment of a stopped recording is uploaded
+	RecordingEventUploaded RecordingEventType = "recording.uploaded"
+	// RecordingEventFailed is emitted when a recording can't start, write, finalize or upload
+	RecordingEventFailed RecordingEventType = "recording.failed"
+)
+
+// RecordingEvent describes a recording lifecycle event
+type RecordingEvent struct {
+	Type      RecordingEventType
+	Recording RecordingInfo
+	// Segment is the finalized segment of a segment event
+	Segment *SegmentInfo
+	// Segments are the recording's segments for completed and uploaded events
+	Segments  []SegmentInfo
+	Error     error
+	Timestamp time.Time
+}
+
 // Recorder defines the interface for recording livestreams
 type Recorder interface {
 	Start(ctx context.Context) error
@@ -126,6 +168,11 @@ type StorageConfig struct {
 	MaxRetries      int
 	RetryDelay      time.Duration
 	Timeout         time.Duration
+	ContentHash     bool // compute a SHA-256 content hash on upload
+
+	// MaxUploadBytesPerSec limits upload bandwidth so offloading recordings
+	// doesn't starve live media traffic (0 = unlimited)
+	MaxUploadBytesPerSec int64
 }
 
 // DefaultStorageConfig returns a default storage configuration
@@ -146,13 +193,33 @@ type StorageObject struct {
 	Size         int64
 	LastModified time.Time
 	ContentType  string
+	Hash         string // hex SHA-256 of the content, if recorded at upload
 	Metadata     map[string]string
 }
 
+// MetadataContentHash is the metadata key holding an object's content hash
+const MetadataContentHash = "content-sha256"
+
+// ObjectRange is a byte range of a stored object returned by DownloadRange
+type ObjectRange struct {
+	io.ReadCloser
+	Offset int64 // first byte served
+	Length int64 // number of bytes served
+	Size   int64 // total size of the object
+}
+
 // Storage defines the interface for storage backends
 type Storage interface {
 	Upload(ctx context.Context, key string, data io.Reader, size int64, contentType string) error
 	Download(ctx context.Context, key string) (io.ReadCloser, error)
+	// DownloadRange reads length bytes starting at offset. A length <= 0 reads
+	// to the end of the object and a negative offset reads the last -offset
+	// bytes. Ranges starting past the end return ErrInvalidRange.
+	DownloadRange(ctx context.Context, key string, offset, length int64) (*ObjectRange, error)
+	// UploadIfAbsent uploads data unless an object with the same content hash
+	// already exists at key. It reports whether an upload took place.
+	UploadIfAbsent(ctx context.Context, key string, data io.Reader, size int64, contentType string) (bool, error)
+	Stat(ctx context.Context, key string) (*StorageObject, error)
 	Delete(ctx context.Context, key string) error
 	Exists(ctx context.Context, key string) (bool, error)
 	List(ctx context.Context, prefix string, maxKeys int) ([]StorageObject, error)
@@ -226,8 +293,12 @@ type RecordingMetadata struct {
 	ViewCount      int64
 	Tags           []string
 	CustomMetadata map[string]string
+	Markers        []RecordingMarker
+	SeekIndex      []SeekPoint
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
+	// SegmentHeader is the container header that starts every segment (nil = none)
+	SegmentHeader []byte
 }
 
 // MetadataQuery contains parameters for querying recording metadata
@@ -2,6 +2,8 @@
 package hls
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -367,3 +369,98 @@ func TestVariantSelector(t *testing.T) {
 		t.Errorf("Aggressive selector chose variant with too high bandwidth: %d", variant.Bandwidth)
 	}
 }
+
+// TestSubtitleTrack tests WebVTT subtitle segments and master playlist renditions
+func TestSubtitleTrack(t *testing.T) {
+	track := NewSubtitleTrack(6, 3)
+	track.AddCue(Cue{Start: 1 * time.Second, End: 3 * time.Second, Text: "hello"})
+	track.AddCue(Cue{Start: 5 * time.Second, End: 8 * time.Second, Text: "world"})
+
+	segment := track.Flush(6 * time.Second)
+	if segment == nil || segment.Type != SegmentTypeSubtitle || segment.Filename != "subs_0.vtt" {
+		t.Fatalf("Unexpected segment: %+v", segment)
+	}
+	vtt := string(segment.Data)
+	if !strings.HasPrefix(vtt, "WEBVTT\n") || !strings.Contains(vtt, "00:00:01.000 --> 00:00:03.000\nhello") {
+		t.Errorf("Unexpected WebVTT body: %q", vtt)
+	}
+
+	// The cue overlapping the boundary is repeated in the next segment
+	segment = track.Flush(12 * time.Second)
+	if vtt := string(segment.Data); !strings.Contains(vtt, "world") || strings.Contains(vtt, "hello") {
+		t.Errorf("Expected only the carried cue, got %q", vtt)
+	}
+	if track.Flush(12*time.Second) != nil {
+		t.Error("Expected no segment for an empty interval")
+	}
+	if track.Playlist().GetSegmentCount() != 2 {
+		t.Errorf("Expected 2 segments, got %d", track.Playlist().GetSegmentCount())
+	}
+
+	master := NewMasterPlaylist()
+	master.AddVariant(&Variant{Name: "720p", Bandwidth: 2800000, URI: "720p/playlist.m3u8"})
+	master.AddSubtitles(&SubtitleRendition{Name: "English", Language: "en", URI: "subs/playlist.m3u8", Default: true, AutoSelect: true})
+
+	rendered := master.Render()
+	if !strings.Contains(rendered, `#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="English",LANGUAGE="en",DEFAULT=YES,AUTOSELECT=YES,URI="subs/playlist.m3u8"`) {
+		t.Errorf("Expected subtitle rendition in master playlist, got:\n%s", rendered)
+	}
+	if !strings.Contains(rendered, `SUBTITLES="subs"`) {
+		t.Errorf("Expected variants to reference the subtitle group, got:\n%s", rendered)
+	}
+}
+
+// TestBuildLadder tests expanding presets into an ABR ladder
+func TestBuildLadder(t *testing.T) {
+	variants, err := BuildLadder([]string{PresetMobile, Preset1080p60, PresetAudioOnly, Preset720p30, PresetMobile})
+	if err != nil {
+		t.Fatalf("Failed to build ladder: %v", err)
+	}
+
+	names := make([]string, len(variants))
+	for i, variant := range variants {
+		names[i] = variant.Name
+	}
+	if strings.Join(names, ",") != "1080p60,720p30,mobile,audio-only" {
+		t.Errorf("Expected ladder ordered by bandwidth without duplicates, got %v", names)
+	}
+
+	if variants[0].Resolution != "1920x1080" || variants[0].FrameRate != 60 || variants[0].URI != "playlist_1080p60.m3u8" {
+		t.Errorf("Unexpected 1080p60 variant: %+v", variants[0])
+	}
+	if variants[0].Bandwidth <= variants[0].AverageBandwidth {
+		t.Error("Expected peak bandwidth above the average")
+	}
+
+	audio := variants[3]
+	if audio.Resolution != "" || audio.Codecs != "mp4a.40.2" {
+		t.Errorf("Expected audio-only variant without video, got %+v", audio)
+	}
+
+	// Presets above the source resolution are not upscaled
+	variants, err = BuildLadderForSource([]string{Preset1080p60, Preset720p30, PresetMobile}, 1280, 720)
+	if err != nil {
+		t.Fatalf("Failed to build ladder for source: %v", err)
+	}
+	if len(variants) != 2 || variants[0].Name != Preset720p30 {
+		t.Errorf("Expected 1080p60 to be left out for a 720p source, got %d variants", len(variants))
+	}
+
+	if _, err := BuildLadderForSource([]string{Preset1080p30}, 640, 360); err == nil {
+		t.Error("Expected an error when no preset fits the source")
+	}
+	if _, err := BuildLadder([]string{"4k"}); !errors.Is(err, ErrUnknownPreset) {
+		t.Errorf("Expected ErrUnknownPreset, got %v", err)
+	}
+
+	// Custom presets can be registered
+	if err := RegisterPreset(Preset{Name: "podcast", AudioBitrate: 128000, AudioCodec: "mp4a.40.2"}); err != nil {
+		t.Fatalf("Failed to register preset: %v", err)
+	}
+	if _, err := BuildLadder([]string{"podcast"}); err != nil {
+		t.Errorf("Expected registered preset to build, got %v", err)
+	}
+	if err := RegisterPreset(Preset{Name: "broken", Width: 640, Height: 360, AudioBitrate: 64000, AudioCodec: "mp4a.40.2"}); err == nil {
+		t.Error("Expected a video preset without codec and bitrate to be rejected")
+	}
+}
@@ -111,6 +111,11 @@ func (p *MediaPlaylist) Render() string {
 		fmt.Fprintf(buf, "#EXT-X-PLAYLIST-TYPE:%s\n", p.PlaylistType)
 	}
 
+	// #EXT-X-START (optional, preferred start position)
+	if p.HasStartOffset {
+		fmt.Fprintf(buf, "#EXT-X-START:TIME-OFFSET=%.3f,PRECISE=YES\n", p.StartOffset)
+	}
+
 	// Segments
 	for _, seg := range p.Segments {
 		// #EXT-X-DISCONTINUITY (if needed)
@@ -218,6 +223,11 @@ func (m *MasterPlaylist) Render() string {
 	// #EXT-X-VERSION
 	fmt.Fprintf(buf, "#EXT-X-VERSION:%d\n", m.Version)
 
+	// #EXT-X-MEDIA subtitle renditions
+	for _, subs := range m.Subtitles {
+		buf.WriteString(subs.render())
+	}
+
 	// Variants
 	for _, variant := range m.Variants {
 		// #EXT-X-STREAM-INF
@@ -241,6 +251,10 @@ func (m *MasterPlaylist) Render() string {
 			attrs = append(attrs, fmt.Sprintf("FRAME-RATE=%.3f", variant.FrameRate))
 		}
 
+		if len(m.Subtitles) > 0 {
+			attrs = append(attrs, fmt.Sprintf("SUBTITLES=\"%s\"", SubtitleGroupID))
+		}
+
 		fmt.Fprintf(buf, "#EXT-X-STREAM-INF:%s\n", strings.Join(attrs, ","))
 
 		// Variant URI
@@ -0,0 +1,266 @@
+package hls
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Built-in preset names
+const (
+	// Preset1080p60 is 1080p at 60 fps, for gaming and sports
+	Preset1080p60 = "1080p60"
+	// Preset1080p30 is 1080p at 30 fps
+	Preset1080p30 = "1080p30"
+	// Preset720p60 is 720p at 60 fps
+	Preset720p60 = "720p60"
+	// Preset720p30 is 720p at 30 fps
+	Preset720p30 = "720p30"
+	// Preset480p30 is 480p at 30 fps
+	Preset480p30 = "480p30"
+	// PresetMobile is 360p with the H.264 baseline profile for low-end devices and cellular networks
+	PresetMobile = "mobile"
+	// PresetAudioOnly is an audio-only rendition for very poor connections
+	PresetAudioOnly = "audio-only"
+)
+
+// DefaultKeyframeInterval is the keyframe interval of the built-in presets.
+// It matches a 2 second segment duration, so every segment starts with a
+// keyframe and players can switch variants at any segment boundary.
+const DefaultKeyframeInterval = 2 * time.Second
+
+// ErrUnknownPreset is returned for a preset name that is not registered
+var ErrUnknownPreset = errors.New("unknown preset")
+
+// Preset is a named encoding configuration for one ABR variant
+type Preset struct {
+	// Name identifies the preset and names the variant
+	Name string
+
+	// Width and Height are the video resolution in pixels (0 = audio only)
+	Width  int
+	Height int
+
+	// FrameRate is the video frame rate
+	FrameRate float64
+
+	// VideoBitrate is the video bitrate in bits per second
+	VideoBitrate int
+
+	// AudioBitrate is the audio bitrate in bits per second
+	AudioBitrate int
+
+	// VideoCodec is the RFC 6381 video codec string (e.g. "avc1.640028")
+	VideoCodec string
+
+	// AudioCodec is the RFC 6381 audio codec string (e.g. "mp4a.40.2")
+	AudioCodec string
+
+	// KeyframeInterval is the time between keyframes
+	KeyframeInterval time.Duration
+}
+
+// IsAudioOnly reports whether the preset has no video
+func (p Preset) IsAudioOnly() bool {
+	return p.Width == 0 || p.Height == 0
+}
+
+// Codecs returns the CODECS attribute of the preset's variant
+func (p Preset) Codecs() string {
+	if p.IsAudioOnly() {
+		return p.AudioCodec
+	}
+	return p.VideoCodec + "," + p.AudioCodec
+}
+
+// Variant expands the preset into an ABR variant. The peak bandwidth allows
+// 10% above the average for encoder rate-control overshoot.
+func (p Preset) Variant() *Variant {
+	average := p.VideoBitrate + p.AudioBitrate
+
+	variant := &Variant{
+		Name:             p.Name,
+		Bandwidth:        average * 11 / 10,
+		AverageBandwidth: average,
+		Codecs:           p.Codecs(),
+		FrameRate:        p.FrameRate,
+		Width:            p.Width,
+		Height:           p.Height,
+		VideoBitrate:     p.VideoBitrate,
+		AudioBitrate:     p.AudioBitrate,
+		URI:              fmt.Sprintf("playlist_%s.m3u8", p.Name),
+	}
+	if !p.IsAudioOnly() {
+		variant.Resolution = fmt.Sprintf("%dx%d", p.Width, p.Height)
+	}
+
+	return variant
+}
+
+// presetsMu protects presets
+var presetsMu sync.RWMutex
+
+// presets stores the registered presets by name
+var presets = map[string]Preset{
+	Preset1080p60: {
+		Name:             Preset1080p60,
+		Width:            1920,
+		Height:           1080,
+		FrameRate:        60,
+		VideoBitrate:     6000000,
+		AudioBitrate:     192000,
+		VideoCodec:       "avc1.64002a",
+		AudioCodec:       "mp4a.40.2",
+		KeyframeInterval: DefaultKeyframeInterval,
+	},
+	Preset1080p30: {
+		Name:             Preset1080p30,
+		Width:            1920,
+		Height:           1080,
+		FrameRate:        30,
+		VideoBitrate:     4500000,
+		AudioBitrate:     192000,
+		VideoCodec:       "avc1.640028",
+		AudioCodec:       "mp4a.40.2",
+		KeyframeInterval: DefaultKeyframeInterval,
+	},
+	Preset720p60: {
+		Name:             Preset720p60,
+		Width:            1280,
+		Height:           720,
+		FrameRate:        60,
+		VideoBitrate:     3500000,
+		AudioBitrate:     128000,
+		VideoCodec:       "avc1.640020",
+		AudioCodec:       "mp4a.40.2",
+		KeyframeInterval: DefaultKeyframeInterval,
+	},
+	Preset720p30: {
+		Name:             Preset720p30,
+		Width:            1280,
+		Height:           720,
+		FrameRate:        30,
+		VideoBitrate:     2400000,
+		AudioBitrate:     128000,
+		VideoCodec:       "avc1.64001f",
+		AudioCodec:       "mp4a.40.2",
+		KeyframeInterval: DefaultKeyframeInterval,
+	},
+	Preset480p30: {
+		Name:             Preset480p30,
+		Width:            854,
+		Height:           480,
+		FrameRate:        30,
+		VideoBitrate:     1100000,
+		AudioBitrate:     128000,
+		VideoCodec:       "avc1.64001e",
+		AudioCodec:       "mp4a.40.2",
+		KeyframeInterval: DefaultKeyframeInterval,
+	},
+	PresetMobile: {
+		Name:             PresetMobile,
+		Width:            640,
+		Height:           360,
+		FrameRate:        30,
+		VideoBitrate:     600000,
+		AudioBitrate:     64000,
+		VideoCodec:       "avc1.42e01e",
+		AudioCodec:       "mp4a.40.2",
+		KeyframeInterval: DefaultKeyframeInterval,
+	},
+	PresetAudioOnly: {
+		Name:         PresetAudioOnly,
+		AudioBitrate: 64000,
+		AudioCodec:   "mp4a.40.2",
+	},
+}
+
+// GetPreset returns a registered preset by name
+func GetPreset(name string) (Preset, error) {
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+
+	preset, exists := presets[name]
+	if !exists {
+		return Preset{}, fmt.Errorf("%w: %s", ErrUnknownPreset, name)
+	}
+	return preset, nil
+}
+
+// RegisterPreset adds or replaces a named preset
+func RegisterPreset(preset Preset) error {
+	if preset.Name == "" {
+		return fmt.Errorf("preset name is required")
+	}
+	if preset.AudioCodec == "" || preset.AudioBitrate <= 0 {
+		return fmt.Errorf("preset %s: audio codec and bitrate are required", preset.Name)
+	}
+	if !preset.IsAudioOnly() && (preset.VideoCodec == "" || preset.VideoBitrate <= 0 || preset.FrameRate <= 0) {
+		return fmt.Errorf("preset %s: video codec, bitrate and frame rate are required", preset.Name)
+	}
+
+	presetsMu.Lock()
+	defer presetsMu.Unlock()
+
+	presets[preset.Name] = preset
+	return nil
+}
+
+// PresetNames returns the names of all registered presets, sorted
+func PresetNames() []string {
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuildLadder expands preset names into ABR variants, ordered from the
+// highest to the lowest bandwidth. Duplicate names are included once.
+func BuildLadder(presetNames []string) ([]*Variant, error) {
+	return BuildLadderForSource(presetNames, 0, 0)
+}
+
+// BuildLadderForSource is BuildLadder for a source of the given resolution.
+// Video presets larger than the source in either dimension are left out,
+// since upscaling costs bandwidth without adding detail. A zero source size
+// keeps every preset. It fails if no preset is left.
+func BuildLadderForSource(presetNames []string, sourceWidth, sourceHeight int) ([]*Variant, error) {
+	variants := make([]*Variant, 0, len(presetNames))
+	seen := make(map[string]bool, len(presetNames))
+
+	for _, name := range presetNames {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		preset, err := GetPreset(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if !preset.IsAudioOnly() && sourceWidth > 0 && sourceHeight > 0 &&
+			(preset.Width > sourceWidth || preset.Height > sourceHeight) {
+			continue
+		}
+
+		variants = append(variants, preset.Variant())
+	}
+
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("no preset fits a %dx%d source", sourceWidth, sourceHeight)
+	}
+
+	sort.SliceStable(variants, func(i, j int) bool {
+		return variants[i].Bandwidth > variants[j].Bandwidth
+	})
+
+	return variants, nil
+}
@@ -60,9 +60,14 @@ func NewServer(config *ServerConfig, transmuxer *Transmuxer, log logger.Logger)
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", server.handleRequest)
 
+	var handler http.Handler = mux
+	if config.Middleware != nil {
+		handler = config.Middleware(handler)
+	}
+
 	server.httpServer = &http.Server{
 		Addr:         config.Address,
-		Handler:      mux,
+		Handler:      handler,
 		ReadTimeout:  config.ReadTimeout,
 		WriteTimeout: config.WriteTimeout,
 	}
@@ -70,6 +75,12 @@ func NewServer(config *ServerConfig, transmuxer *Transmuxer, log logger.Logger)
 	return server, nil
 }
 
+// Handler returns the handler serving playlists and segments, including
+// the configured middleware, for mounting on another HTTP server
+func (s *Server) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
 // Start starts the HLS HTTP server
 func (s *Server) Start() error {
 	s.mu.Lock()
@@ -0,0 +1,163 @@
+package hls
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SubtitleGroupID is the EXT-X-MEDIA group that subtitle renditions belong to
+const SubtitleGroupID = "subs"
+
+// SubtitleRendition is a subtitle track listed in the master playlist
+type SubtitleRendition struct {
+	// Name is the human-readable track name (e.g., "English (live)")
+	Name string
+
+	// Language is the RFC 5646 language tag (e.g., "en")
+	Language string
+
+	// URI is the URI of the subtitle media playlist
+	URI string
+
+	// Default marks the track players select when no preference applies
+	Default bool
+
+	// AutoSelect lets players select the track from the user's language settings
+	AutoSelect bool
+}
+
+// render returns the EXT-X-MEDIA line for the rendition
+func (r *SubtitleRendition) render() string {
+	attrs := []string{
+		"TYPE=SUBTITLES",
+		fmt.Sprintf("GROUP-ID=\"%s\"", SubtitleGroupID),
+		fmt.Sprintf("NAME=\"%s\"", r.Name),
+	}
+
+	if r.Language != "" {
+		attrs = append(attrs, fmt.Sprintf("LANGUAGE=\"%s\"", r.Language))
+	}
+
+	attrs = append(attrs, "DEFAULT="+yesNo(r.Default), "AUTOSELECT="+yesNo(r.AutoSelect))
+	attrs = append(attrs, fmt.Sprintf("URI=\"%s\"", r.URI))
+
+	return "#EXT-X-MEDIA:" + strings.Join(attrs, ",") + "\n"
+}
+
+// yesNo formats a boolean HLS attribute
+func yesNo(b bool) string {
+	if b {
+		return "YES"
+	}
+	return "NO"
+}
+
+// AddSubtitles adds a subtitle rendition to the master playlist
+func (m *MasterPlaylist) AddSubtitles(rendition *SubtitleRendition) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Subtitles = append(m.Subtitles, rendition)
+}
+
+// Cue is a timed subtitle, with times relative to the stream start
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// SubtitleTrack builds a live WebVTT subtitle playlist from timed cues. The
+// HLS segmenter calls Flush at each media segment boundary so subtitle
+// segments line up with the media segments.
+type SubtitleTrack struct {
+	playlist     *MediaPlaylist
+	cues         []Cue
+	segmentStart time.Duration
+	index        uint64
+	maxSegments  int
+	mu           sync.Mutex
+}
+
+// NewSubtitleTrack creates a subtitle track keeping up to maxSegments segments
+func NewSubtitleTrack(targetDuration, maxSegments int) *SubtitleTrack {
+	if maxSegments <= 0 {
+		maxSegments = DefaultPlaylistSize
+	}
+
+	return &SubtitleTrack{
+		playlist:    NewMediaPlaylist(targetDuration, PlaylistTypeLive),
+		maxSegments: maxSegments,
+	}
+}
+
+// AddCue queues a cue for the segment covering its start time. Cues that
+// end before the current segment are ignored.
+func (t *SubtitleTrack) AddCue(cue Cue) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if cue.End <= t.segmentStart || cue.End <= cue.Start {
+		return
+	}
+
+	t.cues = append(t.cues, cue)
+}
+
+// Flush closes the current subtitle segment at end, adds it to the playlist
+// and returns it. Cues running past end are repeated in the next segment,
+// as WebVTT segments must each carry the cues they overlap.
+func (t *SubtitleTrack) Flush(end time.Duration) *Segment {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if end <= t.segmentStart {
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n")
+
+	var carried []Cue
+	for _, cue := range t.cues {
+		if cue.Start >= end {
+			carried = append(carried, cue)
+			continue
+		}
+		fmt.Fprintf(&sb, "\n%s --> %s\n%s\n", vttTimestamp(cue.Start), vttTimestamp(cue.End), cue.Text)
+		if cue.End > end {
+			carried = append(carried, cue)
+		}
+	}
+
+	segment := &Segment{
+		Index:     t.index,
+		Duration:  (end - t.segmentStart).Seconds(),
+		Filename:  fmt.Sprintf("subs_%d.vtt", t.index),
+		Data:      []byte(sb.String()),
+		Type:      SegmentTypeSubtitle,
+		CreatedAt: time.Now(),
+	}
+
+	t.index++
+	t.segmentStart = end
+	t.cues = carried
+
+	t.playlist.AddSegment(segment)
+	t.playlist.RemoveOldSegments(t.maxSegments)
+
+	return segment
+}
+
+// Playlist returns the subtitle media playlist
+func (t *SubtitleTrack) Playlist() *MediaPlaylist {
+	return t.playlist
+}
+
+// vttTimestamp formats a stream offset as a WebVTT timestamp
+func vttTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -4,6 +4,7 @@
 package hls
 
 import (
+	"net/http"
 	"sync"
 	"time"
 )
@@ -48,6 +49,8 @@ const (
 	SegmentTypeAudio
 	// SegmentTypeMuxed represents a muxed audio+video segment
 	SegmentTypeMuxed
+	// SegmentTypeSubtitle represents a WebVTT subtitle segment
+	SegmentTypeSubtitle
 )
 
 // String returns the string representation of SegmentType
@@ -59,6 +62,8 @@ func (s SegmentType) String() string {
 		return "audio"
 	case SegmentTypeMuxed:
 		return "muxed"
+	case SegmentTypeSubtitle:
+		return "subtitle"
 	default:
 		return "unknown"
 	}
@@ -120,6 +125,13 @@ type MediaPlaylist struct {
 	// DVRWindowSize is the DVR window size in seconds
 	DVRWindowSize int
 
+	// StartOffset is where players should start, in seconds from the first
+	// segment (negative = from the end); used only when HasStartOffset is set
+	StartOffset float64
+
+	// HasStartOffset renders an EXT-X-START tag with StartOffset
+	HasStartOffset bool
+
 	mu sync.RWMutex
 }
 
@@ -167,6 +179,9 @@ type MasterPlaylist struct {
 	// Variants contains all quality variants
 	Variants []*Variant
 
+	// Subtitles contains the subtitle renditions offered with every variant
+	Subtitles []*SubtitleRendition
+
 	// CreatedAt is when this master playlist was created
 	CreatedAt time.Time
 
@@ -269,6 +284,10 @@ type ServerConfig struct {
 
 	// WriteTimeout is the HTTP write timeout
 	WriteTimeout time.Duration
+
+	// Middleware wraps the handler serving playlists and segments, e.g. to
+	// require playback tokens (nil = serve every request)
+	Middleware func(http.Handler) http.Handler
 }
 
 // DefaultServerConfig returns a default HLS server configuration
@@ -0,0 +1,273 @@
+package streaming
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/security"
+)
+
+// DefaultModerationSampleInterval is the minimum time between sampled frames of a stream
+const DefaultModerationSampleInterval = 5 * time.Second
+
+// DefaultModerationTimeout bounds a single frame inspection
+const DefaultModerationTimeout = 10 * time.Second
+
+// ErrStreamTerminatedByModeration is returned for frames of a stream that
+// moderation has terminated
+var ErrStreamTerminatedByModeration = errors.New("stream terminated by content moderation")
+
+// ModerationAction is what to do with a stream after a frame is inspected
+type ModerationAction string
+
+const (
+	// ModerationAllow takes no action
+	ModerationAllow ModerationAction = "allow"
+	// ModerationFlag records the frame for human review
+	ModerationFlag ModerationAction = "flag"
+	// ModerationBlur asks downstream processing to blur the stream
+	ModerationBlur ModerationAction = "blur"
+	// ModerationTerminate ends the stream
+	ModerationTerminate ModerationAction = "terminate"
+)
+
+// VideoFrame is a sampled frame from ingest
+type VideoFrame struct {
+	StreamID   string
+	UserID     string
+	Codec      string
+	Keyframe   bool
+	Timestamp  time.Duration // media timestamp
+	CapturedAt time.Time
+	Data       []byte
+}
+
+// InspectionResult is a classifier's verdict on a frame
+type InspectionResult struct {
+	Action     ModerationAction
+	Label      string  // e.g. "nudity", "violence"
+	Confidence float64 // 0.0 to 1.0
+	Reason     string
+}
+
+// FrameInspector classifies sampled frames, typically by calling an
+// external content classifier
+type FrameInspector interface {
+	Inspect(ctx context.Context, frame *VideoFrame) (*InspectionResult, error)
+}
+
+// FrameInspectorFunc adapts a function to a FrameInspector
+type FrameInspectorFunc func(ctx context.Context, frame *VideoFrame) (*InspectionResult, error)
+
+// Inspect calls the function
+func (f FrameInspectorFunc) Inspect(ctx context.Context, frame *VideoFrame) (*InspectionResult, error) {
+	return f(ctx, frame)
+}
+
+// ModerationConfig configures frame sampling
+type ModerationConfig struct {
+	// SampleInterval is the minimum time between sampled frames of a stream
+	SampleInterval time.Duration
+	// KeyframesOnly samples only keyframes, which decode without prior frames
+	KeyframesOnly bool
+	// Timeout bounds a single inspection
+	Timeout time.Duration
+}
+
+// DefaultModerationConfig returns the default moderation configuration
+func DefaultModerationConfig() ModerationConfig {
+	return ModerationConfig{
+		SampleInterval: DefaultModerationSampleInterval,
+		KeyframesOnly:  true,
+		Timeout:        DefaultModerationTimeout,
+	}
+}
+
+// FrameModerator samples ingest frames at a limited rate, passes them to a
+// FrameInspector and applies the resulting action. Every action other than
+// allow is written to the audit log.
+type FrameModerator struct {
+	inspector FrameInspector
+	config    ModerationConfig
+	audit     *security.AuditLogger
+	onAction  func(frame *VideoFrame, result *InspectionResult)
+
+	lastSample map[string]time.Time
+	inFlight   map[string]bool
+	blurred    map[string]bool
+	terminated map[string]bool
+	mu         sync.Mutex
+}
+
+// NewFrameModerator creates a frame moderator. audit may be nil.
+func NewFrameModerator(inspector FrameInspector, config ModerationConfig, audit *security.AuditLogger) *FrameModerator {
+	if config.SampleInterval <= 0 {
+		config.SampleInterval = DefaultModerationSampleInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultModerationTimeout
+	}
+
+	return &FrameModerator{
+		inspector:  inspector,
+		config:     config,
+		audit:      audit,
+		lastSample: make(map[string]time.Time),
+		inFlight:   make(map[string]bool),
+		blurred:    make(map[string]bool),
+		terminated: make(map[string]bool),
+	}
+}
+
+// OnAction registers a callback for actions other than allow, so the caller
+// can blur or tear down the stream on its ingest path
+func (m *FrameModerator) OnAction(callback func(frame *VideoFrame, result *InspectionResult)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onAction = callback
+}
+
+// SubmitFrame offers an ingest frame for moderation. Frames are sampled at
+// most once per SampleInterval per stream, with one inspection in flight at
+// a time, and inspected asynchronously so ingest is never blocked. It
+// returns ErrStreamTerminatedByModeration once the stream is terminated.
+func (m *FrameModerator) SubmitFrame(frame *VideoFrame) error {
+	sampled, err := m.sample(frame)
+	if err != nil || !sampled {
+		return err
+	}
+
+	// Copy the data, since ingest buffers are reused
+	sample := *frame
+	sample.Data = append([]byte(nil), frame.Data...)
+
+	go func() {
+		defer m.finish(sample.StreamID)
+
+		ctx, cancel := context.WithTimeout(context.Background(), m.config.Timeout)
+		defer cancel()
+
+		m.InspectFrame(ctx, &sample)
+	}()
+
+	return nil
+}
+
+// sample decides whether a frame is inspected and marks the stream in flight
+func (m *FrameModerator) sample(frame *VideoFrame) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.terminated[frame.StreamID] {
+		return false, ErrStreamTerminatedByModeration
+	}
+
+	if m.config.KeyframesOnly && !frame.Keyframe {
+		return false, nil
+	}
+
+	now := time.Now()
+	if m.inFlight[frame.StreamID] || now.Sub(m.lastSample[frame.StreamID]) < m.config.SampleInterval {
+		return false, nil
+	}
+
+	m.lastSample[frame.StreamID] = now
+	m.inFlight[frame.StreamID] = true
+	return true, nil
+}
+
+// finish clears a stream's in-flight inspection
+func (m *FrameModerator) finish(streamID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.inFlight, streamID)
+}
+
+// InspectFrame inspects a frame synchronously and applies the result
+func (m *FrameModerator) InspectFrame(ctx context.Context, frame *VideoFrame) (*InspectionResult, error) {
+	result, err := m.inspector.Inspect(ctx, frame)
+	if err != nil {
+		return nil, fmt.Errorf("frame inspection failed: %w", err)
+	}
+	if result == nil || result.Action == "" || result.Action == ModerationAllow {
+		return result, nil
+	}
+
+	m.mu.Lock()
+	switch result.Action {
+	case ModerationBlur:
+		m.blurred[frame.StreamID] = true
+	case ModerationTerminate:
+		m.terminated[frame.StreamID] = true
+	}
+	onAction := m.onAction
+	m.mu.Unlock()
+
+	m.logAction(frame, result)
+
+	if onAction != nil {
+		onAction(frame, result)
+	}
+
+	return result, nil
+}
+
+// logAction writes an audit log entry for a moderation action
+func (m *FrameModerator) logAction(frame *VideoFrame, result *InspectionResult) {
+	if m.audit == nil {
+		return
+	}
+
+	severity := security.AuditSeverityWarning
+	if result.Action == ModerationTerminate {
+		severity = security.AuditSeverityCritical
+	}
+
+	m.audit.Log(&security.AuditEvent{
+		Type:       security.AuditEventCompliance,
+		Severity:   severity,
+		UserID:     frame.UserID,
+		Action:     "moderation." + string(result.Action),
+		Resource:   "stream",
+		ResourceID: frame.StreamID,
+		Status:     string(result.Action),
+		Message:    result.Reason,
+		Metadata: map[string]interface{}{
+			"label":           result.Label,
+			"confidence":      result.Confidence,
+			"frame_timestamp": frame.Timestamp.String(),
+		},
+	})
+}
+
+// IsBlurred reports whether moderation asked for a stream to be blurred
+func (m *FrameModerator) IsBlurred(streamID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.blurred[streamID]
+}
+
+// IsTerminated reports whether moderation terminated a stream
+func (m *FrameModerator) IsTerminated(streamID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.terminated[streamID]
+}
+
+// ClearStream forgets a stream's sampling and moderation state, e.g. after
+// review or when the stream ends
+func (m *FrameModerator) ClearStream(streamID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.lastSample, streamID)
+	delete(m.blurred, streamID)
+	delete(m.terminated, streamID)
+}
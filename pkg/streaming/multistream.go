@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/aminofox/zenlive/pkg/clock"
+	"github.com/aminofox/zenlive/pkg/idgen"
 )
 
 // VideoSource represents a video source in a multi-stream setup
@@ -128,6 +131,7 @@ type MultiStreamManager struct {
 	streamSessions map[string]string              // streamID -> sessionID
 	mu             sync.RWMutex
 	callbacks      MultiStreamCallbacks
+	clock          clock.Clock
 }
 
 // MultiStreamCallbacks defines callback functions for multi-stream events
@@ -146,9 +150,17 @@ func NewMultiStreamManager() *MultiStreamManager {
 	return &MultiStreamManager{
 		sessions:       make(map[string]*MultiStreamSession),
 		streamSessions: make(map[string]string),
+		clock:          clock.Real(),
 	}
 }
 
+// SetClock sets the clock used for session and source timestamps
+func (msm *MultiStreamManager) SetClock(c clock.Clock) {
+	msm.mu.Lock()
+	defer msm.mu.Unlock()
+	msm.clock = clock.OrReal(c)
+}
+
 // SetCallbacks sets the callback functions for multi-stream events
 func (msm *MultiStreamManager) SetCallbacks(callbacks MultiStreamCallbacks) {
 	msm.mu.Lock()
@@ -187,7 +199,7 @@ func (msm *MultiStreamManager) CreateSession(streamID, hostUserID string, maxSou
 		},
 		MaxSources: maxSources,
 		Status:     SessionStatusPending,
-		CreatedAt:  time.Now(),
+		CreatedAt:  msm.clock.Now(),
 	}
 
 	msm.sessions[session.ID] = session
@@ -204,6 +216,7 @@ func (msm *MultiStreamManager) CreateSession(streamID, hostUserID string, maxSou
 // StartSession starts a multi-stream session
 func (msm *MultiStreamManager) StartSession(sessionID string) error {
 	msm.mu.RLock()
+	now := msm.clock.Now()
 	session, exists := msm.sessions[sessionID]
 	msm.mu.RUnlock()
 
@@ -222,7 +235,6 @@ func (msm *MultiStreamManager) StartSession(sessionID string) error {
 		return errors.New("cannot start an ended session")
 	}
 
-	now := time.Now()
 	session.Status = SessionStatusActive
 	session.StartedAt = &now
 
@@ -237,6 +249,7 @@ func (msm *MultiStreamManager) StartSession(sessionID string) error {
 // EndSession ends a multi-stream session
 func (msm *MultiStreamManager) EndSession(sessionID string) error {
 	msm.mu.RLock()
+	now := msm.clock.Now()
 	session, exists := msm.sessions[sessionID]
 	msm.mu.RUnlock()
 
@@ -251,7 +264,6 @@ func (msm *MultiStreamManager) EndSession(sessionID string) error {
 		return errors.New("session is already ended")
 	}
 
-	now := time.Now()
 	session.Status = SessionStatusEnded
 	session.EndedAt = &now
 
@@ -274,6 +286,7 @@ func (msm *MultiStreamManager) EndSession(sessionID string) error {
 // AddVideoSource adds a video source to a session
 func (msm *MultiStreamManager) AddVideoSource(sessionID, userID string, sourceType SourceType, streamURL string, resolution Resolution) (*VideoSource, error) {
 	msm.mu.RLock()
+	now := msm.clock.Now()
 	session, exists := msm.sessions[sessionID]
 	msm.mu.RUnlock()
 
@@ -302,8 +315,8 @@ func (msm *MultiStreamManager) AddVideoSource(sessionID, userID string, sourceTy
 		Bitrate:    2000000, // Default 2 Mbps
 		FPS:        30,      // Default 30 FPS
 		Active:     true,
-		JoinedAt:   time.Now(),
-		LastUpdate: time.Now(),
+		JoinedAt:   now,
+		LastUpdate: now,
 	}
 
 	session.VideoSources[source.ID] = source
@@ -322,6 +335,7 @@ func (msm *MultiStreamManager) AddVideoSource(sessionID, userID string, sourceTy
 // AddAudioSource adds an audio source to a session
 func (msm *MultiStreamManager) AddAudioSource(sessionID, userID, streamURL string, sampleRate, channels int) (*AudioSource, error) {
 	msm.mu.RLock()
+	now := msm.clock.Now()
 	session, exists := msm.sessions[sessionID]
 	msm.mu.RUnlock()
 
@@ -346,7 +360,7 @@ func (msm *MultiStreamManager) AddAudioSource(sessionID, userID, streamURL strin
 		Volume:     1.0,    // Full volume
 		Muted:      false,
 		Active:     true,
-		JoinedAt:   time.Now(),
+		JoinedAt:   now,
 	}
 
 	session.AudioSources[source.ID] = source
@@ -557,7 +571,8 @@ func (msm *MultiStreamManager) MuteAudioSource(sessionID, sourceID string, muted
 	return nil
 }
 
-// GetSession returns a session by ID
+// GetSession returns a session by ID. The session is live and mutated by the
+// manager under its own lock; use Snapshot to read its fields.
 func (msm *MultiStreamManager) GetSession(sessionID string) (*MultiStreamSession, error) {
 	msm.mu.RLock()
 	defer msm.mu.RUnlock()
@@ -570,7 +585,8 @@ func (msm *MultiStreamManager) GetSession(sessionID string) (*MultiStreamSession
 	return session, nil
 }
 
-// GetSessionByStream returns a session by stream ID
+// GetSessionByStream returns a session by stream ID. Like GetSession, the
+// session is live.
 func (msm *MultiStreamManager) GetSessionByStream(streamID string) (*MultiStreamSession, error) {
 	msm.mu.RLock()
 	defer msm.mu.RUnlock()
@@ -584,14 +600,19 @@ func (msm *MultiStreamManager) GetSessionByStream(streamID string) (*MultiStream
 	return session, nil
 }
 
-// GetActiveSessions returns all active sessions
+// GetActiveSessions returns all active sessions. The sessions are live; use
+// Snapshot for copies.
 func (msm *MultiStreamManager) GetActiveSessions() []*MultiStreamSession {
 	msm.mu.RLock()
 	defer msm.mu.RUnlock()
 
 	sessions := make([]*MultiStreamSession, 0)
 	for _, session := range msm.sessions {
-		if session.Status == SessionStatusActive {
+		session.mu.RLock()
+		active := session.Status == SessionStatusActive
+		session.mu.RUnlock()
+
+		if active {
 			sessions = append(sessions, session)
 		}
 	}
@@ -599,6 +620,84 @@ func (msm *MultiStreamManager) GetActiveSessions() []*MultiStreamSession {
 	return sessions
 }
 
+// Snapshot returns deep copies of all sessions, which callers may read or
+// modify freely without racing the manager
+func (msm *MultiStreamManager) Snapshot() []*MultiStreamSession {
+	msm.mu.RLock()
+	defer msm.mu.RUnlock()
+
+	sessions := make([]*MultiStreamSession, 0, len(msm.sessions))
+	for _, session := range msm.sessions {
+		sessions = append(sessions, session.Snapshot())
+	}
+
+	return sessions
+}
+
+// Snapshot returns a deep copy of the session taken under its lock. The copy
+// is detached: later changes to the session are not reflected in it.
+func (s *MultiStreamSession) Snapshot() *MultiStreamSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := &MultiStreamSession{
+		ID:           s.ID,
+		StreamID:     s.StreamID,
+		HostUserID:   s.HostUserID,
+		VideoSources: make(map[string]*VideoSource, len(s.VideoSources)),
+		AudioSources: make(map[string]*AudioSource, len(s.AudioSources)),
+		MaxSources:   s.MaxSources,
+		Status:       s.Status,
+		CreatedAt:    s.CreatedAt,
+		StartedAt:    copyTime(s.StartedAt),
+		EndedAt:      copyTime(s.EndedAt),
+	}
+
+	for id, source := range s.VideoSources {
+		c := *source
+		c.Metadata = copyMetadata(source.Metadata)
+		snapshot.VideoSources[id] = &c
+	}
+	for id, source := range s.AudioSources {
+		c := *source
+		c.Metadata = copyMetadata(source.Metadata)
+		snapshot.AudioSources[id] = &c
+	}
+
+	if s.Layout != nil {
+		layout := *s.Layout
+		layout.Positions = make([]*SourcePosition, len(s.Layout.Positions))
+		for i, pos := range s.Layout.Positions {
+			p := *pos
+			layout.Positions[i] = &p
+		}
+		snapshot.Layout = &layout
+	}
+
+	return snapshot
+}
+
+// copyTime copies an optional timestamp
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+// copyMetadata makes a shallow copy of a metadata map
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		c[key] = value
+	}
+	return c
+}
+
 // MixAudio mixes audio from multiple sources
 func (msm *MultiStreamManager) MixAudio(sessionID string, audioBuffers map[string][]byte) ([]byte, error) {
 	msm.mu.RLock()
@@ -651,21 +750,20 @@ func (msm *MultiStreamManager) MixAudio(sessionID string, audioBuffers map[strin
 	return mixed, nil
 }
 
-var (
-	multiStreamIDCounter int64
-	sourceIDCounter      int64
-)
+// idGenerator produces session and source IDs
+var idGenerator = idgen.NewSwappable(idgen.Default())
+
+// SetIDGenerator replaces the generator used for multi-stream session and source IDs
+func SetIDGenerator(gen idgen.Generator) {
+	idGenerator.Set(gen)
+}
 
 // generateMultiStreamID generates a unique multi-stream session ID
 func generateMultiStreamID() string {
-	id := time.Now().UnixNano() + multiStreamIDCounter
-	multiStreamIDCounter++
-	return fmt.Sprintf("multistream_%d", id)
+	return idgen.WithPrefix(idGenerator, "multistream")
 }
 
 // generateSourceID generates a unique source ID
 func generateSourceID() string {
-	id := time.Now().UnixNano() + sourceIDCounter
-	sourceIDCounter++
-	return fmt.Sprintf("source_%d", id)
+	return idgen.WithPrefix(idGenerator, "source")
 }
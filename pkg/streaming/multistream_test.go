@@ -1,7 +1,23 @@
 package streaming
 
 import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/clock"
+	"github.com/aminofox/zenlive/pkg/cluster"
+	"github.com/aminofox/zenlive/pkg/idgen"
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/security"
+	"github.com/aminofox/zenlive/pkg/streaming/hls"
 )
 
 func TestMultiStreamManager_CreateSession(t *testing.T) {
@@ -396,3 +412,365 @@ func TestMultiStreamManager_MixAudio(t *testing.T) {
 		t.Error("Mixed audio should not be empty")
 	}
 }
+
+func TestFrameModerator(t *testing.T) {
+	inspector := FrameInspectorFunc(func(ctx context.Context, frame *VideoFrame) (*InspectionResult, error) {
+		if frame.StreamID == "bad" {
+			return &InspectionResult{Action: ModerationTerminate, Label: "violence", Confidence: 0.97, Reason: "prohibited content"}, nil
+		}
+		return &InspectionResult{Action: ModerationAllow}, nil
+	})
+
+	audit := security.NewAuditLogger(100, nil)
+	moderator := NewFrameModerator(inspector, ModerationConfig{SampleInterval: time.Hour, KeyframesOnly: true}, audit)
+
+	var actions []ModerationAction
+	moderator.OnAction(func(frame *VideoFrame, result *InspectionResult) {
+		actions = append(actions, result.Action)
+	})
+
+	// Only the first keyframe within the interval is sampled
+	if sampled, _ := moderator.sample(&VideoFrame{StreamID: "good", Keyframe: false}); sampled {
+		t.Error("Expected non-keyframe not to be sampled")
+	}
+	if sampled, _ := moderator.sample(&VideoFrame{StreamID: "good", Keyframe: true}); !sampled {
+		t.Error("Expected first keyframe to be sampled")
+	}
+	moderator.finish("good")
+	if sampled, _ := moderator.sample(&VideoFrame{StreamID: "good", Keyframe: true}); sampled {
+		t.Error("Expected keyframe within sample interval to be skipped")
+	}
+
+	result, err := moderator.InspectFrame(context.Background(), &VideoFrame{StreamID: "bad", UserID: "u1", Keyframe: true})
+	if err != nil || result.Action != ModerationTerminate {
+		t.Fatalf("Expected terminate, got %+v, %v", result, err)
+	}
+	if !moderator.IsTerminated("bad") || len(actions) != 1 {
+		t.Errorf("Expected stream terminated and action reported, got %v", actions)
+	}
+	if err := moderator.SubmitFrame(&VideoFrame{StreamID: "bad", Keyframe: true}); err != ErrStreamTerminatedByModeration {
+		t.Errorf("Expected ErrStreamTerminatedByModeration, got %v", err)
+	}
+
+	events := audit.GetRecent(10)
+	if len(events) != 1 || events[0].Type != security.AuditEventCompliance || events[0].ResourceID != "bad" {
+		t.Errorf("Expected one compliance audit entry, got %+v", events)
+	}
+
+	moderator.ClearStream("bad")
+	if moderator.IsTerminated("bad") {
+		t.Error("Expected cleared stream to be allowed again")
+	}
+}
+
+func TestCaptioner(t *testing.T) {
+	stereo := []int16{100, 300, 200, 400, 300, 500, 400, 600}
+	if mono := Downsample(stereo, 2, 48000, 48000); len(mono) != 4 || mono[0] != 200 {
+		t.Errorf("Expected mono mix, got %v", mono)
+	}
+	if down := Downsample(stereo, 2, 32000, 16000); len(down) != 2 || down[0] != 250 || down[1] != 450 {
+		t.Errorf("Expected averaged downsample, got %v", down)
+	}
+
+	var gotChunk *AudioChunk
+	sink := transcriptionSinkFunc(func(ctx context.Context, chunk *AudioChunk) ([]TranscriptResult, error) {
+		gotChunk = chunk
+		return []TranscriptResult{
+			{Text: "hel", Start: chunk.Timestamp, End: chunk.Timestamp + time.Second},
+			{Text: "hello", Start: chunk.Timestamp, End: chunk.Timestamp + time.Second, Final: true},
+		}, nil
+	})
+
+	captioner := NewCaptioner(sink, 0)
+	track := hls.NewSubtitleTrack(6, 0)
+	captioner.SetSubtitleTrack("stream1", track)
+
+	var captions []TranscriptResult
+	captioner.OnCaption(func(result TranscriptResult) {
+		captions = append(captions, result)
+	})
+
+	chunk := &AudioChunk{StreamID: "stream1", SampleRate: 48000, Channels: 2, Samples: make([]int16, 9600), Timestamp: 2 * time.Second}
+	if _, err := captioner.PushAudio(context.Background(), chunk); err != nil {
+		t.Fatalf("PushAudio failed: %v", err)
+	}
+
+	if gotChunk.SampleRate != DefaultTranscriptionSampleRate || gotChunk.Channels != 1 || len(gotChunk.Samples) != 1600 {
+		t.Errorf("Expected 16 kHz mono audio, got %d Hz, %d channels, %d samples", gotChunk.SampleRate, gotChunk.Channels, len(gotChunk.Samples))
+	}
+	if len(captions) != 2 || captions[0].Final || !captions[1].Final || captions[1].StreamID != "stream1" {
+		t.Errorf("Expected partial then final caption, got %+v", captions)
+	}
+
+	segment := track.Flush(6 * time.Second)
+	if vtt := string(segment.Data); !strings.Contains(vtt, "hello") || strings.Contains(vtt, "hel\n") {
+		t.Errorf("Expected only the final result as a cue, got %q", vtt)
+	}
+}
+
+// transcriptionSinkFunc adapts a function to a TranscriptionSink
+type transcriptionSinkFunc func(ctx context.Context, chunk *AudioChunk) ([]TranscriptResult, error)
+
+func (f transcriptionSinkFunc) Transcribe(ctx context.Context, chunk *AudioChunk) ([]TranscriptResult, error) {
+	return f(ctx, chunk)
+}
+
+func TestDeterministicIDs(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	SetIDGenerator(idgen.NewSequenceGenerator())
+	defer SetIDGenerator(idgen.Default())
+
+	msm := NewMultiStreamManager()
+	msm.SetClock(clock.NewFake(created))
+	session, err := msm.CreateSession("stream1", "host1", 4)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	if session.ID != "multistream_000000000001" {
+		t.Errorf("Expected multistream_000000000001, got %s", session.ID)
+	}
+	if !session.CreatedAt.Equal(created) {
+		t.Errorf("Expected CreatedAt %v, got %v", created, session.CreatedAt)
+	}
+}
+
+func TestSessionSnapshot(t *testing.T) {
+	msm := NewMultiStreamManager()
+	session, _ := msm.CreateSession("stream1", "host1", 4)
+	msm.StartSession(session.ID)
+	source, _ := msm.AddVideoSource(session.ID, "user1", SourceTypeCamera, "rtmp://example/1", Resolution{Width: 1280, Height: 720})
+
+	snapshot := session.Snapshot()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		msm.AddVideoSource(session.ID, "user2", SourceTypeScreen, "rtmp://example/2", Resolution{Width: 1920, Height: 1080})
+		msm.EndSession(session.ID)
+	}()
+	_ = len(snapshot.VideoSources)
+	_ = snapshot.Status
+	wg.Wait()
+
+	if len(snapshot.VideoSources) != 1 || !snapshot.VideoSources[source.ID].Active {
+		t.Errorf("Expected snapshot to keep one active source, got %+v", snapshot.VideoSources)
+	}
+	if snapshot.Status != SessionStatusActive || snapshot.EndedAt != nil {
+		t.Errorf("Expected snapshot to stay active, got %s", snapshot.Status)
+	}
+
+	if sessions := msm.Snapshot(); len(sessions) != 1 || sessions[0].Status != SessionStatusEnded {
+		t.Errorf("Expected one ended session in manager snapshot, got %+v", sessions)
+	}
+}
+
+func TestPlaybackToken(t *testing.T) {
+	token, err := NewPlaybackToken("stream1", "user1", time.Minute, "secret")
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	parsed, err := ParsePlaybackToken(token, "secret")
+	if err != nil || parsed.StreamID != "stream1" || parsed.UserID != "user1" {
+		t.Fatalf("Expected valid token for stream1/user1, got %+v (%v)", parsed, err)
+	}
+	if _, err := ParsePlaybackToken(token, "other"); err != ErrInvalidPlaybackToken {
+		t.Errorf("Expected ErrInvalidPlaybackToken for wrong secret, got %v", err)
+	}
+
+	second, _ := NewPlaybackToken("stream1", "user1", time.Minute, "secret")
+	if second == token {
+		t.Error("Expected a new session token per call")
+	}
+
+	expired := signPlaybackToken(&PlaybackToken{StreamID: "stream1", ExpiresAt: time.Now().Add(-time.Second)}, "secret")
+	if _, err := ParsePlaybackToken(expired, "secret"); err != ErrPlaybackTokenExpired {
+		t.Errorf("Expected ErrPlaybackTokenExpired, got %v", err)
+	}
+
+	origin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".m3u8") {
+			io.WriteString(w, "#EXTM3U\n#EXT-X-MAP:URI=\"init.mp4\"\n#EXTINF:2.0,\nsegment_0.ts\n")
+			return
+		}
+		io.WriteString(w, "segment")
+	})
+	server := httptest.NewServer(PlaybackAuthMiddleware("secret", origin))
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if status, _ := get("/stream1/playlist.m3u8"); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", status)
+	}
+	if status, _ := get("/stream2/playlist.m3u8?token=" + token); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for another stream, got %d", status)
+	}
+
+	status, body := get("/stream1/playlist.m3u8?token=" + token)
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200 with token, got %d", status)
+	}
+	if !strings.Contains(body, "segment_0.ts?token="+token) || !strings.Contains(body, `URI="init.mp4?token=`+token+`"`) {
+		t.Errorf("Expected token appended to playlist URIs, got %q", body)
+	}
+
+	if status, _ := get("/stream1/segment_0.ts?token=" + token); status != http.StatusOK {
+		t.Errorf("Expected 200 for segment with token, got %d", status)
+	}
+
+	manifest := tokenizeManifest([]byte(`<SegmentTemplate media="seg_$Number$.m4s?v=1" initialization="init.mp4"/>`), "abc")
+	if string(manifest) != `<SegmentTemplate media="seg_$Number$.m4s?v=1&amp;token=abc" initialization="init.mp4?token=abc"/>` {
+		t.Errorf("Unexpected tokenized manifest %s", manifest)
+	}
+}
+
+func TestPlaybackAuthHLSServer(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+
+	outputDir := t.TempDir()
+	transmuxer, err := hls.NewTransmuxer(&hls.TransmuxerConfig{OutputDir: outputDir, SegmentDuration: 2}, log)
+	if err != nil {
+		t.Fatalf("Failed to create transmuxer: %v", err)
+	}
+	transmuxer.StartStream("stream1")
+	os.MkdirAll(filepath.Join(outputDir, "stream1"), 0755)
+	os.WriteFile(filepath.Join(outputDir, "stream1", "playlist.m3u8"), []byte("#EXTM3U\n#EXTINF:2.0,\nsegment_0.ts\n"), 0644)
+	os.WriteFile(filepath.Join(outputDir, "stream1", "segment_0.ts"), []byte("segment"), 0644)
+
+	config := hls.DefaultServerConfig()
+	config.Middleware = func(next http.Handler) http.Handler {
+		return PlaybackAuthMiddleware("secret", next)
+	}
+	hlsServer, err := hls.NewServer(config, transmuxer, log)
+	if err != nil {
+		t.Fatalf("Failed to create HLS server: %v", err)
+	}
+	server := httptest.NewServer(hlsServer.Handler())
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if status, _ := get("/stream1/playlist.m3u8"); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a playlist without token, got %d", status)
+	}
+	if status, _ := get("/stream1/segment_0.ts"); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a segment without token, got %d", status)
+	}
+
+	token, _ := NewPlaybackToken("stream1", "user1", time.Minute, "secret")
+	status, body := get("/stream1/playlist.m3u8?token=" + token)
+	if status != http.StatusOK || !strings.Contains(body, "segment_0.ts?token="+token) {
+		t.Fatalf("Expected a tokenized playlist, got %d %q", status, body)
+	}
+	if status, body := get("/stream1/segment_0.ts?token=" + token); status != http.StatusOK || body != "segment" {
+		t.Errorf("Expected the segment with token, got %d %q", status, body)
+	}
+}
+
+func TestViewingLimiter(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewViewingLimiter(cluster.NewInMemorySessionManager(time.Minute), "node-1", 2)
+	limiter.SetLimitFunc(func(ctx context.Context, userID string) int {
+		if userID == "premium" {
+			return 0
+		}
+		return -1
+	})
+
+	var upsell []string
+	limiter.OnLimitReached(func(userID, streamID string, limit int) {
+		upsell = append(upsell, userID+"/"+streamID)
+	})
+
+	if err := limiter.Acquire(ctx, "user1", "stream1", "s1"); err != nil {
+		t.Fatalf("Failed to acquire first stream: %v", err)
+	}
+	if err := limiter.Acquire(ctx, "user1", "stream2", "s2"); err != nil {
+		t.Fatalf("Failed to acquire second stream: %v", err)
+	}
+	if err := limiter.Acquire(ctx, "user1", "stream1", "s3"); err != nil {
+		t.Errorf("Expected another session on a watched stream to be admitted, got %v", err)
+	}
+	if err := limiter.Acquire(ctx, "user1", "stream3", "s4"); err != ErrConcurrentStreamLimit {
+		t.Errorf("Expected ErrConcurrentStreamLimit, got %v", err)
+	}
+	if len(upsell) != 1 || upsell[0] != "user1/stream3" {
+		t.Errorf("Expected one upsell callback, got %v", upsell)
+	}
+	if err := limiter.Acquire(ctx, "user1", "stream2", "s2"); err != nil {
+		t.Errorf("Expected existing session to refresh, got %v", err)
+	}
+
+	limiter.Release(ctx, "s2")
+	if err := limiter.Acquire(ctx, "user1", "stream3", "s4"); err != nil {
+		t.Errorf("Expected admission after release, got %v", err)
+	}
+	if n, _ := limiter.ActiveStreams(ctx, "user1"); n != 2 {
+		t.Errorf("Expected 2 active streams, got %d", n)
+	}
+
+	for i, stream := range []string{"a", "b", "c"} {
+		if err := limiter.Acquire(ctx, "premium", stream, "p"+stream); err != nil {
+			t.Errorf("Expected unlimited plan to admit stream %d, got %v", i, err)
+		}
+	}
+
+	// The playback middleware enforces the limit per token session
+	server := httptest.NewServer(PlaybackAuthMiddlewareWithLimiter("secret", limiter, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "segment")
+	})))
+	defer server.Close()
+
+	token, _ := NewPlaybackToken("stream9", "user1", time.Minute, "secret")
+	resp, err := http.Get(server.URL + "/stream9/segment_0.ts?token=" + token)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 over the stream limit, got %d", resp.StatusCode)
+	}
+}
+
+func TestTranscoderProfiles(t *testing.T) {
+	profiles, err := TranscoderProfiles([]string{hls.Preset1080p60, hls.Preset720p60, hls.PresetAudioOnly}, 1280, 720)
+	if err != nil {
+		t.Fatalf("Failed to expand profiles: %v", err)
+	}
+
+	if len(profiles) != 2 {
+		t.Fatalf("Expected 1080p60 to be left out for a 720p source, got %d profiles", len(profiles))
+	}
+
+	video := profiles[0]
+	if video.Name != hls.Preset720p60 || video.Width != 1280 || video.GOPSize != 120 {
+		t.Errorf("Expected a 720p60 profile with a 120-frame GOP, got %+v", video)
+	}
+	if video.MaxVideoBitrate <= video.VideoBitrate {
+		t.Error("Expected max video bitrate above the target")
+	}
+
+	audio := profiles[1]
+	if audio.VideoCodec != "" || audio.GOPSize != 0 || audio.AudioBitrate == 0 {
+		t.Errorf("Expected an audio-only profile, got %+v", audio)
+	}
+}
@@ -0,0 +1,237 @@
+package streaming
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PlaybackTokenParam is the query parameter carrying a playback token
+const PlaybackTokenParam = "token"
+
+var (
+	// ErrInvalidPlaybackToken is returned for malformed or forged tokens
+	ErrInvalidPlaybackToken = errors.New("invalid playback token")
+
+	// ErrPlaybackTokenExpired is returned for tokens past their expiry
+	ErrPlaybackTokenExpired = errors.New("playback token expired")
+
+	// ErrPlaybackTokenStream is returned when a token is used for another stream
+	ErrPlaybackTokenStream = errors.New("playback token not valid for stream")
+)
+
+// PlaybackToken authorizes one viewer's playback session of a stream
+type PlaybackToken struct {
+	StreamID  string
+	UserID    string
+	SessionID string
+	ExpiresAt time.Time
+}
+
+// NewPlaybackToken signs a token for a new playback session of streamID by
+// userID, valid for ttl. Each call starts a new session, so tokens rotate
+// per session and one leaked token exposes only one session.
+func NewPlaybackToken(streamID, userID string, ttl time.Duration, secret string) (string, error) {
+	if streamID == "" || secret == "" || ttl <= 0 {
+		return "", ErrInvalidPlaybackToken
+	}
+
+	return signPlaybackToken(&PlaybackToken{
+		StreamID:  streamID,
+		UserID:    userID,
+		SessionID: uuid.New().String(),
+		ExpiresAt: time.Now().Add(ttl),
+	}, secret), nil
+}
+
+// signPlaybackToken encodes a token as base64url("stream|user|session|exp")
+// followed by "." and the base64url HMAC-SHA256 of the payload
+func signPlaybackToken(token *PlaybackToken, secret string) string {
+	payload := strings.Join([]string{
+		token.StreamID,
+		token.UserID,
+		token.SessionID,
+		strconv.FormatInt(token.ExpiresAt.Unix(), 10),
+	}, "|")
+
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + playbackSignature(encoded, secret)
+}
+
+// playbackSignature returns the base64url HMAC-SHA256 of an encoded payload
+func playbackSignature(encoded, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// ParsePlaybackToken verifies a token's signature and expiry
+func ParsePlaybackToken(token, secret string) (*PlaybackToken, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(playbackSignature(encoded, secret))) {
+		return nil, ErrInvalidPlaybackToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidPlaybackToken
+	}
+
+	fields := strings.Split(string(payload), "|")
+	if len(fields) != 4 {
+		return nil, ErrInvalidPlaybackToken
+	}
+
+	expires, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidPlaybackToken
+	}
+
+	parsed := &PlaybackToken{
+		StreamID:  fields[0],
+		UserID:    fields[1],
+		SessionID: fields[2],
+		ExpiresAt: time.Unix(expires, 0),
+	}
+
+	if !time.Now().Before(parsed.ExpiresAt) {
+		return nil, ErrPlaybackTokenExpired
+	}
+
+	return parsed, nil
+}
+
+// PlaybackAuthMiddleware gates HLS and DASH requests of the form
+// /{streamID}/{file} behind a playback token for that stream, passed in the
+// PlaybackTokenParam query parameter. Native players do not carry query
+// parameters from a playlist to the URIs in it, so the token is appended to
+// every URI in served playlists and manifests.
+func PlaybackAuthMiddleware(secret string, next http.Handler) http.Handler {
+	return PlaybackAuthMiddlewareWithLimiter(secret, nil, next)
+}
+
+// PlaybackAuthMiddlewareWithLimiter is PlaybackAuthMiddleware that also
+// holds each token's session in a viewing limiter. Every request refreshes
+// the session, so it lapses once the player stops fetching.
+func PlaybackAuthMiddlewareWithLimiter(secret string, limiter *ViewingLimiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streamID, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if r.Method == http.MethodOptions || streamID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := r.URL.Query().Get(PlaybackTokenParam)
+		parsed, err := ParsePlaybackToken(token, secret)
+		if err == nil && parsed.StreamID != streamID {
+			err = ErrPlaybackTokenStream
+		}
+		if err == nil && limiter != nil {
+			err = limiter.Acquire(r.Context(), parsed.UserID, parsed.StreamID, parsed.SessionID)
+		}
+		if err != nil {
+			status := http.StatusUnauthorized
+			switch {
+			case errors.Is(err, ErrPlaybackTokenStream), errors.Is(err, ErrConcurrentStreamLimit):
+				status = http.StatusForbidden
+			case !errors.Is(err, ErrInvalidPlaybackToken) && !errors.Is(err, ErrPlaybackTokenExpired):
+				status = http.StatusInternalServerError
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		if !strings.HasSuffix(r.URL.Path, ".m3u8") && !strings.HasSuffix(r.URL.Path, ".mpd") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rw := &bufferedResponseWriter{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		body := rw.body.Bytes()
+		if rw.status == http.StatusOK {
+			if strings.HasSuffix(r.URL.Path, ".mpd") {
+				body = tokenizeManifest(body, token)
+			} else {
+				body = tokenizePlaylist(body, token)
+			}
+		}
+
+		for key, values := range rw.header {
+			w.Header()[key] = values
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(rw.status)
+		w.Write(body)
+	})
+}
+
+// bufferedResponseWriter captures a response so it can be rewritten
+type bufferedResponseWriter struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func (w *bufferedResponseWriter) Header() http.Header         { return w.header }
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *bufferedResponseWriter) WriteHeader(status int)      { w.status = status }
+
+// playlistURIAttr matches URI attributes of HLS tags (EXT-X-MEDIA, EXT-X-MAP, ...)
+var playlistURIAttr = regexp.MustCompile(`URI="([^"]*)"`)
+
+// manifestURLAttr matches DASH attributes holding segment URLs or templates
+var manifestURLAttr = regexp.MustCompile(`(media|initialization|sourceURL)="([^"]*)"`)
+
+// withToken appends the playback token to a URI
+func withToken(uri, token string) string {
+	separator := "?"
+	if strings.Contains(uri, "?") {
+		separator = "&"
+	}
+	return uri + separator + PlaybackTokenParam + "=" + token
+}
+
+// tokenizePlaylist appends the token to every URI line and URI attribute of
+// an HLS playlist
+func tokenizePlaylist(playlist []byte, token string) []byte {
+	lines := strings.Split(string(playlist), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			lines[i] = playlistURIAttr.ReplaceAllStringFunc(line, func(attr string) string {
+				uri := playlistURIAttr.FindStringSubmatch(attr)[1]
+				return `URI="` + withToken(uri, token) + `"`
+			})
+		default:
+			lines[i] = withToken(trimmed, token)
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// tokenizeManifest appends the token to the segment URLs and templates of
+// a DASH manifest, escaping the separator for XML
+func tokenizeManifest(manifest []byte, token string) []byte {
+	return manifestURLAttr.ReplaceAllFunc(manifest, func(attr []byte) []byte {
+		match := manifestURLAttr.FindSubmatch(attr)
+		uri := string(match[2])
+		separator := "?"
+		if strings.Contains(uri, "?") {
+			separator = "&amp;"
+		}
+		return []byte(string(match[1]) + `="` + uri + separator + PlaybackTokenParam + "=" + token + `"`)
+	})
+}
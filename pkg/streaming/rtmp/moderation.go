@@ -0,0 +1,65 @@
+package rtmp
+
+import (
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/streaming"
+)
+
+// FLV video tag frame types and codec IDs (first byte of the tag body)
+const (
+	flvFrameTypeKeyframe = 1
+	flvCodecAVC          = 7
+	flvCodecHEVC         = 12
+)
+
+// moderationStage submits video tags to a frame moderator
+type moderationStage struct {
+	moderator *streaming.FrameModerator
+}
+
+// NewModerationStage returns a pipeline stage that offers video tags to a
+// frame moderator and rejects the tags of streams it has terminated, which
+// closes the publisher's connection
+func NewModerationStage(moderator *streaming.FrameModerator) PipelineStage {
+	return &moderationStage{moderator: moderator}
+}
+
+// Name returns the stage name
+func (s *moderationStage) Name() string {
+	return "moderation"
+}
+
+// Process submits video tags for sampling
+func (s *moderationStage) Process(event *PipelineEvent) (*PipelineEvent, error) {
+	if event.Type != PipelineEventTag || event.TagType != MessageTypeVideo || len(event.Payload) == 0 {
+		return event, nil
+	}
+
+	frame := &streaming.VideoFrame{
+		StreamID:   event.StreamKey,
+		Codec:      flvVideoCodec(event.Payload[0] & 0x0f),
+		Keyframe:   event.Payload[0]>>4 == flvFrameTypeKeyframe,
+		Timestamp:  time.Duration(event.Timestamp) * time.Millisecond,
+		CapturedAt: time.Now(),
+		Data:       event.Payload,
+	}
+
+	if err := s.moderator.SubmitFrame(frame); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// flvVideoCodec names an FLV video codec ID
+func flvVideoCodec(codecID byte) string {
+	switch codecID {
+	case flvCodecAVC:
+		return "h264"
+	case flvCodecHEVC:
+		return "hevc"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,178 @@
+package rtmp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// PipelineEventType identifies the ingest event passed through a pipeline
+type PipelineEventType string
+
+const (
+	// PipelineEventPublish is run when a client starts publishing a stream
+	PipelineEventPublish PipelineEventType = "publish"
+	// PipelineEventMetadata is run when a publisher sends @setDataFrame metadata
+	PipelineEventMetadata PipelineEventType = "metadata"
+	// PipelineEventTag is run for each audio or video message
+	PipelineEventTag PipelineEventType = "tag"
+	// PipelineEventUnpublish is run when a publisher deletes its stream or
+	// disconnects. Its result is ignored.
+	PipelineEventUnpublish PipelineEventType = "unpublish"
+)
+
+// ErrPublishDropped is returned when a stage drops a publish event, which
+// rejects the publish like an error would
+var ErrPublishDropped = errors.New("publish dropped by pipeline")
+
+// PipelineEvent is an ingest event as seen by pipeline stages. Stages may
+// modify it in place or return a replacement.
+type PipelineEvent struct {
+	// Type is the kind of event
+	Type PipelineEventType
+
+	// StreamKey is the publishing stream
+	StreamKey string
+
+	// PublishType is the publish type (publish events only)
+	PublishType string
+
+	// Metadata is the stream metadata (publish and metadata events)
+	Metadata map[string]interface{}
+
+	// TagType is MessageTypeAudio or MessageTypeVideo (tag events only)
+	TagType uint8
+
+	// Timestamp is the tag timestamp in milliseconds (tag events only)
+	Timestamp uint32
+
+	// Payload is the FLV tag body (tag events only)
+	Payload []byte
+}
+
+// PipelineStage is a step of the publish pipeline. Process returns the event
+// to pass to the next stage, nil to drop the event, or an error to reject
+// it. A rejected publish or metadata event stops the stream; a rejected tag
+// closes the publisher's connection. Dropping a publish event rejects it,
+// dropping metadata keeps the previous metadata, and dropping a tag skips it.
+type PipelineStage interface {
+	// Name identifies the stage in logs and errors
+	Name() string
+
+	// Process handles an event
+	Process(event *PipelineEvent) (*PipelineEvent, error)
+}
+
+// StageFunc adapts a function to a PipelineStage
+type StageFunc struct {
+	StageName string
+	Fn        func(event *PipelineEvent) (*PipelineEvent, error)
+}
+
+// Name returns the stage name
+func (f StageFunc) Name() string {
+	return f.StageName
+}
+
+// Process calls the function
+func (f StageFunc) Process(event *PipelineEvent) (*PipelineEvent, error) {
+	return f.Fn(event)
+}
+
+// StageError reports the stage that rejected an event
+type StageError struct {
+	Stage string
+	Event PipelineEventType
+	Err   error
+}
+
+// Error implements the error interface
+func (e *StageError) Error() string {
+	return fmt.Sprintf("pipeline stage %s rejected %s: %v", e.Stage, e.Event, e.Err)
+}
+
+// Unwrap returns the stage's error
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Pipeline runs ingest events through stages in the order they were added
+type Pipeline struct {
+	stages []PipelineStage
+	mu     sync.RWMutex
+}
+
+// NewPipeline creates a pipeline with the given stages
+func NewPipeline(stages ...PipelineStage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Use appends stages to the end of the pipeline
+func (p *Pipeline) Use(stages ...PipelineStage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stages = append(p.stages, stages...)
+}
+
+// Remove removes the stage with the given name, reporting whether it existed
+func (p *Pipeline) Remove(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, stage := range p.stages {
+		if stage.Name() == name {
+			p.stages = append(p.stages[:i:i], p.stages[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Stages returns the names of the stages in order
+func (p *Pipeline) Stages() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	names := make([]string, len(p.stages))
+	for i, stage := range p.stages {
+		names[i] = stage.Name()
+	}
+
+	return names
+}
+
+// Run passes an event through each stage. It returns the final event, nil
+// if a stage dropped it, or a *StageError if a stage rejected it or panicked.
+func (p *Pipeline) Run(event *PipelineEvent) (*PipelineEvent, error) {
+	p.mu.RLock()
+	stages := p.stages
+	p.mu.RUnlock()
+
+	eventType := event.Type
+	for _, stage := range stages {
+		next, err := runStage(stage, event)
+		if err != nil {
+			return nil, &StageError{Stage: stage.Name(), Event: eventType, Err: err}
+		}
+		if next == nil {
+			return nil, nil
+		}
+		event = next
+	}
+
+	return event, nil
+}
+
+// runStage calls a stage, turning a panic into an error so one faulty stage
+// cannot take down the server
+func runStage(stage PipelineStage, event *PipelineEvent) (next *PipelineEvent, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			next, err = nil, fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return stage.Process(event)
+}
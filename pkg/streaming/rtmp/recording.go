@@ -0,0 +1,120 @@
+package rtmp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/storage"
+)
+
+// TagRecorder is a recorder fed with the audio and video tags of a
+// published stream, such as formats.FLVRecorder
+type TagRecorder interface {
+	storage.Recorder
+
+	// WriteTag records a tag body with its type and timestamp
+	WriteTag(tagType uint8, timestamp uint32, payload []byte) error
+}
+
+// recordingStage records published streams
+type recordingStage struct {
+	newRecorder func(streamKey string) (TagRecorder, error)
+	registry    *storage.RecorderRegistry
+	logger      logger.Logger
+
+	recorders map[string]TagRecorder
+	mu        sync.Mutex
+}
+
+// NewRecordingStage returns a pipeline stage that records every published
+// stream with a recorder from newRecorder, started on publish and stopped
+// when the stream is unpublished. Recorders are tracked in registry, if
+// set, so they can be finalized on shutdown. A stream whose recording fails
+// keeps publishing.
+func NewRecordingStage(newRecorder func(streamKey string) (TagRecorder, error), registry *storage.RecorderRegistry, log logger.Logger) PipelineStage {
+	return &recordingStage{
+		newRecorder: newRecorder,
+		registry:    registry,
+		logger:      log,
+		recorders:   make(map[string]TagRecorder),
+	}
+}
+
+// Name returns the stage name
+func (s *recordingStage) Name() string {
+	return "recording"
+}
+
+// Process starts, feeds and stops the recording of the event's stream
+func (s *recordingStage) Process(event *PipelineEvent) (*PipelineEvent, error) {
+	switch event.Type {
+	case PipelineEventPublish:
+		s.stop(event.StreamKey)
+		s.start(event.StreamKey)
+	case PipelineEventTag:
+		s.mu.Lock()
+		recorder := s.recorders[event.StreamKey]
+		s.mu.Unlock()
+
+		if recorder != nil {
+			if err := recorder.WriteTag(event.TagType, event.Timestamp, event.Payload); err != nil {
+				s.logger.Warn("Failed to record stream tag",
+					logger.Field{Key: "key", Value: event.StreamKey},
+					logger.Field{Key: "error", Value: err.Error()})
+			}
+		}
+	case PipelineEventUnpublish:
+		s.stop(event.StreamKey)
+	}
+
+	return event, nil
+}
+
+// start creates and starts the recorder of a stream
+func (s *recordingStage) start(streamKey string) {
+	recorder, err := s.newRecorder(streamKey)
+	if err == nil {
+		err = recorder.Start(context.Background())
+		if err != nil {
+			recorder.Close()
+		}
+	}
+	if err != nil {
+		s.logger.Error("Failed to start stream recording",
+			logger.Field{Key: "key", Value: streamKey},
+			logger.Field{Key: "error", Value: err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	s.recorders[streamKey] = recorder
+	s.mu.Unlock()
+
+	if s.registry != nil {
+		s.registry.Register(recorder)
+	}
+}
+
+// stop stops and closes the recorder of a stream, if it has one
+func (s *recordingStage) stop(streamKey string) {
+	s.mu.Lock()
+	recorder, exists := s.recorders[streamKey]
+	delete(s.recorders, streamKey)
+	s.mu.Unlock()
+
+	if !exists {
+		return
+	}
+
+	if s.registry != nil {
+		s.registry.Unregister(recorder.GetInfo().ID)
+	}
+
+	if err := recorder.Stop(context.Background()); err != nil {
+		s.logger.Error("Failed to stop stream recording",
+			logger.Field{Key: "key", Value: streamKey},
+			logger.Field{Key: "error", Value: err.Error()})
+	}
+	recorder.Close()
+}
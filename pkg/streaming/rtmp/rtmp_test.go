@@ -2,7 +2,13 @@ package rtmp
 
 import (
 	"bytes"
+	"errors"
+	"os"
 	"testing"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/aminofox/zenlive/pkg/storage"
+	"github.com/aminofox/zenlive/pkg/storage/formats"
 )
 
 func TestAMF0Encoding(t *testing.T) {
@@ -182,3 +188,122 @@ func TestConnectionState(t *testing.T) {
 		})
 	}
 }
+
+func TestPipeline(t *testing.T) {
+	var order []string
+	stage := func(name string, fn func(*PipelineEvent) (*PipelineEvent, error)) PipelineStage {
+		return StageFunc{StageName: name, Fn: func(event *PipelineEvent) (*PipelineEvent, error) {
+			order = append(order, name)
+			return fn(event)
+		}}
+	}
+
+	pipeline := NewPipeline(
+		stage("enrich", func(event *PipelineEvent) (*PipelineEvent, error) {
+			if event.Metadata == nil {
+				event.Metadata = make(map[string]interface{})
+			}
+			event.Metadata["region"] = "eu"
+			return event, nil
+		}),
+		stage("moderate", func(event *PipelineEvent) (*PipelineEvent, error) {
+			if event.StreamKey == "banned" {
+				return nil, errors.New("stream key is banned")
+			}
+			if event.Type == PipelineEventTag && len(event.Payload) == 0 {
+				return nil, nil
+			}
+			return event, nil
+		}),
+	)
+	pipeline.Use(stage("panics", func(event *PipelineEvent) (*PipelineEvent, error) {
+		if event.StreamKey == "faulty" {
+			panic("boom")
+		}
+		return event, nil
+	}))
+
+	event, err := pipeline.Run(&PipelineEvent{Type: PipelineEventPublish, StreamKey: "live"})
+	if err != nil || event.Metadata["region"] != "eu" {
+		t.Fatalf("Expected enriched publish event, got %+v, %v", event, err)
+	}
+	if len(order) != 3 || order[0] != "enrich" || order[1] != "moderate" || order[2] != "panics" {
+		t.Errorf("Expected stages to run in order, got %v", order)
+	}
+
+	var stageErr *StageError
+	if _, err := pipeline.Run(&PipelineEvent{Type: PipelineEventPublish, StreamKey: "banned"}); !errors.As(err, &stageErr) || stageErr.Stage != "moderate" {
+		t.Errorf("Expected rejection by moderate stage, got %v", err)
+	}
+
+	if event, err := pipeline.Run(&PipelineEvent{Type: PipelineEventTag, StreamKey: "live"}); event != nil || err != nil {
+		t.Errorf("Expected empty tag to be dropped, got %+v, %v", event, err)
+	}
+
+	if _, err := pipeline.Run(&PipelineEvent{Type: PipelineEventTag, StreamKey: "faulty", Payload: []byte{1}}); !errors.As(err, &stageErr) || stageErr.Stage != "panics" {
+		t.Errorf("Expected panic to be reported as stage error, got %v", err)
+	}
+
+	if !pipeline.Remove("panics") || len(pipeline.Stages()) != 2 {
+		t.Errorf("Expected stage to be removed, got %v", pipeline.Stages())
+	}
+}
+
+func TestRecordingStage(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "text")
+	registry := storage.NewRecorderRegistry(log)
+	outputPath := t.TempDir()
+
+	var recorder *formats.FLVRecorder
+	pipeline := NewPipeline(NewRecordingStage(func(streamKey string) (TagRecorder, error) {
+		config := storage.DefaultRecordingConfig()
+		config.Format = storage.FormatFLV
+		config.StreamID = streamKey
+		config.OutputPath = outputPath
+
+		var err error
+		recorder, err = formats.NewFLVRecorder(config, log)
+		return recorder, err
+	}, registry, log))
+
+	pipeline.Run(&PipelineEvent{Type: PipelineEventPublish, StreamKey: "live"})
+	if registry.Len() != 1 {
+		t.Fatalf("Expected the recording to be registered, got %d", registry.Len())
+	}
+
+	keyframe := []byte{0x17, 0x01, 0xAA}
+	audio := []byte{0xAF, 0x01}
+	pipeline.Run(&PipelineEvent{Type: PipelineEventTag, StreamKey: "live", TagType: MessageTypeVideo, Timestamp: 40, Payload: keyframe})
+	pipeline.Run(&PipelineEvent{Type: PipelineEventTag, StreamKey: "live", TagType: MessageTypeAudio, Timestamp: 42, Payload: audio})
+	pipeline.Run(&PipelineEvent{Type: PipelineEventUnpublish, StreamKey: "live"})
+
+	if registry.Len() != 0 {
+		t.Errorf("Expected the recording to be unregistered, got %d", registry.Len())
+	}
+	if state := recorder.GetInfo().State; state != storage.StateStopped {
+		t.Errorf("Expected recording to stop, got %s", state)
+	}
+	if index := recorder.GetSeekIndex(); len(index) != 1 || index[0].Offset != 13 {
+		t.Errorf("Expected the keyframe after the FLV header in the seek index, got %+v", index)
+	}
+
+	segments := recorder.GetSegments()
+	if len(segments) != 1 {
+		t.Fatalf("Expected one segment, got %d", len(segments))
+	}
+	data, err := os.ReadFile(segments[0].Path)
+	if err != nil {
+		t.Fatalf("Failed to read segment: %v", err)
+	}
+
+	want := []byte{'F', 'L', 'V', 0x01, 0x05, 0, 0, 0, 0x09, 0, 0, 0, 0}
+	want = append(want, MessageTypeVideo, 0, 0, 3, 0, 0, 40, 0, 0, 0, 0)
+	want = append(want, keyframe...)
+	want = append(want, 0, 0, 0, 14)
+	want = append(want, MessageTypeAudio, 0, 0, 2, 0, 0, 42, 0, 0, 0, 0)
+	want = append(want, audio...)
+	want = append(want, 0, 0, 0, 13)
+	if !bytes.Equal(data, want) {
+		t.Errorf("Expected FLV segment %x, got %x", want, data)
+	}
+}
@@ -23,6 +23,7 @@ type Server struct {
 	conns     map[net.Conn]*Connection
 	onPublish func(streamKey string, metadata map[string]interface{}) error
 	onPlay    func(streamKey string) error
+	pipeline  *Pipeline
 	running   bool
 }
 
@@ -41,10 +42,11 @@ type Connection struct {
 // NewServer creates a new RTMP server
 func NewServer(addr string, log logger.Logger) *Server {
 	return &Server{
-		addr:    addr,
-		logger:  log,
-		streams: make(map[string]*StreamInfo),
-		conns:   make(map[net.Conn]*Connection),
+		addr:     addr,
+		logger:   log,
+		streams:  make(map[string]*StreamInfo),
+		conns:    make(map[net.Conn]*Connection),
+		pipeline: NewPipeline(),
 	}
 }
 
@@ -53,6 +55,17 @@ func (s *Server) SetOnPublish(fn func(streamKey string, metadata map[string]inte
 	s.onPublish = fn
 }
 
+// Use appends stages to the publish pipeline, which runs on publish,
+// metadata and audio/video tag events in the order stages were added
+func (s *Server) Use(stages ...PipelineStage) {
+	s.pipeline.Use(stages...)
+}
+
+// Pipeline returns the publish pipeline
+func (s *Server) Pipeline() *Pipeline {
+	return s.pipeline
+}
+
 // SetOnPlay sets the callback for when a client starts playing
 func (s *Server) SetOnPlay(fn func(streamKey string) error) {
 	s.onPlay = fn
@@ -130,6 +143,10 @@ func (s *Server) handleConnection(netConn net.Conn) {
 		s.mu.Lock()
 		delete(s.conns, netConn)
 		s.mu.Unlock()
+
+		if conn.state == StatePublishing {
+			s.endPublish(conn)
+		}
 	}()
 
 	// Perform handshake
@@ -323,6 +340,25 @@ func (s *Server) handlePublish(conn *Connection, decoder *AMF0Decoder) error {
 	// Read publish type
 	publishType, _ := decoder.DecodeString()
 
+	// Run publish pipeline
+	event, err := s.pipeline.Run(&PipelineEvent{
+		Type:        PipelineEventPublish,
+		StreamKey:   streamKey,
+		PublishType: publishType,
+		Metadata:    conn.metadata,
+	})
+	if err == nil && event == nil {
+		err = ErrPublishDropped
+	}
+	if err != nil {
+		s.logger.Warn("Stream publish rejected",
+			logger.Field{Key: "key", Value: streamKey},
+			logger.Field{Key: "error", Value: err.Error()})
+		s.sendPublishRejected(conn, streamKey)
+		return err
+	}
+	conn.metadata = event.Metadata
+
 	conn.streamKey = streamKey
 	conn.publishMode = PublishMode(publishType)
 	conn.state = StatePublishing
@@ -380,6 +416,32 @@ func (s *Server) sendPublishStatus(conn *Connection, streamKey string) error {
 	return conn.writer.WriteMessage(msg)
 }
 
+func (s *Server) sendPublishRejected(conn *Connection, streamKey string) error {
+	buf := &bytes.Buffer{}
+	encoder := NewAMF0Encoder(buf)
+
+	encoder.EncodeString("onStatus")
+	encoder.EncodeNumber(0)
+	encoder.EncodeNull()
+
+	info := map[string]interface{}{
+		"level":       "error",
+		"code":        "NetStream.Publish.Rejected",
+		"description": fmt.Sprintf("Publishing %s rejected", streamKey),
+	}
+	encoder.EncodeObject(info)
+
+	msg := &Message{
+		ChunkStreamID:   ChunkStreamIDCommand,
+		Timestamp:       0,
+		MessageTypeID:   MessageTypeCommandAMF0,
+		MessageStreamID: conn.streamID,
+		Payload:         buf.Bytes(),
+	}
+
+	return conn.writer.WriteMessage(msg)
+}
+
 func (s *Server) handlePlay(conn *Connection, decoder *AMF0Decoder) error {
 	// Read transaction ID
 	_, _ = decoder.DecodeNumber()
@@ -430,30 +492,85 @@ func (s *Server) sendPlayStatus(conn *Connection, streamKey string) error {
 }
 
 func (s *Server) handleDeleteStream(conn *Connection, decoder *AMF0Decoder) error {
+	if conn.state == StatePublishing {
+		conn.state = StateConnected
+		s.endPublish(conn)
+		return nil
+	}
+
+	s.removeStream(conn.streamKey)
+	return nil
+}
+
+// endPublish removes a publisher's stream and runs the unpublish pipeline
+func (s *Server) endPublish(conn *Connection) {
+	s.removeStream(conn.streamKey)
+
+	if _, err := s.pipeline.Run(&PipelineEvent{
+		Type:      PipelineEventUnpublish,
+		StreamKey: conn.streamKey,
+	}); err != nil {
+		s.logger.Warn("Stream unpublish failed",
+			logger.Field{Key: "key", Value: conn.streamKey},
+			logger.Field{Key: "error", Value: err.Error()})
+	}
+}
+
+// removeStream stops listing a stream as published
+func (s *Server) removeStream(streamKey string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if info, exists := s.streams[conn.streamKey]; exists {
+	if info, exists := s.streams[streamKey]; exists {
 		info.IsPublishing = false
-		delete(s.streams, conn.streamKey)
+		delete(s.streams, streamKey)
 	}
 
-	s.logger.Info("Stream deleted", logger.Field{Key: "key", Value: conn.streamKey})
-	return nil
+	s.logger.Info("Stream deleted", logger.Field{Key: "key", Value: streamKey})
 }
 
 func (s *Server) handleAudioMessage(conn *Connection, msg *Message) error {
 	s.logger.Debug("Audio data received", logger.Field{Key: "size", Value: len(msg.Payload)})
+
+	event, err := s.runTagPipeline(conn, msg)
+	if err != nil || event == nil {
+		return err
+	}
 	// Audio data handling would go here (forwarding to players, recording, etc.)
 	return nil
 }
 
 func (s *Server) handleVideoMessage(conn *Connection, msg *Message) error {
 	s.logger.Debug("Video data received", logger.Field{Key: "size", Value: len(msg.Payload)})
+
+	event, err := s.runTagPipeline(conn, msg)
+	if err != nil || event == nil {
+		return err
+	}
 	// Video data handling would go here (forwarding to players, recording, etc.)
 	return nil
 }
 
+// runTagPipeline runs an audio or video message through the publish
+// pipeline. A nil event means a stage dropped the tag.
+func (s *Server) runTagPipeline(conn *Connection, msg *Message) (*PipelineEvent, error) {
+	event, err := s.pipeline.Run(&PipelineEvent{
+		Type:      PipelineEventTag,
+		StreamKey: conn.streamKey,
+		TagType:   msg.MessageTypeID,
+		Timestamp: msg.Timestamp,
+		Payload:   msg.Payload,
+	})
+	if err != nil {
+		s.logger.Warn("Stream tag rejected",
+			logger.Field{Key: "key", Value: conn.streamKey},
+			logger.Field{Key: "error", Value: err.Error()})
+		return nil, err
+	}
+
+	return event, nil
+}
+
 func (s *Server) handleDataMessage(conn *Connection, msg *Message) error {
 	decoder := NewAMF0Decoder(bytes.NewReader(msg.Payload))
 	dataType, _ := decoder.DecodeString()
@@ -464,14 +581,40 @@ func (s *Server) handleDataMessage(conn *Connection, msg *Message) error {
 		metadata, _ := decoder.Decode()
 
 		if meta, ok := metadata.(map[string]interface{}); ok {
-			conn.metadata = meta
-			s.logger.Info("Stream metadata received", logger.Field{Key: "metadata", Value: meta})
+			event, err := s.pipeline.Run(&PipelineEvent{
+				Type:      PipelineEventMetadata,
+				StreamKey: conn.streamKey,
+				Metadata:  meta,
+			})
+			if err != nil {
+				s.logger.Warn("Stream metadata rejected",
+					logger.Field{Key: "key", Value: conn.streamKey},
+					logger.Field{Key: "error", Value: err.Error()})
+				return err
+			}
+			if event == nil {
+				return nil
+			}
+
+			conn.metadata = event.Metadata
+			s.updateStreamMetadata(conn.streamKey, event.Metadata)
+			s.logger.Info("Stream metadata received", logger.Field{Key: "metadata", Value: event.Metadata})
 		}
 	}
 
 	return nil
 }
 
+// updateStreamMetadata records metadata sent after publishing started
+func (s *Server) updateStreamMetadata(streamKey string, metadata map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if info, exists := s.streams[streamKey]; exists {
+		info.Metadata = metadata
+	}
+}
+
 func (s *Server) handleSetChunkSize(conn *Connection, msg *Message) error {
 	if len(msg.Payload) < 4 {
 		return fmt.Errorf("invalid chunk size message")
@@ -0,0 +1,79 @@
+package streaming
+
+import (
+	"math"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/streaming/hls"
+)
+
+// TranscoderProfile is the encoder configuration of one rendition produced
+// by a transcoder, expanded from an hls.Preset
+type TranscoderProfile struct {
+	// Name is the preset name, also used as the variant name
+	Name string `json:"name"`
+	// Width and Height are the output resolution in pixels (0 = audio only)
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// FrameRate is the output frame rate
+	FrameRate float64 `json:"frame_rate"`
+	// VideoBitrate is the target video bitrate in bits per second
+	VideoBitrate int `json:"video_bitrate"`
+	// MaxVideoBitrate caps the video bitrate for rate control in bits per second
+	MaxVideoBitrate int `json:"max_video_bitrate"`
+	// AudioBitrate is the audio bitrate in bits per second
+	AudioBitrate int `json:"audio_bitrate"`
+	// VideoCodec and AudioCodec are RFC 6381 codec strings
+	VideoCodec string `json:"video_codec,omitempty"`
+	AudioCodec string `json:"audio_codec"`
+	// KeyframeInterval is the time between keyframes
+	KeyframeInterval time.Duration `json:"keyframe_interval"`
+	// GOPSize is the keyframe interval in frames
+	GOPSize int `json:"gop_size"`
+}
+
+// NewTranscoderProfile expands a preset into a transcoder profile. The
+// maximum video bitrate allows 10% overshoot, matching the peak bandwidth
+// advertised for the preset's variant.
+func NewTranscoderProfile(preset hls.Preset) TranscoderProfile {
+	profile := TranscoderProfile{
+		Name:             preset.Name,
+		Width:            preset.Width,
+		Height:           preset.Height,
+		FrameRate:        preset.FrameRate,
+		VideoBitrate:     preset.VideoBitrate,
+		MaxVideoBitrate:  preset.VideoBitrate * 11 / 10,
+		AudioBitrate:     preset.AudioBitrate,
+		AudioCodec:       preset.AudioCodec,
+		KeyframeInterval: preset.KeyframeInterval,
+	}
+
+	if !preset.IsAudioOnly() {
+		profile.VideoCodec = preset.VideoCodec
+		profile.GOPSize = int(math.Round(preset.KeyframeInterval.Seconds() * preset.FrameRate))
+	}
+
+	return profile
+}
+
+// TranscoderProfiles expands preset names into the transcoder profiles for
+// a source of the given resolution, in the same order and with the same
+// presets left out as hls.BuildLadderForSource, so the transcoder output
+// matches the advertised ladder
+func TranscoderProfiles(presetNames []string, sourceWidth, sourceHeight int) ([]TranscoderProfile, error) {
+	variants, err := hls.BuildLadderForSource(presetNames, sourceWidth, sourceHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make([]TranscoderProfile, 0, len(variants))
+	for _, variant := range variants {
+		preset, err := hls.GetPreset(variant.Name)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, NewTranscoderProfile(preset))
+	}
+
+	return profiles, nil
+}
@@ -0,0 +1,293 @@
+package streaming
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/streaming/hls"
+)
+
+// DefaultTranscriptionSampleRate is the sample rate audio is downsampled to
+// before transcription; 16 kHz mono is what most ASR engines expect
+const DefaultTranscriptionSampleRate = 16000
+
+// DefaultTranscriptionTimeout bounds a single transcription request
+const DefaultTranscriptionTimeout = 10 * time.Second
+
+// ErrTranscriptionFailed is returned when the ASR service rejects a request
+var ErrTranscriptionFailed = errors.New("transcription failed")
+
+// AudioChunk is a block of interleaved 16-bit PCM audio from a stream
+type AudioChunk struct {
+	StreamID   string
+	SampleRate int
+	Channels   int
+	Samples    []int16
+	Timestamp  time.Duration // media timestamp of the first sample
+}
+
+// Duration returns the length of the chunk
+func (c *AudioChunk) Duration() time.Duration {
+	if c.SampleRate <= 0 || c.Channels <= 0 {
+		return 0
+	}
+	frames := len(c.Samples) / c.Channels
+	return time.Duration(frames) * time.Second / time.Duration(c.SampleRate)
+}
+
+// TranscriptResult is timed text recognised in a stream's audio. Partial
+// results may be revised by later results; a final result is not.
+type TranscriptResult struct {
+	StreamID   string        `json:"stream_id"`
+	Text       string        `json:"text"`
+	Start      time.Duration `json:"start"` // media timestamp
+	End        time.Duration `json:"end"`   // media timestamp
+	Final      bool          `json:"final"`
+	Language   string        `json:"language,omitempty"`
+	Confidence float64       `json:"confidence,omitempty"` // 0.0 to 1.0
+}
+
+// TranscriptionSink turns stream audio into timed text, typically by calling
+// an automatic speech recognition (ASR) service. Results may cover audio
+// from earlier chunks, as engines often finalise a phrase after it ends.
+type TranscriptionSink interface {
+	Transcribe(ctx context.Context, chunk *AudioChunk) ([]TranscriptResult, error)
+}
+
+// HTTPTranscriptionSink forwards audio to an external ASR service. Each chunk
+// is POSTed as raw little-endian 16-bit PCM, with the stream and format in
+// query parameters, and the service responds with a JSON array of results
+// whose start and end are in milliseconds relative to the chunk.
+type HTTPTranscriptionSink struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+// NewHTTPTranscriptionSink creates a sink for the ASR service at url
+func NewHTTPTranscriptionSink(url, apiKey string) *HTTPTranscriptionSink {
+	return &HTTPTranscriptionSink{
+		URL:    url,
+		APIKey: apiKey,
+		Client: &http.Client{Timeout: DefaultTranscriptionTimeout},
+	}
+}
+
+// asrResult is a result as returned by the ASR service
+type asrResult struct {
+	Text       string  `json:"text"`
+	StartMs    int64   `json:"start_ms"`
+	EndMs      int64   `json:"end_ms"`
+	Final      bool    `json:"final"`
+	Language   string  `json:"language"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Transcribe sends a chunk to the ASR service
+func (s *HTTPTranscriptionSink) Transcribe(ctx context.Context, chunk *AudioChunk) ([]TranscriptResult, error) {
+	body := new(bytes.Buffer)
+	if err := binary.Write(body, binary.LittleEndian, chunk.Samples); err != nil {
+		return nil, fmt.Errorf("failed to encode audio: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	query := req.URL.Query()
+	query.Set("stream_id", chunk.StreamID)
+	query.Set("sample_rate", strconv.Itoa(chunk.SampleRate))
+	query.Set("channels", strconv.Itoa(chunk.Channels))
+	req.URL.RawQuery = query.Encode()
+
+	req.Header.Set("Content-Type", "audio/L16")
+	if s.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTranscriptionFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", ErrTranscriptionFailed, resp.StatusCode)
+	}
+
+	var raw []asrResult
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode transcription: %w", err)
+	}
+
+	results := make([]TranscriptResult, 0, len(raw))
+	for _, r := range raw {
+		results = append(results, TranscriptResult{
+			StreamID:   chunk.StreamID,
+			Text:       r.Text,
+			Start:      chunk.Timestamp + time.Duration(r.StartMs)*time.Millisecond,
+			End:        chunk.Timestamp + time.Duration(r.EndMs)*time.Millisecond,
+			Final:      r.Final,
+			Language:   r.Language,
+			Confidence: r.Confidence,
+		})
+	}
+
+	return results, nil
+}
+
+// Downsample mixes interleaved PCM down to mono and resamples it from
+// fromRate to toRate by averaging, which is adequate for speech
+func Downsample(samples []int16, channels, fromRate, toRate int) []int16 {
+	if channels <= 0 {
+		channels = 1
+	}
+
+	mono := samples
+	if channels > 1 {
+		mono = make([]int16, len(samples)/channels)
+		for i := range mono {
+			var sum int
+			for c := 0; c < channels; c++ {
+				sum += int(samples[i*channels+c])
+			}
+			mono[i] = int16(sum / channels)
+		}
+	}
+
+	if toRate <= 0 || fromRate <= toRate {
+		return mono
+	}
+
+	out := make([]int16, len(mono)*toRate/fromRate)
+	for i := range out {
+		start := i * fromRate / toRate
+		end := (i + 1) * fromRate / toRate
+		if end > len(mono) {
+			end = len(mono)
+		}
+
+		var sum int
+		for _, s := range mono[start:end] {
+			sum += int(s)
+		}
+		if end > start {
+			out[i] = int16(sum / (end - start))
+		}
+	}
+
+	return out
+}
+
+// Captioner feeds stream audio to a TranscriptionSink and distributes the
+// results: every result goes to the caption callback (e.g. for chat or a
+// live overlay, which can show partials and replace them as they firm up),
+// and final results become cues on the stream's HLS subtitle track.
+type Captioner struct {
+	sink       TranscriptionSink
+	sampleRate int
+	timeout    time.Duration
+	onCaption  func(result TranscriptResult)
+	tracks     map[string]*hls.SubtitleTrack
+	mu         sync.RWMutex
+}
+
+// NewCaptioner creates a captioner that downsamples audio to sampleRate
+// (DefaultTranscriptionSampleRate if zero) before transcription
+func NewCaptioner(sink TranscriptionSink, sampleRate int) *Captioner {
+	if sampleRate <= 0 {
+		sampleRate = DefaultTranscriptionSampleRate
+	}
+
+	return &Captioner{
+		sink:       sink,
+		sampleRate: sampleRate,
+		timeout:    DefaultTranscriptionTimeout,
+		tracks:     make(map[string]*hls.SubtitleTrack),
+	}
+}
+
+// OnCaption registers a callback for partial and final results
+func (c *Captioner) OnCaption(callback func(result TranscriptResult)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.onCaption = callback
+}
+
+// SetSubtitleTrack sets the HLS subtitle track that receives a stream's
+// final results. A nil track stops writing cues for the stream.
+func (c *Captioner) SetSubtitleTrack(streamID string, track *hls.SubtitleTrack) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if track == nil {
+		delete(c.tracks, streamID)
+		return
+	}
+	c.tracks[streamID] = track
+}
+
+// PushAudio downsamples a chunk, transcribes it and distributes the results.
+// It blocks for the transcription, so ingest paths should call it from their
+// own goroutine.
+func (c *Captioner) PushAudio(ctx context.Context, chunk *AudioChunk) ([]TranscriptResult, error) {
+	downsampled := &AudioChunk{
+		StreamID:   chunk.StreamID,
+		SampleRate: c.sampleRate,
+		Channels:   1,
+		Samples:    Downsample(chunk.Samples, chunk.Channels, chunk.SampleRate, c.sampleRate),
+		Timestamp:  chunk.Timestamp,
+	}
+	if chunk.SampleRate < c.sampleRate {
+		downsampled.SampleRate = chunk.SampleRate
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	results, err := c.sink.Transcribe(ctx, downsampled)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		if result.StreamID == "" {
+			result.StreamID = chunk.StreamID
+		}
+		c.handleResult(result)
+	}
+
+	return results, nil
+}
+
+// handleResult forwards a result to the caption callback and, if final, to
+// the stream's subtitle track
+func (c *Captioner) handleResult(result TranscriptResult) {
+	c.mu.RLock()
+	onCaption := c.onCaption
+	track := c.tracks[result.StreamID]
+	c.mu.RUnlock()
+
+	if result.Final && track != nil && result.Text != "" {
+		track.AddCue(hls.Cue{Start: result.Start, End: result.End, Text: result.Text})
+	}
+
+	if onCaption != nil {
+		onCaption(result)
+	}
+}
@@ -0,0 +1,134 @@
+package streaming
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/aminofox/zenlive/pkg/cluster"
+)
+
+// ErrConcurrentStreamLimit is returned when a user already watches as many
+// streams as their plan allows
+var ErrConcurrentStreamLimit = errors.New("concurrent stream limit reached")
+
+// viewingSessionType marks cluster sessions created for viewing
+const viewingSessionType = "viewing"
+
+// ViewingLimiter caps the number of streams a user watches at once. Viewing
+// sessions are kept in a cluster session manager so the count is global
+// across nodes; a session lapses after the manager's TTL unless it is
+// acquired again, so callers re-acquire while the viewer is active.
+type ViewingLimiter struct {
+	sessions     cluster.SessionManager
+	nodeID       string
+	defaultLimit int
+	limitFunc    func(ctx context.Context, userID string) int
+	onLimit      func(userID, streamID string, limit int)
+	mu           sync.Mutex
+}
+
+// NewViewingLimiter creates a limiter allowing defaultLimit concurrent
+// streams per user (0 = unlimited)
+func NewViewingLimiter(sessions cluster.SessionManager, nodeID string, defaultLimit int) *ViewingLimiter {
+	return &ViewingLimiter{
+		sessions:     sessions,
+		nodeID:       nodeID,
+		defaultLimit: defaultLimit,
+	}
+}
+
+// SetLimitFunc sets a per-user limit lookup, typically from the user's plan.
+// A negative result falls back to the default limit; 0 means unlimited.
+func (l *ViewingLimiter) SetLimitFunc(fn func(ctx context.Context, userID string) int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limitFunc = fn
+}
+
+// OnLimitReached registers a callback for rejected sessions, e.g. to prompt
+// the user to upgrade their plan
+func (l *ViewingLimiter) OnLimitReached(callback func(userID, streamID string, limit int)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.onLimit = callback
+}
+
+// limitFor returns the concurrent stream limit for a user
+func (l *ViewingLimiter) limitFor(ctx context.Context, userID string) int {
+	l.mu.Lock()
+	fn := l.limitFunc
+	l.mu.Unlock()
+
+	if fn != nil {
+		if limit := fn(ctx, userID); limit >= 0 {
+			return limit
+		}
+	}
+	return l.defaultLimit
+}
+
+// Acquire admits a viewing session of streamID by userID, or refreshes it if
+// it already exists. Further sessions on a stream the user already watches
+// are admitted, as the limit counts streams rather than devices. The check
+// is not atomic across nodes, so two simultaneous sessions may both pass.
+func (l *ViewingLimiter) Acquire(ctx context.Context, userID, streamID, sessionID string) error {
+	if existing, err := l.sessions.GetSession(ctx, sessionID); err == nil && existing != nil {
+		return l.sessions.RefreshSession(ctx, sessionID)
+	}
+
+	if limit := l.limitFor(ctx, userID); limit > 0 && userID != "" {
+		streams, err := l.viewingStreams(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		if !streams[streamID] && len(streams) >= limit {
+			l.mu.Lock()
+			onLimit := l.onLimit
+			l.mu.Unlock()
+
+			if onLimit != nil {
+				onLimit(userID, streamID, limit)
+			}
+			return ErrConcurrentStreamLimit
+		}
+	}
+
+	return l.sessions.CreateSession(ctx, &cluster.Session{
+		ID:       sessionID,
+		UserID:   userID,
+		StreamID: streamID,
+		NodeID:   l.nodeID,
+		Data:     map[string]interface{}{"type": viewingSessionType},
+	})
+}
+
+// Release ends a viewing session
+func (l *ViewingLimiter) Release(ctx context.Context, sessionID string) error {
+	return l.sessions.DeleteSession(ctx, sessionID)
+}
+
+// ActiveStreams returns the number of streams a user is watching
+func (l *ViewingLimiter) ActiveStreams(ctx context.Context, userID string) (int, error) {
+	streams, err := l.viewingStreams(ctx, userID)
+	return len(streams), err
+}
+
+// viewingStreams returns the set of streams in a user's viewing sessions
+func (l *ViewingLimiter) viewingStreams(ctx context.Context, userID string) (map[string]bool, error) {
+	active, err := l.sessions.GetUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	streams := make(map[string]bool)
+	for _, session := range active {
+		if session.Data["type"] == viewingSessionType {
+			streams[session.StreamID] = true
+		}
+	}
+	return streams, nil
+}
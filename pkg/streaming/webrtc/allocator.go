@@ -0,0 +1,214 @@
+package webrtc
+
+import (
+	"sort"
+	"sync"
+)
+
+// SubscriptionPriority ranks a subscriber's subscriptions when sharing out
+// its bandwidth, typically from the size of the video tile in the layout
+type SubscriptionPriority int
+
+const (
+	// PriorityThumbnail is a small tile; it never gets more than the base layer
+	PriorityThumbnail SubscriptionPriority = iota
+	// PriorityTile is a regular grid tile; it gets up to the second-highest layer
+	PriorityTile
+	// PrioritySpeaker is the active speaker or a pinned tile; it can get every layer
+	PrioritySpeaker
+)
+
+// TrackAllocation is the share of a subscriber's bandwidth given to one
+// subscription
+type TrackAllocation struct {
+	// StreamID is the subscribed stream
+	StreamID string `json:"stream_id"`
+
+	// Priority is the subscription's priority
+	Priority SubscriptionPriority `json:"priority"`
+
+	// Layer is the spatial layer forwarded (-1 when paused)
+	Layer int `json:"layer"`
+
+	// Bitrate is the bitrate allocated in bps
+	Bitrate int `json:"bitrate"`
+
+	// Paused is true when the base layer did not fit in the budget
+	Paused bool `json:"paused"`
+}
+
+// BitrateAllocator shares a subscriber's available bandwidth across its
+// subscriptions to several streams. Every subscription first gets its base
+// layer, in priority order, so no tile goes black while another is upgraded;
+// the remainder then upgrades the highest-priority subscriptions first, up
+// to the layer their priority allows. Only streams with SVC configured have
+// layers to choose from, so other streams are left out of the allocation.
+type BitrateAllocator struct {
+	sfu *SFU
+
+	// priorities maps subscriber ID -> stream ID -> priority
+	priorities map[string]map[string]SubscriptionPriority
+
+	// allocations holds the last allocation of each subscriber
+	allocations map[string][]TrackAllocation
+
+	mu sync.RWMutex
+}
+
+// newBitrateAllocator creates an allocator for the SFU's subscriptions
+func newBitrateAllocator(sfu *SFU) *BitrateAllocator {
+	return &BitrateAllocator{
+		sfu:         sfu,
+		priorities:  make(map[string]map[string]SubscriptionPriority),
+		allocations: make(map[string][]TrackAllocation),
+	}
+}
+
+// BitrateAllocator returns the SFU's bitrate allocator
+func (sfu *SFU) BitrateAllocator() *BitrateAllocator {
+	return sfu.allocator
+}
+
+// SetPriority sets the priority of a subscriber's subscription to a stream.
+// Subscriptions default to PriorityTile.
+func (a *BitrateAllocator) SetPriority(subscriberID, streamID string, priority SubscriptionPriority) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.priorities[subscriberID] == nil {
+		a.priorities[subscriberID] = make(map[string]SubscriptionPriority)
+	}
+	a.priorities[subscriberID][streamID] = priority
+}
+
+// allocationTrack is a subscription being allocated
+type allocationTrack struct {
+	subscriber *Subscriber
+	config     SVCConfig
+	allocation TrackAllocation
+	maxLayer   int
+}
+
+// Allocate shares availableBps across the subscriber's SVC subscriptions,
+// pins each subscription's layer accordingly and returns the allocation
+func (a *BitrateAllocator) Allocate(subscriberID string, availableBps int) []TrackAllocation {
+	tracks := a.collect(subscriberID)
+
+	// Highest priority first; stream ID keeps the order stable
+	sort.Slice(tracks, func(i, j int) bool {
+		if tracks[i].allocation.Priority != tracks[j].allocation.Priority {
+			return tracks[i].allocation.Priority > tracks[j].allocation.Priority
+		}
+		return tracks[i].allocation.StreamID < tracks[j].allocation.StreamID
+	})
+
+	remaining := availableBps
+
+	for _, track := range tracks {
+		base := track.config.SpatialBitrates[0]
+		if base > remaining {
+			continue
+		}
+		track.allocation.Layer = 0
+		track.allocation.Bitrate = base
+		track.allocation.Paused = false
+		remaining -= base
+	}
+
+	for _, track := range tracks {
+		if track.allocation.Paused {
+			continue
+		}
+		for layer := track.allocation.Layer + 1; layer <= track.maxLayer; layer++ {
+			step := track.config.SpatialBitrates[layer] - track.allocation.Bitrate
+			if step > remaining {
+				break
+			}
+			track.allocation.Layer = layer
+			track.allocation.Bitrate += step
+			remaining -= step
+		}
+	}
+
+	allocations := make([]TrackAllocation, 0, len(tracks))
+	for _, track := range tracks {
+		layer := SVCLayer{Spatial: track.allocation.Layer, Temporal: track.config.MaxLayer().Temporal}
+		track.subscriber.SetSVCLayer(&layer)
+		track.subscriber.setAllocatedBitrate(track.allocation.Bitrate)
+		allocations = append(allocations, track.allocation)
+	}
+
+	a.mu.Lock()
+	a.allocations[subscriberID] = allocations
+	a.mu.Unlock()
+
+	return allocations
+}
+
+// collect gathers the subscriber's subscriptions to streams with SVC.
+// Paused subscriptions get no share, leaving it to the displayed ones.
+func (a *BitrateAllocator) collect(subscriberID string) []*allocationTrack {
+	a.mu.RLock()
+	priorities := a.priorities[subscriberID]
+	a.mu.RUnlock()
+
+	var tracks []*allocationTrack
+	for _, stream := range a.sfu.GetStreams() {
+		stream.mu.RLock()
+		subscriber, subscribed := stream.Subscribers[subscriberID]
+		svc := stream.svc
+		stream.mu.RUnlock()
+
+		if !subscribed || svc == nil || len(svc.config.SpatialBitrates) == 0 || subscriber.IsPaused() {
+			continue
+		}
+
+		priority, ok := priorities[stream.ID]
+		if !ok {
+			priority = PriorityTile
+		}
+
+		top := len(svc.config.SpatialBitrates) - 1
+		maxLayer := top
+		switch priority {
+		case PriorityThumbnail:
+			maxLayer = 0
+		case PriorityTile:
+			maxLayer = max(top-1, 0)
+		}
+
+		tracks = append(tracks, &allocationTrack{
+			subscriber: subscriber,
+			config:     svc.config,
+			maxLayer:   maxLayer,
+			allocation: TrackAllocation{
+				StreamID: stream.ID,
+				Priority: priority,
+				Layer:    -1,
+				Paused:   true,
+			},
+		})
+	}
+
+	return tracks
+}
+
+// GetAllocation returns the last allocation made for a subscriber
+func (a *BitrateAllocator) GetAllocation(subscriberID string) []TrackAllocation {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	allocations := a.allocations[subscriberID]
+	result := make([]TrackAllocation, len(allocations))
+	copy(result, allocations)
+	return result
+}
+
+// RemoveSubscriber forgets a subscriber's priorities and allocation
+func (a *BitrateAllocator) RemoveSubscriber(subscriberID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.priorities, subscriberID)
+	delete(a.allocations, subscriberID)
+}
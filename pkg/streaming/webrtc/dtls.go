@@ -0,0 +1,151 @@
+package webrtc
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"strings"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// DTLSRole defines which side of the DTLS handshake a peer takes
+type DTLSRole string
+
+const (
+	// DTLSRoleAuto lets the role be negotiated from the remote SDP (actpass)
+	DTLSRoleAuto DTLSRole = "auto"
+
+	// DTLSRoleClient forces the local side to act as DTLS client (active)
+	DTLSRoleClient DTLSRole = "client"
+
+	// DTLSRoleServer forces the local side to act as DTLS server (passive)
+	DTLSRoleServer DTLSRole = "server"
+)
+
+// Validate checks that the DTLS role is a known value
+func (r DTLSRole) Validate() error {
+	switch r {
+	case "", DTLSRoleAuto, DTLSRoleClient, DTLSRoleServer:
+		return nil
+	default:
+		return ErrInvalidDTLSRole
+	}
+}
+
+// toPion converts the role to the Pion representation
+func (r DTLSRole) toPion() webrtc.DTLSRole {
+	switch r {
+	case DTLSRoleClient:
+		return webrtc.DTLSRoleClient
+	case DTLSRoleServer:
+		return webrtc.DTLSRoleServer
+	default:
+		return webrtc.DTLSRoleAuto
+	}
+}
+
+// newSettingEngine builds a Pion setting engine for the given DTLS role
+func newSettingEngine(role DTLSRole) (webrtc.SettingEngine, error) {
+	var se webrtc.SettingEngine
+
+	if role == "" || role == DTLSRoleAuto {
+		return se, nil
+	}
+
+	// Only the answering side can pick its role; offers are always actpass
+	if err := se.SetAnsweringDTLSRole(role.toPion()); err != nil {
+		return se, err
+	}
+
+	return se, nil
+}
+
+// SDPFingerprint is a certificate fingerprint announced in an SDP
+type SDPFingerprint struct {
+	// Algorithm is the hash function name (e.g. sha-256)
+	Algorithm string
+
+	// Value is the colon-separated hex digest
+	Value string
+}
+
+// ParseSDPFingerprints extracts all a=fingerprint attributes from an SDP
+func ParseSDPFingerprints(sdp string) []SDPFingerprint {
+	var fingerprints []SDPFingerprint
+
+	for _, line := range strings.Split(sdp, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "a=fingerprint:") {
+			continue
+		}
+
+		parts := strings.Fields(strings.TrimPrefix(line, "a=fingerprint:"))
+		if len(parts) != 2 {
+			continue
+		}
+
+		fingerprints = append(fingerprints, SDPFingerprint{
+			Algorithm: strings.ToLower(parts[0]),
+			Value:     strings.ToUpper(parts[1]),
+		})
+	}
+
+	return fingerprints
+}
+
+// VerifyCertificateFingerprint checks a DER certificate against the SDP fingerprints.
+// At least one fingerprint with a supported algorithm must match.
+func VerifyCertificateFingerprint(cert []byte, fingerprints []SDPFingerprint) error {
+	if len(cert) == 0 {
+		return ErrNoRemoteCertificate
+	}
+
+	if len(fingerprints) == 0 {
+		return ErrDTLSFingerprintMismatch
+	}
+
+	for _, fp := range fingerprints {
+		h := fingerprintHash(fp.Algorithm)
+		if h == nil {
+			continue
+		}
+
+		h.Write(cert)
+		if formatFingerprint(h.Sum(nil)) == fp.Value {
+			return nil
+		}
+	}
+
+	return ErrDTLSFingerprintMismatch
+}
+
+// fingerprintHash returns the hash for an SDP fingerprint algorithm
+func fingerprintHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case "sha-1":
+		return sha1.New()
+	case "sha-256":
+		return sha256.New()
+	case "sha-384":
+		return sha512.New384()
+	case "sha-512":
+		return sha512.New()
+	default:
+		return nil
+	}
+}
+
+// formatFingerprint formats a digest as uppercase colon-separated hex
+func formatFingerprint(digest []byte) string {
+	encoded := strings.ToUpper(hex.EncodeToString(digest))
+
+	parts := make([]string, 0, len(digest))
+	for i := 0; i < len(encoded); i += 2 {
+		parts = append(parts, encoded[i:i+2])
+	}
+
+	return strings.Join(parts, ":")
+}
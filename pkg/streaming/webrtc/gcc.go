@@ -0,0 +1,504 @@
+// Package webrtc provides Google Congestion Control (GCC) for subscriber-side forwarding.
+package webrtc
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// GCC tuning constants, following the values used by libwebrtc
+const (
+	// gccBurstInterval groups packets sent within this interval into one delay sample
+	gccBurstInterval = 5 * time.Millisecond
+
+	// gccTrendlineWindow is the number of delay samples used by the trendline filter
+	gccTrendlineWindow = 20
+
+	// gccSmoothingCoef smooths the accumulated delay before the trendline fit
+	gccSmoothingCoef = 0.9
+
+	// gccThresholdGain scales the trendline slope before comparing it to the threshold
+	gccThresholdGain = 4.0
+
+	// gccOveruseTime is how long the trend must exceed the threshold to signal overuse
+	gccOveruseTime = 10 * time.Millisecond
+
+	// gccAckedRateWindow is the window over which the acknowledged bitrate is measured
+	gccAckedRateWindow = 500 * time.Millisecond
+
+	// gccPacingFactor allows the pacer to send faster than the estimate to drain bursts
+	gccPacingFactor = 2.5
+
+	// gccPacerMaxBudget caps how much unused pacing budget can accumulate
+	gccPacerMaxBudget = 500 * time.Millisecond
+)
+
+// BandwidthUsage is the network state signalled by the overuse detector
+type BandwidthUsage string
+
+const (
+	BandwidthNormal     BandwidthUsage = "normal"
+	BandwidthUnderusing BandwidthUsage = "underusing"
+	BandwidthOverusing  BandwidthUsage = "overusing"
+)
+
+// CongestionStats reports the congestion controller state of a subscriber
+type CongestionStats struct {
+	// EstimatedBitrate is the available bandwidth estimate in bps
+	EstimatedBitrate int
+
+	// DelayBasedBitrate is the delay-based estimate in bps
+	DelayBasedBitrate int
+
+	// LossBasedBitrate is the loss-based estimate in bps
+	LossBasedBitrate int
+
+	// AckedBitrate is the bitrate acknowledged by the receiver in bps
+	AckedBitrate int
+
+	// LossRate is the last reported fraction of lost packets
+	LossRate float64
+
+	// Usage is the current overuse detector state
+	Usage BandwidthUsage
+
+	// PacketsPaced is the number of video packets dropped by the pacer
+	PacketsPaced uint64
+
+	// AllocatedBitrate is the bitrate the bitrate allocator gave this
+	// subscription in bps (0 if it has not been allocated)
+	AllocatedBitrate int
+
+	// Paused is true while video forwarding is paused by the client
+	Paused bool
+}
+
+// GCCEstimator implements Google Congestion Control: a delay-based estimator
+// driven by transport-wide feedback and a loss-based estimator driven by
+// receiver reports. The estimate is the minimum of the two.
+type GCCEstimator struct {
+	// config is the BWE configuration
+	config BWEConfig
+
+	// logger for logging
+	logger logger.Logger
+
+	// mu protects concurrent access
+	mu sync.Mutex
+
+	// Packet group being accumulated
+	groupSendTime    time.Time
+	groupArrivalTime time.Time
+	prevSendTime     time.Time
+	prevArrivalTime  time.Time
+	firstArrival     time.Time
+
+	// Trendline filter state
+	accumulatedDelay float64
+	smoothedDelay    float64
+	samples          []trendSample
+	numDeltas        int
+	trend            float64
+	prevTrend        float64
+
+	// Overuse detector state
+	threshold     float64
+	overuseStart  time.Time
+	lastThreshold time.Time
+	usage         BandwidthUsage
+
+	// Acknowledged bitrate measurement
+	acked []ackedPacket
+
+	// Rate controller state
+	delayBitrate  float64
+	lossBitrate   float64
+	lossRate      float64
+	lastIncrease  time.Time
+	lastLossCheck time.Time
+
+	// onEstimate is called when the estimate changes
+	onEstimate func(bitrate int)
+}
+
+// trendSample is one point of the trendline regression
+type trendSample struct {
+	arrivalMs float64
+	delayMs   float64
+}
+
+// ackedPacket is a packet reported as received
+type ackedPacket struct {
+	arrival time.Time
+	size    int
+}
+
+// NewGCCEstimator creates a new GCC estimator
+func NewGCCEstimator(config BWEConfig, log logger.Logger) *GCCEstimator {
+	return &GCCEstimator{
+		config:       config,
+		logger:       log,
+		threshold:    12.5,
+		usage:        BandwidthNormal,
+		delayBitrate: float64(config.StartBitrate),
+		lossBitrate:  float64(config.MaxBitrate),
+	}
+}
+
+// OnPacketFeedback processes a transport-wide feedback entry for one packet:
+// when it was sent, when the receiver got it, and its size in bytes
+func (g *GCCEstimator) OnPacketFeedback(sendTime, arrivalTime time.Time, size int) {
+	g.mu.Lock()
+	before := g.estimateLocked()
+
+	g.acked = append(g.acked, ackedPacket{arrival: arrivalTime, size: size})
+	g.pruneAcked(arrivalTime)
+
+	if g.groupSendTime.IsZero() {
+		g.groupSendTime = sendTime
+		g.groupArrivalTime = arrivalTime
+		g.firstArrival = arrivalTime
+		g.mu.Unlock()
+		return
+	}
+
+	// Packets sent in the same burst form one group
+	if sendTime.Sub(g.groupSendTime) < gccBurstInterval {
+		if arrivalTime.After(g.groupArrivalTime) {
+			g.groupArrivalTime = arrivalTime
+		}
+		g.mu.Unlock()
+		return
+	}
+
+	if !g.prevSendTime.IsZero() {
+		sendDelta := g.groupSendTime.Sub(g.prevSendTime)
+		arrivalDelta := g.groupArrivalTime.Sub(g.prevArrivalTime)
+		g.updateTrendline(arrivalDelta-sendDelta, g.groupArrivalTime)
+		g.detectOveruse(g.groupArrivalTime)
+		g.updateDelayBitrate(g.groupArrivalTime)
+	}
+
+	g.prevSendTime = g.groupSendTime
+	g.prevArrivalTime = g.groupArrivalTime
+	g.groupSendTime = sendTime
+	g.groupArrivalTime = arrivalTime
+
+	after := g.estimateLocked()
+	callback := g.onEstimate
+	g.mu.Unlock()
+
+	if callback != nil && after != before {
+		callback(after)
+	}
+}
+
+// OnLossReport processes the fraction of packets lost since the last
+// receiver report (0.0 - 1.0)
+func (g *GCCEstimator) OnLossReport(fractionLost float64) {
+	g.mu.Lock()
+	before := g.estimateLocked()
+	now := time.Now()
+
+	g.lossRate = fractionLost
+
+	// The loss-based controller adjusts the current estimate
+	current := float64(before)
+	switch {
+	case fractionLost < 0.02:
+		// Low loss: allow probing upwards, at most once per report interval
+		if g.lastLossCheck.IsZero() || now.Sub(g.lastLossCheck) >= 200*time.Millisecond {
+			g.lossBitrate = current * 1.05
+		}
+	case fractionLost > 0.1:
+		// Heavy loss: back off proportionally
+		g.lossBitrate = current * (1 - 0.5*fractionLost)
+	default:
+		g.lossBitrate = current
+	}
+	g.lastLossCheck = now
+	g.lossBitrate = g.clamp(g.lossBitrate)
+
+	after := g.estimateLocked()
+	callback := g.onEstimate
+	g.mu.Unlock()
+
+	if after != before {
+		g.logger.Debug("Loss-based estimate updated",
+			logger.Field{Key: "loss_rate", Value: fractionLost},
+			logger.Field{Key: "bitrate", Value: after},
+		)
+		if callback != nil {
+			callback(after)
+		}
+	}
+}
+
+// OnEstimate sets the callback invoked when the bandwidth estimate changes
+func (g *GCCEstimator) OnEstimate(callback func(bitrate int)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.onEstimate = callback
+}
+
+// GetEstimate returns the available bandwidth estimate in bps
+func (g *GCCEstimator) GetEstimate() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.estimateLocked()
+}
+
+// GetStats returns the estimator state
+func (g *GCCEstimator) GetStats() CongestionStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return CongestionStats{
+		EstimatedBitrate:  g.estimateLocked(),
+		DelayBasedBitrate: int(g.delayBitrate),
+		LossBasedBitrate:  int(g.lossBitrate),
+		AckedBitrate:      int(g.ackedBitrate()),
+		LossRate:          g.lossRate,
+		Usage:             g.usage,
+	}
+}
+
+// estimateLocked returns min(delay, loss). Caller must hold g.mu.
+func (g *GCCEstimator) estimateLocked() int {
+	return int(math.Min(g.delayBitrate, g.lossBitrate))
+}
+
+// updateTrendline adds a delay variation sample and refits the trendline.
+// Caller must hold g.mu.
+func (g *GCCEstimator) updateTrendline(delayDelta time.Duration, arrival time.Time) {
+	g.numDeltas++
+	if g.numDeltas > 60 {
+		g.numDeltas = 60
+	}
+
+	g.accumulatedDelay += float64(delayDelta) / float64(time.Millisecond)
+	g.smoothedDelay = gccSmoothingCoef*g.smoothedDelay + (1-gccSmoothingCoef)*g.accumulatedDelay
+
+	g.samples = append(g.samples, trendSample{
+		arrivalMs: float64(arrival.Sub(g.firstArrival)) / float64(time.Millisecond),
+		delayMs:   g.smoothedDelay,
+	})
+	if len(g.samples) > gccTrendlineWindow {
+		g.samples = g.samples[1:]
+	}
+
+	if len(g.samples) < gccTrendlineWindow {
+		return
+	}
+
+	// Least-squares slope of smoothed delay over arrival time
+	var sumX, sumY float64
+	for _, s := range g.samples {
+		sumX += s.arrivalMs
+		sumY += s.delayMs
+	}
+	meanX := sumX / float64(len(g.samples))
+	meanY := sumY / float64(len(g.samples))
+
+	var num, den float64
+	for _, s := range g.samples {
+		num += (s.arrivalMs - meanX) * (s.delayMs - meanY)
+		den += (s.arrivalMs - meanX) * (s.arrivalMs - meanX)
+	}
+
+	if den != 0 {
+		g.prevTrend = g.trend
+		g.trend = num / den
+	}
+}
+
+// detectOveruse compares the modified trend against an adaptive threshold.
+// Caller must hold g.mu.
+func (g *GCCEstimator) detectOveruse(now time.Time) {
+	modifiedTrend := float64(g.numDeltas) * g.trend * gccThresholdGain
+
+	switch {
+	case modifiedTrend > g.threshold:
+		if g.overuseStart.IsZero() {
+			g.overuseStart = now
+		}
+		if now.Sub(g.overuseStart) >= gccOveruseTime && g.trend >= g.prevTrend {
+			g.setUsage(BandwidthOverusing)
+		}
+	case modifiedTrend < -g.threshold:
+		g.overuseStart = time.Time{}
+		g.setUsage(BandwidthUnderusing)
+	default:
+		g.overuseStart = time.Time{}
+		g.setUsage(BandwidthNormal)
+	}
+
+	g.adaptThreshold(modifiedTrend, now)
+}
+
+// setUsage updates the detector state. Caller must hold g.mu.
+func (g *GCCEstimator) setUsage(usage BandwidthUsage) {
+	if g.usage != usage {
+		g.logger.Debug("Bandwidth usage changed",
+			logger.Field{Key: "from", Value: g.usage},
+			logger.Field{Key: "to", Value: usage},
+		)
+	}
+	g.usage = usage
+}
+
+// adaptThreshold moves the threshold towards the trend so the detector is
+// neither starved by competing TCP flows nor over-sensitive.
+// Caller must hold g.mu.
+func (g *GCCEstimator) adaptThreshold(modifiedTrend float64, now time.Time) {
+	if g.lastThreshold.IsZero() {
+		g.lastThreshold = now
+		return
+	}
+
+	absTrend := math.Abs(modifiedTrend)
+	if absTrend > g.threshold+15 {
+		g.lastThreshold = now
+		return
+	}
+
+	k := 0.039
+	if absTrend < g.threshold {
+		k = 0.0087
+	}
+
+	dt := math.Min(float64(now.Sub(g.lastThreshold))/float64(time.Millisecond), 100)
+	g.threshold += k * (absTrend - g.threshold) * dt
+	g.threshold = math.Max(6, math.Min(600, g.threshold))
+	g.lastThreshold = now
+}
+
+// updateDelayBitrate runs the AIMD rate controller. Caller must hold g.mu.
+func (g *GCCEstimator) updateDelayBitrate(now time.Time) {
+	acked := g.ackedBitrate()
+
+	switch g.usage {
+	case BandwidthOverusing:
+		// Multiplicative decrease relative to what actually got through
+		if acked > 0 {
+			g.delayBitrate = math.Min(g.delayBitrate, 0.85*acked)
+		} else {
+			g.delayBitrate *= 0.85
+		}
+		g.lastIncrease = now
+
+	case BandwidthNormal:
+		// Multiplicative increase of 8% per second, bounded by the acked rate
+		if g.lastIncrease.IsZero() {
+			g.lastIncrease = now
+		}
+		elapsed := math.Min(now.Sub(g.lastIncrease).Seconds(), 1)
+		g.delayBitrate *= math.Pow(1.08, elapsed)
+		if acked > 0 {
+			g.delayBitrate = math.Min(g.delayBitrate, 1.5*acked+10_000)
+		}
+		g.lastIncrease = now
+
+	case BandwidthUnderusing:
+		// Hold while queues drain
+		g.lastIncrease = now
+	}
+
+	g.delayBitrate = g.clamp(g.delayBitrate)
+}
+
+// ackedBitrate returns the received bitrate over the measurement window.
+// Caller must hold g.mu.
+func (g *GCCEstimator) ackedBitrate() float64 {
+	if len(g.acked) < 2 {
+		return 0
+	}
+
+	span := g.acked[len(g.acked)-1].arrival.Sub(g.acked[0].arrival)
+	if span <= 0 {
+		return 0
+	}
+
+	bytes := 0
+	for _, p := range g.acked[1:] {
+		bytes += p.size
+	}
+
+	return float64(bytes*8) / span.Seconds()
+}
+
+// pruneAcked drops packets outside the measurement window. Caller must hold g.mu.
+func (g *GCCEstimator) pruneAcked(now time.Time) {
+	i := 0
+	for i < len(g.acked) && now.Sub(g.acked[i].arrival) > gccAckedRateWindow {
+		i++
+	}
+	g.acked = g.acked[i:]
+}
+
+// clamp bounds a bitrate to the configured range
+func (g *GCCEstimator) clamp(bitrate float64) float64 {
+	return math.Max(float64(g.config.MinBitrate), math.Min(float64(g.config.MaxBitrate), bitrate))
+}
+
+// Pacer limits forwarding to a multiple of the bandwidth estimate using a
+// byte budget that refills over time
+type Pacer struct {
+	// mu protects concurrent access
+	mu sync.Mutex
+
+	// rate is the pacing rate in bps
+	rate float64
+
+	// budget is the number of bytes that may be sent now
+	budget float64
+
+	// lastRefill is the last budget refill time
+	lastRefill time.Time
+}
+
+// NewPacer creates a pacer for the given bandwidth estimate in bps
+func NewPacer(bitrate int) *Pacer {
+	p := &Pacer{lastRefill: time.Now()}
+	p.SetBitrate(bitrate)
+	p.budget = p.maxBudget()
+	return p
+}
+
+// SetBitrate updates the bandwidth estimate the pacer follows
+func (p *Pacer) SetBitrate(bitrate int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rate = float64(bitrate) * gccPacingFactor
+}
+
+// Allow reports whether size bytes may be sent now and consumes the budget if so
+func (p *Pacer) Allow(size int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	p.budget += now.Sub(p.lastRefill).Seconds() * p.rate / 8
+	if max := p.maxBudget(); p.budget > max {
+		p.budget = max
+	}
+	p.lastRefill = now
+
+	if p.budget < float64(size) {
+		return false
+	}
+
+	p.budget -= float64(size)
+	return true
+}
+
+// maxBudget is the most budget that may accumulate. Caller must hold p.mu.
+func (p *Pacer) maxBudget() float64 {
+	return p.rate / 8 * gccPacerMaxBudget.Seconds()
+}
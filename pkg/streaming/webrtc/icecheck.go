@@ -0,0 +1,336 @@
+package webrtc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/pion/webrtc/v3"
+)
+
+// DefaultICECheckInterval is how often an ICEMonitor rechecks its servers
+const DefaultICECheckInterval = 5 * time.Minute
+
+// ICE server kinds reported by connectivity checks
+const (
+	ICEServerSTUN = "stun"
+	ICEServerTURN = "turn"
+)
+
+// ICEServerResult is the reachability of one STUN or TURN server URL
+type ICEServerResult struct {
+	URL       string `json:"url"`
+	Kind      string `json:"kind"`
+	Reachable bool   `json:"reachable"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// ICECheckReport is the result of checking every configured ICE server
+type ICECheckReport struct {
+	Servers   []ICEServerResult `json:"servers"`
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+// TURNReachable reports whether relayed connections are possible: at least
+// one TURN server answered, or none is configured
+func (r *ICECheckReport) TURNReachable() bool {
+	configured := false
+	for _, server := range r.Servers {
+		if server.Kind == ICEServerTURN {
+			if server.Reachable {
+				return true
+			}
+			configured = true
+		}
+	}
+	return !configured
+}
+
+// CheckICEServers gathers candidates against each configured STUN and TURN
+// URL separately. A STUN server is reachable if it yields a server-reflexive
+// candidate and a TURN server if it yields a relay candidate, which also
+// proves its credentials. Each URL gets the config's ICE gathering timeout.
+func CheckICEServers(ctx context.Context, config Config) *ICECheckReport {
+	var servers []webrtc.ICEServer
+	for _, url := range config.STUNServers {
+		servers = append(servers, webrtc.ICEServer{URLs: []string{url}})
+	}
+	for _, turn := range config.TURNServers {
+		for _, url := range turn.URLs {
+			servers = append(servers, webrtc.ICEServer{
+				URLs:       []string{url},
+				Username:   turn.Username,
+				Credential: turn.Credential,
+			})
+		}
+	}
+
+	timeout := config.ICEGatheringTimeout
+	if timeout <= 0 {
+		timeout = DefaultICEGatheringTimeout
+	}
+
+	report := &ICECheckReport{
+		Servers:   make([]ICEServerResult, len(servers)),
+		CheckedAt: time.Now(),
+	}
+
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func(i int, server webrtc.ICEServer) {
+			defer wg.Done()
+			report.Servers[i] = checkICEServer(ctx, server, timeout)
+		}(i, server)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// checkICEServer gathers candidates against a single ICE server URL
+func checkICEServer(ctx context.Context, server webrtc.ICEServer, timeout time.Duration) (result ICEServerResult) {
+	url := server.URLs[0]
+	result = ICEServerResult{URL: url, Kind: ICEServerSTUN}
+	want := webrtc.ICECandidateTypeSrflx
+	policy := webrtc.ICETransportPolicyAll
+	if strings.HasPrefix(url, "turn:") || strings.HasPrefix(url, "turns:") {
+		result.Kind = ICEServerTURN
+		want = webrtc.ICECandidateTypeRelay
+		policy = webrtc.ICETransportPolicyRelay
+	}
+
+	start := time.Now()
+	defer func() {
+		result.LatencyMs = time.Since(start).Milliseconds()
+	}()
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{
+		ICEServers:         []webrtc.ICEServer{server},
+		ICETransportPolicy: policy,
+	})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	// Closing waits for pending STUN/TURN transactions to time out, which
+	// can take seconds for an unreachable server, so don't wait for it
+	defer func() {
+		go pc.Close()
+	}()
+
+	found := make(chan struct{})
+	var once sync.Once
+	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate != nil && candidate.Typ == want {
+			once.Do(func() { close(found) })
+		}
+	})
+
+	// A data channel gives the offer a transport to gather candidates for
+	if _, err := pc.CreateDataChannel("ice-check", nil); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	offer, err := pc.CreateOffer(nil)
+	if err == nil {
+		err = pc.SetLocalDescription(offer)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	select {
+	case <-found:
+		result.Reachable = true
+	case <-webrtc.GatheringCompletePromise(pc):
+		select {
+		case <-found:
+			result.Reachable = true
+		default:
+			result.Error = fmt.Sprintf("no %s candidate gathered", want)
+		}
+	case <-ctx.Done():
+		result.Error = fmt.Sprintf("no %s candidate gathered: %v", want, ctx.Err())
+	}
+
+	return result
+}
+
+// ICEMonitor periodically checks the configured ICE servers and alerts when
+// one stops answering, so NAT traversal failures surface before clients
+// report them
+type ICEMonitor struct {
+	config   Config
+	interval time.Duration
+	logger   logger.Logger
+
+	last    *ICECheckReport
+	onAlert func(result ICEServerResult)
+	cancel  context.CancelFunc
+	mu      sync.RWMutex
+}
+
+// NewICEMonitor creates a monitor checking the config's ICE servers every
+// interval (0 = DefaultICECheckInterval)
+func NewICEMonitor(config Config, interval time.Duration, log logger.Logger) *ICEMonitor {
+	if interval <= 0 {
+		interval = DefaultICECheckInterval
+	}
+
+	return &ICEMonitor{
+		config:   config,
+		interval: interval,
+		logger:   log,
+	}
+}
+
+// OnAlert registers a callback for servers that became unreachable. It is
+// called once per outage, not on every failed check.
+func (m *ICEMonitor) OnAlert(callback func(result ICEServerResult)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onAlert = callback
+}
+
+// Check runs a check now, records it as the latest report and alerts on
+// servers that were reachable (or unchecked) before and are not now
+func (m *ICEMonitor) Check(ctx context.Context) *ICECheckReport {
+	report := CheckICEServers(ctx, m.config)
+
+	m.mu.Lock()
+	previous := make(map[string]bool)
+	if m.last != nil {
+		for _, server := range m.last.Servers {
+			previous[server.URL] = server.Reachable
+		}
+	}
+	m.last = report
+	onAlert := m.onAlert
+	m.mu.Unlock()
+
+	for _, server := range report.Servers {
+		wasReachable, checked := previous[server.URL]
+		switch {
+		case server.Reachable && checked && !wasReachable:
+			m.logger.Info("ICE server reachable again",
+				logger.Field{Key: "url", Value: server.URL},
+			)
+		case !server.Reachable && (!checked || wasReachable):
+			m.logger.Warn("ICE server unreachable",
+				logger.Field{Key: "url", Value: server.URL},
+				logger.Field{Key: "kind", Value: server.Kind},
+				logger.Field{Key: "error", Value: server.Error},
+			)
+			if onAlert != nil {
+				onAlert(server)
+			}
+		}
+	}
+
+	return report
+}
+
+// Start rechecks every interval until Stop. Call Check first for an
+// immediate result, e.g. as a startup self-test.
+func (m *ICEMonitor) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m.mu.Lock()
+	if m.cancel != nil {
+		m.mu.Unlock()
+		cancel()
+		return
+	}
+	m.cancel = cancel
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.Check(ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops periodic checks
+func (m *ICEMonitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+}
+
+// Report returns the latest check, or nil before the first one
+func (m *ICEMonitor) Report() *ICECheckReport {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.last
+}
+
+// ICEServers returns the ICE servers to hand to clients. Servers found
+// unreachable by the latest check are left out so clients don't wait on
+// them; if every server failed, or nothing was checked yet, all configured
+// servers are returned.
+func (m *ICEMonitor) ICEServers() []webrtc.ICEServer {
+	all := CreateICEServers(m.config)
+
+	report := m.Report()
+	if report == nil {
+		return all
+	}
+
+	reachable := make(map[string]bool)
+	for _, server := range report.Servers {
+		if server.Reachable {
+			reachable[server.URL] = true
+		}
+	}
+	if len(reachable) == 0 {
+		return all
+	}
+
+	var servers []webrtc.ICEServer
+	for _, server := range all {
+		var urls []string
+		for _, url := range server.URLs {
+			if reachable[url] {
+				urls = append(urls, url)
+			}
+		}
+		if len(urls) > 0 {
+			server.URLs = urls
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+// CheckHealth reports an error when the latest check found every configured
+// TURN server unreachable
+func (m *ICEMonitor) CheckHealth(ctx context.Context) error {
+	if report := m.Report(); report != nil && !report.TURNReachable() {
+		return fmt.Errorf("no TURN server reachable")
+	}
+	return nil
+}
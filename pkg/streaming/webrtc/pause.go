@@ -0,0 +1,116 @@
+package webrtc
+
+import (
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/pion/rtcp"
+)
+
+// SetPaused stops or restarts video forwarding to the subscriber, e.g.
+// while the client is not displaying the tile. Audio keeps flowing. It
+// reports whether the state changed.
+func (s *Subscriber) SetPaused(paused bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused == paused {
+		return false
+	}
+	s.paused = paused
+	return true
+}
+
+// IsPaused returns whether video forwarding to the subscriber is paused
+func (s *Subscriber) IsPaused() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.paused
+}
+
+// RequestKeyframe asks the publisher for a keyframe with a Picture Loss
+// Indication on its video track
+func (p *Publisher) RequestKeyframe() error {
+	p.mu.RLock()
+	track := p.videoTrack
+	p.mu.RUnlock()
+
+	if track == nil {
+		return &WebRTCError{Code: "NO_VIDEO_TRACK", Message: "no video track available"}
+	}
+
+	peer, err := p.peerManager.GetPeer(p.id)
+	if err != nil {
+		return err
+	}
+
+	return peer.PC.WriteRTCP([]rtcp.Packet{
+		&rtcp.PictureLossIndication{MediaSSRC: uint32(track.SSRC())},
+	})
+}
+
+// PauseSubscription stops forwarding a stream's video to a subscriber that
+// is not displaying it. The subscription and its audio stay up, so
+// ResumeSubscription restarts video without renegotiation.
+func (sfu *SFU) PauseSubscription(streamID, subscriberID string) error {
+	_, subscriber, err := sfu.getSubscription(streamID, subscriberID)
+	if err != nil {
+		return err
+	}
+
+	if subscriber.SetPaused(true) {
+		sfu.logger.Debug("Paused subscription",
+			logger.Field{Key: "stream_id", Value: streamID},
+			logger.Field{Key: "subscriber_id", Value: subscriberID},
+		)
+	}
+
+	return nil
+}
+
+// ResumeSubscription restarts video forwarding to a paused subscriber and
+// requests a keyframe from the publisher so the subscriber can decode
+// immediately
+func (sfu *SFU) ResumeSubscription(streamID, subscriberID string) error {
+	stream, subscriber, err := sfu.getSubscription(streamID, subscriberID)
+	if err != nil {
+		return err
+	}
+
+	if !subscriber.SetPaused(false) {
+		return nil
+	}
+
+	sfu.logger.Debug("Resumed subscription",
+		logger.Field{Key: "stream_id", Value: streamID},
+		logger.Field{Key: "subscriber_id", Value: subscriberID},
+	)
+
+	if publisher := stream.GetPublisher(); publisher != nil {
+		if err := publisher.RequestKeyframe(); err != nil {
+			sfu.logger.Debug("Failed to request keyframe",
+				logger.Field{Key: "stream_id", Value: streamID},
+				logger.Field{Key: "error", Value: err.Error()},
+			)
+		}
+	}
+
+	return nil
+}
+
+// getSubscription returns a stream and one of its subscribers
+func (sfu *SFU) getSubscription(streamID, subscriberID string) (*SFUStream, *Subscriber, error) {
+	stream, err := sfu.GetStream(streamID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stream.mu.RLock()
+	subscriber, exists := stream.Subscribers[subscriberID]
+	stream.mu.RUnlock()
+
+	if !exists {
+		return nil, nil, ErrSubscriberNotFound
+	}
+
+	return stream, subscriber, nil
+}
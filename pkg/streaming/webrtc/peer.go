@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/stats"
 	"github.com/pion/webrtc/v3"
 )
 
@@ -39,6 +41,12 @@ type PeerManager struct {
 
 	// onICEConnectionStateChange is called when ICE connection state changes
 	onICEConnectionStateChange func(peerID string, state webrtc.ICEConnectionState)
+
+	// dtlsRoles stores per-peer DTLS role overrides by peer ID
+	dtlsRoles map[string]DTLSRole
+
+	// audioFEC registers the RED codec for new peers
+	audioFEC bool
 }
 
 // NewPeerManager creates a new peer manager
@@ -49,6 +57,7 @@ func NewPeerManager(config Config, log logger.Logger) *PeerManager {
 		peers:           make(map[string]*PeerConnection),
 		iceGatherer:     NewICEGatherer(config, log),
 		iceStateHandler: NewICEConnectionStateHandler(log),
+		dtlsRoles:       make(map[string]DTLSRole),
 	}
 }
 
@@ -67,6 +76,76 @@ func (pm *PeerManager) OnICEConnectionStateChange(callback func(peerID string, s
 	pm.onICEConnectionStateChange = callback
 }
 
+// SetPeerDTLSRole overrides the DTLS role for a peer.
+// It must be called before the peer is created to take effect.
+func (pm *PeerManager) SetPeerDTLSRole(peerID string, role DTLSRole) error {
+	if err := role.Validate(); err != nil {
+		return err
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.dtlsRoles[peerID] = role
+
+	return nil
+}
+
+// SetAudioFEC enables negotiation of RED redundant audio for new peers
+func (pm *PeerManager) SetAudioFEC(enabled bool) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.audioFEC = enabled
+}
+
+// newMediaEngine registers the default codecs and the MID/RID header
+// extensions simulcast publishers tag their layers with, plus RED when audio
+// FEC is enabled. Caller must hold pm.mu.
+func (pm *PeerManager) newMediaEngine() (*webrtc.MediaEngine, error) {
+	mediaEngine := &webrtc.MediaEngine{}
+	if err := mediaEngine.RegisterDefaultCodecs(); err != nil {
+		return nil, err
+	}
+	if err := webrtc.ConfigureSimulcastExtensionHeaders(mediaEngine); err != nil {
+		return nil, err
+	}
+
+	if pm.audioFEC {
+		err := mediaEngine.RegisterCodec(webrtc.RTPCodecParameters{
+			RTPCodecCapability: REDCodecCapability(),
+			PayloadType:        webrtc.PayloadType(REDPayloadType),
+		}, webrtc.RTPCodecTypeAudio)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return mediaEngine, nil
+}
+
+// newInterceptorRegistry registers the default interceptors that
+// webrtc.NewPeerConnection would (RTCP reports, TWCC and NACK), with the
+// NACK generator and responder only when EnableNACK is set. webrtc.NewAPI
+// adds no interceptors of its own. Caller must hold pm.mu.
+func (pm *PeerManager) newInterceptorRegistry(mediaEngine *webrtc.MediaEngine) (*interceptor.Registry, error) {
+	registry := &interceptor.Registry{}
+
+	if pm.config.EnableNACK {
+		if err := webrtc.ConfigureNack(mediaEngine, registry); err != nil {
+			return nil, err
+		}
+	}
+	if err := webrtc.ConfigureRTCPReports(registry); err != nil {
+		return nil, err
+	}
+	if err := webrtc.ConfigureTWCCSender(mediaEngine, registry); err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
+
 // CreatePeer creates a new peer connection
 func (pm *PeerManager) CreatePeer(ctx context.Context, peerID, streamID string, role PeerRole) (*PeerConnection, error) {
 	pm.mu.Lock()
@@ -82,8 +161,41 @@ func (pm *PeerManager) CreatePeer(ctx context.Context, peerID, streamID string,
 		ICEServers: CreateICEServers(pm.config),
 	}
 
+	// Resolve DTLS role, preferring the per-peer override
+	dtlsRole := pm.config.DTLSRole
+	if override, exists := pm.dtlsRoles[peerID]; exists {
+		dtlsRole = override
+	}
+	if dtlsRole == "" {
+		dtlsRole = DTLSRoleAuto
+	}
+
+	settingEngine, err := newSettingEngine(dtlsRole)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaEngine, err := pm.newMediaEngine()
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := pm.newInterceptorRegistry(mediaEngine)
+	if err != nil {
+		return nil, err
+	}
+	statsGetter, err := newStatsInterceptor(registry)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create peer connection
-	pc, err := webrtc.NewPeerConnection(webrtcConfig)
+	api := webrtc.NewAPI(
+		webrtc.WithSettingEngine(settingEngine),
+		webrtc.WithMediaEngine(mediaEngine),
+		webrtc.WithInterceptorRegistry(registry),
+	)
+	pc, err := api.NewPeerConnection(webrtcConfig)
 	if err != nil {
 		pm.logger.Error("Failed to create peer connection",
 			logger.Field{Key: "peer_id", Value: peerID},
@@ -102,6 +214,9 @@ func (pm *PeerManager) CreatePeer(ctx context.Context, peerID, streamID string,
 		ICECandidates: []webrtc.ICECandidateInit{},
 		CreatedAt:     time.Now(),
 		Stats:         &PeerStats{},
+		DTLSRole:      dtlsRole,
+		statsGetter:   statsGetter,
+		byteSamples:   make(map[uint32]byteSample),
 	}
 
 	// Set up event handlers
@@ -194,6 +309,51 @@ func (pm *PeerManager) setupPeerHandlers(peer *PeerConnection) {
 			logger.Field{Key: "state", Value: state.String()},
 		)
 	})
+
+	// DTLS state handler
+	peer.PC.SCTP().Transport().OnStateChange(func(state webrtc.DTLSTransportState) {
+		pm.logger.Debug("DTLS state changed",
+			logger.Field{Key: "peer_id", Value: peer.ID},
+			logger.Field{Key: "state", Value: state.String()},
+		)
+
+		if state == webrtc.DTLSTransportStateConnected && pm.config.VerifyDTLSFingerprint {
+			if err := pm.verifyRemoteFingerprint(peer); err != nil {
+				pm.logger.Error("DTLS fingerprint verification failed",
+					logger.Field{Key: "peer_id", Value: peer.ID},
+					logger.Field{Key: "error", Value: err.Error()},
+				)
+
+				pm.mu.Lock()
+				peer.State = PeerStateFailed
+				pm.mu.Unlock()
+
+				go peer.PC.Close()
+			}
+		}
+	})
+}
+
+// verifyRemoteFingerprint checks the established DTLS certificate against the remote SDP
+func (pm *PeerManager) verifyRemoteFingerprint(peer *PeerConnection) error {
+	remote := peer.PC.RemoteDescription()
+	if remote == nil {
+		return ErrInvalidSDP
+	}
+
+	cert := peer.PC.SCTP().Transport().GetRemoteCertificate()
+
+	return VerifyCertificateFingerprint(cert, ParseSDPFingerprints(remote.SDP))
+}
+
+// GetDTLSState returns the DTLS transport state for a peer
+func (pm *PeerManager) GetDTLSState(peerID string) (webrtc.DTLSTransportState, error) {
+	peer, err := pm.GetPeer(peerID)
+	if err != nil {
+		return 0, err
+	}
+
+	return peer.PC.SCTP().Transport().State(), nil
 }
 
 // GetPeer returns a peer connection by ID
@@ -219,6 +379,7 @@ func (pm *PeerManager) RemovePeer(peerID string) error {
 	}
 
 	delete(pm.peers, peerID)
+	delete(pm.dtlsRoles, peerID)
 	pm.mu.Unlock()
 
 	// Close peer connection
@@ -380,40 +541,56 @@ func (pm *PeerManager) AddTrack(peerID string, track *webrtc.TrackLocalStaticRTP
 	return sender, nil
 }
 
-// GetStats retrieves statistics for a peer connection
+// GetStats samples RTP statistics for a peer connection from its stats
+// interceptor. Bitrates are averaged since the previous call. The returned
+// stats are a copy; peer.Stats holds the latest sample.
 func (pm *PeerManager) GetStats(peerID string) (*PeerStats, error) {
 	peer, err := pm.GetPeer(peerID)
 	if err != nil {
 		return nil, err
 	}
 
-	stats := peer.PC.GetStats()
+	refs := peerTrackRefs(peer.PC)
+	rtt := candidatePairRTT(peer.PC)
+	now := time.Now()
+
+	var getter stats.Getter
+	if peer.statsGetter != nil {
+		getter = peer.statsGetter()
+	}
 
-	// Update peer stats
 	pm.mu.Lock()
-	peer.Stats.LastUpdated = time.Now()
-	pm.mu.Unlock()
+	defer pm.mu.Unlock()
+
+	tracks := make([]RTPTrackStats, 0, len(refs))
+	for _, ref := range refs {
+		if getter == nil {
+			break
+		}
+		s := getter.Get(ref.ssrc)
+		if s == nil {
+			continue
+		}
 
-	// Parse stats
-	for _, stat := range stats {
-		switch s := stat.(type) {
-		case *webrtc.InboundRTPStreamStats:
-			pm.mu.Lock()
-			peer.Stats.PacketsReceived = uint64(s.PacketsReceived)
-			peer.Stats.BytesReceived = uint64(s.BytesReceived)
-			peer.Stats.PacketsLost = uint64(s.PacketsLost)
-			peer.Stats.Jitter = s.Jitter
-			pm.mu.Unlock()
-
-		case *webrtc.OutboundRTPStreamStats:
-			pm.mu.Lock()
-			peer.Stats.PacketsSent = uint64(s.PacketsSent)
-			peer.Stats.BytesSent = uint64(s.BytesSent)
-			pm.mu.Unlock()
+		var prev *byteSample
+		if sample, ok := peer.byteSamples[ref.ssrc]; ok {
+			prev = &sample
 		}
+
+		ts := newRTPTrackStats(ref, s, prev, now)
+		tracks = append(tracks, ts)
+		peer.byteSamples[ref.ssrc] = byteSample{bytes: ts.BytesSent + ts.BytesReceived, at: now}
+	}
+
+	peerStats := aggregatePeerStats(tracks, now)
+	if peerStats.RTT == 0 {
+		peerStats.RTT = rtt
 	}
+	peer.Stats = peerStats
 
-	return peer.Stats, nil
+	snapshot := *peerStats
+	snapshot.Tracks = append([]RTPTrackStats(nil), peerStats.Tracks...)
+	return &snapshot, nil
 }
 
 // GetPeerCount returns the number of active peers
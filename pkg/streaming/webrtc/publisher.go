@@ -11,6 +11,28 @@ import (
 	"github.com/pion/webrtc/v3"
 )
 
+// MediaPublisher is the ingest side of a stream: it delivers the RTP packets
+// received from the publishing client. Publisher implements it over a peer
+// connection; webrtctest provides an in-memory implementation.
+type MediaPublisher interface {
+	// GetID returns the publisher identifier
+	GetID() string
+
+	// GetStreamID returns the stream identifier
+	GetStreamID() string
+
+	// OnVideoPacket sets the callback for video RTP packets
+	OnVideoPacket(callback func(*rtp.Packet))
+
+	// OnAudioPacket sets the callback for audio RTP packets
+	OnAudioPacket(callback func(*rtp.Packet))
+
+	// Stop stops delivering packets
+	Stop() error
+}
+
+var _ MediaPublisher = (*Publisher)(nil)
+
 // Publisher handles WebRTC stream publishing (ingestion)
 type Publisher struct {
 	// id is the publisher identifier
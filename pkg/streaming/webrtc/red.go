@@ -0,0 +1,186 @@
+// Package webrtc provides RFC 2198 audio redundancy (RED) encoding for lossy subscribers.
+package webrtc
+
+import (
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+)
+
+// Audio redundancy constants
+const (
+	// MimeTypeRED is the MIME type of RFC 2198 redundant audio
+	MimeTypeRED = "audio/red"
+
+	// REDPayloadType is the payload type negotiated for RED
+	REDPayloadType uint8 = 63
+
+	// OpusPayloadType is the payload type negotiated for Opus
+	OpusPayloadType uint8 = 111
+
+	// maxREDDistance is the largest number of redundant blocks per packet
+	maxREDDistance = 2
+
+	// maxREDTimestampOffset is the largest timestamp offset a RED header can carry
+	maxREDTimestampOffset = 1<<14 - 1
+
+	// maxREDBlockLength is the largest redundant block a RED header can carry
+	maxREDBlockLength = 1<<10 - 1
+)
+
+// REDBlock is one block of a RED payload
+type REDBlock struct {
+	// PayloadType is the payload type of the block
+	PayloadType uint8
+
+	// TimestampOffset is how far the block precedes the packet timestamp
+	TimestampOffset uint16
+
+	// Payload is the block data
+	Payload []byte
+}
+
+// REDCodecCapability returns the codec capability for RED carrying Opus
+func REDCodecCapability() webrtc.RTPCodecCapability {
+	return webrtc.RTPCodecCapability{
+		MimeType:    MimeTypeRED,
+		ClockRate:   48000,
+		Channels:    2,
+		SDPFmtpLine: "111/111",
+	}
+}
+
+// EncodeRED builds an RFC 2198 payload from redundant blocks, oldest first,
+// followed by the primary block
+func EncodeRED(redundant []REDBlock, primary REDBlock) []byte {
+	size := 1 + len(primary.Payload)
+	for _, block := range redundant {
+		size += 4 + len(block.Payload)
+	}
+
+	payload := make([]byte, 0, size)
+	for _, block := range redundant {
+		header := uint32(block.TimestampOffset)<<10 | uint32(len(block.Payload))
+		payload = append(payload,
+			0x80|block.PayloadType&0x7f,
+			byte(header>>16), byte(header>>8), byte(header),
+		)
+	}
+	payload = append(payload, primary.PayloadType&0x7f)
+
+	for _, block := range redundant {
+		payload = append(payload, block.Payload...)
+	}
+
+	return append(payload, primary.Payload...)
+}
+
+// DecodeRED splits an RFC 2198 payload into its blocks, oldest first. The
+// primary block is last.
+func DecodeRED(payload []byte) ([]REDBlock, error) {
+	var blocks []REDBlock
+	offset := 0
+
+	// Parse headers
+	for {
+		if offset >= len(payload) {
+			return nil, ErrInvalidREDPayload
+		}
+
+		if payload[offset]&0x80 == 0 {
+			blocks = append(blocks, REDBlock{PayloadType: payload[offset] & 0x7f})
+			offset++
+			break
+		}
+
+		if offset+4 > len(payload) {
+			return nil, ErrInvalidREDPayload
+		}
+
+		header := uint32(payload[offset+1])<<16 | uint32(payload[offset+2])<<8 | uint32(payload[offset+3])
+		blocks = append(blocks, REDBlock{
+			PayloadType:     payload[offset] & 0x7f,
+			TimestampOffset: uint16(header >> 10),
+			Payload:         make([]byte, header&0x3ff),
+		})
+		offset += 4
+	}
+
+	// Copy block data
+	for i := range blocks[:len(blocks)-1] {
+		length := len(blocks[i].Payload)
+		if offset+length > len(payload) {
+			return nil, ErrInvalidREDPayload
+		}
+		copy(blocks[i].Payload, payload[offset:offset+length])
+		offset += length
+	}
+	blocks[len(blocks)-1].Payload = append([]byte(nil), payload[offset:]...)
+
+	return blocks, nil
+}
+
+// REDEncoder wraps Opus packets in RED, adding earlier packets as redundancy
+type REDEncoder struct {
+	// history holds the most recent packets, oldest first
+	history []*rtp.Packet
+}
+
+// NewREDEncoder creates a new RED encoder
+func NewREDEncoder() *REDEncoder {
+	return &REDEncoder{}
+}
+
+// Encode returns a RED packet carrying packet as the primary block and up to
+// distance preceding packets as redundancy. A distance of 0 still produces a
+// RED packet so the negotiated codec stays consistent.
+func (e *REDEncoder) Encode(packet *rtp.Packet, distance int) *rtp.Packet {
+	if distance > maxREDDistance {
+		distance = maxREDDistance
+	}
+
+	redundant := make([]REDBlock, 0, distance)
+	start := len(e.history) - distance
+	if start < 0 {
+		start = 0
+	}
+	for _, previous := range e.history[start:] {
+		offset := packet.Timestamp - previous.Timestamp
+		if offset == 0 || offset > maxREDTimestampOffset || len(previous.Payload) > maxREDBlockLength {
+			continue
+		}
+		redundant = append(redundant, REDBlock{
+			PayloadType:     previous.PayloadType,
+			TimestampOffset: uint16(offset),
+			Payload:         previous.Payload,
+		})
+	}
+
+	// Remember this packet for the following ones
+	e.history = append(e.history, packet)
+	if len(e.history) > maxREDDistance {
+		e.history = e.history[1:]
+	}
+
+	header := packet.Header
+	header.PayloadType = REDPayloadType
+
+	return &rtp.Packet{
+		Header: header,
+		Payload: EncodeRED(redundant, REDBlock{
+			PayloadType: packet.PayloadType,
+			Payload:     packet.Payload,
+		}),
+	}
+}
+
+// redDistance picks how many redundant blocks to send for a loss rate
+func redDistance(lossRate, threshold float64) int {
+	switch {
+	case lossRate >= 2*threshold:
+		return 2
+	case lossRate >= threshold:
+		return 1
+	default:
+		return 0
+	}
+}
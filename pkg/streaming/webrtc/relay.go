@@ -0,0 +1,521 @@
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/auth"
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/gorilla/websocket"
+	"github.com/pion/rtp"
+)
+
+// Relay protocol constants
+const (
+	// RelayPath is the HTTP path of the relay endpoint served by RelayHandler
+	RelayPath = "/relay"
+
+	// HeaderRelayNode carries the node ID of the SFU requesting a relay
+	HeaderRelayNode = "X-Zenlive-Relay-Node"
+
+	// HeaderRelayPath carries the comma-separated node IDs a relayed stream has traversed
+	HeaderRelayPath = "X-Zenlive-Relay-Path"
+
+	// relaySinkBuffer is the number of packets buffered per downstream relay
+	relaySinkBuffer = 256
+
+	// relayInitialBackoff is the first delay before reconnecting to an origin
+	relayInitialBackoff = 250 * time.Millisecond
+
+	// relayMaxBackoff caps the reconnection delay
+	relayMaxBackoff = 30 * time.Second
+)
+
+// Packet kinds in the relay wire format; each binary WebSocket message is
+// one kind byte followed by a marshaled RTP packet
+const (
+	relayKindVideo byte = iota
+	relayKindAudio
+)
+
+// Relay subscribes to a stream on an origin SFU and re-forwards its packets
+// to local subscribers, reconnecting with backoff if the origin goes away
+type Relay struct {
+	// StreamID is the relayed stream
+	StreamID string
+
+	// OriginAddr is the origin SFU address
+	OriginAddr string
+
+	sfu        *SFU
+	connected  bool
+	reconnects int
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+	mu         sync.RWMutex
+}
+
+// relaySink is a downstream SFU receiving a stream from this SFU
+type relaySink struct {
+	packets chan []byte
+	closed  chan struct{}
+}
+
+// AddRelay relays a stream from an origin SFU so local viewers can subscribe
+// here. originAddr is a host:port or ws:// URL of the origin's RelayHandler.
+// The local stream is created if it does not exist.
+func (sfu *SFU) AddRelay(originAddr, streamID string) (*Relay, error) {
+	sfu.mu.Lock()
+	defer sfu.mu.Unlock()
+
+	if _, exists := sfu.relays[streamID]; exists {
+		return nil, ErrRelayExists
+	}
+
+	stream, exists := sfu.streams[streamID]
+	if !exists {
+		stream = &SFUStream{
+			ID:          streamID,
+			Name:        streamID,
+			Subscribers: make(map[string]*Subscriber),
+			relaySinks:  make(map[*relaySink]struct{}),
+			transcodes:  make(map[string]*transcodeSession),
+		}
+		sfu.streams[streamID] = stream
+	} else if stream.HasPublisher() {
+		return nil, ErrPublisherExists
+	}
+
+	ctx, cancel := context.WithCancel(sfu.ctx)
+	relay := &Relay{
+		StreamID:   streamID,
+		OriginAddr: originAddr,
+		sfu:        sfu,
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	sfu.relays[streamID] = relay
+
+	go relay.run()
+
+	sfu.logger.Info("Added relay",
+		logger.Field{Key: "stream_id", Value: streamID},
+		logger.Field{Key: "origin", Value: originAddr},
+	)
+
+	return relay, nil
+}
+
+// RemoveRelay stops relaying a stream. The local stream and its subscribers are kept.
+func (sfu *SFU) RemoveRelay(streamID string) error {
+	sfu.mu.Lock()
+	relay, exists := sfu.relays[streamID]
+	delete(sfu.relays, streamID)
+	sfu.mu.Unlock()
+
+	if !exists {
+		return ErrRelayNotFound
+	}
+
+	relay.Stop()
+
+	sfu.logger.Info("Removed relay",
+		logger.Field{Key: "stream_id", Value: streamID},
+	)
+
+	return nil
+}
+
+// GetRelay returns the relay for a stream
+func (sfu *SFU) GetRelay(streamID string) (*Relay, error) {
+	sfu.mu.RLock()
+	defer sfu.mu.RUnlock()
+
+	relay, exists := sfu.relays[streamID]
+	if !exists {
+		return nil, ErrRelayNotFound
+	}
+
+	return relay, nil
+}
+
+// hasRelay reports whether a stream is relayed from an origin
+func (sfu *SFU) hasRelay(streamID string) bool {
+	sfu.mu.RLock()
+	defer sfu.mu.RUnlock()
+
+	_, exists := sfu.relays[streamID]
+	return exists
+}
+
+// IsConnected returns whether the relay is currently connected to its origin
+func (r *Relay) IsConnected() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.connected
+}
+
+// Reconnects returns how many times the relay has reconnected to its origin
+func (r *Relay) Reconnects() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.reconnects
+}
+
+// Stop disconnects the relay and waits for it to exit
+func (r *Relay) Stop() {
+	r.cancel()
+	<-r.done
+}
+
+// run connects to the origin and reconnects with exponential backoff until stopped
+func (r *Relay) run() {
+	defer close(r.done)
+
+	backoff := relayInitialBackoff
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			r.mu.Lock()
+			r.reconnects++
+			r.mu.Unlock()
+		}
+
+		connected, err := r.connect()
+
+		r.mu.Lock()
+		r.connected = false
+		r.mu.Unlock()
+
+		if r.ctx.Err() != nil {
+			return
+		}
+
+		if connected {
+			backoff = relayInitialBackoff
+		}
+
+		r.sfu.logger.Warn("Relay disconnected from origin",
+			logger.Field{Key: "stream_id", Value: r.StreamID},
+			logger.Field{Key: "origin", Value: r.OriginAddr},
+			logger.Field{Key: "error", Value: err},
+			logger.Field{Key: "retry_in", Value: backoff},
+		)
+
+		select {
+		case <-time.After(backoff):
+		case <-r.ctx.Done():
+			return
+		}
+
+		backoff *= 2
+		if backoff > relayMaxBackoff {
+			backoff = relayMaxBackoff
+		}
+	}
+}
+
+// connect subscribes to the origin and forwards packets until the connection
+// fails. It reports whether the connection was established.
+func (r *Relay) connect() (bool, error) {
+	target := relayURL(r.OriginAddr, r.StreamID)
+	header := http.Header{}
+	header.Set(HeaderRelayNode, r.sfu.config.NodeID)
+	if err := signRelayRequest(header, r.sfu.config.RelaySecret, target); err != nil {
+		return false, err
+	}
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(r.ctx, target, header)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusLoopDetected {
+			return false, ErrRelayLoop
+		}
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return false, ErrRelayUnauthorized
+		}
+		return false, err
+	}
+	defer conn.Close()
+
+	// Refuse streams that already passed through this SFU
+	path := splitRelayPath(resp.Header.Get(HeaderRelayPath))
+	for _, node := range path {
+		if node == r.sfu.config.NodeID {
+			return false, ErrRelayLoop
+		}
+	}
+
+	stream, err := r.sfu.GetStream(r.StreamID)
+	if err != nil {
+		return false, err
+	}
+
+	stream.mu.Lock()
+	stream.relayPath = path
+	stream.mu.Unlock()
+
+	r.mu.Lock()
+	r.connected = true
+	r.mu.Unlock()
+
+	r.sfu.logger.Info("Relay connected to origin",
+		logger.Field{Key: "stream_id", Value: r.StreamID},
+		logger.Field{Key: "origin", Value: r.OriginAddr},
+		logger.Field{Key: "path", Value: path},
+	)
+
+	// Unblock ReadMessage when the relay is stopped
+	stop := context.AfterFunc(r.ctx, func() { conn.Close() })
+	defer stop()
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return true, err
+		}
+
+		if messageType != websocket.BinaryMessage || len(data) < 2 {
+			continue
+		}
+
+		packet := &rtp.Packet{}
+		if err := packet.Unmarshal(data[1:]); err != nil {
+			r.sfu.logger.Debug("Dropping malformed relay packet",
+				logger.Field{Key: "stream_id", Value: r.StreamID},
+				logger.Field{Key: "error", Value: err.Error()},
+			)
+			continue
+		}
+
+		switch data[0] {
+		case relayKindVideo:
+			r.sfu.forwardVideoPacket(r.StreamID, packet)
+		case relayKindAudio:
+			r.sfu.forwardAudioPacket(r.StreamID, packet)
+		}
+	}
+}
+
+// RelayHandler returns an HTTP handler that serves streams to edge SFUs.
+// Requests not signed with the RelaySecret are rejected with 401
+// Unauthorized. Requests whose node already appears in the stream's relay
+// path, or that would exceed MaxRelayHops, are rejected with 508 Loop
+// Detected.
+func (sfu *SFU) RelayHandler() http.Handler {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 64 * 1024,
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := sfu.verifyRelayRequest(r); err != nil {
+			sfu.logger.Warn("Rejected unauthorized relay request",
+				logger.Field{Key: "node", Value: r.Header.Get(HeaderRelayNode)},
+				logger.Field{Key: "remote", Value: r.RemoteAddr},
+				logger.Field{Key: "error", Value: err.Error()},
+			)
+			http.Error(w, ErrRelayUnauthorized.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		streamID := r.URL.Query().Get("stream")
+		node := r.Header.Get(HeaderRelayNode)
+		if streamID == "" || node == "" {
+			http.Error(w, "stream and relay node are required", http.StatusBadRequest)
+			return
+		}
+
+		stream, err := sfu.GetStream(streamID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		stream.mu.RLock()
+		path := append(append([]string{}, stream.relayPath...), sfu.config.NodeID)
+		stream.mu.RUnlock()
+
+		loop := sfu.config.MaxRelayHops > 0 && len(path) >= sfu.config.MaxRelayHops
+		for _, id := range path {
+			if id == node {
+				loop = true
+			}
+		}
+		if loop {
+			sfu.logger.Warn("Rejected relay request",
+				logger.Field{Key: "stream_id", Value: streamID},
+				logger.Field{Key: "node", Value: node},
+				logger.Field{Key: "path", Value: path},
+			)
+			http.Error(w, ErrRelayLoop.Error(), http.StatusLoopDetected)
+			return
+		}
+
+		responseHeader := http.Header{}
+		responseHeader.Set(HeaderRelayPath, strings.Join(path, ","))
+
+		conn, err := upgrader.Upgrade(w, r, responseHeader)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		sink := &relaySink{
+			packets: make(chan []byte, relaySinkBuffer),
+			closed:  make(chan struct{}),
+		}
+		stream.addRelaySink(sink)
+		defer stream.removeRelaySink(sink)
+
+		sfu.logger.Info("Relaying stream to edge",
+			logger.Field{Key: "stream_id", Value: streamID},
+			logger.Field{Key: "node", Value: node},
+		)
+
+		// Detect the edge disconnecting
+		gone := make(chan struct{})
+		go func() {
+			defer close(gone)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case data := <-sink.packets:
+				if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+					return
+				}
+			case <-sink.closed:
+				return
+			case <-gone:
+				return
+			case <-sfu.ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+// addRelaySink registers a downstream relay
+func (s *SFUStream) addRelaySink(sink *relaySink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.relaySinks[sink] = struct{}{}
+}
+
+// removeRelaySink unregisters a downstream relay
+func (s *SFUStream) removeRelaySink(sink *relaySink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.relaySinks, sink)
+}
+
+// closeRelaySinks disconnects all downstream relays. Caller must hold s.mu.
+func (s *SFUStream) closeRelaySinks() {
+	for sink := range s.relaySinks {
+		close(sink.closed)
+		delete(s.relaySinks, sink)
+	}
+}
+
+// relayPacket sends a packet to all downstream relays, dropping it for relays
+// that are falling behind. Caller must hold s.mu for reading.
+func (s *SFUStream) relayPacket(kind byte, packet *rtp.Packet) {
+	if len(s.relaySinks) == 0 {
+		return
+	}
+
+	payload, err := packet.Marshal()
+	if err != nil {
+		return
+	}
+	data := append([]byte{kind}, payload...)
+
+	for sink := range s.relaySinks {
+		select {
+		case sink.packets <- data:
+		default:
+		}
+	}
+}
+
+// GetRelayPath returns the SFU node IDs the stream traversed to reach this SFU
+func (s *SFUStream) GetRelayPath() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]string{}, s.relayPath...)
+}
+
+// relayURL builds the WebSocket URL of an origin's relay endpoint
+func relayURL(originAddr, streamID string) string {
+	base := originAddr
+	if !strings.Contains(base, "://") {
+		base = "ws://" + base
+	}
+	base = strings.TrimSuffix(base, "/")
+	if !strings.HasSuffix(base, RelayPath) {
+		base += RelayPath
+	}
+
+	return fmt.Sprintf("%s?stream=%s", base, url.QueryEscape(streamID))
+}
+
+// signRelayRequest signs a relay request to target with the relay secret,
+// using the API request signing scheme with the node ID as the body so the
+// signature can't be replayed by another node
+func signRelayRequest(header http.Header, secret, target string) error {
+	if secret == "" {
+		return ErrRelayUnauthorized
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	header.Set(auth.HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	header.Set(auth.HeaderSignature, auth.SignRequest(secret, http.MethodGet, u.RequestURI(), []byte(header.Get(HeaderRelayNode)), now))
+
+	return nil
+}
+
+// verifyRelayRequest checks that a relay request was signed with the relay
+// secret within the allowed clock skew
+func (sfu *SFU) verifyRelayRequest(r *http.Request) error {
+	if sfu.config.RelaySecret == "" {
+		return errors.New("relay secret not configured")
+	}
+
+	unix, err := strconv.ParseInt(r.Header.Get(auth.HeaderTimestamp), 10, 64)
+	if err != nil {
+		return errors.New("missing or invalid request timestamp")
+	}
+
+	return auth.VerifySignature(sfu.config.RelaySecret, r.Header.Get(auth.HeaderSignature), r.Method, r.URL.RequestURI(), []byte(r.Header.Get(HeaderRelayNode)), time.Unix(unix, 0), 0)
+}
+
+// splitRelayPath parses a relay path header
+func splitRelayPath(header string) []string {
+	if header == "" {
+		return nil
+	}
+	return strings.Split(header, ",")
+}
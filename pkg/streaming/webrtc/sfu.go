@@ -4,10 +4,14 @@ package webrtc
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
+	"github.com/aminofox/zenlive/pkg/flags"
 	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/google/uuid"
 	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
 )
 
 // SFU implements a Selective Forwarding Unit for WebRTC streaming
@@ -33,6 +37,22 @@ type SFU struct {
 	// bwe for bandwidth estimation
 	bwe *BandwidthEstimator
 
+	// relays stores active relays from origin SFUs by stream ID
+	relays map[string]*Relay
+
+	// flags gates features per stream and subscriber (nil = config only)
+	flags *flags.Flags
+
+	// allocator shares subscriber bandwidth across their subscriptions
+	allocator *BitrateAllocator
+
+	// transcoderFactory creates transcoders for codec-incompatible
+	// subscribers (nil = no transcoding)
+	transcoderFactory TranscoderFactory
+
+	// viewerLimiter caps concurrent viewing per subscriber (nil = unlimited)
+	viewerLimiter ViewerLimiter
+
 	// ctx for cancellation
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -55,6 +75,25 @@ type SFUStream struct {
 	// mu protects concurrent access
 	mu sync.RWMutex
 
+	// relaySinks are downstream SFUs relaying this stream
+	relaySinks map[*relaySink]struct{}
+
+	// relayPath lists the SFU node IDs the stream traversed to reach this SFU
+	relayPath []string
+
+	// svc is the stream's SVC encoding (nil = not scalable)
+	svc *streamSVC
+
+	// videoCodec is the video MIME type set for streams without a publisher track
+	videoCodec string
+
+	// encrypted marks end-to-end encrypted media, which is never transcoded
+	encrypted bool
+
+	// transcodes maps lowercased target MIME type to its shared transcode
+	transcodes  map[string]*transcodeSession
+	transcodeMu sync.Mutex
+
 	// createdAt is the creation timestamp
 	createdAt int64
 }
@@ -63,19 +102,51 @@ type SFUStream struct {
 func NewSFU(config SFUConfig, log logger.Logger) *SFU {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	if config.NodeID == "" {
+		config.NodeID = uuid.New().String()
+	}
+
 	peerManager := NewPeerManager(config.WebRTCConfig, log)
+	peerManager.SetAudioFEC(config.EnableAudioFEC)
 	trackManager := NewTrackManager(log)
 
-	return &SFU{
+	sfu := &SFU{
 		config:       config,
 		logger:       log,
 		streams:      make(map[string]*SFUStream),
 		peerManager:  peerManager,
 		trackManager: trackManager,
 		bwe:          NewBandwidthEstimator(DefaultBWEConfig(), log),
+		relays:       make(map[string]*Relay),
 		ctx:          ctx,
 		cancel:       cancel,
 	}
+	sfu.allocator = newBitrateAllocator(sfu)
+
+	return sfu
+}
+
+// SetFlags gates configured features behind feature flags, so they can be
+// rolled out per stream or subscriber
+func (sfu *SFU) SetFlags(f *flags.Flags) {
+	sfu.mu.Lock()
+	defer sfu.mu.Unlock()
+
+	sfu.flags = f
+}
+
+// featureEnabled reports whether a configured feature is flagged on for a
+// stream and subscriber. Without flags, the configuration alone decides.
+func (sfu *SFU) featureEnabled(flag, streamID, subscriberID string) bool {
+	sfu.mu.RLock()
+	f := sfu.flags
+	sfu.mu.RUnlock()
+
+	if f == nil {
+		return true
+	}
+
+	return f.Enabled(sfu.ctx, flag, flags.Context{UserID: subscriberID, StreamID: streamID})
 }
 
 // CreateStream creates a new stream in the SFU
@@ -91,6 +162,8 @@ func (sfu *SFU) CreateStream(streamID, name string) error {
 		ID:          streamID,
 		Name:        name,
 		Subscribers: make(map[string]*Subscriber),
+		relaySinks:  make(map[*relaySink]struct{}),
+		transcodes:  make(map[string]*transcodeSession),
 	}
 
 	sfu.streams[streamID] = stream
@@ -113,8 +186,15 @@ func (sfu *SFU) DeleteStream(streamID string) error {
 	}
 
 	delete(sfu.streams, streamID)
+	relay := sfu.relays[streamID]
+	delete(sfu.relays, streamID)
 	sfu.mu.Unlock()
 
+	// Stop relay from origin
+	if relay != nil {
+		relay.Stop()
+	}
+
 	// Stop publisher
 	if stream.Publisher != nil {
 		stream.Publisher.Stop()
@@ -124,7 +204,10 @@ func (sfu *SFU) DeleteStream(streamID string) error {
 	stream.mu.Lock()
 	for _, subscriber := range stream.Subscribers {
 		subscriber.Stop()
+		sfu.releaseViewer(streamID, subscriber.GetID())
 	}
+	stream.closeRelaySinks()
+	stream.closeTranscodes()
 	stream.mu.Unlock()
 
 	sfu.logger.Info("Deleted stream",
@@ -144,6 +227,10 @@ func (sfu *SFU) AddPublisher(ctx context.Context, streamID, publisherID string)
 		return nil, ErrStreamNotFound
 	}
 
+	if sfu.hasRelay(streamID) {
+		return nil, ErrRelayExists
+	}
+
 	stream.mu.Lock()
 	if stream.Publisher != nil {
 		stream.mu.Unlock()
@@ -208,6 +295,21 @@ func (sfu *SFU) RemovePublisher(streamID string) error {
 
 // AddSubscriber adds a subscriber to a stream
 func (sfu *SFU) AddSubscriber(ctx context.Context, streamID, subscriberID string) (*Subscriber, error) {
+	return sfu.AddSubscriberWithCodec(ctx, streamID, subscriberID, "")
+}
+
+// AddSubscriberWithCodec adds a subscriber that can only decode the given
+// video MIME type ("" for the default). If the stream is published in
+// another codec and a transcoder factory is set, the subscriber receives a
+// transcode shared with other subscribers needing the same codec.
+func (sfu *SFU) AddSubscriberWithCodec(ctx context.Context, streamID, subscriberID, videoCodec string) (*Subscriber, error) {
+	return sfu.AddUserSubscriber(ctx, streamID, subscriberID, "", videoCodec)
+}
+
+// AddUserSubscriber adds a subscriber watching for the authenticated
+// account userID ("" = anonymous), which the viewer limiter counts the
+// viewing session against
+func (sfu *SFU) AddUserSubscriber(ctx context.Context, streamID, subscriberID, userID, videoCodec string) (*Subscriber, error) {
 	sfu.mu.RLock()
 	stream, exists := sfu.streams[streamID]
 	sfu.mu.RUnlock()
@@ -225,11 +327,29 @@ func (sfu *SFU) AddSubscriber(ctx context.Context, streamID, subscriberID string
 		return nil, ErrMaxSubscribersReached
 	}
 
+	if err := sfu.acquireViewer(ctx, streamID, subscriberID, userID); err != nil {
+		return nil, err
+	}
+
 	// Create subscriber
 	subscriber := NewSubscriber(subscriberID, streamID, sfu.peerManager, sfu.trackManager, sfu.logger)
+	subscriber.userID = userID
+	if videoCodec != "" {
+		subscriber.SetVideoCodec(videoCodec)
+	}
+	if sfu.config.EnableAudioFEC && sfu.featureEnabled(flags.FlagAudioRED, streamID, subscriberID) {
+		subscriber.EnableAudioRED(sfu.config.AudioREDLossThreshold)
+	}
+	stream.mu.RLock()
+	svc := stream.svc
+	stream.mu.RUnlock()
+	if svc != nil {
+		subscriber.EnableSVC(svc.config)
+	}
 
 	// Start subscriber
 	if err := subscriber.Start(ctx); err != nil {
+		sfu.releaseViewer(streamID, subscriberID)
 		return nil, fmt.Errorf("failed to start subscriber: %w", err)
 	}
 
@@ -258,10 +378,12 @@ func (sfu *SFU) RemoveSubscriber(streamID, subscriberID string) error {
 	stream.mu.Lock()
 	subscriber, exists := stream.Subscribers[subscriberID]
 	delete(stream.Subscribers, subscriberID)
+	stream.releaseTranscodes()
 	stream.mu.Unlock()
 
 	if exists {
 		subscriber.Stop()
+		sfu.releaseViewer(streamID, subscriberID)
 		sfu.logger.Info("Removed subscriber",
 			logger.Field{Key: "stream_id", Value: streamID},
 			logger.Field{Key: "subscriber_id", Value: subscriberID},
@@ -284,7 +406,27 @@ func (sfu *SFU) forwardVideoPacket(streamID string, packet *rtp.Packet) {
 	stream.mu.RLock()
 	defer stream.mu.RUnlock()
 
+	if stream.svc != nil {
+		stream.svc.observe(packet)
+	}
+
+	factory := sfu.getTranscoderFactory()
+	source := stream.sourceVideoCodec()
+	var transcoded map[string][]*Subscriber
+
 	for _, subscriber := range stream.Subscribers {
+		// Subscribers that cannot decode the source codec share a transcode
+		needed := factory != nil && !stream.encrypted && needsTranscode(subscriber, source)
+		subscriber.setTranscoded(needed)
+		if needed {
+			if transcoded == nil {
+				transcoded = make(map[string][]*Subscriber)
+			}
+			codec := strings.ToLower(subscriber.GetVideoCodec())
+			transcoded[codec] = append(transcoded[codec], subscriber)
+			continue
+		}
+
 		// Forward packet to subscriber (ignore errors)
 		if err := subscriber.WriteVideoPacket(packet); err != nil {
 			sfu.logger.Debug("Failed to write video packet to subscriber",
@@ -293,6 +435,13 @@ func (sfu *SFU) forwardVideoPacket(streamID string, packet *rtp.Packet) {
 			)
 		}
 	}
+
+	if transcoded != nil {
+		sfu.forwardTranscoded(stream, factory, source, transcoded, packet)
+	}
+
+	// Forward to downstream relays
+	stream.relayPacket(relayKindVideo, packet)
 }
 
 // forwardAudioPacket forwards an audio RTP packet to all subscribers
@@ -317,6 +466,25 @@ func (sfu *SFU) forwardAudioPacket(streamID string, packet *rtp.Packet) {
 			)
 		}
 	}
+
+	// Forward to downstream relays
+	stream.relayPacket(relayKindAudio, packet)
+}
+
+// SetPeerDTLSRole sets the DTLS role for a publisher or subscriber before it is added
+func (sfu *SFU) SetPeerDTLSRole(peerID string, role DTLSRole) error {
+	return sfu.peerManager.SetPeerDTLSRole(peerID, role)
+}
+
+// GetDTLSState returns the DTLS transport state for a peer
+func (sfu *SFU) GetDTLSState(peerID string) (webrtc.DTLSTransportState, error) {
+	return sfu.peerManager.GetDTLSState(peerID)
+}
+
+// GetPeerStats samples RTP statistics for a publisher or subscriber peer
+// connection, the server-side equivalent of the browser's getStats()
+func (sfu *SFU) GetPeerStats(peerID string) (*PeerStats, error) {
+	return sfu.peerManager.GetStats(peerID)
 }
 
 // GetStream returns a stream by ID
@@ -369,6 +537,29 @@ func (sfu *SFU) GetSubscriberCount(streamID string) int {
 	return len(stream.Subscribers)
 }
 
+// GetCongestionStats returns the congestion controller state of each subscriber of a stream
+func (sfu *SFU) GetCongestionStats(streamID string) (map[string]CongestionStats, error) {
+	stream, err := sfu.GetStream(streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]CongestionStats)
+	for _, subscriber := range stream.GetSubscribers() {
+		stats[subscriber.GetID()] = subscriber.GetCongestionStats()
+	}
+
+	return stats, nil
+}
+
+// CheckHealth reports ErrSFUClosed once the SFU is closed
+func (sfu *SFU) CheckHealth(ctx context.Context) error {
+	if sfu.ctx.Err() != nil {
+		return ErrSFUClosed
+	}
+	return nil
+}
+
 // Close closes the SFU and all streams
 func (sfu *SFU) Close() error {
 	sfu.cancel()
@@ -0,0 +1,219 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/stats"
+	"github.com/pion/webrtc/v3"
+)
+
+// Track stats directions
+const (
+	// TrackDirectionInbound is a track received from the remote peer
+	TrackDirectionInbound = "inbound"
+	// TrackDirectionOutbound is a track sent to the remote peer
+	TrackDirectionOutbound = "outbound"
+)
+
+// RTPTrackStats holds the RTP statistics of one track of a peer connection,
+// the server-side equivalent of the browser's inbound-rtp and outbound-rtp
+// getStats() entries
+type RTPTrackStats struct {
+	// TrackID is the track identifier
+	TrackID string `json:"track_id"`
+
+	// Kind is the track kind (audio or video)
+	Kind string `json:"kind"`
+
+	// SSRC is the RTP synchronization source
+	SSRC uint32 `json:"ssrc"`
+
+	// Direction is TrackDirectionInbound or TrackDirectionOutbound
+	Direction string `json:"direction"`
+
+	// PacketsSent is the number of packets sent (outbound)
+	PacketsSent uint64 `json:"packets_sent,omitempty"`
+
+	// PacketsReceived is the number of packets received (inbound)
+	PacketsReceived uint64 `json:"packets_received,omitempty"`
+
+	// PacketsLost is the number of packets lost, as measured locally for
+	// inbound tracks and reported by the remote peer for outbound tracks
+	PacketsLost int64 `json:"packets_lost"`
+
+	// BytesSent is the number of payload bytes sent (outbound)
+	BytesSent uint64 `json:"bytes_sent,omitempty"`
+
+	// BytesReceived is the number of payload bytes received (inbound)
+	BytesReceived uint64 `json:"bytes_received,omitempty"`
+
+	// Jitter is the interarrival jitter in seconds
+	Jitter float64 `json:"jitter"`
+
+	// RTT is the round-trip time from RTCP receiver reports (outbound)
+	RTT time.Duration `json:"rtt,omitempty"`
+
+	// NACKCount is the number of NACKs sent (inbound) or received (outbound)
+	NACKCount uint32 `json:"nack_count"`
+
+	// PLICount is the number of PLIs sent (inbound) or received (outbound)
+	PLICount uint32 `json:"pli_count"`
+
+	// FIRCount is the number of FIRs sent (inbound) or received (outbound)
+	FIRCount uint32 `json:"fir_count"`
+
+	// Bitrate is the bitrate in bps since the previous sample
+	Bitrate int `json:"bitrate"`
+}
+
+// trackRef identifies a track to sample stats for
+type trackRef struct {
+	id        string
+	kind      string
+	ssrc      uint32
+	direction string
+}
+
+// byteSample is the byte count of a track at a point in time, used to
+// derive bitrate between samples
+type byteSample struct {
+	bytes uint64
+	at    time.Time
+}
+
+// newStatsInterceptor registers an RTP stats interceptor and returns a
+// function that reports the stats getter once the peer connection is built
+func newStatsInterceptor(registry *interceptor.Registry) (func() stats.Getter, error) {
+	factory, err := stats.NewInterceptor()
+	if err != nil {
+		return nil, err
+	}
+
+	var getter stats.Getter
+	factory.OnNewPeerConnection(func(_ string, g stats.Getter) {
+		getter = g
+	})
+	registry.Add(factory)
+
+	return func() stats.Getter { return getter }, nil
+}
+
+// peerTrackRefs lists the SSRCs a peer connection sends and receives
+func peerTrackRefs(pc *webrtc.PeerConnection) []trackRef {
+	var refs []trackRef
+
+	for _, receiver := range pc.GetReceivers() {
+		for _, track := range receiver.Tracks() {
+			refs = append(refs, trackRef{
+				id:        track.ID(),
+				kind:      track.Kind().String(),
+				ssrc:      uint32(track.SSRC()),
+				direction: TrackDirectionInbound,
+			})
+		}
+	}
+
+	for _, sender := range pc.GetSenders() {
+		track := sender.Track()
+		if track == nil {
+			continue
+		}
+		for _, encoding := range sender.GetParameters().Encodings {
+			refs = append(refs, trackRef{
+				id:        track.ID(),
+				kind:      track.Kind().String(),
+				ssrc:      uint32(encoding.SSRC),
+				direction: TrackDirectionOutbound,
+			})
+		}
+	}
+
+	return refs
+}
+
+// newRTPTrackStats converts interceptor stats for a track, deriving bitrate
+// from the previous byte sample if there is one
+func newRTPTrackStats(ref trackRef, s *stats.Stats, prev *byteSample, now time.Time) RTPTrackStats {
+	ts := RTPTrackStats{
+		TrackID:   ref.id,
+		Kind:      ref.kind,
+		SSRC:      ref.ssrc,
+		Direction: ref.direction,
+	}
+
+	var bytes uint64
+	if ref.direction == TrackDirectionInbound {
+		in := s.InboundRTPStreamStats
+		ts.PacketsReceived = in.PacketsReceived
+		ts.PacketsLost = in.PacketsLost
+		ts.BytesReceived = in.BytesReceived
+		ts.Jitter = in.Jitter
+		ts.NACKCount = in.NACKCount
+		ts.PLICount = in.PLICount
+		ts.FIRCount = in.FIRCount
+		bytes = in.BytesReceived
+	} else {
+		out := s.OutboundRTPStreamStats
+		remote := s.RemoteInboundRTPStreamStats
+		ts.PacketsSent = out.PacketsSent
+		ts.BytesSent = out.BytesSent
+		ts.PacketsLost = remote.PacketsLost
+		ts.Jitter = remote.Jitter
+		ts.RTT = remote.RoundTripTime
+		ts.NACKCount = out.NACKCount
+		ts.PLICount = out.PLICount
+		ts.FIRCount = out.FIRCount
+		bytes = out.BytesSent
+	}
+
+	if prev != nil && bytes >= prev.bytes {
+		if elapsed := now.Sub(prev.at); elapsed > 0 {
+			ts.Bitrate = int(float64(bytes-prev.bytes) * 8 / elapsed.Seconds())
+		}
+	}
+
+	return ts
+}
+
+// aggregatePeerStats sums per-track stats into peer totals. RTT and jitter
+// are the worst across tracks, since one bad track degrades the call.
+func aggregatePeerStats(tracks []RTPTrackStats, now time.Time) *PeerStats {
+	peerStats := &PeerStats{
+		Tracks:      tracks,
+		LastUpdated: now,
+	}
+
+	for _, ts := range tracks {
+		peerStats.PacketsSent += ts.PacketsSent
+		peerStats.PacketsReceived += ts.PacketsReceived
+		peerStats.BytesSent += ts.BytesSent
+		peerStats.BytesReceived += ts.BytesReceived
+		if ts.PacketsLost > 0 {
+			peerStats.PacketsLost += uint64(ts.PacketsLost)
+		}
+		peerStats.NACKCount += ts.NACKCount
+		peerStats.PLICount += ts.PLICount
+		peerStats.Bitrate += ts.Bitrate
+
+		if ts.Jitter > peerStats.Jitter {
+			peerStats.Jitter = ts.Jitter
+		}
+		if rtt := ts.RTT.Seconds(); rtt > peerStats.RTT {
+			peerStats.RTT = rtt
+		}
+	}
+
+	return peerStats
+}
+
+// candidatePairRTT returns the current RTT of the nominated ICE candidate
+// pair in seconds, or 0 if there is none
+func candidatePairRTT(pc *webrtc.PeerConnection) float64 {
+	for _, stat := range pc.GetStats() {
+		if pair, ok := stat.(webrtc.ICECandidatePairStats); ok && pair.Nominated {
+			return pair.CurrentRoundTripTime
+		}
+	}
+	return 0
+}
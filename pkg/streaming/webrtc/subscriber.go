@@ -5,12 +5,35 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/aminofox/zenlive/pkg/logger"
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
 )
 
+// MediaSubscriber is the playback side of a stream: it sends forwarded RTP
+// packets to the subscribing client. Subscriber implements it over a peer
+// connection; webrtctest provides an in-memory implementation.
+type MediaSubscriber interface {
+	// GetID returns the subscriber identifier
+	GetID() string
+
+	// GetStreamID returns the stream identifier
+	GetStreamID() string
+
+	// WriteVideoPacket writes a video RTP packet to the subscriber
+	WriteVideoPacket(packet *rtp.Packet) error
+
+	// WriteAudioPacket writes an audio RTP packet to the subscriber
+	WriteAudioPacket(packet *rtp.Packet) error
+
+	// Stop stops the subscriber
+	Stop() error
+}
+
+var _ MediaSubscriber = (*Subscriber)(nil)
+
 // Subscriber handles WebRTC stream subscription (playback)
 type Subscriber struct {
 	// id is the subscriber identifier
@@ -19,6 +42,9 @@ type Subscriber struct {
 	// streamID is the stream identifier
 	streamID string
 
+	// userID is the authenticated account watching ("" = anonymous)
+	userID string
+
 	// peerManager manages peer connections
 	peerManager *PeerManager
 
@@ -37,6 +63,39 @@ type Subscriber struct {
 	// audioTrack is the local audio track
 	audioTrack *webrtc.TrackLocalStaticRTP
 
+	// gcc estimates the bandwidth available towards this subscriber
+	gcc *GCCEstimator
+
+	// pacer limits video forwarding to the bandwidth estimate
+	pacer *Pacer
+
+	// packetsPaced counts video packets dropped by the pacer
+	packetsPaced uint64
+
+	// allocatedBitrate is the bitrate given by the SFU's bitrate allocator
+	allocatedBitrate int
+
+	// onBandwidthEstimate is called when the bandwidth estimate changes
+	onBandwidthEstimate func(bitrate int)
+
+	// red generates redundant audio when audio FEC is enabled
+	red *REDEncoder
+
+	// redLossThreshold is the loss rate at which redundancy is added
+	redLossThreshold float64
+
+	// videoCodec is the video MIME type the subscriber can decode
+	videoCodec string
+
+	// transcoded is true while the subscriber receives a transcode
+	transcoded bool
+
+	// svc drops SVC layers above the subscriber's target when SVC is enabled
+	svc *SVCSelector
+
+	// paused stops video forwarding while the client is not displaying it
+	paused bool
+
 	// onSubscribeStart is called when subscription starts
 	onSubscribeStart func()
 
@@ -55,15 +114,24 @@ type Subscriber struct {
 func NewSubscriber(id, streamID string, pm *PeerManager, tm *TrackManager, log logger.Logger) *Subscriber {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Subscriber{
+	bweConfig := DefaultBWEConfig()
+
+	s := &Subscriber{
 		id:           id,
 		streamID:     streamID,
 		peerManager:  pm,
 		trackManager: tm,
 		logger:       log,
+		gcc:          NewGCCEstimator(bweConfig, log),
+		pacer:        NewPacer(bweConfig.StartBitrate),
+		videoCodec:   webrtc.MimeTypeH264,
 		ctx:          ctx,
 		cancel:       cancel,
 	}
+
+	s.gcc.OnEstimate(s.handleBandwidthEstimate)
+
+	return s
 }
 
 // OnSubscribeStart sets the callback for subscribe start event
@@ -76,6 +144,94 @@ func (s *Subscriber) OnSubscribeStop(callback func()) {
 	s.onSubscribeStop = callback
 }
 
+// EnableAudioRED makes the subscriber receive RED audio, adding redundancy
+// once its loss rate reaches lossThreshold. It must be called before Start.
+func (s *Subscriber) EnableAudioRED(lossThreshold float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.red = NewREDEncoder()
+	s.redLossThreshold = lossThreshold
+}
+
+// SetVideoCodec sets the video MIME type the subscriber can decode. It must
+// be called before Start.
+func (s *Subscriber) SetVideoCodec(codec string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.videoCodec = codec
+}
+
+// GetVideoCodec returns the video MIME type the subscriber receives
+func (s *Subscriber) GetVideoCodec() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.videoCodec
+}
+
+// IsTranscoded reports whether the subscriber receives a transcode because
+// it cannot decode the stream's video codec
+func (s *Subscriber) IsTranscoded() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.transcoded
+}
+
+// setTranscoded records whether the subscriber receives a transcode
+func (s *Subscriber) setTranscoded(transcoded bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.transcoded = transcoded
+}
+
+// EnableSVC makes the subscriber receive only the SVC layers its bandwidth
+// estimate allows
+func (s *Subscriber) EnableSVC(config SVCConfig) {
+	selector := NewSVCSelector(config)
+	selector.OnBandwidthEstimate(s.gcc.GetEstimate())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.svc = selector
+}
+
+// SetSVCLayer pins the SVC layer forwarded to the subscriber. A nil layer
+// returns to selection by bandwidth estimate.
+func (s *Subscriber) SetSVCLayer(layer *SVCLayer) error {
+	s.mu.RLock()
+	selector := s.svc
+	s.mu.RUnlock()
+
+	if selector == nil {
+		return ErrSVCNotConfigured
+	}
+
+	if layer == nil {
+		selector.ClearTarget()
+		selector.OnBandwidthEstimate(s.gcc.GetEstimate())
+	} else {
+		selector.SetTarget(*layer)
+	}
+
+	return nil
+}
+
+// GetSVCLayer returns the SVC layer currently forwarded to the subscriber
+// and whether SVC is enabled
+func (s *Subscriber) GetSVCLayer() (SVCLayer, bool) {
+	s.mu.RLock()
+	selector := s.svc
+	s.mu.RUnlock()
+
+	if selector == nil {
+		return SVCLayer{}, false
+	}
+	return selector.Current(), true
+}
+
 // Start starts the subscriber and creates tracks
 func (s *Subscriber) Start(ctx context.Context) error {
 	s.mu.Lock()
@@ -169,7 +325,7 @@ func (s *Subscriber) createTracks() error {
 	// Create video track
 	videoTrack, err := s.trackManager.CreateLocalTrack(
 		webrtc.RTPCodecCapability{
-			MimeType:  webrtc.MimeTypeH264,
+			MimeType:  s.GetVideoCodec(),
 			ClockRate: 90000,
 		},
 		"video",
@@ -183,14 +339,22 @@ func (s *Subscriber) createTracks() error {
 	s.videoTrack = videoTrack
 	s.mu.Unlock()
 
-	// Create audio track
+	// Create audio track, carrying RED when audio FEC is enabled
+	audioCodec := webrtc.RTPCodecCapability{
+		MimeType:    webrtc.MimeTypeOpus,
+		ClockRate:   48000,
+		Channels:    2,
+		SDPFmtpLine: "minptime=10;useinbandfec=1",
+	}
+
+	s.mu.RLock()
+	if s.red != nil {
+		audioCodec = REDCodecCapability()
+	}
+	s.mu.RUnlock()
+
 	audioTrack, err := s.trackManager.CreateLocalTrack(
-		webrtc.RTPCodecCapability{
-			MimeType:    webrtc.MimeTypeOpus,
-			ClockRate:   48000,
-			Channels:    2,
-			SDPFmtpLine: "minptime=10;useinbandfec=1",
-		},
+		audioCodec,
 		"audio",
 		s.streamID,
 	)
@@ -205,20 +369,45 @@ func (s *Subscriber) createTracks() error {
 	return nil
 }
 
-// WriteVideoPacket writes a video RTP packet to the subscriber
+// WriteVideoPacket writes a video RTP packet to the subscriber. With SVC
+// enabled, packets of layers above the subscriber's target are dropped.
+// Packets exceeding the pacing budget for the bandwidth estimate are dropped.
+// Nothing is written while the subscriber is paused.
 func (s *Subscriber) WriteVideoPacket(packet *rtp.Packet) error {
 	s.mu.RLock()
 	track := s.videoTrack
+	selector := s.svc
+	paused := s.paused
 	s.mu.RUnlock()
 
+	if paused {
+		return nil
+	}
+
 	if track == nil {
 		return &WebRTCError{Code: "NO_VIDEO_TRACK", Message: "no video track available"}
 	}
 
+	if selector != nil {
+		var forward bool
+		if packet, forward = selector.Filter(packet); !forward {
+			return nil
+		}
+	}
+
+	if !s.pacer.Allow(packet.MarshalSize()) {
+		s.mu.Lock()
+		s.packetsPaced++
+		s.mu.Unlock()
+		return ErrPacingBudgetExceeded
+	}
+
 	return WriteRTPToTrack(track, packet)
 }
 
-// WriteAudioPacket writes an audio RTP packet to the subscriber
+// WriteAudioPacket writes an audio RTP packet to the subscriber. With RED
+// enabled, RED packets from the publisher pass through and Opus packets are
+// wrapped in RED with redundancy chosen from the subscriber's loss rate.
 func (s *Subscriber) WriteAudioPacket(packet *rtp.Packet) error {
 	s.mu.RLock()
 	track := s.audioTrack
@@ -228,9 +417,90 @@ func (s *Subscriber) WriteAudioPacket(packet *rtp.Packet) error {
 		return &WebRTCError{Code: "NO_AUDIO_TRACK", Message: "no audio track available"}
 	}
 
+	packet = s.encodeRED(packet)
+
 	return WriteRTPToTrack(track, packet)
 }
 
+// encodeRED wraps an Opus packet in RED for subscribers with RED enabled
+func (s *Subscriber) encodeRED(packet *rtp.Packet) *rtp.Packet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.red == nil || packet.PayloadType == REDPayloadType {
+		return packet
+	}
+
+	distance := redDistance(s.gcc.GetStats().LossRate, s.redLossThreshold)
+	return s.red.Encode(packet, distance)
+}
+
+// OnTransportFeedback feeds a transport-wide congestion control report entry
+// for one forwarded packet into the bandwidth estimator
+func (s *Subscriber) OnTransportFeedback(sendTime, arrivalTime time.Time, size int) {
+	s.gcc.OnPacketFeedback(sendTime, arrivalTime, size)
+}
+
+// OnReceiverReport feeds the fraction of packets lost from an RTCP receiver
+// report into the bandwidth estimator
+func (s *Subscriber) OnReceiverReport(fractionLost float64) {
+	s.gcc.OnLossReport(fractionLost)
+}
+
+// OnBandwidthEstimate sets the callback for bandwidth estimate changes, used
+// to drive simulcast layer selection
+func (s *Subscriber) OnBandwidthEstimate(callback func(bitrate int)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onBandwidthEstimate = callback
+}
+
+// GetBandwidthEstimate returns the estimated available bandwidth in bps
+func (s *Subscriber) GetBandwidthEstimate() int {
+	return s.gcc.GetEstimate()
+}
+
+// GetCongestionStats returns the congestion controller state
+func (s *Subscriber) GetCongestionStats() CongestionStats {
+	stats := s.gcc.GetStats()
+
+	s.mu.RLock()
+	stats.PacketsPaced = s.packetsPaced
+	stats.AllocatedBitrate = s.allocatedBitrate
+	stats.Paused = s.paused
+	s.mu.RUnlock()
+
+	return stats
+}
+
+// setAllocatedBitrate records the bitrate given by the bitrate allocator
+func (s *Subscriber) setAllocatedBitrate(bitrate int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.allocatedBitrate = bitrate
+}
+
+// handleBandwidthEstimate updates the pacer and SVC layer and notifies the
+// estimate callback
+func (s *Subscriber) handleBandwidthEstimate(bitrate int) {
+	s.pacer.SetBitrate(bitrate)
+
+	s.mu.RLock()
+	callback := s.onBandwidthEstimate
+	selector := s.svc
+	s.mu.RUnlock()
+
+	if selector != nil {
+		selector.OnBandwidthEstimate(bitrate)
+	}
+
+	if callback != nil {
+		callback(bitrate)
+	}
+}
+
 // GetVideoTrack returns the video track
 func (s *Subscriber) GetVideoTrack() *webrtc.TrackLocalStaticRTP {
 	s.mu.RLock()
@@ -260,6 +530,12 @@ func (s *Subscriber) GetID() string {
 	return s.id
 }
 
+// GetUserID returns the account watching through the subscriber, or "" if
+// it is anonymous
+func (s *Subscriber) GetUserID() string {
+	return s.userID
+}
+
 // GetStreamID returns the stream ID
 func (s *Subscriber) GetStreamID() string {
 	return s.streamID
@@ -0,0 +1,437 @@
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/pion/rtp"
+)
+
+// SVC codecs whose RTP payloads carry layer information
+const (
+	// SVCCodecVP9 is VP9 with the RTP payload descriptor layer indices
+	SVCCodecVP9 = "vp9"
+	// SVCCodecAV1 is AV1 with OBU extension headers
+	SVCCodecAV1 = "av1"
+)
+
+// VP9 payload descriptor flags (first byte)
+const (
+	vp9FlagPictureID    = 0x80
+	vp9FlagInterPicture = 0x40
+	vp9FlagLayerIndices = 0x20
+	vp9FlagStartOfFrame = 0x08
+	vp9FlagEndOfFrame   = 0x04
+)
+
+// AV1 aggregation header and OBU header fields
+const (
+	av1FlagContinuation = 0x80
+	av1FlagNewSequence  = 0x08
+	av1FlagOBUExtension = 0x04
+)
+
+// SVCLayer identifies a spatial and temporal layer of a scalable video
+// stream. Layer 0 is the base layer; higher layers add resolution
+// (spatial) or frame rate (temporal).
+type SVCLayer struct {
+	Spatial  int `json:"spatial"`
+	Temporal int `json:"temporal"`
+}
+
+// SVCConfig describes the scalable encoding of a published stream
+type SVCConfig struct {
+	// Codec is SVCCodecVP9 or SVCCodecAV1
+	Codec string `json:"codec"`
+
+	// SpatialBitrates is the bitrate in bps needed to receive each spatial
+	// layer at full frame rate, including the layers below it
+	SpatialBitrates []int `json:"spatial_bitrates"`
+
+	// TemporalLayers is the number of temporal layers (e.g. 3 for L1T3)
+	TemporalLayers int `json:"temporal_layers"`
+}
+
+// DefaultSVCConfig returns a configuration for an L3T3 VP9 encoding
+func DefaultSVCConfig() SVCConfig {
+	return SVCConfig{
+		Codec:           SVCCodecVP9,
+		SpatialBitrates: []int{150_000, 500_000, 1_500_000},
+		TemporalLayers:  3,
+	}
+}
+
+// MaxLayer returns the highest layer of the encoding
+func (c SVCConfig) MaxLayer() SVCLayer {
+	return SVCLayer{Spatial: max(len(c.SpatialBitrates)-1, 0), Temporal: max(c.TemporalLayers-1, 0)}
+}
+
+// SelectLayer picks the highest layer that fits in the available bandwidth.
+// Spatial layers are dropped first; below the base spatial layer's bitrate,
+// temporal layers are dropped, each halving the bitrate.
+func (c SVCConfig) SelectLayer(availableBps int) SVCLayer {
+	top := c.MaxLayer()
+
+	for s := len(c.SpatialBitrates) - 1; s >= 0; s-- {
+		if c.SpatialBitrates[s] <= availableBps {
+			return SVCLayer{Spatial: s, Temporal: top.Temporal}
+		}
+	}
+
+	layer := SVCLayer{}
+	if len(c.SpatialBitrates) == 0 {
+		return top
+	}
+	bitrate := c.SpatialBitrates[0]
+	for t := top.Temporal; t > 0; t-- {
+		bitrate /= 2
+		if bitrate <= availableBps {
+			layer.Temporal = t - 1
+			break
+		}
+	}
+
+	return layer
+}
+
+// svcPacketInfo is the layer information parsed from one RTP payload
+type svcPacketInfo struct {
+	layer        SVCLayer
+	hasLayer     bool // the payload carries layer indices
+	continuation bool // the payload continues an OBU from the previous packet
+	keyframe     bool
+	startOfFrame bool
+	endOfFrame   bool
+}
+
+// parseSVCPayload reads the layer information of a VP9 or AV1 payload
+func parseSVCPayload(codec string, payload []byte) svcPacketInfo {
+	switch codec {
+	case SVCCodecVP9:
+		return parseVP9Payload(payload)
+	case SVCCodecAV1:
+		return parseAV1Payload(payload)
+	default:
+		return svcPacketInfo{}
+	}
+}
+
+// parseVP9Payload reads the VP9 RTP payload descriptor
+func parseVP9Payload(payload []byte) svcPacketInfo {
+	if len(payload) < 1 {
+		return svcPacketInfo{}
+	}
+
+	flags := payload[0]
+	info := svcPacketInfo{
+		startOfFrame: flags&vp9FlagStartOfFrame != 0,
+		endOfFrame:   flags&vp9FlagEndOfFrame != 0,
+	}
+
+	offset := 1
+	if flags&vp9FlagPictureID != 0 {
+		if len(payload) <= offset {
+			return svcPacketInfo{}
+		}
+		if payload[offset]&0x80 != 0 {
+			offset += 2 // 15-bit picture ID
+		} else {
+			offset++
+		}
+	}
+
+	if flags&vp9FlagLayerIndices == 0 {
+		return info
+	}
+	if len(payload) <= offset {
+		return svcPacketInfo{}
+	}
+
+	indices := payload[offset]
+	info.hasLayer = true
+	info.layer = SVCLayer{Spatial: int(indices>>1) & 0x07, Temporal: int(indices >> 5)}
+	info.keyframe = flags&vp9FlagInterPicture == 0 && info.startOfFrame && info.layer.Spatial == 0
+
+	return info
+}
+
+// parseAV1Payload reads the AV1 aggregation header and the first OBU's
+// extension header
+func parseAV1Payload(payload []byte) svcPacketInfo {
+	if len(payload) < 2 {
+		return svcPacketInfo{}
+	}
+
+	aggregation := payload[0]
+	info := svcPacketInfo{
+		keyframe:     aggregation&av1FlagNewSequence != 0,
+		startOfFrame: aggregation&av1FlagContinuation == 0,
+	}
+
+	if aggregation&av1FlagContinuation != 0 {
+		info.continuation = true
+		return info
+	}
+
+	// The first OBU element has a length field unless it is the only one (W=1)
+	offset := 1
+	if w := (aggregation >> 4) & 0x03; w != 1 {
+		for offset < len(payload) && payload[offset]&0x80 != 0 {
+			offset++
+		}
+		offset++
+	}
+
+	if offset+1 >= len(payload) || payload[offset]&av1FlagOBUExtension == 0 {
+		return info
+	}
+
+	ext := payload[offset+1]
+	info.hasLayer = true
+	info.layer = SVCLayer{Spatial: int(ext>>3) & 0x03, Temporal: int(ext >> 5)}
+
+	return info
+}
+
+// SVCSelector drops the layers of a scalable video stream above a target
+// layer for one subscriber. Spatial upswitches wait for a keyframe and
+// temporal upswitches for a base temporal layer frame, so the subscriber
+// never receives frames that reference dropped ones. Sequence numbers are
+// rewritten so dropped packets do not look like loss.
+type SVCSelector struct {
+	config  SVCConfig
+	target  SVCLayer
+	current SVCLayer
+	pinned  bool
+	last    svcPacketInfo
+
+	seqOffset uint16
+	dropped   uint64
+	mu        sync.Mutex
+}
+
+// NewSVCSelector creates a selector that starts at the highest layer
+func NewSVCSelector(config SVCConfig) *SVCSelector {
+	top := config.MaxLayer()
+	return &SVCSelector{config: config, target: top, current: top}
+}
+
+// SetTarget pins the target layer, overriding bandwidth-based selection
+// until ClearTarget is called
+func (s *SVCSelector) SetTarget(layer SVCLayer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.target = layer
+	s.pinned = true
+}
+
+// ClearTarget returns to bandwidth-based selection
+func (s *SVCSelector) ClearTarget() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pinned = false
+}
+
+// OnBandwidthEstimate selects the target layer for an estimate unless the
+// target is pinned
+func (s *SVCSelector) OnBandwidthEstimate(bitrate int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.pinned {
+		s.target = s.config.SelectLayer(bitrate)
+	}
+}
+
+// Target returns the layer the selector is switching to
+func (s *SVCSelector) Target() SVCLayer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.target
+}
+
+// Current returns the highest layer currently forwarded
+func (s *SVCSelector) Current() SVCLayer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current
+}
+
+// Dropped returns the number of packets dropped
+func (s *SVCSelector) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Filter returns the packet to forward, with its sequence number rewritten,
+// or false if its layer is dropped. Packets without layer information are
+// always forwarded.
+func (s *SVCSelector) Filter(packet *rtp.Packet) (*rtp.Packet, bool) {
+	info := parseSVCPayload(s.config.Codec, packet.Payload)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if info.continuation {
+		info.layer, info.hasLayer = s.last.layer, s.last.hasLayer
+	}
+	s.last = info
+
+	if !info.hasLayer {
+		return s.rewrite(packet, false), true
+	}
+
+	if info.startOfFrame && info.layer.Spatial == 0 {
+		s.switchLayer(info)
+	}
+
+	if info.layer.Spatial > s.current.Spatial || info.layer.Temporal > s.current.Temporal {
+		s.seqOffset++
+		s.dropped++
+		return nil, false
+	}
+
+	// With upper spatial layers dropped, the top forwarded layer ends the frame
+	marker := s.config.Codec == SVCCodecVP9 && info.endOfFrame && info.layer.Spatial == s.current.Spatial
+	return s.rewrite(packet, marker), true
+}
+
+// switchLayer moves the current layer towards the target at a frame
+// boundary. Caller must hold s.mu.
+func (s *SVCSelector) switchLayer(info svcPacketInfo) {
+	if s.target == s.current {
+		return
+	}
+
+	next := s.current
+	if s.target.Spatial < next.Spatial || info.keyframe {
+		next.Spatial = s.target.Spatial
+	}
+	if s.target.Temporal < next.Temporal || info.layer.Temporal == 0 || info.keyframe {
+		next.Temporal = s.target.Temporal
+	}
+
+	s.current = next
+}
+
+// rewrite returns a copy of the packet with the sequence number shifted past
+// dropped packets. Caller must hold s.mu.
+func (s *SVCSelector) rewrite(packet *rtp.Packet, marker bool) *rtp.Packet {
+	if s.seqOffset == 0 && !marker {
+		return packet
+	}
+
+	out := *packet
+	out.Header.SequenceNumber -= s.seqOffset
+	if marker {
+		out.Header.Marker = true
+	}
+	return &out
+}
+
+// streamSVC is the SVC encoding of a stream and the layers seen in it
+type streamSVC struct {
+	config   SVCConfig
+	observed SVCLayer
+	seen     bool
+	mu       sync.Mutex
+}
+
+// observe records the layer of a packet published on the stream
+func (v *streamSVC) observe(packet *rtp.Packet) {
+	info := parseSVCPayload(v.config.Codec, packet.Payload)
+	if !info.hasLayer {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.seen = true
+	v.observed.Spatial = max(v.observed.Spatial, info.layer.Spatial)
+	v.observed.Temporal = max(v.observed.Temporal, info.layer.Temporal)
+}
+
+// SetStreamSVC declares that a stream is published with SVC, so each
+// subscriber receives only the layers its bandwidth estimate allows. Existing
+// and future subscribers of the stream are switched to SVC forwarding.
+func (sfu *SFU) SetStreamSVC(streamID string, config SVCConfig) error {
+	if !sfu.config.EnableSVC {
+		return ErrSVCDisabled
+	}
+	if config.Codec != SVCCodecVP9 && config.Codec != SVCCodecAV1 {
+		return ErrUnsupportedSVCCodec
+	}
+
+	stream, err := sfu.GetStream(streamID)
+	if err != nil {
+		return err
+	}
+
+	stream.mu.Lock()
+	stream.svc = &streamSVC{config: config}
+	subscribers := make([]*Subscriber, 0, len(stream.Subscribers))
+	for _, subscriber := range stream.Subscribers {
+		subscribers = append(subscribers, subscriber)
+	}
+	stream.mu.Unlock()
+
+	for _, subscriber := range subscribers {
+		subscriber.EnableSVC(config)
+	}
+
+	sfu.logger.Info("Enabled SVC forwarding",
+		logger.Field{Key: "stream_id", Value: streamID},
+		logger.Field{Key: "codec", Value: config.Codec},
+	)
+
+	return nil
+}
+
+// GetSVCLayers returns the highest SVC layer available on a stream: the
+// highest layer seen in published packets, or the configured encoding
+// before any layered packet has arrived
+func (sfu *SFU) GetSVCLayers(streamID string) (SVCLayer, error) {
+	stream, err := sfu.GetStream(streamID)
+	if err != nil {
+		return SVCLayer{}, err
+	}
+
+	stream.mu.RLock()
+	svc := stream.svc
+	stream.mu.RUnlock()
+
+	if svc == nil {
+		return SVCLayer{}, ErrSVCNotConfigured
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	if !svc.seen {
+		return svc.config.MaxLayer(), nil
+	}
+	return svc.observed, nil
+}
+
+// SetSubscriberSVCLayer pins the SVC layer forwarded to a subscriber. A nil
+// layer returns the subscriber to selection by bandwidth estimate.
+func (sfu *SFU) SetSubscriberSVCLayer(streamID, subscriberID string, layer *SVCLayer) error {
+	stream, err := sfu.GetStream(streamID)
+	if err != nil {
+		return err
+	}
+
+	stream.mu.RLock()
+	subscriber, exists := stream.Subscribers[subscriberID]
+	stream.mu.RUnlock()
+
+	if !exists {
+		return ErrSubscriberNotFound
+	}
+
+	return subscriber.SetSVCLayer(layer)
+}
@@ -0,0 +1,215 @@
+package webrtc
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/pion/rtp"
+)
+
+// Transcoder converts the RTP packets of a video track to another codec.
+// Implementations depacketize, decode, re-encode and repacketize, so they
+// are stateful and see every packet of the track in order; one packet in
+// may produce zero or more packets out.
+type Transcoder interface {
+	// Transcode converts one packet of the source codec
+	Transcode(packet *rtp.Packet) ([]*rtp.Packet, error)
+
+	// Close releases the decoder and encoder
+	Close() error
+}
+
+// TranscoderFactory creates a transcoder between two video MIME types
+// (e.g. webrtc.MimeTypeVP8 to webrtc.MimeTypeH264)
+type TranscoderFactory func(fromCodec, toCodec string) (Transcoder, error)
+
+// transcodeSession is a transcode of a stream's video to one codec, shared
+// by every subscriber of the stream that needs that codec
+type transcodeSession struct {
+	transcoder Transcoder
+	err        error
+	mu         sync.Mutex
+}
+
+// SetTranscoderFactory enables transcoding for subscribers that cannot
+// decode a stream's video codec. Without a factory, such subscribers get
+// the publisher's packets unchanged.
+func (sfu *SFU) SetTranscoderFactory(factory TranscoderFactory) {
+	sfu.mu.Lock()
+	defer sfu.mu.Unlock()
+
+	sfu.transcoderFactory = factory
+}
+
+// getTranscoderFactory returns the transcoder factory, or nil if transcoding
+// is disabled
+func (sfu *SFU) getTranscoderFactory() TranscoderFactory {
+	sfu.mu.RLock()
+	defer sfu.mu.RUnlock()
+	return sfu.transcoderFactory
+}
+
+// SetStreamVideoCodec sets the video MIME type a stream is published in,
+// for streams whose codec cannot be read from a publisher track (e.g.
+// relayed streams)
+func (sfu *SFU) SetStreamVideoCodec(streamID, codec string) error {
+	stream, err := sfu.GetStream(streamID)
+	if err != nil {
+		return err
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	stream.videoCodec = codec
+	return nil
+}
+
+// SetStreamEncrypted marks a stream's media as end-to-end encrypted. The SFU
+// cannot decode encrypted payloads, so they are forwarded untouched and
+// subscribers that cannot decode the source codec are not transcoded.
+func (sfu *SFU) SetStreamEncrypted(streamID string, encrypted bool) error {
+	stream, err := sfu.GetStream(streamID)
+	if err != nil {
+		return err
+	}
+
+	stream.mu.Lock()
+	stream.encrypted = encrypted
+	stream.mu.Unlock()
+
+	if encrypted {
+		stream.closeTranscodes()
+	}
+	return nil
+}
+
+// IsEncrypted returns whether the stream's media is end-to-end encrypted
+func (s *SFUStream) IsEncrypted() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.encrypted
+}
+
+// sourceVideoCodec returns the stream's video MIME type, or "" if it is not
+// known yet. Caller must hold s.mu.
+func (s *SFUStream) sourceVideoCodec() string {
+	if s.videoCodec != "" {
+		return s.videoCodec
+	}
+	if s.Publisher != nil {
+		if track := s.Publisher.GetVideoTrack(); track != nil {
+			return track.Codec().MimeType
+		}
+	}
+	return ""
+}
+
+// needsTranscode reports whether a subscriber cannot decode the source codec
+func needsTranscode(subscriber *Subscriber, source string) bool {
+	target := subscriber.GetVideoCodec()
+	return source != "" && target != "" && !strings.EqualFold(source, target)
+}
+
+// transcode converts a packet to the target codec through the stream's
+// shared session for that codec, creating it on first use
+func (s *SFUStream) transcode(factory TranscoderFactory, source, target string, packet *rtp.Packet) ([]*rtp.Packet, error) {
+	key := strings.ToLower(target)
+
+	s.transcodeMu.Lock()
+	session, exists := s.transcodes[key]
+	if !exists {
+		session = &transcodeSession{}
+		session.transcoder, session.err = factory(source, target)
+		s.transcodes[key] = session
+	}
+	s.transcodeMu.Unlock()
+
+	if session.err != nil {
+		return nil, session.err
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	return session.transcoder.Transcode(packet)
+}
+
+// releaseTranscodes closes the transcode sessions no subscriber needs any
+// more. Caller must hold s.mu.
+func (s *SFUStream) releaseTranscodes() {
+	needed := make(map[string]bool)
+	for _, subscriber := range s.Subscribers {
+		if subscriber.IsTranscoded() {
+			needed[strings.ToLower(subscriber.GetVideoCodec())] = true
+		}
+	}
+
+	s.transcodeMu.Lock()
+	defer s.transcodeMu.Unlock()
+
+	for key, session := range s.transcodes {
+		if needed[key] {
+			continue
+		}
+		if session.transcoder != nil {
+			session.transcoder.Close()
+		}
+		delete(s.transcodes, key)
+	}
+}
+
+// closeTranscodes closes every transcode session of the stream
+func (s *SFUStream) closeTranscodes() {
+	s.transcodeMu.Lock()
+	defer s.transcodeMu.Unlock()
+
+	for key, session := range s.transcodes {
+		if session.transcoder != nil {
+			session.transcoder.Close()
+		}
+		delete(s.transcodes, key)
+	}
+}
+
+// GetTranscodes returns the codecs a stream is being transcoded to
+func (s *SFUStream) GetTranscodes() []string {
+	s.transcodeMu.Lock()
+	defer s.transcodeMu.Unlock()
+
+	codecs := make([]string, 0, len(s.transcodes))
+	for key, session := range s.transcodes {
+		if session.err == nil {
+			codecs = append(codecs, key)
+		}
+	}
+	return codecs
+}
+
+// forwardTranscoded transcodes a packet once per target codec and writes
+// the result to the subscribers that need it. Caller must hold stream.mu.
+func (sfu *SFU) forwardTranscoded(stream *SFUStream, factory TranscoderFactory, source string, groups map[string][]*Subscriber, packet *rtp.Packet) {
+	for target, subscribers := range groups {
+		packets, err := stream.transcode(factory, source, target, packet)
+		if err != nil {
+			sfu.logger.Debug("Failed to transcode video packet",
+				logger.Field{Key: "stream_id", Value: stream.ID},
+				logger.Field{Key: "codec", Value: target},
+				logger.Field{Key: "error", Value: err.Error()},
+			)
+			continue
+		}
+
+		for _, subscriber := range subscribers {
+			for _, out := range packets {
+				if err := subscriber.WriteVideoPacket(out); err != nil {
+					sfu.logger.Debug("Failed to write transcoded packet to subscriber",
+						logger.Field{Key: "subscriber_id", Value: subscriber.GetID()},
+						logger.Field{Key: "error", Value: err.Error()},
+					)
+				}
+			}
+		}
+	}
+}
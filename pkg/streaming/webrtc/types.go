@@ -6,6 +6,7 @@ package webrtc
 import (
 	"time"
 
+	"github.com/pion/interceptor/pkg/stats"
 	"github.com/pion/webrtc/v3"
 )
 
@@ -127,6 +128,13 @@ type Config struct {
 
 	// EnableFIR enables Full Intra Request
 	EnableFIR bool
+
+	// DTLSRole is the default DTLS role used when answering
+	DTLSRole DTLSRole
+
+	// VerifyDTLSFingerprint rejects peers whose DTLS certificate does not
+	// match the fingerprint announced in their SDP
+	VerifyDTLSFingerprint bool
 }
 
 // TURNServer represents a TURN server configuration
@@ -199,6 +207,15 @@ type PeerConnection struct {
 
 	// Stats contains connection statistics
 	Stats *PeerStats
+
+	// DTLSRole is the DTLS role configured for this peer
+	DTLSRole DTLSRole
+
+	// statsGetter returns the RTP stats interceptor's getter
+	statsGetter func() stats.Getter
+
+	// byteSamples holds the previous byte count per SSRC for bitrate
+	byteSamples map[uint32]byteSample
 }
 
 // Track represents a media track (audio or video)
@@ -254,6 +271,15 @@ type PeerStats struct {
 	// Bitrate is the current bitrate in bps
 	Bitrate int
 
+	// NACKCount is the number of NACKs sent and received
+	NACKCount uint32
+
+	// PLICount is the number of PLIs sent and received
+	PLICount uint32
+
+	// Tracks holds per-track RTP statistics
+	Tracks []RTPTrackStats
+
 	// LastUpdated is the last update timestamp
 	LastUpdated time.Time
 }
@@ -343,6 +369,25 @@ type SFUConfig struct {
 
 	// EnableSVC enables Scalable Video Coding
 	EnableSVC bool
+
+	// NodeID identifies this SFU in relay chains (generated if empty)
+	NodeID string
+
+	// MaxRelayHops is the maximum number of SFUs a relayed stream may traverse
+	MaxRelayHops int
+
+	// RelaySecret is shared by the SFUs that relay streams to each other.
+	// Relay requests are signed with it, and RelayHandler refuses every
+	// request while it is empty.
+	RelaySecret string
+
+	// EnableAudioFEC negotiates RED alongside Opus in-band FEC, forwards RED
+	// from publishers and generates it for subscribers on lossy links
+	EnableAudioFEC bool
+
+	// AudioREDLossThreshold is the subscriber loss rate at which redundant
+	// audio is added (doubled above twice this rate)
+	AudioREDLossThreshold float64
 }
 
 // BWEConfig represents bandwidth estimation configuration
@@ -386,6 +431,8 @@ func DefaultConfig() Config {
 		EnableNACK:            true,
 		EnablePLI:             true,
 		EnableFIR:             true,
+		DTLSRole:              DTLSRoleAuto,
+		VerifyDTLSFingerprint: true,
 	}
 }
 
@@ -412,6 +459,9 @@ func DefaultSFUConfig() SFUConfig {
 		MaxStreams:              100,
 		EnableSimulcast:         false,
 		EnableSVC:               false,
+		MaxRelayHops:            4,
+		EnableAudioFEC:          false,
+		AudioREDLossThreshold:   0.03,
 	}
 }
 
@@ -451,6 +501,10 @@ func (c *Config) Validate() error {
 		return ErrInvalidBitrateRange
 	}
 
+	if err := c.DTLSRole.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -494,20 +548,62 @@ var (
 	// ErrPeerNotFound indicates peer not found
 	ErrPeerNotFound = &WebRTCError{Code: "PEER_NOT_FOUND", Message: "peer not found"}
 
+	// ErrSFUClosed indicates the SFU has been closed
+	ErrSFUClosed = &WebRTCError{Code: "SFU_CLOSED", Message: "SFU closed"}
+
 	// ErrStreamNotFound indicates stream not found
 	ErrStreamNotFound = &WebRTCError{Code: "STREAM_NOT_FOUND", Message: "stream not found"}
 
 	// ErrPublisherExists indicates publisher already exists
 	ErrPublisherExists = &WebRTCError{Code: "PUBLISHER_EXISTS", Message: "publisher already exists for stream"}
 
+	// ErrRelayExists indicates the stream is already relayed from an origin
+	ErrRelayExists = &WebRTCError{Code: "RELAY_EXISTS", Message: "relay already exists for stream"}
+
+	// ErrRelayNotFound indicates the stream has no relay
+	ErrRelayNotFound = &WebRTCError{Code: "RELAY_NOT_FOUND", Message: "relay not found"}
+
+	// ErrRelayLoop indicates a relay would loop back through this SFU or exceed the hop limit
+	ErrRelayLoop = &WebRTCError{Code: "RELAY_LOOP", Message: "relay loop detected"}
+
+	// ErrRelayUnauthorized indicates a relay request was not signed with the relay secret
+	ErrRelayUnauthorized = &WebRTCError{Code: "RELAY_UNAUTHORIZED", Message: "relay request not authorized"}
+
 	// ErrMaxSubscribersReached indicates maximum subscribers reached
 	ErrMaxSubscribersReached = &WebRTCError{Code: "MAX_SUBSCRIBERS", Message: "maximum subscribers reached"}
 
+	// ErrPacingBudgetExceeded indicates a packet was dropped to stay within the bandwidth estimate
+	ErrPacingBudgetExceeded = &WebRTCError{Code: "PACING_BUDGET_EXCEEDED", Message: "packet dropped by pacer"}
+
+	// ErrSVCDisabled indicates SVC forwarding is not enabled in the SFU config
+	ErrSVCDisabled = &WebRTCError{Code: "SVC_DISABLED", Message: "SVC is not enabled"}
+
+	// ErrSVCNotConfigured indicates the stream has no SVC configuration
+	ErrSVCNotConfigured = &WebRTCError{Code: "SVC_NOT_CONFIGURED", Message: "SVC is not configured for stream"}
+
+	// ErrUnsupportedSVCCodec indicates a codec without SVC layer parsing
+	ErrUnsupportedSVCCodec = &WebRTCError{Code: "UNSUPPORTED_SVC_CODEC", Message: "unsupported SVC codec"}
+
+	// ErrSubscriberNotFound indicates the subscriber does not exist
+	ErrSubscriberNotFound = &WebRTCError{Code: "SUBSCRIBER_NOT_FOUND", Message: "subscriber not found"}
+
+	// ErrInvalidREDPayload indicates a malformed RFC 2198 payload
+	ErrInvalidREDPayload = &WebRTCError{Code: "INVALID_RED_PAYLOAD", Message: "invalid RED payload"}
+
 	// ErrInvalidSDP indicates invalid SDP
 	ErrInvalidSDP = &WebRTCError{Code: "INVALID_SDP", Message: "invalid SDP"}
 
 	// ErrConnectionFailed indicates connection failed
 	ErrConnectionFailed = &WebRTCError{Code: "CONNECTION_FAILED", Message: "peer connection failed"}
+
+	// ErrInvalidDTLSRole indicates an unknown DTLS role
+	ErrInvalidDTLSRole = &WebRTCError{Code: "INVALID_DTLS_ROLE", Message: "invalid DTLS role"}
+
+	// ErrDTLSFingerprintMismatch indicates the remote certificate does not match the SDP fingerprint
+	ErrDTLSFingerprintMismatch = &WebRTCError{Code: "DTLS_FINGERPRINT_MISMATCH", Message: "remote certificate does not match SDP fingerprint"}
+
+	// ErrNoRemoteCertificate indicates the DTLS handshake produced no remote certificate
+	ErrNoRemoteCertificate = &WebRTCError{Code: "NO_REMOTE_CERTIFICATE", Message: "no remote DTLS certificate"}
 )
 
 // WebRTCError represents a WebRTC-specific error
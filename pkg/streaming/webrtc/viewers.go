@@ -0,0 +1,97 @@
+package webrtc
+
+import (
+	"context"
+	"time"
+
+	"github.com/aminofox/zenlive/pkg/logger"
+)
+
+// viewerRefreshInterval is how often the SFU refreshes the viewing sessions
+// of its subscribers, which must be well under the limiter's session TTL
+const viewerRefreshInterval = time.Minute
+
+// ViewerLimiter admits or rejects viewing sessions, e.g. to cap the streams
+// a user watches at once. streaming.ViewingLimiter implements it.
+type ViewerLimiter interface {
+	// Acquire admits a session, or refreshes it if it already exists
+	Acquire(ctx context.Context, userID, streamID, sessionID string) error
+
+	// Release ends a session
+	Release(ctx context.Context, sessionID string) error
+}
+
+// SetViewerLimiter makes subscribing count as a viewing session of the
+// subscriber's account, rejecting accounts over their limit. Anonymous
+// subscribers are not limited.
+func (sfu *SFU) SetViewerLimiter(limiter ViewerLimiter) {
+	sfu.mu.Lock()
+	start := sfu.viewerLimiter == nil && limiter != nil
+	sfu.viewerLimiter = limiter
+	sfu.mu.Unlock()
+
+	if start {
+		go sfu.refreshViewerSessions()
+	}
+}
+
+// getViewerLimiter returns the viewer limiter, or nil if there is none
+func (sfu *SFU) getViewerLimiter() ViewerLimiter {
+	sfu.mu.RLock()
+	defer sfu.mu.RUnlock()
+	return sfu.viewerLimiter
+}
+
+// viewerSessionID returns the viewing session ID of a subscription
+func viewerSessionID(streamID, subscriberID string) string {
+	return "webrtc:" + streamID + ":" + subscriberID
+}
+
+// acquireViewer admits a subscriber's viewing session for account userID
+func (sfu *SFU) acquireViewer(ctx context.Context, streamID, subscriberID, userID string) error {
+	limiter := sfu.getViewerLimiter()
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Acquire(ctx, userID, streamID, viewerSessionID(streamID, subscriberID))
+}
+
+// releaseViewer ends a subscriber's viewing session
+func (sfu *SFU) releaseViewer(streamID, subscriberID string) {
+	limiter := sfu.getViewerLimiter()
+	if limiter == nil {
+		return
+	}
+
+	if err := limiter.Release(context.Background(), viewerSessionID(streamID, subscriberID)); err != nil {
+		sfu.logger.Debug("Failed to release viewing session",
+			logger.Field{Key: "stream_id", Value: streamID},
+			logger.Field{Key: "subscriber_id", Value: subscriberID},
+			logger.Field{Key: "error", Value: err.Error()},
+		)
+	}
+}
+
+// refreshViewerSessions keeps the viewing sessions of connected subscribers
+// alive until the SFU closes
+func (sfu *SFU) refreshViewerSessions() {
+	ticker := time.NewTicker(viewerRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sfu.ctx.Done():
+			return
+		case <-ticker.C:
+			limiter := sfu.getViewerLimiter()
+			if limiter == nil {
+				return
+			}
+			for _, stream := range sfu.GetStreams() {
+				for _, subscriber := range stream.GetSubscribers() {
+					limiter.Acquire(sfu.ctx, subscriber.GetUserID(), stream.ID, viewerSessionID(stream.ID, subscriber.GetID()))
+				}
+			}
+		}
+	}
+}
@@ -3,10 +3,18 @@ package webrtc
 
 import (
 	"context"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aminofox/zenlive/pkg/logger"
+	"github.com/gorilla/websocket"
+	"github.com/pion/interceptor/pkg/stats"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
 )
 
 // TestDefaultConfig tests the default WebRTC configuration
@@ -323,3 +331,853 @@ func TestWebRTCErrors(t *testing.T) {
 		t.Errorf("Unexpected error format: %s", customErr.Error())
 	}
 }
+
+// TestDTLSFingerprintVerification tests SDP fingerprint parsing and certificate matching
+func TestDTLSFingerprintVerification(t *testing.T) {
+	cert := []byte("test-certificate-der")
+	digest := sha256.Sum256(cert)
+
+	sdp := "v=0\r\n" +
+		"a=fingerprint:sha-256 " + formatFingerprint(digest[:]) + "\r\n" +
+		"m=audio 9 UDP/TLS/RTP/SAVPF 111\r\n"
+
+	fingerprints := ParseSDPFingerprints(sdp)
+	if len(fingerprints) != 1 {
+		t.Fatalf("Expected 1 fingerprint, got %d", len(fingerprints))
+	}
+
+	if fingerprints[0].Algorithm != "sha-256" {
+		t.Errorf("Expected algorithm sha-256, got %s", fingerprints[0].Algorithm)
+	}
+
+	if err := VerifyCertificateFingerprint(cert, fingerprints); err != nil {
+		t.Errorf("Expected matching fingerprint, got %v", err)
+	}
+
+	if err := VerifyCertificateFingerprint([]byte("other-certificate"), fingerprints); err != ErrDTLSFingerprintMismatch {
+		t.Errorf("Expected ErrDTLSFingerprintMismatch, got %v", err)
+	}
+
+	if err := VerifyCertificateFingerprint(nil, fingerprints); err != ErrNoRemoteCertificate {
+		t.Errorf("Expected ErrNoRemoteCertificate, got %v", err)
+	}
+}
+
+// TestPeerDTLSRole tests per-peer DTLS role configuration
+func TestPeerDTLSRole(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "json")
+	pm := NewPeerManager(DefaultConfig(), log)
+	defer pm.CloseAll()
+
+	if err := pm.SetPeerDTLSRole("peer-1", DTLSRole("bogus")); err != ErrInvalidDTLSRole {
+		t.Errorf("Expected ErrInvalidDTLSRole, got %v", err)
+	}
+
+	if err := pm.SetPeerDTLSRole("peer-1", DTLSRoleServer); err != nil {
+		t.Fatalf("Failed to set DTLS role: %v", err)
+	}
+
+	peer, err := pm.CreatePeer(context.Background(), "peer-1", "stream-1", PeerRolePublisher)
+	if err != nil {
+		t.Fatalf("Failed to create peer: %v", err)
+	}
+
+	if peer.DTLSRole != DTLSRoleServer {
+		t.Errorf("Expected DTLS role %s, got %s", DTLSRoleServer, peer.DTLSRole)
+	}
+
+	state, err := pm.GetDTLSState("peer-1")
+	if err != nil {
+		t.Errorf("Failed to get DTLS state: %v", err)
+	}
+
+	if state == webrtc.DTLSTransportStateConnected {
+		t.Error("DTLS should not be connected before negotiation")
+	}
+
+	if _, err := pm.GetDTLSState("missing"); err != ErrPeerNotFound {
+		t.Errorf("Expected ErrPeerNotFound, got %v", err)
+	}
+}
+
+// TestSFURelay tests relaying a stream from an origin SFU through an edge SFU
+func TestSFURelay(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "json")
+
+	originConfig := DefaultSFUConfig()
+	originConfig.NodeID = "origin"
+	originConfig.RelaySecret = "relay-secret"
+	origin := NewSFU(originConfig, log)
+	defer origin.Close()
+
+	edgeConfig := DefaultSFUConfig()
+	edgeConfig.NodeID = "edge"
+	edgeConfig.RelaySecret = "relay-secret"
+	edge := NewSFU(edgeConfig, log)
+	defer edge.Close()
+
+	if err := origin.CreateStream("stream-1", "Test Stream"); err != nil {
+		t.Fatalf("Failed to create stream: %v", err)
+	}
+
+	originServer := httptest.NewServer(origin.RelayHandler())
+	defer originServer.Close()
+	edgeServer := httptest.NewServer(edge.RelayHandler())
+	defer edgeServer.Close()
+
+	relay, err := edge.AddRelay(originServer.Listener.Addr().String(), "stream-1")
+	if err != nil {
+		t.Fatalf("Failed to add relay: %v", err)
+	}
+
+	if _, err := edge.AddRelay(originServer.Listener.Addr().String(), "stream-1"); err != ErrRelayExists {
+		t.Errorf("Expected ErrRelayExists, got %v", err)
+	}
+
+	waitFor(t, "relay to connect", relay.IsConnected)
+
+	stream, _ := edge.GetStream("stream-1")
+	if path := stream.GetRelayPath(); len(path) != 1 || path[0] != "origin" {
+		t.Errorf("Expected relay path [origin], got %v", path)
+	}
+
+	// A viewer-side relay on the edge receives packets published at the origin
+	wsURL := "ws" + strings.TrimPrefix(edgeServer.URL, "http") + RelayPath + "?stream=stream-1"
+	header := http.Header{}
+	header.Set(HeaderRelayNode, "downstream")
+
+	// Requests not signed with the relay secret are refused before upgrading
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL, header); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected unsigned relay request to be rejected, got %v", err)
+	}
+	signRelayRequest(header, "wrong-secret", wsURL)
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL, header); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected relay request with the wrong secret to be rejected, got %v", err)
+	}
+
+	signRelayRequest(header, "relay-secret", wsURL)
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if err != nil {
+		t.Fatalf("Failed to connect to edge: %v", err)
+	}
+	defer conn.Close()
+
+	if got := resp.Header.Get(HeaderRelayPath); got != "origin,edge" {
+		t.Errorf("Expected relay path 'origin,edge', got %q", got)
+	}
+
+	waitFor(t, "edge to register downstream", func() bool {
+		stream.mu.RLock()
+		defer stream.mu.RUnlock()
+		return len(stream.relaySinks) == 1
+	})
+
+	origin.forwardVideoPacket("stream-1", &rtp.Packet{
+		Header:  rtp.Header{Version: 2, SequenceNumber: 42, PayloadType: 96},
+		Payload: []byte{1, 2, 3},
+	})
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read relayed packet: %v", err)
+	}
+
+	packet := &rtp.Packet{}
+	if data[0] != relayKindVideo || packet.Unmarshal(data[1:]) != nil || packet.SequenceNumber != 42 {
+		t.Errorf("Unexpected relayed packet %v", data)
+	}
+
+	// The origin must not relay the stream back from the edge
+	header.Set(HeaderRelayNode, "origin")
+	signRelayRequest(header, "relay-secret", wsURL)
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL, header); err == nil || resp == nil || resp.StatusCode != http.StatusLoopDetected {
+		t.Errorf("Expected loop to be rejected, got %v", err)
+	}
+
+	// Publishing locally on a relayed stream is not allowed
+	if _, err := edge.AddPublisher(context.Background(), "stream-1", "pub-1"); err != ErrRelayExists {
+		t.Errorf("Expected ErrRelayExists, got %v", err)
+	}
+
+	// The relay reconnects once the origin stream comes back
+	origin.DeleteStream("stream-1")
+	waitFor(t, "relay to disconnect", func() bool { return !relay.IsConnected() })
+	origin.CreateStream("stream-1", "Test Stream")
+	waitFor(t, "relay to reconnect", func() bool {
+		return relay.Reconnects() > 0 && relay.IsConnected()
+	})
+
+	if err := edge.RemoveRelay("stream-1"); err != nil {
+		t.Errorf("Failed to remove relay: %v", err)
+	}
+	if relay.IsConnected() {
+		t.Error("Expected relay to be disconnected after removal")
+	}
+}
+
+// waitFor polls cond until it is true or the test times out
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestGCCEstimator tests delay-based and loss-based congestion control
+func TestGCCEstimator(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "json")
+	config := DefaultBWEConfig()
+	base := time.Now()
+
+	// feed sends 200 packets/s of 1200 bytes; queueing adds extra delay per packet
+	feed := func(g *GCCEstimator, packets int, queueing time.Duration) {
+		for i := 0; i < packets; i++ {
+			send := base.Add(time.Duration(i) * 5 * time.Millisecond)
+			arrival := send.Add(20*time.Millisecond + time.Duration(i)*queueing)
+			g.OnPacketFeedback(send, arrival, 1200)
+		}
+	}
+
+	// A stable path lets the estimate ramp up
+	stable := NewGCCEstimator(config, log)
+	feed(stable, 1000, 0)
+	if got := stable.GetEstimate(); got <= config.StartBitrate {
+		t.Errorf("Expected estimate to grow above %d on a stable path, got %d", config.StartBitrate, got)
+	}
+	if usage := stable.GetStats().Usage; usage != BandwidthNormal {
+		t.Errorf("Expected normal usage, got %s", usage)
+	}
+
+	// Growing queueing delay signals overuse and backs off
+	congested := NewGCCEstimator(config, log)
+	var estimates []int
+	congested.OnEstimate(func(bitrate int) {
+		estimates = append(estimates, bitrate)
+	})
+	feed(congested, 400, time.Millisecond)
+
+	stats := congested.GetStats()
+	if stats.EstimatedBitrate >= config.StartBitrate {
+		t.Errorf("Expected estimate below %d under congestion, got %d", config.StartBitrate, stats.EstimatedBitrate)
+	}
+	if stats.AckedBitrate == 0 {
+		t.Error("Expected acked bitrate to be measured")
+	}
+	if len(estimates) == 0 {
+		t.Error("Expected estimate callback to fire")
+	}
+
+	// Heavy loss reduces the loss-based estimate
+	lossy := NewGCCEstimator(config, log)
+	lossy.OnLossReport(0.3)
+	if got := lossy.GetEstimate(); got >= config.StartBitrate {
+		t.Errorf("Expected loss to reduce estimate below %d, got %d", config.StartBitrate, got)
+	}
+}
+
+// TestPacer tests pacing budget enforcement
+func TestPacer(t *testing.T) {
+	// 8 kbps * 2.5 pacing factor = 2500 B/s with a 1250 byte budget cap
+	pacer := NewPacer(8000)
+
+	if !pacer.Allow(1000) {
+		t.Error("Expected first packet to fit in the budget")
+	}
+	if pacer.Allow(1000) {
+		t.Error("Expected second packet to exceed the budget")
+	}
+
+	pacer.SetBitrate(10_000_000)
+	time.Sleep(10 * time.Millisecond)
+	if !pacer.Allow(1000) {
+		t.Error("Expected budget to refill after raising the bitrate")
+	}
+}
+
+func TestREDEncodeDecode(t *testing.T) {
+	redundant := []REDBlock{
+		{PayloadType: OpusPayloadType, TimestampOffset: 1920, Payload: []byte{1, 2, 3}},
+		{PayloadType: OpusPayloadType, TimestampOffset: 960, Payload: []byte{4, 5}},
+	}
+	primary := REDBlock{PayloadType: OpusPayloadType, Payload: []byte{6, 7, 8, 9}}
+
+	blocks, err := DecodeRED(EncodeRED(redundant, primary))
+	if err != nil {
+		t.Fatalf("Failed to decode RED payload: %v", err)
+	}
+
+	if len(blocks) != 3 {
+		t.Fatalf("Expected 3 blocks, got %d", len(blocks))
+	}
+
+	for i, want := range append(redundant, primary) {
+		if blocks[i].PayloadType != want.PayloadType {
+			t.Errorf("Block %d: expected payload type %d, got %d", i, want.PayloadType, blocks[i].PayloadType)
+		}
+		if blocks[i].TimestampOffset != want.TimestampOffset {
+			t.Errorf("Block %d: expected offset %d, got %d", i, want.TimestampOffset, blocks[i].TimestampOffset)
+		}
+		if string(blocks[i].Payload) != string(want.Payload) {
+			t.Errorf("Block %d: expected payload %v, got %v", i, want.Payload, blocks[i].Payload)
+		}
+	}
+
+	if _, err := DecodeRED([]byte{0x80 | OpusPayloadType, 0, 0}); err != ErrInvalidREDPayload {
+		t.Errorf("Expected ErrInvalidREDPayload for truncated header, got %v", err)
+	}
+}
+
+func TestREDEncoder(t *testing.T) {
+	encoder := NewREDEncoder()
+
+	packet := func(seq uint16) *rtp.Packet {
+		return &rtp.Packet{
+			Header:  rtp.Header{PayloadType: OpusPayloadType, SequenceNumber: seq, Timestamp: uint32(seq) * 960},
+			Payload: []byte{byte(seq)},
+		}
+	}
+
+	for seq := uint16(1); seq <= 3; seq++ {
+		encoder.Encode(packet(seq), 0)
+	}
+
+	red := encoder.Encode(packet(4), 2)
+	if red.PayloadType != REDPayloadType {
+		t.Errorf("Expected payload type %d, got %d", REDPayloadType, red.PayloadType)
+	}
+	if red.SequenceNumber != 4 {
+		t.Errorf("Expected sequence number 4, got %d", red.SequenceNumber)
+	}
+
+	blocks, err := DecodeRED(red.Payload)
+	if err != nil {
+		t.Fatalf("Failed to decode RED payload: %v", err)
+	}
+
+	if len(blocks) != 3 {
+		t.Fatalf("Expected 3 blocks, got %d", len(blocks))
+	}
+	if blocks[0].TimestampOffset != 1920 || blocks[0].Payload[0] != 2 {
+		t.Errorf("Unexpected oldest redundant block: %+v", blocks[0])
+	}
+	if blocks[2].Payload[0] != 4 {
+		t.Errorf("Unexpected primary block: %+v", blocks[2])
+	}
+
+	blocks, _ = DecodeRED(encoder.Encode(packet(5), 0).Payload)
+	if len(blocks) != 1 {
+		t.Errorf("Expected only the primary block at distance 0, got %d blocks", len(blocks))
+	}
+
+	if d := redDistance(0.01, 0.03); d != 0 {
+		t.Errorf("Expected distance 0 below threshold, got %d", d)
+	}
+	if d := redDistance(0.04, 0.03); d != 1 {
+		t.Errorf("Expected distance 1 above threshold, got %d", d)
+	}
+	if d := redDistance(0.1, 0.03); d != 2 {
+		t.Errorf("Expected distance 2 at high loss, got %d", d)
+	}
+}
+
+// TestPeerStats tests RTP stats conversion and peer aggregation
+func TestPeerStats(t *testing.T) {
+	now := time.Now()
+
+	var s stats.Stats
+	s.OutboundRTPStreamStats.PacketsSent = 100
+	s.OutboundRTPStreamStats.BytesSent = 250000
+	s.OutboundRTPStreamStats.NACKCount = 3
+	s.OutboundRTPStreamStats.PLICount = 1
+	s.RemoteInboundRTPStreamStats.PacketsLost = 2
+	s.RemoteInboundRTPStreamStats.RoundTripTime = 40 * time.Millisecond
+
+	out := trackRef{id: "video", kind: "video", ssrc: 1, direction: TrackDirectionOutbound}
+	prev := &byteSample{bytes: 0, at: now.Add(-time.Second)}
+	video := newRTPTrackStats(out, &s, prev, now)
+
+	if video.PacketsSent != 100 || video.PacketsLost != 2 || video.NACKCount != 3 || video.PLICount != 1 {
+		t.Errorf("Unexpected outbound stats: %+v", video)
+	}
+	if video.RTT != 40*time.Millisecond || video.Bitrate != 2000000 {
+		t.Errorf("Expected 40ms RTT and 2 Mbps, got %v and %d", video.RTT, video.Bitrate)
+	}
+
+	var in stats.Stats
+	in.InboundRTPStreamStats.PacketsReceived = 50
+	in.InboundRTPStreamStats.BytesReceived = 4000
+	in.InboundRTPStreamStats.Jitter = 0.005
+	audio := newRTPTrackStats(trackRef{id: "audio", kind: "audio", ssrc: 2, direction: TrackDirectionInbound}, &in, nil, now)
+	if audio.PacketsReceived != 50 || audio.Bitrate != 0 {
+		t.Errorf("Unexpected inbound stats: %+v", audio)
+	}
+
+	peerStats := aggregatePeerStats([]RTPTrackStats{video, audio}, now)
+	if peerStats.PacketsSent != 100 || peerStats.PacketsReceived != 50 || peerStats.PacketsLost != 2 {
+		t.Errorf("Unexpected peer totals: %+v", peerStats)
+	}
+	if peerStats.RTT != 0.04 || peerStats.Jitter != 0.005 || len(peerStats.Tracks) != 2 {
+		t.Errorf("Unexpected peer RTT/jitter: %+v", peerStats)
+	}
+
+	pm := NewPeerManager(DefaultConfig(), logger.NewDefaultLogger(logger.InfoLevel, "text"))
+	if _, err := pm.CreatePeer(context.Background(), "peer-1", "stream-1", PeerRoleSubscriber); err != nil {
+		t.Fatalf("Failed to create peer: %v", err)
+	}
+	got, err := pm.GetStats("peer-1")
+	if err != nil || len(got.Tracks) != 0 {
+		t.Errorf("Expected empty stats for an unnegotiated peer, got %+v, %v", got, err)
+	}
+	if _, err := pm.GetStats("missing"); err != ErrPeerNotFound {
+		t.Errorf("Expected ErrPeerNotFound, got %v", err)
+	}
+	pm.CloseAll()
+}
+
+// vp9Packet builds a VP9 packet with a 7-bit picture ID and layer indices
+func vp9Packet(seq uint16, spatial, temporal int, start, end, inter bool) *rtp.Packet {
+	flags := byte(vp9FlagPictureID | vp9FlagLayerIndices)
+	if inter {
+		flags |= vp9FlagInterPicture
+	}
+	if start {
+		flags |= vp9FlagStartOfFrame
+	}
+	if end {
+		flags |= vp9FlagEndOfFrame
+	}
+	indices := byte(temporal<<5) | byte(spatial<<1)
+	return &rtp.Packet{
+		Header:  rtp.Header{SequenceNumber: seq},
+		Payload: []byte{flags, 0x01, indices, 0x00, 0xAA},
+	}
+}
+
+// TestSVCPayloadParsing tests VP9 and AV1 layer parsing
+func TestSVCPayloadParsing(t *testing.T) {
+	info := parseVP9Payload(vp9Packet(1, 2, 1, true, false, false).Payload)
+	if !info.hasLayer || info.layer != (SVCLayer{Spatial: 2, Temporal: 1}) {
+		t.Errorf("Expected VP9 layer S2T1, got %+v", info.layer)
+	}
+	if info.keyframe {
+		t.Error("Expected upper spatial layer not to start a keyframe")
+	}
+
+	info = parseVP9Payload(vp9Packet(1, 0, 0, true, true, false).Payload)
+	if !info.keyframe || !info.startOfFrame || !info.endOfFrame {
+		t.Errorf("Expected VP9 base layer keyframe, got %+v", info)
+	}
+
+	// Aggregation header W=1, OBU header with extension, extension T2 S1
+	av1 := []byte{0x10 | av1FlagNewSequence, 0x32 | av1FlagOBUExtension, 2<<5 | 1<<3, 0x00}
+	info = parseAV1Payload(av1)
+	if !info.hasLayer || info.layer != (SVCLayer{Spatial: 1, Temporal: 2}) {
+		t.Errorf("Expected AV1 layer S1T2, got %+v", info.layer)
+	}
+	if !info.keyframe {
+		t.Error("Expected AV1 new coded video sequence to be a keyframe")
+	}
+
+	// W=0: the first OBU element is preceded by its LEB128 length
+	av1 = []byte{0x00, 0x03, 0x32 | av1FlagOBUExtension, 1 << 5, 0x00}
+	info = parseAV1Payload(av1)
+	if !info.hasLayer || info.layer != (SVCLayer{Spatial: 0, Temporal: 1}) {
+		t.Errorf("Expected AV1 layer S0T1, got %+v", info.layer)
+	}
+
+	if info = parseAV1Payload([]byte{av1FlagContinuation, 0x00}); !info.continuation {
+		t.Error("Expected AV1 continuation packet")
+	}
+}
+
+// TestSVCSelector tests layer dropping, switching and sequence rewriting
+func TestSVCSelector(t *testing.T) {
+	config := SVCConfig{Codec: SVCCodecVP9, SpatialBitrates: []int{100_000, 400_000}, TemporalLayers: 2}
+
+	if layer := config.SelectLayer(1_000_000); layer != (SVCLayer{Spatial: 1, Temporal: 1}) {
+		t.Errorf("Expected S1T1 at 1 Mbps, got %+v", layer)
+	}
+	if layer := config.SelectLayer(200_000); layer != (SVCLayer{Spatial: 0, Temporal: 1}) {
+		t.Errorf("Expected S0T1 at 200 kbps, got %+v", layer)
+	}
+	if layer := config.SelectLayer(60_000); layer != (SVCLayer{Spatial: 0, Temporal: 0}) {
+		t.Errorf("Expected S0T0 at 60 kbps, got %+v", layer)
+	}
+
+	selector := NewSVCSelector(config)
+	selector.OnBandwidthEstimate(200_000)
+
+	// S0 then S1 of one picture: S1 is dropped and S0 ends the frame
+	out, ok := selector.Filter(vp9Packet(10, 0, 0, true, true, true))
+	if !ok || out.SequenceNumber != 10 || !out.Marker {
+		t.Errorf("Expected base layer forwarded with marker, got %+v ok=%v", out, ok)
+	}
+	if _, ok := selector.Filter(vp9Packet(11, 1, 0, true, true, true)); ok {
+		t.Error("Expected spatial layer 1 to be dropped")
+	}
+	out, ok = selector.Filter(vp9Packet(12, 0, 1, true, true, true))
+	if !ok || out.SequenceNumber != 11 {
+		t.Errorf("Expected sequence number rewritten to 11, got %+v ok=%v", out, ok)
+	}
+	if selector.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped packet, got %d", selector.Dropped())
+	}
+
+	// Spatial upswitch waits for a keyframe
+	selector.SetTarget(SVCLayer{Spatial: 1, Temporal: 1})
+	selector.Filter(vp9Packet(13, 0, 0, true, true, true))
+	if selector.Current().Spatial != 0 {
+		t.Errorf("Expected spatial layer 0 before keyframe, got %+v", selector.Current())
+	}
+	selector.Filter(vp9Packet(14, 0, 0, true, true, false))
+	if selector.Current() != (SVCLayer{Spatial: 1, Temporal: 1}) {
+		t.Errorf("Expected S1T1 after keyframe, got %+v", selector.Current())
+	}
+
+	// A pinned target ignores bandwidth estimates until cleared
+	selector.OnBandwidthEstimate(50_000)
+	if selector.Target() != (SVCLayer{Spatial: 1, Temporal: 1}) {
+		t.Errorf("Expected pinned target, got %+v", selector.Target())
+	}
+	selector.ClearTarget()
+	selector.OnBandwidthEstimate(50_000)
+	if selector.Target() != (SVCLayer{}) {
+		t.Errorf("Expected S0T0 target, got %+v", selector.Target())
+	}
+}
+
+// TestSFUStreamSVC tests enabling SVC on a stream and reading its layers
+func TestSFUStreamSVC(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "json")
+
+	sfu := NewSFU(DefaultSFUConfig(), log)
+	sfu.CreateStream("stream-1", "Test Stream")
+	if err := sfu.SetStreamSVC("stream-1", DefaultSVCConfig()); err != ErrSVCDisabled {
+		t.Errorf("Expected ErrSVCDisabled, got %v", err)
+	}
+	sfu.Close()
+
+	config := DefaultSFUConfig()
+	config.EnableSVC = true
+	sfu = NewSFU(config, log)
+	defer sfu.Close()
+	sfu.CreateStream("stream-1", "Test Stream")
+
+	if _, err := sfu.GetSVCLayers("stream-1"); err != ErrSVCNotConfigured {
+		t.Errorf("Expected ErrSVCNotConfigured, got %v", err)
+	}
+	if err := sfu.SetStreamSVC("stream-1", SVCConfig{Codec: "h264"}); err != ErrUnsupportedSVCCodec {
+		t.Errorf("Expected ErrUnsupportedSVCCodec, got %v", err)
+	}
+	if err := sfu.SetStreamSVC("stream-1", DefaultSVCConfig()); err != nil {
+		t.Fatalf("Failed to enable SVC: %v", err)
+	}
+
+	if layers, _ := sfu.GetSVCLayers("stream-1"); layers != (SVCLayer{Spatial: 2, Temporal: 2}) {
+		t.Errorf("Expected configured layers S2T2, got %+v", layers)
+	}
+
+	sfu.forwardVideoPacket("stream-1", vp9Packet(1, 1, 0, true, true, false))
+	if layers, _ := sfu.GetSVCLayers("stream-1"); layers != (SVCLayer{Spatial: 1, Temporal: 0}) {
+		t.Errorf("Expected observed layers S1T0, got %+v", layers)
+	}
+
+	if err := sfu.SetSubscriberSVCLayer("stream-1", "missing", nil); err != ErrSubscriberNotFound {
+		t.Errorf("Expected ErrSubscriberNotFound, got %v", err)
+	}
+}
+
+// TestSFUPauseSubscription tests pausing video forwarding for hidden tiles
+func TestSFUPauseSubscription(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "json")
+	sfu := NewSFU(DefaultSFUConfig(), log)
+	defer sfu.Close()
+
+	sfu.CreateStream("stream-1", "Test Stream")
+	stream, _ := sfu.GetStream("stream-1")
+	subscriber := NewSubscriber("viewer", "stream-1", sfu.peerManager, sfu.trackManager, log)
+	stream.Subscribers["viewer"] = subscriber
+
+	packet := &rtp.Packet{Header: rtp.Header{PayloadType: 96}, Payload: []byte{1}}
+
+	if err := sfu.PauseSubscription("stream-1", "viewer"); err != nil {
+		t.Fatalf("Failed to pause subscription: %v", err)
+	}
+	if err := subscriber.WriteVideoPacket(packet); err != nil {
+		t.Errorf("Expected paused subscriber to drop video silently, got %v", err)
+	}
+	stats, _ := sfu.GetCongestionStats("stream-1")
+	if !stats["viewer"].Paused {
+		t.Error("Expected paused state in congestion stats")
+	}
+
+	if err := sfu.ResumeSubscription("stream-1", "viewer"); err != nil {
+		t.Fatalf("Failed to resume subscription: %v", err)
+	}
+	if subscriber.IsPaused() {
+		t.Error("Expected subscription to be resumed")
+	}
+	// Forwarding resumes, so the missing track is reported again
+	if err := subscriber.WriteVideoPacket(packet); err == nil {
+		t.Error("Expected resumed subscriber to forward video")
+	}
+
+	if err := sfu.PauseSubscription("stream-1", "missing"); err != ErrSubscriberNotFound {
+		t.Errorf("Expected ErrSubscriberNotFound, got %v", err)
+	}
+}
+
+// TestBitrateAllocator tests sharing a subscriber's bandwidth across streams
+func TestBitrateAllocator(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "json")
+	config := DefaultSFUConfig()
+	config.EnableSVC = true
+	sfu := NewSFU(config, log)
+	defer sfu.Close()
+
+	svc := SVCConfig{Codec: SVCCodecVP9, SpatialBitrates: []int{100_000, 300_000, 1_000_000}, TemporalLayers: 1}
+	for _, id := range []string{"speaker", "tile", "thumb", "plain"} {
+		sfu.CreateStream(id, id)
+		if id != "plain" {
+			sfu.SetStreamSVC(id, svc)
+		}
+		stream, _ := sfu.GetStream(id)
+		subscriber := NewSubscriber("viewer", id, sfu.peerManager, sfu.trackManager, log)
+		subscriber.EnableSVC(svc)
+		stream.Subscribers["viewer"] = subscriber
+	}
+
+	allocator := sfu.BitrateAllocator()
+	allocator.SetPriority("viewer", "speaker", PrioritySpeaker)
+	allocator.SetPriority("viewer", "thumb", PriorityThumbnail)
+
+	allocations := allocator.Allocate("viewer", 2_000_000)
+	if len(allocations) != 3 {
+		t.Fatalf("Expected 3 allocations (non-SVC stream skipped), got %d", len(allocations))
+	}
+
+	want := map[string]int{"speaker": 2, "tile": 1, "thumb": 0}
+	for _, a := range allocations {
+		if a.Layer != want[a.StreamID] {
+			t.Errorf("Expected %s at layer %d, got %d", a.StreamID, want[a.StreamID], a.Layer)
+		}
+	}
+	if allocations[0].StreamID != "speaker" {
+		t.Errorf("Expected speaker allocated first, got %s", allocations[0].StreamID)
+	}
+
+	// Base layers come before upgrades: 250 kbps pauses only the last track
+	allocations = allocator.Allocate("viewer", 250_000)
+	for _, a := range allocations {
+		if a.StreamID == "thumb" && !a.Paused {
+			t.Error("Expected thumbnail to be paused")
+		}
+		if a.StreamID != "thumb" && a.Layer != 0 {
+			t.Errorf("Expected %s at base layer, got %d", a.StreamID, a.Layer)
+		}
+	}
+
+	stats, _ := sfu.GetCongestionStats("speaker")
+	if stats["viewer"].AllocatedBitrate != 100_000 {
+		t.Errorf("Expected allocated bitrate in stats, got %d", stats["viewer"].AllocatedBitrate)
+	}
+	if got := allocator.GetAllocation("viewer"); len(got) != 3 {
+		t.Errorf("Expected stored allocation, got %d entries", len(got))
+	}
+	stream, _ := sfu.GetStream("tile")
+	if target := stream.Subscribers["viewer"].svc.Target(); target.Spatial != 0 {
+		t.Errorf("Expected tile subscriber pinned to layer 0, got %+v", target)
+	}
+}
+
+// fakeTranscoder counts transcoded packets
+type fakeTranscoder struct {
+	packets int
+	closed  bool
+}
+
+func (f *fakeTranscoder) Transcode(packet *rtp.Packet) ([]*rtp.Packet, error) {
+	f.packets++
+	return []*rtp.Packet{packet}, nil
+}
+
+func (f *fakeTranscoder) Close() error {
+	f.closed = true
+	return nil
+}
+
+// TestTranscodeOnDemand tests shared transcodes for codec-incompatible subscribers
+func TestTranscodeOnDemand(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "json")
+	sfu := NewSFU(DefaultSFUConfig(), log)
+	defer sfu.Close()
+
+	var created []*fakeTranscoder
+	sfu.SetTranscoderFactory(func(from, to string) (Transcoder, error) {
+		if from != webrtc.MimeTypeVP8 {
+			t.Errorf("Expected transcode from VP8, got %s", from)
+		}
+		transcoder := &fakeTranscoder{}
+		created = append(created, transcoder)
+		return transcoder, nil
+	})
+
+	sfu.CreateStream("stream-1", "Test Stream")
+	sfu.SetStreamVideoCodec("stream-1", webrtc.MimeTypeVP8)
+
+	stream, _ := sfu.GetStream("stream-1")
+	for id, codec := range map[string]string{"safari-1": "", "safari-2": "", "chrome": webrtc.MimeTypeVP8} {
+		subscriber := NewSubscriber(id, "stream-1", sfu.peerManager, sfu.trackManager, log)
+		if codec != "" {
+			subscriber.SetVideoCodec(codec)
+		}
+		stream.Subscribers[id] = subscriber
+	}
+
+	sfu.forwardVideoPacket("stream-1", &rtp.Packet{Header: rtp.Header{SequenceNumber: 1}, Payload: []byte{0x01}})
+
+	if len(created) != 1 || created[0].packets != 1 {
+		t.Fatalf("Expected one shared transcode of one packet, got %d transcoders", len(created))
+	}
+	if !stream.Subscribers["safari-1"].IsTranscoded() || !stream.Subscribers["safari-2"].IsTranscoded() {
+		t.Error("Expected H.264 subscribers to be transcoded")
+	}
+	if stream.Subscribers["chrome"].IsTranscoded() {
+		t.Error("Expected VP8 subscriber not to be transcoded")
+	}
+	if codecs := stream.GetTranscodes(); len(codecs) != 1 {
+		t.Errorf("Expected one transcode, got %v", codecs)
+	}
+
+	sfu.RemoveSubscriber("stream-1", "safari-1")
+	if created[0].closed {
+		t.Error("Expected transcode kept for remaining subscriber")
+	}
+	sfu.RemoveSubscriber("stream-1", "safari-2")
+	if !created[0].closed || len(stream.GetTranscodes()) != 0 {
+		t.Error("Expected transcode closed after last subscriber left")
+	}
+
+	// End-to-end encrypted media is forwarded untouched
+	sfu.SetStreamEncrypted("stream-1", true)
+	stream.Subscribers["safari-3"] = NewSubscriber("safari-3", "stream-1", sfu.peerManager, sfu.trackManager, log)
+	sfu.forwardVideoPacket("stream-1", &rtp.Packet{Header: rtp.Header{SequenceNumber: 2}, Payload: []byte{0x02}})
+	if len(created) != 1 || stream.Subscribers["safari-3"].IsTranscoded() {
+		t.Error("Expected encrypted stream not to be transcoded")
+	}
+}
+
+// rejectingLimiter rejects every viewing session
+type rejectingLimiter struct{ users, released []string }
+
+func (l *rejectingLimiter) Acquire(ctx context.Context, userID, streamID, sessionID string) error {
+	l.users = append(l.users, userID)
+	return ErrMaxSubscribersReached
+}
+
+func (l *rejectingLimiter) Release(ctx context.Context, sessionID string) error {
+	l.released = append(l.released, sessionID)
+	return nil
+}
+
+// TestSFUViewerLimiter tests that subscribing goes through the viewer limiter
+func TestSFUViewerLimiter(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "json")
+	sfu := NewSFU(DefaultSFUConfig(), log)
+	defer sfu.Close()
+
+	limiter := &rejectingLimiter{}
+	sfu.SetViewerLimiter(limiter)
+	sfu.CreateStream("stream-1", "Test Stream")
+
+	if _, err := sfu.AddUserSubscriber(context.Background(), "stream-1", "subscriber-1", "account-1", ""); err != ErrMaxSubscribersReached {
+		t.Errorf("Expected limiter error, got %v", err)
+	}
+	if sfu.GetSubscriberCount("stream-1") != 0 {
+		t.Error("Expected rejected subscriber not to be added")
+	}
+
+	// Sessions count against the account, not the subscriber
+	if len(limiter.users) != 1 || limiter.users[0] != "account-1" {
+		t.Errorf("Expected a session for account-1, got %v", limiter.users)
+	}
+}
+
+// TestICEMonitor tests connectivity checks against unreachable ICE servers
+func TestICEMonitor(t *testing.T) {
+	log := logger.NewDefaultLogger(logger.InfoLevel, "json")
+	config := DefaultConfig()
+	config.STUNServers = []string{"stun:127.0.0.1:9"}
+	config.TURNServers = []TURNServer{{URLs: []string{"turn:127.0.0.1:9?transport=udp"}, Username: "user", Credential: "pass"}}
+	config.ICEGatheringTimeout = 300 * time.Millisecond
+
+	monitor := NewICEMonitor(config, time.Hour, log)
+	alerts := 0
+	monitor.OnAlert(func(result ICEServerResult) {
+		alerts++
+	})
+
+	report := monitor.Check(context.Background())
+	if len(report.Servers) != 2 {
+		t.Fatalf("Expected 2 checked servers, got %d", len(report.Servers))
+	}
+	for _, server := range report.Servers {
+		if server.Reachable || server.Error == "" {
+			t.Errorf("Expected %s to be unreachable with an error, got %+v", server.URL, server)
+		}
+	}
+	if report.Servers[1].Kind != ICEServerTURN || report.TURNReachable() {
+		t.Error("Expected the TURN server to be reported down")
+	}
+	if err := monitor.CheckHealth(context.Background()); err == nil {
+		t.Error("Expected health check to fail while TURN is down")
+	}
+
+	// A continuing outage alerts only once
+	monitor.Check(context.Background())
+	if alerts != 2 {
+		t.Errorf("Expected one alert per server, got %d", alerts)
+	}
+
+	// With nothing reachable, clients still get every configured server
+	if servers := monitor.ICEServers(); len(servers) != 2 {
+		t.Errorf("Expected fallback to all servers, got %d", len(servers))
+	}
+
+	monitor.last.Servers[0].Reachable = true
+	if servers := monitor.ICEServers(); len(servers) != 1 || servers[0].URLs[0] != "stun:127.0.0.1:9" {
+		t.Errorf("Expected only the reachable server, got %+v", servers)
+	}
+}
+
+// TestPeerDefaultInterceptors tests that peers negotiate the default
+// codecs and TWCC like webrtc.NewPeerConnection, and the simulcast header
+// extensions
+func TestPeerDefaultInterceptors(t *testing.T) {
+	pm := NewPeerManager(DefaultConfig(), logger.NewDefaultLogger(logger.InfoLevel, "text"))
+	peer, err := pm.CreatePeer(context.Background(), "peer-1", "stream-1", PeerRolePublisher)
+	if err != nil {
+		t.Fatalf("Failed to create peer: %v", err)
+	}
+	defer pm.RemovePeer("peer-1")
+
+	if _, err := peer.PC.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo); err != nil {
+		t.Fatalf("Failed to add transceiver: %v", err)
+	}
+	offer, err := peer.PC.CreateOffer(nil)
+	if err != nil {
+		t.Fatalf("Failed to create offer: %v", err)
+	}
+
+	for _, want := range []string{"VP8/90000", "transport-wide-cc", "nack", "urn:ietf:params:rtp-hdrext:sdes:mid", "urn:ietf:params:rtp-hdrext:sdes:rtp-stream-id"} {
+		if !strings.Contains(offer.SDP, want) {
+			t.Errorf("Expected %q in the offer", want)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+// Package webrtctest provides an in-memory SFU and transport for testing
+// code built on the webrtc package. Packets are forwarded synchronously
+// between in-memory publishers and subscribers, without peer connections,
+// ICE or DTLS, so publish, subscribe and forward logic can be exercised
+// deterministically.
+package webrtctest
+
+import (
+	"sync"
+
+	"github.com/aminofox/zenlive/pkg/streaming/webrtc"
+	"github.com/pion/rtp"
+)
+
+// MockSFU is an in-memory SFU that forwards every packet of a stream's
+// publisher to all of the stream's subscribers. It returns the same errors
+// as webrtc.SFU for the same mistakes.
+type MockSFU struct {
+	// MaxSubscribersPerStream caps the subscribers of each stream (0 = unlimited)
+	MaxSubscribersPerStream int
+
+	streams map[string]*mockStream
+	closed  bool
+	mu      sync.RWMutex
+}
+
+// mockStream is a stream of a MockSFU
+type mockStream struct {
+	name        string
+	publisher   webrtc.MediaPublisher
+	subscribers map[string]webrtc.MediaSubscriber
+	forwarded   int
+	dropped     int
+	mu          sync.RWMutex
+}
+
+// NewMockSFU creates an empty mock SFU with the default subscriber limit
+func NewMockSFU() *MockSFU {
+	return &MockSFU{
+		MaxSubscribersPerStream: webrtc.DefaultSFUConfig().MaxSubscribersPerStream,
+		streams:                 make(map[string]*mockStream),
+	}
+}
+
+// CreateStream creates a new stream
+func (m *MockSFU) CreateStream(streamID, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return webrtc.ErrSFUClosed
+	}
+	if _, exists := m.streams[streamID]; exists {
+		return &webrtc.WebRTCError{Code: "STREAM_EXISTS", Message: "stream already exists"}
+	}
+
+	m.streams[streamID] = &mockStream{
+		name:        name,
+		subscribers: make(map[string]webrtc.MediaSubscriber),
+	}
+
+	return nil
+}
+
+// DeleteStream deletes a stream, stopping its publisher and subscribers
+func (m *MockSFU) DeleteStream(streamID string) error {
+	m.mu.Lock()
+	stream, exists := m.streams[streamID]
+	delete(m.streams, streamID)
+	m.mu.Unlock()
+
+	if !exists {
+		return webrtc.ErrStreamNotFound
+	}
+
+	stream.mu.Lock()
+	publisher := stream.publisher
+	subscribers := stream.subscribers
+	stream.publisher = nil
+	stream.subscribers = make(map[string]webrtc.MediaSubscriber)
+	stream.mu.Unlock()
+
+	if publisher != nil {
+		publisher.Stop()
+	}
+	for _, subscriber := range subscribers {
+		subscriber.Stop()
+	}
+
+	return nil
+}
+
+// AddPublisher attaches a publisher to a stream and forwards its packets
+func (m *MockSFU) AddPublisher(streamID string, publisher webrtc.MediaPublisher) error {
+	stream, err := m.getStream(streamID)
+	if err != nil {
+		return err
+	}
+
+	stream.mu.Lock()
+	if stream.publisher != nil {
+		stream.mu.Unlock()
+		return webrtc.ErrPublisherExists
+	}
+	stream.publisher = publisher
+	stream.mu.Unlock()
+
+	publisher.OnVideoPacket(func(packet *rtp.Packet) {
+		stream.forward(packet, webrtc.MediaSubscriber.WriteVideoPacket)
+	})
+	publisher.OnAudioPacket(func(packet *rtp.Packet) {
+		stream.forward(packet, webrtc.MediaSubscriber.WriteAudioPacket)
+	})
+
+	return nil
+}
+
+// Publish creates an in-memory publisher and attaches it to a stream
+func (m *MockSFU) Publish(streamID, publisherID string) (*Publisher, error) {
+	publisher := NewPublisher(publisherID, streamID)
+	if err := m.AddPublisher(streamID, publisher); err != nil {
+		return nil, err
+	}
+	return publisher, nil
+}
+
+// RemovePublisher stops and detaches a stream's publisher
+func (m *MockSFU) RemovePublisher(streamID string) error {
+	stream, err := m.getStream(streamID)
+	if err != nil {
+		return err
+	}
+
+	stream.mu.Lock()
+	publisher := stream.publisher
+	stream.publisher = nil
+	stream.mu.Unlock()
+
+	if publisher != nil {
+		publisher.Stop()
+	}
+
+	return nil
+}
+
+// AddSubscriber attaches a subscriber to a stream
+func (m *MockSFU) AddSubscriber(streamID string, subscriber webrtc.MediaSubscriber) error {
+	stream, err := m.getStream(streamID)
+	if err != nil {
+		return err
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	if m.MaxSubscribersPerStream > 0 && len(stream.subscribers) >= m.MaxSubscribersPerStream {
+		return webrtc.ErrMaxSubscribersReached
+	}
+	stream.subscribers[subscriber.GetID()] = subscriber
+
+	return nil
+}
+
+// Subscribe creates an in-memory subscriber and attaches it to a stream
+func (m *MockSFU) Subscribe(streamID, subscriberID string) (*Subscriber, error) {
+	subscriber := NewSubscriber(subscriberID, streamID)
+	if err := m.AddSubscriber(streamID, subscriber); err != nil {
+		return nil, err
+	}
+	return subscriber, nil
+}
+
+// RemoveSubscriber stops and detaches a subscriber
+func (m *MockSFU) RemoveSubscriber(streamID, subscriberID string) error {
+	stream, err := m.getStream(streamID)
+	if err != nil {
+		return err
+	}
+
+	stream.mu.Lock()
+	subscriber, exists := stream.subscribers[subscriberID]
+	delete(stream.subscribers, subscriberID)
+	stream.mu.Unlock()
+
+	if !exists {
+		return webrtc.ErrSubscriberNotFound
+	}
+
+	subscriber.Stop()
+	return nil
+}
+
+// HasPublisher reports whether a stream has a publisher
+func (m *MockSFU) HasPublisher(streamID string) bool {
+	stream, err := m.getStream(streamID)
+	if err != nil {
+		return false
+	}
+
+	stream.mu.RLock()
+	defer stream.mu.RUnlock()
+
+	return stream.publisher != nil
+}
+
+// GetStreamCount returns the number of streams
+func (m *MockSFU) GetStreamCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.streams)
+}
+
+// GetSubscriberCount returns the number of subscribers of a stream
+func (m *MockSFU) GetSubscriberCount(streamID string) int {
+	stream, err := m.getStream(streamID)
+	if err != nil {
+		return 0
+	}
+
+	stream.mu.RLock()
+	defer stream.mu.RUnlock()
+
+	return len(stream.subscribers)
+}
+
+// GetForwardStats returns how many packet writes to subscribers of a stream
+// succeeded and failed
+func (m *MockSFU) GetForwardStats(streamID string) (forwarded, dropped int, err error) {
+	stream, err := m.getStream(streamID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	stream.mu.RLock()
+	defer stream.mu.RUnlock()
+
+	return stream.forwarded, stream.dropped, nil
+}
+
+// CheckHealth reports ErrSFUClosed once the mock SFU is closed
+func (m *MockSFU) CheckHealth() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return webrtc.ErrSFUClosed
+	}
+	return nil
+}
+
+// Close deletes every stream
+func (m *MockSFU) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	streamIDs := make([]string, 0, len(m.streams))
+	for id := range m.streams {
+		streamIDs = append(streamIDs, id)
+	}
+	m.mu.Unlock()
+
+	for _, streamID := range streamIDs {
+		m.DeleteStream(streamID)
+	}
+
+	return nil
+}
+
+// getStream returns a stream by ID
+func (m *MockSFU) getStream(streamID string) (*mockStream, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stream, exists := m.streams[streamID]
+	if !exists {
+		return nil, webrtc.ErrStreamNotFound
+	}
+	return stream, nil
+}
+
+// forward writes a packet to every subscriber of the stream. Like the SFU,
+// a failed write to one subscriber does not affect the others.
+func (s *mockStream) forward(packet *rtp.Packet, write func(webrtc.MediaSubscriber, *rtp.Packet) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, subscriber := range s.subscribers {
+		if err := write(subscriber, packet); err != nil {
+			s.dropped++
+			continue
+		}
+		s.forwarded++
+	}
+}
@@ -0,0 +1,208 @@
+package webrtctest
+
+import (
+	"sync"
+
+	"github.com/aminofox/zenlive/pkg/streaming/webrtc"
+	"github.com/pion/rtp"
+)
+
+// Publisher is an in-memory webrtc.MediaPublisher. Packets written to it are
+// delivered synchronously to its callbacks, so they reach every subscriber
+// of a MockSFU before the write returns.
+type Publisher struct {
+	id       string
+	streamID string
+
+	onVideoPacket func(*rtp.Packet)
+	onAudioPacket func(*rtp.Packet)
+	stopped       bool
+	mu            sync.RWMutex
+}
+
+var _ webrtc.MediaPublisher = (*Publisher)(nil)
+
+// NewPublisher creates an in-memory publisher for a stream
+func NewPublisher(id, streamID string) *Publisher {
+	return &Publisher{
+		id:       id,
+		streamID: streamID,
+	}
+}
+
+// GetID returns the publisher identifier
+func (p *Publisher) GetID() string {
+	return p.id
+}
+
+// GetStreamID returns the stream identifier
+func (p *Publisher) GetStreamID() string {
+	return p.streamID
+}
+
+// OnVideoPacket sets the callback for video RTP packets
+func (p *Publisher) OnVideoPacket(callback func(*rtp.Packet)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.onVideoPacket = callback
+}
+
+// OnAudioPacket sets the callback for audio RTP packets
+func (p *Publisher) OnAudioPacket(callback func(*rtp.Packet)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.onAudioPacket = callback
+}
+
+// WriteVideo publishes a video RTP packet, as if received from the client.
+// Packets written after Stop are dropped.
+func (p *Publisher) WriteVideo(packet *rtp.Packet) {
+	p.mu.RLock()
+	callback := p.onVideoPacket
+	stopped := p.stopped
+	p.mu.RUnlock()
+
+	if callback != nil && !stopped {
+		callback(packet)
+	}
+}
+
+// WriteAudio publishes an audio RTP packet, as if received from the client.
+// Packets written after Stop are dropped.
+func (p *Publisher) WriteAudio(packet *rtp.Packet) {
+	p.mu.RLock()
+	callback := p.onAudioPacket
+	stopped := p.stopped
+	p.mu.RUnlock()
+
+	if callback != nil && !stopped {
+		callback(packet)
+	}
+}
+
+// Stop stops delivering packets
+func (p *Publisher) Stop() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stopped = true
+	return nil
+}
+
+// IsStopped reports whether the publisher was stopped
+func (p *Publisher) IsStopped() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.stopped
+}
+
+// Subscriber is an in-memory webrtc.MediaSubscriber that records the packets
+// forwarded to it
+type Subscriber struct {
+	id       string
+	streamID string
+
+	video    []*rtp.Packet
+	audio    []*rtp.Packet
+	writeErr error
+	stopped  bool
+	mu       sync.RWMutex
+}
+
+var _ webrtc.MediaSubscriber = (*Subscriber)(nil)
+
+// NewSubscriber creates an in-memory subscriber for a stream
+func NewSubscriber(id, streamID string) *Subscriber {
+	return &Subscriber{
+		id:       id,
+		streamID: streamID,
+	}
+}
+
+// GetID returns the subscriber identifier
+func (s *Subscriber) GetID() string {
+	return s.id
+}
+
+// GetStreamID returns the stream identifier
+func (s *Subscriber) GetStreamID() string {
+	return s.streamID
+}
+
+// SetWriteError makes every following write fail with err, to simulate a
+// broken transport (nil restores normal writes)
+func (s *Subscriber) SetWriteError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.writeErr = err
+}
+
+// WriteVideoPacket records a video RTP packet
+func (s *Subscriber) WriteVideoPacket(packet *rtp.Packet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.writeErr != nil {
+		return s.writeErr
+	}
+	s.video = append(s.video, packet.Clone())
+	return nil
+}
+
+// WriteAudioPacket records an audio RTP packet
+func (s *Subscriber) WriteAudioPacket(packet *rtp.Packet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.writeErr != nil {
+		return s.writeErr
+	}
+	s.audio = append(s.audio, packet.Clone())
+	return nil
+}
+
+// VideoPackets returns the video packets received so far, in order
+func (s *Subscriber) VideoPackets() []*rtp.Packet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]*rtp.Packet(nil), s.video...)
+}
+
+// AudioPackets returns the audio packets received so far, in order
+func (s *Subscriber) AudioPackets() []*rtp.Packet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]*rtp.Packet(nil), s.audio...)
+}
+
+// Reset discards the packets received so far
+func (s *Subscriber) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.video = nil
+	s.audio = nil
+}
+
+// Stop stops the subscriber
+func (s *Subscriber) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+	return nil
+}
+
+// IsStopped reports whether the subscriber was stopped
+func (s *Subscriber) IsStopped() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.stopped
+}
@@ -0,0 +1,110 @@
+package webrtctest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aminofox/zenlive/pkg/streaming/webrtc"
+	"github.com/pion/rtp"
+)
+
+// TestMockSFUForwarding tests publish, subscribe and forwarding over the in-memory transport
+func TestMockSFUForwarding(t *testing.T) {
+	sfu := NewMockSFU()
+	defer sfu.Close()
+
+	if _, err := sfu.Publish("missing", "pub"); !errors.Is(err, webrtc.ErrStreamNotFound) {
+		t.Fatalf("Expected ErrStreamNotFound, got %v", err)
+	}
+
+	if err := sfu.CreateStream("stream-1", "Test Stream"); err != nil {
+		t.Fatalf("Failed to create stream: %v", err)
+	}
+
+	publisher, err := sfu.Publish("stream-1", "pub")
+	if err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	if _, err := sfu.Publish("stream-1", "pub-2"); !errors.Is(err, webrtc.ErrPublisherExists) {
+		t.Errorf("Expected ErrPublisherExists, got %v", err)
+	}
+
+	alice, _ := sfu.Subscribe("stream-1", "alice")
+	bob, _ := sfu.Subscribe("stream-1", "bob")
+	if count := sfu.GetSubscriberCount("stream-1"); count != 2 {
+		t.Fatalf("Expected 2 subscribers, got %d", count)
+	}
+
+	for seq := uint16(1); seq <= 3; seq++ {
+		publisher.WriteVideo(&rtp.Packet{Header: rtp.Header{SequenceNumber: seq}, Payload: []byte{byte(seq)}})
+	}
+	publisher.WriteAudio(&rtp.Packet{Header: rtp.Header{SequenceNumber: 10}})
+
+	for _, sub := range []*Subscriber{alice, bob} {
+		video := sub.VideoPackets()
+		if len(video) != 3 {
+			t.Fatalf("Expected %s to receive 3 video packets, got %d", sub.GetID(), len(video))
+		}
+		for i, packet := range video {
+			if packet.SequenceNumber != uint16(i+1) {
+				t.Errorf("Expected %s packet %d to have sequence %d, got %d", sub.GetID(), i, i+1, packet.SequenceNumber)
+			}
+		}
+		if len(sub.AudioPackets()) != 1 {
+			t.Errorf("Expected %s to receive 1 audio packet, got %d", sub.GetID(), len(sub.AudioPackets()))
+		}
+	}
+
+	// A failing subscriber does not affect the others
+	bob.SetWriteError(errors.New("broken"))
+	publisher.WriteVideo(&rtp.Packet{Header: rtp.Header{SequenceNumber: 4}})
+	if len(alice.VideoPackets()) != 4 || len(bob.VideoPackets()) != 3 {
+		t.Errorf("Expected only alice to receive the packet, got %d and %d", len(alice.VideoPackets()), len(bob.VideoPackets()))
+	}
+	forwarded, dropped, _ := sfu.GetForwardStats("stream-1")
+	if forwarded != 9 || dropped != 1 {
+		t.Errorf("Expected 9 forwarded and 1 dropped, got %d and %d", forwarded, dropped)
+	}
+
+	// Removed subscribers are stopped and receive nothing more
+	if err := sfu.RemoveSubscriber("stream-1", "alice"); err != nil {
+		t.Fatalf("Failed to remove subscriber: %v", err)
+	}
+	if !alice.IsStopped() {
+		t.Error("Expected removed subscriber to be stopped")
+	}
+	publisher.WriteVideo(&rtp.Packet{Header: rtp.Header{SequenceNumber: 5}})
+	if len(alice.VideoPackets()) != 4 {
+		t.Error("Expected removed subscriber to receive no packets")
+	}
+	if err := sfu.RemoveSubscriber("stream-1", "alice"); !errors.Is(err, webrtc.ErrSubscriberNotFound) {
+		t.Errorf("Expected ErrSubscriberNotFound, got %v", err)
+	}
+
+	// Deleting the stream stops its publisher and subscribers
+	if err := sfu.DeleteStream("stream-1"); err != nil {
+		t.Fatalf("Failed to delete stream: %v", err)
+	}
+	if !publisher.IsStopped() || !bob.IsStopped() {
+		t.Error("Expected publisher and subscribers to be stopped with the stream")
+	}
+}
+
+// TestMockSFUSubscriberLimit tests the per-stream subscriber limit
+func TestMockSFUSubscriberLimit(t *testing.T) {
+	sfu := NewMockSFU()
+	sfu.MaxSubscribersPerStream = 1
+	sfu.CreateStream("stream-1", "Test Stream")
+
+	if _, err := sfu.Subscribe("stream-1", "alice"); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if _, err := sfu.Subscribe("stream-1", "bob"); !errors.Is(err, webrtc.ErrMaxSubscribersReached) {
+		t.Errorf("Expected ErrMaxSubscribersReached, got %v", err)
+	}
+
+	sfu.Close()
+	if err := sfu.CheckHealth(); !errors.Is(err, webrtc.ErrSFUClosed) {
+		t.Errorf("Expected ErrSFUClosed after close, got %v", err)
+	}
+}
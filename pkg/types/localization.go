@@ -0,0 +1,93 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidLocalization is returned for malformed localized fields
+var ErrInvalidLocalization = errors.New("invalid localization")
+
+// Localized field names
+const (
+	// FieldTitle is the localized title field
+	FieldTitle = "title"
+
+	// FieldDescription is the localized description field
+	FieldDescription = "description"
+)
+
+// languageTag matches BCP 47 style language tags (e.g. "en", "ja", "pt-BR")
+var languageTag = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// LocalizedText holds the translations of a text by language tag
+type LocalizedText map[string]string
+
+// Get returns the text in lang, falling back to the base language of lang
+// (e.g. "pt" for "pt-BR") and then to defaultLang
+func (t LocalizedText) Get(lang, defaultLang string) (string, bool) {
+	for _, candidate := range []string{lang, baseLanguage(lang), defaultLang} {
+		if candidate == "" {
+			continue
+		}
+		for tag, text := range t {
+			if strings.EqualFold(tag, candidate) {
+				return text, true
+			}
+		}
+	}
+	return "", false
+}
+
+// baseLanguage returns the primary subtag of a language tag
+func baseLanguage(lang string) string {
+	base, _, _ := strings.Cut(lang, "-")
+	return base
+}
+
+// ValidateLocalized checks the language tags and texts of localized fields
+// and that defaultLang, if set, is a valid tag
+func ValidateLocalized(fields map[string]LocalizedText, defaultLang string) error {
+	if defaultLang != "" && !languageTag.MatchString(defaultLang) {
+		return fmt.Errorf("%w: invalid default language %q", ErrInvalidLocalization, defaultLang)
+	}
+
+	for field, texts := range fields {
+		if field == "" {
+			return fmt.Errorf("%w: empty field name", ErrInvalidLocalization)
+		}
+		for lang, text := range texts {
+			if !languageTag.MatchString(lang) {
+				return fmt.Errorf("%w: invalid language %q for %s", ErrInvalidLocalization, lang, field)
+			}
+			if strings.TrimSpace(text) == "" {
+				return fmt.Errorf("%w: empty %s.%s", ErrInvalidLocalization, field, lang)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetLocalized returns a localized field of the stream in lang, falling
+// back to the stream's default language and then to Title or Description
+func (s *Stream) GetLocalized(field, lang string) string {
+	if text, ok := s.Localized[field].Get(lang, s.DefaultLanguage); ok {
+		return text
+	}
+
+	switch field {
+	case FieldTitle:
+		return s.Title
+	case FieldDescription:
+		return s.Description
+	}
+	return ""
+}
+
+// ValidateLocalization checks the stream's localized fields
+func (s *Stream) ValidateLocalization() error {
+	return ValidateLocalized(s.Localized, s.DefaultLanguage)
+}
@@ -72,6 +72,12 @@ type Stream struct {
 	// Description is the stream description
 	Description string `json:"description"`
 
+	// Localized holds translations of localized fields (e.g. "title" -> "ja" -> text)
+	Localized map[string]LocalizedText `json:"localized,omitempty"`
+
+	// DefaultLanguage is the language used when a translation is missing
+	DefaultLanguage string `json:"default_language,omitempty"`
+
 	// StreamKey is the unique key for publishing to this stream
 	StreamKey string `json:"stream_key"`
 
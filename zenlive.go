@@ -1,11 +1,13 @@
 package zenlive
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
 	"github.com/aminofox/zenlive/pkg/config"
 	"github.com/aminofox/zenlive/pkg/errors"
+	"github.com/aminofox/zenlive/pkg/health"
 	"github.com/aminofox/zenlive/pkg/logger"
 	"github.com/aminofox/zenlive/pkg/room"
 	"github.com/aminofox/zenlive/pkg/types"
@@ -22,6 +24,9 @@ type SDK struct {
 	// Room manager for video conferencing
 	roomManager *room.RoomManager
 
+	// Health of the SDK's components
+	health *health.Registry
+
 	// Internal state
 	mu        sync.RWMutex
 	isRunning bool
@@ -45,8 +50,10 @@ func New(cfg *config.Config) (*SDK, error) {
 		logger:      log,
 		providers:   make(map[types.StreamProtocol]types.StreamProvider),
 		roomManager: roomMgr,
+		health:      health.NewRegistry(),
 		isRunning:   false,
 	}
+	sdk.health.Register("room_manager", roomMgr, true)
 
 	return sdk, nil
 }
@@ -80,6 +87,7 @@ func (s *SDK) Start() error {
 	}
 
 	s.isRunning = true
+	s.health.SetReady(true)
 	s.logger.Info("ZenLive SDK started successfully")
 
 	return nil
@@ -95,6 +103,7 @@ func (s *SDK) Stop() error {
 	}
 
 	s.logger.Info("Stopping ZenLive SDK")
+	s.health.SetReady(false)
 
 	// Stop all providers
 	for protocol, provider := range s.providers {
@@ -187,6 +196,24 @@ func (s *SDK) GetRoomManager() *room.RoomManager {
 	return s.roomManager
 }
 
+// RegisterHealthCheck adds a component (SFU, storage, cache, certificate,
+// ...) to the SDK's health report. A failing critical component makes the
+// SDK unhealthy and not ready; any other failing component degrades it.
+func (s *SDK) RegisterHealthCheck(name string, checker health.Checker, critical bool) {
+	s.health.Register(name, checker, critical)
+}
+
+// Health checks all registered components and returns the aggregated report
+func (s *SDK) Health(ctx context.Context) *health.Report {
+	return s.health.Check(ctx)
+}
+
+// HealthRegistry returns the registry behind Health, e.g. to serve it from
+// the API server's health and readiness endpoints
+func (s *SDK) HealthRegistry() *health.Registry {
+	return s.health
+}
+
 // Room Management Methods
 
 // CreateRoom creates a new room with the given name and options
@@ -1,9 +1,12 @@
 package zenlive
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/aminofox/zenlive/pkg/config"
+	"github.com/aminofox/zenlive/pkg/health"
 )
 
 func TestNew(t *testing.T) {
@@ -78,6 +81,32 @@ func TestSDK_StartStop(t *testing.T) {
 	}
 }
 
+func TestSDK_Health(t *testing.T) {
+	sdk, err := New(nil)
+	if err != nil {
+		t.Fatalf("Failed to create SDK: %v", err)
+	}
+
+	if report := sdk.Health(context.Background()); report.Status != health.StatusHealthy || report.Ready {
+		t.Errorf("Expected healthy but not ready before Start(), got %s ready=%v", report.Status, report.Ready)
+	}
+
+	sdk.Start()
+	sdk.RegisterHealthCheck("cache", health.CheckerFunc(func(ctx context.Context) error {
+		return errors.New("redis unreachable")
+	}), false)
+
+	if report := sdk.Health(context.Background()); report.Status != health.StatusDegraded || !report.Ready {
+		t.Errorf("Expected degraded and ready, got %s ready=%v", report.Status, report.Ready)
+	}
+
+	// Stopping closes the room manager, a critical component
+	sdk.Stop()
+	if report := sdk.Health(context.Background()); report.Status != health.StatusUnhealthy || report.Ready {
+		t.Errorf("Expected unhealthy and not ready after Stop(), got %s ready=%v", report.Status, report.Ready)
+	}
+}
+
 func TestSDK_Version(t *testing.T) {
 	sdk, err := New(nil)
 	if err != nil {